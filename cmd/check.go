@@ -3,7 +3,6 @@ package cmd
 import (
 	"fmt"
 
-	"github.com/shipyard-run/shipyard/pkg/clients"
 	"github.com/spf13/cobra"
 )
 
@@ -14,8 +13,7 @@ var checkCmd = &cobra.Command{
 	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Do Stuff Here
-		s := clients.SystemImpl{}
-		o, _ := s.Preflight()
+		o, _ := engineClients.Browser.Preflight()
 
 		fmt.Println("")
 		fmt.Println("###### SYSTEM DIAGNOSTICS ######")
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+	"github.com/spf13/cobra"
+	"golang.org/x/xerrors"
+)
+
+// dashboardServe serves the dashboard until the process is interrupted,
+// replaced in tests
+var dashboardServe = func(addr string, h http.Handler) error {
+	return http.ListenAndServe(addr, h)
+}
+
+func newDashboardCmd(e shipyard.Engine) *cobra.Command {
+	var port int
+	var address string
+
+	dashboardCmd := &cobra.Command{
+		Use:   "dashboard",
+		Short: "Serve a web page showing the health of the running resources",
+		Long: `Serve a web page showing the health of the running resources.
+
+The page lists every resource in the environment along with its health,
+endpoints, outputs, and a link to the logs of its containers. The page
+refreshes every few seconds, the same information is available as JSON at
+/api/status.
+
+The dashboard has no authentication and shows the logs and outputs of the
+resources, by default it is only reachable from this machine. Only bind it to
+a non loopback address on a network you trust.`,
+		Example: `
+  # Serve the dashboard on http://127.0.0.1:8080
+  yard dashboard
+
+  # Serve the dashboard on a different port
+  yard dashboard --port 9090
+`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr := net.JoinHostPort(address, strconv.Itoa(port))
+
+			if !isLoopback(address) {
+				fmt.Printf("Warning: the dashboard has no authentication, logs and outputs are visible to anyone who can reach %s\n\n", address)
+			}
+
+			fmt.Printf("Serving dashboard on http://%s, press Ctrl-C to stop\n", addr)
+
+			err := dashboardServe(addr, e.Dashboard())
+			if err != nil {
+				return xerrors.Errorf("Unable to serve dashboard: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	dashboardCmd.Flags().IntVarP(&port, "port", "", 8080, "Port to serve the dashboard on")
+	dashboardCmd.Flags().StringVarP(&address, "address", "", "127.0.0.1", "Address to bind the dashboard to")
+
+	return dashboardCmd
+}
+
+// isLoopback returns true when the address is only reachable from this machine
+func isLoopback(address string) bool {
+	if address == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(address)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/shipyard/mocks"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupDashboard(serveErr error) (*cobra.Command, *string) {
+	me := &mocks.Engine{}
+	me.On("Dashboard").Return(http.NewServeMux())
+
+	addr := ""
+	dashboardServe = func(a string, h http.Handler) error {
+		addr = a
+		return serveErr
+	}
+
+	return newDashboardCmd(me), &addr
+}
+
+func TestDashboardServesOnPort(t *testing.T) {
+	c, addr := setupDashboard(nil)
+
+	c.SetArgs([]string{"--port", "9090"})
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "127.0.0.1:9090", *addr)
+}
+
+func TestDashboardReturnsErrorWhenServeFails(t *testing.T) {
+	c, _ := setupDashboard(fmt.Errorf("address in use"))
+
+	c.SetArgs([]string{})
+	err := c.Execute()
+	assert.Error(t, err)
+}
+
+func TestDashboardServesOnAddress(t *testing.T) {
+	c, addr := setupDashboard(nil)
+
+	c.SetArgs([]string{"--address", "0.0.0.0"})
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "0.0.0.0:8080", *addr)
+}
+
+func TestIsLoopbackDetectsLoopbackAddresses(t *testing.T) {
+	assert.True(t, isLoopback("localhost"))
+	assert.True(t, isLoopback("127.0.0.1"))
+	assert.True(t, isLoopback("::1"))
+	assert.False(t, isLoopback("0.0.0.0"))
+	assert.False(t, isLoopback("192.168.1.10"))
+}
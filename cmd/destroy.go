@@ -2,9 +2,13 @@ package cmd
 
 import (
 	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
 	"github.com/spf13/cobra"
 )
 
+var destroyNoTUI bool
+var destroyContinueOnError bool
+
 var destroyCmd = &cobra.Command{
 	Use:   "destroy [file]",
 	Short: "Destroy the current stack or file",
@@ -18,15 +22,23 @@ var destroyCmd = &cobra.Command{
 			dst = args[0]
 		}
 
+		stopTUI := func() {}
+		if !destroyNoTUI {
+			stopTUI = startTUI(engine)
+		}
+
 		// When destroying a stack all the config
 		// which is created with apply is copied
 		// to the state folder
-		var err error
-		if dst == "" {
-			err = engine.Destroy(dst, true)
-		} else {
-			err = engine.Destroy(dst, false)
-		}
+		ctx, stop := interruptContext()
+
+		err := engine.DestroyWithOptions(ctx, dst, shipyard.DestroyOptions{
+			All:             dst == "",
+			ContinueOnError: destroyContinueOnError,
+		})
+
+		stop()
+		stopTUI()
 
 		if err != nil {
 			hclog.Default().Error("Unable to destroy stack", "error", err)
@@ -34,3 +46,8 @@ var destroyCmd = &cobra.Command{
 		}
 	},
 }
+
+func init() {
+	destroyCmd.Flags().BoolVarP(&destroyContinueOnError, "continue-on-error", "", false, "When set to true resources which fail to be destroyed do not stop the remaining resources being destroyed")
+	destroyCmd.Flags().BoolVarP(&destroyNoTUI, "no-tui", "", false, "When set to true Shipyard writes the log output instead of showing the progress of each resource")
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/spf13/viper"
+)
+
+// dockerConfig returns the connection settings for the Docker engine used
+// by Shipyard. The docker_host, docker_cert_path, docker_tls_verify, and
+// docker_context settings in $HOME/.shipyard/config, or the equivalent
+// SHIPYARD_ prefixed environment variables e.g. SHIPYARD_DOCKER_HOST,
+// override the standard Docker environment variables so that environments
+// can be created on a remote machine without changing the Docker CLI
+func dockerConfig() clients.DockerConfig {
+	v := viper.New()
+	v.SetEnvPrefix("shipyard")
+	v.AutomaticEnv()
+	v.AddConfigPath(utils.ShipyardHome())
+	v.SetConfigName("config")
+
+	// the config file is optional
+	v.ReadInConfig()
+
+	return dockerConfigFrom(v, shipyard.DockerConfig())
+}
+
+// dockerConfigFrom applies the Docker settings in v to the connection
+// settings c, a host replaces the context set by the environment
+func dockerConfigFrom(v *viper.Viper, c clients.DockerConfig) clients.DockerConfig {
+	if v.IsSet("docker_host") {
+		c.Host = v.GetString("docker_host")
+		c.Context = ""
+	}
+
+	if v.IsSet("docker_cert_path") {
+		c.CertPath = v.GetString("docker_cert_path")
+	}
+
+	if v.IsSet("docker_tls_verify") {
+		c.TLSVerify = v.GetBool("docker_tls_verify")
+	}
+
+	if v.IsSet("docker_context") {
+		c.Context = v.GetString("docker_context")
+	}
+
+	return c
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDockerConfigFromUsesEnvironmentWhenNotSet(t *testing.T) {
+	c := dockerConfigFrom(viper.New(), clients.DockerConfig{Context: "default"})
+
+	assert.Equal(t, clients.DockerConfig{Context: "default"}, c)
+}
+
+func TestDockerConfigReadsHostFromEnvironment(t *testing.T) {
+	os.Setenv("SHIPYARD_DOCKER_HOST", "ssh://build@10.0.0.1")
+	defer os.Unsetenv("SHIPYARD_DOCKER_HOST")
+
+	c := dockerConfig()
+
+	assert.Equal(t, "ssh://build@10.0.0.1", c.Host)
+	assert.Empty(t, c.Context)
+}
+
+func TestDockerConfigReadsHostFromConfigFile(t *testing.T) {
+	home := os.Getenv("HOME")
+	defer os.Setenv("HOME", home)
+
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	os.Setenv("HOME", dir)
+	os.MkdirAll(filepath.Join(dir, ".shipyard"), 0755)
+	ioutil.WriteFile(
+		filepath.Join(dir, ".shipyard", "config.yaml"),
+		[]byte("docker_host: tcp://10.0.0.1:2376\ndocker_cert_path: /certs\ndocker_tls_verify: true\n"),
+		0644,
+	)
+
+	c := dockerConfig()
+
+	assert.Equal(t, "tcp://10.0.0.1:2376", c.Host)
+	assert.Equal(t, "/certs", c.CertPath)
+	assert.True(t, c.TLSVerify)
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"time"
@@ -105,13 +106,13 @@ func createContainerShell(r config.Resource, dt clients.ContainerTasks, command
 	}
 
 	// find the container id
-	ids, err := dt.FindContainerIDs(r.Info().Name, config.TypeContainer)
+	ids, err := dt.FindContainerIDs(context.Background(), r.Info().Name, config.TypeContainer)
 	if err != nil || len(ids) == 0 {
 		return fmt.Errorf("Unable to find container %s", r.Info().Name)
 	}
 
 	in, stdout, _ := term.StdStreams()
-	err = dt.CreateShell(ids[0], command, in, stdout, stdout)
+	err = dt.CreateShell(context.Background(), ids[0], command, in, stdout, stdout)
 	if err != nil {
 		return fmt.Errorf("Could not execute command for container %s. Error: %s", ids[0], err)
 	}
@@ -134,7 +135,7 @@ func createK8sShell(r config.Resource, dt clients.ContainerTasks, pod, container
 
 	// start a tools container
 	i := config.Image{Name: "shipyardrun/ingress:latest"}
-	err := dt.PullImage(i, false)
+	err := dt.PullImage(context.Background(), i, false)
 	if err != nil {
 		return xerrors.Errorf("Could pull ingress image. Error: %w", err)
 	}
@@ -172,14 +173,14 @@ func createK8sShell(r config.Resource, dt clients.ContainerTasks, pod, container
 		},
 	}
 
-	tools, err := dt.CreateContainer(c)
+	tools, err := dt.CreateContainer(context.Background(), c)
 	if err != nil {
 		return fmt.Errorf("Could not create exec container. Error: %s", err)
 	}
-	defer dt.RemoveContainer(tools)
+	defer dt.RemoveContainer(context.Background(), tools)
 
 	in, stdout, _ := term.StdStreams()
-	err = dt.CreateShell(tools, append(exec, command...), in, stdout, stdout)
+	err = dt.CreateShell(context.Background(), tools, append(exec, command...), in, stdout, stdout)
 	if err != nil {
 		return fmt.Errorf("Could not execute command for cluster %s. Error: %s", clusterName, err)
 	}
@@ -8,7 +8,6 @@ import (
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
-	"github.com/shipyard-run/shipyard/pkg/clients"
 	"github.com/spf13/cobra"
 )
 
@@ -21,15 +20,9 @@ var pauseCmd = &cobra.Command{
 	`,
 	Args: cobra.ArbitraryArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		var err error
 		fmt.Println("Pausing resources")
 
-		// create a docker client
-		c, err := clients.NewDocker()
-		if err != nil {
-			fmt.Println("Unable to connect to Docker daemon", err)
-			os.Exit(1)
-		}
+		c := engineClients.Docker
 
 		filters := filters.NewArgs()
 		filters.Add("name", "shipyard")
@@ -41,6 +34,10 @@ var pauseCmd = &cobra.Command{
 				Filters: filters,
 			},
 		)
+		if err != nil {
+			fmt.Println("Unable to connect to Docker daemon", err)
+			os.Exit(1)
+		}
 
 		sd := 20 * time.Second
 		for _, con := range cl {
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
 	"github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients"
 	"github.com/shipyard-run/shipyard/pkg/utils"
@@ -48,6 +49,17 @@ func newPurgeCmdFunc(dt clients.Docker, il clients.ImageLog, l hclog.Logger) fun
 			return fmt.Errorf("Unable to remove cached image volume, error: %s", err)
 		}
 
+		l.Info("Removing image cache")
+		err = dt.ContainerRemove(context.Background(), utils.FQDN("image-cache", "container"), types.ContainerRemoveOptions{Force: true})
+		if err != nil && !client.IsErrNotFound(err) {
+			return fmt.Errorf("Unable to remove image cache, error: %s", err)
+		}
+
+		err = dt.VolumeRemove(context.Background(), utils.FQDNVolumeName("image-cache"), true)
+		if err != nil && !client.IsErrNotFound(err) {
+			return fmt.Errorf("Unable to remove image cache volume, error: %s", err)
+		}
+
 		hcp := utils.GetBlueprintLocalFolder("")
 		l.Info("Removing Helm charts", "path", hcp)
 		err = os.RemoveAll(hcp)
@@ -40,6 +40,7 @@ func setupPurgeCommand(t *testing.T) (*cobra.Command, *mocks.MockDocker, *mocks.
 	mockDocker := &mocks.MockDocker{}
 	mockDocker.On("ImageRemove", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
 	mockDocker.On("VolumeRemove", mock.Anything, mock.Anything, true).Return(nil)
+	mockDocker.On("ContainerRemove", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
 	mockImageLog := &mocks.ImageLog{}
 	mockImageLog.On("Read", mock.Anything).Return([]string{"one", "two"}, nil)
@@ -77,6 +78,17 @@ func TestPurgeReturnsErrorOnImageRemoveError(t *testing.T) {
 	mi.AssertNotCalled(t, "Clear")
 }
 
+func TestPurgeRemovesImageCache(t *testing.T) {
+	pc, md, _, cleanup := setupPurgeCommand(t)
+	defer cleanup()
+
+	err := pc.Execute()
+
+	assert.NoError(t, err)
+	md.AssertCalled(t, "ContainerRemove", mock.Anything, utils.FQDN("image-cache", "container"), mock.Anything)
+	md.AssertCalled(t, "VolumeRemove", mock.Anything, utils.FQDNVolumeName("image-cache"), true)
+}
+
 func TestPurgeRemovesBlueprints(t *testing.T) {
 	pc, _, _, cleanup := setupPurgeCommand(t)
 	defer cleanup()
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -75,14 +76,14 @@ func pushK8sCluster(image string, c *config.K8sCluster, ct clients.ContainerTask
 	cl := providers.NewK8sCluster(c, ct, kc, ht, log)
 
 	// get the id of the cluster
-	ids, err := cl.Lookup()
+	ids, err := cl.Lookup(context.Background())
 	if err != nil {
 		return xerrors.Errorf("Error getting id for cluster")
 	}
 
 	for _, id := range ids {
 		log.Info("Pushing to container", "id", id, "image", image)
-		err = cl.ImportLocalDockerImages(utils.ImageVolumeName, id, []config.Image{config.Image{Name: strings.Trim(image, " ")}}, force)
+		err = cl.ImportLocalDockerImages(context.Background(), utils.ImageVolumeName, id, []config.Image{config.Image{Name: strings.Trim(image, " ")}}, force)
 		if err != nil {
 			return xerrors.Errorf("Error pushing image: %w ", err)
 		}
@@ -95,14 +96,14 @@ func pushNomadCluster(image string, c *config.NomadCluster, ct clients.Container
 	cl := providers.NewNomadCluster(c, ct, ht, log)
 
 	// get the id of the cluster
-	ids, err := cl.Lookup()
+	ids, err := cl.Lookup(context.Background())
 	if err != nil {
 		return xerrors.Errorf("Error getting id for cluster")
 	}
 
 	for _, id := range ids {
 		log.Info("Pushing to container", "id", id, "image", image)
-		err = cl.ImportLocalDockerImages(utils.ImageVolumeName, id, []config.Image{config.Image{Name: strings.Trim(image, " ")}}, force)
+		err = cl.ImportLocalDockerImages(context.Background(), utils.ImageVolumeName, id, []config.Image{config.Image{Name: strings.Trim(image, " ")}}, force)
 		if err != nil {
 			return xerrors.Errorf("Error pushing image: %w ", err)
 		}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var refreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Update the state with resources which have been changed outside of Shipyard",
+	Long: `Checks Docker, Kubernetes, and Nomad for the resources in the state, resources
+	which have been removed are created on the next run and resources which
+	have been modified are replaced.
+	Example use to recreate a container which has been removed with docker rm
+	shipyard refresh
+	shipyard run ./my-stack
+	`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		d, err := engine.Refresh()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if !d.HasDrift() {
+			fmt.Println("No changes have been made outside of Shipyard")
+			return
+		}
+
+		for _, n := range d.Missing {
+			fmt.Printf("+ %s (missing)\n", n)
+		}
+
+		for _, n := range d.Modified {
+			fmt.Printf("~ %s (modified)\n", n)
+		}
+
+		for _, n := range d.Dependents {
+			fmt.Printf("~ %s (dependency)\n", n)
+		}
+	},
+}
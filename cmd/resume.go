@@ -30,12 +30,7 @@ var resumeCmd = &cobra.Command{
 
 		l := createLogger()
 
-		// create a docker client
-		c, err := clients.NewDocker()
-		if err != nil {
-			l.Error("Unable to connect to Docker daemon", "error", err)
-			os.Exit(1)
-		}
+		c := engineClients.Docker
 
 		cl, err := getContainers(c, "exited")
 		if err != nil {
@@ -171,7 +166,7 @@ func healthCheckHelm(h *config.Helm) error {
 		return nil
 	}
 
-	err = kc.HealthCheckPods(h.HealthCheck.Pods, 500*time.Second)
+	err = kc.HealthCheckPods(context.Background(), h.HealthCheck.Pods, 500*time.Second)
 	if err != nil {
 		return err
 	}
@@ -192,7 +187,7 @@ func healthCheckK8sConfig(h *config.K8sConfig) error {
 		return nil
 	}
 
-	err = kc.HealthCheckPods(h.HealthCheck.Pods, 500*time.Second)
+	err = kc.HealthCheckPods(context.Background(), h.HealthCheck.Pods, 500*time.Second)
 	if err != nil {
 		return err
 	}
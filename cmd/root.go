@@ -2,8 +2,11 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/ci"
+	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/shipyard-run/shipyard/pkg/shipyard"
 
 	homedir "github.com/mitchellh/go-homedir"
@@ -29,21 +32,32 @@ func init() {
 	// setup dependencies
 	var err error
 	logger = createLogger()
-	engine, err = shipyard.New(logger)
+	engine, err = shipyard.NewWithDockerConfig(logger, dockerConfig())
 	if err != nil {
 		panic(err)
 	}
 
 	engineClients = engine.GetClients()
 
+	// when running in CI group the log output for each resource and annotate failures,
+	// annotations are written to the same stream as the logs to preserve ordering
+	if f := ci.DetectFormat(); f != ci.None {
+		engine.AddEventHandler(ci.NewAnnotator(os.Stderr, f).Handle)
+	}
+
 	cobra.OnInitialize(configure)
 
 	//rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "config file (default is $HOME/.shipyard/config)")
+	rootCmd.PersistentFlags().StringVar(&stateBackendFlag, "state-backend", "", "Store the state remotely, e.g. s3://bucket/key, consul://localhost:8500/key, or https://host/path. Can also be set with SHIPYARD_STATE_BACKEND")
+
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return configureStateBackend(engine, stateBackendFlag)
+	}
 
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(checkCmd)
 	rootCmd.AddCommand(newEnvCmd(engine))
-	rootCmd.AddCommand(newRunCmd(engine, engineClients.Getter, engineClients.HTTP, engineClients.Browser, logger))
+	rootCmd.AddCommand(newRunCmd(engine, engineClients.Getter, engineClients.Browser))
 	rootCmd.AddCommand(pauseCmd)
 	rootCmd.AddCommand(resumeCmd)
 	rootCmd.AddCommand(newGetCmd(engineClients.Getter))
@@ -51,7 +65,13 @@ func init() {
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(newPurgeCmd(engineClients.Docker, engineClients.ImageLog, logger))
 	rootCmd.AddCommand(taintCmd)
+	rootCmd.AddCommand(refreshCmd)
 	rootCmd.AddCommand(newExecCmd(engineClients.ContainerTasks))
+	rootCmd.AddCommand(newShareCmd(engineClients.Tunnel))
+	rootCmd.AddCommand(newDashboardCmd(engine))
+	rootCmd.AddCommand(newValidateCmd(engine))
+	rootCmd.AddCommand(newSignCmd())
+	rootCmd.AddCommand(newWorkspaceCmd(engine))
 	rootCmd.AddCommand(versionCmd)
 	//rootCmd.AddCommand(exposeCmd)
 	//rootCmd.AddCommand(containerCmd)
@@ -89,5 +109,6 @@ func configure() {
 // Execute the root command
 func Execute(v string) error {
 	version = v
+	config.Version = v
 	return rootCmd.Execute()
 }
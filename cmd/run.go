@@ -1,17 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"runtime"
-	"strconv"
-	"sync"
 	"time"
 
-	"github.com/hashicorp/go-hclog"
-
 	"github.com/shipyard-run/shipyard/pkg/clients"
-	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/shipyard-run/shipyard/pkg/shipyard"
 	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/spf13/cobra"
@@ -19,9 +15,18 @@ import (
 	markdown "github.com/MichaelMure/go-term-markdown"
 )
 
-func newRunCmd(e shipyard.Engine, bp clients.Getter, hc clients.HTTP, bc clients.System, l hclog.Logger) *cobra.Command {
+func newRunCmd(e shipyard.Engine, bp clients.Getter, bc clients.System) *cobra.Command {
 	var noOpen bool
 	var force bool
+	var noTUI bool
+	var offline bool
+	var bundle string
+	var checkpoint bool
+	var dryRun bool
+	var maxParallel int
+	var rollback bool
+	var replace []string
+	var timeout time.Duration
 	runCmd := &cobra.Command{
 		Use:   "run [file] [directory] ...",
 		Short: "Run the supplied stack configuration",
@@ -35,44 +40,95 @@ func newRunCmd(e shipyard.Engine, bp clients.Getter, hc clients.HTTP, bc clients
   
   # Create a stack from a blueprint in GitHub
   shipyard run github.com/shipyard-run/blueprints//vault-k8s
+
+  # Create a stack from a packaged blueprint
+  shipyard run ./vault-k8s.yardpack
+
+  # Create a stack without a network connection using an offline bundle
+  shipyard run --bundle ./vault-k8s.tar.gz github.com/shipyard-run/blueprints//vault-k8s
+
+  # Show the resources which would be created without creating them
+  shipyard run --dry-run ./my-stack
+
+  # Create at most 4 resources at the same time
+  shipyard run --max-parallel 4 ./my-stack
+
+  # Destroy and create a container again even when its config has not changed
+  shipyard run --replace container.consul ./my-stack
+
+  # Stop the run when the resources have not been created within 10 minutes
+  shipyard run --timeout 10m ./my-stack
 	`,
 		Args:         cobra.ArbitraryArgs,
-		RunE:         newRunCmdFunc(e, bp, hc, bc, &noOpen, &force, l),
+		RunE:         newRunCmdFunc(e, bp, bc, &noOpen, &force, &noTUI, &offline, &bundle, &checkpoint, &dryRun, &maxParallel, &rollback, &replace, &timeout),
 		SilenceUsage: true,
 	}
 	runCmd.Flags().BoolVarP(&noOpen, "no-browser", "", false, "When set to true Shipyard does not open the browser windows defined in the blueprint")
 	runCmd.Flags().BoolVarP(&force, "force-update", "", false, "When set to true Shipyard will ignore cached images or files and will download all resources")
+	runCmd.Flags().BoolVarP(&noTUI, "no-tui", "", false, "When set to true Shipyard writes the log output instead of showing the progress of each resource")
+	runCmd.Flags().BoolVarP(&offline, "offline", "", false, "When set to true Shipyard does not access the network, images, charts and files must have been loaded from a bundle")
+	runCmd.Flags().StringVarP(&bundle, "bundle", "", "", "Load images, charts and files from an offline bundle before running the blueprint, implies --offline")
+	runCmd.Flags().BoolVarP(&checkpoint, "checkpoint", "", false, "When set to true Shipyard creates a checkpoint of the containers once the blueprint is running, running the same blueprint again restores the containers from the checkpoint")
+	runCmd.Flags().BoolVarP(&dryRun, "dry-run", "", false, "When set to true Shipyard validates the blueprint and lists the resources which would be created without creating them, Docker is not required")
+	runCmd.Flags().IntVarP(&maxParallel, "max-parallel", "", 0, "Maximum number of resources created and images pulled at the same time, 0 does not limit the number")
+	runCmd.Flags().BoolVarP(&rollback, "rollback", "", false, "When set to true Shipyard destroys the resources created by the run when any resource fails, resources which were already running are not changed")
+	runCmd.Flags().StringSliceVarP(&replace, "replace", "", nil, "Resources which are destroyed and created again even when their config has not changed e.g. container.consul")
+	runCmd.Flags().DurationVarP(&timeout, "timeout", "", 0, "Maximum time to create the resources e.g. 10m, resources which have not been created are cancelled, 0 does not limit the time")
 
 	return runCmd
 }
 
-func newRunCmdFunc(e shipyard.Engine, bp clients.Getter, hc clients.HTTP, bc clients.System, noOpen *bool, force *bool, l hclog.Logger) func(cmd *cobra.Command, args []string) error {
+func newRunCmdFunc(e shipyard.Engine, bp clients.Getter, bc clients.System, noOpen *bool, force *bool, noTUI *bool, offline *bool, bundle *string, checkpoint *bool, dryRun *bool, maxParallel *int, rollback *bool, replace *[]string, timeout *time.Duration) func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
+		// loading a bundle implies offline mode, remote blueprints are only
+		// read from the local cache
+		if *bundle != "" {
+			*offline = true
+		}
+
+		if *offline {
+			bp.SetOffline(true)
+		}
+
 		if *force == true {
 			bp.SetForce(true)
 			e.GetClients().ContainerTasks.SetForcePull(true)
 		}
 
-		// Check the system to see if Docker is running and everything is installed
-		s, err := bc.Preflight()
-		if err != nil {
-			fmt.Println("")
-			fmt.Println("###### SYSTEM DIAGNOSTICS ######")
-			fmt.Println(s)
-			return err
-		}
+		// Check the system to see if Docker is running and everything is
+		// installed, a dry run does not use Docker
+		if !*dryRun {
+			s, err := bc.Preflight()
+			if err != nil {
+				fmt.Println("")
+				fmt.Println("###### SYSTEM DIAGNOSTICS ######")
+				fmt.Println(s)
+				return err
+			}
 
-		// check the shipyard version
-		text, ok := bc.CheckVersion(version)
-		if !ok {
-			fmt.Println("")
-			fmt.Println(text)
-			fmt.Println("")
+			// check the shipyard version, this needs the network
+			if !*offline {
+				text, ok := bc.CheckVersion(version)
+				if !ok {
+					fmt.Println("")
+					fmt.Println(text)
+					fmt.Println("")
+				}
+			}
 		}
 
 		// create the shipyard home
 		os.MkdirAll(utils.ShipyardHome(), os.FileMode(0755))
 
+		if *bundle != "" {
+			cmd.Println("Loading bundle: ", *bundle)
+
+			_, err := e.LoadBundle(*bundle)
+			if err != nil {
+				return fmt.Errorf("Unable to load bundle: %s", err)
+			}
+		}
+
 		dst := ""
 		if len(args) == 1 {
 			dst = args[0]
@@ -88,7 +144,15 @@ func newRunCmdFunc(e shipyard.Engine, bp clients.Getter, hc clients.HTTP, bc cli
 			cmd.Println("Running configuration from: ", dst)
 			cmd.Println("")
 
-			if !utils.IsLocalFolder(dst) && !utils.IsHCLFile(dst) {
+			if utils.IsRemoteBlueprintPackage(dst) {
+				// fetch the package and its signature which is verified by the engine
+				pkg, err := shipyard.FetchPackage(bp, dst)
+				if err != nil {
+					return fmt.Errorf("Unable to retrieve blueprint: %s", err)
+				}
+
+				dst = pkg
+			} else if !utils.IsLocalFolder(dst) && !utils.IsHCLFile(dst) && !utils.IsBlueprintPackage(dst) {
 				// fetch the remote server from github
 				err := bp.Get(dst, utils.GetBlueprintLocalFolder(dst))
 				if err != nil {
@@ -99,95 +163,36 @@ func newRunCmdFunc(e shipyard.Engine, bp clients.Getter, hc clients.HTTP, bc cli
 			}
 		}
 
-		// have we already got a blueprint in the state
-		blueprintExists := false
-		if bluePrintInState() {
-			blueprintExists = true
-		}
-
-		// Load the files
-		res, err := e.Apply(dst)
-		if err != nil {
-			return fmt.Errorf("Unable to apply blueprint: %s", err)
-		}
-
-		// do not open the browser windows
-		if *noOpen == false {
+		if *dryRun {
+			_, err := e.ApplyWithOptions(context.Background(), dst, shipyard.ApplyOptions{DryRun: true, Replace: *replace, Offline: *offline})
+			if err != nil {
+				return fmt.Errorf("Unable to apply blueprint: %s", err)
+			}
 
-			browserList := []string{}
+			cmd.Println("Dry run, the following resources would be changed:")
+			cmd.Println("")
 
-			// check if blueprint is in the state, if so do not open these windows again
-			if !blueprintExists && e.Blueprint() != nil {
-				browserList = e.Blueprint().BrowserWindows
+			for _, r := range e.Result().Resources {
+				cmd.Printf("  %s.%s (%s)\n", r.Type, r.Name, r.Action)
 			}
 
-			// check for browser windows in the applied resources
-			for _, r := range res {
-				switch r.Info().Type {
-				case config.TypeContainer:
-					c := r.(*config.Container)
-					for _, p := range c.Ports {
-						if p.Host != "" && p.OpenInBrowser != "" {
-							browserList = append(browserList, buildBrowserPath(r.Info().Name, p.Host, r.Info().Type, p.OpenInBrowser))
-						}
-					}
-				case config.TypeIngress:
-					c := r.(*config.Ingress)
-					for _, p := range c.Ports {
-						if p.Host != "" && p.OpenInBrowser != "" {
-							browserList = append(browserList, buildBrowserPath(r.Info().Name, p.Host, r.Info().Type, p.OpenInBrowser))
-						}
-					}
-				case config.TypeContainerIngress:
-					c := r.(*config.ContainerIngress)
-					for _, p := range c.Ports {
-						if p.Host != "" && p.OpenInBrowser != "" {
-							browserList = append(browserList, buildBrowserPath(r.Info().Name, p.Host, r.Info().Type, p.OpenInBrowser))
-						}
-					}
-				case config.TypeNomadIngress:
-					c := r.(*config.NomadIngress)
-					for _, p := range c.Ports {
-						if p.Host != "" && p.OpenInBrowser != "" {
-							browserList = append(browserList, buildBrowserPath(r.Info().Name, p.Host, r.Info().Type, p.OpenInBrowser))
-						}
-					}
-				case config.TypeK8sIngress:
-					c := r.(*config.K8sIngress)
-					for _, p := range c.Ports {
-						if p.Host != "" && p.OpenInBrowser != "" {
-							browserList = append(browserList, buildBrowserPath(r.Info().Name, p.Host, r.Info().Type, p.OpenInBrowser))
-						}
-					}
-				case config.TypeDocs:
-					c := r.(*config.Docs)
-					if c.OpenInBrowser {
-						browserList = append(browserList, buildBrowserPath(r.Info().Name, strconv.Itoa(c.Port), r.Info().Type, ""))
-					}
-				}
-			}
+			return nil
+		}
 
-			// check the browser windows in the blueprint file
-			wg := sync.WaitGroup{}
-
-			for _, b := range browserList {
-				wg.Add(1)
-				go func(uri string) {
-					// health check the URL
-					err := hc.HealthCheckHTTP(uri, 30*time.Second)
-					if err == nil {
-						be := bc.OpenBrowser(uri)
-						if be != nil {
-							l.Error("Unable to open browser", "error", be)
-						}
-					}
-
-					wg.Done()
-				}(b)
-			}
+		stopTUI := func() {}
+		if !*noTUI {
+			stopTUI = startTUI(e)
+		}
 
-			wg.Wait()
+		// Load the files, browser windows are opened by the engine once the
+		// resources have been created, interrupting stops the apply
+		ctx, stop := interruptContext()
+		_, err := e.ApplyWithOptions(ctx, dst, shipyard.ApplyOptions{DisableBrowser: *noOpen, Checkpoint: *checkpoint, MaxParallel: *maxParallel, Rollback: *rollback, Replace: *replace, Timeout: *timeout, Offline: *offline})
+		stop()
+		stopTUI()
 
+		if err != nil {
+			return fmt.Errorf("Unable to apply blueprint: %s", err)
 		}
 
 		// if we have a blueprint show the header
@@ -236,20 +241,3 @@ func newRunCmdFunc(e shipyard.Engine, bp clients.Getter, hc clients.HTTP, bc cli
 		return nil
 	}
 }
-
-func buildBrowserPath(n, p string, t config.ResourceType, path string) string {
-	ty := t
-	if t == config.TypeNomadIngress || t == config.TypeContainerIngress || t == config.TypeK8sIngress {
-		ty = config.TypeIngress
-	}
-
-	return fmt.Sprintf("http://%s.%s.shipyard.run:%s%s", n, ty, p, path)
-}
-
-func bluePrintInState() bool {
-	//load the state
-	sc := config.New()
-	sc.FromJSON(utils.StatePath())
-
-	return sc.Blueprint != nil
-}
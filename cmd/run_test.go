@@ -1,11 +1,12 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"path/filepath"
 	"testing"
+	"time"
 
-	"github.com/hashicorp/go-hclog"
 	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
 	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/shipyard-run/shipyard/pkg/shipyard"
@@ -16,17 +17,13 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
-func setupRun(t *testing.T) (*cobra.Command, *mocks.Engine, *clientmocks.Getter, *clientmocks.MockHTTP, *clientmocks.System) {
-
-	mockHTTP := &clientmocks.MockHTTP{}
-	mockHTTP.On("HealthCheckHTTP", mock.Anything, mock.Anything).Return(nil)
-
+func setupRun(t *testing.T) (*cobra.Command, *mocks.Engine, *clientmocks.Getter, *clientmocks.System) {
 	mockGetter := &clientmocks.Getter{}
 	mockGetter.On("Get", mock.Anything, mock.Anything).Return(nil)
 	mockGetter.On("SetForce", mock.Anything)
+	mockGetter.On("SetOffline", mock.Anything)
 
 	mockBrowser := &clientmocks.System{}
-	mockBrowser.On("OpenBrowser", mock.Anything).Return(nil)
 	mockBrowser.On("Preflight").Return(nil)
 	mockBrowser.On("CheckVersion", mock.Anything).Return("", false)
 
@@ -34,22 +31,22 @@ func setupRun(t *testing.T) (*cobra.Command, *mocks.Engine, *clientmocks.Getter,
 	mockTasks.On("SetForcePull", mock.Anything)
 
 	clients := &shipyard.Clients{
-		HTTP:           mockHTTP,
 		Getter:         mockGetter,
 		Browser:        mockBrowser,
 		ContainerTasks: mockTasks,
 	}
 
 	mockEngine := &mocks.Engine{}
-	mockEngine.On("Apply", mock.Anything).Return(nil, nil)
+	mockEngine.On("ApplyWithOptions", mock.Anything, mock.Anything).Return(nil, nil)
 	mockEngine.On("GetClients", mock.Anything).Return(clients)
+	mockEngine.On("Result").Return(&shipyard.Result{})
 	mockEngine.On("Blueprint").Return(&config.Blueprint{BrowserWindows: []string{"http://localhost", "http://localhost2"}})
 
-	return newRunCmd(mockEngine, mockGetter, mockHTTP, mockBrowser, hclog.Default()), mockEngine, mockGetter, mockHTTP, mockBrowser
+	return newRunCmd(mockEngine, mockGetter, mockBrowser), mockEngine, mockGetter, mockBrowser
 }
 
 func TestRunSetsForceOnGetter(t *testing.T) {
-	rf, _, mg, _, _ := setupRun(t)
+	rf, _, mg, _ := setupRun(t)
 	rf.Flags().Set("force-update", "true")
 
 	err := rf.Execute()
@@ -59,7 +56,7 @@ func TestRunSetsForceOnGetter(t *testing.T) {
 }
 
 func TestRunPreflightsSystem(t *testing.T) {
-	rf, _, _, _, mb := setupRun(t)
+	rf, _, _, mb := setupRun(t)
 	rf.SetArgs([]string{"/tmp"})
 
 	err := rf.Execute()
@@ -69,28 +66,28 @@ func TestRunPreflightsSystem(t *testing.T) {
 }
 
 func TestRunSetsDestinationFromArgsWhenPresent(t *testing.T) {
-	rf, me, _, _, _ := setupRun(t)
+	rf, me, _, _ := setupRun(t)
 	rf.SetArgs([]string{"/tmp"})
 
 	err := rf.Execute()
 	assert.NoError(t, err)
 
-	me.AssertCalled(t, "Apply", "/tmp")
+	me.AssertCalled(t, "ApplyWithOptions", "/tmp", mock.Anything)
 }
 
 func TestRunSetsDestinationToDownloadedBlueprintFromArgsWhenRemote(t *testing.T) {
-	rf, me, _, _, _ := setupRun(t)
+	rf, me, _, _ := setupRun(t)
 	rf.SetArgs([]string{"github.com/shipyard-run/blueprints//vault-k8s"})
 
 	err := rf.Execute()
 	assert.NoError(t, err)
 
-	me.AssertCalled(t, "Apply", filepath.Join(utils.ShipyardHome(), "blueprints/github.com/shipyard-run/blueprints/vault-k8s"))
+	me.AssertCalled(t, "ApplyWithOptions", filepath.Join(utils.ShipyardHome(), "blueprints/github.com/shipyard-run/blueprints/vault-k8s"), mock.Anything)
 }
 
 func TestRunFetchesBlueprint(t *testing.T) {
 	bpf := "github.com/shipyard-run/blueprints//vault-k8s"
-	rf, _, mg, _, _ := setupRun(t)
+	rf, _, mg, _ := setupRun(t)
 	rf.SetArgs([]string{bpf})
 
 	err := rf.Execute()
@@ -101,7 +98,7 @@ func TestRunFetchesBlueprint(t *testing.T) {
 
 func TestRunFetchesBlueprintErrorReturnsError(t *testing.T) {
 	bpf := "github.com/shipyard-run/blueprints//vault-k8s"
-	rf, _, mb, _, _ := setupRun(t)
+	rf, _, mb, _ := setupRun(t)
 	rf.SetArgs([]string{bpf})
 
 	removeOn(&mb.Mock, "Get")
@@ -111,62 +108,181 @@ func TestRunFetchesBlueprintErrorReturnsError(t *testing.T) {
 	assert.Error(t, err)
 }
 
-func TestRunOpensBrowserWindow(t *testing.T) {
-	rf, _, _, mh, mb := setupRun(t)
+func TestRunFetchesRemotePackageAndSignature(t *testing.T) {
+	uri := "https://example.com/consul" + utils.BlueprintPackageExtension
+	rf, me, mg, _ := setupRun(t)
+	rf.SetArgs([]string{uri})
+
+	mg.On("Fetch", uri).Return("/tmp/download", nil)
+	mg.On("Fetch", uri+shipyard.SignatureExtension).Return("", fmt.Errorf("not found"))
+
+	err := rf.Execute()
+	assert.NoError(t, err)
+
+	mg.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+	mg.AssertCalled(t, "Fetch", uri+shipyard.SignatureExtension)
+	me.AssertCalled(t, "ApplyWithOptions", filepath.Join("/tmp/download", "consul"+utils.BlueprintPackageExtension), mock.Anything)
+}
+
+func TestRunOpensBrowserWindowsByDefault(t *testing.T) {
+	rf, me, _, _ := setupRun(t)
+	rf.SetArgs([]string{"/tmp"})
+
+	err := rf.Execute()
+	assert.NoError(t, err)
+
+	me.AssertCalled(t, "ApplyWithOptions", "/tmp", shipyard.ApplyOptions{DisableBrowser: false})
+}
+
+func TestRunDisablesBrowserWindowsWithFlag(t *testing.T) {
+	rf, me, _, _ := setupRun(t)
+	rf.SetArgs([]string{"/tmp"})
+	rf.Flags().Set("no-browser", "true")
+
+	err := rf.Execute()
+	assert.NoError(t, err)
+
+	me.AssertCalled(t, "ApplyWithOptions", "/tmp", shipyard.ApplyOptions{DisableBrowser: true})
+}
+
+func TestRunEnablesCheckpointWithFlag(t *testing.T) {
+	rf, me, _, _ := setupRun(t)
+	rf.SetArgs([]string{"/tmp"})
+	rf.Flags().Set("checkpoint", "true")
+
+	err := rf.Execute()
+	assert.NoError(t, err)
+
+	me.AssertCalled(t, "ApplyWithOptions", "/tmp", shipyard.ApplyOptions{Checkpoint: true})
+}
+
+func TestRunSetsMaxParallelWithFlag(t *testing.T) {
+	rf, me, _, _ := setupRun(t)
+	rf.SetArgs([]string{"/tmp"})
+	rf.Flags().Set("max-parallel", "4")
+
+	err := rf.Execute()
+	assert.NoError(t, err)
+
+	me.AssertCalled(t, "ApplyWithOptions", "/tmp", shipyard.ApplyOptions{MaxParallel: 4})
+}
+
+func TestRunEnablesRollbackWithFlag(t *testing.T) {
+	rf, me, _, _ := setupRun(t)
 	rf.SetArgs([]string{"/tmp"})
+	rf.Flags().Set("rollback", "true")
 
 	err := rf.Execute()
 	assert.NoError(t, err)
 
-	mh.AssertNumberOfCalls(t, "HealthCheckHTTP", 2)
-	mb.AssertNumberOfCalls(t, "OpenBrowser", 2)
+	me.AssertCalled(t, "ApplyWithOptions", "/tmp", shipyard.ApplyOptions{Rollback: true})
 }
 
-func TestRunOpensBrowserWindowForResources(t *testing.T) {
-	rf, me, _, mh, mb := setupRun(t)
+func TestRunReplacesResourcesWithFlag(t *testing.T) {
+	rf, me, _, _ := setupRun(t)
 	rf.SetArgs([]string{"/tmp"})
+	rf.Flags().Set("replace", "container.consul,container.vault")
 
-	removeOn(&me.Mock, "Apply")
+	err := rf.Execute()
+	assert.NoError(t, err)
 
-	d := config.NewDocs("test")
-	d.OpenInBrowser = true
+	me.AssertCalled(t, "ApplyWithOptions", "/tmp", shipyard.ApplyOptions{Replace: []string{"container.consul", "container.vault"}})
+}
 
-	i := config.NewIngress("test")
-	i.Ports = []config.Port{config.Port{Host: "8080", OpenInBrowser: "/"}}
+func TestRunSetsTimeoutWithFlag(t *testing.T) {
+	rf, me, _, _ := setupRun(t)
+	rf.SetArgs([]string{"/tmp"})
+	rf.Flags().Set("timeout", "10m")
 
-	c := config.NewContainer("test")
-	c.Ports = []config.Port{config.Port{Host: "8080", OpenInBrowser: "/"}}
+	err := rf.Execute()
+	assert.NoError(t, err)
 
-	// should not be opened
-	d2 := config.NewDocs("test")
+	me.AssertCalled(t, "ApplyWithOptions", "/tmp", shipyard.ApplyOptions{Timeout: 10 * time.Minute})
+}
 
-	i2 := config.NewIngress("test")
-	i2.Ports = []config.Port{config.Port{Host: "8080", OpenInBrowser: ""}}
+func TestRunWithDryRunDoesNotPreflightSystem(t *testing.T) {
+	rf, me, _, mb := setupRun(t)
+	rf.Flags().Set("dry-run", "true")
+	rf.SetArgs([]string{"/tmp"})
 
-	c2 := config.NewContainer("test2")
-	c2.Ports = []config.Port{config.Port{OpenInBrowser: ""}}
+	err := rf.Execute()
+	assert.NoError(t, err)
 
-	me.On("Apply", mock.Anything).Return(
-		[]config.Resource{d, i, c, d2, i2, c2},
-		nil,
-	)
+	mb.AssertNotCalled(t, "Preflight")
+	me.AssertCalled(t, "ApplyWithOptions", "/tmp", shipyard.ApplyOptions{DryRun: true})
+}
+
+func TestRunWithDryRunPrintsResourceActions(t *testing.T) {
+	rf, me, _, _ := setupRun(t)
+	rf.Flags().Set("dry-run", "true")
+	rf.Flags().Set("replace", "container.consul")
+	rf.SetArgs([]string{"/tmp"})
+
+	out := bytes.NewBuffer(nil)
+	rf.SetOut(out)
+
+	removeOn(&me.Mock, "Result")
+	me.On("Result").Return(&shipyard.Result{
+		Resources: []shipyard.ResourceResult{
+			{Name: "consul", Type: "container", Status: "pending_modification", Action: shipyard.DryRunReplace},
+		},
+	})
 
 	err := rf.Execute()
 	assert.NoError(t, err)
 
-	mh.AssertNumberOfCalls(t, "HealthCheckHTTP", 5)
-	mb.AssertNumberOfCalls(t, "OpenBrowser", 5)
+	me.AssertCalled(t, "ApplyWithOptions", "/tmp", shipyard.ApplyOptions{DryRun: true, Replace: []string{"container.consul"}})
+	assert.Contains(t, out.String(), "container.consul (replace)")
 }
 
-func TestRunDoesNotOpensBrowserWindowWhenCheckError(t *testing.T) {
-	rf, _, _, mh, mb := setupRun(t)
+func TestRunApplyErrorReturnsError(t *testing.T) {
+	rf, me, _, _ := setupRun(t)
 	rf.SetArgs([]string{"/tmp"})
 
-	removeOn(&mh.Mock, "HealthCheckHTTP")
-	mh.On("HealthCheckHTTP", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+	removeOn(&me.Mock, "ApplyWithOptions")
+	me.On("ApplyWithOptions", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	err := rf.Execute()
+	assert.Error(t, err)
+}
+
+func TestRunWithOfflineAppliesOfflineAndDoesNotCheckVersion(t *testing.T) {
+	rf, me, mg, mb := setupRun(t)
+	rf.SetArgs([]string{"/tmp"})
+	rf.Flags().Set("offline", "true")
+
+	err := rf.Execute()
+	assert.NoError(t, err)
+
+	mg.AssertCalled(t, "SetOffline", true)
+	mb.AssertNotCalled(t, "CheckVersion", mock.Anything)
+	me.AssertCalled(t, "ApplyWithOptions", "/tmp", shipyard.ApplyOptions{Offline: true})
+}
+
+func TestRunWithBundleLoadsBundleAndSetsOffline(t *testing.T) {
+	rf, me, mg, _ := setupRun(t)
+	rf.SetArgs([]string{"/tmp"})
+	rf.Flags().Set("bundle", "/tmp/bundle.tar.gz")
+
+	me.On("LoadBundle", mock.Anything).Return(&shipyard.BundleManifest{}, nil)
 
 	err := rf.Execute()
 	assert.NoError(t, err)
 
-	mb.AssertNumberOfCalls(t, "OpenBrowser", 0)
+	me.AssertCalled(t, "LoadBundle", "/tmp/bundle.tar.gz")
+	mg.AssertCalled(t, "SetOffline", true)
+	me.AssertCalled(t, "ApplyWithOptions", "/tmp", shipyard.ApplyOptions{Offline: true})
+}
+
+func TestRunWithBundleReturnsErrorWhenLoadFails(t *testing.T) {
+	rf, me, _, _ := setupRun(t)
+	rf.SetArgs([]string{"/tmp"})
+	rf.Flags().Set("bundle", "/tmp/bundle.tar.gz")
+
+	me.On("LoadBundle", mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	err := rf.Execute()
+	assert.Error(t, err)
+
+	me.AssertNotCalled(t, "ApplyWithOptions", mock.Anything, mock.Anything)
 }
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/spf13/cobra"
+	"golang.org/x/xerrors"
+)
+
+// shareWait blocks until the user interrupts the process, replaced in tests
+var shareWait = func() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	<-c
+}
+
+func newShareCmd(t clients.Tunnel) *cobra.Command {
+	var provider string
+
+	shareCmd := &cobra.Command{
+		Use:   "share [ingress]",
+		Short: "Share ingress ports with remote users through a tunnel",
+		Long: `Share ingress ports with remote users through a tunnel.
+
+Each host port of the given ingress resources is exposed using an outbound
+tunnel and a public URL is printed which can be shared with remote users.
+When no resources are specified all ingress resources are shared. Tunnels
+remain open until the command is interrupted.
+
+The ssh provider forwards ports to the relay set by SHIPYARD_TUNNEL_RELAY,
+by default localhost.run, connecting as the user set by
+SHIPYARD_TUNNEL_RELAY_USER, by default nokey. The host key of the relay is
+recorded on the first connection and must not change, set
+SHIPYARD_TUNNEL_RELAY_HOST_KEY to the public key of the relay to only trust
+that key. The cloudflared and ngrok providers require the respective tools
+to be installed.`,
+		Example: `
+  # Share all ingress ports
+  yard share
+
+  # Share a single ingress using Cloudflare
+  yard share ingress.consul-http --provider cloudflared
+`,
+		Args:         cobra.ArbitraryArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sc := config.New()
+			err := sc.FromJSON(utils.StatePath())
+			if err != nil {
+				return xerrors.Errorf("No resources are running, start a stack with 'shipyard run [blueprint]'")
+			}
+
+			resources := []config.Resource{}
+			if len(args) == 0 {
+				for _, r := range sc.Resources {
+					if len(ingressPorts(r)) > 0 {
+						resources = append(resources, r)
+					}
+				}
+			}
+
+			for _, a := range args {
+				r, err := sc.FindResource(a)
+				if err != nil {
+					return xerrors.Errorf("Resource %s is not running", a)
+				}
+
+				if len(ingressPorts(r)) == 0 {
+					return xerrors.Errorf("Resource %s does not expose any host ports, only ingress resources can be shared", a)
+				}
+
+				resources = append(resources, r)
+			}
+
+			if len(resources) == 0 {
+				return xerrors.Errorf("No ingress resources with host ports are running")
+			}
+
+			stop := make(chan struct{})
+			defer close(stop)
+
+			fmt.Printf("Opening tunnels using %s, this may take a few seconds\n\n", provider)
+
+			for _, r := range resources {
+				for _, p := range ingressPorts(r) {
+					port, err := strconv.Atoi(p)
+					if err != nil {
+						return xerrors.Errorf("Invalid host port %s for %s.%s: %w", p, r.Info().Type, r.Info().Name, err)
+					}
+
+					u, err := t.Open(provider, port, stop)
+					if err != nil {
+						return xerrors.Errorf("Unable to share %s.%s port %d: %w", r.Info().Type, r.Info().Name, port, err)
+					}
+
+					fmt.Printf("%s.%s localhost:%d -> %s\n", r.Info().Type, r.Info().Name, port, u)
+				}
+			}
+
+			fmt.Println("\nPress Ctrl-C to close the tunnels")
+			shareWait()
+
+			return nil
+		},
+	}
+
+	shareCmd.Flags().StringVarP(&provider, "provider", "", clients.DefaultTunnelProvider, "Tunnel provider to use, one of ssh, cloudflared, or ngrok")
+
+	return shareCmd
+}
+
+// ingressPorts returns the host ports for ingress resources
+func ingressPorts(r config.Resource) []string {
+	ports := []config.Port{}
+
+	switch v := r.(type) {
+	case *config.Ingress:
+		ports = v.Ports
+	case *config.ContainerIngress:
+		ports = v.Ports
+	case *config.K8sIngress:
+		ports = v.Ports
+	case *config.NomadIngress:
+		ports = v.Ports
+	}
+
+	hp := []string{}
+	for _, p := range ports {
+		if p.Host != "" {
+			hp = append(hp, p.Host)
+		}
+	}
+
+	return hp
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupShare(state string) (*cobra.Command, *mocks.MockTunnel, func()) {
+	mt := &mocks.MockTunnel{}
+	mt.On("Open", mock.Anything, mock.Anything, mock.Anything).Return("https://abc.example.com", nil)
+
+	shareWait = func() {}
+
+	return newShareCmd(mt), mt, setupState(state)
+}
+
+func TestShareNoStateReturnsError(t *testing.T) {
+	c, _, cleanup := setupShare("")
+	defer cleanup()
+
+	err := c.Execute()
+	assert.Error(t, err)
+}
+
+func TestShareSharesAllIngressPorts(t *testing.T) {
+	c, mt, cleanup := setupShare(shareState)
+	defer cleanup()
+
+	c.SetArgs([]string{})
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	mt.AssertNumberOfCalls(t, "Open", 2)
+	mt.AssertCalled(t, "Open", "ssh", 18500, mock.Anything)
+	mt.AssertCalled(t, "Open", "ssh", 18200, mock.Anything)
+}
+
+func TestShareSharesSelectedIngressWithProvider(t *testing.T) {
+	c, mt, cleanup := setupShare(shareState)
+	defer cleanup()
+
+	c.SetArgs([]string{"k8s_ingress.vault-http", "--provider", "cloudflared"})
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	mt.AssertNumberOfCalls(t, "Open", 1)
+	mt.AssertCalled(t, "Open", "cloudflared", 18200, mock.Anything)
+}
+
+func TestShareNonIngressReturnsError(t *testing.T) {
+	c, mt, cleanup := setupShare(shareState)
+	defer cleanup()
+
+	c.SetArgs([]string{"network.dc1"})
+	err := c.Execute()
+	assert.Error(t, err)
+
+	mt.AssertNotCalled(t, "Open", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestShareTunnelErrorReturnsError(t *testing.T) {
+	c, mt, cleanup := setupShare(shareState)
+	defer cleanup()
+
+	removeOn(&mt.Mock, "Open")
+	mt.On("Open", mock.Anything, mock.Anything, mock.Anything).Return("", fmt.Errorf("boom"))
+
+	c.SetArgs([]string{})
+	err := c.Execute()
+	assert.Error(t, err)
+}
+
+var shareState = `
+{
+  "blueprint": null,
+  "resources": [
+    {
+      "name": "dc1",
+      "subnet": "10.15.0.0/16",
+      "status": "applied",
+      "type": "network"
+    },
+    {
+      "name": "consul-http",
+      "type": "container_ingress",
+      "status": "applied",
+      "target": "container.consul",
+      "ports": [
+        { "local": "8500", "remote": "8500", "host": "18500" }
+      ]
+    },
+    {
+      "name": "vault-http",
+      "type": "k8s_ingress",
+      "status": "applied",
+      "cluster": "k8s_cluster.k3s",
+      "service": "vault",
+      "ports": [
+        { "local": "8200", "remote": "8200", "host": "18200" },
+        { "local": "8201", "remote": "8201" }
+      ]
+    }
+  ]
+}
+`
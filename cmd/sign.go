@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+	"github.com/spf13/cobra"
+	"golang.org/x/xerrors"
+)
+
+func newSignCmd() *cobra.Command {
+	var key string
+
+	signCmd := &cobra.Command{
+		Use:   "sign [package]",
+		Short: "Sign a blueprint package so that it can be verified before it is run",
+		Long: `Sign a blueprint package so that it can be verified before it is run.
+
+The signature is written next to the package with the extension .minisig and
+is compatible with minisign. Anyone who adds the public key to their trusted
+keys folder can only run packages signed with the secret key, keys can be
+created with "yard sign keygen" or "minisign -G -W".`,
+		Example: `
+  # Create a key pair
+  yard sign keygen ./shipyard.key
+
+  # Sign a package with the secret key
+  yard sign ./consul.yardpack --key ./shipyard.key
+`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if key == "" {
+				return xerrors.Errorf("A secret key must be specified with --key")
+			}
+
+			err := shipyard.SignPackage(args[0], key)
+			if err != nil {
+				return xerrors.Errorf("Unable to sign package: %w", err)
+			}
+
+			fmt.Printf("Signature written to %s%s\n", args[0], shipyard.SignatureExtension)
+
+			return nil
+		},
+	}
+
+	signCmd.Flags().StringVarP(&key, "key", "", "", "Path to the minisign secret key used to sign the package")
+
+	keygenCmd := &cobra.Command{
+		Use:   "keygen [path]",
+		Short: "Create a key pair for signing blueprint packages",
+		Long: `Create a key pair for signing blueprint packages.
+
+The secret key is written to path and the public key to path.pub, copy the
+public key to the trusted keys folder, ` + shipyard.TrustedKeysDir() + `, of
+anyone who runs your packages. The secret key is not encrypted, keep it safe.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := shipyard.GenerateSigningKey(args[0])
+			if err != nil {
+				return xerrors.Errorf("Unable to create key: %w", err)
+			}
+
+			fmt.Printf("Secret key written to %s, public key written to %s.pub\n", args[0], args[0])
+
+			return nil
+		},
+	}
+
+	signCmd.AddCommand(keygenCmd)
+
+	return signCmd
+}
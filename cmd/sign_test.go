@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignCreatesKeyAndSignsPackage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	key := filepath.Join(dir, "shipyard.key")
+	pack := filepath.Join(dir, "consul.yardpack")
+	ioutil.WriteFile(pack, []byte("package"), 0644)
+
+	c := newSignCmd()
+	c.SetArgs([]string{"keygen", key})
+	err = c.Execute()
+	assert.NoError(t, err)
+
+	assert.FileExists(t, key)
+	assert.FileExists(t, key+".pub")
+
+	c = newSignCmd()
+	c.SetArgs([]string{pack, "--key", key})
+	err = c.Execute()
+	assert.NoError(t, err)
+
+	assert.FileExists(t, pack+shipyard.SignatureExtension)
+}
+
+func TestSignWithoutKeyReturnsError(t *testing.T) {
+	c := newSignCmd()
+	c.SetArgs([]string{"./consul.yardpack"})
+
+	err := c.Execute()
+	assert.Error(t, err)
+}
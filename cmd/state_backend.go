@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+)
+
+// stateBackendEnvVar sets the state backend when the --state-backend flag is
+// not used
+const stateBackendEnvVar = "SHIPYARD_STATE_BACKEND"
+
+var stateBackendFlag string
+
+// configureStateBackend stores the state in the backend set by the flag or
+// the environment, the backend overrides any backend defined by the blueprint
+func configureStateBackend(e shipyard.Engine, s string) error {
+	if s == "" {
+		s = os.Getenv(stateBackendEnvVar)
+	}
+
+	if s == "" {
+		return nil
+	}
+
+	c, err := parseStateBackend(s)
+	if err != nil {
+		return err
+	}
+
+	return e.SetStateBackend(c)
+}
+
+// parseStateBackend converts a backend URL e.g.
+// s3://bucket/key?region=eu-west-1&endpoint=http://localhost:9000,
+// consul://localhost:8500/key, or https://state.example.com/dev into the
+// config for the backend. Tokens are read from the environment so they are
+// not visible in the process list
+func parseStateBackend(s string) (*config.StateBackend, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid state backend %s: %s", s, err)
+	}
+
+	q := u.Query()
+
+	switch u.Scheme {
+	case "s3":
+		return &config.StateBackend{
+			Type:    "s3",
+			Bucket:  u.Host,
+			Key:     strings.TrimPrefix(u.Path, "/"),
+			Region:  q.Get("region"),
+			Address: q.Get("endpoint"),
+		}, nil
+
+	case "consul":
+		c := &config.StateBackend{Type: "consul", Key: strings.TrimPrefix(u.Path, "/")}
+		if u.Host != "" {
+			c.Address = "http://" + u.Host
+		}
+
+		return c, nil
+
+	case "http", "https":
+		return &config.StateBackend{Type: "http", Address: s}, nil
+	}
+
+	return nil, fmt.Errorf("Invalid state backend %s, must be an s3://, consul://, http://, or https:// URL", s)
+}
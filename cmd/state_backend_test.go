@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/shipyard/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStateBackendParsesS3(t *testing.T) {
+	c, err := parseStateBackend("s3://shipyard/dev/state.json?region=eu-west-1&endpoint=http://localhost:9000")
+	assert.NoError(t, err)
+
+	assert.Equal(t, &config.StateBackend{Type: "s3", Bucket: "shipyard", Key: "dev/state.json", Region: "eu-west-1", Address: "http://localhost:9000"}, c)
+}
+
+func TestParseStateBackendParsesConsul(t *testing.T) {
+	c, err := parseStateBackend("consul://localhost:8500/shipyard/dev")
+	assert.NoError(t, err)
+
+	assert.Equal(t, &config.StateBackend{Type: "consul", Key: "shipyard/dev", Address: "http://localhost:8500"}, c)
+}
+
+func TestParseStateBackendParsesHTTP(t *testing.T) {
+	c, err := parseStateBackend("https://state.example.com/dev")
+	assert.NoError(t, err)
+
+	assert.Equal(t, &config.StateBackend{Type: "http", Address: "https://state.example.com/dev"}, c)
+}
+
+func TestParseStateBackendReturnsErrorForUnknownScheme(t *testing.T) {
+	_, err := parseStateBackend("ftp://example.com/state")
+	assert.Error(t, err)
+}
+
+func TestConfigureStateBackendReadsEnvironment(t *testing.T) {
+	os.Setenv(stateBackendEnvVar, "consul://localhost:8500/shipyard/dev")
+	defer os.Unsetenv(stateBackendEnvVar)
+
+	me := &mocks.Engine{}
+	me.On("SetStateBackend", &config.StateBackend{Type: "consul", Key: "shipyard/dev", Address: "http://localhost:8500"}).Return(nil)
+
+	err := configureStateBackend(me, "")
+	assert.NoError(t, err)
+
+	me.AssertNumberOfCalls(t, "SetStateBackend", 1)
+}
+
+func TestConfigureStateBackendDoesNothingWhenNotSet(t *testing.T) {
+	me := &mocks.Engine{}
+
+	err := configureStateBackend(me, "")
+	assert.NoError(t, err)
+
+	me.AssertNotCalled(t, "SetStateBackend")
+}
@@ -6,7 +6,6 @@ import (
 
 	"github.com/hokaccha/go-prettyjson"
 	"github.com/shipyard-run/shipyard/pkg/config"
-	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -36,8 +35,7 @@ var statusCmd = &cobra.Command{
 	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		// load the stack
-		c := config.New()
-		err := c.FromJSON(utils.StatePath())
+		c, err := engine.State()
 		if err != nil {
 			fmt.Println("Unable to load state", err)
 			os.Exit(1)
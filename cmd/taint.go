@@ -4,8 +4,6 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/shipyard-run/shipyard/pkg/config"
-	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -23,24 +21,9 @@ var taintCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		c := config.New()
-		err := c.FromJSON(utils.StatePath())
+		err := engine.Taint(args[0])
 		if err != nil {
-			fmt.Println("Unable to load state", err)
-			os.Exit(1)
-		}
-
-		r, err := c.FindResource(args[0])
-		if err != nil || r == nil {
-			fmt.Println("Unable to locate resource in the state", args[0])
-			os.Exit(1)
-		}
-
-		r.Info().Status = config.PendingModification
-
-		err = c.ToJSON(utils.StatePath())
-		if err != nil {
-			fmt.Println("Unable to save state", err)
+			fmt.Println(err)
 			os.Exit(1)
 		}
 	},
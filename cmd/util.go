@@ -1,7 +1,105 @@
 package cmd
 
-import "github.com/hashicorp/go-hclog"
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/pkg/term"
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/ci"
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+	"github.com/shipyard-run/shipyard/pkg/tui"
+)
+
+// logOutput is the destination for log output, the terminal UI redirects
+// the log output while it is drawing
+var logOutput = &redirectWriter{w: os.Stderr}
 
 func createLogger() hclog.Logger {
-	return hclog.New(&hclog.LoggerOptions{Level: hclog.Debug, Color: hclog.AutoColor})
+	// the output is not a file so colors can not be detected by the logger
+	color := hclog.ColorOff
+	if term.IsTerminal(os.Stderr.Fd()) {
+		color = hclog.ForceColor
+	}
+
+	return hclog.New(&hclog.LoggerOptions{Level: hclog.Debug, Color: color, Output: logOutput})
+}
+
+// interruptContext returns a context which is cancelled when the process is
+// interrupted so running operations are stopped, the returned function
+// stops listening for the signals
+func interruptContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-c:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(c)
+		cancel()
+	}
+}
+
+// startTUI draws a live view of the resources created or destroyed by the
+// engine when running in an interactive terminal, the log output is shown
+// below the resources. The returned function stops the UI and restores
+// the log output
+func startTUI(e shipyard.Engine) func() {
+	if !term.IsTerminal(os.Stderr.Fd()) || ci.DetectFormat() != ci.None {
+		return func() {}
+	}
+
+	width := 0
+	if ws, err := term.GetWinsize(os.Stderr.Fd()); err == nil {
+		width = int(ws.Width)
+	}
+
+	r := tui.New(os.Stderr, width)
+	e.AddEventHandler(r.Handle)
+
+	prev := logOutput.set(r)
+	r.Start(250 * time.Millisecond)
+
+	return func() {
+		r.Stop()
+		logOutput.set(prev)
+	}
+}
+
+// redirectWriter forwards writes to a writer which can be replaced after
+// the logger has been created
+type redirectWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (r *redirectWriter) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.w.Write(p)
+}
+
+// set replaces the writer returning the previous writer
+func (r *redirectWriter) set(w io.Writer) io.Writer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prev := r.w
+	r.w = w
+
+	return prev
 }
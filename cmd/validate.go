@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+	"github.com/spf13/cobra"
+)
+
+func newValidateCmd(e shipyard.Engine) *cobra.Command {
+	validateCmd := &cobra.Command{
+		Use:   "validate [file] | [directory]",
+		Short: "Check a blueprint for errors without creating any resources",
+		Long: `Check a blueprint for errors without creating any resources.
+
+The blueprint is parsed and references, resource names, ports, and cluster
+drivers are checked. Docker is not required so blueprints can be validated
+in CI.`,
+		Example: `
+  # Validate the blueprint in the current folder
+  yard validate
+
+  # Validate a single file
+  yard validate ./my-stack/network.hcl
+`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dst := "./"
+			if len(args) == 1 {
+				dst = args[0]
+			}
+
+			err := e.Validate(dst)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("Blueprint is valid")
+
+			return nil
+		},
+	}
+
+	return validateCmd
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/shipyard/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestValidateUsesCurrentFolderByDefault(t *testing.T) {
+	me := &mocks.Engine{}
+	me.On("Validate", mock.Anything).Return(nil)
+
+	c := newValidateCmd(me)
+	c.SetArgs([]string{})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	me.AssertCalled(t, "Validate", "./")
+}
+
+func TestValidateReturnsErrorWhenBlueprintInvalid(t *testing.T) {
+	me := &mocks.Engine{}
+	me.On("Validate", mock.Anything).Return(fmt.Errorf("boom"))
+
+	c := newValidateCmd(me)
+	c.SetArgs([]string{"/tmp"})
+
+	err := c.Execute()
+	assert.Error(t, err)
+
+	me.AssertCalled(t, "Validate", "/tmp")
+}
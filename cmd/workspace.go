@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+	"github.com/spf13/cobra"
+)
+
+func newWorkspaceCmd(e shipyard.Engine) *cobra.Command {
+	workspaceCmd := &cobra.Command{
+		Use:   "workspace",
+		Short: "Manage workspaces which allow several environments to run side by side",
+		Long: `Manage workspaces which allow several environments to run side by side.
+
+Each workspace has its own state, resources in workspaces other than default
+are prefixed with the workspace name, networks use a separate subnet, and
+host ports are offset. The workspace can also be set with the environment
+variable SHIPYARD_WORKSPACE.`,
+		Example: `
+  # Create and select the staging workspace
+  yard workspace new staging
+  yard workspace select staging
+
+  # List the workspaces
+  yard workspace list
+
+  # Delete the staging workspace once its resources have been destroyed
+  yard workspace delete staging
+`,
+	}
+
+	workspaceCmd.AddCommand(&cobra.Command{
+		Use:          "list",
+		Short:        "List the workspaces, the current workspace is marked with *",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, err := e.Workspaces()
+			if err != nil {
+				return err
+			}
+
+			for _, w := range ws {
+				current := " "
+				if w.Current {
+					current = "*"
+				}
+
+				fmt.Printf("%s %s (%d resources)\n", current, w.Name, w.Resources)
+			}
+
+			return nil
+		},
+	})
+
+	workspaceCmd.AddCommand(&cobra.Command{
+		Use:          "new [name]",
+		Short:        "Create a workspace",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := e.CreateWorkspace(args[0])
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Created workspace %s\n", args[0])
+
+			return nil
+		},
+	})
+
+	workspaceCmd.AddCommand(&cobra.Command{
+		Use:          "select [name]",
+		Short:        "Select the workspace used by other commands",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := e.SelectWorkspace(args[0])
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Switched to workspace %s\n", args[0])
+
+			return nil
+		},
+	})
+
+	workspaceCmd.AddCommand(&cobra.Command{
+		Use:          "delete [name]",
+		Short:        "Delete a workspace, the resources in the workspace must be destroyed first",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := e.DeleteWorkspace(args[0])
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Deleted workspace %s\n", args[0])
+
+			return nil
+		},
+	})
+
+	return workspaceCmd
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+	"github.com/shipyard-run/shipyard/pkg/shipyard/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWorkspaceListCallsWorkspaces(t *testing.T) {
+	me := &mocks.Engine{}
+	me.On("Workspaces").Return([]shipyard.Workspace{{Name: "default", Current: true}}, nil)
+
+	c := newWorkspaceCmd(me)
+	c.SetArgs([]string{"list"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	me.AssertCalled(t, "Workspaces")
+}
+
+func TestWorkspaceNewCallsCreateWorkspace(t *testing.T) {
+	me := &mocks.Engine{}
+	me.On("CreateWorkspace", mock.Anything).Return(nil)
+
+	c := newWorkspaceCmd(me)
+	c.SetArgs([]string{"new", "staging"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	me.AssertCalled(t, "CreateWorkspace", "staging")
+}
+
+func TestWorkspaceSelectCallsSelectWorkspace(t *testing.T) {
+	me := &mocks.Engine{}
+	me.On("SelectWorkspace", mock.Anything).Return(nil)
+
+	c := newWorkspaceCmd(me)
+	c.SetArgs([]string{"select", "staging"})
+
+	err := c.Execute()
+	assert.NoError(t, err)
+
+	me.AssertCalled(t, "SelectWorkspace", "staging")
+}
+
+func TestWorkspaceDeleteReturnsError(t *testing.T) {
+	me := &mocks.Engine{}
+	me.On("DeleteWorkspace", mock.Anything).Return(fmt.Errorf("boom"))
+
+	c := newWorkspaceCmd(me)
+	c.SetArgs([]string{"delete", "staging"})
+
+	err := c.Execute()
+	assert.Error(t, err)
+
+	me.AssertCalled(t, "DeleteWorkspace", "staging")
+}
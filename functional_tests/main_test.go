@@ -91,7 +91,7 @@ func FeatureContext(s *godog.Suite) {
 func iRunApply(config string) error {
 	// create the clients
 	var err error
-	currentClients, err = shipyard.GenerateClients(hclog.Default())
+	currentClients, err = shipyard.GenerateClients(hclog.Default(), shipyard.DockerConfig())
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,123 @@
+package ci
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+)
+
+// Format is the annotation syntax understood by a CI system
+type Format string
+
+// None disables annotations
+const None Format = ""
+
+// GitHub uses GitHub Actions workflow commands
+const GitHub Format = "github"
+
+// GitLab uses GitLab CI collapsible log sections
+const GitLab Format = "gitlab"
+
+// DetectFormat returns the annotation format for the CI system the
+// process is running in, None is returned when not running in CI
+func DetectFormat() Format {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return GitHub
+	case os.Getenv("GITLAB_CI") == "true":
+		return GitLab
+	}
+
+	return None
+}
+
+// Annotator writes CI annotations and log grouping markers for engine events,
+// the log output for each resource is wrapped in a collapsible group and
+// failures are reported as errors which reference the failing resource.
+// Resources are created in parallel so the output of resources which run
+// at the same time may appear in the same group
+type Annotator struct {
+	w      io.Writer
+	format Format
+	now    func() time.Time
+}
+
+// NewAnnotator creates an Annotator which writes to w in the given format
+func NewAnnotator(w io.Writer, f Format) *Annotator {
+	return &Annotator{w: w, format: f, now: time.Now}
+}
+
+// Handle is an engine EventHandler
+func (a *Annotator) Handle(e shipyard.Event) {
+	// only resources are annotated
+	if e.Resource == nil {
+		return
+	}
+
+	title := fmt.Sprintf("%s %s.%s", e.Action, e.Resource.Info().Type, e.Resource.Info().Name)
+
+	switch a.format {
+	case GitHub:
+		a.github(e, title)
+	case GitLab:
+		a.gitlab(e, title)
+	}
+}
+
+func (a *Annotator) github(e shipyard.Event, title string) {
+	switch e.Phase {
+	case shipyard.EventResourceStarted:
+		fmt.Fprintf(a.w, "::group::%s\n", githubEscapeData(title))
+
+	case shipyard.EventResourceCompleted:
+		fmt.Fprintf(a.w, "::endgroup::\n")
+
+	case shipyard.EventResourceFailed:
+		fmt.Fprintf(a.w, "::endgroup::\n")
+		fmt.Fprintf(
+			a.w,
+			"::error title=%s::%s\n",
+			githubEscapeProperty(title+" failed"),
+			githubEscapeData(fmt.Sprint(e.Error)),
+		)
+	}
+}
+
+var gitlabSectionChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+func (a *Annotator) gitlab(e shipyard.Event, title string) {
+	// section names must be unique and only contain a restricted set of characters
+	name := gitlabSectionChars.ReplaceAllString(strings.Replace(title, " ", "_", -1), "_")
+	ts := a.now().Unix()
+
+	switch e.Phase {
+	case shipyard.EventResourceStarted:
+		fmt.Fprintf(a.w, "\x1b[0Ksection_start:%d:%s[collapsed=true]\r\x1b[0K%s\n", ts, name, title)
+
+	case shipyard.EventResourceCompleted:
+		fmt.Fprintf(a.w, "\x1b[0Ksection_end:%d:%s\r\x1b[0K\n", ts, name)
+
+	case shipyard.EventResourceFailed:
+		fmt.Fprintf(a.w, "\x1b[0Ksection_end:%d:%s\r\x1b[0K\n", ts, name)
+		fmt.Fprintf(a.w, "\x1b[31;1mERROR: %s failed: %s\x1b[0m\n", title, e.Error)
+	}
+}
+
+// githubEscapeData escapes the message of a workflow command
+func githubEscapeData(s string) string {
+	s = strings.Replace(s, "%", "%25", -1)
+	s = strings.Replace(s, "\r", "%0D", -1)
+	return strings.Replace(s, "\n", "%0A", -1)
+}
+
+// githubEscapeProperty escapes a property value of a workflow command
+func githubEscapeProperty(s string) string {
+	s = githubEscapeData(s)
+	s = strings.Replace(s, ":", "%3A", -1)
+	return strings.Replace(s, ",", "%2C", -1)
+}
@@ -0,0 +1,94 @@
+package ci
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupAnnotator(f Format) (*Annotator, *bytes.Buffer) {
+	b := &bytes.Buffer{}
+	a := NewAnnotator(b, f)
+	a.now = func() time.Time { return time.Unix(1600000000, 0) }
+
+	return a, b
+}
+
+func testEvent(p shipyard.EventPhase, err error) shipyard.Event {
+	return shipyard.Event{Action: "apply", Phase: p, Resource: config.NewContainer("consul"), Error: err}
+}
+
+func TestDetectFormatReturnsGitHub(t *testing.T) {
+	os.Setenv("GITHUB_ACTIONS", "true")
+	defer os.Unsetenv("GITHUB_ACTIONS")
+
+	assert.Equal(t, GitHub, DetectFormat())
+}
+
+func TestDetectFormatReturnsGitLab(t *testing.T) {
+	os.Setenv("GITLAB_CI", "true")
+	defer os.Unsetenv("GITLAB_CI")
+
+	assert.Equal(t, GitLab, DetectFormat())
+}
+
+func TestGitHubWritesGroups(t *testing.T) {
+	a, b := setupAnnotator(GitHub)
+
+	a.Handle(testEvent(shipyard.EventResourceStarted, nil))
+	a.Handle(testEvent(shipyard.EventResourceCompleted, nil))
+
+	assert.Equal(t, "::group::apply container.consul\n::endgroup::\n", b.String())
+}
+
+func TestGitHubWritesEscapedErrorOnFailure(t *testing.T) {
+	a, b := setupAnnotator(GitHub)
+
+	a.Handle(testEvent(shipyard.EventResourceFailed, fmt.Errorf("boom: 100%%\nexit 1")))
+
+	assert.Equal(t, "::endgroup::\n::error title=apply container.consul failed::boom: 100%25%0Aexit 1\n", b.String())
+}
+
+func TestGitLabWritesSections(t *testing.T) {
+	a, b := setupAnnotator(GitLab)
+
+	a.Handle(testEvent(shipyard.EventResourceStarted, nil))
+	a.Handle(testEvent(shipyard.EventResourceCompleted, nil))
+
+	assert.Equal(
+		t,
+		"\x1b[0Ksection_start:1600000000:apply_container.consul[collapsed=true]\r\x1b[0Kapply container.consul\n"+
+			"\x1b[0Ksection_end:1600000000:apply_container.consul\r\x1b[0K\n",
+		b.String(),
+	)
+}
+
+func TestGitLabWritesErrorOnFailure(t *testing.T) {
+	a, b := setupAnnotator(GitLab)
+
+	a.Handle(testEvent(shipyard.EventResourceFailed, fmt.Errorf("boom")))
+
+	assert.Contains(t, b.String(), "ERROR: apply container.consul failed: boom")
+}
+
+func TestNoneWritesNothing(t *testing.T) {
+	a, b := setupAnnotator(None)
+
+	a.Handle(testEvent(shipyard.EventResourceFailed, fmt.Errorf("boom")))
+
+	assert.Empty(t, b.String())
+}
+
+func TestOperationEventsAreNotAnnotated(t *testing.T) {
+	a, b := setupAnnotator(GitHub)
+
+	a.Handle(shipyard.Event{Action: "apply", Phase: shipyard.EventOperationStarted})
+
+	assert.Empty(t, b.String())
+}
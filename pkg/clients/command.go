@@ -1,16 +1,41 @@
 package clients
 
 import (
+	"bytes"
+	"context"
+	"io"
+	"os"
 	"os/exec"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
+	"golang.org/x/xerrors"
 )
 
+// Command defines an interface for executing local commands
 type Command interface {
-	Execute(string, ...string) error
+	Execute(ctx context.Context, config CommandConfig) error
 }
 
+// CommandConfig defines the command to execute
+type CommandConfig struct {
+	Command          string
+	Arguments        []string
+	Env              []string      // environment variables in the form key=value, added to the current environment
+	WorkingDirectory string        // directory to run the command in, defaults to the current directory
+	Timeout          time.Duration // maximum time for each attempt, defaults to the clients timeout
+	Retries          int           // number of times to retry a failed command
+	RetryDelay       time.Duration // interval between attempts, defaults to one second
+	Output           io.Writer     // optional writer which receives the standard output of the command
+	Name             string        // optional name of the resource running the command, added to each line of logged output
+	TTY              bool          // run the command attached to a pseudo-terminal, standard error is combined with standard out
+	Stdin            io.Reader     // optional reader which is proxied to the standard input of the command
+}
+
+// defaultRetryDelay is the interval between attempts when a command fails
+const defaultRetryDelay = 1 * time.Second
+
 // Command executes local commands
 type CommandImpl struct {
 	timeout time.Duration
@@ -19,41 +44,167 @@ type CommandImpl struct {
 
 // NewCommand creates a new command with the given logger and maximum command time
 func NewCommand(maxCommandTime time.Duration, l hclog.Logger) Command {
-	return &CommandImpl{maxCommandTime, l}
+	return &CommandImpl{timeout: maxCommandTime, log: l}
+}
+
+// Execute the given command, when the command fails it is retried up to
+// config.Retries times, running commands are killed when ctx is cancelled
+func (c *CommandImpl) Execute(ctx context.Context, config CommandConfig) error {
+	if config.Retries == 0 {
+		return c.execute(ctx, config)
+	}
+
+	delay := config.RetryDelay
+	if delay == 0 {
+		delay = defaultRetryDelay
+	}
+
+	// only the output of the last attempt is written to the callers output
+	output := config.Output
+	attempt := &bytes.Buffer{}
+	if output != nil {
+		config.Output = attempt
+	}
+
+	var err error
+
+	for i := 0; i <= config.Retries; i++ {
+		if i > 0 {
+			c.log.Debug("Retrying command", "command", config.Command, "attempt", i+1, "error", err)
+
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return xerrors.Errorf("Command cancelled: %w", ctx.Err())
+			}
+		}
+
+		attempt.Reset()
+
+		err = c.execute(ctx, config)
+		if err == nil || ctx.Err() != nil {
+			// do not retry commands which were cancelled
+			break
+		}
+	}
+
+	if output != nil {
+		output.Write(attempt.Bytes())
+	}
+
+	return err
 }
 
-// Execute the given command
-func (c *CommandImpl) Execute(command string, args ...string) error {
+func (c *CommandImpl) execute(ctx context.Context, config CommandConfig) error {
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = c.timeout
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-	cmd := exec.Command(
-		command,
-		args...,
+	cmd := exec.CommandContext(
+		tctx,
+		config.Command,
+		config.Arguments...,
 	)
 
-	// set the standard out and error to the logger
-	cmd.Stdout = c.log.StandardWriter(&hclog.StandardLoggerOptions{InferLevels: true})
-	cmd.Stderr = c.log.StandardWriter(&hclog.StandardLoggerOptions{InferLevels: true})
+	cmd.Dir = config.WorkingDirectory
+	cmd.Env = append(os.Environ(), config.Env...)
 
-	/*
-		// wait for timeout
-		t := time.AfterFunc(c.timeout, func() {
-			// kill the running process
-			cmd.Process.Kill()
-			return fmt.Errorf("Command timed out before completing")
-		})
-	*/
+	// stream the standard out and error to the logger as each line is written
+	stdout := newLineLogger(c.log, config.Name, "stdout")
+	stderr := newLineLogger(c.log, config.Name, "stderr")
+
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if config.Output != nil {
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, config.Output)
+	}
 
-	err := cmd.Run()
-	if err != nil {
-		return err
+	var err error
+	if config.TTY {
+		err = runWithPTY(cmd, config.Stdin, cmd.Stdout)
+	} else {
+		cmd.Stdin = config.Stdin
+		err = cmd.Run()
 	}
 
-	// command has completed clear the timeout timer
-	/*
-		if t!= nil {
-			t.Stop()
+	stdout.Flush()
+	stderr.Flush()
+
+	if ctx.Err() != nil {
+		return xerrors.Errorf("Command cancelled: %w", ctx.Err())
+	}
+
+	if tctx.Err() == context.DeadlineExceeded {
+		return xerrors.Errorf("Command timed out after %s", timeout)
+	}
+
+	// add the last lines written to standard error so failures can be debugged
+	// without re-running with debug logging
+	if err != nil && len(stderr.tail) > 0 {
+		return xerrors.Errorf("Command failed with output %q: %w", strings.Join(stderr.tail, "\n"), err)
+	}
+
+	return err
+}
+
+// maxTailLines is the number of lines of standard error returned with a failed command
+const maxTailLines = 10
+
+// lineLogger is an io.Writer which writes each complete line to the logger
+// tagged with the resource name and stream, the most recent lines are kept
+// so that they can be returned with any error
+type lineLogger struct {
+	log    hclog.Logger
+	name   string
+	stream string
+	buf    bytes.Buffer
+	tail   []string
+}
+
+func newLineLogger(l hclog.Logger, name, stream string) *lineLogger {
+	return &lineLogger{log: l, name: name, stream: stream}
+}
+
+// Write implements io.Writer, partial lines are buffered until
+// the line is complete or the writer is flushed
+func (l *lineLogger) Write(p []byte) (int, error) {
+	l.buf.Write(p)
+
+	for {
+		i := bytes.IndexByte(l.buf.Bytes(), '\n')
+		if i < 0 {
+			break
 		}
-	*/
 
-	return nil
+		line := string(l.buf.Next(i + 1))
+		l.logLine(strings.TrimRight(line, "\r\n"))
+	}
+
+	return len(p), nil
+}
+
+// Flush writes any remaining partial line to the logger
+func (l *lineLogger) Flush() {
+	if l.buf.Len() > 0 {
+		l.logLine(l.buf.String())
+		l.buf.Reset()
+	}
+}
+
+func (l *lineLogger) logLine(line string) {
+	if l.name != "" {
+		l.log.Info(line, "ref", l.name, "stream", l.stream)
+	} else {
+		l.log.Info(line, "stream", l.stream)
+	}
+
+	l.tail = append(l.tail, line)
+	if len(l.tail) > maxTailLines {
+		l.tail = l.tail[1:]
+	}
 }
@@ -0,0 +1,100 @@
+//go:build !windows
+// +build !windows
+
+package clients
+
+import (
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/creack/pty"
+	"github.com/docker/docker/pkg/term"
+	"golang.org/x/sys/unix"
+	"golang.org/x/xerrors"
+)
+
+// runWithPTY runs the command attached to a pseudo-terminal, input is copied
+// to the terminal and the combined output of the command is written to out
+func runWithPTY(cmd *exec.Cmd, in io.Reader, out io.Writer) error {
+	// the standard streams must be unset so that they are attached to the terminal
+	cmd.Stdin = nil
+	cmd.Stdout = nil
+	cmd.Stderr = nil
+
+	f, err := pty.Start(cmd)
+	if err != nil {
+		return xerrors.Errorf("Unable to start command with a pseudo-terminal: %w", err)
+	}
+	defer f.Close()
+
+	if in != nil {
+		// when the input is the users terminal put it in raw mode so that
+		// keystrokes are sent directly to the command and are not echoed twice
+		if fd, isTerminal := term.GetFdInfo(in); isTerminal {
+			if file, ok := in.(*os.File); ok {
+				pty.InheritSize(file, f)
+			}
+
+			state, err := term.SetRawTerminal(fd)
+			if err == nil {
+				defer term.RestoreTerminal(fd, state)
+			}
+		}
+
+		stop := copyInput(f, in)
+		defer stop()
+	}
+
+	// reading from the terminal returns an error once the command
+	// exits and the terminal is closed
+	io.Copy(out, f)
+
+	return cmd.Wait()
+}
+
+// copyInput copies in to the terminal until the returned function is called.
+// A read from a file blocks until there is input, so files are read through a
+// non-blocking duplicate of the descriptor which can be closed to interrupt
+// the read when the command exits, otherwise the next keystroke would be
+// swallowed. Other readers stop at the first read after the command exits as
+// the write to the closed terminal fails
+func copyInput(f *os.File, in io.Reader) func() {
+	file, ok := in.(*os.File)
+	if !ok {
+		go io.Copy(f, in)
+		return func() {}
+	}
+
+	fd := int(file.Fd())
+
+	flags, err := unix.FcntlInt(uintptr(fd), unix.F_GETFL, 0)
+	if err != nil {
+		go io.Copy(f, in)
+		return func() {}
+	}
+
+	dup, err := unix.Dup(fd)
+	if err != nil {
+		go io.Copy(f, in)
+		return func() {}
+	}
+
+	// the duplicate shares the file status flags with the original so the
+	// original mode is restored once the copy has stopped
+	unix.SetNonblock(dup, true)
+	nb := os.NewFile(uintptr(dup), file.Name())
+
+	done := make(chan struct{})
+	go func() {
+		io.Copy(f, nb)
+		close(done)
+	}()
+
+	return func() {
+		nb.Close()
+		<-done
+
+		unix.SetNonblock(fd, flags&unix.O_NONBLOCK != 0)
+	}
+}
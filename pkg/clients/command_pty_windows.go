@@ -0,0 +1,13 @@
+package clients
+
+import (
+	"io"
+	"os/exec"
+
+	"golang.org/x/xerrors"
+)
+
+// runWithPTY is not supported on Windows as it does not have pseudo-terminals
+func runWithPTY(cmd *exec.Cmd, in io.Reader, out io.Writer) error {
+	return xerrors.Errorf("Running commands with a TTY is not supported on Windows")
+}
@@ -1,10 +1,17 @@
 package clients
 
 import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
 )
 
 func setupExecute(t *testing.T) Command {
@@ -15,5 +22,186 @@ func TestExecuteWithBasicParams(t *testing.T) {
 	t.Skip()
 	e := setupExecute(t)
 
-	e.Execute("ls")
+	e.Execute(context.Background(), CommandConfig{Command: "ls"})
+}
+
+func TestExecuteWritesOutput(t *testing.T) {
+	e := setupExecute(t)
+	out := bytes.NewBufferString("")
+
+	err := e.Execute(context.Background(), CommandConfig{Command: "echo", Arguments: []string{"hello"}, Output: out})
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n", out.String())
+}
+
+func TestExecuteSetsEnvAndWorkingDirectory(t *testing.T) {
+	e := setupExecute(t)
+	out := bytes.NewBufferString("")
+
+	err := e.Execute(context.Background(), CommandConfig{
+		Command:          "sh",
+		Arguments:        []string{"-c", "echo $FOO && pwd"},
+		Env:              []string{"FOO=bar"},
+		WorkingDirectory: "/",
+		Output:           out,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "bar\n/\n", out.String())
+}
+
+func TestExecuteTimesOut(t *testing.T) {
+	e := setupExecute(t)
+
+	err := e.Execute(context.Background(), CommandConfig{Command: "sleep", Arguments: []string{"10"}, Timeout: 10 * time.Millisecond})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+}
+
+func TestExecuteStopsWhenContextCancelled(t *testing.T) {
+	e := setupExecute(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	st := time.Now()
+	err := e.Execute(ctx, CommandConfig{Command: "sleep", Arguments: []string{"10"}, Retries: 2})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cancelled")
+	assert.Less(t, time.Since(st).Seconds(), 5.0)
+}
+
+func TestExecuteRetriesFailedCommand(t *testing.T) {
+	e := setupExecute(t)
+	out := bytes.NewBufferString("")
+
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	count := filepath.Join(dir, "count")
+
+	err = e.Execute(context.Background(), CommandConfig{Command: "sh", Arguments: []string{"-c", "echo attempt >> " + count + " && echo attempt && exit 1"}, Retries: 2, RetryDelay: time.Millisecond, Output: out})
+	assert.Error(t, err)
+
+	d, _ := ioutil.ReadFile(count)
+	assert.Equal(t, "attempt\nattempt\nattempt\n", string(d))
+
+	// only the output of the last attempt is returned
+	assert.Equal(t, "attempt\n", out.String())
+}
+
+func TestExecuteWaitsBetweenRetries(t *testing.T) {
+	e := setupExecute(t)
+
+	st := time.Now()
+	err := e.Execute(context.Background(), CommandConfig{Command: "sh", Arguments: []string{"-c", "exit 1"}, Retries: 2, RetryDelay: 100 * time.Millisecond})
+	assert.Error(t, err)
+	assert.GreaterOrEqual(t, time.Since(st).Milliseconds(), int64(200))
+}
+
+func TestExecuteReturnsOutputOfSuccessfulRetry(t *testing.T) {
+	e := setupExecute(t)
+	out := bytes.NewBufferString("")
+
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// fails on the first attempt and succeeds on the second
+	flag := filepath.Join(dir, "flag")
+	script := "if [ -f " + flag + " ]; then echo success; else touch " + flag + " && echo failed && exit 1; fi"
+
+	err = e.Execute(context.Background(), CommandConfig{Command: "sh", Arguments: []string{"-c", script}, Retries: 2, RetryDelay: time.Millisecond, Output: out})
+	assert.NoError(t, err)
+	assert.Equal(t, "success\n", out.String())
+}
+
+func TestExecuteLogsEachLineWithResourceName(t *testing.T) {
+	logs := bytes.NewBufferString("")
+	e := NewCommand(30*time.Second, hclog.New(&hclog.LoggerOptions{Output: logs}))
+
+	err := e.Execute(context.Background(), CommandConfig{Command: "sh", Arguments: []string{"-c", "echo one && echo two >&2"}, Name: "setup"})
+	assert.NoError(t, err)
+
+	assert.Contains(t, logs.String(), "one: ref=setup stream=stdout")
+	assert.Contains(t, logs.String(), "two: ref=setup stream=stderr")
+}
+
+func TestExecuteReturnsErrorOutputOnFailure(t *testing.T) {
+	e := setupExecute(t)
+
+	err := e.Execute(context.Background(), CommandConfig{Command: "sh", Arguments: []string{"-c", "echo boom >&2 && exit 1"}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+}
+
+func TestLineLoggerBuffersPartialLines(t *testing.T) {
+	logs := bytes.NewBufferString("")
+	l := newLineLogger(hclog.New(&hclog.LoggerOptions{Output: logs}), "", "stdout")
+
+	l.Write([]byte("hel"))
+	assert.Empty(t, l.tail)
+
+	l.Write([]byte("lo\nwor"))
+	assert.Equal(t, []string{"hello"}, l.tail)
+
+	l.Flush()
+	assert.Equal(t, []string{"hello", "wor"}, l.tail)
+}
+
+func TestLineLoggerKeepsLastLines(t *testing.T) {
+	l := newLineLogger(hclog.NewNullLogger(), "", "stderr")
+
+	for i := 0; i < maxTailLines+5; i++ {
+		l.Write([]byte("line\n"))
+	}
+
+	assert.Len(t, l.tail, maxTailLines)
+}
+
+func TestExecuteWithTTYRunsInTerminal(t *testing.T) {
+	e := setupExecute(t)
+	out := bytes.NewBufferString("")
+
+	err := e.Execute(context.Background(), CommandConfig{Command: "sh", Arguments: []string{"-c", "test -t 0 && test -t 1 && echo tty"}, TTY: true, Output: out})
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "tty")
+}
+
+func TestExecuteWithTTYProxiesStdin(t *testing.T) {
+	e := setupExecute(t)
+	out := bytes.NewBufferString("")
+
+	err := e.Execute(context.Background(), CommandConfig{Command: "sh", Arguments: []string{"-c", "read name && echo hello $name"}, TTY: true, Stdin: strings.NewReader("nic\n"), Output: out})
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "hello nic")
+}
+
+func TestExecuteWithTTYStopsReadingStdinWhenCommandExits(t *testing.T) {
+	e := setupExecute(t)
+
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	err = e.Execute(context.Background(), CommandConfig{Command: "true", TTY: true, Stdin: r, Output: bytes.NewBufferString("")})
+	assert.NoError(t, err)
+
+	// input written after the command exits must not be consumed
+	w.Write([]byte("x"))
+
+	d := make([]byte, 1)
+	_, err = r.Read(d)
+	assert.NoError(t, err)
+	assert.Equal(t, "x", string(d))
+}
+
+func TestExecuteWithoutTTYProxiesStdin(t *testing.T) {
+	e := setupExecute(t)
+	out := bytes.NewBufferString("")
+
+	err := e.Execute(context.Background(), CommandConfig{Command: "cat", Stdin: strings.NewReader("hello\n"), Output: out})
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n", out.String())
 }
@@ -0,0 +1,21 @@
+package clients
+
+import "context"
+
+// configHashLabel is added to containers with the hash of the definition of
+// the resource which created them
+const configHashLabel = "run.shipyard.config_hash"
+
+type configHashKey struct{}
+
+// WithConfigHash returns a copy of ctx where the containers created by
+// CreateContainer are labelled with hash
+func WithConfigHash(ctx context.Context, hash string) context.Context {
+	return context.WithValue(ctx, configHashKey{}, hash)
+}
+
+// configHash returns the hash set on ctx with WithConfigHash
+func configHash(ctx context.Context) string {
+	h, _ := ctx.Value(configHashKey{}).(string)
+	return h
+}
@@ -0,0 +1,210 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"golang.org/x/xerrors"
+)
+
+// Consul defines an interface for a Consul client
+type Consul interface {
+	// PassingInstances returns the number of instances of the service registered
+	// with the Consul agent at address which have all their health checks passing
+	PassingInstances(address, token, service string) (int, error)
+	// HealthCheckServices blocks until every service has at least one instance
+	// registered with all health checks passing or the timeout elapses
+	HealthCheckServices(ctx context.Context, address, token string, services []string, timeout time.Duration) error
+	// GetKV returns the value of the key from the Consul KV store at address,
+	// nil is returned when the key does not exist
+	GetKV(address, token, key string) ([]byte, error)
+	// PutKV sets the value of the key in the Consul KV store at address
+	PutKV(address, token, key string, value []byte) error
+	// DeleteKV removes the key from the Consul KV store at address
+	DeleteKV(address, token, key string) error
+	// CreateKV sets the value of the key in the Consul KV store at address
+	// only when the key does not exist, false is returned when the key exists
+	CreateKV(address, token, key string, value []byte) (bool, error)
+}
+
+// ConsulImpl is an implementation of the Consul interface
+type ConsulImpl struct {
+	httpClient HTTP
+	backoff    time.Duration
+	l          hclog.Logger
+}
+
+// NewConsul creates a new Consul client
+func NewConsul(c HTTP, backoff time.Duration, l hclog.Logger) Consul {
+	return &ConsulImpl{c, backoff, l}
+}
+
+// PassingInstances returns the number of passing instances for the service
+func (c *ConsulImpl) PassingInstances(address, token, service string) (int, error) {
+	u := fmt.Sprintf("%s/v1/health/service/%s?passing=true", strings.TrimSuffix(address, "/"), url.PathEscape(service))
+
+	r, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return 0, xerrors.Errorf("Unable to create http request: %w", err)
+	}
+
+	if token != "" {
+		r.Header.Set("X-Consul-Token", token)
+	}
+
+	resp, err := c.httpClient.Do(r)
+	if err != nil {
+		return 0, xerrors.Errorf("Unable to get health for service %s: %w", service, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		d, _ := ioutil.ReadAll(resp.Body)
+		return 0, xerrors.Errorf("Error getting health for service %s, got status code %d, error: %s", service, resp.StatusCode, string(d))
+	}
+
+	instances := []map[string]interface{}{}
+	err = json.NewDecoder(resp.Body).Decode(&instances)
+	if err != nil {
+		return 0, xerrors.Errorf("Unable to decode health for service %s: %w", service, err)
+	}
+
+	return len(instances), nil
+}
+
+// HealthCheckServices polls the Consul health API until every service has a passing instance
+func (c *ConsulImpl) HealthCheckServices(ctx context.Context, address, token string, services []string, timeout time.Duration) error {
+	c.l.Debug("Performing Consul health check for services", "address", address, "services", services)
+
+	st := time.Now()
+	for {
+		pending := []string{}
+		for _, s := range services {
+			i, err := c.PassingInstances(address, token, s)
+			if err != nil {
+				c.l.Debug("Unable to check Consul service", "service", s, "error", err)
+			}
+
+			if i == 0 {
+				pending = append(pending, s)
+			}
+		}
+
+		if len(pending) == 0 {
+			c.l.Debug("Consul health check complete", "address", address)
+			return nil
+		}
+
+		if time.Now().Sub(st) > timeout {
+			c.l.Error("Timeout waiting for Consul services", "address", address, "services", pending)
+
+			return fmt.Errorf("Timeout waiting for Consul services %s to be healthy", strings.Join(pending, ", "))
+		}
+
+		// backoff
+		if err := wait(ctx, c.backoff); err != nil {
+			return xerrors.Errorf("Consul health check cancelled: %w", err)
+		}
+	}
+}
+
+// GetKV returns the raw value of the key
+func (c *ConsulImpl) GetKV(address, token, key string) ([]byte, error) {
+	resp, err := c.kvRequest(http.MethodGet, address, token, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	d, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, xerrors.Errorf("Error getting key %s, got status code %d, error: %s", key, resp.StatusCode, string(d))
+	}
+
+	return d, nil
+}
+
+// PutKV sets the value of the key
+func (c *ConsulImpl) PutKV(address, token, key string, value []byte) error {
+	resp, err := c.kvRequest(http.MethodPut, address, token, key, value)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		d, _ := ioutil.ReadAll(resp.Body)
+		return xerrors.Errorf("Error setting key %s, got status code %d, error: %s", key, resp.StatusCode, string(d))
+	}
+
+	return nil
+}
+
+// DeleteKV removes the key
+func (c *ConsulImpl) DeleteKV(address, token, key string) error {
+	resp, err := c.kvRequest(http.MethodDelete, address, token, key, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		d, _ := ioutil.ReadAll(resp.Body)
+		return xerrors.Errorf("Error deleting key %s, got status code %d, error: %s", key, resp.StatusCode, string(d))
+	}
+
+	return nil
+}
+
+// CreateKV sets the value of the key using a check-and-set with index 0 so
+// that the key is only written when it does not exist
+func (c *ConsulImpl) CreateKV(address, token, key string, value []byte) (bool, error) {
+	resp, err := c.kvRequest(http.MethodPut, address, token, key+"?cas=0", value)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	d, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return false, xerrors.Errorf("Error creating key %s, got status code %d, error: %s", key, resp.StatusCode, string(d))
+	}
+
+	return strings.TrimSpace(string(d)) == "true", nil
+}
+
+// kvRequest calls the KV API for the key, values are read and written raw
+func (c *ConsulImpl) kvRequest(method, address, token, key string, value []byte) (*http.Response, error) {
+	u := fmt.Sprintf("%s/v1/kv/%s", strings.TrimSuffix(address, "/"), strings.TrimPrefix(key, "/"))
+	if method == http.MethodGet {
+		u += "?raw=true"
+	}
+
+	r, err := http.NewRequest(method, u, bytes.NewReader(value))
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to create http request: %w", err)
+	}
+
+	if token != "" {
+		r.Header.Set("X-Consul-Token", token)
+	}
+
+	resp, err := c.httpClient.Do(r)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to call Consul KV API for key %s: %w", key, err)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,143 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupConsulTests(responses ...*http.Response) (Consul, *mocks.MockHTTP) {
+	mh := &mocks.MockHTTP{}
+	for _, r := range responses {
+		mh.On("Do", mock.Anything).Once().Return(r, nil)
+	}
+
+	return NewConsul(mh, 1*time.Millisecond, hclog.NewNullLogger()), mh
+}
+
+func consulResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte(body))),
+	}
+}
+
+func TestConsulPassingInstancesCallsHealthAPI(t *testing.T) {
+	c, mh := setupConsulTests(consulResponse(http.StatusOK, `[{"Service":{"ID":"web"}},{"Service":{"ID":"web2"}}]`))
+
+	i, err := c.PassingInstances("http://localhost:8500/", "abc", "web")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, i)
+
+	r := getCalls(&mh.Mock, "Do")[0].Arguments[0].(*http.Request)
+	assert.Equal(t, "http://localhost:8500/v1/health/service/web?passing=true", r.URL.String())
+	assert.Equal(t, "abc", r.Header.Get("X-Consul-Token"))
+}
+
+func TestConsulPassingInstancesReturnsErrorOnStatus(t *testing.T) {
+	c, _ := setupConsulTests(consulResponse(http.StatusForbidden, "denied"))
+
+	_, err := c.PassingInstances("http://localhost:8500", "", "web")
+	assert.Error(t, err)
+}
+
+func TestConsulHealthCheckServicesReturnsWhenPassing(t *testing.T) {
+	c, mh := setupConsulTests(
+		consulResponse(http.StatusOK, `[{"Service":{"ID":"web"}}]`),
+		consulResponse(http.StatusOK, `[{"Service":{"ID":"api"}}]`),
+	)
+
+	err := c.HealthCheckServices(context.Background(), "http://localhost:8500", "", []string{"web", "api"}, 10*time.Millisecond)
+	assert.NoError(t, err)
+	mh.AssertNumberOfCalls(t, "Do", 2)
+}
+
+func TestConsulHealthCheckServicesTimesOutOnError(t *testing.T) {
+	c, mh := setupConsulTests()
+	mh.On("Do", mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	err := c.HealthCheckServices(context.Background(), "http://localhost:8500", "", []string{"web"}, 10*time.Millisecond)
+	assert.Error(t, err)
+}
+
+func TestConsulHealthCheckServicesRetriesUntilPassing(t *testing.T) {
+	c, mh := setupConsulTests(
+		consulResponse(http.StatusOK, `[]`),
+		consulResponse(http.StatusOK, `[{"Service":{"ID":"web"}}]`),
+	)
+
+	err := c.HealthCheckServices(context.Background(), "http://localhost:8500", "", []string{"web"}, 1*time.Second)
+	assert.NoError(t, err)
+	mh.AssertNumberOfCalls(t, "Do", 2)
+}
+
+func TestConsulGetKVReturnsValue(t *testing.T) {
+	c, mh := setupConsulTests(consulResponse(http.StatusOK, `{"resources":[]}`))
+
+	d, err := c.GetKV("http://localhost:8500/", "abc", "shipyard/state")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"resources":[]}`, string(d))
+
+	r := getCalls(&mh.Mock, "Do")[0].Arguments[0].(*http.Request)
+	assert.Equal(t, http.MethodGet, r.Method)
+	assert.Equal(t, "http://localhost:8500/v1/kv/shipyard/state?raw=true", r.URL.String())
+	assert.Equal(t, "abc", r.Header.Get("X-Consul-Token"))
+}
+
+func TestConsulGetKVReturnsNilWhenNotFound(t *testing.T) {
+	c, _ := setupConsulTests(consulResponse(http.StatusNotFound, ""))
+
+	d, err := c.GetKV("http://localhost:8500", "", "shipyard/state")
+	assert.NoError(t, err)
+	assert.Nil(t, d)
+}
+
+func TestConsulPutKVSetsValue(t *testing.T) {
+	c, mh := setupConsulTests(consulResponse(http.StatusOK, "true"))
+
+	err := c.PutKV("http://localhost:8500", "", "shipyard/state", []byte("state"))
+	assert.NoError(t, err)
+
+	r := getCalls(&mh.Mock, "Do")[0].Arguments[0].(*http.Request)
+	assert.Equal(t, http.MethodPut, r.Method)
+	assert.Equal(t, "http://localhost:8500/v1/kv/shipyard/state", r.URL.String())
+
+	d, _ := ioutil.ReadAll(r.Body)
+	assert.Equal(t, "state", string(d))
+}
+
+func TestConsulCreateKVUsesCheckAndSet(t *testing.T) {
+	c, mh := setupConsulTests(consulResponse(http.StatusOK, "true"))
+
+	ok, err := c.CreateKV("http://localhost:8500", "", "shipyard/state.lock", []byte("lock"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	r := getCalls(&mh.Mock, "Do")[0].Arguments[0].(*http.Request)
+	assert.Equal(t, http.MethodPut, r.Method)
+	assert.Equal(t, "http://localhost:8500/v1/kv/shipyard/state.lock?cas=0", r.URL.String())
+}
+
+func TestConsulCreateKVReturnsFalseWhenKeyExists(t *testing.T) {
+	c, _ := setupConsulTests(consulResponse(http.StatusOK, "false"))
+
+	ok, err := c.CreateKV("http://localhost:8500", "", "shipyard/state.lock", []byte("lock"))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestConsulDeleteKVReturnsErrorOnStatus(t *testing.T) {
+	c, _ := setupConsulTests(consulResponse(http.StatusForbidden, "denied"))
+
+	err := c.DeleteKV("http://localhost:8500", "", "shipyard/state")
+	assert.Error(t, err)
+}
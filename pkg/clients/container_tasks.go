@@ -1,7 +1,9 @@
 package clients
 
 import (
+	"context"
 	"io"
+	"time"
 
 	"github.com/shipyard-run/shipyard/pkg/config"
 )
@@ -19,42 +21,102 @@ type ContainerTasks interface {
 	// CreateContainer creates a new container for the given configuration
 	// if successful CreateContainer returns the ID of the created container and a nil error
 	// if not successful CreateContainer returns a blank string for the id and an error message
-	CreateContainer(*config.Container) (id string, err error)
+	CreateContainer(ctx context.Context, c *config.Container) (id string, err error)
 	// RemoveContainer stops and removes a running container
-	RemoveContainer(id string) error
+	RemoveContainer(ctx context.Context, id string) error
+	// StopContainer stops a running container, the container is killed when
+	// it does not stop within the timeout
+	StopContainer(ctx context.Context, id string, timeout time.Duration) error
+	// StartContainer starts a stopped container
+	StartContainer(ctx context.Context, id string) error
 	// CreateVolume creates a new volume with the given name.
-	// If successful the id of the newly created volume is returned
-	CreateVolume(name string) (id string, err error)
+	// If successful the id of the newly created volume is returned, volumes
+	// created with a ctx from WithPersistentVolume are not garbage collected
+	CreateVolume(ctx context.Context, name string) (id string, err error)
 	// RemoveVolume removes a volume with the given name
-	RemoveVolume(name string) error
+	RemoveVolume(ctx context.Context, name string) error
 	// PullImage pulls a Docker image from the registry if it is not already
 	// present in the local cache.
 	// If the Username and Password config options are set then PullImage will attempt to
 	// authenticate with the registry before pulling the image.
 	// If the force parameter is set then PullImage will pull regardless of the image already
 	// being cached locally.
-	PullImage(image config.Image, force bool) error
+	PullImage(ctx context.Context, image config.Image, force bool) error
 	// FindContainerIDs returns the Container IDs for the given identifier
-	FindContainerIDs(name string, typeName config.ResourceType) ([]string, error)
+	FindContainerIDs(ctx context.Context, name string, typeName config.ResourceType) ([]string, error)
+	// FindContainers returns the state of the containers for the given
+	// identifier, stopped containers are included
+	FindContainers(ctx context.Context, name string, typeName config.ResourceType) ([]config.ContainerInfo, error)
 	// ContainerLogs attaches to the container and streams the logs to the returned
 	// io.ReadCloser.
 	// Returns an error if the container is not running
-	ContainerLogs(id string, stdOut, stdErr bool) (io.ReadCloser, error)
-	// CopyFromContainer allows the copying of a file from a container
-	CopyFromContainer(id, src, dst string) error
+	ContainerLogs(ctx context.Context, id string, stdOut, stdErr bool) (io.ReadCloser, error)
+	// CopyFromContainer copies the file or directory src in the container
+	// to the local path dst
+	CopyFromContainer(ctx context.Context, id, src, dst string) error
+	// CopyToContainer copies the local file or directory src to the path dst
+	// in the container, files keep the permissions of the local file
+	CopyToContainer(ctx context.Context, id, src, dst string) error
+	// SaveImages writes the images to w as a tar archive in the format
+	// used by docker save
+	SaveImages(ctx context.Context, images []string, w io.Writer) error
+	// LoadImages loads the images from a tar archive created by SaveImages
+	LoadImages(ctx context.Context, r io.Reader) error
+	// CommitContainer creates the image ref from the filesystem of the
+	// container, the container is paused while the image is created
+	CommitContainer(ctx context.Context, id, ref string) error
+	// ImageExists returns true when the image is in the local Docker cache
+	ImageExists(ctx context.Context, ref string) (bool, error)
+	// RemoveImage removes the image ref from the local Docker cache
+	RemoveImage(ctx context.Context, ref string) error
+	// InspectImage returns the details of an image in the local Docker cache
+	InspectImage(ctx context.Context, ref string) (*config.ImageInfo, error)
+	// ExportVolume writes the contents of the named volume to w as a tar archive
+	ExportVolume(ctx context.Context, name string, w io.Writer) error
+	// ImportVolume restores the contents of a volume from a tar archive
+	// created by ExportVolume, the volume is created if it does not exist
+	ImportVolume(ctx context.Context, name string, r io.Reader) error
 	// CopyLocaDockerImageToVolume copies the docker images to the docker volume as a
 	// compressed archive.
 	// the path in the docker volume where the archive is created is returned
 	// along with any errors.
-	CopyLocalDockerImageToVolume(images []string, volume string, force bool) ([]string, error)
+	CopyLocalDockerImageToVolume(ctx context.Context, images []string, volume string, force bool) ([]string, error)
 	// Execute command allows the execution of commands in a running docker container
 	// id is the id of the container to execute the command in
 	// command is a slice of strings to execute
 	// writer [optional] will be used to write any output from the command execution.
-	ExecuteCommand(id string, command []string, env []string, workingDirectory string, writer io.Writer) error
+	// an *ExecError is returned when the command exits with a non zero code
+	ExecuteCommand(ctx context.Context, id string, command []string, env []string, workingDirectory string, writer io.Writer) error
 	// NetworkDisconnect disconnects a container from the network
-	DetachNetwork(network, containerid string) error
+	DetachNetwork(ctx context.Context, network, containerid string) error
+	// AttachNetwork connects a running container to the network
+	// no error is returned if the container is already connected
+	AttachNetwork(ctx context.Context, network, containerid string) error
 
 	// CreateShell in the running container and attach
-	CreateShell(id string, command []string, stdin io.ReadCloser, stdout io.Writer, stderr io.Writer) error
+	CreateShell(ctx context.Context, id string, command []string, stdin io.ReadCloser, stdout io.Writer, stderr io.Writer) error
+
+	// PruneContainers removes stopped containers created by Shipyard which are
+	// older than age, containers with a name in keep are not removed.
+	// Returns the ids of the removed containers
+	PruneContainers(ctx context.Context, age time.Duration, keep []string) ([]string, error)
+	// PruneImages removes dangling images built by Shipyard which are older
+	// than age, returns the ids of the removed images
+	PruneImages(ctx context.Context, age time.Duration) ([]string, error)
+	// PruneVolumes removes unused volumes created by Shipyard which are older
+	// than age, volumes with a name in keep and persistent volumes are not
+	// removed. Returns the names of the removed volumes
+	PruneVolumes(ctx context.Context, age time.Duration, keep []string) ([]string, error)
+
+	// WaitForExit blocks until the container exits or the timeout elapses, returning
+	// the exit code and the combined stdout and stderr of the container
+	WaitForExit(ctx context.Context, id string, timeout time.Duration) (exitCode int, output string, err error)
+
+	// ContainerStats returns a sample of the CPU, memory and network usage for the container
+	ContainerStats(ctx context.Context, id string) (*config.ContainerStats, error)
+	// StreamContainerStats sends samples of the resource usage for the container to
+	// the channel until the stop channel is closed or the container exits
+	StreamContainerStats(ctx context.Context, id string, stats chan<- config.ContainerStats, stop <-chan struct{}) error
+	// DiskUsage returns the disk space used by each container keyed by the container id
+	DiskUsage(ctx context.Context) (map[string]config.ContainerDiskUsage, error)
 }
@@ -3,14 +3,22 @@ package clients
 import (
 	"context"
 	"io"
+	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 
+	"github.com/docker/cli/cli/connhelper"
+
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/network"
+	registrytypes "github.com/docker/docker/api/types/registry"
 	volumetypes "github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/tlsconfig"
+	"golang.org/x/xerrors"
 )
 
 // Docker defines an interface for a Docker client
@@ -32,6 +40,9 @@ type Docker interface {
 	ContainerExecAttach(ctx context.Context, execID string, config types.ExecStartCheck) (types.HijackedResponse, error)
 	ContainerExecInspect(ctx context.Context, execID string) (types.ContainerExecInspect, error)
 	ContainerExecResize(ctx context.Context, execID string, config types.ResizeOptions) error
+	ContainerStats(ctx context.Context, container string, stream bool) (types.ContainerStats, error)
+	ContainerWait(ctx context.Context, containerID string, condition container.WaitCondition) (<-chan container.ContainerWaitOKBody, <-chan error)
+	ContainerCommit(ctx context.Context, container string, options types.ContainerCommitOptions) (types.IDResponse, error)
 
 	CopyToContainer(ctx context.Context, container, path string, content io.Reader, options types.CopyToContainerOptions) error
 	CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, types.ContainerPathStat, error)
@@ -49,15 +60,146 @@ type Docker interface {
 	ImagePull(ctx context.Context, refStr string, options types.ImagePullOptions) (io.ReadCloser, error)
 	ImageList(ctx context.Context, options types.ImageListOptions) ([]types.ImageSummary, error)
 	ImageSave(ctx context.Context, imageIDs []string) (io.ReadCloser, error)
+	ImageLoad(ctx context.Context, input io.Reader, quiet bool) (types.ImageLoadResponse, error)
 	ImageRemove(ctx context.Context, imageID string, options types.ImageRemoveOptions) ([]types.ImageDeleteResponseItem, error)
+	ImageTag(ctx context.Context, source, target string) error
+	ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error)
+	DistributionInspect(ctx context.Context, image, encodedRegistryAuth string) (registrytypes.DistributionInspect, error)
+
+	Ping(ctx context.Context) (types.Ping, error)
+	DiskUsage(ctx context.Context) (types.DiskUsage, error)
+	Info(ctx context.Context) (types.Info, error)
+}
+
+// DockerConfig defines the connection settings for the Docker daemon
+type DockerConfig struct {
+	Host       string // address of the daemon e.g. unix:///var/run/docker.sock, tcp://10.0.0.1:2376, ssh://user@host
+	CertPath   string // folder containing ca.pem, cert.pem and key.pem for TLS connections
+	TLSVerify  bool   // verify the certificate presented by the daemon
+	APIVersion string // pin the API version, when empty the latest version is used
+	Context    string // name of a Docker CLI context to use, overrides Host
+}
+
+// DockerConfigFromEnv returns the Docker connection settings from the
+// standard Docker environment variables, like the Docker CLI DOCKER_HOST
+// takes precedence over DOCKER_CONTEXT
+func DockerConfigFromEnv() DockerConfig {
+	c := DockerConfig{
+		Host:       os.Getenv("DOCKER_HOST"),
+		CertPath:   os.Getenv("DOCKER_CERT_PATH"),
+		TLSVerify:  os.Getenv("DOCKER_TLS_VERIFY") != "",
+		APIVersion: os.Getenv("DOCKER_API_VERSION"),
+	}
+
+	if c.Host == "" {
+		c.Context = os.Getenv("DOCKER_CONTEXT")
+	}
+
+	return c
 }
 
-// NewDocker creates a new Docker client
-func NewDocker() (Docker, error) {
-	cli, err := client.NewEnvClient()
+// dockerPingTimeout is the maximum time to wait for the daemon to respond
+// when negotiating the API version
+var dockerPingTimeout = 2 * time.Second
+
+// NewDocker creates a new Docker client using the given connection settings.
+// When neither a Host or a Context is set the active Docker context is used,
+// falling back to the first local socket which exists. Unless APIVersion is
+// set the client negotiates the API version with the daemon
+func NewDocker(c DockerConfig) (Docker, error) {
+	name := c.Context
+	if name == "" && c.Host == "" {
+		name = currentDockerContext(dockerConfigDir())
+	}
+
+	c, err := resolveDockerContext(c, name, dockerConfigDir())
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Host == "" {
+		c.Host = detectDockerHost(dockerSocketCandidates())
+	}
+
+	opts, err := dockerClientOpts(c)
 	if err != nil {
 		return nil, err
 	}
 
+	cli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to create Docker client for host %s: %w", c.Host, err)
+	}
+
+	if c.APIVersion == "" {
+		negotiateDockerAPIVersion(cli)
+	}
+
 	return cli, nil
 }
+
+// negotiateDockerAPIVersion downgrades the client API version to the version
+// supported by the daemon. When the daemon can not be reached the version is
+// not changed so that the connection error is returned from the first request
+func negotiateDockerAPIVersion(cli *client.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerPingTimeout)
+	defer cancel()
+
+	p, err := cli.Ping(ctx)
+	if err != nil {
+		return
+	}
+
+	cli.NegotiateAPIVersionPing(p)
+}
+
+// dockerClientOpts converts the DockerConfig into options for the Docker SDK
+func dockerClientOpts(c DockerConfig) ([]func(*client.Client) error, error) {
+	opts := []func(*client.Client) error{}
+
+	if c.APIVersion != "" {
+		opts = append(opts, client.WithVersion(c.APIVersion))
+	}
+
+	if c.Host == "" {
+		return opts, nil
+	}
+
+	// ssh hosts are not handled by the SDK, tunnel the connection through
+	// docker system dial-stdio on the remote machine
+	helper, err := connhelper.GetConnectionHelper(c.Host)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to create connection for host %s: %w", c.Host, err)
+	}
+
+	if helper != nil {
+		hc := &http.Client{
+			Transport:     &http.Transport{DialContext: helper.Dialer},
+			CheckRedirect: client.CheckRedirect,
+		}
+
+		return append(opts, client.WithHTTPClient(hc), client.WithHost(helper.Host)), nil
+	}
+
+	if c.CertPath != "" {
+		tlsc, err := tlsconfig.Client(tlsconfig.Options{
+			CAFile:             filepath.Join(c.CertPath, "ca.pem"),
+			CertFile:           filepath.Join(c.CertPath, "cert.pem"),
+			KeyFile:            filepath.Join(c.CertPath, "key.pem"),
+			InsecureSkipVerify: !c.TLSVerify,
+		})
+		if err != nil {
+			return nil, xerrors.Errorf("unable to load TLS certificates from %s: %w", c.CertPath, err)
+		}
+
+		hc := &http.Client{
+			Transport:     &http.Transport{TLSClientConfig: tlsc},
+			CheckRedirect: client.CheckRedirect,
+		}
+
+		opts = append(opts, client.WithHTTPClient(hc))
+	}
+
+	// the host must be set after the http client so the transport is configured
+	return append(opts, client.WithHost(c.Host)), nil
+}
@@ -0,0 +1,93 @@
+package clients
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// defaultDockerContext is the name of the built in context which uses the
+// local daemon or the DOCKER_HOST environment variable
+const defaultDockerContext = "default"
+
+// dockerContextMeta is the metadata file written by the Docker CLI
+// for each context in $DOCKER_CONFIG/contexts/meta
+type dockerContextMeta struct {
+	Name      string `json:"Name"`
+	Endpoints struct {
+		Docker struct {
+			Host          string `json:"Host"`
+			SkipTLSVerify bool   `json:"SkipTLSVerify"`
+		} `json:"docker"`
+	} `json:"Endpoints"`
+}
+
+// dockerConfigDir returns the location of the Docker CLI config,
+// usually $HOME/.docker
+func dockerConfigDir() string {
+	if d := os.Getenv("DOCKER_CONFIG"); d != "" {
+		return d
+	}
+
+	return filepath.Join(utils.HomeFolder(), ".docker")
+}
+
+// currentDockerContext returns the context selected with docker context use,
+// an empty string is returned when no context has been selected
+func currentDockerContext(configDir string) string {
+	d, err := ioutil.ReadFile(filepath.Join(configDir, "config.json"))
+	if err != nil {
+		return ""
+	}
+
+	cf := struct {
+		CurrentContext string `json:"currentContext"`
+	}{}
+
+	if json.Unmarshal(d, &cf) != nil {
+		return ""
+	}
+
+	return cf.CurrentContext
+}
+
+// resolveDockerContext returns a DockerConfig containing the connection
+// settings for the named context
+func resolveDockerContext(c DockerConfig, name, configDir string) (DockerConfig, error) {
+	if name == "" || name == defaultDockerContext {
+		return c, nil
+	}
+
+	// the Docker CLI stores contexts in a folder named with the sha256 of the context name
+	sum := sha256.Sum256([]byte(name))
+	id := hex.EncodeToString(sum[:])
+
+	d, err := ioutil.ReadFile(filepath.Join(configDir, "contexts", "meta", id, "meta.json"))
+	if err != nil {
+		return c, xerrors.Errorf("unable to find Docker context %s: %w", name, err)
+	}
+
+	meta := dockerContextMeta{}
+	err = json.Unmarshal(d, &meta)
+	if err != nil {
+		return c, xerrors.Errorf("unable to read Docker context %s: %w", name, err)
+	}
+
+	c.Host = meta.Endpoints.Docker.Host
+	c.TLSVerify = !meta.Endpoints.Docker.SkipTLSVerify
+	c.CertPath = ""
+
+	// certificates are only present for contexts which use TLS
+	tlsDir := filepath.Join(configDir, "contexts", "tls", id, "docker")
+	if _, err := os.Stat(tlsDir); err == nil {
+		c.CertPath = tlsDir
+	}
+
+	return c, nil
+}
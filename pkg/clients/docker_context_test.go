@@ -0,0 +1,115 @@
+package clients
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupDockerContext(t *testing.T, name, host string, tls bool) (string, func()) {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	sum := sha256.Sum256([]byte(name))
+	id := hex.EncodeToString(sum[:])
+
+	metaDir := filepath.Join(dir, "contexts", "meta", id)
+	os.MkdirAll(metaDir, os.ModePerm)
+	ioutil.WriteFile(
+		filepath.Join(metaDir, "meta.json"),
+		[]byte(fmt.Sprintf(dockerContextMetaJSON, name, host)),
+		os.ModePerm,
+	)
+
+	if tls {
+		os.MkdirAll(filepath.Join(dir, "contexts", "tls", id, "docker"), os.ModePerm)
+	}
+
+	return dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestResolveDockerContextReadsEndpoint(t *testing.T) {
+	dir, cleanup := setupDockerContext(t, "remote", "ssh://user@remote", false)
+	defer cleanup()
+
+	c, err := resolveDockerContext(DockerConfig{}, "remote", dir)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "ssh://user@remote", c.Host)
+	assert.True(t, c.TLSVerify)
+	assert.Equal(t, "", c.CertPath)
+}
+
+func TestResolveDockerContextSetsCertPathWhenTLS(t *testing.T) {
+	dir, cleanup := setupDockerContext(t, "remote", "tcp://10.0.0.1:2376", true)
+	defer cleanup()
+
+	c, err := resolveDockerContext(DockerConfig{}, "remote", dir)
+	assert.NoError(t, err)
+
+	assert.Contains(t, c.CertPath, filepath.Join(dir, "contexts", "tls"))
+}
+
+func TestResolveDockerContextIgnoresDefault(t *testing.T) {
+	c, err := resolveDockerContext(DockerConfig{Host: "tcp://10.0.0.1:2375"}, "default", "/missing")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "tcp://10.0.0.1:2375", c.Host)
+}
+
+func TestResolveDockerContextReturnsErrorWhenNotFound(t *testing.T) {
+	_, err := resolveDockerContext(DockerConfig{}, "missing", "/missing")
+	assert.Error(t, err)
+}
+
+func TestNewDockerUsesCurrentContext(t *testing.T) {
+	dir, cleanup := setupDockerContext(t, "colima", "tcp://10.0.0.5:2375", false)
+	defer cleanup()
+
+	ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"currentContext": "colima"}`), os.ModePerm)
+
+	os.Setenv("DOCKER_CONFIG", dir)
+	defer os.Unsetenv("DOCKER_CONFIG")
+
+	d, err := NewDocker(DockerConfig{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "tcp://10.0.0.5:2375", d.(*client.Client).DaemonHost())
+}
+
+func TestNewDockerUsesNamedContextOverCurrent(t *testing.T) {
+	dir, cleanup := setupDockerContext(t, "remote", "tcp://10.0.0.6:2375", false)
+	defer cleanup()
+
+	ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"currentContext": "missing"}`), os.ModePerm)
+
+	os.Setenv("DOCKER_CONFIG", dir)
+	defer os.Unsetenv("DOCKER_CONFIG")
+
+	d, err := NewDocker(DockerConfig{Context: "remote"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "tcp://10.0.0.6:2375", d.(*client.Client).DaemonHost())
+}
+
+var dockerContextMetaJSON = `
+{
+	"Name": "%s",
+	"Metadata": {},
+	"Endpoints": {
+		"docker": {
+			"Host": "%s",
+			"SkipTLSVerify": false
+		}
+	}
+}
+`
@@ -0,0 +1,125 @@
+package clients
+
+import (
+	"context"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// defaultPlatform is used as the fallback when an image is not available for
+// the platform of the Docker engine, Docker Desktop runs these images using
+// emulation
+const defaultPlatform = "linux/amd64"
+
+// enginePlatform returns the platform of the Docker engine in the form
+// os/arch, an empty string is returned when the platform can not be
+// determined
+func (d *DockerTasks) enginePlatform() string {
+	d.platformOnce.Do(func() {
+		i, err := d.c.Info(context.Background())
+		if err != nil {
+			d.l.Debug("Unable to determine the Docker engine platform", "error", err)
+			return
+		}
+
+		d.platform = normalizePlatform(i.OSType, i.Architecture)
+	})
+
+	return d.platform
+}
+
+// selectPlatform returns the platform to pull for the image, an empty
+// platform pulls the default for the Docker engine. When the image does
+// not support the platform of the engine linux/amd64 is used if available
+func (d *DockerTasks) selectPlatform(ctx context.Context, ref string, image config.Image, auth string) (string, error) {
+	engine := d.enginePlatform()
+
+	if image.Platform != "" {
+		if engine != "" && !platformMatches(image.Platform, engine) {
+			d.l.Warn("Image platform does not match the Docker engine, the container will run using emulation", "image", image.Name, "platform", image.Platform, "engine", engine)
+		}
+
+		return image.Platform, nil
+	}
+
+	// nearly every image is available for amd64 only check other platforms
+	if engine == "" || engine == defaultPlatform {
+		return "", nil
+	}
+
+	di, err := d.c.DistributionInspect(ctx, ref, auth)
+	if err != nil || len(di.Platforms) == 0 {
+		d.l.Debug("Unable to determine the platforms for image", "image", image.Name, "error", err)
+		return "", nil
+	}
+
+	available := []string{}
+	for _, p := range di.Platforms {
+		available = append(available, normalizePlatform(p.OS, p.Architecture))
+	}
+
+	for _, p := range available {
+		if platformMatches(p, engine) {
+			return "", nil
+		}
+	}
+
+	for _, p := range available {
+		if platformMatches(p, defaultPlatform) {
+			d.l.Warn("Image is not available for the Docker engine platform, the container will run using emulation", "image", image.Name, "engine", engine, "platform", defaultPlatform)
+			return defaultPlatform, nil
+		}
+	}
+
+	return "", xerrors.Errorf(
+		"Image %s is not available for the Docker engine platform %s, available platforms: %s. Set the platform in the image block to use a different platform",
+		image.Name, engine, strings.Join(available, ", "),
+	)
+}
+
+// imagePlatformMatches returns false when the cached image was pulled for a
+// different platform to the one requested
+func (d *DockerTasks) imagePlatformMatches(ctx context.Context, ref, platform string) bool {
+	i, _, err := d.c.ImageInspectWithRaw(ctx, ref)
+	if err != nil {
+		return false
+	}
+
+	return platformMatches(normalizePlatform(i.Os, i.Architecture), platform)
+}
+
+// normalizePlatform returns the platform in the form os/arch using the
+// architecture names used by image manifests
+func normalizePlatform(os, arch string) string {
+	if os == "" {
+		os = "linux"
+	}
+
+	switch arch {
+	case "x86_64", "x86-64":
+		arch = "amd64"
+	case "aarch64", "arm64/v8":
+		arch = "arm64"
+	case "armv7l", "armhf":
+		arch = "arm"
+	case "i386", "i686":
+		arch = "386"
+	}
+
+	return os + "/" + arch
+}
+
+// platformMatches compares the os and architecture of two platforms, any
+// variant is ignored
+func platformMatches(a, b string) bool {
+	pa := strings.Split(a, "/")
+	pb := strings.Split(b, "/")
+
+	if len(pa) < 2 || len(pb) < 2 {
+		return a == b
+	}
+
+	return pa[0] == pb[0] && normalizePlatform(pa[0], pa[1]) == normalizePlatform(pb[0], pb[1])
+}
@@ -0,0 +1,87 @@
+package clients
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/docker/docker/client"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// dockerSocketCandidates returns the locations of the Docker API socket
+// for common installations in the order they should be tried. The default
+// socket is always first, Windows uses a named pipe which is not probed
+func dockerSocketCandidates() []string {
+	if runtime.GOOS == "windows" {
+		return []string{}
+	}
+
+	home := utils.HomeFolder()
+	runDir := os.Getenv("XDG_RUNTIME_DIR")
+
+	c := []string{client.DefaultDockerHost}
+
+	// rootless Docker
+	if runDir != "" {
+		c = append(c, "unix://"+filepath.Join(runDir, "docker.sock"))
+	}
+
+	// Docker Desktop on macOS and Linux
+	c = append(c,
+		"unix://"+filepath.Join(home, ".docker", "run", "docker.sock"),
+		"unix://"+filepath.Join(home, ".docker", "desktop", "docker.sock"),
+	)
+
+	// Docker running in WSL and shared between distributions
+	if utils.IsWSL() {
+		c = append(c, "unix:///mnt/wsl/shared-docker/docker.sock")
+	}
+
+	// Podman Docker compatible API
+	if runDir != "" {
+		c = append(c, "unix://"+filepath.Join(runDir, "podman", "podman.sock"))
+	}
+
+	return append(c, "unix:///run/podman/podman.sock")
+}
+
+// detectDockerHost returns the first candidate socket which exists, when
+// the default socket exists or no candidate is found an empty string is
+// returned so that the Docker SDK default is used
+func detectDockerHost(candidates []string) string {
+	for _, c := range candidates {
+		if _, err := os.Stat(strings.TrimPrefix(c, "unix://")); err == nil {
+			if c == client.DefaultDockerHost {
+				return ""
+			}
+
+			return c
+		}
+	}
+
+	return ""
+}
+
+// dockerConnectionError returns an error which describes how to resolve a
+// failed connection to the Docker daemon
+func dockerConnectionError(host string, err error) error {
+	if host == "" {
+		host = client.DefaultDockerHost
+	}
+
+	if !client.IsErrConnectionFailed(err) {
+		return xerrors.Errorf("unable to connect to Docker at %s: %w", host, err)
+	}
+
+	return xerrors.Errorf(
+		"unable to connect to Docker at %s, ensure Docker is running. "+
+			"If Docker uses a non-standard socket set DOCKER_HOST or select a context with DOCKER_CONTEXT, "+
+			"the following locations were checked: %s: %w",
+		host,
+		strings.Join(dockerSocketCandidates(), ", "),
+		err,
+	)
+}
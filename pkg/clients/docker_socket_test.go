@@ -0,0 +1,59 @@
+package clients
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectDockerHostReturnsFirstExistingSocket(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	sock := filepath.Join(tmpDir, "podman.sock")
+	ioutil.WriteFile(sock, []byte(""), 0644)
+
+	h := detectDockerHost([]string{
+		"unix://" + filepath.Join(tmpDir, "missing.sock"),
+		"unix://" + sock,
+	})
+
+	assert.Equal(t, "unix://"+sock, h)
+}
+
+func TestDetectDockerHostReturnsEmptyWhenNoSocket(t *testing.T) {
+	h := detectDockerHost([]string{"unix:///missing/docker.sock"})
+
+	assert.Equal(t, "", h)
+}
+
+func TestDockerSocketCandidatesIncludesRootless(t *testing.T) {
+	os.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	defer os.Unsetenv("XDG_RUNTIME_DIR")
+
+	c := dockerSocketCandidates()
+
+	assert.Equal(t, client.DefaultDockerHost, c[0])
+	assert.Contains(t, c, "unix:///run/user/1000/docker.sock")
+	assert.Contains(t, c, "unix:///run/user/1000/podman/podman.sock")
+}
+
+func TestDockerConnectionErrorListsCheckedSockets(t *testing.T) {
+	err := dockerConnectionError("", client.ErrorConnectionFailed("unix:///var/run/docker.sock"))
+
+	assert.Contains(t, err.Error(), "DOCKER_HOST")
+	assert.Contains(t, err.Error(), client.DefaultDockerHost)
+}
+
+func TestDockerConnectionErrorWrapsOtherErrors(t *testing.T) {
+	err := dockerConnectionError("tcp://10.0.0.1:2375", fmt.Errorf("boom"))
+
+	assert.Contains(t, err.Error(), "tcp://10.0.0.1:2375")
+	assert.NotContains(t, err.Error(), "DOCKER_HOST")
+}
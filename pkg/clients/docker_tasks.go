@@ -5,15 +5,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	gosignal "os/signal"
 	"path"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	dockerconfig "github.com/docker/cli/cli/config"
+	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
@@ -21,6 +26,7 @@ import (
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/pkg/signal"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
 	"github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients/streams"
@@ -29,12 +35,19 @@ import (
 	"golang.org/x/xerrors"
 )
 
+// shipyardLabel is added to the containers, volumes and images created by
+// Shipyard so they can be found when garbage collecting
+const shipyardLabel = "run.shipyard"
+
 // DockerTasks is a concrete implementation of ContainerTasks which uses the Docker SDK
 type DockerTasks struct {
 	c     Docker
 	il    ImageLog
 	force bool
 	l     hclog.Logger
+
+	platform     string // platform of the Docker engine, see enginePlatform
+	platformOnce sync.Once
 }
 
 // NewDockerTasks creates a DockerTasks with the given Docker client
@@ -49,7 +62,7 @@ func (d *DockerTasks) SetForcePull(force bool) {
 }
 
 // CreateContainer creates a new Docker container for the given configuation
-func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
+func (d *DockerTasks) CreateContainer(ctx context.Context, c *config.Container) (string, error) {
 	d.l.Info("Creating Container", "ref", c.Name)
 
 	// create a unique name based on service network [container].[network].shipyard
@@ -73,6 +86,13 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 		AttachStdin:  true,
 		AttachStdout: true,
 		AttachStderr: true,
+		Labels:       map[string]string{shipyardLabel: "true"},
+	}
+
+	// the hash identifies the definition which created the container so an
+	// existing container is only used for the same definition
+	if h := configHash(ctx); h != "" {
+		dc.Labels[configHashLabel] = h
 	}
 
 	// create the host and network configs
@@ -100,8 +120,10 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 		}
 
 		// if we have a bind type mount then ensure that the local folder exists or
-		// an error will be raised when creating
-		if t == mount.TypeBind {
+		// an error will be raised when creating, paths in the Docker VM such as
+		// the Docker socket on Windows can not be checked
+		source := vc.Source
+		if t == mount.TypeBind && utils.IsHostPath(vc.Source) {
 			// check to see id the source exists
 			_, err := os.Stat(vc.Source)
 			if err != nil {
@@ -112,20 +134,25 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 					return "", xerrors.Errorf("Source for Volume %s does not exist, error creating directory: %w", err)
 				}
 			}
+
+			// Docker on Windows expects the drive in the form //c/
+			source = utils.DockerPath(vc.Source)
 		}
 
 		// create the mount
 		mounts = append(mounts, mount.Mount{
 			Type:   t,
-			Source: vc.Source,
+			Source: source,
 			Target: vc.Destination,
 		})
 	}
 
 	hc.Mounts = mounts
 
-	// create the ports config
-	ports := createPublishedPorts(c.Ports)
+	// create the ports config, ports are also published on the IPv6
+	// interfaces when the container is attached to a dual stack network,
+	// e.g. an ingress for a cluster on an IPv6 network
+	ports := createPublishedPorts(c.Ports, dualStack(c))
 	dc.ExposedPorts = ports.ExposedPorts
 	hc.PortBindings = ports.PortBindings
 
@@ -141,7 +168,7 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 
 		if net.Info().Type == config.TypeContainer {
 			// find the id of the container
-			ids, err := d.FindContainerIDs(net.Info().Name, net.Info().Type)
+			ids, err := d.FindContainerIDs(ctx, net.Info().Name, net.Info().Type)
 			if err != nil {
 				return "", xerrors.Errorf("Unable to attach to container network, ID for container not found: %w", err)
 			}
@@ -160,7 +187,7 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 	}
 
 	cont, err := d.c.ContainerCreate(
-		context.Background(),
+		ctx,
 		dc,
 		hc,
 		nc,
@@ -174,7 +201,7 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 	// all containers should have custom networks
 	// only add networks if we are not adding the container network
 	if len(c.Networks) > 0 && !hc.NetworkMode.IsContainer() {
-		err := d.c.NetworkDisconnect(context.Background(), "bridge", cont.ID, true)
+		err := d.c.NetworkDisconnect(ctx, "bridge", cont.ID, true)
 		if err != nil {
 			return "", xerrors.Errorf("Unable to remove container from the default bridge network: %w", err)
 		}
@@ -182,7 +209,7 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 		for _, n := range c.Networks {
 			net, err := c.FindDependentResource(n.Name)
 			if err != nil {
-				errRemove := d.RemoveContainer(cont.ID)
+				errRemove := d.RemoveContainer(context.Background(), cont.ID)
 				if errRemove != nil {
 					return "", xerrors.Errorf("Unable to connect container to network %s, unable to roll back container: %w", n.Name, err)
 				}
@@ -199,15 +226,15 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 			}
 
 			// are we binding to a specific ip
-			if n.IPAddress != "" {
-				d.l.Debug("Assigning static ip address", "ref", c.Name, "network", n.Name, "ip_address", n.IPAddress)
-				es.IPAMConfig = &network.EndpointIPAMConfig{IPv4Address: n.IPAddress}
+			if n.IPAddress != "" || n.IPv6Address != "" {
+				d.l.Debug("Assigning static ip address", "ref", c.Name, "network", n.Name, "ip_address", n.IPAddress, "ipv6_address", n.IPv6Address)
+				es.IPAMConfig = &network.EndpointIPAMConfig{IPv4Address: n.IPAddress, IPv6Address: n.IPv6Address}
 			}
 
-			err = d.c.NetworkConnect(context.Background(), net.Info().Name, cont.ID, es)
+			err = d.c.NetworkConnect(ctx, net.Info().Name, cont.ID, es)
 			if err != nil {
 				// if we fail to connect to the network roll back the container
-				errRemove := d.RemoveContainer(cont.ID)
+				errRemove := d.RemoveContainer(context.Background(), cont.ID)
 				if errRemove != nil {
 					return "", xerrors.Errorf("Unable to connect container to network %s, unable to roll back container: %w", n.Name, err)
 				}
@@ -217,35 +244,76 @@ func (d *DockerTasks) CreateContainer(c *config.Container) (string, error) {
 		}
 	}
 
-	err = d.c.ContainerStart(context.Background(), cont.ID, types.ContainerStartOptions{})
+	err = d.c.ContainerStart(ctx, cont.ID, types.ContainerStartOptions{})
 	if err != nil {
 		return "", err
 	}
 
+	// apply any traffic shaping, this can only be done once the container is running
+	for _, n := range c.Networks {
+		net, err := c.FindDependentResource(n.Name)
+		if err != nil {
+			return "", xerrors.Errorf("Network not found: %w", err)
+		}
+
+		// sidecar networks are shaped by the container which owns the network
+		nw, ok := net.(*config.Network)
+		if !ok {
+			continue
+		}
+
+		// attachment settings override the defaults for the network
+		imp := n.Impairment
+		if imp == nil {
+			imp = nw.Impairment
+		}
+
+		if imp == nil {
+			continue
+		}
+
+		err = d.impairNetwork(ctx, cont.ID, nw.Subnet, imp)
+		if err != nil {
+			// roll back the container so that it is not left running without the impairment
+			errRemove := d.RemoveContainer(context.Background(), cont.ID)
+			if errRemove != nil {
+				return "", xerrors.Errorf("Unable to apply network impairment for network %s, %s, unable to roll back container: %w", n.Name, err, errRemove)
+			}
+
+			return "", xerrors.Errorf("Unable to apply network impairment for network %s: %w", n.Name, err)
+		}
+	}
+
 	return cont.ID, nil
 }
 
 // PullImage pulls a Docker image from a remote repo
-func (d *DockerTasks) PullImage(image config.Image, force bool) error {
+func (d *DockerTasks) PullImage(ctx context.Context, image config.Image, force bool) error {
 	in := makeImageCanonical(image.Name)
 
 	args := filters.NewArgs()
 	args.Add("reference", image.Name)
 
 	// only pull if image is not in current registry so check to see if the image is present
-	// if force then skil this check
-	if !force && !d.force {
-		sum, err := d.c.ImageList(context.Background(), types.ImageListOptions{Filters: args})
+	// if force then skil this check, in offline mode the image must be present
+	offline := offlineMode(ctx)
+	if (!force && !d.force) || offline {
+		sum, err := d.c.ImageList(ctx, types.ImageListOptions{Filters: args})
 		if err != nil {
 			return xerrors.Errorf("unable to list images in local Docker cache: %w", err)
 		}
 
-		// if we have images do not pull
-		if len(sum) > 0 {
+		// if we have images do not pull, unless the cached image is for a
+		// different platform
+		if len(sum) > 0 && (image.Platform == "" || d.imagePlatformMatches(ctx, image.Name, image.Platform)) {
 			d.l.Debug("Image exists in local cache", "image", image.Name)
 
 			return nil
 		}
+
+		if offline {
+			return xerrors.Errorf("Image %s is not in the local cache, load a bundle which contains the image: %w", image.Name, utils.OfflineError)
+		}
 	}
 
 	ipo := types.ImagePullOptions{}
@@ -254,30 +322,87 @@ func (d *DockerTasks) PullImage(image config.Image, force bool) error {
 	// image pull
 	if image.Username != "" && image.Password != "" {
 		ipo.RegistryAuth = createRegistryAuth(image.Username, image.Password)
+	} else {
+		// fall back to any credentials stored by docker login
+		auth, err := registryAuthFromConfig(in, dockerConfigDir())
+		if err != nil {
+			d.l.Debug("Unable to read registry credentials from Docker config", "image", image.Name, "error", err)
+		}
+
+		ipo.RegistryAuth = auth
+	}
+
+	platform, err := d.selectPlatform(ctx, in, image, ipo.RegistryAuth)
+	if err != nil {
+		return err
 	}
 
-	d.l.Debug("Pulling image", "image", image.Name)
+	ipo.Platform = platform
+
+	d.l.Debug("Pulling image", "image", image.Name, "platform", platform)
+
+	// anonymous pulls from the Docker Hub go through the mirror when one is
+	// set, images pulled by digest can not be tagged so are pulled direct
+	mirror := pullMirror(ctx)
+	if mirror != "" && ipo.RegistryAuth == "" && strings.HasPrefix(in, "docker.io/") && !strings.Contains(in, "@") {
+		err := d.pullImageThroughMirror(ctx, mirror, in, ipo)
+		if err == nil {
+			d.logImage(in)
+			return nil
+		}
+
+		d.l.Debug("Unable to pull image through mirror, pulling from the Docker Hub", "image", image.Name, "mirror", mirror, "error", err)
+	}
 
-	out, err := d.c.ImagePull(context.Background(), in, ipo)
+	out, err := d.c.ImagePull(ctx, in, ipo)
 	if err != nil {
 		return xerrors.Errorf("Error pulling image: %w", err)
 	}
 
-	// update the image log
-	err = d.il.Log(in, ImageTypeDocker)
+	d.logImage(in)
+
+	// the pull is complete once the output has been read
+	readPullProgress(ctx, out)
+
+	return nil
+}
+
+// pullImageThroughMirror pulls the image from the mirror and tags it with its
+// Docker Hub name so that it can be used as if it had been pulled direct
+func (d *DockerTasks) pullImageThroughMirror(ctx context.Context, mirror, in string, ipo types.ImagePullOptions) error {
+	ref := mirror + strings.TrimPrefix(in, "docker.io")
+
+	out, err := d.c.ImagePull(ctx, ref, ipo)
 	if err != nil {
-		d.l.Error("Unable to add image name to cache", "error", err)
+		return err
 	}
 
-	// write the output to /dev/null
-	// TODO this stuff needs to be logged correctly
-	io.Copy(ioutil.Discard, out)
+	readPullProgress(ctx, out)
+
+	err = d.c.ImageTag(ctx, ref, in)
+	if err != nil {
+		return err
+	}
+
+	// only the mirror tag is removed, the image is kept by its Docker Hub name
+	_, err = d.c.ImageRemove(ctx, ref, types.ImageRemoveOptions{})
+	if err != nil {
+		d.l.Debug("Unable to remove mirror tag", "image", ref, "error", err)
+	}
 
 	return nil
 }
 
+// logImage adds the image to the image log so that it is removed on purge
+func (d *DockerTasks) logImage(in string) {
+	err := d.il.Log(in, ImageTypeDocker)
+	if err != nil {
+		d.l.Error("Unable to add image name to cache", "error", err)
+	}
+}
+
 // FindContainerIDs returns the Container IDs for the given identifier
-func (d *DockerTasks) FindContainerIDs(containerName string, typeName config.ResourceType) ([]string, error) {
+func (d *DockerTasks) FindContainerIDs(ctx context.Context, containerName string, typeName config.ResourceType) ([]string, error) {
 	fullName := utils.FQDN(containerName, string(typeName))
 
 	args := filters.NewArgs()
@@ -286,7 +411,7 @@ func (d *DockerTasks) FindContainerIDs(containerName string, typeName config.Res
 
 	opts := types.ContainerListOptions{Filters: args, All: true}
 
-	cl, err := d.c.ContainerList(context.Background(), opts)
+	cl, err := d.c.ContainerList(ctx, opts)
 	if err != nil || cl == nil {
 		return nil, err
 	}
@@ -303,14 +428,58 @@ func (d *DockerTasks) FindContainerIDs(containerName string, typeName config.Res
 	return nil, nil
 }
 
+// FindContainers returns the state of the containers for the given
+// identifier, stopped containers are included
+func (d *DockerTasks) FindContainers(ctx context.Context, containerName string, typeName config.ResourceType) ([]config.ContainerInfo, error) {
+	args := filters.NewArgs()
+	args.Add("name", fmt.Sprintf("^/%s$", utils.FQDN(containerName, string(typeName))))
+
+	cl, err := d.c.ContainerList(ctx, types.ContainerListOptions{Filters: args, All: true})
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to list containers: %w", err)
+	}
+
+	cs := []config.ContainerInfo{}
+	for _, c := range cl {
+		cs = append(cs, config.ContainerInfo{ID: c.ID, Running: c.State == "running", ConfigHash: c.Labels[configHashLabel]})
+	}
+
+	return cs, nil
+}
+
 // RemoveContainer with the given id
-func (d *DockerTasks) RemoveContainer(id string) error {
+func (d *DockerTasks) RemoveContainer(ctx context.Context, id string) error {
 	// try and shutdown graceful
-	err := d.c.ContainerRemove(context.Background(), id, types.ContainerRemoveOptions{Force: false, RemoveVolumes: true})
+	err := d.c.ContainerRemove(ctx, id, types.ContainerRemoveOptions{Force: false, RemoveVolumes: true})
 
 	// unable to shutdown graceful try force
 	if err != nil {
-		return d.c.ContainerRemove(context.Background(), id, types.ContainerRemoveOptions{Force: true, RemoveVolumes: true})
+		return d.c.ContainerRemove(ctx, id, types.ContainerRemoveOptions{Force: true, RemoveVolumes: true})
+	}
+
+	return nil
+}
+
+// StopContainer stops the container with the given id, Docker kills the
+// container when it has not stopped before the timeout
+func (d *DockerTasks) StopContainer(ctx context.Context, id string, timeout time.Duration) error {
+	d.l.Debug("Stopping container", "id", id)
+
+	err := d.c.ContainerStop(ctx, id, &timeout)
+	if err != nil {
+		return xerrors.Errorf("unable to stop container %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// StartContainer starts the stopped container with the given id
+func (d *DockerTasks) StartContainer(ctx context.Context, id string) error {
+	d.l.Debug("Starting container", "id", id)
+
+	err := d.c.ContainerStart(ctx, id, types.ContainerStartOptions{})
+	if err != nil {
+		return xerrors.Errorf("unable to start container %s: %w", id, err)
 	}
 
 	return nil
@@ -319,13 +488,13 @@ func (d *DockerTasks) RemoveContainer(id string) error {
 // CreateVolume creates a Docker volume for a cluster
 // if the volume exists performs no action
 // returns the volume name and an error if unsuccessful
-func (d *DockerTasks) CreateVolume(name string) (string, error) {
+func (d *DockerTasks) CreateVolume(ctx context.Context, name string) (string, error) {
 	vn := utils.FQDNVolumeName(name)
 
 	args := filters.NewArgs()
 	// By default Docker will wildcard searches, use regex to return the absolute
 	args.Add("name", vn)
-	ops, err := d.c.VolumeList(context.Background(), args)
+	ops, err := d.c.VolumeList(ctx, args)
 	if err != nil {
 		return "", fmt.Errorf("unable to lookup volume [%s] for cluster [%s]\n%+v", vn, name, err)
 	}
@@ -341,9 +510,14 @@ func (d *DockerTasks) CreateVolume(name string) (string, error) {
 		Name:       vn,
 		Driver:     "local", //TODO: allow setting driver + opts
 		DriverOpts: map[string]string{},
+		Labels:     map[string]string{shipyardLabel: "true"},
+	}
+
+	if persistentVolume(ctx) {
+		volumeCreateOptions.Labels[persistentLabel] = "true"
 	}
 
-	vol, err := d.c.VolumeCreate(context.Background(), volumeCreateOptions)
+	vol, err := d.c.VolumeCreate(ctx, volumeCreateOptions)
 	if err != nil {
 		return "", fmt.Errorf("failed to create image volume [%s] for cluster [%s]\n%+v", vn, name, err)
 	}
@@ -352,57 +526,760 @@ func (d *DockerTasks) CreateVolume(name string) (string, error) {
 }
 
 // RemoveVolume deletes the Docker volume associated with  a cluster
-func (d *DockerTasks) RemoveVolume(name string) error {
+func (d *DockerTasks) RemoveVolume(ctx context.Context, name string) error {
 	vn := utils.FQDNVolumeName(name)
 	d.l.Debug("Deleting Volume", "ref", name, "name", vn)
 
-	return d.c.VolumeRemove(context.Background(), vn, true)
+	return d.c.VolumeRemove(ctx, vn, true)
+}
+
+// PruneContainers removes stopped containers created by Shipyard which are older than age
+func (d *DockerTasks) PruneContainers(ctx context.Context, age time.Duration, keep []string) ([]string, error) {
+	args := filters.NewArgs()
+	args.Add("label", shipyardLabel)
+	args.Add("status", "created")
+	args.Add("status", "exited")
+	args.Add("status", "dead")
+
+	cl, err := d.c.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: args})
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to list stopped containers: %w", err)
+	}
+
+	kept := map[string]bool{}
+	for _, k := range keep {
+		kept["/"+k] = true
+	}
+
+	removed := []string{}
+	for _, c := range cl {
+		if !olderThan(time.Unix(c.Created, 0), age) || containsName(kept, c.Names) {
+			continue
+		}
+
+		d.l.Debug("Removing stopped container", "id", c.ID, "names", c.Names)
+
+		err := d.c.ContainerRemove(ctx, c.ID, types.ContainerRemoveOptions{RemoveVolumes: true})
+		if err != nil {
+			return removed, xerrors.Errorf("Unable to remove container %s: %w", c.ID, err)
+		}
+
+		removed = append(removed, c.ID)
+	}
+
+	return removed, nil
+}
+
+// PruneImages removes dangling images built by Shipyard which are older than age
+func (d *DockerTasks) PruneImages(ctx context.Context, age time.Duration) ([]string, error) {
+	args := filters.NewArgs()
+	args.Add("label", shipyardLabel)
+	args.Add("dangling", "true")
+
+	il, err := d.c.ImageList(ctx, types.ImageListOptions{Filters: args})
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to list dangling images: %w", err)
+	}
+
+	removed := []string{}
+	for _, i := range il {
+		if !olderThan(time.Unix(i.Created, 0), age) {
+			continue
+		}
+
+		d.l.Debug("Removing dangling image", "id", i.ID)
+
+		_, err := d.c.ImageRemove(ctx, i.ID, types.ImageRemoveOptions{PruneChildren: true})
+		if err != nil {
+			return removed, xerrors.Errorf("Unable to remove image %s: %w", i.ID, err)
+		}
+
+		removed = append(removed, i.ID)
+	}
+
+	return removed, nil
+}
+
+// PruneVolumes removes volumes created by Shipyard which are not used by any container
+// and are older than age
+func (d *DockerTasks) PruneVolumes(ctx context.Context, age time.Duration, keep []string) ([]string, error) {
+	args := filters.NewArgs()
+	args.Add("label", shipyardLabel)
+	args.Add("dangling", "true")
+
+	vl, err := d.c.VolumeList(ctx, args)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to list unused volumes: %w", err)
+	}
+
+	kept := map[string]bool{}
+	for _, k := range keep {
+		kept[k] = true
+	}
+
+	removed := []string{}
+	for _, v := range vl.Volumes {
+		if kept[v.Name] || v.Labels[persistentLabel] == "true" {
+			continue
+		}
+
+		// volumes without a valid creation date are only removed when age is 0
+		created, _ := time.Parse(time.RFC3339, v.CreatedAt)
+		if !olderThan(created, age) {
+			continue
+		}
+
+		d.l.Debug("Removing unused volume", "name", v.Name)
+
+		err := d.c.VolumeRemove(ctx, v.Name, false)
+		if err != nil {
+			return removed, xerrors.Errorf("Unable to remove volume %s: %w", v.Name, err)
+		}
+
+		removed = append(removed, v.Name)
+	}
+
+	return removed, nil
+}
+
+// containsName returns true when any of the container names is in names
+func containsName(names map[string]bool, containerNames []string) bool {
+	for _, n := range containerNames {
+		if names[n] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// olderThan returns true when the time is older than the given age,
+// an age of 0 matches everything
+func olderThan(t time.Time, age time.Duration) bool {
+	return age == 0 || time.Now().Sub(t) > age
 }
 
 // ContainerLogs streams the logs for the container to the returned io.ReadCloser
-func (d *DockerTasks) ContainerLogs(id string, stdOut, stdErr bool) (io.ReadCloser, error) {
-	return d.c.ContainerLogs(context.Background(), id, types.ContainerLogsOptions{ShowStderr: stdErr, ShowStdout: stdOut})
+func (d *DockerTasks) ContainerLogs(ctx context.Context, id string, stdOut, stdErr bool) (io.ReadCloser, error) {
+	return d.c.ContainerLogs(ctx, id, types.ContainerLogsOptions{ShowStderr: stdErr, ShowStdout: stdOut})
+}
+
+// WaitForExit waits for the container to stop running and returns the exit code
+// and the output written by the container
+func (d *DockerTasks) WaitForExit(ctx context.Context, id string, timeout time.Duration) (int, string, error) {
+	d.l.Debug("Waiting for container to exit", "id", id, "timeout", timeout)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var exitCode int
+
+	resp, errs := d.c.ContainerWait(ctx, id, container.WaitConditionNotRunning)
+	select {
+	case r := <-resp:
+		if r.Error != nil {
+			return 0, "", xerrors.Errorf("Error waiting for container %s: %s", id, r.Error.Message)
+		}
+
+		exitCode = int(r.StatusCode)
+	case err := <-errs:
+		if ctx.Err() == context.DeadlineExceeded {
+			return 0, "", xerrors.Errorf("Timeout waiting for container %s to exit", id)
+		}
+
+		return 0, "", xerrors.Errorf("Error waiting for container %s: %w", id, err)
+	}
+
+	rc, err := d.c.ContainerLogs(ctx, id, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return exitCode, "", xerrors.Errorf("Unable to get output for container %s: %w", id, err)
+	}
+	defer rc.Close()
+
+	// container logs are multiplexed, combine stdout and stderr
+	out := &bytes.Buffer{}
+	_, err = stdcopy.StdCopy(out, out, rc)
+	if err != nil {
+		return exitCode, "", xerrors.Errorf("Unable to read output for container %s: %w", id, err)
+	}
+
+	d.l.Debug("Container exited", "id", id, "exit_code", exitCode)
+
+	return exitCode, out.String(), nil
+}
+
+// ContainerStats returns a sample of the resource usage for the container
+func (d *DockerTasks) ContainerStats(ctx context.Context, id string) (*config.ContainerStats, error) {
+	resp, err := d.c.ContainerStats(ctx, id, false)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to get stats for container %s: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	s := types.StatsJSON{}
+	err = json.NewDecoder(resp.Body).Decode(&s)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to decode stats for container %s: %w", id, err)
+	}
+
+	return containerStatsFromJSON(id, s), nil
+}
+
+// StreamContainerStats sends a sample of the resource usage to the channel each time
+// it is reported by the Docker daemon, returns when the stop channel is closed or
+// the container exits
+func (d *DockerTasks) StreamContainerStats(ctx context.Context, id string, stats chan<- config.ContainerStats, stop <-chan struct{}) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resp, err := d.c.ContainerStats(ctx, id, true)
+	if err != nil {
+		return xerrors.Errorf("unable to stream stats for container %s: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	// cancel the request when stopped so the blocking decode returns
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		s := types.StatsJSON{}
+		err := dec.Decode(&s)
+		if err != nil {
+			select {
+			case <-stop:
+				return nil
+			default:
+			}
+
+			if err == io.EOF {
+				return nil
+			}
+
+			return xerrors.Errorf("unable to decode stats for container %s: %w", id, err)
+		}
+
+		select {
+		case stats <- *containerStatsFromJSON(id, s):
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// containerStatsFromJSON calculates the resource usage from the raw Docker
+// stats using the same method as the docker stats command
+func containerStatsFromJSON(id string, s types.StatsJSON) *config.ContainerStats {
+	cs := &config.ContainerStats{
+		ID:          id,
+		Name:        strings.TrimPrefix(s.Name, "/"),
+		Read:        s.Read,
+		MemoryLimit: s.MemoryStats.Limit,
+	}
+
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+
+	cpus := float64(s.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+
+	if cpuDelta > 0 && systemDelta > 0 {
+		cs.CPUPercentage = (cpuDelta / systemDelta) * cpus * 100
+	}
+
+	// the page cache can be reclaimed so it is not counted as used memory,
+	// cgroup v1 reports cache and v2 reports inactive_file
+	cs.MemoryUsage = s.MemoryStats.Usage
+	cache := s.MemoryStats.Stats["cache"]
+	if v, ok := s.MemoryStats.Stats["inactive_file"]; ok {
+		cache = v
+	}
+
+	if cache < cs.MemoryUsage {
+		cs.MemoryUsage -= cache
+	}
+
+	if cs.MemoryLimit > 0 {
+		cs.MemoryPercentage = float64(cs.MemoryUsage) / float64(cs.MemoryLimit) * 100
+	}
+
+	for _, n := range s.Networks {
+		cs.NetworkRx += n.RxBytes
+		cs.NetworkTx += n.TxBytes
+	}
+
+	return cs
+}
+
+// DiskUsage returns the disk space used by each container, the size of the
+// image and named volumes used by the container are included. Images and
+// volumes can be shared so the sizes should not be summed without checking
+// for duplicates
+func (d *DockerTasks) DiskUsage(ctx context.Context) (map[string]config.ContainerDiskUsage, error) {
+	du, err := d.c.DiskUsage(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("unable to get disk usage: %w", err)
+	}
+
+	images := map[string]int64{}
+	for _, i := range du.Images {
+		images[i.ID] = i.Size
+	}
+
+	volumes := map[string]int64{}
+	for _, v := range du.Volumes {
+		// the size is -1 when it could not be calculated
+		if v.UsageData != nil && v.UsageData.Size > 0 {
+			volumes[v.Name] = v.UsageData.Size
+		}
+	}
+
+	usage := map[string]config.ContainerDiskUsage{}
+	for _, c := range du.Containers {
+		cu := config.ContainerDiskUsage{
+			ID:           c.ID,
+			Image:        c.Image,
+			ImageSize:    images[c.ImageID],
+			WritableSize: c.SizeRw,
+			Volumes:      map[string]int64{},
+		}
+
+		for _, m := range c.Mounts {
+			if m.Type == mount.TypeVolume {
+				cu.Volumes[m.Name] = volumes[m.Name]
+			}
+		}
+
+		usage[c.ID] = cu
+	}
+
+	return usage, nil
 }
 
-// CopyFromContainer copies a file from a container
-func (d *DockerTasks) CopyFromContainer(id, src, dst string) error {
-	d.l.Debug("Copying file from", "id", id, "src", src, "dst", dst)
+// CopyFromContainer copies the file or directory src from the container to dst,
+// the content is streamed from the Docker API as a tar archive
+func (d *DockerTasks) CopyFromContainer(ctx context.Context, id, src, dst string) error {
+	d.l.Debug("Copying from container", "id", id, "src", src, "dst", dst)
 
-	reader, _, err := d.c.CopyFromContainer(context.Background(), id, src)
+	reader, _, err := d.c.CopyFromContainer(ctx, id, src)
 	if err != nil {
-		return fmt.Errorf("Couldn't copy kubeconfig.yaml from server container %s\n%+v", id, err)
+		return xerrors.Errorf("Unable to copy %s from container %s: %w", src, id, err)
 	}
 	defer reader.Close()
 
-	readBytes, err := ioutil.ReadAll(reader)
+	err = extractArchive(reader, dst)
+	if err != nil {
+		return xerrors.Errorf("Unable to extract %s from container %s to %s: %w", src, id, dst, err)
+	}
+
+	return nil
+}
+
+// CopyToContainer copies the local file or directory src to the path dst in the container,
+// files keep the permissions of the local file
+func (d *DockerTasks) CopyToContainer(ctx context.Context, id, src, dst string) error {
+	d.l.Debug("Copying to container", "id", id, "src", src, "dst", dst)
+
+	// the Docker API expects the content to be a tar archive
+	// which is extracted to the destination folder
+	buf := &bytes.Buffer{}
+	err := createArchive(src, path.Base(dst), buf)
+	if err != nil {
+		return xerrors.Errorf("Unable to create archive for %s: %w", src, err)
+	}
+
+	err = d.c.CopyToContainer(ctx, id, path.Dir(dst), buf, types.CopyToContainerOptions{})
+	if err != nil {
+		return xerrors.Errorf("Unable to copy %s to container %s: %w", src, id, err)
+	}
+
+	return nil
+}
+
+// SaveImages writes the images to w as a tar archive in the format used by docker save
+func (d *DockerTasks) SaveImages(ctx context.Context, images []string, w io.Writer) error {
+	d.l.Debug("Saving images", "images", images)
+
+	r, err := d.c.ImageSave(ctx, images)
+	if err != nil {
+		return xerrors.Errorf("Unable to save images %v: %w", images, err)
+	}
+	defer r.Close()
+
+	_, err = io.Copy(w, r)
+	if err != nil {
+		return xerrors.Errorf("Unable to write images %v: %w", images, err)
+	}
+
+	return nil
+}
+
+// LoadImages loads the images from a tar archive created by SaveImages
+func (d *DockerTasks) LoadImages(ctx context.Context, r io.Reader) error {
+	d.l.Debug("Loading images")
+
+	resp, err := d.c.ImageLoad(ctx, r, true)
+	if err != nil {
+		return xerrors.Errorf("Unable to load images: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// the images are not loaded until the response has been read
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	if err != nil {
+		return xerrors.Errorf("Unable to load images: %w", err)
+	}
+
+	return nil
+}
+
+// CommitContainer creates the image ref from the filesystem of the container,
+// named volumes and the memory of the running processes are not included
+func (d *DockerTasks) CommitContainer(ctx context.Context, id, ref string) error {
+	d.l.Debug("Committing container", "id", id, "image", ref)
+
+	_, err := d.c.ContainerCommit(ctx, id, types.ContainerCommitOptions{
+		Reference: ref,
+		Comment:   "Checkpoint created by Shipyard",
+		Pause:     true,
+	})
+
+	if err != nil {
+		return xerrors.Errorf("Unable to commit container %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// RemoveImage removes the image ref from the local Docker cache
+func (d *DockerTasks) RemoveImage(ctx context.Context, ref string) error {
+	d.l.Debug("Removing image", "image", ref)
+
+	_, err := d.c.ImageRemove(ctx, ref, types.ImageRemoveOptions{PruneChildren: true})
+	if err != nil {
+		return xerrors.Errorf("Unable to remove image %s: %w", ref, err)
+	}
+
+	return nil
+}
+
+// ImageExists returns true when the image is in the local Docker cache
+func (d *DockerTasks) ImageExists(ctx context.Context, ref string) (bool, error) {
+	args := filters.NewArgs()
+	args.Add("reference", ref)
+
+	sum, err := d.c.ImageList(ctx, types.ImageListOptions{Filters: args})
+	if err != nil {
+		return false, xerrors.Errorf("unable to list images in local Docker cache: %w", err)
+	}
+
+	return len(sum) > 0, nil
+}
+
+// InspectImage returns the details of an image in the local Docker cache, the
+// digest is taken from the repo digests matching the repository of ref
+func (d *DockerTasks) InspectImage(ctx context.Context, ref string) (*config.ImageInfo, error) {
+	i, _, err := d.c.ImageInspectWithRaw(ctx, ref)
 	if err != nil {
-		return fmt.Errorf("Couldn't read kubeconfig from container\n%+v", err)
+		return nil, xerrors.Errorf("unable to inspect image %s: %w", ref, err)
+	}
+
+	info := &config.ImageInfo{
+		Name:         ref,
+		ID:           i.ID,
+		Architecture: i.Architecture,
+		OS:           i.Os,
+		Size:         i.Size,
+		Created:      i.Created,
+	}
+
+	if i.Config != nil {
+		info.Labels = i.Config.Labels
 	}
 
-	// write to file, skipping the first 512 bytes which contain file metadata
-	// and trimming any NULL characters
-	trimBytes := bytes.Trim(readBytes[512:], "\x00")
+	repo := ref
+	if n := strings.LastIndex(ref, ":"); n > strings.LastIndex(ref, "/") {
+		repo = ref[:n]
+	}
+
+	for _, rd := range i.RepoDigests {
+		parts := strings.SplitN(rd, "@", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		// repo digests for Docker Hub images do not contain the registry
+		if parts[0] == repo || strings.TrimPrefix(parts[0], "docker.io/") == strings.TrimPrefix(repo, "docker.io/") || info.Digest == "" {
+			info.Digest = parts[1]
+		}
+	}
+
+	return info, nil
+}
+
+// ExportVolume writes the contents of the named volume to w as a tar archive,
+// the volume is read using a temporary container which mounts the volume
+func (d *DockerTasks) ExportVolume(ctx context.Context, name string, w io.Writer) error {
+	d.l.Debug("Exporting volume", "volume", name)
+
+	id, err := d.createVolumeContainer(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer d.RemoveContainer(context.Background(), id)
+
+	r, _, err := d.c.CopyFromContainer(ctx, id, volumeContainerPath)
+	if err != nil {
+		return xerrors.Errorf("Unable to read volume %s: %w", name, err)
+	}
+	defer r.Close()
+
+	_, err = io.Copy(w, r)
+	if err != nil {
+		return xerrors.Errorf("Unable to write volume %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// ImportVolume restores the contents of the named volume from a tar archive
+// created by ExportVolume, the volume is created if it does not exist
+func (d *DockerTasks) ImportVolume(ctx context.Context, name string, r io.Reader) error {
+	d.l.Debug("Importing volume", "volume", name)
 
-	file, err := os.Create(dst)
+	id, err := d.createVolumeContainer(ctx, name)
 	if err != nil {
-		return fmt.Errorf("Couldn't create file %s\n%+v", dst, err)
+		return err
 	}
+	defer d.RemoveContainer(context.Background(), id)
 
-	defer file.Close()
-	file.Write(trimBytes)
+	// the archive contains the volume folder so it is extracted to the root
+	err = d.c.CopyToContainer(ctx, id, path.Dir(volumeContainerPath), r, types.CopyToContainerOptions{})
+	if err != nil {
+		return xerrors.Errorf("Unable to write volume %s: %w", name, err)
+	}
 
 	return nil
 }
 
+// volumeContainerPath is the path the volume is mounted at by createVolumeContainer
+const volumeContainerPath = "/volume"
+
+// createVolumeContainer starts a temporary container with the named volume
+// mounted at volumeContainerPath
+func (d *DockerTasks) createVolumeContainer(ctx context.Context, name string) (string, error) {
+	err := d.PullImage(ctx, config.Image{Name: "alpine:latest"}, false)
+	if err != nil {
+		return "", xerrors.Errorf("Unable pull alpine:latest for copying volume: %w", err)
+	}
+
+	cc := config.NewContainer(fmt.Sprintf("%d.volume", time.Now().Nanosecond()))
+	cc.Image = config.Image{Name: "alpine:latest"}
+	cc.Volumes = []config.Volume{
+		config.Volume{
+			Source:      name,
+			Destination: volumeContainerPath,
+			Type:        "volume",
+		},
+	}
+	cc.Command = []string{"tail", "-f", "/dev/null"}
+
+	id, err := d.CreateContainer(ctx, cc)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to create container for copying volume %s: %w", name, err)
+	}
+
+	return id, nil
+}
+
+// createArchive writes the file or directory src to a tar archive, the root of
+// src is renamed to name
+func createArchive(src, name string, w io.Writer) error {
+	ta := tar.NewWriter(w)
+
+	err := filepath.Walk(src, func(file string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if fi.Mode()&os.ModeSymlink != 0 {
+			link, err = os.Readlink(file)
+			if err != nil {
+				return err
+			}
+		}
+
+		hdr, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+
+		// archive paths always use forward slashes
+		hdr.Name = path.Join(name, filepath.ToSlash(rel))
+		if fi.IsDir() {
+			hdr.Name += "/"
+		}
+
+		err = ta.WriteHeader(hdr)
+		if err != nil {
+			return err
+		}
+
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(ta, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return ta.Close()
+}
+
+// extractArchive extracts a tar archive returned by the Docker API to dst,
+// the root entry of the archive is renamed to dst
+func extractArchive(r io.Reader, dst string) error {
+	tr := tar.NewReader(r)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		// strip the root folder or file name from the entry
+		rel := ""
+		if parts := strings.SplitN(strings.TrimPrefix(hdr.Name, "/"), "/", 2); len(parts) == 2 {
+			rel = parts[1]
+		}
+
+		target := filepath.Join(dst, filepath.FromSlash(rel))
+		if !insideDir(dst, target) {
+			return xerrors.Errorf("Archive entry %s is outside of the destination", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, os.FileMode(hdr.Mode)|0700)
+		case tar.TypeReg:
+			err = writeArchiveFile(tr, target, os.FileMode(hdr.Mode))
+		case tar.TypeSymlink:
+			// later entries could be written through a link which leaves
+			// the destination
+			if !linkInsideDir(dst, target, hdr.Linkname) {
+				return xerrors.Errorf("Archive entry %s links to %s which is outside of the destination", hdr.Name, hdr.Linkname)
+			}
+
+			err = os.MkdirAll(filepath.Dir(target), os.ModePerm)
+			if err != nil {
+				return err
+			}
+
+			os.Remove(target)
+			err = os.Symlink(hdr.Linkname, target)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// insideDir returns true when path is dir or is contained in dir
+func insideDir(dir, path string) bool {
+	dir = filepath.Clean(dir)
+	return path == dir || strings.HasPrefix(path, dir+string(os.PathSeparator))
+}
+
+// linkInsideDir returns true when a symlink at target pointing to link
+// resolves to a path in dir, links which have already been extracted are
+// followed so that a chain of links can not be used to leave dir
+func linkInsideDir(dir, target, link string) bool {
+	if filepath.IsAbs(link) {
+		return false
+	}
+
+	if r, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = r
+	}
+
+	p := filepath.Dir(target)
+	if r, err := filepath.EvalSymlinks(p); err == nil {
+		p = r
+	}
+
+	for _, part := range strings.Split(filepath.ToSlash(link), "/") {
+		switch part {
+		case "", ".":
+		case "..":
+			p = filepath.Dir(p)
+		default:
+			p = filepath.Join(p, part)
+			if r, err := filepath.EvalSymlinks(p); err == nil {
+				p = r
+			}
+		}
+
+		if !insideDir(dir, p) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func writeArchiveFile(r io.Reader, file string, mode os.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(file), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, r)
+	return err
+}
+
 // CopyLocalDockerImageToVolume writes multiple Docker images to a Docker volume as a compressed archive
 // returns the filename of the archive and an error if one occured
-func (d *DockerTasks) CopyLocalDockerImageToVolume(images []string, volume string, force bool) ([]string, error) {
+func (d *DockerTasks) CopyLocalDockerImageToVolume(ctx context.Context, images []string, volume string, force bool) ([]string, error) {
 	d.l.Debug("Writing docker images to volume", "images", images, "volume", volume)
 
 	savedImages := []string{}
 
 	// make sure we have the alpine image needed to copy
-	err := d.PullImage(config.Image{Name: "alpine:latest"}, false)
+	err := d.PullImage(ctx, config.Image{Name: "alpine:latest"}, false)
 	if err != nil {
 		return nil, xerrors.Errorf("Unable pull alpine:latest for importing images: %w", err)
 	}
@@ -420,11 +1297,11 @@ func (d *DockerTasks) CopyLocalDockerImageToVolume(images []string, volume strin
 	}
 	cc.Command = []string{"tail", "-f", "/dev/null"}
 
-	tmpID, err := d.CreateContainer(cc)
+	tmpID, err := d.CreateContainer(ctx, cc)
 	if err != nil {
 		return nil, xerrors.Errorf("unable to create dummy container for importing images: %w", err)
 	}
-	defer d.RemoveContainer(tmpID)
+	defer d.RemoveContainer(context.Background(), tmpID)
 
 	// add each image individually
 	for _, i := range images {
@@ -432,7 +1309,7 @@ func (d *DockerTasks) CopyLocalDockerImageToVolume(images []string, volume strin
 
 		// check if the image exists if we are not doing a forced update
 		if !d.force && !force {
-			err := d.ExecuteCommand(tmpID, []string{"find", "/images/" + compressedImageName}, nil, "/", nil)
+			err := d.ExecuteCommand(ctx, tmpID, []string{"find", "/images/" + compressedImageName}, nil, "/", nil)
 			if err == nil {
 				// we have the image already
 				d.l.Debug("Image already cached", "image", i)
@@ -444,7 +1321,7 @@ func (d *DockerTasks) CopyLocalDockerImageToVolume(images []string, volume strin
 		d.l.Debug("Copying image to container", "image", i)
 
 		// save the image to a local temp file
-		ir, err := d.c.ImageSave(context.Background(), []string{i})
+		ir, err := d.c.ImageSave(ctx, []string{i})
 		if err != nil {
 			return nil, xerrors.Errorf("unable to save images: %w", err)
 		}
@@ -519,7 +1396,7 @@ func (d *DockerTasks) CopyLocalDockerImageToVolume(images []string, volume strin
 		// reset the file seek so we can copy to the container
 		tmpTarFile.Seek(0, 0)
 
-		err = d.c.CopyToContainer(context.Background(), utils.FQDN(cc.Name, string(cc.Type)), "/images", tmpTarFile, types.CopyToContainerOptions{})
+		err = d.c.CopyToContainer(ctx, utils.FQDN(cc.Name, string(cc.Type)), "/images", tmpTarFile, types.CopyToContainerOptions{})
 		if err != nil {
 			return nil, xerrors.Errorf("unable to copy file to container: %w", err)
 		}
@@ -531,12 +1408,22 @@ func (d *DockerTasks) CopyLocalDockerImageToVolume(images []string, volume strin
 	return savedImages, nil
 }
 
+// ExecError is returned by ExecuteCommand when the command exits with a non
+// zero exit code
+type ExecError struct {
+	ExitCode int
+}
+
+func (e *ExecError) Error() string {
+	return fmt.Sprintf("container exec failed with exit code %d", e.ExitCode)
+}
+
 // ExecuteCommand allows the execution of commands in a running docker container
 // id is the id of the container to execute the command in
 // command is a slice of strings to execute
 // writer [optional] will be used to write any output from the command execution.
-func (d *DockerTasks) ExecuteCommand(id string, command []string, env []string, workingDir string, writer io.Writer) error {
-	execid, err := d.c.ContainerExecCreate(context.Background(), id, types.ExecConfig{
+func (d *DockerTasks) ExecuteCommand(ctx context.Context, id string, command []string, env []string, workingDir string, writer io.Writer) error {
+	execid, err := d.c.ContainerExecCreate(ctx, id, types.ExecConfig{
 		Cmd:          command,
 		AttachStdout: true,
 		AttachStderr: true,
@@ -549,14 +1436,14 @@ func (d *DockerTasks) ExecuteCommand(id string, command []string, env []string,
 	}
 
 	// get logs from an attach
-	stream, err := d.c.ContainerExecAttach(context.Background(), execid.ID, types.ExecStartCheck{})
+	stream, err := d.c.ContainerExecAttach(ctx, execid.ID, types.ExecStartCheck{})
 	if err != nil {
 		return xerrors.Errorf("unable to attach logging to exec process: %w", err)
 	}
 
 	defer stream.Close()
 
-	streamContext, cancelStream := context.WithCancel(context.Background())
+	streamContext, cancelStream := context.WithCancel(ctx)
 	// if we have a writer stream the logs from the container to the writer
 	if writer != nil {
 
@@ -582,7 +1469,7 @@ func (d *DockerTasks) ExecuteCommand(id string, command []string, env []string,
 		}
 	}
 
-	err = d.c.ContainerExecStart(context.Background(), execid.ID, types.ExecStartCheck{})
+	err = d.c.ContainerExecStart(ctx, execid.ID, types.ExecStartCheck{})
 	if err != nil {
 		cancelStream()
 		return xerrors.Errorf("unable to start exec process: %w", err)
@@ -590,7 +1477,7 @@ func (d *DockerTasks) ExecuteCommand(id string, command []string, env []string,
 
 	// loop until the container finishes execution
 	for {
-		i, err := d.c.ContainerExecInspect(context.Background(), execid.ID)
+		i, err := d.c.ContainerExecInspect(ctx, execid.ID)
 		if err != nil {
 			cancelStream()
 			return xerrors.Errorf("unable to determine status of exec process: %w", err)
@@ -603,7 +1490,7 @@ func (d *DockerTasks) ExecuteCommand(id string, command []string, env []string,
 			}
 
 			cancelStream()
-			return xerrors.Errorf("container exec failed with exit code %d", i.ExitCode)
+			return &ExecError{ExitCode: i.ExitCode}
 		}
 
 		time.Sleep(1 * time.Second)
@@ -614,8 +1501,8 @@ func (d *DockerTasks) ExecuteCommand(id string, command []string, env []string,
 
 // CreateShell creates an interactive shell inside a container
 // https://github.com/docker/cli/blob/ae1618713f83e7da07317d579d0675f578de22fa/cli/command/container/exec.go
-func (d *DockerTasks) CreateShell(id string, command []string, stdin io.ReadCloser, stdout io.Writer, stderr io.Writer) error {
-	execid, err := d.c.ContainerExecCreate(context.Background(), id, types.ExecConfig{
+func (d *DockerTasks) CreateShell(ctx context.Context, id string, command []string, stdin io.ReadCloser, stdout io.Writer, stderr io.Writer) error {
+	execid, err := d.c.ContainerExecCreate(ctx, id, types.ExecConfig{
 		Cmd:          command,
 		WorkingDir:   "/",
 		AttachStdin:  true,
@@ -628,12 +1515,12 @@ func (d *DockerTasks) CreateShell(id string, command []string, stdin io.ReadClos
 		return xerrors.Errorf("unable to create container exec: %w", err)
 	}
 
-	// err = d.c.ContainerExecStart(context.Background(), execid.ID, types.ExecStartCheck{})
+	// err = d.c.ContainerExecStart(ctx, execid.ID, types.ExecStartCheck{})
 	// if err != nil {
 	// 	return xerrors.Errorf("unable to start exec process: %w", err)
 	// }
 
-	resp, err := d.c.ContainerExecAttach(context.Background(), execid.ID, types.ExecStartCheck{Tty: true})
+	resp, err := d.c.ContainerExecAttach(ctx, execid.ID, types.ExecStartCheck{Tty: true})
 	if err != nil {
 		return err
 	}
@@ -647,7 +1534,7 @@ func (d *DockerTasks) CreateShell(id string, command []string, stdin io.ReadClos
 
 	errCh := make(chan error, 1)
 
-	streamContext, streamCancel := context.WithCancel(context.Background())
+	streamContext, streamCancel := context.WithCancel(ctx)
 
 	go func() {
 		defer close(errCh)
@@ -660,20 +1547,20 @@ func (d *DockerTasks) CreateShell(id string, command []string, stdin io.ReadClos
 	}()
 
 	// init the TTY
-	d.initTTY(execid.ID, ttyOut)
+	d.initTTY(ctx, execid.ID, ttyOut)
 
 	// monitor for TTY changes
 	sigchan := make(chan os.Signal, 1)
 	gosignal.Notify(sigchan, signal.SIGWINCH)
 	go func() {
 		for range sigchan {
-			d.resizeTTY(execid.ID, ttyOut)
+			d.resizeTTY(ctx, execid.ID, ttyOut)
 		}
 	}()
 
 	// loop until the container finishes execution
 	for {
-		i, err := d.c.ContainerExecInspect(context.Background(), execid.ID)
+		i, err := d.c.ContainerExecInspect(ctx, execid.ID)
 		if err != nil {
 			streamCancel()
 			return xerrors.Errorf("unable to determine status of exec process: %w", err)
@@ -686,20 +1573,20 @@ func (d *DockerTasks) CreateShell(id string, command []string, stdin io.ReadClos
 			}
 
 			streamCancel()
-			return xerrors.Errorf("container exec failed with exit code %d", i.ExitCode)
+			return &ExecError{ExitCode: i.ExitCode}
 		}
 
 		time.Sleep(1 * time.Second)
 	}
 }
 
-func (d *DockerTasks) initTTY(id string, out *streams.Out) error {
-	if err := d.resizeTTY(id, out); err != nil {
+func (d *DockerTasks) initTTY(ctx context.Context, id string, out *streams.Out) error {
+	if err := d.resizeTTY(ctx, id, out); err != nil {
 		go func() {
 			var err error
 			for retry := 0; retry < 5; retry++ {
 				time.Sleep(10 * time.Millisecond)
-				if err = d.resizeTTY(id, out); err == nil {
+				if err = d.resizeTTY(ctx, id, out); err == nil {
 					break
 				}
 			}
@@ -713,7 +1600,7 @@ func (d *DockerTasks) initTTY(id string, out *streams.Out) error {
 	return nil
 }
 
-func (d *DockerTasks) resizeTTY(id string, out *streams.Out) error {
+func (d *DockerTasks) resizeTTY(ctx context.Context, id string, out *streams.Out) error {
 	h, w := out.GetTtySize()
 
 	if h == 0 && w == 0 {
@@ -726,7 +1613,7 @@ func (d *DockerTasks) resizeTTY(id string, out *streams.Out) error {
 	}
 
 	// resize the contiainer
-	err := d.c.ContainerExecResize(context.Background(), id, options)
+	err := d.c.ContainerExecResize(ctx, id, options)
 	if err != nil {
 		return err
 	}
@@ -738,9 +1625,9 @@ func (d *DockerTasks) resizeTTY(id string, out *streams.Out) error {
 // TODO: Docker returns success before removing a container
 // tasks which depend on the network being removed may fail in the future
 // we need to check it has been removed before returning
-func (d *DockerTasks) DetachNetwork(network, containerid string) error {
+func (d *DockerTasks) DetachNetwork(ctx context.Context, network, containerid string) error {
 	network = strings.Replace(network, "network.", "", -1)
-	err := d.c.NetworkDisconnect(context.Background(), network, containerid, true)
+	err := d.c.NetworkDisconnect(ctx, network, containerid, true)
 
 	// Hacky hack for now
 	//time.Sleep(1000 * time.Millisecond)
@@ -748,14 +1635,119 @@ func (d *DockerTasks) DetachNetwork(network, containerid string) error {
 	return err
 }
 
+// AttachNetwork connects a running container to a network
+func (d *DockerTasks) AttachNetwork(ctx context.Context, net, containerid string) error {
+	net = strings.Replace(net, "network.", "", -1)
+	err := d.c.NetworkConnect(ctx, net, containerid, &network.EndpointSettings{NetworkID: net})
+
+	// connecting a container which is already attached is not an error
+	if err != nil && strings.Contains(err.Error(), "already exists") {
+		return nil
+	}
+
+	return err
+}
+
+// impairmentImage contains the iproute2 tools used to apply network impairments,
+// the version is pinned so that the tools do not change between runs
+const impairmentImage = "nicolaka/netshoot:v0.11"
+
+// impairNetwork applies traffic shaping to the interface of the container which
+// is attached to the given subnet. tc is run from a privileged helper container
+// which shares the network namespace of the target so that the target image
+// does not need to contain any networking tools
+func (d *DockerTasks) impairNetwork(ctx context.Context, id, subnet string, i *config.NetworkImpairment) error {
+	if subnet == "" {
+		return xerrors.Errorf("network impairment requires the subnet of the network to be set")
+	}
+
+	d.l.Debug("Applying network impairment", "container", id, "subnet", subnet, "latency", i.Latency, "packet_loss", i.PacketLoss, "bandwidth", i.Bandwidth)
+
+	err := d.PullImage(ctx, config.Image{Name: impairmentImage}, false)
+	if err != nil {
+		return xerrors.Errorf("Unable to pull %s for network impairment: %w", impairmentImage, err)
+	}
+
+	dc := &container.Config{
+		Image:      impairmentImage,
+		Entrypoint: []string{"tail", "-f", "/dev/null"},
+	}
+
+	hc := &container.HostConfig{
+		NetworkMode: container.NetworkMode(fmt.Sprintf("container:%s", id)),
+		Privileged:  true,
+	}
+
+	tmp, err := d.c.ContainerCreate(ctx, dc, hc, nil, fmt.Sprintf("%d.impairment", time.Now().Nanosecond()))
+	if err != nil {
+		return xerrors.Errorf("Unable to create network impairment container: %w", err)
+	}
+	defer d.RemoveContainer(context.Background(), tmp.ID)
+
+	err = d.c.ContainerStart(ctx, tmp.ID, types.ContainerStartOptions{})
+	if err != nil {
+		return xerrors.Errorf("Unable to start network impairment container: %w", err)
+	}
+
+	// find the interface which routes to the subnet and add a netem qdisc to it
+	script := fmt.Sprintf(
+		"dev=$(ip route show %s | awk '{print $3}') && tc qdisc add dev $dev root netem %s",
+		subnet,
+		strings.Join(netemArgs(i), " "),
+	)
+
+	return d.ExecuteCommand(ctx, tmp.ID, []string{"sh", "-c", script}, nil, "/", nil)
+}
+
+// netemArgs converts a NetworkImpairment into arguments for tc netem
+func netemArgs(i *config.NetworkImpairment) []string {
+	args := []string{}
+
+	if i.Latency != "" {
+		args = append(args, "delay", i.Latency)
+
+		if i.Jitter != "" {
+			args = append(args, i.Jitter)
+		}
+	}
+
+	if i.PacketLoss > 0 {
+		args = append(args, "loss", fmt.Sprintf("%g%%", i.PacketLoss))
+	}
+
+	if i.Bandwidth != "" {
+		args = append(args, "rate", i.Bandwidth)
+	}
+
+	return args
+}
+
 // publishedPorts defines a Docker published port
 type publishedPorts struct {
 	ExposedPorts map[nat.Port]struct{}
 	PortBindings map[nat.Port][]nat.PortBinding
 }
 
-// createPublishedPorts converts a list of config.Port to Docker publishedPorts type
-func createPublishedPorts(ps []config.Port) publishedPorts {
+// dualStack returns true when the container is attached to a network which
+// has an IPv6 subnet
+func dualStack(c *config.Container) bool {
+	for _, n := range c.Networks {
+		net, err := c.FindDependentResource(n.Name)
+		if err != nil {
+			continue
+		}
+
+		if nc, ok := net.(*config.Network); ok && nc.SubnetIPv6 != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// createPublishedPorts converts a list of config.Port to Docker publishedPorts type,
+// when ipv6 is set host ports are also bound to the IPv6 interfaces
+func createPublishedPorts(ps []config.Port, ipv6 bool) publishedPorts {
 	pp := publishedPorts{
 		ExposedPorts: make(map[nat.Port]struct{}, 0),
 		PortBindings: make(map[nat.Port][]nat.PortBinding, 0),
@@ -772,6 +1764,11 @@ func createPublishedPorts(ps []config.Port) publishedPorts {
 			},
 		}
 
+		// a random host port would differ between the interfaces
+		if ipv6 && p.Host != "" {
+			pb = append(pb, nat.PortBinding{HostIP: "::", HostPort: p.Host})
+		}
+
 		pp.PortBindings[dp] = pb
 	}
 
@@ -787,6 +1784,43 @@ func createRegistryAuth(username, password string) string {
 	)
 }
 
+// registryAuthFromConfig returns the encoded credentials for the registry hosting
+// the image from the Docker CLI config, credential helpers are used when configured.
+// An empty string is returned when there are no credentials for the registry
+func registryAuthFromConfig(image, configDir string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", xerrors.Errorf("unable to parse image name %s: %w", image, err)
+	}
+
+	// the Docker CLI stores credentials for the Docker Hub under the legacy index address
+	host := reference.Domain(named)
+	if host == "docker.io" {
+		host = "https://index.docker.io/v1/"
+	}
+
+	cf, err := dockerconfig.Load(configDir)
+	if err != nil {
+		return "", xerrors.Errorf("unable to load Docker config: %w", err)
+	}
+
+	ac, err := cf.GetAuthConfig(host)
+	if err != nil {
+		return "", xerrors.Errorf("unable to get credentials for %s: %w", host, err)
+	}
+
+	if ac.Username == "" && ac.IdentityToken == "" && ac.RegistryToken == "" {
+		return "", nil
+	}
+
+	d, err := json.Marshal(ac)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.URLEncoding.EncodeToString(d), nil
+}
+
 // makeImageCanonical makes sure the image reference uses full canonical name i.e.
 // consul:1.6.1 -> docker.io/library/consul:1.6.1
 func makeImageCanonical(image string) string {
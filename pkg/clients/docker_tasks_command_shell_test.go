@@ -3,6 +3,7 @@ package clients
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"io/ioutil"
 	"net"
 	"testing"
@@ -40,7 +41,7 @@ func TestCreateShellCreatesExec(t *testing.T) {
 	out := ioutil.Discard
 	errW := ioutil.Discard
 
-	err := p.CreateShell("abc", []string{"sh"}, in, out, errW)
+	err := p.CreateShell(context.Background(), "abc", []string{"sh"}, in, out, errW)
 	assert.NoError(t, err)
 
 	md.AssertCalled(t, "ContainerExecCreate", mock.Anything, "abc", mock.Anything)
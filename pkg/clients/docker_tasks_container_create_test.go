@@ -1,8 +1,10 @@
 package clients
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"strings"
 	"testing"
@@ -80,6 +82,7 @@ func setupContainerMocks() (*clients.MockDocker, *clients.ImageLog) {
 		ioutil.NopCloser(strings.NewReader("hello world")),
 		nil,
 	)
+	md.On("Info", mock.Anything).Return(types.Info{OSType: "linux", Architecture: "x86_64"}, nil)
 	md.On("ContainerCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 		Return(container.ContainerCreateCreatedBody{ID: "test"}, nil)
 	md.On("ContainerStart", mock.Anything, mock.Anything, mock.Anything).Return(nil)
@@ -101,7 +104,7 @@ func setupContainer(t *testing.T, cc *config.Container, md *clients.MockDocker,
 	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
 
 	// create the container
-	_, err := p.CreateContainer(cc)
+	_, err := p.CreateContainer(context.Background(), cc)
 
 	return err
 }
@@ -131,6 +134,18 @@ func TestContainerCreatesCorrectly(t *testing.T) {
 	assert.True(t, cfg.AttachStderr)
 }
 
+func TestContainerAddsConfigHashLabel(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	_, err := p.CreateContainer(WithConfigHash(context.Background(), "abc123"), cc)
+	assert.NoError(t, err)
+
+	cfg := getCalls(&md.Mock, "ContainerCreate")[0].Arguments[1].(*container.Config)
+	assert.Equal(t, "abc123", cfg.Labels["run.shipyard.config_hash"])
+}
+
 func TestContainerRemovesBridgeBeforeAttachingToUserNetwork(t *testing.T) {
 	cc, _, _, md, mic := createContainerConfig()
 
@@ -234,6 +249,19 @@ func TestContainerAssignsIPToUserNetwork(t *testing.T) {
 	assert.Equal(t, cc.Networks[0].IPAddress, nc.IPAMConfig.IPv4Address)
 }
 
+func TestContainerAssignsIPv6ToUserNetwork(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+	cc.Networks[0].IPv6Address = "2001:db8::10"
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "NetworkConnect")[0].Arguments
+	nc := params[3].(*network.EndpointSettings)
+
+	assert.Equal(t, cc.Networks[0].IPv6Address, nc.IPAMConfig.IPv6Address)
+}
+
 func TestContainerAssignsAliasesToUserNetwork(t *testing.T) {
 	cc, _, _, md, mic := createContainerConfig()
 	cc.Networks[0].Aliases = []string{"abc", "123"}
@@ -247,6 +275,98 @@ func TestContainerAssignsAliasesToUserNetwork(t *testing.T) {
 	assert.Equal(t, cc.Networks[0].Aliases, nc.Aliases)
 }
 
+func setupImpairmentMocks(md *clients.MockDocker) {
+	md.On("ContainerExecCreate", mock.Anything, mock.Anything, mock.Anything).Return(types.IDResponse{ID: "abc"}, nil)
+	md.On("ContainerExecAttach", mock.Anything, "abc", mock.Anything).Return(types.HijackedResponse{Conn: &net.TCPConn{}}, nil)
+	md.On("ContainerExecStart", mock.Anything, "abc", mock.Anything).Return(nil)
+	md.On("ContainerExecInspect", mock.Anything, "abc", mock.Anything).Return(types.ContainerExecInspect{Running: false, ExitCode: 0}, nil)
+}
+
+func TestContainerAppliesNetworkImpairmentFromAttachment(t *testing.T) {
+	cc, cn, _, md, mic := createContainerConfig()
+	setupImpairmentMocks(md)
+
+	cc.Networks = []config.NetworkAttachment{
+		config.NetworkAttachment{
+			Name:       "network.testnet",
+			Impairment: &config.NetworkImpairment{Latency: "100ms", PacketLoss: 1, Bandwidth: "1mbit"},
+		},
+	}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	// helper container should share the network of the target
+	params := getCalls(&md.Mock, "ContainerCreate")[1].Arguments
+	hc := params[2].(*container.HostConfig)
+	assert.Equal(t, "container:test", string(hc.NetworkMode))
+	assert.True(t, hc.Privileged)
+
+	// the helper image is pinned to a version
+	dc := params[1].(*container.Config)
+	assert.Equal(t, impairmentImage, dc.Image)
+	assert.NotContains(t, dc.Image, ":latest")
+
+	ec := getCalls(&md.Mock, "ContainerExecCreate")[0].Arguments[2].(types.ExecConfig)
+	assert.Contains(t, ec.Cmd[2], cn.Subnet)
+	assert.Contains(t, ec.Cmd[2], "netem delay 100ms loss 1% rate 1mbit")
+
+	// helper should be removed
+	md.AssertNumberOfCalls(t, "ContainerRemove", 1)
+}
+
+func TestContainerAppliesNetworkImpairmentFromNetwork(t *testing.T) {
+	cc, _, wn, md, mic := createContainerConfig()
+	setupImpairmentMocks(md)
+
+	wn.Impairment = &config.NetworkImpairment{Latency: "50ms", Jitter: "5ms"}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	md.AssertNumberOfCalls(t, "ContainerExecCreate", 1)
+
+	ec := getCalls(&md.Mock, "ContainerExecCreate")[0].Arguments[2].(types.ExecConfig)
+	assert.Contains(t, ec.Cmd[2], wn.Subnet)
+	assert.Contains(t, ec.Cmd[2], "netem delay 50ms 5ms")
+}
+
+func TestContainerDoesNotApplyNetworkImpairmentWhenNotSet(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	md.AssertNumberOfCalls(t, "ContainerCreate", 1)
+	md.AssertNotCalled(t, "ContainerExecCreate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestContainerReturnsErrorWhenImpairmentOnNetworkWithoutSubnet(t *testing.T) {
+	cc, _, wn, md, mic := createContainerConfig()
+
+	wn.Subnet = ""
+	wn.Impairment = &config.NetworkImpairment{Latency: "50ms"}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.Error(t, err)
+
+	// the container is removed when the impairment can not be applied
+	md.AssertCalled(t, "ContainerRemove", mock.Anything, "test", mock.Anything)
+}
+
+func TestContainerReturnsRollbackErrorWhenImpairmentFailsAndRemoveFails(t *testing.T) {
+	cc, _, wn, md, mic := createContainerConfig()
+	removeOn(&md.Mock, "ContainerRemove")
+	md.On("ContainerRemove", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("remove failed"))
+
+	wn.Subnet = ""
+	wn.Impairment = &config.NetworkImpairment{Latency: "50ms"}
+
+	err := setupContainer(t, cc, md, mic)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "remove failed")
+}
+
 func TestContainerRollsbackWhenUnableToConnectToWANNetwork(t *testing.T) {
 	cc, _, _, md, mic := createContainerConfig()
 	removeOn(&md.Mock, "NetworkConnect")
@@ -330,6 +450,45 @@ func TestContainerPublishesPorts(t *testing.T) {
 	assert.Equal(t, "0.0.0.0", hc.PortBindings[exp][0].HostIP)
 }
 
+func TestContainerPublishesPortsOnIPv6ForDualStackNetwork(t *testing.T) {
+	cc, cn, _, md, mic := createContainerConfig()
+	cn.SubnetIPv6 = "2001:db8::/64"
+	cc.Ports = append(cc.Ports, config.Port{Local: "8082", Protocol: "tcp"})
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ContainerCreate")[0].Arguments
+	hc := params[2].(*container.HostConfig)
+
+	exp, err := nat.NewPort(cc.Ports[0].Protocol, cc.Ports[0].Local)
+	assert.NoError(t, err)
+
+	assert.Len(t, hc.PortBindings[exp], 2)
+	assert.Equal(t, "0.0.0.0", hc.PortBindings[exp][0].HostIP)
+	assert.Equal(t, "::", hc.PortBindings[exp][1].HostIP)
+	assert.Equal(t, cc.Ports[0].Host, hc.PortBindings[exp][1].HostPort)
+
+	// random host ports are only published on IPv4
+	exp, err = nat.NewPort(cc.Ports[2].Protocol, cc.Ports[2].Local)
+	assert.NoError(t, err)
+	assert.Len(t, hc.PortBindings[exp], 1)
+}
+
+func TestContainerDoesNotPublishPortsOnIPv6ForIPv4Network(t *testing.T) {
+	cc, _, _, md, mic := createContainerConfig()
+
+	err := setupContainer(t, cc, md, mic)
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ContainerCreate")[0].Arguments
+	hc := params[2].(*container.HostConfig)
+
+	exp, err := nat.NewPort(cc.Ports[0].Protocol, cc.Ports[0].Local)
+	assert.NoError(t, err)
+	assert.Len(t, hc.PortBindings[exp], 1)
+}
+
 // removeOn is a utility function for removing Expectations from mock objects
 func removeOn(m *mock.Mock, method string) {
 	ec := m.ExpectedCalls
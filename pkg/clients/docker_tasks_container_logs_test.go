@@ -2,6 +2,7 @@ package clients
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"testing"
@@ -22,7 +23,7 @@ func TestContainerLogsCalled(t *testing.T) {
 
 	dt := NewDockerTasks(md, mic, hclog.NewNullLogger())
 
-	rc, err := dt.ContainerLogs("123", true, true)
+	rc, err := dt.ContainerLogs(context.Background(), "123", true, true)
 	assert.NotNil(t, rc)
 	assert.Error(t, err)
 }
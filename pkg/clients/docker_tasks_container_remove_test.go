@@ -1,13 +1,16 @@
 package clients
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
 	clients "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -18,7 +21,7 @@ func TestContainerRemoveCallsRemoveGently(t *testing.T) {
 
 	md.On("ContainerRemove", mock.Anything, "test", types.ContainerRemoveOptions{Force: false, RemoveVolumes: true}).Return(nil)
 
-	dt.RemoveContainer("test")
+	dt.RemoveContainer(context.Background(), "test")
 
 	md.AssertNumberOfCalls(t, "ContainerRemove", 1)
 }
@@ -31,8 +34,43 @@ func TestContainerRemoveFailsCallsRemoveForcefully(t *testing.T) {
 	md.On("ContainerRemove", mock.Anything, "test", types.ContainerRemoveOptions{Force: false, RemoveVolumes: true}).Return(fmt.Errorf("boom"))
 	md.On("ContainerRemove", mock.Anything, "test", types.ContainerRemoveOptions{Force: true, RemoveVolumes: true}).Return(nil)
 
-	dt.RemoveContainer("test")
+	dt.RemoveContainer(context.Background(), "test")
 	md.AssertCalled(t, "ContainerRemove", mock.Anything, "test", types.ContainerRemoveOptions{Force: true, RemoveVolumes: true})
 
 	md.AssertNumberOfCalls(t, "ContainerRemove", 2)
 }
+
+func TestStopContainerCallsStopWithTimeout(t *testing.T) {
+	md := &mocks.MockDocker{}
+	dt := NewDockerTasks(md, &clients.ImageLog{}, hclog.NewNullLogger())
+
+	timeout := 10 * time.Second
+	md.On("ContainerStop", mock.Anything, "test", &timeout).Return(nil)
+
+	err := dt.StopContainer(context.Background(), "test", timeout)
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ContainerStop", mock.Anything, "test", &timeout)
+}
+
+func TestStopContainerReturnsErrorOnFail(t *testing.T) {
+	md := &mocks.MockDocker{}
+	dt := NewDockerTasks(md, &clients.ImageLog{}, hclog.NewNullLogger())
+
+	md.On("ContainerStop", mock.Anything, "test", mock.Anything).Return(fmt.Errorf("boom"))
+
+	err := dt.StopContainer(context.Background(), "test", time.Second)
+	assert.Error(t, err)
+}
+
+func TestStartContainerCallsStart(t *testing.T) {
+	md := &mocks.MockDocker{}
+	dt := NewDockerTasks(md, &clients.ImageLog{}, hclog.NewNullLogger())
+
+	md.On("ContainerStart", mock.Anything, "test", types.ContainerStartOptions{}).Return(nil)
+
+	err := dt.StartContainer(context.Background(), "test")
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ContainerStart", mock.Anything, "test", types.ContainerStartOptions{})
+}
@@ -0,0 +1,158 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const containerStatsJSON = `{
+	"name": "/consul.container.shipyard.run",
+	"cpu_stats": {"cpu_usage": {"total_usage": 400}, "system_cpu_usage": 2000, "online_cpus": 2},
+	"precpu_stats": {"cpu_usage": {"total_usage": 200}, "system_cpu_usage": 1000},
+	"memory_stats": {"usage": 300, "limit": 1000, "stats": {"cache": 100}},
+	"networks": {"eth0": {"rx_bytes": 10, "tx_bytes": 20}, "eth1": {"rx_bytes": 5, "tx_bytes": 5}}
+}
+`
+
+func setupContainerStatsTests(body io.Reader, err error) (*DockerTasks, *mocks.MockDocker) {
+	md := &mocks.MockDocker{}
+	md.On("ContainerStats", mock.Anything, mock.Anything, mock.Anything).Return(
+		types.ContainerStats{Body: ioutil.NopCloser(body)},
+		err,
+	)
+
+	return NewDockerTasks(md, &mocks.ImageLog{}, hclog.NewNullLogger()), md
+}
+
+func TestContainerStatsCalculatesUsage(t *testing.T) {
+	dt, md := setupContainerStatsTests(bytes.NewBufferString(containerStatsJSON), nil)
+
+	s, err := dt.ContainerStats(context.Background(), "123")
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ContainerStats", mock.Anything, "123", false)
+
+	assert.Equal(t, "consul.container.shipyard.run", s.Name)
+	assert.Equal(t, float64(40), s.CPUPercentage)
+	assert.Equal(t, uint64(200), s.MemoryUsage)
+	assert.Equal(t, float64(20), s.MemoryPercentage)
+	assert.Equal(t, uint64(15), s.NetworkRx)
+	assert.Equal(t, uint64(25), s.NetworkTx)
+}
+
+func TestContainerStatsReturnsErrorOnFail(t *testing.T) {
+	dt, _ := setupContainerStatsTests(bytes.NewBufferString(""), fmt.Errorf("boom"))
+
+	_, err := dt.ContainerStats(context.Background(), "123")
+	assert.Error(t, err)
+}
+
+func TestContainerStatsUsesInactiveFileForCgroupV2(t *testing.T) {
+	s := containerStatsFromJSON("123", types.StatsJSON{
+		Stats: types.Stats{
+			MemoryStats: types.MemoryStats{Usage: 300, Limit: 1000, Stats: map[string]uint64{"inactive_file": 50}},
+		},
+	})
+
+	assert.Equal(t, uint64(250), s.MemoryUsage)
+	assert.Equal(t, float64(0), s.CPUPercentage)
+}
+
+func TestStreamContainerStatsSendsEachSample(t *testing.T) {
+	dt, md := setupContainerStatsTests(bytes.NewBufferString(containerStatsJSON+containerStatsJSON), nil)
+
+	stats := make(chan config.ContainerStats, 2)
+	err := dt.StreamContainerStats(context.Background(), "123", stats, make(chan struct{}))
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ContainerStats", mock.Anything, "123", true)
+	assert.Len(t, stats, 2)
+}
+
+func TestStreamContainerStatsReturnsWhenStopped(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+
+	dt, _ := setupContainerStatsTests(r, nil)
+
+	stop := make(chan struct{})
+	stats := make(chan config.ContainerStats)
+	done := make(chan error)
+
+	go func() {
+		done <- dt.StreamContainerStats(context.Background(), "123", stats, stop)
+	}()
+
+	go w.Write([]byte(containerStatsJSON))
+	<-stats
+
+	close(stop)
+	// the mock does not close the body when the context is cancelled
+	r.CloseWithError(fmt.Errorf("cancelled"))
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for stream to stop")
+	}
+}
+
+func TestDiskUsageReturnsUsagePerContainer(t *testing.T) {
+	md := &mocks.MockDocker{}
+	md.On("DiskUsage", mock.Anything).Return(
+		types.DiskUsage{
+			Images: []*types.ImageSummary{&types.ImageSummary{ID: "sha256:abc", Size: 1000}},
+			Containers: []*types.Container{
+				&types.Container{
+					ID:      "123",
+					Image:   "consul:1.8.0",
+					ImageID: "sha256:abc",
+					SizeRw:  50,
+					Mounts: []types.MountPoint{
+						types.MountPoint{Type: mount.TypeVolume, Name: "data"},
+						types.MountPoint{Type: mount.TypeBind, Source: "/tmp"},
+					},
+				},
+			},
+			Volumes: []*types.Volume{&types.Volume{Name: "data", UsageData: &types.VolumeUsageData{Size: 200}}},
+		},
+		nil,
+	)
+
+	dt := NewDockerTasks(md, &mocks.ImageLog{}, hclog.NewNullLogger())
+
+	du, err := dt.DiskUsage(context.Background())
+	assert.NoError(t, err)
+
+	assert.Equal(t, config.ContainerDiskUsage{
+		ID:           "123",
+		Image:        "consul:1.8.0",
+		ImageSize:    1000,
+		WritableSize: 50,
+		Volumes:      map[string]int64{"data": 200},
+	}, du["123"])
+}
+
+func TestDiskUsageReturnsErrorOnFail(t *testing.T) {
+	md := &mocks.MockDocker{}
+	md.On("DiskUsage", mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	dt := NewDockerTasks(md, &mocks.ImageLog{}, hclog.NewNullLogger())
+
+	_, err := dt.DiskUsage(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,70 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupContainerWaitTests(exitCode int64) (*DockerTasks, *mocks.MockDocker) {
+	resp := make(chan container.ContainerWaitOKBody, 1)
+	errs := make(chan error, 1)
+	resp <- container.ContainerWaitOKBody{StatusCode: exitCode}
+
+	// logs for containers without a TTY are multiplexed
+	logs := &bytes.Buffer{}
+	stdcopy.NewStdWriter(logs, stdcopy.Stdout).Write([]byte("migrating\n"))
+	stdcopy.NewStdWriter(logs, stdcopy.Stderr).Write([]byte("failed\n"))
+
+	md := &mocks.MockDocker{}
+	md.On("ContainerWait", mock.Anything, mock.Anything, mock.Anything).Return(resp, errs)
+	md.On("ContainerLogs", mock.Anything, mock.Anything, mock.Anything).Return(ioutil.NopCloser(logs), nil)
+
+	return NewDockerTasks(md, &mocks.ImageLog{}, hclog.NewNullLogger()), md
+}
+
+func TestWaitForExitReturnsExitCodeAndOutput(t *testing.T) {
+	dt, md := setupContainerWaitTests(2)
+
+	code, out, err := dt.WaitForExit(context.Background(), "abc", 1*time.Second)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, code)
+	assert.Equal(t, "migrating\nfailed\n", out)
+	md.AssertCalled(t, "ContainerWait", mock.Anything, "abc", container.WaitConditionNotRunning)
+}
+
+func TestWaitForExitReturnsErrorOnWaitError(t *testing.T) {
+	dt, md := setupContainerWaitTests(0)
+
+	errs := make(chan error, 1)
+	errs <- fmt.Errorf("boom")
+
+	removeOn(&md.Mock, "ContainerWait")
+	md.On("ContainerWait", mock.Anything, mock.Anything, mock.Anything).Return(make(chan container.ContainerWaitOKBody), errs)
+
+	_, _, err := dt.WaitForExit(context.Background(), "abc", 1*time.Second)
+	assert.Error(t, err)
+	md.AssertNotCalled(t, "ContainerLogs", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWaitForExitReturnsErrorWhenLogsFail(t *testing.T) {
+	dt, md := setupContainerWaitTests(1)
+
+	removeOn(&md.Mock, "ContainerLogs")
+	md.On("ContainerLogs", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	code, _, err := dt.WaitForExit(context.Background(), "abc", 1*time.Second)
+	assert.Error(t, err)
+	assert.Equal(t, 1, code)
+}
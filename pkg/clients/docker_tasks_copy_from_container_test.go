@@ -1,10 +1,15 @@
 package clients
 
 import (
+	"archive/tar"
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/docker/docker/api/types"
@@ -15,7 +20,28 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
-var fileContent = `kubeconfig.yam@@@@i@@@@@@@@@@@@@@@@@@@@@@2@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@lapiVersion: v1`
+// createTestArchive returns a tar archive in the format returned by the
+// Docker API containing the given files
+func createTestArchive(t *testing.T, files map[string]string) io.ReadCloser {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+
+	for name, content := range files {
+		if strings.HasSuffix(name, "/") {
+			tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeDir, Mode: 0755})
+			continue
+		}
+
+		err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))})
+		assert.NoError(t, err)
+
+		tw.Write([]byte(content))
+	}
+
+	tw.Close()
+
+	return ioutil.NopCloser(buf)
+}
 
 func TestCopyFromContainerCopiesFile(t *testing.T) {
 	id := "abc"
@@ -24,7 +50,7 @@ func TestCopyFromContainerCopiesFile(t *testing.T) {
 	md := &mocks.MockDocker{}
 	mic := &clients.ImageLog{}
 	md.On("CopyFromContainer", mock.Anything, id, src).Return(
-		ioutil.NopCloser(bytes.NewBufferString(fileContent)),
+		createTestArchive(t, map[string]string{"file.hcl": "apiVersion: v1"}),
 		types.ContainerPathStat{},
 		nil,
 	)
@@ -33,13 +59,133 @@ func TestCopyFromContainerCopiesFile(t *testing.T) {
 	tmpDir, _ := ioutil.TempDir("", "")
 	defer os.RemoveAll(tmpDir)
 
-	err := dt.CopyFromContainer(id, src, tmpDir+"/new.hcl")
+	err := dt.CopyFromContainer(context.Background(), id, src, tmpDir+"/new.hcl")
 	assert.NoError(t, err)
 
 	// check the file was written correctly
 	d, err := ioutil.ReadFile(tmpDir + "/new.hcl")
 	assert.NoError(t, err)
-	assert.Equal(t, ": v1", string(d))
+	assert.Equal(t, "apiVersion: v1", string(d))
+}
+
+func TestCopyFromContainerCopiesDirectory(t *testing.T) {
+	id := "abc"
+	src := "/output"
+
+	md := &mocks.MockDocker{}
+	mic := &clients.ImageLog{}
+	md.On("CopyFromContainer", mock.Anything, id, src).Return(
+		createTestArchive(t, map[string]string{
+			"output/":             "",
+			"output/sub/":         "",
+			"output/kubeconfig":   "apiVersion: v1",
+			"output/sub/file.hcl": "a = 1",
+		}),
+		types.ContainerPathStat{},
+		nil,
+	)
+	dt := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+
+	err := dt.CopyFromContainer(context.Background(), id, src, filepath.Join(tmpDir, "out"))
+	assert.NoError(t, err)
+
+	d, err := ioutil.ReadFile(filepath.Join(tmpDir, "out", "kubeconfig"))
+	assert.NoError(t, err)
+	assert.Equal(t, "apiVersion: v1", string(d))
+
+	d, err = ioutil.ReadFile(filepath.Join(tmpDir, "out", "sub", "file.hcl"))
+	assert.NoError(t, err)
+	assert.Equal(t, "a = 1", string(d))
+}
+
+func TestCopyFromContainerRejectsPathsOutsideDestination(t *testing.T) {
+	id := "abc"
+	src := "/output"
+
+	md := &mocks.MockDocker{}
+	mic := &clients.ImageLog{}
+	md.On("CopyFromContainer", mock.Anything, id, src).Return(
+		createTestArchive(t, map[string]string{"output/../../evil": "boom"}),
+		types.ContainerPathStat{},
+		nil,
+	)
+	dt := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+
+	err := dt.CopyFromContainer(context.Background(), id, src, filepath.Join(tmpDir, "out"))
+	assert.Error(t, err)
+}
+
+// createTestLinkArchive returns an archive containing the output folder and
+// the given symlinks
+func createTestLinkArchive(t *testing.T, links [][2]string) io.ReadCloser {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+
+	err := tw.WriteHeader(&tar.Header{Name: "output/", Typeflag: tar.TypeDir, Mode: 0755})
+	assert.NoError(t, err)
+
+	for _, l := range links {
+		err := tw.WriteHeader(&tar.Header{Name: l[0], Linkname: l[1], Typeflag: tar.TypeSymlink, Mode: 0777})
+		assert.NoError(t, err)
+	}
+
+	tw.Close()
+
+	return ioutil.NopCloser(buf)
+}
+
+func testCopyLinks(t *testing.T, links [][2]string) (string, error) {
+	md := &mocks.MockDocker{}
+	mic := &clients.ImageLog{}
+	md.On("CopyFromContainer", mock.Anything, "abc", "/output").Return(
+		createTestLinkArchive(t, links),
+		types.ContainerPathStat{},
+		nil,
+	)
+	dt := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	tmpDir, _ := ioutil.TempDir("", "")
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	dst := filepath.Join(tmpDir, "out")
+	return dst, dt.CopyFromContainer(context.Background(), "abc", "/output", dst)
+}
+
+func TestCopyFromContainerCreatesLinksInsideDestination(t *testing.T) {
+	dst, err := testCopyLinks(t, [][2]string{
+		{"output/current", "releases/v1"},
+		{"output/bin/app", "../current/app"},
+	})
+	assert.NoError(t, err)
+
+	l, err := os.Readlink(filepath.Join(dst, "current"))
+	assert.NoError(t, err)
+	assert.Equal(t, "releases/v1", l)
+}
+
+func TestCopyFromContainerRejectsAbsoluteLinks(t *testing.T) {
+	_, err := testCopyLinks(t, [][2]string{{"output/etc", "/etc"}})
+	assert.Error(t, err)
+}
+
+func TestCopyFromContainerRejectsLinksOutsideDestination(t *testing.T) {
+	_, err := testCopyLinks(t, [][2]string{{"output/etc", "../../../../../../etc"}})
+	assert.Error(t, err)
+}
+
+func TestCopyFromContainerRejectsLinksThroughOtherLinks(t *testing.T) {
+	// up resolves to the destination so up/.. is its parent
+	_, err := testCopyLinks(t, [][2]string{
+		{"output/sub/up", ".."},
+		{"output/escape", "sub/up/.."},
+	})
+	assert.Error(t, err)
 }
 
 func TestCopyFromContainerReturnsErrorOnDockerError(t *testing.T) {
@@ -55,6 +201,6 @@ func TestCopyFromContainerReturnsErrorOnDockerError(t *testing.T) {
 	)
 	dt := NewDockerTasks(md, mic, hclog.NewNullLogger())
 
-	err := dt.CopyFromContainer(id, src, "/new.hcl")
+	err := dt.CopyFromContainer(context.Background(), id, src, "/new.hcl")
 	assert.Error(t, err)
 }
@@ -3,6 +3,7 @@ package clients
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/base64"
 	"fmt"
 	"io/ioutil"
@@ -42,6 +43,7 @@ func testCreateCopyLocalMocks() *mocks.MockDocker {
 		ioutil.NopCloser(strings.NewReader("hello world")),
 		nil,
 	)
+	mk.On("Info", mock.Anything).Return(types.Info{OSType: "linux", Architecture: "x86_64"}, nil)
 
 	mk.On("ContainerExecCreate", mock.Anything, mock.Anything, mock.Anything).Return(types.IDResponse{ID: "abc"}, nil)
 	mk.On("ContainerExecAttach", mock.Anything, "abc", mock.Anything).Return(
@@ -67,7 +69,7 @@ func TestCopyLocalDoesNothingWhenCached(t *testing.T) {
 	mic.On("Log", mock.Anything, mock.Anything).Return(nil)
 	dt := NewDockerTasks(mk, mic, hclog.NewNullLogger())
 
-	_, err := dt.CopyLocalDockerImageToVolume(testCopyLocalImages, testCopyLocalVolume, false)
+	_, err := dt.CopyLocalDockerImageToVolume(context.Background(), testCopyLocalImages, testCopyLocalVolume, false)
 	assert.NoError(t, err)
 
 	args := types.ExecConfig{
@@ -92,7 +94,7 @@ func TestCopyLocalDoesNotChecksVolumeCacheWhenGlobalForce(t *testing.T) {
 	dt := NewDockerTasks(mk, mic, hclog.NewNullLogger())
 	dt.SetForcePull(true) // set force pull to avoid execute command block
 
-	_, err := dt.CopyLocalDockerImageToVolume(testCopyLocalImages, testCopyLocalVolume, false)
+	_, err := dt.CopyLocalDockerImageToVolume(context.Background(), testCopyLocalImages, testCopyLocalVolume, false)
 	assert.NoError(t, err)
 
 	mk.AssertNotCalled(t, "ContainerExecCreate", mock.Anything, mock.Anything, mock.Anything)
@@ -105,7 +107,7 @@ func TestCopyLocalDoesNotChecksVolumeCacheWhenLocalForce(t *testing.T) {
 	dt := NewDockerTasks(mk, mic, hclog.NewNullLogger())
 	dt.SetForcePull(false) // set force pull to avoid execute command block
 
-	_, err := dt.CopyLocalDockerImageToVolume(testCopyLocalImages, testCopyLocalVolume, true)
+	_, err := dt.CopyLocalDockerImageToVolume(context.Background(), testCopyLocalImages, testCopyLocalVolume, true)
 	assert.NoError(t, err)
 
 	mk.AssertNotCalled(t, "ContainerExecCreate", mock.Anything, mock.Anything, mock.Anything)
@@ -118,7 +120,7 @@ func TestCopyLocalSavesImages(t *testing.T) {
 	dt := NewDockerTasks(mk, mic, hclog.NewNullLogger())
 	dt.SetForcePull(true) // set force pull to avoid execute command block
 
-	_, err := dt.CopyLocalDockerImageToVolume(testCopyLocalImages, testCopyLocalVolume, false)
+	_, err := dt.CopyLocalDockerImageToVolume(context.Background(), testCopyLocalImages, testCopyLocalVolume, false)
 	assert.NoError(t, err)
 	mk.AssertCalled(t, "ImageSave", mock.Anything, testCopyLocalImages)
 }
@@ -135,7 +137,7 @@ func TestCopyLocalSavesImageFailReturnsError(t *testing.T) {
 	dt := NewDockerTasks(mk, mic, hclog.NewNullLogger())
 	dt.SetForcePull(true) // set force pull to avoid execute command block
 
-	_, err := dt.CopyLocalDockerImageToVolume(testCopyLocalImages, testCopyLocalVolume, false)
+	_, err := dt.CopyLocalDockerImageToVolume(context.Background(), testCopyLocalImages, testCopyLocalVolume, false)
 	assert.Error(t, err)
 }
 
@@ -146,7 +148,7 @@ func TestCopyLocalCreatesTempContainer(t *testing.T) {
 	dt := NewDockerTasks(mk, mic, hclog.NewNullLogger())
 	dt.SetForcePull(true) // set force pull to avoid execute command block
 
-	_, err := dt.CopyLocalDockerImageToVolume(testCopyLocalImages, testCopyLocalVolume, false)
+	_, err := dt.CopyLocalDockerImageToVolume(context.Background(), testCopyLocalImages, testCopyLocalVolume, false)
 	assert.NoError(t, err)
 
 	// ensure it mounts the volume
@@ -175,7 +177,7 @@ func TestCopyLocalTempContainerFailsReturnError(t *testing.T) {
 	dt := NewDockerTasks(mk, mic, hclog.NewNullLogger())
 	dt.SetForcePull(true) // set force pull to avoid execute command block
 
-	_, err := dt.CopyLocalDockerImageToVolume(testCopyLocalImages, testCopyLocalVolume, false)
+	_, err := dt.CopyLocalDockerImageToVolume(context.Background(), testCopyLocalImages, testCopyLocalVolume, false)
 	assert.Error(t, err)
 }
 
@@ -186,7 +188,7 @@ func TestCopyLocalPullsImportImage(t *testing.T) {
 	dt := NewDockerTasks(mk, mic, hclog.NewNullLogger())
 	dt.SetForcePull(true) // set force pull to avoid execute command block
 
-	_, err := dt.CopyLocalDockerImageToVolume(testCopyLocalImages, testCopyLocalVolume, false)
+	_, err := dt.CopyLocalDockerImageToVolume(context.Background(), testCopyLocalImages, testCopyLocalVolume, false)
 	assert.NoError(t, err)
 	mk.AssertCalled(t, "ImagePull", mock.Anything, makeImageCanonical("alpine:latest"), mock.Anything)
 }
@@ -198,7 +200,7 @@ func TestCopyLocalCopiesArchive(t *testing.T) {
 	dt := NewDockerTasks(mk, mic, hclog.NewNullLogger())
 	dt.SetForcePull(true) // set force pull to avoid execute command block
 
-	_, err := dt.CopyLocalDockerImageToVolume(testCopyLocalImages, testCopyLocalVolume, false)
+	_, err := dt.CopyLocalDockerImageToVolume(context.Background(), testCopyLocalImages, testCopyLocalVolume, false)
 	assert.NoError(t, err)
 	mk.AssertCalled(t, "CopyToContainer", mock.Anything, mock.Anything, "/images", mock.Anything, mock.Anything)
 }
@@ -213,7 +215,7 @@ func TestCopyLocalCopiesArchiveFailReturnsError(t *testing.T) {
 	dt := NewDockerTasks(mk, mic, hclog.NewNullLogger())
 	dt.SetForcePull(true) // set force pull to avoid execute command block
 
-	_, err := dt.CopyLocalDockerImageToVolume(testCopyLocalImages, testCopyLocalVolume, false)
+	_, err := dt.CopyLocalDockerImageToVolume(context.Background(), testCopyLocalImages, testCopyLocalVolume, false)
 	assert.Error(t, err)
 }
 
@@ -225,7 +227,7 @@ func TestCopyLocalRemovesTempContainer(t *testing.T) {
 	dt := NewDockerTasks(mk, mic, hclog.NewNullLogger())
 	dt.SetForcePull(true) // set force pull to avoid execute command block
 
-	_, err := dt.CopyLocalDockerImageToVolume(testCopyLocalImages, testCopyLocalVolume, false)
+	_, err := dt.CopyLocalDockerImageToVolume(context.Background(), testCopyLocalImages, testCopyLocalVolume, false)
 	assert.NoError(t, err)
 	mk.AssertCalled(t, "ContainerRemove", mock.Anything, mock.Anything, mock.Anything)
 }
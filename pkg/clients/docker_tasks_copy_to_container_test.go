@@ -0,0 +1,103 @@
+package clients
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	clients "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupCopyToContainer(t *testing.T) (string, func()) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	err := ioutil.WriteFile(tmpDir+"/script.sh", []byte("echo hello"), 0755)
+	assert.NoError(t, err)
+
+	return tmpDir + "/script.sh", func() {
+		os.RemoveAll(tmpDir)
+	}
+}
+
+func TestCopyToContainerCopiesArchive(t *testing.T) {
+	src, cleanup := setupCopyToContainer(t)
+	defer cleanup()
+
+	md := &mocks.MockDocker{}
+	mic := &clients.ImageLog{}
+	md.On("CopyToContainer", mock.Anything, "abc", "/tmp", mock.Anything, mock.Anything).Return(nil)
+	dt := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	err := dt.CopyToContainer(context.Background(), "abc", src, "/tmp/setup.sh")
+	assert.NoError(t, err)
+
+	// check the archive contains the file with the destination name
+	tr := tar.NewReader(getCalls(&md.Mock, "CopyToContainer")[0].Arguments[3].(io.Reader))
+	hdr, err := tr.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "setup.sh", hdr.Name)
+	assert.Equal(t, int64(0755), hdr.Mode&0777)
+
+	d, _ := ioutil.ReadAll(tr)
+	assert.Equal(t, "echo hello", string(d))
+}
+
+func TestCopyToContainerWithMissingFileReturnsError(t *testing.T) {
+	md := &mocks.MockDocker{}
+	mic := &clients.ImageLog{}
+	dt := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	err := dt.CopyToContainer(context.Background(), "abc", "/missing/script.sh", "/tmp/setup.sh")
+	assert.Error(t, err)
+}
+
+func TestCopyToContainerReturnsErrorOnDockerError(t *testing.T) {
+	src, cleanup := setupCopyToContainer(t)
+	defer cleanup()
+
+	md := &mocks.MockDocker{}
+	mic := &clients.ImageLog{}
+	md.On("CopyToContainer", mock.Anything, "abc", "/tmp", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+	dt := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	err := dt.CopyToContainer(context.Background(), "abc", src, "/tmp/setup.sh")
+	assert.Error(t, err)
+}
+
+func TestCopyToContainerCopiesDirectory(t *testing.T) {
+	src, cleanup := setupCopyToContainer(t)
+	defer cleanup()
+
+	dir := filepath.Dir(src)
+	os.MkdirAll(filepath.Join(dir, "sub"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "sub", "config.hcl"), []byte("a = 1"), 0644)
+
+	md := &mocks.MockDocker{}
+	mic := &clients.ImageLog{}
+	md.On("CopyToContainer", mock.Anything, "abc", "/etc", mock.Anything, mock.Anything).Return(nil)
+	dt := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	err := dt.CopyToContainer(context.Background(), "abc", dir, "/etc/app")
+	assert.NoError(t, err)
+
+	tr := tar.NewReader(getCalls(&md.Mock, "CopyToContainer")[0].Arguments[3].(io.Reader))
+	names := []string{}
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+
+		names = append(names, hdr.Name)
+	}
+
+	assert.Equal(t, []string{"app/", "app/script.sh", "app/sub/", "app/sub/config.hcl"}, names)
+}
@@ -3,6 +3,7 @@ package clients
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"net"
 	"testing"
@@ -13,6 +14,7 @@ import (
 	clients "github.com/shipyard-run/shipyard/pkg/clients/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/xerrors"
 )
 
 func testExecCommandMockSetup() (*mocks.MockDocker, *mocks.ImageLog) {
@@ -43,7 +45,7 @@ func TestExecuteCommandCreatesExec(t *testing.T) {
 	writer := bytes.NewBufferString("")
 
 	command := []string{"ls", "-las"}
-	err := md.ExecuteCommand("testcontainer", command, []string{"abc=123"}, "/files", writer)
+	err := md.ExecuteCommand(context.Background(), "testcontainer", command, []string{"abc=123"}, "/files", writer)
 	assert.NoError(t, err)
 
 	mk.AssertCalled(t, "ContainerExecCreate", mock.Anything, "testcontainer", mock.Anything)
@@ -68,7 +70,7 @@ func TestExecuteCommandExecFailReturnError(t *testing.T) {
 	writer := bytes.NewBufferString("")
 
 	command := []string{"ls", "-las"}
-	err := md.ExecuteCommand("testcontainer", command, nil, "/", writer)
+	err := md.ExecuteCommand(context.Background(), "testcontainer", command, nil, "/", writer)
 	assert.Error(t, err)
 }
 
@@ -78,7 +80,7 @@ func TestExecuteCommandAttachesToExec(t *testing.T) {
 	writer := bytes.NewBufferString("")
 
 	command := []string{"ls", "-las"}
-	err := md.ExecuteCommand("testcontainer", command, nil, "/", writer)
+	err := md.ExecuteCommand(context.Background(), "testcontainer", command, nil, "/", writer)
 	assert.NoError(t, err)
 
 	mk.AssertCalled(t, "ContainerExecAttach", mock.Anything, "abc", mock.Anything)
@@ -92,7 +94,7 @@ func TestExecuteCommandAttachFailReturnError(t *testing.T) {
 	writer := bytes.NewBufferString("")
 
 	command := []string{"ls", "-las"}
-	err := md.ExecuteCommand("testcontainer", command, nil, "/", writer)
+	err := md.ExecuteCommand(context.Background(), "testcontainer", command, nil, "/", writer)
 	assert.Error(t, err)
 }
 
@@ -102,7 +104,7 @@ func TestExecuteCommandStartsExec(t *testing.T) {
 	writer := bytes.NewBufferString("")
 
 	command := []string{"ls", "-las"}
-	err := md.ExecuteCommand("testcontainer", command, nil, "/", writer)
+	err := md.ExecuteCommand(context.Background(), "testcontainer", command, nil, "/", writer)
 	assert.NoError(t, err)
 
 	mk.AssertCalled(t, "ContainerExecStart", mock.Anything, "abc", mock.Anything)
@@ -116,7 +118,7 @@ func TestExecuteStartsFailReturnsError(t *testing.T) {
 	writer := bytes.NewBufferString("")
 
 	command := []string{"ls", "-las"}
-	err := md.ExecuteCommand("testcontainer", command, nil, "/", writer)
+	err := md.ExecuteCommand(context.Background(), "testcontainer", command, nil, "/", writer)
 	assert.Error(t, err)
 }
 
@@ -128,8 +130,12 @@ func TestExecuteCommandInspectsExecAndReturnsErrorOnFail(t *testing.T) {
 	writer := bytes.NewBufferString("")
 
 	command := []string{"ls", "-las"}
-	err := md.ExecuteCommand("testcontainer", command, nil, "/", writer)
+	err := md.ExecuteCommand(context.Background(), "testcontainer", command, nil, "/", writer)
 	assert.Error(t, err)
 
+	exErr := &ExecError{}
+	assert.True(t, xerrors.As(err, &exErr))
+	assert.Equal(t, 1, exErr.ExitCode)
+
 	mk.AssertCalled(t, "ContainerExecInspect", mock.Anything, "abc", mock.Anything)
 }
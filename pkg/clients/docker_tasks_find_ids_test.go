@@ -1,6 +1,7 @@
 package clients
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -23,7 +24,7 @@ func TestFindContainerIDsReturnsID(t *testing.T) {
 
 	dt := NewDockerTasks(md, nil, hclog.NewNullLogger())
 
-	ids, err := dt.FindContainerIDs("test", "cloud")
+	ids, err := dt.FindContainerIDs(context.Background(), "test", "cloud")
 	assert.NoError(t, err)
 
 	// assert that the docker api call was made
@@ -45,7 +46,7 @@ func TestFindContainerIDsReturnsErrorWhenDockerFail(t *testing.T) {
 
 	dt := NewDockerTasks(md, nil, hclog.NewNullLogger())
 
-	_, err := dt.FindContainerIDs("test", "cloud")
+	_, err := dt.FindContainerIDs(context.Background(), "test", "cloud")
 	assert.Error(t, err)
 }
 
@@ -55,7 +56,7 @@ func TestFindContainerIDsReturnsNilWhenNoIDs(t *testing.T) {
 
 	dt := NewDockerTasks(md, nil, hclog.NewNullLogger())
 
-	ids, err := dt.FindContainerIDs("test", "cloud")
+	ids, err := dt.FindContainerIDs(context.Background(), "test", "cloud")
 	assert.NoError(t, err)
 	assert.Nil(t, ids)
 }
@@ -66,7 +67,42 @@ func TestFindContainerIDsReturnsNilWhenEmpty(t *testing.T) {
 
 	dt := NewDockerTasks(md, nil, hclog.NewNullLogger())
 
-	ids, err := dt.FindContainerIDs("test", "cloud")
+	ids, err := dt.FindContainerIDs(context.Background(), "test", "cloud")
 	assert.NoError(t, err)
 	assert.Nil(t, ids)
 }
+
+func TestFindContainersReturnsStateAndConfigHash(t *testing.T) {
+	md := &mocks.MockDocker{}
+	md.On("ContainerList", mock.Anything, mock.Anything).Return(
+		[]types.Container{
+			types.Container{ID: "abc", State: "running", Labels: map[string]string{"run.shipyard.config_hash": "123"}},
+			types.Container{ID: "def", State: "exited"},
+		},
+		nil,
+	)
+
+	dt := NewDockerTasks(md, nil, hclog.NewNullLogger())
+
+	cs, err := dt.FindContainers(context.Background(), "test", "cloud")
+	assert.NoError(t, err)
+
+	// stopped containers are included
+	args := getCalls(&md.Mock, "ContainerList")[0].Arguments[1].(types.ContainerListOptions)
+	assert.True(t, args.All)
+
+	assert.Len(t, cs, 2)
+	assert.True(t, cs[0].Running)
+	assert.Equal(t, "123", cs[0].ConfigHash)
+	assert.False(t, cs[1].Running)
+}
+
+func TestFindContainersReturnsErrorWhenDockerFail(t *testing.T) {
+	md := &mocks.MockDocker{}
+	md.On("ContainerList", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	dt := NewDockerTasks(md, nil, hclog.NewNullLogger())
+
+	_, err := dt.FindContainers(context.Background(), "test", "cloud")
+	assert.Error(t, err)
+}
@@ -1,18 +1,26 @@
 package clients
 
 import (
+	"context"
 	"encoding/base64"
+	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/registry"
 	"github.com/hashicorp/go-hclog"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
 	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/xerrors"
 )
 
 func setupImagePullMocks() (*mocks.MockDocker, *mocks.ImageLog) {
@@ -22,6 +30,7 @@ func setupImagePullMocks() (*mocks.MockDocker, *mocks.ImageLog) {
 		ioutil.NopCloser(strings.NewReader("hello world")),
 		nil,
 	)
+	md.On("Info", mock.Anything).Return(types.Info{OSType: "linux", Architecture: "x86_64"}, nil)
 
 	mic := &mocks.ImageLog{}
 	mic.On("Log", mock.Anything, mock.Anything).Return(nil)
@@ -43,7 +52,7 @@ func setupImagePull(t *testing.T, cc config.Image, md *mocks.MockDocker, mic *mo
 	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
 
 	// create the container
-	err := p.PullImage(cc, force)
+	err := p.PullImage(context.Background(), cc, force)
 	assert.NoError(t, err)
 
 	return
@@ -64,6 +73,20 @@ func TestPullImageWhenNOTCached(t *testing.T) {
 	mic.AssertCalled(t, "Log", mock.Anything, mock.Anything)
 }
 
+func TestPullImageUsesContext(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	err := p.PullImage(ctx, cc, false)
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ImagePull", ctx, makeImageCanonical(cc.Name), types.ImagePullOptions{})
+}
+
 func TestPullImageWithCredentialsWhenNOTCached(t *testing.T) {
 	cc, md, mic := createImagePullConfig()
 	cc.Username = "nicjackson"
@@ -119,3 +142,261 @@ func TestPullImageAlwaysWhenForce(t *testing.T) {
 	md.AssertCalled(t, "ImagePull", mock.Anything, mock.Anything, mock.Anything)
 	mic.AssertCalled(t, "Log", mock.Anything, mock.Anything)
 }
+
+func TestPullImageDoesNotPullWhenOfflineAndForce(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+	removeOn(&md.Mock, "ImageList")
+	md.On("ImageList", mock.Anything, mock.Anything, mock.Anything).Return([]types.ImageSummary{types.ImageSummary{}}, nil)
+
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	err := p.PullImage(WithOffline(context.Background()), cc, true)
+	assert.NoError(t, err)
+
+	md.AssertNotCalled(t, "ImagePull", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPullImageReturnsErrorWhenOfflineAndNotCached(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	err := p.PullImage(WithOffline(context.Background()), cc, false)
+	assert.True(t, xerrors.Is(err, utils.OfflineError))
+
+	md.AssertNotCalled(t, "ImagePull", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func setupDockerConfigAuth(t *testing.T, registry string) func() {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	auth := base64.StdEncoding.EncodeToString([]byte("nicjackson:S3cur1t11"))
+	ioutil.WriteFile(
+		filepath.Join(dir, "config.json"),
+		[]byte(fmt.Sprintf(`{"auths": {"%s": {"auth": "%s"}}}`, registry, auth)),
+		os.ModePerm,
+	)
+
+	os.Setenv("DOCKER_CONFIG", dir)
+
+	return func() {
+		os.Unsetenv("DOCKER_CONFIG")
+		os.RemoveAll(dir)
+	}
+}
+
+func TestPullImageUsesCredentialsFromDockerConfig(t *testing.T) {
+	cleanup := setupDockerConfigAuth(t, "gcr.io")
+	defer cleanup()
+
+	cc, md, mic := createImagePullConfig()
+	cc.Name = "gcr.io/private/app:latest"
+
+	setupImagePull(t, cc, md, mic, false)
+
+	ipo := getCalls(&md.Mock, "ImagePull")[0].Arguments[2].(types.ImagePullOptions)
+	d, err := base64.URLEncoding.DecodeString(ipo.RegistryAuth)
+	assert.NoError(t, err)
+
+	assert.Contains(t, string(d), `"username":"nicjackson"`)
+	assert.Contains(t, string(d), `"password":"S3cur1t11"`)
+}
+
+func TestPullImageUsesDockerHubCredentialsFromDockerConfig(t *testing.T) {
+	cleanup := setupDockerConfigAuth(t, "https://index.docker.io/v1/")
+	defer cleanup()
+
+	cc, md, mic := createImagePullConfig()
+
+	setupImagePull(t, cc, md, mic, false)
+
+	ipo := getCalls(&md.Mock, "ImagePull")[0].Arguments[2].(types.ImagePullOptions)
+	assert.NotEmpty(t, ipo.RegistryAuth)
+}
+
+func TestPullImageDoesNotUseCredentialsForOtherRegistries(t *testing.T) {
+	cleanup := setupDockerConfigAuth(t, "gcr.io")
+	defer cleanup()
+
+	cc, md, mic := createImagePullConfig()
+	cc.Name = "quay.io/public/app:latest"
+
+	setupImagePull(t, cc, md, mic, false)
+
+	ipo := getCalls(&md.Mock, "ImagePull")[0].Arguments[2].(types.ImagePullOptions)
+	assert.Empty(t, ipo.RegistryAuth)
+}
+
+func TestPullImagePrefersResourceCredentialsOverDockerConfig(t *testing.T) {
+	cleanup := setupDockerConfigAuth(t, "https://index.docker.io/v1/")
+	defer cleanup()
+
+	cc, md, mic := createImagePullConfig()
+	cc.Username = "override"
+	cc.Password = "secret"
+
+	setupImagePull(t, cc, md, mic, false)
+
+	ipo := getCalls(&md.Mock, "ImagePull")[0].Arguments[2].(types.ImagePullOptions)
+	assert.Equal(t, createRegistryAuth(cc.Username, cc.Password), ipo.RegistryAuth)
+}
+
+func setupArm64Engine(md *mocks.MockDocker, platforms ...v1.Platform) {
+	removeOn(&md.Mock, "Info")
+	md.On("Info", mock.Anything).Return(types.Info{OSType: "linux", Architecture: "aarch64"}, nil)
+	md.On("DistributionInspect", mock.Anything, mock.Anything, mock.Anything).Return(
+		registry.DistributionInspect{Platforms: platforms},
+		nil,
+	)
+}
+
+func TestPullImageUsesEnginePlatformWhenImageSupportsIt(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+	setupArm64Engine(md, v1.Platform{OS: "linux", Architecture: "amd64"}, v1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"})
+
+	setupImagePull(t, cc, md, mic, false)
+
+	ipo := getCalls(&md.Mock, "ImagePull")[0].Arguments[2].(types.ImagePullOptions)
+	assert.Empty(t, ipo.Platform)
+}
+
+func TestPullImageFallsBackToAmd64WhenImageDoesNotSupportEnginePlatform(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+	setupArm64Engine(md, v1.Platform{OS: "linux", Architecture: "amd64"})
+
+	setupImagePull(t, cc, md, mic, false)
+
+	ipo := getCalls(&md.Mock, "ImagePull")[0].Arguments[2].(types.ImagePullOptions)
+	assert.Equal(t, "linux/amd64", ipo.Platform)
+}
+
+func TestPullImageReturnsErrorWhenNoPlatformIsAvailable(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+	setupArm64Engine(md, v1.Platform{OS: "linux", Architecture: "s390x"})
+
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+	err := p.PullImage(context.Background(), cc, false)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "linux/s390x")
+	md.AssertNotCalled(t, "ImagePull", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPullImageUsesPlatformOverride(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+	cc.Platform = "linux/amd64"
+	setupArm64Engine(md)
+
+	setupImagePull(t, cc, md, mic, false)
+
+	ipo := getCalls(&md.Mock, "ImagePull")[0].Arguments[2].(types.ImagePullOptions)
+	assert.Equal(t, "linux/amd64", ipo.Platform)
+	md.AssertNotCalled(t, "DistributionInspect", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPullImageWhenCachedImageIsForDifferentPlatform(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+	cc.Platform = "linux/amd64"
+
+	removeOn(&md.Mock, "ImageList")
+	md.On("ImageList", mock.Anything, mock.Anything, mock.Anything).Return([]types.ImageSummary{types.ImageSummary{}}, nil)
+	md.On("ImageInspectWithRaw", mock.Anything, cc.Name).Return(types.ImageInspect{Os: "linux", Architecture: "arm64"}, nil)
+
+	setupImagePull(t, cc, md, mic, false)
+
+	md.AssertCalled(t, "ImagePull", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPullImageNothingWhenCachedImageMatchesPlatform(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+	cc.Platform = "linux/arm64"
+
+	removeOn(&md.Mock, "ImageList")
+	md.On("ImageList", mock.Anything, mock.Anything, mock.Anything).Return([]types.ImageSummary{types.ImageSummary{}}, nil)
+	md.On("ImageInspectWithRaw", mock.Anything, cc.Name).Return(types.ImageInspect{Os: "linux", Architecture: "arm64"}, nil)
+
+	setupImagePull(t, cc, md, mic, false)
+
+	md.AssertNotCalled(t, "ImagePull", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestNormalizePlatformUsesManifestArchitectureNames(t *testing.T) {
+	assert.Equal(t, "linux/amd64", normalizePlatform("linux", "x86_64"))
+	assert.Equal(t, "linux/arm64", normalizePlatform("", "aarch64"))
+	assert.Equal(t, "linux/arm", normalizePlatform("linux", "armv7l"))
+	assert.True(t, platformMatches("linux/arm64/v8", "linux/arm64"))
+	assert.False(t, platformMatches("linux/arm64", "linux/amd64"))
+}
+
+func TestPullImageReportsProgress(t *testing.T) {
+	cc, _, mic := createImagePullConfig()
+
+	md := &mocks.MockDocker{}
+	md.On("ImageList", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	md.On("ImagePull", mock.Anything, mock.Anything, mock.Anything).Return(
+		ioutil.NopCloser(strings.NewReader(`
+{"status":"Pulling fs layer","id":"a"}
+{"status":"Downloading","progressDetail":{"current":50,"total":100},"id":"a"}
+{"status":"Downloading","progressDetail":{"current":100,"total":300},"id":"b"}
+{"status":"Download complete","id":"a"}
+{"status":"Extracting","progressDetail":{"current":10,"total":100},"id":"a"}
+`)),
+		nil,
+	)
+	md.On("Info", mock.Anything).Return(types.Info{OSType: "linux", Architecture: "x86_64"}, nil)
+
+	percent := []float64{}
+	ctx := WithPullProgress(context.Background(), func(p float64) {
+		percent = append(percent, p)
+	})
+
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+	err := p.PullImage(ctx, cc, false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []float64{50, 37.5, 50}, percent)
+}
+
+func TestPullImageUsesPullMirror(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+	md.On("ImageTag", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	md.On("ImageRemove", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	err := p.PullImage(WithPullMirror(context.Background(), "127.0.0.1:5050"), cc, false)
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ImagePull", mock.Anything, "127.0.0.1:5050/library/consul:1.6.1", types.ImagePullOptions{})
+	md.AssertNotCalled(t, "ImagePull", mock.Anything, makeImageCanonical(cc.Name), mock.Anything)
+
+	// the image is tagged with its Docker Hub name and the mirror tag removed
+	md.AssertCalled(t, "ImageTag", mock.Anything, "127.0.0.1:5050/library/consul:1.6.1", makeImageCanonical(cc.Name))
+	md.AssertCalled(t, "ImageRemove", mock.Anything, "127.0.0.1:5050/library/consul:1.6.1", types.ImageRemoveOptions{})
+	mic.AssertCalled(t, "Log", makeImageCanonical(cc.Name), ImageTypeDocker)
+}
+
+func TestPullImageFallsBackWhenPullMirrorFails(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+	md.On("ImageTag", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("No such image"))
+
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	err := p.PullImage(WithPullMirror(context.Background(), "127.0.0.1:5050"), cc, false)
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ImagePull", mock.Anything, makeImageCanonical(cc.Name), types.ImagePullOptions{})
+}
+
+func TestPullImageDoesNotUsePullMirrorForOtherRegistries(t *testing.T) {
+	cc, md, mic := createImagePullConfig()
+	cc.Name = "gcr.io/public/app:latest"
+
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	err := p.PullImage(WithPullMirror(context.Background(), "127.0.0.1:5050"), cc, false)
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ImagePull", mock.Anything, "gcr.io/public/app:latest", types.ImagePullOptions{})
+	md.AssertNumberOfCalls(t, "ImagePull", 1)
+}
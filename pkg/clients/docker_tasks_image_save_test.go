@@ -0,0 +1,129 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSaveImagesWritesArchive(t *testing.T) {
+	md, mic := setupContainerMocks()
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	md.On("ImageSave", mock.Anything, []string{"consul:1.8.1"}).Return(ioutil.NopCloser(bytes.NewBufferString("images")), nil)
+
+	out := &bytes.Buffer{}
+	err := p.SaveImages(context.Background(), []string{"consul:1.8.1"}, out)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "images", out.String())
+}
+
+func TestSaveImagesReturnsErrorWhenSaveFails(t *testing.T) {
+	md, mic := setupContainerMocks()
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	md.On("ImageSave", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	err := p.SaveImages(context.Background(), []string{"consul:1.8.1"}, &bytes.Buffer{})
+	assert.Error(t, err)
+}
+
+func TestLoadImagesReadsResponse(t *testing.T) {
+	md, mic := setupContainerMocks()
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	body := bytes.NewBufferString("loaded")
+	md.On("ImageLoad", mock.Anything, mock.Anything, true).Return(types.ImageLoadResponse{Body: ioutil.NopCloser(body)}, nil)
+
+	err := p.LoadImages(context.Background(), bytes.NewBufferString("images"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, body.Len())
+}
+
+func TestLoadImagesReturnsErrorWhenLoadFails(t *testing.T) {
+	md, mic := setupContainerMocks()
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	md.On("ImageLoad", mock.Anything, mock.Anything, true).Return(nil, fmt.Errorf("boom"))
+
+	err := p.LoadImages(context.Background(), bytes.NewBufferString("images"))
+	assert.Error(t, err)
+}
+
+func TestCommitContainerCreatesImage(t *testing.T) {
+	md, mic := setupContainerMocks()
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	md.On("ContainerCommit", mock.Anything, "abc", mock.Anything).Return(types.IDResponse{ID: "sha256:123"}, nil)
+
+	err := p.CommitContainer(context.Background(), "abc", "shipyard-checkpoint/consul:123")
+	assert.NoError(t, err)
+
+	opts := md.Calls[len(md.Calls)-1].Arguments.Get(2).(types.ContainerCommitOptions)
+	assert.Equal(t, "shipyard-checkpoint/consul:123", opts.Reference)
+	assert.True(t, opts.Pause)
+}
+
+func TestCommitContainerReturnsErrorWhenCommitFails(t *testing.T) {
+	md, mic := setupContainerMocks()
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	md.On("ContainerCommit", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	err := p.CommitContainer(context.Background(), "abc", "shipyard-checkpoint/consul:123")
+	assert.Error(t, err)
+}
+
+func TestImageExistsChecksLocalCache(t *testing.T) {
+	md, mic := setupContainerMocks()
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	removeOn(&md.Mock, "ImageList")
+	md.On("ImageList", mock.Anything, mock.Anything).Return([]types.ImageSummary{types.ImageSummary{}}, nil)
+
+	ok, err := p.ImageExists(context.Background(), "shipyard-checkpoint/consul:123")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestInspectImageReturnsDigestForRepository(t *testing.T) {
+	md, mic := setupContainerMocks()
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	removeOn(&md.Mock, "ImageInspectWithRaw")
+	md.On("ImageInspectWithRaw", mock.Anything, "consul:1.8.1").Return(types.ImageInspect{
+		ID:           "sha256:123",
+		Architecture: "amd64",
+		Os:           "linux",
+		Size:         1000,
+		RepoDigests:  []string{"myregistry.io/consul@sha256:def", "consul@sha256:abc"},
+	}, nil)
+
+	i, err := p.InspectImage(context.Background(), "consul:1.8.1")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "sha256:123", i.ID)
+	assert.Equal(t, "sha256:abc", i.Digest)
+	assert.Equal(t, "amd64", i.Architecture)
+	assert.Equal(t, int64(1000), i.Size)
+}
+
+func TestInspectImageReturnsErrorWhenNotFound(t *testing.T) {
+	md, mic := setupContainerMocks()
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	removeOn(&md.Mock, "ImageInspectWithRaw")
+	md.On("ImageInspectWithRaw", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("not found"))
+
+	_, err := p.InspectImage(context.Background(), "consul:1.8.1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,151 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	volumetypes "github.com/docker/docker/api/types/volume"
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupPruneTests() (*DockerTasks, *mocks.MockDocker) {
+	old := time.Now().Add(-48 * time.Hour)
+	recent := time.Now().Add(-1 * time.Minute)
+
+	md := &mocks.MockDocker{}
+	md.On("ContainerList", mock.Anything, mock.Anything).Return(
+		[]types.Container{
+			types.Container{ID: "old", Names: []string{"/old.container.shipyard.run"}, Created: old.Unix()},
+			types.Container{ID: "recent", Names: []string{"/recent.container.shipyard.run"}, Created: recent.Unix()},
+		},
+		nil,
+	)
+	md.On("ContainerRemove", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	md.On("ImageList", mock.Anything, mock.Anything).Return(
+		[]types.ImageSummary{
+			types.ImageSummary{ID: "sha256:old", Created: old.Unix()},
+			types.ImageSummary{ID: "sha256:recent", Created: recent.Unix()},
+		},
+		nil,
+	)
+	md.On("ImageRemove", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+
+	md.On("VolumeList", mock.Anything, mock.Anything).Return(
+		volumetypes.VolumeListOKBody{
+			Volumes: []*types.Volume{
+				&types.Volume{Name: "old.volume.shipyard.run", CreatedAt: old.Format(time.RFC3339)},
+				&types.Volume{Name: "recent.volume.shipyard.run", CreatedAt: recent.Format(time.RFC3339)},
+				&types.Volume{Name: "image-cache.volume.shipyard.run", CreatedAt: old.Format(time.RFC3339), Labels: map[string]string{persistentLabel: "true"}},
+			},
+		},
+		nil,
+	)
+	md.On("VolumeRemove", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	return NewDockerTasks(md, &mocks.ImageLog{}, hclog.NewNullLogger()), md
+}
+
+func TestPruneContainersRemovesStoppedContainersOlderThanAge(t *testing.T) {
+	dt, md := setupPruneTests()
+
+	ids, err := dt.PruneContainers(context.Background(), 24*time.Hour, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"old"}, ids)
+
+	opts := getCalls(&md.Mock, "ContainerList")[0].Arguments[1].(types.ContainerListOptions)
+	assert.True(t, opts.All)
+	assert.True(t, opts.Filters.ExactMatch("label", shipyardLabel))
+	assert.True(t, opts.Filters.ExactMatch("status", "exited"))
+
+	md.AssertCalled(t, "ContainerRemove", mock.Anything, "old", mock.Anything)
+	md.AssertNumberOfCalls(t, "ContainerRemove", 1)
+}
+
+func TestPruneContainersWithZeroAgeRemovesAll(t *testing.T) {
+	dt, md := setupPruneTests()
+
+	ids, err := dt.PruneContainers(context.Background(), 0, nil)
+	assert.NoError(t, err)
+	assert.Len(t, ids, 2)
+	md.AssertNumberOfCalls(t, "ContainerRemove", 2)
+}
+
+func TestPruneContainersDoesNotRemoveKeptContainers(t *testing.T) {
+	dt, md := setupPruneTests()
+
+	ids, err := dt.PruneContainers(context.Background(), 0, []string{"old.container.shipyard.run"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"recent"}, ids)
+
+	md.AssertNotCalled(t, "ContainerRemove", mock.Anything, "old", mock.Anything)
+}
+
+func TestPruneContainersReturnsErrorOnRemoveError(t *testing.T) {
+	dt, md := setupPruneTests()
+	removeOn(&md.Mock, "ContainerRemove")
+	md.On("ContainerRemove", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	_, err := dt.PruneContainers(context.Background(), 0, nil)
+	assert.Error(t, err)
+}
+
+func TestPruneImagesRemovesDanglingImagesOlderThanAge(t *testing.T) {
+	dt, md := setupPruneTests()
+
+	ids, err := dt.PruneImages(context.Background(), 24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sha256:old"}, ids)
+
+	opts := getCalls(&md.Mock, "ImageList")[0].Arguments[1].(types.ImageListOptions)
+	assert.True(t, opts.Filters.ExactMatch("dangling", "true"))
+	assert.True(t, opts.Filters.ExactMatch("label", shipyardLabel))
+}
+
+func TestPruneVolumesRemovesUnusedVolumesOlderThanAge(t *testing.T) {
+	dt, md := setupPruneTests()
+
+	names, err := dt.PruneVolumes(context.Background(), 24*time.Hour, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"old.volume.shipyard.run"}, names)
+
+	args := getCalls(&md.Mock, "VolumeList")[0].Arguments[1].(filters.Args)
+	assert.True(t, args.ExactMatch("dangling", "true"))
+
+	md.AssertCalled(t, "VolumeRemove", mock.Anything, "old.volume.shipyard.run", false)
+}
+
+func TestPruneVolumesDoesNotRemoveKeptOrPersistentVolumes(t *testing.T) {
+	dt, md := setupPruneTests()
+
+	names, err := dt.PruneVolumes(context.Background(), 0, []string{"old.volume.shipyard.run"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"recent.volume.shipyard.run"}, names)
+
+	md.AssertNotCalled(t, "VolumeRemove", mock.Anything, "image-cache.volume.shipyard.run", mock.Anything)
+}
+
+func TestPruneVolumesReturnsErrorOnListError(t *testing.T) {
+	dt, md := setupPruneTests()
+	removeOn(&md.Mock, "VolumeList")
+	md.On("VolumeList", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	_, err := dt.PruneVolumes(context.Background(), 0, nil)
+	assert.Error(t, err)
+}
+
+func TestRemoveImageRemovesImage(t *testing.T) {
+	dt, md := setupPruneTests()
+
+	err := dt.RemoveImage(context.Background(), "shipyard-checkpoint/container-consul:abc")
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "ImageRemove", mock.Anything, "shipyard-checkpoint/container-consul:abc", types.ImageRemoveOptions{PruneChildren: true})
+}
@@ -1,11 +1,16 @@
 package clients
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io/ioutil"
 	"testing"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/volume"
 	"github.com/hashicorp/go-hclog"
 	"github.com/stretchr/testify/assert"
@@ -21,7 +26,7 @@ func TestCreateVolumeDoesNothingWhenVolumeExists(t *testing.T) {
 	f.Add("name", "test.volume.shipyard.run")
 	md.On("VolumeList", mock.Anything, f).Return(volume.VolumeListOKBody{Volumes: []*types.Volume{&types.Volume{}}}, nil)
 
-	_, err := p.CreateVolume("test")
+	_, err := p.CreateVolume(context.Background(), "test")
 	assert.NoError(t, err)
 
 	md.AssertNotCalled(t, "VolumeCreate")
@@ -36,7 +41,7 @@ func TestCreateVolumeReturnsErrorWhenVolumeListError(t *testing.T) {
 	f.Add("name", "test.volume.shipyard.run")
 	md.On("VolumeList", mock.Anything, f).Return(nil, fmt.Errorf("Boom"))
 
-	_, err := p.CreateVolume("test")
+	_, err := p.CreateVolume(context.Background(), "test")
 	assert.Error(t, err)
 
 	md.AssertNotCalled(t, "VolumeCreate")
@@ -46,19 +51,80 @@ func TestCreateVolumeCreatesSuccesfully(t *testing.T) {
 	_, _, _, md, mic := createContainerConfig()
 	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
 
-	id, err := p.CreateVolume("test")
+	id, err := p.CreateVolume(context.Background(), "test")
 	assert.NoError(t, err)
 
 	md.AssertCalled(t, "VolumeCreate", mock.Anything, mock.Anything)
 	assert.Equal(t, "test_volume", id)
 }
 
+func TestCreateVolumeLabelsPersistentVolumes(t *testing.T) {
+	_, _, _, md, mic := createContainerConfig()
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	_, err := p.CreateVolume(WithPersistentVolume(context.Background()), "test")
+	assert.NoError(t, err)
+
+	opts := getCalls(&md.Mock, "VolumeCreate")[0].Arguments[1].(volume.VolumeCreateBody)
+	assert.Equal(t, "true", opts.Labels[persistentLabel])
+}
+
 func TestRemoveVolumeRemotesSuccesfully(t *testing.T) {
 	_, _, _, md, mic := createContainerConfig()
 	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
 
-	err := p.RemoveVolume("test")
+	err := p.RemoveVolume(context.Background(), "test")
 	assert.NoError(t, err)
 
 	md.AssertCalled(t, "VolumeRemove", mock.Anything, "test.volume.shipyard.run", true)
 }
+
+func TestExportVolumeWritesVolumeContents(t *testing.T) {
+	_, _, _, md, mic := createContainerConfig()
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	md.On("CopyFromContainer", mock.Anything, "test", "/volume").Return(
+		ioutil.NopCloser(bytes.NewBufferString("volume data")),
+		types.ContainerPathStat{},
+		nil,
+	)
+
+	out := &bytes.Buffer{}
+	err := p.ExportVolume(context.Background(), "data", out)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "volume data", out.String())
+
+	// check the volume is mounted in the temporary container
+	hc := getCalls(&md.Mock, "ContainerCreate")[0].Arguments[2].(*container.HostConfig)
+	assert.Equal(t, "data", hc.Mounts[0].Source)
+	assert.Equal(t, mount.TypeVolume, hc.Mounts[0].Type)
+
+	md.AssertCalled(t, "ContainerRemove", mock.Anything, "test", mock.Anything)
+}
+
+func TestExportVolumeReturnsErrorWhenCopyFails(t *testing.T) {
+	_, _, _, md, mic := createContainerConfig()
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	md.On("CopyFromContainer", mock.Anything, "test", "/volume").Return(nil, types.ContainerPathStat{}, fmt.Errorf("boom"))
+
+	err := p.ExportVolume(context.Background(), "data", &bytes.Buffer{})
+	assert.Error(t, err)
+
+	md.AssertCalled(t, "ContainerRemove", mock.Anything, "test", mock.Anything)
+}
+
+func TestImportVolumeCopiesArchiveToContainer(t *testing.T) {
+	_, _, _, md, mic := createContainerConfig()
+	p := NewDockerTasks(md, mic, hclog.NewNullLogger())
+
+	in := bytes.NewBufferString("volume data")
+	md.On("CopyToContainer", mock.Anything, "test", "/", in, mock.Anything).Return(nil)
+
+	err := p.ImportVolume(context.Background(), "data", in)
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "CopyToContainer", mock.Anything, "test", "/", in, mock.Anything)
+	md.AssertCalled(t, "ContainerRemove", mock.Anything, "test", mock.Anything)
+}
@@ -0,0 +1,61 @@
+package clients
+
+import (
+	"os"
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDockerConfigFromEnvReadsVariables(t *testing.T) {
+	os.Setenv("DOCKER_HOST", "tcp://10.0.0.1:2376")
+	os.Setenv("DOCKER_CERT_PATH", "/certs")
+	os.Setenv("DOCKER_TLS_VERIFY", "1")
+	defer os.Unsetenv("DOCKER_HOST")
+	defer os.Unsetenv("DOCKER_CERT_PATH")
+	defer os.Unsetenv("DOCKER_TLS_VERIFY")
+
+	c := DockerConfigFromEnv()
+
+	assert.Equal(t, "tcp://10.0.0.1:2376", c.Host)
+	assert.Equal(t, "/certs", c.CertPath)
+	assert.True(t, c.TLSVerify)
+}
+
+func TestNewDockerUsesTCPHost(t *testing.T) {
+	d, err := NewDocker(DockerConfig{Host: "tcp://10.0.0.1:2375", APIVersion: "1.40"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "tcp://10.0.0.1:2375", d.(*client.Client).DaemonHost())
+	assert.Equal(t, "1.40", d.(*client.Client).ClientVersion())
+}
+
+func TestNewDockerUsesSSHHelper(t *testing.T) {
+	d, err := NewDocker(DockerConfig{Host: "ssh://user@remote"})
+	assert.NoError(t, err)
+
+	// ssh connections are tunneled, requests are sent to a dummy host
+	assert.Equal(t, "http://docker", d.(*client.Client).DaemonHost())
+}
+
+func TestNewDockerReturnsErrorWhenCertsMissing(t *testing.T) {
+	_, err := NewDocker(DockerConfig{Host: "tcp://10.0.0.1:2376", CertPath: "/missing", TLSVerify: true})
+	assert.Error(t, err)
+}
+
+func TestNewDockerReturnsErrorWhenInvalidSSHHost(t *testing.T) {
+	_, err := NewDocker(DockerConfig{Host: "ssh://"})
+	assert.Error(t, err)
+}
+
+func TestDockerConfigFromEnvIgnoresContextWhenHostSet(t *testing.T) {
+	os.Setenv("DOCKER_HOST", "tcp://10.0.0.1:2376")
+	os.Setenv("DOCKER_CONTEXT", "remote")
+	defer os.Unsetenv("DOCKER_HOST")
+	defer os.Unsetenv("DOCKER_CONTEXT")
+
+	c := DockerConfigFromEnv()
+
+	assert.Equal(t, "", c.Context)
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/hashicorp/go-getter"
+	"github.com/shipyard-run/shipyard/pkg/utils"
 	"golang.org/x/xerrors"
 )
 
@@ -12,20 +13,31 @@ import (
 // downloading remote folders
 type Getter interface {
 	Get(uri, dst string) error
+	// Fetch downloads the file or folder at the uri into the Shipyard download
+	// cache and returns the local folder which contains it. Any source supported
+	// by go-getter can be used, e.g. git, http and s3, a checksum can be verified
+	// by adding ?checksum=type:value to the uri.
+	// Previously downloaded files are reused unless force is set
+	Fetch(uri string) (string, error)
 	SetForce(force bool)
+	// SetOffline stops the Getter downloading files, only files which have
+	// already been downloaded are used
+	SetOffline(offline bool)
 }
 
 // GetterImpl is a concrete implementation of the Getter interface
 type GetterImpl struct {
 	//
-	force bool
-	get   func(uri, dst, pwd string) error
+	force   bool
+	offline bool
+	get     func(uri, dst, pwd string) error
 }
 
 // NewGetter creates a new Getter
 func NewGetter(force bool) *GetterImpl {
 	gi := &GetterImpl{
 		force,
+		false,
 		func(uri, dst, pwd string) error {
 			// if the argument is a url fetch it first
 			c := &getter.Client{
@@ -49,11 +61,18 @@ func (g *GetterImpl) SetForce(force bool) {
 	g.force = force
 }
 
+// SetOffline sets the offline flag, when set to true existing destination
+// folders are always used and nothing is downloaded
+func (g *GetterImpl) SetOffline(offline bool) {
+	g.offline = offline
+}
+
 // Get attempts to retrieve a folder
 // from a remote location and stores it at the destination.
 //
 // If force was set to true when creating a Getter then
 // the destination folder will automatically be overwritten.
+// When offline is set only existing destination folders are used.
 //
 // Returns error on failure
 func (g *GetterImpl) Get(uri, dst string) error {
@@ -62,7 +81,8 @@ func (g *GetterImpl) Get(uri, dst string) error {
 	_, err := os.Stat(dst)
 	if err == nil {
 		// we already have files at the destination do we want to overwrite?
-		if g.force == false {
+		// in offline mode the cached files are always used
+		if g.force == false || g.offline {
 			return nil
 		}
 
@@ -72,6 +92,10 @@ func (g *GetterImpl) Get(uri, dst string) error {
 		}
 	}
 
+	if g.offline {
+		return xerrors.Errorf("%s has not been downloaded, load a bundle which contains it: %w", uri, utils.OfflineError)
+	}
+
 	pwd, err := os.Getwd()
 	if err != nil {
 		return err
@@ -84,3 +108,16 @@ func (g *GetterImpl) Get(uri, dst string) error {
 
 	return nil
 }
+
+// Fetch downloads the uri to the Shipyard download cache and returns the
+// local folder
+func (g *GetterImpl) Fetch(uri string) (string, error) {
+	dst := utils.GetDownloadLocalFolder(uri)
+
+	err := g.Get(uri, dst)
+	if err != nil {
+		return "", err
+	}
+
+	return dst, nil
+}
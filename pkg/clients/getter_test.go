@@ -1,13 +1,20 @@
 package clients
 
 import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strconv"
 	"testing"
 	"time"
 
+	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
 )
 
 func setupGetter(t *testing.T, force bool, err error) (string, Getter, *string, *string) {
@@ -66,3 +73,78 @@ func TestDoesGetsFolderWhenExistsAndForceTrue(t *testing.T) {
 	assert.Equal(t, *gs, "github.com/shipyard-run/blueprints//consul-nomad")
 	assert.Equal(t, *gd, outDir)
 }
+
+func TestDoesNotGetFolderWhenOfflineAndForceTrue(t *testing.T) {
+	tmpDir, g, gs, _ := setupGetter(t, true, nil)
+	defer os.RemoveAll(tmpDir)
+	g.SetOffline(true)
+	outDir := filepath.Join(tmpDir, "consul")
+	os.MkdirAll(outDir, os.ModePerm)
+
+	err := g.Get("github.com/shipyard-run/blueprints//consul-nomad", outDir)
+	assert.NoError(t, err)
+
+	assert.Equal(t, *gs, "")
+}
+
+func TestGetReturnsErrorWhenOfflineAndNotDownloaded(t *testing.T) {
+	tmpDir, g, gs, _ := setupGetter(t, false, nil)
+	defer os.RemoveAll(tmpDir)
+	g.SetOffline(true)
+
+	err := g.Get("github.com/shipyard-run/blueprints//consul-nomad", filepath.Join(tmpDir, "consul"))
+	assert.True(t, xerrors.Is(err, utils.OfflineError))
+
+	assert.Equal(t, *gs, "")
+}
+
+func TestFetchDownloadsToCacheFolder(t *testing.T) {
+	_, g, gs, gd := setupGetter(t, false, nil)
+
+	home := os.Getenv("HOME")
+	os.Setenv("HOME", os.TempDir())
+	defer os.Setenv("HOME", home)
+
+	uri := "https://example.com/manifests/app.yaml"
+
+	dir, err := g.Fetch(uri)
+	assert.NoError(t, err)
+
+	assert.Equal(t, utils.GetDownloadLocalFolder(uri), dir)
+	assert.Equal(t, uri, *gs)
+	assert.Equal(t, dir, *gd)
+}
+
+func TestFetchReturnsErrorWhenGetFails(t *testing.T) {
+	_, g, _, _ := setupGetter(t, false, fmt.Errorf("boom"))
+
+	home := os.Getenv("HOME")
+	os.Setenv("HOME", os.TempDir())
+	defer os.Setenv("HOME", home)
+
+	_, err := g.Fetch("https://example.com/manifests/app.yaml")
+	assert.Error(t, err)
+}
+
+func TestFetchVerifiesChecksum(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("kind: Namespace"))
+	}))
+	defer s.Close()
+
+	tmpDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(tmpDir)
+
+	home := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", home)
+
+	g := NewGetter(false)
+
+	dir, err := g.Fetch(s.URL + "/app.yaml?checksum=sha256:" + fmt.Sprintf("%x", sha256.Sum256([]byte("kind: Namespace"))))
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "app.yaml"))
+
+	_, err = g.Fetch(s.URL + "/app.yaml?checksum=sha256:" + fmt.Sprintf("%x", sha256.Sum256([]byte("invalid"))))
+	assert.Error(t, err)
+}
@@ -1,6 +1,8 @@
 package clients
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 
@@ -12,6 +14,8 @@ import (
 	"helm.sh/helm/v3/pkg/cli/values"
 	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
 )
 
 var helmLock sync.Mutex
@@ -22,8 +26,16 @@ func init() {
 }
 
 type Helm interface {
-	Create(kubeConfig, name, namespace, chartPath, valuesPath string, valuesString map[string]string) error
+	// Create installs the chart, ctx is checked before each step as the
+	// Helm client can not stop an install which has started
+	Create(ctx context.Context, kubeConfig, name, namespace, chartPath, valuesPath string, valuesString map[string]string) error
 	Destroy(kubeConfig, name, namespace string) error
+	// ReleaseExists returns true when the named release is deployed, releases
+	// which have been uninstalled, e.g. with the Helm CLI, do not exist
+	ReleaseExists(kubeConfig, name, namespace string) (bool, error)
+	// Manifest returns the Kubernetes manifest rendered for the deployed
+	// release
+	Manifest(kubeConfig, name, namespace string) (string, error)
 }
 
 type HelmImpl struct {
@@ -31,10 +43,10 @@ type HelmImpl struct {
 }
 
 func NewHelm(l hclog.Logger) Helm {
-	return &HelmImpl{l}
+	return &HelmImpl{log: l}
 }
 
-func (h *HelmImpl) Create(kubeConfig, name, namespace, chartPath, valuesPath string, valuesString map[string]string) error {
+func (h *HelmImpl) Create(ctx context.Context, kubeConfig, name, namespace, chartPath, valuesPath string, valuesString map[string]string) error {
 	// set the kubeclient for Helm
 	s := kube.GetConfig(kubeConfig, "default", namespace)
 	cfg := &action.Configuration{}
@@ -65,6 +77,10 @@ func (h *HelmImpl) Create(kubeConfig, name, namespace, chartPath, valuesPath str
 		vo.ValueFiles = []string{valuesPath}
 	}
 
+	if ctx.Err() != nil {
+		return xerrors.Errorf("Helm install cancelled: %w", ctx.Err())
+	}
+
 	h.log.Debug("Creating chart from config", "ref", name, "path", chartPath)
 	cp, err := client.ChartPathOptions.LocateChart(chartPath, &settings)
 	if err != nil {
@@ -88,6 +104,10 @@ func (h *HelmImpl) Create(kubeConfig, name, namespace, chartPath, valuesPath str
 		return xerrors.Errorf("Error validating chart: %w", err)
 	}
 
+	if ctx.Err() != nil {
+		return xerrors.Errorf("Helm install cancelled: %w", ctx.Err())
+	}
+
 	h.log.Debug("Run chart", "ref", name)
 	_, err = client.Run(chartRequested, vals)
 	if err != nil {
@@ -117,3 +137,54 @@ func (h *HelmImpl) Destroy(kubeConfig, name, namespace string) error {
 
 	return nil
 }
+
+// ReleaseExists returns true when the named release is deployed to the cluster
+func (h *HelmImpl) ReleaseExists(kubeConfig, name, namespace string) (bool, error) {
+	s := kube.GetConfig(kubeConfig, "default", namespace)
+	cfg := &action.Configuration{}
+	err := cfg.Init(s, namespace, "", func(format string, v ...interface{}) {
+		h.log.Debug("Helm debug message", "message", fmt.Sprintf(format, v...))
+	})
+
+	if err != nil {
+		return false, xerrors.Errorf("unable to initialize Helm: %w", err)
+	}
+
+	rels, err := cfg.Releases.History(name)
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, xerrors.Errorf("Unable to get history for release %s: %w", name, err)
+	}
+
+	for _, r := range rels {
+		if r.Info != nil && r.Info.Status == release.StatusDeployed {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Manifest returns the rendered manifest for the current revision of the
+// named release
+func (h *HelmImpl) Manifest(kubeConfig, name, namespace string) (string, error) {
+	s := kube.GetConfig(kubeConfig, "default", namespace)
+	cfg := &action.Configuration{}
+	err := cfg.Init(s, namespace, "", func(format string, v ...interface{}) {
+		h.log.Debug("Helm debug message", "message", fmt.Sprintf(format, v...))
+	})
+
+	if err != nil {
+		return "", xerrors.Errorf("unable to initialize Helm: %w", err)
+	}
+
+	rel, err := action.NewGet(cfg).Run(name)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to get release %s: %w", name, err)
+	}
+
+	return rel.Manifest, nil
+}
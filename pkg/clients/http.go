@@ -1,11 +1,23 @@
 package clients
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+	"k8s.io/client-go/util/jsonpath"
 )
 
 // HTTP defines an interface for a HTTP client
@@ -14,7 +26,16 @@ type HTTP interface {
 	// if a successful status 200 is returned the method returns a nil error.
 	// If it is not possible to contact the URI or if any status other than 200 is returned
 	// by the upstream, then the URI is retried until the timeout elapses.
-	HealthCheckHTTP(uri string, timeout time.Duration) error
+	HealthCheckHTTP(ctx context.Context, uri string, timeout time.Duration) error
+	// HealthCheckHTTPWithOptions performs a HTTP health check using the given options
+	// to set the expected status codes, retry backoff, request timeout and TLS settings.
+	// Options can also define checks for the response body, JSON values and the
+	// validity of the TLS certificate which must all pass for the check to succeed
+	HealthCheckHTTPWithOptions(ctx context.Context, uri string, options config.HTTPHealthCheck, timeout time.Duration) error
+	// HealthCheckTCP opens a TCP connection to the given address in the form
+	// host:port, the connection is retried until it succeeds or the timeout
+	// elapses
+	HealthCheckTCP(ctx context.Context, address string, timeout time.Duration) error
 	// Do executes a HTTP request and returns the response
 	Do(r *http.Request) (*http.Response, error)
 }
@@ -28,8 +49,46 @@ func NewHTTP(backoff time.Duration, l hclog.Logger) HTTP {
 	return &HTTPImpl{backoff, l}
 }
 
-func (h *HTTPImpl) HealthCheckHTTP(address string, timeout time.Duration) error {
+func (h *HTTPImpl) HealthCheckHTTP(ctx context.Context, address string, timeout time.Duration) error {
+	return h.HealthCheckHTTPWithOptions(ctx, address, config.HTTPHealthCheck{}, timeout)
+}
+
+// HealthCheckHTTPWithOptions makes HTTP GET requests to the given address until
+// one of the success codes is returned, the timeout elapses or ctx is cancelled
+func (h *HTTPImpl) HealthCheckHTTPWithOptions(ctx context.Context, address string, options config.HTTPHealthCheck, timeout time.Duration) error {
 	h.l.Debug("Performing health check for address", "address", address)
+
+	client, err := healthCheckClient(options)
+	if err != nil {
+		return err
+	}
+
+	codes := options.SuccessCodes
+	if len(codes) == 0 {
+		codes = []int{http.StatusOK}
+	}
+
+	backoff := h.backoff
+	if options.Backoff != "" {
+		backoff, err = time.ParseDuration(options.Backoff)
+		if err != nil {
+			return xerrors.Errorf("Invalid backoff %s: %w", options.Backoff, err)
+		}
+	}
+
+	checks, err := newHTTPResponseChecks(options)
+	if err != nil {
+		return err
+	}
+
+	maxBackoff := backoff
+	if options.MaxBackoff != "" {
+		maxBackoff, err = time.ParseDuration(options.MaxBackoff)
+		if err != nil {
+			return xerrors.Errorf("Invalid max_backoff %s: %w", options.MaxBackoff, err)
+		}
+	}
+
 	st := time.Now()
 	for {
 		if time.Now().Sub(st) > timeout {
@@ -38,18 +97,243 @@ func (h *HTTPImpl) HealthCheckHTTP(address string, timeout time.Duration) error
 			return fmt.Errorf("Timeout waiting for HTTP healthcheck %s", address)
 		}
 
-		resp, err := http.Get(address)
-		if err == nil && resp.StatusCode == 200 {
-			h.l.Debug("Health check complete", "address", address)
+		rq, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Do(rq)
+		if err == nil {
+			if !containsStatusCode(codes, resp.StatusCode) {
+				h.l.Debug("Unexpected status code for health check", "address", address, "status", resp.StatusCode)
+			} else if err := checks.check(resp); err != nil {
+				h.l.Debug("Health check failed", "address", address, "error", err)
+			} else {
+				resp.Body.Close()
+
+				h.l.Debug("Health check complete", "address", address)
+				return nil
+			}
+
+			resp.Body.Close()
+		}
+
+		// backoff, doubling the interval after each attempt up to the maximum
+		if err := wait(ctx, backoff); err != nil {
+			return xerrors.Errorf("HTTP healthcheck %s cancelled: %w", address, err)
+		}
+
+		backoff = backoff * 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// HealthCheckTCP opens TCP connections to the given address until one
+// succeeds, the timeout elapses or ctx is cancelled
+func (h *HTTPImpl) HealthCheckTCP(ctx context.Context, address string, timeout time.Duration) error {
+	d := net.Dialer{Timeout: h.backoff}
+
+	st := time.Now()
+	for {
+		conn, err := d.DialContext(ctx, "tcp", address)
+		if err == nil {
+			conn.Close()
+
+			h.l.Debug("TCP health check complete", "address", address)
 			return nil
 		}
 
-		// backoff
-		time.Sleep(h.backoff)
+		if time.Now().Sub(st) > timeout {
+			h.l.Error("Timeout wating for TCP healthcheck", "address", address)
+
+			return fmt.Errorf("Timeout waiting for TCP healthcheck %s: %s", address, err)
+		}
+
+		h.l.Debug("TCP health check failed", "address", address, "error", err)
+		if err := wait(ctx, h.backoff); err != nil {
+			return xerrors.Errorf("TCP healthcheck %s cancelled: %w", address, err)
+		}
 	}
 }
 
+// requestClient is used by Do, the client does not set an overall timeout
+// as responses such as logs are streamed but connections to servers which do
+// not respond are not left waiting
+var requestClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConns:          100,
+	},
+}
+
 // Do executes a HTTP request and returns the response
 func (h *HTTPImpl) Do(r *http.Request) (*http.Response, error) {
-	return http.DefaultClient.Do(r)
+	return requestClient.Do(r)
+}
+
+// healthCheckClient creates a HTTP client with the request timeout
+// and TLS settings from the options
+func healthCheckClient(options config.HTTPHealthCheck) (*http.Client, error) {
+	c := &http.Client{}
+
+	if options.RequestTimeout != "" {
+		t, err := time.ParseDuration(options.RequestTimeout)
+		if err != nil {
+			return nil, xerrors.Errorf("Invalid request_timeout %s: %w", options.RequestTimeout, err)
+		}
+
+		c.Timeout = t
+	}
+
+	if options.CACert == "" && !options.Insecure {
+		return c, nil
+	}
+
+	tc := &tls.Config{InsecureSkipVerify: options.Insecure}
+
+	if options.CACert != "" {
+		pem, err := ioutil.ReadFile(options.CACert)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to read CA bundle %s: %w", options.CACert, err)
+		}
+
+		// add the custom CAs to the system pool so public endpoints still verify
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, xerrors.Errorf("No certificates found in CA bundle %s", options.CACert)
+		}
+
+		tc.RootCAs = pool
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = tc
+	c.Transport = t
+
+	return c, nil
+}
+
+// httpResponseChecks are the optional checks applied to a response
+// after the status code has been validated
+type httpResponseChecks struct {
+	body         *regexp.Regexp
+	jsonPath     map[string]*jsonpath.JSONPath
+	expected     map[string]string
+	certValidFor time.Duration
+}
+
+// newHTTPResponseChecks parses the checks defined in the options
+func newHTTPResponseChecks(options config.HTTPHealthCheck) (*httpResponseChecks, error) {
+	c := &httpResponseChecks{
+		jsonPath: map[string]*jsonpath.JSONPath{},
+		expected: map[string]string{},
+	}
+
+	if options.Body != "" {
+		r, err := regexp.Compile(options.Body)
+		if err != nil {
+			return nil, xerrors.Errorf("Invalid body expression %s: %w", options.Body, err)
+		}
+
+		c.body = r
+	}
+
+	for p, v := range options.JSONPath {
+		// allow paths to be specified without the template braces
+		exp := p
+		if !strings.HasPrefix(exp, "{") {
+			exp = fmt.Sprintf("{%s}", exp)
+		}
+
+		j := jsonpath.New(p)
+		err := j.Parse(exp)
+		if err != nil {
+			return nil, xerrors.Errorf("Invalid json_path %s: %w", p, err)
+		}
+
+		c.jsonPath[p] = j
+		c.expected[p] = v
+	}
+
+	if options.CertValidFor != "" {
+		d, err := time.ParseDuration(options.CertValidFor)
+		if err != nil {
+			return nil, xerrors.Errorf("Invalid cert_valid_for %s: %w", options.CertValidFor, err)
+		}
+
+		c.certValidFor = d
+	}
+
+	return c, nil
+}
+
+// check returns an error if the response does not pass all the checks
+func (c *httpResponseChecks) check(resp *http.Response) error {
+	if c.certValidFor > 0 {
+		if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+			return xerrors.Errorf("Response was not served over TLS")
+		}
+
+		exp := resp.TLS.PeerCertificates[0].NotAfter
+		if time.Now().Add(c.certValidFor).After(exp) {
+			return xerrors.Errorf("Certificate expires at %s which is within %s", exp, c.certValidFor)
+		}
+	}
+
+	if c.body == nil && len(c.jsonPath) == 0 {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return xerrors.Errorf("Unable to read response body: %w", err)
+	}
+
+	if c.body != nil && !c.body.Match(body) {
+		return xerrors.Errorf("Response body does not match %s", c.body.String())
+	}
+
+	if len(c.jsonPath) == 0 {
+		return nil
+	}
+
+	var data interface{}
+	err = json.Unmarshal(body, &data)
+	if err != nil {
+		return xerrors.Errorf("Response body is not valid JSON: %w", err)
+	}
+
+	for p, j := range c.jsonPath {
+		buf := &bytes.Buffer{}
+		err := j.Execute(buf, data)
+		if err != nil {
+			return xerrors.Errorf("Unable to evaluate json_path %s: %w", p, err)
+		}
+
+		if v := strings.TrimSpace(buf.String()); v != c.expected[p] {
+			return xerrors.Errorf("Value %q at json_path %s does not equal %q", v, p, c.expected[p])
+		}
+	}
+
+	return nil
+}
+
+func containsStatusCode(codes []int, code int) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
 }
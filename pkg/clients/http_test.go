@@ -1,12 +1,19 @@
 package clients
 
 import (
+	"context"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -29,7 +36,7 @@ func TestHTTPHealthCallsGet(t *testing.T) {
 
 	c := NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
 
-	err := c.HealthCheckHTTP(url, 10*time.Millisecond)
+	err := c.HealthCheckHTTP(context.Background(), url, 10*time.Millisecond)
 	assert.NoError(t, err)
 	assert.Len(t, *reqs, 1)
 }
@@ -40,18 +47,218 @@ func TestHTTPHealthRetryiesOnServerErrorCode(t *testing.T) {
 
 	c := NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
 
-	err := c.HealthCheckHTTP(url, 10*time.Millisecond)
+	err := c.HealthCheckHTTP(context.Background(), url, 10*time.Millisecond)
 	assert.Error(t, err)
 	assert.Greater(t, len(*reqs), 1)
 }
 
+func TestHTTPHealthStopsWhenContextCancelled(t *testing.T) {
+	url, _, cleanup := testSetupHTTPBasicServer(http.StatusServiceUnavailable, "")
+	defer cleanup()
+
+	c := NewHTTP(10*time.Millisecond, hclog.NewNullLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	st := time.Now()
+	err := c.HealthCheckHTTP(ctx, url, 10*time.Second)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cancelled")
+	assert.Less(t, time.Since(st).Seconds(), 5.0)
+}
+
 func TestHTTPHealthErrorsOnClientError(t *testing.T) {
 	_, reqs, cleanup := testSetupHTTPBasicServer(http.StatusBadRequest, "")
 	defer cleanup()
 
 	c := NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
 
-	err := c.HealthCheckHTTP("http://127.0.0.2:19091", 10*time.Millisecond)
+	err := c.HealthCheckHTTP(context.Background(), "http://127.0.0.2:19091", 10*time.Millisecond)
 	assert.Error(t, err)
 	assert.Len(t, *reqs, 0)
 }
+
+func TestHTTPHealthSucceedsWithCustomStatusCode(t *testing.T) {
+	url, reqs, cleanup := testSetupHTTPBasicServer(http.StatusTooManyRequests, "")
+	defer cleanup()
+
+	c := NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
+
+	err := c.HealthCheckHTTPWithOptions(context.Background(), url, config.HTTPHealthCheck{SuccessCodes: []int{200, 429}}, 10*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Len(t, *reqs, 1)
+}
+
+func TestHTTPHealthBacksOffBetweenRetries(t *testing.T) {
+	url, reqs, cleanup := testSetupHTTPBasicServer(http.StatusServiceUnavailable, "")
+	defer cleanup()
+
+	c := NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
+
+	// with a doubling backoff starting at 10ms only a few requests fit in the timeout
+	err := c.HealthCheckHTTPWithOptions(context.Background(), url, config.HTTPHealthCheck{Backoff: "10ms", MaxBackoff: "1s"}, 60*time.Millisecond)
+	assert.Error(t, err)
+	assert.LessOrEqual(t, len(*reqs), 4)
+}
+
+func TestHTTPHealthReturnsErrorForInvalidBackoff(t *testing.T) {
+	c := NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
+
+	err := c.HealthCheckHTTPWithOptions(context.Background(), "http://localhost", config.HTTPHealthCheck{Backoff: "abc"}, 10*time.Millisecond)
+	assert.Error(t, err)
+}
+
+func TestHTTPHealthFailsWithSelfSignedCertificate(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+
+	c := NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
+
+	err := c.HealthCheckHTTP(context.Background(), s.URL, 10*time.Millisecond)
+	assert.Error(t, err)
+}
+
+func TestHTTPHealthSucceedsWithSelfSignedCertificateWhenInsecure(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+
+	c := NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
+
+	err := c.HealthCheckHTTPWithOptions(context.Background(), s.URL, config.HTTPHealthCheck{Insecure: true}, 100*time.Millisecond)
+	assert.NoError(t, err)
+}
+
+func TestHTTPHealthSucceedsWithCustomCA(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := filepath.Join(dir, "ca.pem")
+	err = ioutil.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}), 0644)
+	assert.NoError(t, err)
+
+	c := NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
+
+	err = c.HealthCheckHTTPWithOptions(context.Background(), s.URL, config.HTTPHealthCheck{CACert: ca}, 100*time.Millisecond)
+	assert.NoError(t, err)
+}
+
+func TestHTTPHealthReturnsErrorWhenCAMissing(t *testing.T) {
+	c := NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
+
+	err := c.HealthCheckHTTPWithOptions(context.Background(), "https://localhost", config.HTTPHealthCheck{CACert: "/missing/ca.pem"}, 10*time.Millisecond)
+	assert.Error(t, err)
+}
+
+func TestHTTPHealthSucceedsWhenBodyMatches(t *testing.T) {
+	url, _, cleanup := testSetupHTTPBasicServer(http.StatusOK, `"10.5.0.2:8300"`)
+	defer cleanup()
+
+	c := NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
+
+	err := c.HealthCheckHTTPWithOptions(context.Background(), url, config.HTTPHealthCheck{Body: `\d+\.\d+\.\d+\.\d+:8300`}, 10*time.Millisecond)
+	assert.NoError(t, err)
+}
+
+func TestHTTPHealthRetriesWhenBodyDoesNotMatch(t *testing.T) {
+	url, reqs, cleanup := testSetupHTTPBasicServer(http.StatusOK, `""`)
+	defer cleanup()
+
+	c := NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
+
+	err := c.HealthCheckHTTPWithOptions(context.Background(), url, config.HTTPHealthCheck{Body: `:8300`}, 10*time.Millisecond)
+	assert.Error(t, err)
+	assert.Greater(t, len(*reqs), 1)
+}
+
+func TestHTTPHealthReturnsErrorWhenBodyExpressionInvalid(t *testing.T) {
+	c := NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
+
+	err := c.HealthCheckHTTPWithOptions(context.Background(), "http://localhost", config.HTTPHealthCheck{Body: `(`}, 10*time.Millisecond)
+	assert.Error(t, err)
+}
+
+func TestHTTPHealthSucceedsWhenJSONPathEquals(t *testing.T) {
+	url, _, cleanup := testSetupHTTPBasicServer(http.StatusOK, `{"status": "ok", "nodes": [{"ready": true}]}`)
+	defer cleanup()
+
+	c := NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
+
+	err := c.HealthCheckHTTPWithOptions(context.Background(),
+		url,
+		config.HTTPHealthCheck{JSONPath: map[string]string{"{.status}": "ok", ".nodes[0].ready": "true"}},
+		10*time.Millisecond,
+	)
+	assert.NoError(t, err)
+}
+
+func TestHTTPHealthFailsWhenJSONPathDoesNotEqual(t *testing.T) {
+	url, _, cleanup := testSetupHTTPBasicServer(http.StatusOK, `{"status": "starting"}`)
+	defer cleanup()
+
+	c := NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
+
+	err := c.HealthCheckHTTPWithOptions(context.Background(), url, config.HTTPHealthCheck{JSONPath: map[string]string{"{.status}": "ok"}}, 10*time.Millisecond)
+	assert.Error(t, err)
+}
+
+func TestHTTPHealthFailsWhenJSONPathMissing(t *testing.T) {
+	url, _, cleanup := testSetupHTTPBasicServer(http.StatusOK, `{}`)
+	defer cleanup()
+
+	c := NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
+
+	err := c.HealthCheckHTTPWithOptions(context.Background(), url, config.HTTPHealthCheck{JSONPath: map[string]string{"{.status}": "ok"}}, 10*time.Millisecond)
+	assert.Error(t, err)
+}
+
+func TestHTTPHealthChecksCertificateValidity(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+
+	c := NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
+
+	// the test certificate is valid until 2084
+	err := c.HealthCheckHTTPWithOptions(context.Background(), s.URL, config.HTTPHealthCheck{Insecure: true, CertValidFor: "24h"}, 100*time.Millisecond)
+	assert.NoError(t, err)
+
+	err = c.HealthCheckHTTPWithOptions(context.Background(), s.URL, config.HTTPHealthCheck{Insecure: true, CertValidFor: "1000000h"}, 10*time.Millisecond)
+	assert.Error(t, err)
+}
+
+func TestHTTPHealthCertificateCheckFailsWithoutTLS(t *testing.T) {
+	url, _, cleanup := testSetupHTTPBasicServer(http.StatusOK, "")
+	defer cleanup()
+
+	c := NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
+
+	err := c.HealthCheckHTTPWithOptions(context.Background(), url, config.HTTPHealthCheck{CertValidFor: "1h"}, 10*time.Millisecond)
+	assert.Error(t, err)
+}
+
+func TestHTTPHealthTCPSucceedsWhenListening(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+
+	c := NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
+
+	err = c.HealthCheckTCP(context.Background(), l.Addr().String(), 10*time.Millisecond)
+	assert.NoError(t, err)
+}
+
+func TestHTTPHealthTCPTimesOutWhenNotListening(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	c := NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
+
+	err = c.HealthCheckTCP(context.Background(), addr, 10*time.Millisecond)
+	assert.Error(t, err)
+}
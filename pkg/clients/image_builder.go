@@ -0,0 +1,135 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"golang.org/x/xerrors"
+)
+
+// ImageBuilder defines an interface for building Docker images
+type ImageBuilder interface {
+	Build(ctx context.Context, config BuildConfig) error
+}
+
+// BuildConfig defines the parameters for an image build
+type BuildConfig struct {
+	Context    string            // folder containing the build context
+	Dockerfile string            // path to the Dockerfile, defaults to Dockerfile in the context
+	Tags       []string          // tags to apply to the built image
+	Target     string            // build stage to target for multi-stage Dockerfiles
+	Platforms  []string          // platforms to build for e.g. linux/amd64, linux/arm64
+	Args       map[string]string // build time arguments
+	Secrets    []BuildSecret     // secrets exposed to RUN --mount=type=secret
+	CacheFrom  []string          // external cache sources e.g. type=local,src=/tmp/cache
+	CacheTo    []string          // external cache destinations e.g. type=local,dest=/tmp/cache
+	Push       bool              // push the image to the registry rather than loading it into the local daemon
+	Output     io.Writer         // optional writer which receives the build output
+}
+
+// BuildSecret is a secret which is available to the build but is not stored in the image
+type BuildSecret struct {
+	ID     string // id used to reference the secret in the Dockerfile
+	Source string // path to the file containing the secret
+}
+
+// ImageBuilderImpl builds images with BuildKit using the docker buildx plugin
+type ImageBuilderImpl struct {
+	command Command
+	timeout time.Duration
+	log     hclog.Logger
+}
+
+// NewImageBuilder creates a new ImageBuilder, builds are terminated if they run longer
+// than the given timeout
+func NewImageBuilder(c Command, timeout time.Duration, l hclog.Logger) ImageBuilder {
+	return &ImageBuilderImpl{c, timeout, l}
+}
+
+// Build an image using BuildKit
+func (b *ImageBuilderImpl) Build(ctx context.Context, config BuildConfig) error {
+	if config.Context == "" {
+		return xerrors.Errorf("unable to build image, a build context must be specified")
+	}
+
+	// multi-platform images can not be loaded into the local daemon
+	if len(config.Platforms) > 1 && !config.Push {
+		return xerrors.Errorf("unable to build image, building for multiple platforms requires push to be enabled")
+	}
+
+	b.log.Debug("Building image", "context", config.Context, "tags", config.Tags, "platforms", config.Platforms)
+
+	err := b.command.Execute(ctx, CommandConfig{
+		Command:   "docker",
+		Arguments: buildxArgs(config),
+		Env:       []string{"DOCKER_BUILDKIT=1"},
+		Timeout:   b.timeout,
+		Output:    config.Output,
+	})
+
+	if err != nil {
+		return xerrors.Errorf("unable to build image: %w", err)
+	}
+
+	return nil
+}
+
+// buildxArgs converts the BuildConfig into arguments for docker buildx build
+func buildxArgs(config BuildConfig) []string {
+	// plain progress writes each step on a new line so it can be logged
+	args := []string{"buildx", "build", "--progress", "plain"}
+
+	if config.Dockerfile != "" {
+		args = append(args, "--file", config.Dockerfile)
+	}
+
+	for _, t := range config.Tags {
+		args = append(args, "--tag", t)
+	}
+
+	// label the image so dangling builds can be garbage collected
+	args = append(args, "--label", shipyardLabel+"=true")
+
+	if config.Target != "" {
+		args = append(args, "--target", config.Target)
+	}
+
+	for _, p := range config.Platforms {
+		args = append(args, "--platform", p)
+	}
+
+	// sort the build args so the command is deterministic
+	keys := []string{}
+	for k := range config.Args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		args = append(args, "--build-arg", fmt.Sprintf("%s=%s", k, config.Args[k]))
+	}
+
+	for _, s := range config.Secrets {
+		args = append(args, "--secret", fmt.Sprintf("id=%s,src=%s", s.ID, s.Source))
+	}
+
+	for _, c := range config.CacheFrom {
+		args = append(args, "--cache-from", c)
+	}
+
+	for _, c := range config.CacheTo {
+		args = append(args, "--cache-to", c)
+	}
+
+	if config.Push {
+		args = append(args, "--push")
+	} else {
+		args = append(args, "--load")
+	}
+
+	return append(args, config.Context)
+}
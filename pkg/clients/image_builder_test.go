@@ -0,0 +1,102 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockBuildCommand struct {
+	mock.Mock
+}
+
+func (m *mockBuildCommand) Execute(ctx context.Context, config CommandConfig) error {
+	args := m.Called(config)
+
+	return args.Error(0)
+}
+
+func setupImageBuilder(err error) (ImageBuilder, *mockBuildCommand) {
+	mc := &mockBuildCommand{}
+	mc.On("Execute", mock.Anything).Return(err)
+
+	return NewImageBuilder(mc, 10*time.Minute, hclog.NewNullLogger()), mc
+}
+
+func TestBuildRunsBuildx(t *testing.T) {
+	b, mc := setupImageBuilder(nil)
+
+	err := b.Build(context.Background(), BuildConfig{Context: "/src", Tags: []string{"app:latest"}})
+	assert.NoError(t, err)
+
+	cc := mc.Calls[0].Arguments[0].(CommandConfig)
+	assert.Equal(t, "docker", cc.Command)
+	assert.Equal(t, []string{"buildx", "build", "--progress", "plain", "--tag", "app:latest", "--label", "run.shipyard=true", "--load", "/src"}, cc.Arguments)
+	assert.Contains(t, cc.Env, "DOCKER_BUILDKIT=1")
+	assert.Equal(t, 10*time.Minute, cc.Timeout)
+}
+
+func TestBuildAddsOptionalArguments(t *testing.T) {
+	b, mc := setupImageBuilder(nil)
+
+	err := b.Build(context.Background(), BuildConfig{
+		Context:    "/src",
+		Dockerfile: "/src/Dockerfile.dev",
+		Target:     "dev",
+		Platforms:  []string{"linux/amd64", "linux/arm64"},
+		Args:       map[string]string{"B": "2", "A": "1"},
+		Secrets:    []BuildSecret{BuildSecret{ID: "npm", Source: "/home/.npmrc"}},
+		CacheFrom:  []string{"type=local,src=/cache"},
+		CacheTo:    []string{"type=local,dest=/cache"},
+		Push:       true,
+	})
+	assert.NoError(t, err)
+
+	args := mc.Calls[0].Arguments[0].(CommandConfig).Arguments
+	assert.Equal(t,
+		[]string{
+			"buildx", "build", "--progress", "plain",
+			"--file", "/src/Dockerfile.dev",
+			"--label", "run.shipyard=true",
+			"--target", "dev",
+			"--platform", "linux/amd64", "--platform", "linux/arm64",
+			"--build-arg", "A=1", "--build-arg", "B=2",
+			"--secret", "id=npm,src=/home/.npmrc",
+			"--cache-from", "type=local,src=/cache",
+			"--cache-to", "type=local,dest=/cache",
+			"--push",
+			"/src",
+		},
+		args,
+	)
+}
+
+func TestBuildReturnsErrorWhenNoContext(t *testing.T) {
+	b, mc := setupImageBuilder(nil)
+
+	err := b.Build(context.Background(), BuildConfig{})
+	assert.Error(t, err)
+
+	mc.AssertNotCalled(t, "Execute", mock.Anything)
+}
+
+func TestBuildReturnsErrorWhenMultiPlatformWithoutPush(t *testing.T) {
+	b, mc := setupImageBuilder(nil)
+
+	err := b.Build(context.Background(), BuildConfig{Context: "/src", Platforms: []string{"linux/amd64", "linux/arm64"}})
+	assert.Error(t, err)
+
+	mc.AssertNotCalled(t, "Execute", mock.Anything)
+}
+
+func TestBuildReturnsErrorWhenCommandFails(t *testing.T) {
+	b, _ := setupImageBuilder(fmt.Errorf("boom"))
+
+	err := b.Build(context.Background(), BuildConfig{Context: "/src"})
+	assert.Error(t, err)
+}
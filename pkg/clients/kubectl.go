@@ -1,36 +1,88 @@
 package clients
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	gosignal "os/signal"
 	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/docker/docker/pkg/signal"
 	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/streams"
+	"github.com/shipyard-run/shipyard/pkg/config"
 	"golang.org/x/xerrors"
 	"helm.sh/helm/v3/pkg/kube"
 	v1 "k8s.io/api/core/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
 )
 
 // Kubernetes defines an interface for a Kuberenetes client
 type Kubernetes interface {
 	SetConfig(string) error
+	// SetConfigWithContext configures the client using the named context, kubeconfig
+	// can contain multiple files separated by the OS path list separator which are
+	// merged in the same way as the KUBECONFIG environment variable. When context
+	// is empty the current context from the merged config is used
+	SetConfigWithContext(kubeconfig, context string) error
 	GetPods(string) (*v1.PodList, error)
-	HealthCheckPods(selectors []string, timeout time.Duration) error
-	Apply(files []string, waitUntilReady bool) error
+	HealthCheckPods(ctx context.Context, selectors []string, timeout time.Duration) error
+	Apply(ctx context.Context, files []string, waitUntilReady bool) error
 	Delete(files []string) error
+	// Inventory returns references to all the Kubernetes objects defined
+	// in the given files
+	Inventory(files []string) ([]config.K8sObject, error)
+	// DeleteObjects removes the given objects from the cluster, objects which
+	// do not exist are ignored
+	DeleteObjects(objects []config.K8sObject) error
+	// MissingObjects returns the given objects which do not exist in the
+	// cluster, e.g. they have been deleted with kubectl
+	MissingObjects(objects []config.K8sObject) ([]config.K8sObject, error)
+	// ExecPod executes a command in the first running pod which matches
+	// the selector, or the pod with the given name, output from the command is written to the writer
+	ExecPod(namespace, selector, container string, command []string, writer io.Writer) error
+	// ShellPod executes an interactive command in the first running pod which
+	// matches the selector, attaching stdin with a TTY
+	ShellPod(namespace, selector, container string, command []string, stdin io.ReadCloser, stdout io.Writer, stderr io.Writer) error
+	// PodLogs streams the logs from the running pods which match the selector
+	// to the writer, when follow is true it blocks until the streams close
+	PodLogs(namespace, selector, container string, follow bool, since time.Duration, writer io.Writer) error
+	// WaitForCRDs blocks until the named CustomResourceDefinitions are
+	// established and custom resources can be created
+	WaitForCRDs(ctx context.Context, names []string, timeout time.Duration) error
+	// WaitForEndpoints blocks until the services have at least one ready
+	// endpoint, services are specified as name or namespace/name
+	WaitForEndpoints(ctx context.Context, services []string, timeout time.Duration) error
+	// PortForward forwards a local port to a pod or service until the stop
+	// channel is closed, targets are specified as pod/name or service/name.
+	// A localPort of 0 selects a free port, the port used is returned
+	PortForward(namespace, target string, localPort, remotePort int, stop <-chan struct{}) (int, error)
 }
 
 // KubernetesImpl is a concrete implementation of a Kubernetes client
 type KubernetesImpl struct {
 	clientset  *kubernetes.Clientset
 	client     corev1.CoreV1Interface
+	extClient  apiextensionsclient.Interface
+	restConfig *rest.Config
 	configPath string
+	context    string
 	timeout    time.Duration
 	l          hclog.Logger
 }
@@ -42,7 +94,13 @@ func NewKubernetes(t time.Duration, l hclog.Logger) Kubernetes {
 
 // SetConfig for the Kubernetes cluster
 func (k *KubernetesImpl) SetConfig(kubeconfig string) error {
+	return k.SetConfigWithContext(kubeconfig, "")
+}
+
+// SetConfigWithContext for the Kubernetes cluster
+func (k *KubernetesImpl) SetConfigWithContext(kubeconfig, context string) error {
 	k.configPath = kubeconfig
+	k.context = context
 
 	st := time.Now()
 	for {
@@ -63,7 +121,7 @@ func (k *KubernetesImpl) SetConfig(kubeconfig string) error {
 // it is possible that the cluster is not fully ready when
 // this operation is first called
 func (k *KubernetesImpl) setConfig() error {
-	config, err := clientcmd.BuildConfigFromFlags("", k.configPath)
+	config, err := restConfigFromKubeconfig(k.configPath, k.context)
 	if err != nil {
 		return err
 	}
@@ -73,12 +131,36 @@ func (k *KubernetesImpl) setConfig() error {
 		return err
 	}
 
+	extClient, err := apiextensionsclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
 	k.clientset = clientset
 	k.client = clientset.CoreV1()
+	k.extClient = extClient
+	k.restConfig = config
 
 	return nil
 }
 
+// restConfigFromKubeconfig loads and merges the kubeconfig files, a single file must exist
+// however when multiple files are given missing files are ignored
+func restConfigFromKubeconfig(kubeconfig, context string) (*rest.Config, error) {
+	rules := &clientcmd.ClientConfigLoadingRules{}
+
+	paths := filepath.SplitList(kubeconfig)
+	if len(paths) == 1 {
+		rules.ExplicitPath = paths[0]
+	} else {
+		rules.Precedence = paths
+	}
+
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+}
+
 // GetPods returns the Kubernetes pods based on the label selector
 func (k *KubernetesImpl) GetPods(selector string) (*v1.PodList, error) {
 	lo := metav1.ListOptions{
@@ -92,9 +174,165 @@ func (k *KubernetesImpl) GetPods(selector string) (*v1.PodList, error) {
 	return pl, nil
 }
 
+// ExecPod executes a command in the first running pod matching the selector
+func (k *KubernetesImpl) ExecPod(namespace, selector, container string, command []string, writer io.Writer) error {
+	pod, err := k.findPod(namespace, selector)
+	if err != nil {
+		return err
+	}
+
+	k.l.Debug("Executing command in pod", "pod", pod.Name, "namespace", pod.Namespace, "command", command)
+
+	ex, err := k.podExecutor(pod, container, command, false)
+	if err != nil {
+		return err
+	}
+
+	err = ex.Stream(remotecommand.StreamOptions{Stdout: writer, Stderr: writer})
+	if err != nil {
+		return xerrors.Errorf("Error executing command in pod %s: %w", pod.Name, err)
+	}
+
+	return nil
+}
+
+// ShellPod executes an interactive command in the first running pod matching the
+// selector, stdin is attached to a TTY which is resized with the local terminal
+func (k *KubernetesImpl) ShellPod(namespace, selector, container string, command []string, stdin io.ReadCloser, stdout io.Writer, stderr io.Writer) error {
+	pod, err := k.findPod(namespace, selector)
+	if err != nil {
+		return err
+	}
+
+	k.l.Debug("Creating shell in pod", "pod", pod.Name, "namespace", pod.Namespace, "command", command)
+
+	ex, err := k.podExecutor(pod, container, command, true)
+	if err != nil {
+		return err
+	}
+
+	// wrap the standard streams
+	ttyIn := streams.NewIn(stdin)
+	ttyOut := streams.NewOut(stdout)
+
+	err = ttyIn.SetRawTerminal()
+	if err != nil {
+		return xerrors.Errorf("Unable to set raw terminal: %w", err)
+	}
+	defer ttyIn.RestoreTerminal()
+
+	sizes := newTerminalSizeQueue(ttyOut)
+	defer sizes.stop()
+
+	err = ex.Stream(remotecommand.StreamOptions{
+		Stdin:             ttyIn,
+		Stdout:            ttyOut,
+		Stderr:            stderr,
+		Tty:               true,
+		TerminalSizeQueue: sizes,
+	})
+	if err != nil {
+		return xerrors.Errorf("Error executing command in pod %s: %w", pod.Name, err)
+	}
+
+	return nil
+}
+
+// findPod returns the first running pod in the namespace matching the selector,
+// selectors which are not label selectors are treated as the name of the pod
+func (k *KubernetesImpl) findPod(namespace, selector string) (*v1.Pod, error) {
+	if !strings.ContainsAny(selector, "=,!()") {
+		pod, err := k.client.Pods(namespace).Get(selector, metav1.GetOptions{})
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to get pod %s in namespace %s: %w", selector, namespace, err)
+		}
+
+		if pod.Status.Phase != v1.PodRunning {
+			return nil, xerrors.Errorf("Pod %s in namespace %s is not running", selector, namespace)
+		}
+
+		return pod, nil
+	}
+
+	lo := metav1.ListOptions{
+		LabelSelector: selector,
+		FieldSelector: "status.phase=Running",
+	}
+
+	pl, err := k.client.Pods(namespace).List(lo)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to list pods: %w", err)
+	}
+
+	if len(pl.Items) == 0 {
+		return nil, xerrors.Errorf("No running pods found in namespace %s matching selector %s", namespace, selector)
+	}
+
+	return &pl.Items[0], nil
+}
+
+// podExecutor creates an executor which streams a command in the pod
+func (k *KubernetesImpl) podExecutor(pod *v1.Pod, container string, command []string, tty bool) (remotecommand.Executor, error) {
+	req := k.client.RESTClient().
+		Post().
+		Resource("pods").
+		Name(pod.Name).
+		Namespace(pod.Namespace).
+		SubResource("exec").
+		VersionedParams(&v1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdin:     tty,
+			Stdout:    true,
+			Stderr:    !tty,
+			TTY:       tty,
+		}, scheme.ParameterCodec)
+
+	ex, err := remotecommand.NewSPDYExecutor(k.restConfig, "POST", req.URL())
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to create executor for pod %s: %w", pod.Name, err)
+	}
+
+	return ex, nil
+}
+
+// terminalSizeQueue sends the size of the local terminal to the pod
+// when it is first attached and whenever the terminal is resized
+type terminalSizeQueue struct {
+	out     *streams.Out
+	resized chan os.Signal
+}
+
+func newTerminalSizeQueue(out *streams.Out) *terminalSizeQueue {
+	t := &terminalSizeQueue{out: out, resized: make(chan os.Signal, 1)}
+
+	// queue the initial size
+	t.resized <- signal.SIGWINCH
+	gosignal.Notify(t.resized, signal.SIGWINCH)
+
+	return t
+}
+
+// Next blocks until the terminal is resized, returns nil when stopped
+func (t *terminalSizeQueue) Next() *remotecommand.TerminalSize {
+	_, ok := <-t.resized
+	if !ok {
+		return nil
+	}
+
+	h, w := t.out.GetTtySize()
+
+	return &remotecommand.TerminalSize{Width: uint16(w), Height: uint16(h)}
+}
+
+func (t *terminalSizeQueue) stop() {
+	gosignal.Stop(t.resized)
+	close(t.resized)
+}
+
 // Apply Kubernetes YAML files at path
 // if waitUntilReady is true then the client will block until all resources have been created
-func (k *KubernetesImpl) Apply(files []string, waitUntilReady bool) error {
+func (k *KubernetesImpl) Apply(ctx context.Context, files []string, waitUntilReady bool) error {
 	allFiles, err := buildFileList(files)
 	if err != nil {
 		return err
@@ -106,7 +344,21 @@ func (k *KubernetesImpl) Apply(files []string, waitUntilReady bool) error {
 	// process the files
 	for _, f := range allFiles {
 		k.l.Debug("Applying Kubernetes config", "file", f)
-		err := applyFile(f, waitUntilReady, kc)
+		r, err := applyFile(f, waitUntilReady, kc)
+		if err != nil {
+			return err
+		}
+
+		// custom resources in the following files can not be created
+		// until any definitions in this file have been established
+		crds := []string{}
+		for _, i := range r {
+			if i.Mapping.GroupVersionKind.Kind == "CustomResourceDefinition" {
+				crds = append(crds, i.Name)
+			}
+		}
+
+		err = k.WaitForCRDs(ctx, crds, k.timeout)
 		if err != nil {
 			return err
 		}
@@ -138,16 +390,192 @@ func (k *KubernetesImpl) Delete(files []string) error {
 	return nil
 }
 
+// Inventory returns references to the Kubernetes objects defined in the YAML files at path
+func (k *KubernetesImpl) Inventory(files []string) ([]config.K8sObject, error) {
+	allFiles, err := buildFileList(files)
+	if err != nil {
+		return nil, err
+	}
+
+	s := kube.GetConfig(k.configPath, "default", "default")
+	kc := kube.New(s)
+
+	objects := []config.K8sObject{}
+	for _, f := range allFiles {
+		r, err := buildFile(f, false, kc)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, i := range r {
+			gvk := i.Mapping.GroupVersionKind
+			objects = append(objects, config.K8sObject{
+				APIVersion: gvk.GroupVersion().String(),
+				Kind:       gvk.Kind,
+				Namespace:  i.Namespace,
+				Name:       i.Name,
+			})
+		}
+	}
+
+	return objects, nil
+}
+
+// DeleteObjects removes the given objects from the Kubernetes cluster
+func (k *KubernetesImpl) DeleteObjects(objects []config.K8sObject) error {
+	if len(objects) == 0 {
+		return nil
+	}
+
+	s := kube.GetConfig(k.configPath, "default", "default")
+	kc := kube.New(s)
+
+	// build a minimal manifest for each of the objects so that the
+	// Kubernetes client can resolve the API mapping
+	docs := []string{}
+	for _, o := range objects {
+		k.l.Debug("Removing Kubernetes object", "kind", o.Kind, "name", o.Name, "namespace", o.Namespace)
+		docs = append(docs, objectManifest(o))
+	}
+
+	r, err := kc.Build(strings.NewReader(strings.Join(docs, "\n---\n")), false)
+	if err != nil {
+		return xerrors.Errorf("Unable to build resources for objects: %w", err)
+	}
+
+	_, errs := kc.Delete(r)
+	if errs != nil {
+		return xerrors.Errorf("Error deleting objects: %v", errs)
+	}
+
+	return nil
+}
+
+// MissingObjects returns the objects which do not exist in the Kubernetes
+// cluster
+func (k *KubernetesImpl) MissingObjects(objects []config.K8sObject) ([]config.K8sObject, error) {
+	missing := []config.K8sObject{}
+	if len(objects) == 0 {
+		return missing, nil
+	}
+
+	s := kube.GetConfig(k.configPath, "default", "default")
+	kc := kube.New(s)
+
+	for _, o := range objects {
+		r, err := kc.Build(strings.NewReader(objectManifest(o)), false)
+		if err != nil {
+			// the API for the object no longer exists, e.g. the CRD was removed
+			k.l.Debug("Unable to build resource for object", "kind", o.Kind, "name", o.Name, "namespace", o.Namespace, "error", err)
+			missing = append(missing, o)
+			continue
+		}
+
+		for _, i := range r {
+			err := i.Get()
+			if errors.IsNotFound(err) {
+				missing = append(missing, o)
+				break
+			}
+
+			if err != nil {
+				return nil, xerrors.Errorf("Unable to get %s %s: %w", o.Kind, o.Name, err)
+			}
+		}
+	}
+
+	return missing, nil
+}
+
+// WaitForCRDs waits until the CustomResourceDefinitions with the given names
+// have the Established condition
+func (k *KubernetesImpl) WaitForCRDs(ctx context.Context, names []string, timeout time.Duration) error {
+	for _, n := range names {
+		k.l.Debug("Waiting for CustomResourceDefinition to be established", "name", n)
+
+		st := time.Now()
+		for !k.crdEstablished(n) {
+			if time.Now().Sub(st) > timeout {
+				return xerrors.Errorf("Timeout waiting for CustomResourceDefinition %s to be established", n)
+			}
+
+			if err := wait(ctx, 500*time.Millisecond); err != nil {
+				return xerrors.Errorf("Waiting for CustomResourceDefinition %s cancelled: %w", n, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (k *KubernetesImpl) crdEstablished(name string) bool {
+	crd, err := k.extClient.ApiextensionsV1beta1().CustomResourceDefinitions().Get(name, metav1.GetOptions{})
+	if err != nil {
+		return false
+	}
+
+	for _, c := range crd.Status.Conditions {
+		if c.Type == apiextensionsv1beta1.Established && c.Status == apiextensionsv1beta1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WaitForEndpoints waits until each of the services has a ready endpoint,
+// this ensures services such as admission webhooks can receive requests
+func (k *KubernetesImpl) WaitForEndpoints(ctx context.Context, services []string, timeout time.Duration) error {
+	for _, s := range services {
+		namespace := "default"
+		name := s
+		if parts := strings.SplitN(s, "/", 2); len(parts) == 2 {
+			namespace = parts[0]
+			name = parts[1]
+		}
+
+		k.l.Debug("Waiting for service endpoints", "service", name, "namespace", namespace)
+
+		st := time.Now()
+		for !k.endpointsReady(namespace, name) {
+			if time.Now().Sub(st) > timeout {
+				return xerrors.Errorf("Timeout waiting for endpoints for service %s", s)
+			}
+
+			if err := wait(ctx, 500*time.Millisecond); err != nil {
+				return xerrors.Errorf("Waiting for endpoints for service %s cancelled: %w", s, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (k *KubernetesImpl) endpointsReady(namespace, name string) bool {
+	ep, err := k.client.Endpoints(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return false
+	}
+
+	for _, ss := range ep.Subsets {
+		if len(ss.Addresses) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
 // HealthCheckPods uses the given selector to check that all pods are started
 // and running.
 // selectors are checked sequentially
 // pods = ["component=server,app=consul", "component=client,app=consul"]
-func (k *KubernetesImpl) HealthCheckPods(selectors []string, timeout time.Duration) error {
+func (k *KubernetesImpl) HealthCheckPods(ctx context.Context, selectors []string, timeout time.Duration) error {
 	// check all pods are running
 	for _, s := range selectors {
 		k.l.Debug("Health checking pods", "selector", s)
 
-		err := k.healthCheckSingle(s, timeout)
+		err := k.healthCheckSingle(ctx, s, timeout)
 		if err != nil {
 			return err
 		}
@@ -157,13 +585,17 @@ func (k *KubernetesImpl) HealthCheckPods(selectors []string, timeout time.Durati
 }
 
 // healthCheckSingle checks for running containers with the given selector
-func (k *KubernetesImpl) healthCheckSingle(selector string, timeout time.Duration) error {
+func (k *KubernetesImpl) healthCheckSingle(ctx context.Context, selector string, timeout time.Duration) error {
 	st := time.Now()
 	for {
 		if time.Now().Sub(st) > timeout {
 			return fmt.Errorf("Timeout waiting for pods %s to start", selector)
 		}
 
+		if ctx.Err() != nil {
+			return xerrors.Errorf("Health check for pods %s cancelled: %w", selector, ctx.Err())
+		}
+
 		// GetPods may return an error if the API server is not available
 		pl, err := k.GetPods(selector)
 		if err != nil {
@@ -197,7 +629,9 @@ func (k *KubernetesImpl) healthCheckSingle(selector string, timeout time.Duratio
 		}
 
 		// backoff
-		time.Sleep(2 * time.Second)
+		if err := wait(ctx, 2*time.Second); err != nil {
+			return xerrors.Errorf("Health check for pods %s cancelled: %w", selector, err)
+		}
 	}
 
 	return nil
@@ -235,30 +669,93 @@ func buildFileList(files []string) ([]string, error) {
 	return allFiles, nil
 }
 
-func applyFile(path string, waitUntilReady bool, kc *kube.Client) error {
-	f, err := os.Open(path)
+func applyFile(path string, waitUntilReady bool, kc *kube.Client) (kube.ResourceList, error) {
+	r, err := buildFile(path, true, kc)
 	if err != nil {
-		return xerrors.Errorf("Unable to open file: %w", err)
+		return nil, err
 	}
-	defer f.Close()
 
-	r, err := kc.Build(f, true)
-	if err != nil {
-		return xerrors.Errorf("Unable to build resources for file %s: %w", path, err)
+	err = serverSideApply(r)
+	if err == errServerSideApplyUnsupported {
+		// clusters older than 1.16 do not support server-side apply, update
+		// creates any resources which do not exist and patches the existing ones
+		_, err = kc.Update(r, r, false)
 	}
 
-	_, err = kc.Create(r)
 	if err != nil {
-		return xerrors.Errorf("Unable to create resources for file %s: %w", path, err)
+		return nil, xerrors.Errorf("Unable to create resources for file %s: %w", path, err)
 	}
 
 	if waitUntilReady {
-		return kc.WatchUntilReady(r, 30*time.Second)
+		return r, kc.WatchUntilReady(r, 30*time.Second)
+	}
+
+	return r, nil
+}
+
+// fieldManager identifies Shipyard as the owner of the fields it sets with server-side apply
+const fieldManager = "shipyard"
+
+var errServerSideApplyUnsupported = xerrors.New("server-side apply is not supported by the cluster")
+
+// serverSideApply creates or updates the resources using server-side apply.
+// When fields are owned by another manager, for example after a manual kubectl edit,
+// the apply is retried with force so that the config files remain the source of truth
+func serverSideApply(r kube.ResourceList) error {
+	for _, i := range r {
+		data, err := json.Marshal(i.Object)
+		if err != nil {
+			return xerrors.Errorf("Unable to encode %s: %w", i.Name, err)
+		}
+
+		h := resource.NewHelper(i.Client, i.Mapping)
+
+		force := false
+		obj, err := h.Patch(i.Namespace, i.Name, types.ApplyPatchType, data, &metav1.PatchOptions{FieldManager: fieldManager, Force: &force})
+		if errors.IsConflict(err) {
+			force = true
+			obj, err = h.Patch(i.Namespace, i.Name, types.ApplyPatchType, data, &metav1.PatchOptions{FieldManager: fieldManager, Force: &force})
+		}
+
+		if errors.IsUnsupportedMediaType(err) {
+			return errServerSideApplyUnsupported
+		}
+
+		if err != nil {
+			return xerrors.Errorf("Unable to apply %s %s: %w", i.Mapping.GroupVersionKind.Kind, i.Name, err)
+		}
+
+		i.Refresh(obj, true)
 	}
 
 	return nil
 }
 
+func buildFile(path string, validate bool, kc *kube.Client) (kube.ResourceList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to open file: %w", err)
+	}
+	defer f.Close()
+
+	r, err := kc.Build(f, validate)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to build resources for file %s: %w", path, err)
+	}
+
+	return r, nil
+}
+
+// objectManifest returns a minimal YAML manifest which identifies the object
+func objectManifest(o config.K8sObject) string {
+	m := fmt.Sprintf("apiVersion: %s\nkind: %s\nmetadata:\n  name: %s\n", o.APIVersion, o.Kind, o.Name)
+	if o.Namespace != "" {
+		m += fmt.Sprintf("  namespace: %s\n", o.Namespace)
+	}
+
+	return m
+}
+
 func deleteFile(path string, kc *kube.Client) error {
 	f, err := os.Open(path)
 	if err != nil {
@@ -0,0 +1,95 @@
+package clients
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testKubeconfig = `
+apiVersion: v1
+kind: Config
+current-context: %[1]s
+clusters:
+- name: %[1]s
+  cluster:
+    server: %[2]s
+contexts:
+- name: %[1]s
+  context:
+    cluster: %[1]s
+    user: %[1]s
+users:
+- name: %[1]s
+  user:
+    token: abc
+`
+
+func setupKubeconfigs(t *testing.T) (string, string, func()) {
+	tmpDir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	dev := filepath.Join(tmpDir, "dev.yaml")
+	ioutil.WriteFile(dev, []byte(fmt.Sprintf(testKubeconfig, "dev", "https://dev:6443")), 0644)
+
+	prod := filepath.Join(tmpDir, "prod.yaml")
+	ioutil.WriteFile(prod, []byte(fmt.Sprintf(testKubeconfig, "prod", "https://prod:6443")), 0644)
+
+	return dev, prod, func() {
+		os.RemoveAll(tmpDir)
+	}
+}
+
+func TestRestConfigUsesCurrentContext(t *testing.T) {
+	dev, _, cleanup := setupKubeconfigs(t)
+	defer cleanup()
+
+	c, err := restConfigFromKubeconfig(dev, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://dev:6443", c.Host)
+}
+
+func TestRestConfigMergesFilesAndSelectsContext(t *testing.T) {
+	dev, prod, cleanup := setupKubeconfigs(t)
+	defer cleanup()
+
+	paths := strings.Join([]string{dev, prod}, string(os.PathListSeparator))
+
+	c, err := restConfigFromKubeconfig(paths, "prod")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://prod:6443", c.Host)
+
+	// the first file takes precedence for the current context
+	c, err = restConfigFromKubeconfig(paths, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://dev:6443", c.Host)
+}
+
+func TestRestConfigIgnoresMissingFilesWhenMerging(t *testing.T) {
+	dev, _, cleanup := setupKubeconfigs(t)
+	defer cleanup()
+
+	paths := strings.Join([]string{"/missing/config.yaml", dev}, string(os.PathListSeparator))
+
+	c, err := restConfigFromKubeconfig(paths, "dev")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://dev:6443", c.Host)
+}
+
+func TestRestConfigReturnsErrorWhenContextMissing(t *testing.T) {
+	dev, _, cleanup := setupKubeconfigs(t)
+	defer cleanup()
+
+	_, err := restConfigFromKubeconfig(dev, "staging")
+	assert.Error(t, err)
+}
+
+func TestRestConfigReturnsErrorWhenSingleFileMissing(t *testing.T) {
+	_, err := restConfigFromKubeconfig("/missing/config.yaml", "")
+	assert.Error(t, err)
+}
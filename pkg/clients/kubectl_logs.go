@@ -0,0 +1,104 @@
+package clients
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PodLogs streams the logs from all the running pods in the namespace which match
+// the selector to the writer, each line is prefixed with the name of the pod.
+// When container is empty the logs for the default container are returned, a since
+// of 0 returns all the logs. When follow is true PodLogs blocks until every stream
+// has been closed
+func (k *KubernetesImpl) PodLogs(namespace, selector, container string, follow bool, since time.Duration, writer io.Writer) error {
+	pl, err := k.client.Pods(namespace).List(metav1.ListOptions{
+		LabelSelector: selector,
+		FieldSelector: "status.phase=Running",
+	})
+	if err != nil {
+		return xerrors.Errorf("Unable to list pods: %w", err)
+	}
+
+	if len(pl.Items) == 0 {
+		return xerrors.Errorf("No running pods found in namespace %s matching selector %s", namespace, selector)
+	}
+
+	opts := podLogOptions(container, follow, since)
+
+	// pods are streamed concurrently so that following the logs from one
+	// pod does not block the others, the mutex ensures lines are not interleaved
+	wg := sync.WaitGroup{}
+	mu := &sync.Mutex{}
+	errs := make(chan error, len(pl.Items))
+
+	for _, p := range pl.Items {
+		wg.Add(1)
+
+		go func(name string) {
+			defer wg.Done()
+
+			k.l.Debug("Streaming logs from pod", "pod", name, "namespace", namespace, "container", container, "follow", follow)
+
+			rc, err := k.client.Pods(namespace).GetLogs(name, opts).Stream()
+			if err != nil {
+				errs <- xerrors.Errorf("Unable to stream logs for pod %s: %w", name, err)
+				return
+			}
+			defer rc.Close()
+
+			err = copyLogLines(name, rc, writer, mu)
+			if err != nil {
+				errs <- xerrors.Errorf("Unable to read logs for pod %s: %w", name, err)
+			}
+		}(p.Name)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	// return the first error
+	return <-errs
+}
+
+// podLogOptions returns the options for a log request
+func podLogOptions(container string, follow bool, since time.Duration) *v1.PodLogOptions {
+	opts := &v1.PodLogOptions{
+		Container: container,
+		Follow:    follow,
+	}
+
+	if since > 0 {
+		s := int64(since.Seconds())
+		if s < 1 {
+			s = 1
+		}
+
+		opts.SinceSeconds = &s
+	}
+
+	return opts
+}
+
+// copyLogLines copies lines from the reader to the writer prefixed with the
+// given name
+func copyLogLines(name string, r io.Reader, w io.Writer, mu *sync.Mutex) error {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		mu.Lock()
+		_, err := fmt.Fprintf(w, "[%s] %s\n", name, s.Text())
+		mu.Unlock()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.Err()
+}
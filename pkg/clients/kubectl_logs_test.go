@@ -0,0 +1,47 @@
+package clients
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPodLogsReturnsErrorWhenNoPods(t *testing.T) {
+	k := setupFakeKubernetes(nil, nil)
+
+	err := k.PodLogs("default", "app=vault", "", false, 0, bytes.NewBufferString(""))
+	assert.Error(t, err)
+}
+
+func TestPodLogOptionsSetsContainerAndFollow(t *testing.T) {
+	o := podLogOptions("web", true, 0)
+
+	assert.Equal(t, "web", o.Container)
+	assert.True(t, o.Follow)
+	assert.Nil(t, o.SinceSeconds)
+}
+
+func TestPodLogOptionsSetsSince(t *testing.T) {
+	o := podLogOptions("", false, 2*time.Minute)
+
+	assert.Equal(t, int64(120), *o.SinceSeconds)
+}
+
+func TestPodLogOptionsRoundsSinceToOneSecond(t *testing.T) {
+	o := podLogOptions("", false, 10*time.Millisecond)
+
+	assert.Equal(t, int64(1), *o.SinceSeconds)
+}
+
+func TestCopyLogLinesPrefixesPodName(t *testing.T) {
+	out := bytes.NewBufferString("")
+
+	err := copyLogLines("vault-0", strings.NewReader("one\ntwo\n"), out, &sync.Mutex{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "[vault-0] one\n[vault-0] two\n", out.String())
+}
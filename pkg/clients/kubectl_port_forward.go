@@ -0,0 +1,194 @@
+package clients
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// portForwardRetryInterval is the time to wait before re-establishing
+// a port forward which has lost its connection to the pod
+var portForwardRetryInterval = 1 * time.Second
+
+// PortForward forwards a local port to the remote port of a pod or service. Targets
+// are specified as pod/name, service/name, or name for a pod. When localPort is 0
+// a free port is chosen. The forward reconnects automatically, selecting a new pod
+// for services, until the stop channel is closed
+func (k *KubernetesImpl) PortForward(namespace, target string, localPort, remotePort int, stop <-chan struct{}) (int, error) {
+	if localPort == 0 {
+		p, err := freeLocalPort()
+		if err != nil {
+			return 0, xerrors.Errorf("Unable to find a free local port: %w", err)
+		}
+
+		localPort = p
+	}
+
+	ready := make(chan struct{})
+	errs := make(chan error, 1)
+
+	go func() {
+		// the first connection reports errors to the caller, once it has been
+		// established failures are retried until the forward is closed
+		r := ready
+		for {
+			pod, port, err := k.resolvePortForwardTarget(namespace, target, remotePort)
+			if err == nil {
+				err = k.forwardPort(namespace, pod, localPort, port, r, stop)
+			}
+
+			if r == ready {
+				select {
+				case <-ready:
+				default:
+					errs <- err
+					return
+				}
+			}
+
+			select {
+			case <-stop:
+				return
+			case <-time.After(portForwardRetryInterval):
+			}
+
+			r = make(chan struct{})
+			k.l.Debug("Reconnecting port forward", "target", target, "namespace", namespace, "local_port", localPort, "error", err)
+		}
+	}()
+
+	select {
+	case <-ready:
+		return localPort, nil
+	case err := <-errs:
+		return 0, xerrors.Errorf("Unable to forward port %d to %s: %w", localPort, target, err)
+	}
+}
+
+// forwardPort forwards the local port to the pod, blocks until the stop channel
+// is closed or the connection to the pod is lost
+func (k *KubernetesImpl) forwardPort(namespace, pod string, localPort, remotePort int, ready chan struct{}, stop <-chan struct{}) error {
+	u := k.client.RESTClient().
+		Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("portforward").
+		URL()
+
+	transport, upgrader, err := spdy.RoundTripperFor(k.restConfig)
+	if err != nil {
+		return err
+	}
+
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", u)
+	ports := []string{fmt.Sprintf("%d:%d", localPort, remotePort)}
+
+	fw, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, ports, stop, ready, ioutil.Discard, ioutil.Discard)
+	if err != nil {
+		return err
+	}
+
+	k.l.Debug("Forwarding port", "pod", pod, "namespace", namespace, "local_port", localPort, "remote_port", remotePort)
+
+	return fw.ForwardPorts()
+}
+
+// resolvePortForwardTarget returns the pod name and container port for a target,
+// services are resolved to the first running pod which matches their selector
+func (k *KubernetesImpl) resolvePortForwardTarget(namespace, target string, port int) (string, int, error) {
+	kind := "pod"
+	name := target
+	if parts := strings.SplitN(target, "/", 2); len(parts) == 2 {
+		kind = strings.ToLower(parts[0])
+		name = parts[1]
+	}
+
+	switch kind {
+	case "pod", "pods", "po":
+		return name, port, nil
+	case "service", "services", "svc":
+	default:
+		return "", 0, xerrors.Errorf("Unsupported port forward target %s, must be a pod or service", target)
+	}
+
+	svc, err := k.client.Services(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return "", 0, xerrors.Errorf("Unable to get service %s: %w", name, err)
+	}
+
+	var targetPort *intstr.IntOrString
+	for _, p := range svc.Spec.Ports {
+		if int(p.Port) == port {
+			tp := p.TargetPort
+			targetPort = &tp
+			break
+		}
+	}
+
+	if targetPort == nil {
+		return "", 0, xerrors.Errorf("Service %s does not expose port %d", name, port)
+	}
+
+	pl, err := k.client.Pods(namespace).List(metav1.ListOptions{
+		LabelSelector: metav1.FormatLabelSelector(&metav1.LabelSelector{MatchLabels: svc.Spec.Selector}),
+		FieldSelector: "status.phase=Running",
+	})
+	if err != nil {
+		return "", 0, xerrors.Errorf("Unable to list pods for service %s: %w", name, err)
+	}
+
+	if len(pl.Items) == 0 {
+		return "", 0, xerrors.Errorf("No running pods found for service %s", name)
+	}
+
+	pod := pl.Items[0]
+	cp, err := containerPort(pod, *targetPort, port)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return pod.Name, cp, nil
+}
+
+// containerPort resolves a service target port to the port number in the pod
+func containerPort(pod v1.Pod, targetPort intstr.IntOrString, servicePort int) (int, error) {
+	if targetPort.Type == intstr.Int {
+		if targetPort.IntVal == 0 {
+			return servicePort, nil
+		}
+
+		return int(targetPort.IntVal), nil
+	}
+
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.Name == targetPort.StrVal {
+				return int(p.ContainerPort), nil
+			}
+		}
+	}
+
+	return 0, xerrors.Errorf("Pod %s does not define a port named %s", pod.Name, targetPort.StrVal)
+}
+
+// freeLocalPort returns a port which is currently free on the loopback interface
+func freeLocalPort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
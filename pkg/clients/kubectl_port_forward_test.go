@@ -0,0 +1,87 @@
+package clients
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func setupPortForwardObjects(targetPort intstr.IntOrString) []runtime.Object {
+	return []runtime.Object{
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: v1.ServiceSpec{
+				Selector: map[string]string{"app": "web"},
+				Ports:    []v1.ServicePort{v1.ServicePort{Port: 80, TargetPort: targetPort}},
+			},
+		},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "default", Labels: map[string]string{"app": "web"}},
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{
+					v1.Container{Name: "web", Ports: []v1.ContainerPort{v1.ContainerPort{Name: "http", ContainerPort: 9090}}},
+				},
+			},
+			Status: v1.PodStatus{Phase: v1.PodRunning},
+		},
+	}
+}
+
+func TestPortForwardResolvesPodTarget(t *testing.T) {
+	k := setupFakeKubernetes(nil, nil)
+
+	for _, target := range []string{"web-abc", "pod/web-abc", "po/web-abc"} {
+		pod, port, err := k.resolvePortForwardTarget("default", target, 8080)
+		assert.NoError(t, err)
+		assert.Equal(t, "web-abc", pod)
+		assert.Equal(t, 8080, port)
+	}
+}
+
+func TestPortForwardResolvesServiceToPodAndTargetPort(t *testing.T) {
+	k := setupFakeKubernetes(setupPortForwardObjects(intstr.FromInt(9000)), nil)
+
+	pod, port, err := k.resolvePortForwardTarget("default", "svc/web", 80)
+	assert.NoError(t, err)
+	assert.Equal(t, "web-abc", pod)
+	assert.Equal(t, 9000, port)
+}
+
+func TestPortForwardResolvesServiceNamedTargetPort(t *testing.T) {
+	k := setupFakeKubernetes(setupPortForwardObjects(intstr.FromString("http")), nil)
+
+	_, port, err := k.resolvePortForwardTarget("default", "service/web", 80)
+	assert.NoError(t, err)
+	assert.Equal(t, 9090, port)
+}
+
+func TestPortForwardReturnsErrorWhenServicePortNotExposed(t *testing.T) {
+	k := setupFakeKubernetes(setupPortForwardObjects(intstr.FromInt(9000)), nil)
+
+	_, _, err := k.resolvePortForwardTarget("default", "svc/web", 443)
+	assert.Error(t, err)
+}
+
+func TestPortForwardReturnsErrorWhenServiceMissing(t *testing.T) {
+	k := setupFakeKubernetes(nil, nil)
+
+	_, _, err := k.resolvePortForwardTarget("default", "svc/web", 80)
+	assert.Error(t, err)
+}
+
+func TestPortForwardReturnsErrorForUnsupportedTarget(t *testing.T) {
+	k := setupFakeKubernetes(nil, nil)
+
+	_, _, err := k.resolvePortForwardTarget("default", "deployment/web", 80)
+	assert.Error(t, err)
+}
+
+func TestFreeLocalPortReturnsPort(t *testing.T) {
+	p, err := freeLocalPort()
+	assert.NoError(t, err)
+	assert.Greater(t, p, 0)
+}
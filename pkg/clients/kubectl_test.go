@@ -1,7 +1,28 @@
 package clients
 
 import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"helm.sh/helm/v3/pkg/kube"
+	v1 "k8s.io/api/core/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	extfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	restfake "k8s.io/client-go/rest/fake"
 )
 
 // TODO: implement these tests
@@ -14,6 +35,75 @@ func TestApply(t *testing.T) {
 	t.Skip()
 }
 
+func setupFakeKubernetes(objects []runtime.Object, crds []runtime.Object) *KubernetesImpl {
+	return &KubernetesImpl{
+		client:    fake.NewSimpleClientset(objects...).CoreV1(),
+		extClient: extfake.NewSimpleClientset(crds...),
+		timeout:   time.Second,
+		l:         hclog.NewNullLogger(),
+	}
+}
+
+func testCRD(name string, established bool) *apiextensionsv1beta1.CustomResourceDefinition {
+	status := apiextensionsv1beta1.ConditionFalse
+	if established {
+		status = apiextensionsv1beta1.ConditionTrue
+	}
+
+	return &apiextensionsv1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: apiextensionsv1beta1.CustomResourceDefinitionStatus{
+			Conditions: []apiextensionsv1beta1.CustomResourceDefinitionCondition{
+				apiextensionsv1beta1.CustomResourceDefinitionCondition{Type: apiextensionsv1beta1.Established, Status: status},
+			},
+		},
+	}
+}
+
+func TestWaitForCRDsReturnsWhenEstablished(t *testing.T) {
+	k := setupFakeKubernetes(nil, []runtime.Object{testCRD("widgets.example.com", true)})
+
+	err := k.WaitForCRDs(context.Background(), []string{"widgets.example.com"}, time.Second)
+	assert.NoError(t, err)
+}
+
+func TestWaitForCRDsTimesOutWhenNotEstablished(t *testing.T) {
+	k := setupFakeKubernetes(nil, []runtime.Object{testCRD("widgets.example.com", false)})
+
+	err := k.WaitForCRDs(context.Background(), []string{"widgets.example.com"}, 10*time.Millisecond)
+	assert.Error(t, err)
+}
+
+func TestWaitForCRDsTimesOutWhenMissing(t *testing.T) {
+	k := setupFakeKubernetes(nil, nil)
+
+	err := k.WaitForCRDs(context.Background(), []string{"widgets.example.com"}, 10*time.Millisecond)
+	assert.Error(t, err)
+}
+
+func TestWaitForEndpointsReturnsWhenReady(t *testing.T) {
+	ep := &v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook", Namespace: "cert-manager"},
+		Subsets: []v1.EndpointSubset{
+			v1.EndpointSubset{Addresses: []v1.EndpointAddress{v1.EndpointAddress{IP: "10.0.0.1"}}},
+		},
+	}
+
+	k := setupFakeKubernetes([]runtime.Object{ep}, nil)
+
+	err := k.WaitForEndpoints(context.Background(), []string{"cert-manager/webhook"}, time.Second)
+	assert.NoError(t, err)
+}
+
+func TestWaitForEndpointsTimesOutWhenNoAddresses(t *testing.T) {
+	ep := &v1.Endpoints{ObjectMeta: metav1.ObjectMeta{Name: "webhook", Namespace: "default"}}
+
+	k := setupFakeKubernetes([]runtime.Object{ep}, nil)
+
+	err := k.WaitForEndpoints(context.Background(), []string{"webhook"}, 10*time.Millisecond)
+	assert.Error(t, err)
+}
+
 const guestbookManifest = `
 apiVersion: v1
 kind: Service
@@ -137,3 +227,135 @@ spec:
         ports:
         - containerPort: 80
 `
+
+func setupServerSideApply(status ...int) (kube.ResourceList, *[]*http.Request) {
+	reqs := []*http.Request{}
+	reasons := map[int]metav1.StatusReason{
+		http.StatusConflict:             metav1.StatusReasonConflict,
+		http.StatusUnsupportedMediaType: metav1.StatusReasonUnsupportedMediaType,
+		http.StatusInternalServerError:  metav1.StatusReasonInternalError,
+	}
+
+	client := &restfake.RESTClient{
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		GroupVersion:         v1.SchemeGroupVersion,
+		Client: restfake.CreateHTTPClient(func(r *http.Request) (*http.Response, error) {
+			reqs = append(reqs, r)
+
+			code := status[len(reqs)-1]
+			body := fmt.Sprintf(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":%q,"code":%d}`, reasons[code], code)
+			if code == http.StatusOK {
+				body = `{"kind":"ConfigMap","apiVersion":"v1","metadata":{"name":"test","namespace":"default","resourceVersion":"2"}}`
+			}
+
+			return &http.Response{
+				StatusCode: code,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       ioutil.NopCloser(strings.NewReader(body)),
+			}, nil
+		}),
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetName("test")
+	obj.SetNamespace("default")
+
+	info := &resource.Info{
+		Client: client,
+		Mapping: &meta.RESTMapping{
+			Resource:         v1.SchemeGroupVersion.WithResource("configmaps"),
+			GroupVersionKind: v1.SchemeGroupVersion.WithKind("ConfigMap"),
+			Scope:            meta.RESTScopeNamespace,
+		},
+		Namespace: "default",
+		Name:      "test",
+		Object:    obj,
+	}
+
+	return kube.ResourceList{info}, &reqs
+}
+
+func TestServerSideApplyPatchesWithFieldManager(t *testing.T) {
+	r, reqs := setupServerSideApply(http.StatusOK)
+
+	err := serverSideApply(r)
+	assert.NoError(t, err)
+
+	assert.Len(t, *reqs, 1)
+	req := (*reqs)[0]
+	assert.Equal(t, http.MethodPatch, req.Method)
+	assert.Equal(t, "application/apply-patch+yaml", req.Header.Get("Content-Type"))
+	assert.Equal(t, "shipyard", req.URL.Query().Get("fieldManager"))
+	assert.Equal(t, "false", req.URL.Query().Get("force"))
+}
+
+func TestServerSideApplyForcesOnConflict(t *testing.T) {
+	r, reqs := setupServerSideApply(http.StatusConflict, http.StatusOK)
+
+	err := serverSideApply(r)
+	assert.NoError(t, err)
+
+	assert.Len(t, *reqs, 2)
+	assert.Equal(t, "true", (*reqs)[1].URL.Query().Get("force"))
+}
+
+func TestServerSideApplyReturnsUnsupportedOnOldClusters(t *testing.T) {
+	r, _ := setupServerSideApply(http.StatusUnsupportedMediaType)
+
+	err := serverSideApply(r)
+	assert.Equal(t, errServerSideApplyUnsupported, err)
+}
+
+func TestServerSideApplyReturnsErrorOnFailure(t *testing.T) {
+	r, _ := setupServerSideApply(http.StatusInternalServerError)
+
+	err := serverSideApply(r)
+	assert.Error(t, err)
+	assert.NotEqual(t, errServerSideApplyUnsupported, err)
+}
+
+func testPod(name string, phase v1.PodPhase) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "vault"}},
+		Status:     v1.PodStatus{Phase: phase},
+	}
+}
+
+func TestFindPodReturnsPodByName(t *testing.T) {
+	k := setupFakeKubernetes([]runtime.Object{testPod("vault-0", v1.PodRunning)}, nil)
+
+	p, err := k.findPod("default", "vault-0")
+	assert.NoError(t, err)
+	assert.Equal(t, "vault-0", p.Name)
+}
+
+func TestFindPodReturnsErrorWhenNamedPodNotRunning(t *testing.T) {
+	k := setupFakeKubernetes([]runtime.Object{testPod("vault-0", v1.PodPending)}, nil)
+
+	_, err := k.findPod("default", "vault-0")
+	assert.Error(t, err)
+}
+
+func TestFindPodReturnsErrorWhenNamedPodMissing(t *testing.T) {
+	k := setupFakeKubernetes(nil, nil)
+
+	_, err := k.findPod("default", "vault-0")
+	assert.Error(t, err)
+}
+
+func TestFindPodReturnsPodBySelector(t *testing.T) {
+	k := setupFakeKubernetes([]runtime.Object{testPod("vault-0", v1.PodRunning)}, nil)
+
+	p, err := k.findPod("default", "app=vault")
+	assert.NoError(t, err)
+	assert.Equal(t, "vault-0", p.Name)
+}
+
+func TestFindPodReturnsErrorWhenSelectorMatchesNothing(t *testing.T) {
+	k := setupFakeKubernetes([]runtime.Object{testPod("vault-0", v1.PodRunning)}, nil)
+
+	_, err := k.findPod("default", "app=consul")
+	assert.Error(t, err)
+}
@@ -0,0 +1,54 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockConsul is a mock implementation of the Consul client
+// interface
+type MockConsul struct {
+	mock.Mock
+}
+
+func (m *MockConsul) PassingInstances(address, token, service string) (int, error) {
+	args := m.Called(address, token, service)
+
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockConsul) HealthCheckServices(ctx context.Context, address, token string, services []string, timeout time.Duration) error {
+	args := m.Called(address, token, services, timeout)
+
+	return args.Error(0)
+}
+
+func (m *MockConsul) GetKV(address, token, key string) ([]byte, error) {
+	args := m.Called(address, token, key)
+
+	if d, ok := args.Get(0).([]byte); ok {
+		return d, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockConsul) PutKV(address, token, key string, value []byte) error {
+	args := m.Called(address, token, key, value)
+
+	return args.Error(0)
+}
+
+func (m *MockConsul) DeleteKV(address, token, key string) error {
+	args := m.Called(address, token, key)
+
+	return args.Error(0)
+}
+
+func (m *MockConsul) CreateKV(address, token, key string, value []byte) (bool, error) {
+	args := m.Called(address, token, key, value)
+
+	return args.Bool(0), args.Error(1)
+}
@@ -1,7 +1,9 @@
 package mocks
 
 import (
+	"context"
 	"io"
+	"time"
 
 	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/stretchr/testify/mock"
@@ -15,37 +17,59 @@ func (m *MockContainerTasks) SetForcePull(f bool) {
 	m.Called(f)
 }
 
-func (m *MockContainerTasks) CreateContainer(c *config.Container) (id string, err error) {
+func (m *MockContainerTasks) CreateContainer(ctx context.Context, c *config.Container) (id string, err error) {
 	args := m.Called(c)
 
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockContainerTasks) RemoveContainer(id string) error {
+func (m *MockContainerTasks) RemoveContainer(ctx context.Context, id string) error {
 	args := m.Called(id)
 
 	return args.Error(0)
 }
 
-func (m *MockContainerTasks) CreateVolume(name string) (id string, err error) {
+func (m *MockContainerTasks) StopContainer(ctx context.Context, id string, timeout time.Duration) error {
+	args := m.Called(id, timeout)
+
+	return args.Error(0)
+}
+
+func (m *MockContainerTasks) StartContainer(ctx context.Context, id string) error {
+	args := m.Called(id)
+
+	return args.Error(0)
+}
+
+func (m *MockContainerTasks) CreateVolume(ctx context.Context, name string) (id string, err error) {
 	args := m.Called(name)
 
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockContainerTasks) RemoveVolume(name string) error {
+func (m *MockContainerTasks) RemoveVolume(ctx context.Context, name string) error {
 	args := m.Called(name)
 
 	return args.Error(0)
 }
 
-func (m *MockContainerTasks) PullImage(i config.Image, f bool) error {
+func (m *MockContainerTasks) PullImage(ctx context.Context, i config.Image, f bool) error {
 	args := m.Called(i, f)
 
 	return args.Error(0)
 }
 
-func (m *MockContainerTasks) FindContainerIDs(name string, typeName config.ResourceType) ([]string, error) {
+func (m *MockContainerTasks) FindContainers(ctx context.Context, name string, typeName config.ResourceType) ([]config.ContainerInfo, error) {
+	args := m.Called(name, typeName)
+
+	if cs, ok := args.Get(0).([]config.ContainerInfo); ok {
+		return cs, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockContainerTasks) FindContainerIDs(ctx context.Context, name string, typeName config.ResourceType) ([]string, error) {
 	args := m.Called(name, typeName)
 
 	if sa, ok := args.Get(0).([]string); ok {
@@ -55,7 +79,7 @@ func (m *MockContainerTasks) FindContainerIDs(name string, typeName config.Resou
 	return nil, args.Error(1)
 }
 
-func (d *MockContainerTasks) ContainerLogs(id string, stdOut, stdErr bool) (io.ReadCloser, error) {
+func (d *MockContainerTasks) ContainerLogs(ctx context.Context, id string, stdOut, stdErr bool) (io.ReadCloser, error) {
 	args := d.Called(id, stdOut, stdErr)
 
 	if rc, ok := args.Get(0).(io.ReadCloser); ok {
@@ -65,13 +89,71 @@ func (d *MockContainerTasks) ContainerLogs(id string, stdOut, stdErr bool) (io.R
 	return nil, args.Error(1)
 }
 
-func (d *MockContainerTasks) CopyFromContainer(id, src, dst string) error {
+func (d *MockContainerTasks) CopyFromContainer(ctx context.Context, id, src, dst string) error {
+	args := d.Called(id, src, dst)
+
+	return args.Error(0)
+}
+
+func (d *MockContainerTasks) CopyToContainer(ctx context.Context, id, src, dst string) error {
 	args := d.Called(id, src, dst)
 
 	return args.Error(0)
 }
 
-func (d *MockContainerTasks) CopyLocalDockerImageToVolume(images []string, volume string, force bool) ([]string, error) {
+func (d *MockContainerTasks) SaveImages(ctx context.Context, images []string, w io.Writer) error {
+	args := d.Called(images, w)
+
+	return args.Error(0)
+}
+
+func (d *MockContainerTasks) LoadImages(ctx context.Context, r io.Reader) error {
+	args := d.Called(r)
+
+	return args.Error(0)
+}
+
+func (d *MockContainerTasks) CommitContainer(ctx context.Context, id, ref string) error {
+	args := d.Called(id, ref)
+
+	return args.Error(0)
+}
+
+func (d *MockContainerTasks) RemoveImage(ctx context.Context, ref string) error {
+	args := d.Called(ref)
+
+	return args.Error(0)
+}
+
+func (d *MockContainerTasks) ImageExists(ctx context.Context, ref string) (bool, error) {
+	args := d.Called(ref)
+
+	return args.Bool(0), args.Error(1)
+}
+
+func (d *MockContainerTasks) InspectImage(ctx context.Context, ref string) (*config.ImageInfo, error) {
+	args := d.Called(ref)
+
+	if i, ok := args.Get(0).(*config.ImageInfo); ok {
+		return i, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (d *MockContainerTasks) ExportVolume(ctx context.Context, name string, w io.Writer) error {
+	args := d.Called(name, w)
+
+	return args.Error(0)
+}
+
+func (d *MockContainerTasks) ImportVolume(ctx context.Context, name string, r io.Reader) error {
+	args := d.Called(name, r)
+
+	return args.Error(0)
+}
+
+func (d *MockContainerTasks) CopyLocalDockerImageToVolume(ctx context.Context, images []string, volume string, force bool) ([]string, error) {
 	args := d.Called(images, volume, force)
 
 	if a, ok := args.Get(0).([]string); ok {
@@ -81,19 +163,88 @@ func (d *MockContainerTasks) CopyLocalDockerImageToVolume(images []string, volum
 	return nil, args.Error(1)
 }
 
-func (d *MockContainerTasks) ExecuteCommand(id string, command []string, env []string, workingDirectory string, writer io.Writer) error {
+func (d *MockContainerTasks) ExecuteCommand(ctx context.Context, id string, command []string, env []string, workingDirectory string, writer io.Writer) error {
 	args := d.Called(id, command, env, workingDirectory, writer)
 
 	return args.Error(0)
 }
 
-func (d *MockContainerTasks) DetachNetwork(network, containerid string) error {
+func (d *MockContainerTasks) DetachNetwork(ctx context.Context, network, containerid string) error {
+	args := d.Called(network, containerid)
+
+	return args.Error(0)
+}
+
+func (d *MockContainerTasks) AttachNetwork(ctx context.Context, network, containerid string) error {
 	args := d.Called(network, containerid)
 
 	return args.Error(0)
 }
-func (d *MockContainerTasks) CreateShell(id string, command []string, stdin io.ReadCloser, stdout io.Writer, stderr io.Writer) error {
+
+func (d *MockContainerTasks) CreateShell(ctx context.Context, id string, command []string, stdin io.ReadCloser, stdout io.Writer, stderr io.Writer) error {
 	args := d.Called(id, command, stdin, stdout, stderr)
 
 	return args.Error(0)
 }
+
+func (d *MockContainerTasks) PruneContainers(ctx context.Context, age time.Duration, keep []string) ([]string, error) {
+	args := d.Called(age, keep)
+
+	if ids, ok := args.Get(0).([]string); ok {
+		return ids, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (d *MockContainerTasks) PruneImages(ctx context.Context, age time.Duration) ([]string, error) {
+	args := d.Called(age)
+
+	if ids, ok := args.Get(0).([]string); ok {
+		return ids, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (d *MockContainerTasks) PruneVolumes(ctx context.Context, age time.Duration, keep []string) ([]string, error) {
+	args := d.Called(age, keep)
+
+	if names, ok := args.Get(0).([]string); ok {
+		return names, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (d *MockContainerTasks) WaitForExit(ctx context.Context, id string, timeout time.Duration) (int, string, error) {
+	args := d.Called(id, timeout)
+
+	return args.Int(0), args.String(1), args.Error(2)
+}
+
+func (d *MockContainerTasks) ContainerStats(ctx context.Context, id string) (*config.ContainerStats, error) {
+	args := d.Called(id)
+
+	if cs, ok := args.Get(0).(*config.ContainerStats); ok {
+		return cs, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (d *MockContainerTasks) StreamContainerStats(ctx context.Context, id string, stats chan<- config.ContainerStats, stop <-chan struct{}) error {
+	args := d.Called(id, stats, stop)
+
+	return args.Error(0)
+}
+
+func (d *MockContainerTasks) DiskUsage(ctx context.Context) (map[string]config.ContainerDiskUsage, error) {
+	args := d.Called()
+
+	if du, ok := args.Get(0).(map[string]config.ContainerDiskUsage); ok {
+		return du, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
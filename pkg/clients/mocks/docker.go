@@ -9,6 +9,7 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/network"
+	registrytypes "github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/api/types/volume"
 	volumetypes "github.com/docker/docker/api/types/volume"
 	"github.com/stretchr/testify/mock"
@@ -81,6 +82,45 @@ func (m *MockDocker) ContainerLogs(ctx context.Context, containerID string, opti
 	return rc, args.Error(1)
 }
 
+func (m *MockDocker) ContainerStats(ctx context.Context, containerID string, stream bool) (types.ContainerStats, error) {
+	args := m.Called(ctx, containerID, stream)
+
+	if cs, ok := args.Get(0).(types.ContainerStats); ok {
+		return cs, args.Error(1)
+	}
+
+	return types.ContainerStats{}, args.Error(1)
+}
+
+func (m *MockDocker) DiskUsage(ctx context.Context) (types.DiskUsage, error) {
+	args := m.Called(ctx)
+
+	if du, ok := args.Get(0).(types.DiskUsage); ok {
+		return du, args.Error(1)
+	}
+
+	return types.DiskUsage{}, args.Error(1)
+}
+
+func (m *MockDocker) Info(ctx context.Context) (types.Info, error) {
+	args := m.Called(ctx)
+
+	if i, ok := args.Get(0).(types.Info); ok {
+		return i, args.Error(1)
+	}
+
+	return types.Info{}, args.Error(1)
+}
+
+func (m *MockDocker) ContainerWait(ctx context.Context, containerID string, condition container.WaitCondition) (<-chan container.ContainerWaitOKBody, <-chan error) {
+	args := m.Called(ctx, containerID, condition)
+
+	resp, _ := args.Get(0).(chan container.ContainerWaitOKBody)
+	errs, _ := args.Get(1).(chan error)
+
+	return resp, errs
+}
+
 func (m *MockDocker) ContainerExecCreate(ctx context.Context, container string, config types.ExecConfig) (types.IDResponse, error) {
 	args := m.Called(ctx, container, config)
 
@@ -215,6 +255,46 @@ func (m *MockDocker) ImageSave(ctx context.Context, imageIDs []string) (io.ReadC
 	return nil, args.Error(1)
 }
 
+func (m *MockDocker) ImageLoad(ctx context.Context, input io.Reader, quiet bool) (types.ImageLoadResponse, error) {
+	args := m.Called(ctx, input, quiet)
+
+	if r, ok := args.Get(0).(types.ImageLoadResponse); ok {
+		return r, args.Error(1)
+	}
+
+	return types.ImageLoadResponse{}, args.Error(1)
+}
+
+func (m *MockDocker) ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	args := m.Called(ctx, imageID)
+
+	if i, ok := args.Get(0).(types.ImageInspect); ok {
+		return i, nil, args.Error(1)
+	}
+
+	return types.ImageInspect{}, nil, args.Error(1)
+}
+
+func (m *MockDocker) DistributionInspect(ctx context.Context, image, encodedRegistryAuth string) (registrytypes.DistributionInspect, error) {
+	args := m.Called(ctx, image, encodedRegistryAuth)
+
+	if di, ok := args.Get(0).(registrytypes.DistributionInspect); ok {
+		return di, args.Error(1)
+	}
+
+	return registrytypes.DistributionInspect{}, args.Error(1)
+}
+
+func (m *MockDocker) Ping(ctx context.Context) (types.Ping, error) {
+	args := m.Called(ctx)
+
+	if p, ok := args.Get(0).(types.Ping); ok {
+		return p, args.Error(1)
+	}
+
+	return types.Ping{}, args.Error(1)
+}
+
 func (m *MockDocker) ImageList(ctx context.Context, options types.ImageListOptions) ([]types.ImageSummary, error) {
 	args := m.Called(ctx, options)
 
@@ -225,6 +305,16 @@ func (m *MockDocker) ImageList(ctx context.Context, options types.ImageListOptio
 	return []types.ImageSummary{}, args.Error(1)
 }
 
+func (m *MockDocker) ContainerCommit(ctx context.Context, container string, options types.ContainerCommitOptions) (types.IDResponse, error) {
+	args := m.Called(ctx, container, options)
+
+	if r, ok := args.Get(0).(types.IDResponse); ok {
+		return r, args.Error(1)
+	}
+
+	return types.IDResponse{}, args.Error(1)
+}
+
 func (m *MockDocker) ImageRemove(ctx context.Context, imageID string, options types.ImageRemoveOptions) ([]types.ImageDeleteResponseItem, error) {
 	args := m.Called(ctx, imageID, options)
 
@@ -234,3 +324,9 @@ func (m *MockDocker) ImageRemove(ctx context.Context, imageID string, options ty
 
 	return nil, args.Error(1)
 }
+
+func (m *MockDocker) ImageTag(ctx context.Context, source, target string) error {
+	args := m.Called(ctx, source, target)
+
+	return args.Error(0)
+}
@@ -11,6 +11,15 @@ func (mb *Getter) Get(src, dst string) error {
 	return args.Error(0)
 }
 
+func (mb *Getter) Fetch(uri string) (string, error) {
+	args := mb.Called(uri)
+	return args.String(0), args.Error(1)
+}
+
 func (mb *Getter) SetForce(force bool) {
 	mb.Called(force)
 }
+
+func (mb *Getter) SetOffline(offline bool) {
+	mb.Called(offline)
+}
@@ -1,6 +1,7 @@
 package mocks
 
 import (
+	"context"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -8,7 +9,7 @@ type MockHelm struct {
 	mock.Mock
 }
 
-func (h *MockHelm) Create(kubeConfig, name, namespace, chartPath, valuesPath string, valueString map[string]string) error {
+func (h *MockHelm) Create(ctx context.Context, kubeConfig, name, namespace, chartPath, valuesPath string, valueString map[string]string) error {
 	args := h.Called(kubeConfig, name, namespace, chartPath, valuesPath, valueString)
 
 	return args.Error(0)
@@ -19,3 +20,15 @@ func (h *MockHelm) Destroy(kubeConfig, name, namespace string) error {
 
 	return args.Error(0)
 }
+
+func (h *MockHelm) ReleaseExists(kubeConfig, name, namespace string) (bool, error) {
+	args := h.Called(kubeConfig, name, namespace)
+
+	return args.Bool(0), args.Error(1)
+}
+
+func (h *MockHelm) Manifest(kubeConfig, name, namespace string) (string, error) {
+	args := h.Called(kubeConfig, name, namespace)
+
+	return args.String(0), args.Error(1)
+}
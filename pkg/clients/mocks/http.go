@@ -2,10 +2,12 @@ package mocks
 
 import (
 	"bytes"
+	"context"
 	"io/ioutil"
 	"net/http"
 	"time"
 
+	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -15,12 +17,24 @@ type MockHTTP struct {
 	mock.Mock
 }
 
-func (m *MockHTTP) HealthCheckHTTP(uri string, timeout time.Duration) error {
+func (m *MockHTTP) HealthCheckHTTP(ctx context.Context, uri string, timeout time.Duration) error {
 	args := m.Called(uri, timeout)
 
 	return args.Error(0)
 }
 
+func (m *MockHTTP) HealthCheckHTTPWithOptions(ctx context.Context, uri string, options config.HTTPHealthCheck, timeout time.Duration) error {
+	args := m.Called(uri, options, timeout)
+
+	return args.Error(0)
+}
+
+func (m *MockHTTP) HealthCheckTCP(ctx context.Context, address string, timeout time.Duration) error {
+	args := m.Called(address, timeout)
+
+	return args.Error(0)
+}
+
 func (m *MockHTTP) Do(r *http.Request) (*http.Response, error) {
 	args := m.Called(r)
 
@@ -1,8 +1,11 @@
 package mocks
 
 import (
+	"context"
+	"io"
 	"time"
 
+	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/stretchr/testify/mock"
 	v1 "k8s.io/api/core/v1"
 )
@@ -17,6 +20,12 @@ func (m *MockKubernetes) SetConfig(kubeconfig string) error {
 	return args.Error(0)
 }
 
+func (m *MockKubernetes) SetConfigWithContext(kubeconfig, context string) error {
+	args := m.Called(kubeconfig, context)
+
+	return args.Error(0)
+}
+
 func (m *MockKubernetes) GetPods(selector string) (*v1.PodList, error) {
 	args := m.Called(selector)
 
@@ -27,7 +36,7 @@ func (m *MockKubernetes) GetPods(selector string) (*v1.PodList, error) {
 	return nil, args.Error(1)
 }
 
-func (m *MockKubernetes) Apply(files []string, waitUntilReady bool) error {
+func (m *MockKubernetes) Apply(ctx context.Context, files []string, waitUntilReady bool) error {
 	args := m.Called(files, waitUntilReady)
 
 	return args.Error(0)
@@ -39,8 +48,70 @@ func (m *MockKubernetes) Delete(files []string) error {
 	return args.Error(0)
 }
 
-func (m *MockKubernetes) HealthCheckPods(selectors []string, timeout time.Duration) error {
+func (m *MockKubernetes) HealthCheckPods(ctx context.Context, selectors []string, timeout time.Duration) error {
 	args := m.Called(selectors, timeout)
 
 	return args.Error(0)
 }
+
+func (m *MockKubernetes) Inventory(files []string) ([]config.K8sObject, error) {
+	args := m.Called(files)
+
+	if o, ok := args.Get(0).([]config.K8sObject); ok {
+		return o, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockKubernetes) DeleteObjects(objects []config.K8sObject) error {
+	args := m.Called(objects)
+
+	return args.Error(0)
+}
+
+func (m *MockKubernetes) MissingObjects(objects []config.K8sObject) ([]config.K8sObject, error) {
+	args := m.Called(objects)
+
+	if o, ok := args.Get(0).([]config.K8sObject); ok {
+		return o, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockKubernetes) ExecPod(namespace, selector, container string, command []string, writer io.Writer) error {
+	args := m.Called(namespace, selector, container, command, writer)
+
+	return args.Error(0)
+}
+
+func (m *MockKubernetes) ShellPod(namespace, selector, container string, command []string, stdin io.ReadCloser, stdout io.Writer, stderr io.Writer) error {
+	args := m.Called(namespace, selector, container, command, stdin, stdout, stderr)
+
+	return args.Error(0)
+}
+
+func (m *MockKubernetes) PodLogs(namespace, selector, container string, follow bool, since time.Duration, writer io.Writer) error {
+	args := m.Called(namespace, selector, container, follow, since, writer)
+
+	return args.Error(0)
+}
+
+func (m *MockKubernetes) WaitForCRDs(ctx context.Context, names []string, timeout time.Duration) error {
+	args := m.Called(names, timeout)
+
+	return args.Error(0)
+}
+
+func (m *MockKubernetes) WaitForEndpoints(ctx context.Context, services []string, timeout time.Duration) error {
+	args := m.Called(services, timeout)
+
+	return args.Error(0)
+}
+
+func (m *MockKubernetes) PortForward(namespace, target string, localPort, remotePort int, stop <-chan struct{}) (int, error) {
+	args := m.Called(namespace, target, localPort, remotePort, stop)
+
+	return args.Int(0), args.Error(1)
+}
@@ -1,8 +1,11 @@
 package mocks
 
 import (
+	"context"
+	"io"
 	"time"
 
+	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -28,6 +31,16 @@ func (m *MockNomad) Stop(files []string) error {
 	return args.Error(0)
 }
 
+func (m *MockNomad) MissingJobs(files []string) ([]string, error) {
+	args := m.Called(files)
+
+	if j, ok := args.Get(0).([]string); ok {
+		return j, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
 func (m *MockNomad) ParseJob(file string) ([]byte, error) {
 	args := m.Called(file)
 
@@ -40,7 +53,29 @@ func (m *MockNomad) JobStatus(job string) (string, error) {
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockNomad) HealthCheckAPI(timeout time.Duration) error {
+func (m *MockNomad) JobAllocations(job string) ([]config.NomadAllocation, error) {
+	args := m.Called(job)
+
+	if a, ok := args.Get(0).([]config.NomadAllocation); ok {
+		return a, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockNomad) AllocationLogs(allocID, task string, stderr, follow bool, writer io.Writer) error {
+	args := m.Called(allocID, task, stderr, follow, writer)
+
+	return args.Error(0)
+}
+
+func (m *MockNomad) Exec(allocID, task string, command []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	args := m.Called(allocID, task, command, stdin, stdout, stderr)
+
+	return args.Error(0)
+}
+
+func (m *MockNomad) HealthCheckAPI(ctx context.Context, timeout time.Duration) error {
 	args := m.Called(timeout)
 
 	return args.Error(0)
@@ -10,6 +10,10 @@ func (b *System) OpenBrowser(uri string) error {
 	return b.Called(uri).Error(0)
 }
 
+func (b *System) Notify(title, message string) error {
+	return b.Called(title, message).Error(0)
+}
+
 func (b *System) Preflight() (string, error) {
 	args := b.Called()
 	return "", args.Error(0)
@@ -0,0 +1,21 @@
+package mocks
+
+import "github.com/stretchr/testify/mock"
+
+type MockTunnel struct {
+	mock.Mock
+}
+
+func (m *MockTunnel) Open(provider string, localPort int, stop <-chan struct{}) (string, error) {
+	args := m.Called(provider, localPort, stop)
+
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockTunnel) Providers() []string {
+	if p, ok := m.Called().Get(0).([]string); ok {
+		return p
+	}
+
+	return nil
+}
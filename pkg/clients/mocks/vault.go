@@ -0,0 +1,46 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockVault is a mock implementation of the Vault client
+// interface
+type MockVault struct {
+	mock.Mock
+}
+
+func (m *MockVault) SetConfig(address, token string) {
+	m.Called(address, token)
+}
+
+func (m *MockVault) Status() (bool, bool, error) {
+	args := m.Called()
+
+	return args.Bool(0), args.Bool(1), args.Error(2)
+}
+
+func (m *MockVault) HealthCheckAPI(ctx context.Context, timeout time.Duration) error {
+	args := m.Called(timeout)
+
+	return args.Error(0)
+}
+
+func (m *MockVault) LookupToken() (map[string]interface{}, error) {
+	args := m.Called()
+
+	if d, ok := args.Get(0).(map[string]interface{}); ok {
+		return d, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockVault) WriteKV(mount, path string, data map[string]interface{}) error {
+	args := m.Called(mount, path, data)
+
+	return args.Error(0)
+}
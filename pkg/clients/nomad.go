@@ -2,13 +2,16 @@ package clients
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/config"
 	"golang.org/x/xerrors"
 )
 
@@ -24,10 +27,21 @@ type Nomad interface {
 	ParseJob(file string) ([]byte, error)
 	// JobStatus returns the status for the given job
 	JobStatus(job string) (string, error)
+	// MissingJobs returns the IDs of the jobs defined in the files which are
+	// not registered with the cluster or have been stopped, e.g. with the
+	// Nomad CLI
+	MissingJobs(files []string) ([]string, error)
+	// JobAllocations returns the allocations for the given job
+	JobAllocations(job string) ([]config.NomadAllocation, error)
+	// AllocationLogs writes the stdout or stderr logs for a task in the allocation
+	// to the writer, when follow is true it blocks until the stream is closed
+	AllocationLogs(allocID, task string, stderr, follow bool, writer io.Writer) error
+	// Exec runs a command in a task of the allocation, stdin is optional
+	Exec(allocID, task string, command []string, stdin io.Reader, stdout, stderr io.Writer) error
 	// HealthCheckAPI uses the Nomad API to check that all servers and nodes
 	// are ready. The function will block until either all nodes are healthy or the
 	// timeout period elapses.
-	HealthCheckAPI(time.Duration) error
+	HealthCheckAPI(ctx context.Context, timeout time.Duration) error
 }
 
 // NomadImpl is an implementation of the Nomad interface
@@ -66,7 +80,7 @@ func (n *NomadImpl) SetConfig(nomadconfig string) error {
 }
 
 // HealthCheckAPI executes a HTTP heathcheck for a Nomad cluster
-func (n *NomadImpl) HealthCheckAPI(timeout time.Duration) error {
+func (n *NomadImpl) HealthCheckAPI(ctx context.Context, timeout time.Duration) error {
 	// get the address and the nodecount from the config
 	address := n.c.Location
 	nodeCount := n.c.NodeCount
@@ -80,7 +94,7 @@ func (n *NomadImpl) HealthCheckAPI(timeout time.Duration) error {
 			return fmt.Errorf("Timeout waiting for Nomad healthcheck %s", address)
 		}
 
-		rq, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/nodes", address), nil)
+		rq, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/v1/nodes", address), nil)
 		if err != nil {
 			return err
 		}
@@ -106,7 +120,9 @@ func (n *NomadImpl) HealthCheckAPI(timeout time.Duration) error {
 		}
 
 		// backoff
-		time.Sleep(n.backoff)
+		if err := wait(ctx, n.backoff); err != nil {
+			return xerrors.Errorf("Nomad healthcheck %s cancelled: %w", address, err)
+		}
 	}
 }
 
@@ -234,6 +250,53 @@ func (n *NomadImpl) JobStatus(job string) (string, error) {
 	return jobDetail["Status"].(string), nil
 }
 
+// MissingJobs returns the IDs of the jobs in the files which are not
+// registered or have the status dead
+func (n *NomadImpl) MissingJobs(files []string) ([]string, error) {
+	missing := []string{}
+
+	for _, f := range files {
+		id, err := n.getJobID(f)
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/job/%s", n.c.Location, id), nil)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to create http request: %w", err)
+		}
+
+		resp, err := n.httpClient.Do(r)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to get job %s: %w", id, err)
+		}
+
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			missing = append(missing, id)
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, xerrors.Errorf("Error getting job %s, got status code %d", id, resp.StatusCode)
+		}
+
+		job := struct{ Status string }{}
+		err = json.NewDecoder(resp.Body).Decode(&job)
+		resp.Body.Close()
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to decode job %s: %w", id, err)
+		}
+
+		if job.Status == "dead" {
+			missing = append(missing, id)
+		}
+	}
+
+	return missing, nil
+}
+
 func (n *NomadImpl) getJobID(file string) (string, error) {
 	// parse the job
 	jsonJob, err := n.ParseJob(file)
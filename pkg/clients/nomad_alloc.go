@@ -0,0 +1,194 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// execFrame is a message sent to or received from the Nomad exec API,
+// byte slices are base64 encoded by the JSON encoder as the API requires
+type execFrame struct {
+	Stdin   *execStream `json:"stdin,omitempty"`
+	Stdout  *execStream `json:"stdout,omitempty"`
+	Stderr  *execStream `json:"stderr,omitempty"`
+	Exited  bool        `json:"exited,omitempty"`
+	Result  *execResult `json:"result,omitempty"`
+	TTYSize *execSize   `json:"tty_size,omitempty"`
+}
+
+type execStream struct {
+	Data  []byte `json:"data,omitempty"`
+	Close bool   `json:"close,omitempty"`
+}
+
+type execResult struct {
+	ExitCode int `json:"exit_code"`
+}
+
+type execSize struct {
+	Height int `json:"height"`
+	Width  int `json:"width"`
+}
+
+// JobAllocations returns the allocations for the given job
+func (n *NomadImpl) JobAllocations(job string) ([]config.NomadAllocation, error) {
+	r, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/job/%s/allocations", n.c.Location, job), nil)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to create http request: %w", err)
+	}
+
+	resp, err := n.httpClient.Do(r)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to get allocations for job %s: %w", job, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		d, _ := ioutil.ReadAll(resp.Body)
+		return nil, xerrors.Errorf("Error getting allocations for job %s, got status code %d, error: %s", job, resp.StatusCode, string(d))
+	}
+
+	allocs := []config.NomadAllocation{}
+	err = json.NewDecoder(resp.Body).Decode(&allocs)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to decode allocations for job %s: %w", job, err)
+	}
+
+	return allocs, nil
+}
+
+// AllocationLogs writes the logs for a task in the allocation to the writer, when follow
+// is true AllocationLogs blocks until the stream is closed by the server
+func (n *NomadImpl) AllocationLogs(allocID, task string, stderr, follow bool, writer io.Writer) error {
+	logType := "stdout"
+	if stderr {
+		logType = "stderr"
+	}
+
+	q := url.Values{}
+	q.Set("task", task)
+	q.Set("type", logType)
+	q.Set("origin", "start")
+	q.Set("plain", "true")
+	q.Set("follow", fmt.Sprintf("%t", follow))
+
+	r, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/client/fs/logs/%s?%s", n.c.Location, allocID, q.Encode()), nil)
+	if err != nil {
+		return xerrors.Errorf("Unable to create http request: %w", err)
+	}
+
+	resp, err := n.httpClient.Do(r)
+	if err != nil {
+		return xerrors.Errorf("Unable to get logs for allocation %s: %w", allocID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		d, _ := ioutil.ReadAll(resp.Body)
+		return xerrors.Errorf("Error getting logs for allocation %s, got status code %d, error: %s", allocID, resp.StatusCode, string(d))
+	}
+
+	_, err = io.Copy(writer, resp.Body)
+	if err != nil {
+		return xerrors.Errorf("Unable to read logs for allocation %s: %w", allocID, err)
+	}
+
+	return nil
+}
+
+// Exec runs a command in a task of the allocation using the Nomad exec API,
+// stdin is optional. An error is returned when the command exits with a non
+// zero exit code
+func (n *NomadImpl) Exec(allocID, task string, command []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	u, err := execURL(n.c.Location, allocID, task, command)
+	if err != nil {
+		return err
+	}
+
+	n.l.Debug("Executing command in allocation", "allocation", allocID, "task", task, "command", command)
+
+	conn, _, err := websocket.DefaultDialer.Dial(u, nil)
+	if err != nil {
+		return xerrors.Errorf("Unable to connect to exec API for allocation %s: %w", allocID, err)
+	}
+	defer conn.Close()
+
+	if stdin != nil {
+		go sendExecInput(conn, stdin)
+	} else {
+		conn.WriteJSON(execFrame{Stdin: &execStream{Close: true}})
+	}
+
+	for {
+		f := execFrame{}
+		err := conn.ReadJSON(&f)
+		if err != nil {
+			return xerrors.Errorf("Error reading output from allocation %s: %w", allocID, err)
+		}
+
+		if f.Stdout != nil && stdout != nil {
+			stdout.Write(f.Stdout.Data)
+		}
+
+		if f.Stderr != nil && stderr != nil {
+			stderr.Write(f.Stderr.Data)
+		}
+
+		if f.Exited {
+			if f.Result != nil && f.Result.ExitCode != 0 {
+				return xerrors.Errorf("Command exited with code %d", f.Result.ExitCode)
+			}
+
+			return nil
+		}
+	}
+}
+
+// sendExecInput copies the input to the exec API, closing stdin in the
+// task once the reader returns EOF
+func sendExecInput(conn *websocket.Conn, in io.Reader) {
+	buf := make([]byte, 4096)
+	for {
+		c, err := in.Read(buf)
+		if c > 0 {
+			if conn.WriteJSON(execFrame{Stdin: &execStream{Data: buf[:c]}}) != nil {
+				return
+			}
+		}
+
+		if err != nil {
+			conn.WriteJSON(execFrame{Stdin: &execStream{Close: true}})
+			return
+		}
+	}
+}
+
+// execURL returns the websocket URL for the exec API
+func execURL(location, allocID, task string, command []string) (string, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", xerrors.Errorf("Invalid Nomad address %s: %w", location, err)
+	}
+
+	u.Scheme = strings.Replace(u.Scheme, "http", "ws", 1)
+	u.Path = fmt.Sprintf("/v1/client/allocation/%s/exec", allocID)
+
+	cmd, _ := json.Marshal(command)
+
+	q := url.Values{}
+	q.Set("task", task)
+	q.Set("tty", "false")
+	q.Set("command", string(cmd))
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
@@ -0,0 +1,165 @@
+package clients
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupNomadAllocResponse(t *testing.T, status int, body string) (Nomad, *[]*http.Request, func()) {
+	fp, tmpDir, mh := setupNomadTests(t)
+
+	reqs := []*http.Request{}
+
+	removeOn(&mh.Mock, "Do")
+	mh.On("Do", mock.Anything).Run(func(args mock.Arguments) {
+		reqs = append(reqs, args.Get(0).(*http.Request))
+	}).Return(
+		&http.Response{
+			StatusCode: status,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		},
+		nil,
+	)
+
+	c := NewNomad(mh, 1*time.Millisecond, hclog.NewNullLogger())
+	c.SetConfig(fp)
+
+	return c, &reqs, func() {
+		os.RemoveAll(tmpDir)
+	}
+}
+
+func TestNomadJobAllocationsReturnsAllocations(t *testing.T) {
+	c, reqs, cleanup := setupNomadAllocResponse(t, http.StatusOK, `[{"ID": "abc", "TaskGroup": "web", "ClientStatus": "running", "TaskStates": {"server": {"State": "running", "Restarts": 1}}}]`)
+	defer cleanup()
+
+	a, err := c.JobAllocations("example")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "/v1/job/example/allocations", (*reqs)[0].URL.Path)
+	assert.Len(t, a, 1)
+	assert.Equal(t, "abc", a[0].ID)
+	assert.Equal(t, "web", a[0].TaskGroup)
+	assert.Equal(t, "running", a[0].ClientStatus)
+	assert.Equal(t, "running", a[0].TaskStates["server"].State)
+	assert.Equal(t, 1, a[0].TaskStates["server"].Restarts)
+}
+
+func TestNomadJobAllocationsNot200ReturnsError(t *testing.T) {
+	c, _, cleanup := setupNomadAllocResponse(t, http.StatusNotFound, "job not found")
+	defer cleanup()
+
+	_, err := c.JobAllocations("example")
+	assert.Error(t, err)
+}
+
+func TestNomadAllocationLogsWritesLogs(t *testing.T) {
+	c, reqs, cleanup := setupNomadAllocResponse(t, http.StatusOK, "hello world")
+	defer cleanup()
+
+	out := bytes.NewBufferString("")
+
+	err := c.AllocationLogs("abc", "web", true, false, out)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "hello world", out.String())
+
+	q := (*reqs)[0].URL.Query()
+	assert.Equal(t, "/v1/client/fs/logs/abc", (*reqs)[0].URL.Path)
+	assert.Equal(t, "web", q.Get("task"))
+	assert.Equal(t, "stderr", q.Get("type"))
+	assert.Equal(t, "false", q.Get("follow"))
+	assert.Equal(t, "true", q.Get("plain"))
+}
+
+func TestNomadAllocationLogsNot200ReturnsError(t *testing.T) {
+	c, _, cleanup := setupNomadAllocResponse(t, http.StatusInternalServerError, "boom")
+	defer cleanup()
+
+	err := c.AllocationLogs("abc", "web", false, false, bytes.NewBufferString(""))
+	assert.Error(t, err)
+}
+
+func setupNomadExecServer(t *testing.T, exitCode int) (Nomad, *http.Request, func()) {
+	req := &http.Request{}
+	upgrader := websocket.Upgrader{}
+
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		*req = *r
+
+		conn, err := upgrader.Upgrade(rw, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// echo stdin to stdout until stdin is closed
+		for {
+			f := execFrame{}
+			if conn.ReadJSON(&f) != nil {
+				return
+			}
+
+			if f.Stdin != nil && f.Stdin.Close {
+				break
+			}
+
+			if f.Stdin != nil {
+				conn.WriteJSON(execFrame{Stdout: &execStream{Data: f.Stdin.Data}})
+			}
+		}
+
+		conn.WriteJSON(execFrame{Stderr: &execStream{Data: []byte("done")}})
+		conn.WriteJSON(execFrame{Exited: true, Result: &execResult{ExitCode: exitCode}})
+	}))
+
+	fp, tmpDir, mh := setupNomadTests(t)
+	ioutil.WriteFile(fp, []byte(getNomadConfig(strings.TrimPrefix(s.URL, "http://"))), 0644)
+
+	c := NewNomad(mh, 1*time.Millisecond, hclog.NewNullLogger())
+	c.SetConfig(fp)
+
+	return c, req, func() {
+		s.Close()
+		os.RemoveAll(tmpDir)
+	}
+}
+
+func TestNomadExecStreamsInputAndOutput(t *testing.T) {
+	c, req, cleanup := setupNomadExecServer(t, 0)
+	defer cleanup()
+
+	stdout := bytes.NewBufferString("")
+	stderr := bytes.NewBufferString("")
+
+	err := c.Exec("abc", "web", []string{"cat"}, strings.NewReader("hello"), stdout, stderr)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "hello", stdout.String())
+	assert.Equal(t, "done", stderr.String())
+
+	assert.Equal(t, "/v1/client/allocation/abc/exec", req.URL.Path)
+	assert.Equal(t, "web", req.URL.Query().Get("task"))
+	assert.Equal(t, `["cat"]`, req.URL.Query().Get("command"))
+}
+
+func TestNomadExecNonZeroExitReturnsError(t *testing.T) {
+	c, _, cleanup := setupNomadExecServer(t, 2)
+	defer cleanup()
+
+	err := c.Exec("abc", "web", []string{"false"}, nil, nil, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("code %d", 2))
+}
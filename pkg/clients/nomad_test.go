@@ -2,6 +2,7 @@ package clients
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -262,6 +263,64 @@ func TestNomadJobStatusReturnsStatus(t *testing.T) {
 	assert.Equal(t, "running", s)
 }
 
+func TestNomadMissingJobsReturnsJobsNotRegistered(t *testing.T) {
+	fp, tmpDir, mh := setupNomadTests(t)
+	defer os.RemoveAll(tmpDir)
+
+	removeOn(&mh.Mock, "Do")
+	mh.On("Do", mock.Anything, mock.Anything, mock.Anything).Return(
+		&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(validateResponse))),
+		},
+		nil,
+	).Once()
+	mh.On("Do", mock.Anything, mock.Anything, mock.Anything).Return(
+		&http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte("job not found"))),
+		},
+		nil,
+	)
+
+	c := NewNomad(mh, 1*time.Millisecond, hclog.NewNullLogger())
+	c.SetConfig(fp)
+
+	m, err := c.MissingJobs([]string{"../../functional_tests/test_fixtures/nomad/app_config/example.nomad"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"my-job"}, m)
+}
+
+func TestNomadMissingJobsIgnoresRunningJobs(t *testing.T) {
+	fp, tmpDir, mh := setupNomadTests(t)
+	defer os.RemoveAll(tmpDir)
+
+	removeOn(&mh.Mock, "Do")
+	mh.On("Do", mock.Anything, mock.Anything, mock.Anything).Return(
+		&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(validateResponse))),
+		},
+		nil,
+	).Once()
+	mh.On("Do", mock.Anything, mock.Anything, mock.Anything).Return(
+		&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(allocationsResponse))),
+		},
+		nil,
+	)
+
+	c := NewNomad(mh, 1*time.Millisecond, hclog.NewNullLogger())
+	c.SetConfig(fp)
+
+	m, err := c.MissingJobs([]string{"../../functional_tests/test_fixtures/nomad/app_config/example.nomad"})
+	assert.NoError(t, err)
+
+	assert.Empty(t, m)
+}
+
 func TestNomadHealthCallsAPI(t *testing.T) {
 	fp, tmpDir, mh := setupNomadTests(t)
 	defer os.RemoveAll(tmpDir)
@@ -278,7 +337,7 @@ func TestNomadHealthCallsAPI(t *testing.T) {
 	c := NewNomad(mh, 1*time.Millisecond, hclog.NewNullLogger())
 	c.SetConfig(fp)
 
-	err := c.HealthCheckAPI(10 * time.Millisecond)
+	err := c.HealthCheckAPI(context.Background(), 10*time.Millisecond)
 	assert.NoError(t, err)
 }
 
@@ -307,7 +366,7 @@ func TestNomadHealthWithNotReadyNodeRetries(t *testing.T) {
 	c := NewNomad(mh, 1*time.Millisecond, hclog.NewNullLogger())
 	c.SetConfig(fp)
 
-	err := c.HealthCheckAPI(10 * time.Millisecond)
+	err := c.HealthCheckAPI(context.Background(), 10*time.Millisecond)
 	assert.NoError(t, err)
 	mh.AssertNumberOfCalls(t, "Do", 2)
 }
@@ -325,7 +384,7 @@ func TestNomadHealthErrorsOnClientError(t *testing.T) {
 	c := NewNomad(mh, 1*time.Millisecond, hclog.NewNullLogger())
 	c.SetConfig(fp)
 
-	err := c.HealthCheckAPI(10 * time.Millisecond)
+	err := c.HealthCheckAPI(context.Background(), 10*time.Millisecond)
 	assert.Error(t, err)
 }
 
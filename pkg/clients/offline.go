@@ -0,0 +1,17 @@
+package clients
+
+import "context"
+
+type offlineKey struct{}
+
+// WithOffline returns a copy of ctx where PullImage only uses images in the
+// local cache and returns an error for images which have not been pulled
+func WithOffline(ctx context.Context) context.Context {
+	return context.WithValue(ctx, offlineKey{}, true)
+}
+
+// offlineMode returns true when ctx was created with WithOffline
+func offlineMode(ctx context.Context) bool {
+	o, _ := ctx.Value(offlineKey{}).(bool)
+	return o
+}
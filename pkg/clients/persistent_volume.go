@@ -0,0 +1,22 @@
+package clients
+
+import "context"
+
+// persistentLabel is added to volumes which must survive garbage collection
+// even when no container uses them, e.g. the image cache
+const persistentLabel = "run.shipyard.persistent"
+
+type persistentVolumeKey struct{}
+
+// WithPersistentVolume returns a copy of ctx where the volumes created by
+// CreateVolume are labelled as persistent and are never removed by
+// PruneVolumes
+func WithPersistentVolume(ctx context.Context) context.Context {
+	return context.WithValue(ctx, persistentVolumeKey{}, true)
+}
+
+// persistentVolume returns true when ctx was created with WithPersistentVolume
+func persistentVolume(ctx context.Context) bool {
+	p, _ := ctx.Value(persistentVolumeKey{}).(bool)
+	return p
+}
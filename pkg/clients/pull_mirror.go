@@ -0,0 +1,17 @@
+package clients
+
+import "context"
+
+type pullMirrorKey struct{}
+
+// WithPullMirror returns a copy of ctx where PullImage pulls images from the
+// Docker Hub through the registry at host
+func WithPullMirror(ctx context.Context, host string) context.Context {
+	return context.WithValue(ctx, pullMirrorKey{}, host)
+}
+
+// pullMirror returns the registry set on ctx with WithPullMirror
+func pullMirror(ctx context.Context) string {
+	m, _ := ctx.Value(pullMirrorKey{}).(string)
+	return m
+}
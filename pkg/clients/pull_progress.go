@@ -0,0 +1,66 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// PullProgressFunc is called as the layers of an image are downloaded with
+// the percentage of the image which has been pulled
+type PullProgressFunc func(percent float64)
+
+type pullProgressKey struct{}
+
+// WithPullProgress returns a copy of ctx where PullImage reports the
+// progress of the pull to f
+func WithPullProgress(ctx context.Context, f PullProgressFunc) context.Context {
+	return context.WithValue(ctx, pullProgressKey{}, f)
+}
+
+// readPullProgress reads the messages returned by the Docker engine while
+// an image is pulled, the progress of the layers is reported to the
+// PullProgressFunc set on ctx
+func readPullProgress(ctx context.Context, out io.Reader) {
+	f, ok := ctx.Value(pullProgressKey{}).(PullProgressFunc)
+	if !ok {
+		io.Copy(ioutil.Discard, out)
+		return
+	}
+
+	current := map[string]int64{}
+	total := map[string]int64{}
+
+	dec := json.NewDecoder(out)
+	for {
+		m := jsonmessage.JSONMessage{}
+		if err := dec.Decode(&m); err != nil {
+			// drain the output so the pull completes
+			io.Copy(ioutil.Discard, out)
+			return
+		}
+
+		// only the download of a layer is reported, extracting the layer
+		// reports its own progress which would reset the layer
+		switch {
+		case m.Status == "Downloading" && m.Progress != nil && m.Progress.Total > 0:
+			current[m.ID] = m.Progress.Current
+			total[m.ID] = m.Progress.Total
+		case m.Status == "Download complete" && total[m.ID] > 0:
+			current[m.ID] = total[m.ID]
+		default:
+			continue
+		}
+
+		var c, t int64
+		for id := range total {
+			c += current[id]
+			t += total[id]
+		}
+
+		f(float64(c) / float64(t) * 100)
+	}
+}
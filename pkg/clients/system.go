@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
 )
 
 const (
@@ -21,12 +22,18 @@ const (
 // System handles interactions between Shipyard and the OS
 type System interface {
 	OpenBrowser(string) error
+	// Notify shows a desktop notification with the given title and message
+	Notify(title, message string) error
 	Preflight() (string, error)
 	CheckVersion(string) (string, bool)
 }
 
 // SystemImpl is a concrete implementation of the System interface
-type SystemImpl struct{}
+type SystemImpl struct {
+	// Docker is the connection settings for the Docker engine which is
+	// checked by Preflight
+	Docker DockerConfig
+}
 
 // OpenBrowser opens a URI in a new browser window
 func (b *SystemImpl) OpenBrowser(uri string) error {
@@ -49,6 +56,23 @@ func (b *SystemImpl) OpenBrowser(uri string) error {
 	return cmd.Run()
 }
 
+// Notify shows a desktop notification using notify-send on Linux and
+// osascript on macOS
+func (b *SystemImpl) Notify(title, message string) error {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "linux":
+		cmd = exec.Command("notify-send", title, message)
+	case "darwin":
+		cmd = exec.Command("osascript", "-e", fmt.Sprintf("display notification %q with title %q", message, title))
+	default:
+		return fmt.Errorf("Desktop notifications are not supported on %s", runtime.GOOS)
+	}
+
+	return cmd.Run()
+}
+
 // Preflight checks that the required software is installed and is
 // working correctly
 func (b *SystemImpl) Preflight() (string, error) {
@@ -59,9 +83,9 @@ func (b *SystemImpl) Preflight() (string, error) {
 
 	// check docker
 
-	if checkDocker() != nil {
+	if err := checkDocker(b.Docker); err != nil {
 		output += fmt.Sprintf(" [ %s ] Docker\n", fmt.Sprintf(Red, " ERROR "))
-		errors += "* Unable to connect to Docker, ensure Docker is installed and running.\n"
+		errors += fmt.Sprintf("* Unable to connect to Docker, ensure Docker is installed and running.\n  %s\n", err)
 		dockerPass = false
 	} else {
 		output += fmt.Sprintf(" [ %s ] Docker\n", fmt.Sprintf(Green, "  OK   "))
@@ -136,15 +160,15 @@ see the documentation at:
 https://shipyard.run/docs/install for other options.
 `
 
-func checkDocker() error {
-	d, err := NewDocker()
+func checkDocker(c DockerConfig) error {
+	d, err := NewDocker(c)
 	if err != nil {
 		return err
 	}
 
 	_, err = d.ContainerList(context.Background(), types.ContainerListOptions{All: true})
 	if err != nil {
-		return err
+		return dockerConnectionError(d.(*client.Client).DaemonHost(), err)
 	}
 
 	return nil
@@ -0,0 +1,225 @@
+package clients
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// tunnelStartTimeout is the maximum time to wait for a tunnel to report its public URL
+var tunnelStartTimeout = 30 * time.Second
+
+// Tunnel exposes local ports to remote users through an outbound tunnel
+type Tunnel interface {
+	// Open exposes the local port using the named provider and returns the
+	// public URL, the tunnel remains open until the stop channel is closed
+	Open(provider string, localPort int, stop <-chan struct{}) (string, error)
+	// Providers returns the names of the available tunnel providers
+	Providers() []string
+}
+
+// TunnelProvider defines an external command which opens a tunnel, the URL
+// of the tunnel is read from the output of the command
+type TunnelProvider struct {
+	Command   string
+	Arguments func(localPort int) []string
+	URL       *regexp.Regexp // matches the public URL in the output of the command
+}
+
+// DefaultTunnelProvider uses SSH remote forwarding to a relay server, this
+// only requires an SSH client on the local machine
+const DefaultTunnelProvider = "ssh"
+
+// TunnelImpl opens tunnels by running the command for a provider
+type TunnelImpl struct {
+	providers map[string]TunnelProvider
+	l         hclog.Logger
+}
+
+// NewTunnel creates a Tunnel client with the built in providers:
+//   ssh         - remote forwarding to the relay set by SHIPYARD_TUNNEL_RELAY, default localhost.run,
+//                 as the user set by SHIPYARD_TUNNEL_RELAY_USER, default nokey
+//   cloudflared - Cloudflare quick tunnels
+//   ngrok       - ngrok HTTP tunnels, requires a configured auth token
+func NewTunnel(l hclog.Logger) *TunnelImpl {
+	relay := os.Getenv("SHIPYARD_TUNNEL_RELAY")
+	if relay == "" {
+		relay = "localhost.run"
+	}
+
+	user := os.Getenv("SHIPYARD_TUNNEL_RELAY_USER")
+	if user == "" {
+		user = "nokey"
+	}
+
+	// the pinned host key is written once, ssh would otherwise fail later
+	// with an error which does not explain why the relay is not trusted
+	hostKeyOptions, err := relayHostKeyOptions(relay, os.Getenv("SHIPYARD_TUNNEL_RELAY_HOST_KEY"))
+	if err != nil {
+		l.Error("Unable to write the pinned host key for the tunnel relay, ssh tunnels will fail", "relay", relay, "error", err)
+	}
+
+	return &TunnelImpl{
+		l: l,
+		providers: map[string]TunnelProvider{
+			"ssh": TunnelProvider{
+				Command: "ssh",
+				Arguments: func(p int) []string {
+					return append(
+						append([]string{}, hostKeyOptions...),
+						"-o", "ServerAliveInterval=30",
+						"-R", fmt.Sprintf("80:localhost:%d", p),
+						fmt.Sprintf("%s@%s", user, relay),
+					)
+				},
+				// relays print a banner containing links, the tunnel URL is the only one at the end of a line
+				URL: regexp.MustCompile(`https://[a-zA-Z0-9.-]+$`),
+			},
+			"cloudflared": TunnelProvider{
+				Command: "cloudflared",
+				Arguments: func(p int) []string {
+					return []string{"tunnel", "--no-autoupdate", "--url", fmt.Sprintf("http://localhost:%d", p)}
+				},
+				URL: regexp.MustCompile(`https://[a-zA-Z0-9-]+\.trycloudflare\.com`),
+			},
+			"ngrok": TunnelProvider{
+				Command: "ngrok",
+				Arguments: func(p int) []string {
+					return []string{"http", fmt.Sprintf("%d", p), "--log", "stdout"}
+				},
+				URL: regexp.MustCompile(`https://[a-zA-Z0-9.-]+\.ngrok[a-zA-Z0-9.-]*`),
+			},
+		},
+	}
+}
+
+// relayHostKeyOptions returns the ssh options which verify the host key of the
+// relay. When a key is set by SHIPYARD_TUNNEL_RELAY_HOST_KEY only that key is
+// trusted and is written to the known hosts file, otherwise the key presented
+// on the first connection is recorded and every later connection must present
+// the same key
+func relayHostKeyOptions(relay, key string) ([]string, error) {
+	knownHosts := filepath.Join(utils.ShipyardHome(), "tunnel_known_hosts")
+	check := "accept-new"
+
+	var err error
+	if key != "" {
+		knownHosts = filepath.Join(utils.ShipyardHome(), "tunnel_pinned_hosts")
+		check = "yes"
+
+		err = os.MkdirAll(utils.ShipyardHome(), os.ModePerm)
+		if err == nil {
+			err = ioutil.WriteFile(knownHosts, []byte(fmt.Sprintf("%s %s\n", relay, key)), 0600)
+		}
+
+		if err != nil {
+			err = xerrors.Errorf("Unable to write known hosts file %s: %w", knownHosts, err)
+		}
+	}
+
+	return []string{
+		"-o", "StrictHostKeyChecking=" + check,
+		"-o", "UserKnownHostsFile=" + knownHosts,
+	}, err
+}
+
+// AddProvider registers a provider or replaces an existing provider with the same name
+func (t *TunnelImpl) AddProvider(name string, p TunnelProvider) {
+	t.providers[name] = p
+}
+
+// Providers returns the names of the available tunnel providers
+func (t *TunnelImpl) Providers() []string {
+	n := []string{}
+	for k := range t.providers {
+		n = append(n, k)
+	}
+
+	sort.Strings(n)
+
+	return n
+}
+
+// Open starts the command for the provider and waits until it reports the
+// public URL for the tunnel, the command is stopped when the stop channel
+// is closed
+func (t *TunnelImpl) Open(provider string, localPort int, stop <-chan struct{}) (string, error) {
+	p, ok := t.providers[provider]
+	if !ok {
+		return "", xerrors.Errorf("Unknown tunnel provider %s, valid providers are %s", provider, strings.Join(t.Providers(), ", "))
+	}
+
+	_, err := exec.LookPath(p.Command)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to find %s, please ensure it is installed and in your path: %w", p.Command, err)
+	}
+
+	pr, pw := io.Pipe()
+
+	cmd := exec.Command(p.Command, p.Arguments(localPort)...)
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+
+	t.l.Debug("Opening tunnel", "provider", provider, "port", localPort, "command", cmd.Args)
+
+	err = cmd.Start()
+	if err != nil {
+		return "", xerrors.Errorf("Unable to start tunnel: %w", err)
+	}
+
+	urls := make(chan string, 1)
+	exited := make(chan error, 1)
+
+	// read the output until the URL is found, the remaining output is logged
+	go func() {
+		found := false
+		s := bufio.NewScanner(pr)
+		for s.Scan() {
+			t.l.Debug("Tunnel output", "provider", provider, "port", localPort, "output", s.Text())
+
+			if u := p.URL.FindString(strings.TrimSpace(s.Text())); u != "" && !found {
+				found = true
+				urls <- u
+			}
+		}
+	}()
+
+	go func() {
+		exited <- cmd.Wait()
+		pw.Close()
+	}()
+
+	select {
+	case u := <-urls:
+		go func() {
+			select {
+			case <-stop:
+				cmd.Process.Kill()
+			case err := <-exited:
+				t.l.Error("Tunnel closed", "provider", provider, "port", localPort, "error", err)
+			}
+		}()
+
+		return u, nil
+	case err := <-exited:
+		return "", xerrors.Errorf("Tunnel exited before it was ready: %v", err)
+	case <-time.After(tunnelStartTimeout):
+		cmd.Process.Kill()
+		return "", xerrors.Errorf("Timeout waiting for tunnel to start")
+	case <-stop:
+		cmd.Process.Kill()
+		return "", xerrors.Errorf("Tunnel was closed before it was ready")
+	}
+}
@@ -0,0 +1,138 @@
+package clients
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTunnel(script string) *TunnelImpl {
+	t := NewTunnel(hclog.NewNullLogger())
+	t.AddProvider("test", TunnelProvider{
+		Command:   "sh",
+		Arguments: func(p int) []string { return []string{"-c", script} },
+		URL:       regexp.MustCompile(`https://[a-z]+\.example\.com`),
+	})
+
+	return t
+}
+
+func TestTunnelOpenReturnsURL(t *testing.T) {
+	tu := setupTunnel("echo starting; echo 'your url is https://abc.example.com'; sleep 10")
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	u, err := tu.Open("test", 18500, stop)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://abc.example.com", u)
+}
+
+func TestTunnelOpenReturnsErrorWhenCommandExits(t *testing.T) {
+	tu := setupTunnel("echo failed; exit 1")
+
+	_, err := tu.Open("test", 18500, make(chan struct{}))
+	assert.Error(t, err)
+}
+
+func TestTunnelOpenReturnsErrorOnTimeout(t *testing.T) {
+	tunnelStartTimeout = 10 * time.Millisecond
+	defer func() { tunnelStartTimeout = 30 * time.Second }()
+
+	tu := setupTunnel("sleep 10")
+
+	_, err := tu.Open("test", 18500, make(chan struct{}))
+	assert.Error(t, err)
+}
+
+func TestTunnelOpenReturnsErrorForUnknownProvider(t *testing.T) {
+	tu := setupTunnel("")
+
+	_, err := tu.Open("nope", 18500, make(chan struct{}))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cloudflared, ngrok, ssh, test")
+}
+
+func TestTunnelSSHProviderIgnoresBannerLinks(t *testing.T) {
+	p := NewTunnel(hclog.NewNullLogger()).providers["ssh"]
+
+	assert.Empty(t, p.URL.FindString("docs are at https://localhost.run/docs/forever-free/"))
+	assert.Equal(t, "https://abc.lhr.life", p.URL.FindString("abc.lhr.life tunneled with tls termination, https://abc.lhr.life"))
+}
+
+func setupTunnelHome(t *testing.T) func() {
+	home := os.Getenv("HOME")
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	os.Setenv("HOME", dir)
+
+	return func() {
+		os.Setenv("HOME", home)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestTunnelSSHProviderRecordsRelayHostKey(t *testing.T) {
+	cleanup := setupTunnelHome(t)
+	defer cleanup()
+
+	args := NewTunnel(hclog.NewNullLogger()).providers["ssh"].Arguments(18500)
+
+	assert.Contains(t, args, "StrictHostKeyChecking=accept-new")
+	assert.Contains(t, args, "UserKnownHostsFile="+filepath.Join(utils.ShipyardHome(), "tunnel_known_hosts"))
+	assert.NotContains(t, args, "StrictHostKeyChecking=no")
+	assert.Equal(t, "nokey@localhost.run", args[len(args)-1])
+}
+
+func TestTunnelSSHProviderPinsRelayHostKey(t *testing.T) {
+	cleanup := setupTunnelHome(t)
+	defer cleanup()
+
+	os.Setenv("SHIPYARD_TUNNEL_RELAY_HOST_KEY", "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIK")
+	defer os.Unsetenv("SHIPYARD_TUNNEL_RELAY_HOST_KEY")
+
+	tn := NewTunnel(hclog.NewNullLogger())
+
+	// the key is written when the client is created
+	knownHosts := filepath.Join(utils.ShipyardHome(), "tunnel_pinned_hosts")
+	d, err := ioutil.ReadFile(knownHosts)
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost.run ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIK\n", string(d))
+
+	args := tn.providers["ssh"].Arguments(18500)
+	assert.Contains(t, args, "StrictHostKeyChecking=yes")
+	assert.Contains(t, args, "UserKnownHostsFile="+knownHosts)
+}
+
+func TestTunnelRelayHostKeyReturnsErrorWhenUnableToWriteKey(t *testing.T) {
+	cleanup := setupTunnelHome(t)
+	defer cleanup()
+
+	// the shipyard home can not be created as a file exists at the path
+	ioutil.WriteFile(utils.ShipyardHome(), []byte(""), 0644)
+
+	_, err := relayHostKeyOptions("localhost.run", "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIK")
+	assert.Error(t, err)
+}
+
+func TestTunnelSSHProviderUsesRelayUser(t *testing.T) {
+	cleanup := setupTunnelHome(t)
+	defer cleanup()
+
+	os.Setenv("SHIPYARD_TUNNEL_RELAY", "relay.corp.com")
+	defer os.Unsetenv("SHIPYARD_TUNNEL_RELAY")
+	os.Setenv("SHIPYARD_TUNNEL_RELAY_USER", "shipyard")
+	defer os.Unsetenv("SHIPYARD_TUNNEL_RELAY_USER")
+
+	args := NewTunnel(hclog.NewNullLogger()).providers["ssh"].Arguments(18500)
+
+	assert.Equal(t, "shipyard@relay.corp.com", args[len(args)-1])
+}
@@ -0,0 +1,205 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"golang.org/x/xerrors"
+)
+
+// Vault defines an interface for a Vault client
+type Vault interface {
+	// SetConfig sets the address of the Vault server and the token used
+	// to authenticate requests, when the token is empty the VAULT_TOKEN
+	// environment variable is used
+	SetConfig(address, token string)
+	// Status returns the initialization and seal status of the server
+	Status() (initialized bool, sealed bool, err error)
+	// HealthCheckAPI blocks until the server is initialized and unsealed
+	// or the timeout elapses
+	HealthCheckAPI(ctx context.Context, timeout time.Duration) error
+	// LookupToken returns the properties of the configured token, an
+	// error is returned if the token is not valid
+	LookupToken() (map[string]interface{}, error)
+	// WriteKV writes the data to the path in the KV secrets engine mounted
+	// at mount, both version 1 and version 2 engines are supported
+	WriteKV(mount, path string, data map[string]interface{}) error
+}
+
+// VaultImpl is an implementation of the Vault interface
+type VaultImpl struct {
+	httpClient HTTP
+	backoff    time.Duration
+	l          hclog.Logger
+	address    string
+	token      string
+}
+
+// NewVault creates a new Vault client
+func NewVault(c HTTP, backoff time.Duration, l hclog.Logger) Vault {
+	return &VaultImpl{httpClient: c, backoff: backoff, l: l}
+}
+
+// SetConfig for the client
+func (v *VaultImpl) SetConfig(address, token string) {
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+
+	v.address = strings.TrimSuffix(address, "/")
+	v.token = token
+}
+
+// Status returns the initialization and seal status of the server
+func (v *VaultImpl) Status() (bool, bool, error) {
+	status := struct {
+		Initialized bool `json:"initialized"`
+		Sealed      bool `json:"sealed"`
+	}{}
+
+	err := v.do(http.MethodGet, "sys/seal-status", nil, &status)
+	if err != nil {
+		return false, false, xerrors.Errorf("Unable to get seal status: %w", err)
+	}
+
+	return status.Initialized, status.Sealed, nil
+}
+
+// HealthCheckAPI executes a health check for the Vault server
+func (v *VaultImpl) HealthCheckAPI(ctx context.Context, timeout time.Duration) error {
+	v.l.Debug("Performing Vault health check for address", "address", v.address)
+
+	st := time.Now()
+	for {
+		if time.Now().Sub(st) > timeout {
+			v.l.Error("Timeout wating for Vault healthcheck", "address", v.address)
+
+			return fmt.Errorf("Timeout waiting for Vault healthcheck %s", v.address)
+		}
+
+		i, s, err := v.Status()
+		if err == nil && i && !s {
+			v.l.Debug("Vault check complete", "address", v.address)
+			return nil
+		}
+
+		v.l.Debug("Vault not ready", "address", v.address, "initialized", i, "sealed", s, "error", err)
+
+		// backoff
+		if err := wait(ctx, v.backoff); err != nil {
+			return xerrors.Errorf("Vault health check cancelled: %w", err)
+		}
+	}
+}
+
+// LookupToken returns the properties of the configured token
+func (v *VaultImpl) LookupToken() (map[string]interface{}, error) {
+	resp := struct {
+		Data map[string]interface{} `json:"data"`
+	}{}
+
+	err := v.do(http.MethodGet, "auth/token/lookup-self", nil, &resp)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to lookup token: %w", err)
+	}
+
+	return resp.Data, nil
+}
+
+// WriteKV writes the data to the KV secrets engine
+func (v *VaultImpl) WriteKV(mount, path string, data map[string]interface{}) error {
+	mount = strings.Trim(mount, "/")
+	path = strings.Trim(path, "/")
+
+	version, err := v.kvVersion(mount)
+	if err != nil {
+		return err
+	}
+
+	v.l.Debug("Writing secret to Vault", "mount", mount, "path", path, "version", version)
+
+	// version 2 engines nest the secret under the data path and key
+	var body interface{} = data
+	p := fmt.Sprintf("%s/%s", mount, path)
+	if version == "2" {
+		body = map[string]interface{}{"data": data}
+		p = fmt.Sprintf("%s/data/%s", mount, path)
+	}
+
+	err = v.do(http.MethodPost, p, body, nil)
+	if err != nil {
+		return xerrors.Errorf("Unable to write secret %s: %w", p, err)
+	}
+
+	return nil
+}
+
+// kvVersion returns the version of the KV secrets engine mounted at the
+// given path, engines which do not report a version are version 1
+func (v *VaultImpl) kvVersion(mount string) (string, error) {
+	resp := struct {
+		Data struct {
+			Options map[string]string `json:"options"`
+		} `json:"data"`
+	}{}
+
+	err := v.do(http.MethodGet, fmt.Sprintf("sys/internal/ui/mounts/%s", mount), nil, &resp)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to get details for mount %s: %w", mount, err)
+	}
+
+	if ver := resp.Data.Options["version"]; ver != "" {
+		return ver, nil
+	}
+
+	return "1", nil
+}
+
+// do makes a request to the Vault API decoding the JSON response into out
+// when it is not nil
+func (v *VaultImpl) do(method, path string, body interface{}, out interface{}) error {
+	var rb io.Reader
+	if body != nil {
+		d, err := json.Marshal(body)
+		if err != nil {
+			return xerrors.Errorf("Unable to encode request: %w", err)
+		}
+
+		rb = bytes.NewReader(d)
+	}
+
+	r, err := http.NewRequest(method, fmt.Sprintf("%s/v1/%s", v.address, path), rb)
+	if err != nil {
+		return xerrors.Errorf("Unable to create http request: %w", err)
+	}
+
+	if v.token != "" {
+		r.Header.Set("X-Vault-Token", v.token)
+	}
+
+	resp, err := v.httpClient.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		d, _ := ioutil.ReadAll(resp.Body)
+		return xerrors.Errorf("Got status code %d, error: %s", resp.StatusCode, string(d))
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
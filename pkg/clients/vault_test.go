@@ -0,0 +1,148 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupVaultTests(responses ...string) (Vault, *mocks.MockHTTP) {
+	mh := &mocks.MockHTTP{}
+	for _, r := range responses {
+		mh.On("Do", mock.Anything).Once().Return(
+			&http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte(r))),
+			},
+			nil,
+		)
+	}
+
+	v := NewVault(mh, 1*time.Millisecond, hclog.NewNullLogger())
+	v.SetConfig("http://localhost:8200/", "root")
+
+	return v, mh
+}
+
+func getVaultRequest(mh *mocks.MockHTTP, i int) *http.Request {
+	return getCalls(&mh.Mock, "Do")[i].Arguments[0].(*http.Request)
+}
+
+func TestVaultSetConfigUsesEnvironmentToken(t *testing.T) {
+	os.Setenv("VAULT_TOKEN", "env")
+	defer os.Unsetenv("VAULT_TOKEN")
+
+	v, mh := setupVaultTests(`{"initialized": true, "sealed": false}`)
+	v.SetConfig("http://localhost:8200", "")
+
+	_, _, err := v.Status()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "env", getVaultRequest(mh, 0).Header.Get("X-Vault-Token"))
+}
+
+func TestVaultStatusReturnsSealStatus(t *testing.T) {
+	v, mh := setupVaultTests(`{"initialized": true, "sealed": true}`)
+
+	i, s, err := v.Status()
+	assert.NoError(t, err)
+	assert.True(t, i)
+	assert.True(t, s)
+
+	r := getVaultRequest(mh, 0)
+	assert.Equal(t, "http://localhost:8200/v1/sys/seal-status", r.URL.String())
+	assert.Equal(t, "root", r.Header.Get("X-Vault-Token"))
+}
+
+func TestVaultStatusReturnsErrorOnFail(t *testing.T) {
+	v, mh := setupVaultTests()
+	mh.On("Do", mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	_, _, err := v.Status()
+	assert.Error(t, err)
+}
+
+func TestVaultHealthCheckWaitsForUnseal(t *testing.T) {
+	v, mh := setupVaultTests(
+		`{"initialized": true, "sealed": true}`,
+		`{"initialized": true, "sealed": false}`,
+	)
+
+	err := v.HealthCheckAPI(context.Background(), 1*time.Second)
+	assert.NoError(t, err)
+	mh.AssertNumberOfCalls(t, "Do", 2)
+}
+
+func TestVaultHealthCheckTimesOut(t *testing.T) {
+	v, mh := setupVaultTests()
+	mh.On("Do", mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	err := v.HealthCheckAPI(context.Background(), 10*time.Millisecond)
+	assert.Error(t, err)
+}
+
+func TestVaultLookupTokenReturnsData(t *testing.T) {
+	v, mh := setupVaultTests(`{"data": {"id": "root", "policies": ["root"]}}`)
+
+	d, err := v.LookupToken()
+	assert.NoError(t, err)
+	assert.Equal(t, "root", d["id"])
+
+	assert.Equal(t, "http://localhost:8200/v1/auth/token/lookup-self", getVaultRequest(mh, 0).URL.String())
+}
+
+func TestVaultLookupTokenReturnsErrorWhenInvalid(t *testing.T) {
+	v, mh := setupVaultTests()
+	mh.On("Do", mock.Anything).Return(
+		&http.Response{
+			StatusCode: http.StatusForbidden,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"errors": ["permission denied"]}`))),
+		},
+		nil,
+	)
+
+	_, err := v.LookupToken()
+	assert.Error(t, err)
+}
+
+func TestVaultWriteKVV2NestsData(t *testing.T) {
+	v, mh := setupVaultTests(`{"data": {"type": "kv", "options": {"version": "2"}}}`, `{}`)
+
+	err := v.WriteKV("secret", "/app/db", map[string]interface{}{"password": "abc"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "http://localhost:8200/v1/sys/internal/ui/mounts/secret", getVaultRequest(mh, 0).URL.String())
+
+	r := getVaultRequest(mh, 1)
+	assert.Equal(t, http.MethodPost, r.Method)
+	assert.Equal(t, "http://localhost:8200/v1/secret/data/app/db", r.URL.String())
+
+	body := map[string]interface{}{}
+	json.NewDecoder(r.Body).Decode(&body)
+	assert.Equal(t, map[string]interface{}{"data": map[string]interface{}{"password": "abc"}}, body)
+}
+
+func TestVaultWriteKVV1WritesData(t *testing.T) {
+	v, mh := setupVaultTests(`{"data": {"type": "kv", "options": null}}`, `{}`)
+
+	err := v.WriteKV("kv", "app", map[string]interface{}{"password": "abc"})
+	assert.NoError(t, err)
+
+	r := getVaultRequest(mh, 1)
+	assert.Equal(t, "http://localhost:8200/v1/kv/app", r.URL.String())
+
+	body := map[string]interface{}{}
+	json.NewDecoder(r.Body).Decode(&body)
+	assert.Equal(t, map[string]interface{}{"password": "abc"}, body)
+}
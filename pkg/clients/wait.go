@@ -0,0 +1,20 @@
+package clients
+
+import (
+	"context"
+	"time"
+)
+
+// wait blocks for the duration d, returning early with the context error
+// when ctx is cancelled
+func wait(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
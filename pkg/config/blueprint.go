@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"net/url"
+	"strings"
 )
 
 // Blueprint defines a stack blueprint for defining yard configs
@@ -13,6 +14,107 @@ type Blueprint struct {
 	Intro          string   `hcl:"intro,optional" json:"intro,omitempty"`
 	BrowserWindows []string `hcl:"browser_windows,optional" json:"browser_windows,omitempty" mapstructure:"browser_windows"`
 	Environment    []KV     `hcl:"env,block" json:"environment,omitempty"`
+
+	// RequiredVersion is a version constraint for the Shipyard binary which
+	// can run the blueprint, e.g. ">= 0.1.0, < 0.2.0"
+	RequiredVersion string `hcl:"required_version,optional" json:"required_version,omitempty" mapstructure:"required_version"`
+
+	Assertions []Assertion `hcl:"assert,block" json:"assertions,omitempty"`
+	Demos      []Demo      `hcl:"demo,block" json:"demos,omitempty"`
+
+	// Scan checks the images used by the blueprint for vulnerabilities
+	// before any resources are created
+	Scan *ImageScan `hcl:"scan,block" json:"scan,omitempty"`
+
+	// StateBackend stores the state remotely so that it can be shared
+	StateBackend *StateBackend `hcl:"state_backend,block" json:"state_backend,omitempty" mapstructure:"state_backend"`
+}
+
+// StateBackend stores the state of the blueprint remotely, the local state
+// is downloaded before and uploaded after each command so that CI runners
+// and teammates can share the same environment. The state is locked in the
+// backend while it is changed. The token is not stored in the state, when it
+// is not set CONSUL_HTTP_TOKEN or SHIPYARD_STATE_TOKEN are used
+// example config:
+//    state_backend {
+//      type    = "s3"                     // s3, consul, or http
+//      address = "http://localhost:8500"  // URL of Consul, the HTTP endpoint, or a custom S3 endpoint
+//      bucket  = "shipyard"               // S3 bucket
+//      key     = "dev/state.json"         // S3 object key or Consul KV path
+//      region  = "eu-west-1"              // S3 region
+//      token   = "abc123"                 // Consul ACL token or HTTP bearer token, not stored in the state
+//    }
+type StateBackend struct {
+	Type    string `hcl:"type" json:"type"`
+	Address string `hcl:"address,optional" json:"address,omitempty"`
+	Bucket  string `hcl:"bucket,optional" json:"bucket,omitempty"`
+	Key     string `hcl:"key,optional" json:"key,omitempty"`
+	Region  string `hcl:"region,optional" json:"region,omitempty"`
+	Token   string `hcl:"token,optional" json:"-"`
+}
+
+// ImageScan configures the vulnerability scanning of images using an
+// external scanner which must be installed on the local machine
+// example config:
+//    scan {
+//      scanner  = "trivy"                 // trivy or grype, default trivy
+//      command  = "/usr/local/bin/trivy"  // path to the scanner, default the scanner name
+//      severity = "HIGH"                  // minimum severity reported, default HIGH
+//      action   = "block"                 // warn or block, default warn
+//      ignore   = ["CVE-2020-1234"]       // vulnerabilities which are not reported
+//    }
+type ImageScan struct {
+	Scanner  string   `hcl:"scanner,optional" json:"scanner,omitempty"`
+	Command  string   `hcl:"command,optional" json:"command,omitempty"`
+	Severity string   `hcl:"severity,optional" json:"severity,omitempty"`
+	Action   string   `hcl:"action,optional" json:"action,omitempty"`
+	Ignore   []string `hcl:"ignore,optional" json:"ignore,omitempty"`
+}
+
+// ScanSeverities are the severities reported by scanners from lowest to highest
+var ScanSeverities = []string{"UNKNOWN", "NEGLIGIBLE", "LOW", "MEDIUM", "HIGH", "CRITICAL"}
+
+// Assertion is a check which is run against the resources in a blueprint
+// when the blueprint is tested to ensure it works as expected, each assertion
+// defines one or more of http, exec, or pods
+// example config:
+//    assert "consul_leader" {
+//      timeout = "30s"                              // time to wait for the assertion to pass, default 30s
+//      http    = "http://localhost:18500/v1/leader" // does the endpoint return a success code
+//
+//      exec {
+//        target    = "container.consul"             // container to run the command in
+//        command   = ["consul", "members"]
+//        exit_code = 0                              // expected exit code, default 0
+//      }
+//
+//      pods {
+//        cluster   = "k8s_cluster.k3s"
+//        selectors = ["app=consul"]                 // are the pods running and healthy
+//      }
+//    }
+type Assertion struct {
+	Name    string `hcl:"name,label" json:"name"`
+	Timeout string `hcl:"timeout,optional" json:"timeout,omitempty"`
+	HTTP    string `hcl:"http,optional" json:"http,omitempty"`
+
+	HTTPOptions *HTTPHealthCheck `hcl:"http_options,block" json:"http_options,omitempty" mapstructure:"http_options"`
+	Exec        *ExecAssertion   `hcl:"exec,block" json:"exec,omitempty"`
+	Pods        *PodsAssertion   `hcl:"pods,block" json:"pods,omitempty"`
+}
+
+// ExecAssertion runs a command in a container and checks the exit code
+type ExecAssertion struct {
+	Target   string   `hcl:"target" json:"target"`
+	Command  []string `hcl:"command" json:"command"`
+	ExitCode int      `hcl:"exit_code,optional" json:"exit_code,omitempty" mapstructure:"exit_code"`
+}
+
+// PodsAssertion checks that the pods matching the selectors are running
+// in a Kubernetes cluster
+type PodsAssertion struct {
+	Cluster   string   `hcl:"cluster" json:"cluster"`
+	Selectors []string `hcl:"selectors" json:"selectors"`
 }
 
 // Validate the Blueprint and return errors
@@ -36,5 +138,81 @@ func (b *Blueprint) Validate() []error {
 		}
 	}
 
+	for _, a := range b.Assertions {
+		if a.HTTP == "" && a.Exec == nil && a.Pods == nil {
+			errors = append(
+				errors,
+				fmt.Errorf("assertion %s must define at least one of http, exec, or pods", a.Name),
+			)
+		}
+	}
+
+	for _, d := range b.Demos {
+		errors = append(errors, d.validate()...)
+	}
+
+	if b.Scan != nil {
+		errors = append(errors, b.Scan.validate()...)
+	}
+
+	if b.StateBackend != nil {
+		errors = append(errors, b.StateBackend.Validate()...)
+	}
+
+	return errors
+}
+
+func (s *ImageScan) validate() []error {
+	errors := make([]error, 0)
+
+	if s.Scanner != "" && s.Scanner != "trivy" && s.Scanner != "grype" {
+		errors = append(errors, fmt.Errorf("scan scanner %s must be one of trivy or grype", s.Scanner))
+	}
+
+	if s.Action != "" && s.Action != "warn" && s.Action != "block" {
+		errors = append(errors, fmt.Errorf("scan action %s must be one of warn or block", s.Action))
+	}
+
+	if s.Severity != "" && SeverityLevel(s.Severity) < 0 {
+		errors = append(errors, fmt.Errorf("scan severity %s must be one of %s", s.Severity, strings.Join(ScanSeverities, ", ")))
+	}
+
+	return errors
+}
+
+// Validate returns an error for each setting the backend type requires which
+// is not set
+func (s *StateBackend) Validate() []error {
+	errors := make([]error, 0)
+
+	switch s.Type {
+	case "s3":
+		if s.Bucket == "" || s.Key == "" {
+			errors = append(errors, fmt.Errorf("state_backend s3 must define bucket and key"))
+		}
+	case "consul":
+		if s.Key == "" {
+			errors = append(errors, fmt.Errorf("state_backend consul must define key"))
+		}
+	case "http":
+		if s.Address == "" {
+			errors = append(errors, fmt.Errorf("state_backend http must define address"))
+		}
+	default:
+		errors = append(errors, fmt.Errorf("state_backend type %s must be one of s3, consul, or http", s.Type))
+	}
+
 	return errors
 }
+
+// SeverityLevel returns the position of the severity in ScanSeverities,
+// -1 is returned for unknown severities
+func SeverityLevel(s string) int {
+	for i, v := range ScanSeverities {
+		if strings.EqualFold(v, s) {
+			return i
+		}
+	}
+
+	return -1
+}
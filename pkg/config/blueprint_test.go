@@ -43,6 +43,193 @@ func TestBlueprintValidationInvalidBrowser(t *testing.T) {
 	assert.Len(t, errs, 1)
 }
 
+func TestBlueprintParsesAssertions(t *testing.T) {
+	c, cleanup := setupBlueprints(t, blueprintAssertions)
+	defer cleanup()
+
+	a := c.Blueprint.Assertions
+	assert.Len(t, a, 2)
+
+	assert.Equal(t, "consul_leader", a[0].Name)
+	assert.Equal(t, "10s", a[0].Timeout)
+	assert.Equal(t, "http://localhost:18500/v1/leader", a[0].HTTP)
+	assert.Equal(t, []int{200, 429}, a[0].HTTPOptions.SuccessCodes)
+
+	assert.Equal(t, "container.consul", a[1].Exec.Target)
+	assert.Equal(t, []string{"consul", "members"}, a[1].Exec.Command)
+	assert.Equal(t, 1, a[1].Exec.ExitCode)
+	assert.Equal(t, "k8s_cluster.k3s", a[1].Pods.Cluster)
+	assert.Equal(t, []string{"app=consul"}, a[1].Pods.Selectors)
+
+	assert.Empty(t, c.Blueprint.Validate())
+}
+
+func TestBlueprintValidationEmptyAssertion(t *testing.T) {
+	c, cleanup := setupBlueprints(t, `
+assert "empty" {
+  timeout = "10s"
+}
+`)
+	defer cleanup()
+
+	errs := c.Blueprint.Validate()
+	assert.Len(t, errs, 1)
+}
+
+func TestBlueprintParsesDemos(t *testing.T) {
+	c, cleanup := setupBlueprints(t, blueprintDemo)
+	defer cleanup()
+
+	d := c.Blueprint.Demos
+	assert.Len(t, d, 1)
+	assert.Equal(t, "failover", d[0].Name)
+	assert.Len(t, d[0].Steps, 3)
+
+	assert.True(t, d[0].Steps[0].Pause)
+	assert.Equal(t, []string{"consul", "members"}, d[0].Steps[0].Exec.Command)
+	assert.Equal(t, "POST", d[0].Steps[1].HTTP.Method)
+	assert.Equal(t, "application/json", d[0].Steps[1].HTTP.Headers["Content-Type"])
+	assert.Equal(t, ChaosDisconnect, d[0].Steps[2].Chaos.Action)
+	assert.Equal(t, "5s", d[0].Steps[2].Wait)
+
+	assert.Empty(t, c.Blueprint.Validate())
+}
+
+func TestBlueprintValidationInvalidDemo(t *testing.T) {
+	c, cleanup := setupBlueprints(t, `
+demo "broken" {
+  step "wait" {
+    wait = "soon"
+  }
+
+  step "chaos" {
+    chaos {
+      target = "container.consul"
+      action = "explode"
+    }
+  }
+
+  step "partition" {
+    chaos {
+      target = "container.consul"
+      action = "disconnect"
+    }
+  }
+}
+`)
+	defer cleanup()
+
+	errs := c.Blueprint.Validate()
+	assert.Len(t, errs, 3)
+}
+
+func TestBlueprintParsesScan(t *testing.T) {
+	c, cleanup := setupBlueprints(t, `
+scan {
+  scanner  = "grype"
+  severity = "critical"
+  action   = "block"
+  ignore   = ["CVE-2020-1234"]
+}
+`)
+	defer cleanup()
+
+	s := c.Blueprint.Scan
+	assert.Equal(t, "grype", s.Scanner)
+	assert.Equal(t, "block", s.Action)
+	assert.Equal(t, []string{"CVE-2020-1234"}, s.Ignore)
+	assert.Equal(t, 5, SeverityLevel(s.Severity))
+
+	assert.Empty(t, c.Blueprint.Validate())
+}
+
+func TestBlueprintValidationInvalidScan(t *testing.T) {
+	c, cleanup := setupBlueprints(t, `
+scan {
+  scanner  = "clair"
+  severity = "terrible"
+  action   = "ignore"
+}
+`)
+	defer cleanup()
+
+	errs := c.Blueprint.Validate()
+	assert.Len(t, errs, 3)
+}
+
+func TestBlueprintParsesStateBackend(t *testing.T) {
+	c, cleanup := setupBlueprints(t, `
+state_backend {
+  type   = "s3"
+  bucket = "shipyard"
+  key    = "dev/state.json"
+  region = "eu-west-1"
+}
+`)
+	defer cleanup()
+
+	s := c.Blueprint.StateBackend
+	assert.Equal(t, "s3", s.Type)
+	assert.Equal(t, "shipyard", s.Bucket)
+	assert.Equal(t, "dev/state.json", s.Key)
+	assert.Equal(t, "eu-west-1", s.Region)
+
+	assert.Empty(t, c.Blueprint.Validate())
+}
+
+func TestBlueprintValidationInvalidStateBackend(t *testing.T) {
+	c, cleanup := setupBlueprints(t, `
+state_backend {
+  type = "consul"
+}
+`)
+	defer cleanup()
+
+	errs := c.Blueprint.Validate()
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "must define key")
+}
+
+func TestBlueprintParsesRequiredVersionWhenVersionMatches(t *testing.T) {
+	defer setVersion("0.1.5")()
+
+	c, cleanup := setupBlueprints(t, blueprintRequiredVersion)
+	defer cleanup()
+
+	assert.Equal(t, ">= 0.1.0, < 0.2.0", c.Blueprint.RequiredVersion)
+}
+
+func TestBlueprintReturnsErrorWhenRequiredVersionDoesNotMatch(t *testing.T) {
+	defer setVersion("0.2.1")()
+
+	dir, cleanup := createTestFiles(t)
+	defer cleanup()
+	createNamedFile(t, dir, "*.yard", blueprintRequiredVersion)
+
+	err := ParseFolder(dir, &Config{})
+	assert.Error(t, err)
+	assert.IsType(t, RequiredVersionError{}, err)
+	assert.Contains(t, err.Error(), "0.2.1")
+}
+
+func TestBlueprintIgnoresRequiredVersionForDevelopmentBuilds(t *testing.T) {
+	defer setVersion("dev")()
+
+	c, cleanup := setupBlueprints(t, blueprintRequiredVersion)
+	defer cleanup()
+
+	assert.NotNil(t, c.Blueprint)
+}
+
+func setVersion(v string) func() {
+	old := Version
+	Version = v
+
+	return func() {
+		Version = old
+	}
+}
+
 var blueprintDefault = `
 title = "default blueprint"
 author = "Keyser Söze"
@@ -66,9 +253,73 @@ env {
 }
 `
 
+var blueprintRequiredVersion = `
+title = "versioned blueprint"
+required_version = ">= 0.1.0, < 0.2.0"
+`
+
 var blueprintInvalidBrowser = `
 browser_windows = [
 	"",
 	"https://www.something.com",
 ]
 `
+
+var blueprintAssertions = `
+assert "consul_leader" {
+  timeout = "10s"
+  http    = "http://localhost:18500/v1/leader"
+
+  http_options {
+    success_codes = [200, 429]
+  }
+}
+
+assert "consul_members" {
+  exec {
+    target    = "container.consul"
+    command   = ["consul", "members"]
+    exit_code = 1
+  }
+
+  pods {
+    cluster   = "k8s_cluster.k3s"
+    selectors = ["app=consul"]
+  }
+}
+`
+
+var blueprintDemo = `
+demo "failover" {
+  step "members" {
+    description = "Consul is running"
+    pause       = true
+
+    exec {
+      target  = "container.consul"
+      command = ["consul", "members"]
+    }
+  }
+
+  step "write" {
+    http {
+      url     = "http://localhost:18500/v1/kv/demo"
+      method  = "POST"
+      body    = "{}"
+      headers = {
+        "Content-Type" = "application/json"
+      }
+    }
+  }
+
+  step "partition" {
+    chaos {
+      target  = "container.consul"
+      action  = "disconnect"
+      network = "network.cloud"
+    }
+
+    wait = "5s"
+  }
+}
+`
@@ -0,0 +1,545 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+	"sigs.k8s.io/yaml"
+)
+
+// composeServiceFields are the keys of a docker-compose service which
+// can be converted into Container or Sidecar resources
+var composeServiceFields = map[string]bool{
+	"image":        true,
+	"entrypoint":   true,
+	"command":      true,
+	"environment":  true,
+	"ports":        true,
+	"volumes":      true,
+	"tmpfs":        true,
+	"networks":     true,
+	"network_mode": true,
+	"depends_on":   true,
+	"privileged":   true,
+	"cpus":         true,
+	"cpuset":       true,
+	"mem_limit":    true,
+}
+
+// ParseComposeFile parses a docker-compose file adding a Container for each
+// service and a Network for each network to the config. Services which use the
+// network of another service are added as Sidecars.
+// Compose features which have no equivalent in Shipyard are not converted,
+// a description of each is returned so that they can be reviewed
+func ParseComposeFile(file string, c *Config) ([]string, error) {
+	d, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to read compose file %s: %w", file, err)
+	}
+
+	cf := struct {
+		Version  string                            `json:"version"`
+		Services map[string]map[string]interface{} `json:"services"`
+		Networks map[string]map[string]interface{} `json:"networks"`
+		Volumes  map[string]map[string]interface{} `json:"volumes"`
+	}{}
+
+	err = yaml.Unmarshal(d, &cf)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to parse compose file %s: %w", file, err)
+	}
+
+	unsupported := []string{}
+
+	for _, name := range sortedKeys(cf.Networks) {
+		n, u := composeNetwork(name, cf.Networks[name])
+		unsupported = append(unsupported, u...)
+
+		err := c.AddResource(n)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range sortedKeys(cf.Volumes) {
+		if len(cf.Volumes[name]) > 0 {
+			unsupported = append(unsupported, fmt.Sprintf("volumes.%s: volume options are not supported", name))
+		}
+	}
+
+	// services which share the network of another service become sidecars
+	// so the type of each service must be known before resolving depends_on
+	types := map[string]ResourceType{}
+	for name, s := range cf.Services {
+		types[name] = TypeContainer
+		if strings.HasPrefix(fmt.Sprint(s["network_mode"]), "service:") {
+			types[name] = TypeSidecar
+		}
+	}
+
+	for _, name := range sortedKeys(cf.Services) {
+		r, u := composeService(name, cf.Services[name], types, file)
+		unsupported = append(unsupported, u...)
+
+		err := c.AddResource(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return unsupported, nil
+}
+
+func composeNetwork(name string, n map[string]interface{}) (*Network, []string) {
+	net := NewNetwork(name)
+	unsupported := []string{}
+
+	for _, k := range sortedKeys(n) {
+		v := n[k]
+
+		switch k {
+		case "external":
+			net.External, _ = v.(bool)
+
+		case "enable_ipv6":
+			// IPv6 is enabled by setting an IPv6 subnet
+
+		case "ipam":
+			ipam, _ := v.(map[string]interface{})
+			conf, _ := ipam["config"].([]interface{})
+
+			for _, ic := range conf {
+				icm, _ := ic.(map[string]interface{})
+				subnet, _ := icm["subnet"].(string)
+
+				if strings.Contains(subnet, ":") {
+					net.SubnetIPv6 = subnet
+				} else {
+					net.Subnet = subnet
+				}
+			}
+
+		default:
+			unsupported = append(unsupported, fmt.Sprintf("networks.%s.%s", name, k))
+		}
+	}
+
+	if net.Subnet == "" && !net.External {
+		unsupported = append(unsupported, fmt.Sprintf("networks.%s: no ipam subnet, a subnet must be set", name))
+	}
+
+	return net, unsupported
+}
+
+func composeService(name string, s map[string]interface{}, types map[string]ResourceType, file string) (Resource, []string) {
+	unsupported := []string{}
+	report := func(format string, a ...interface{}) {
+		unsupported = append(unsupported, fmt.Sprintf("services.%s.", name)+fmt.Sprintf(format, a...))
+	}
+
+	co := NewContainer(name)
+
+	for _, k := range sortedKeys(s) {
+		v := s[k]
+
+		if !composeServiceFields[k] {
+			report(k)
+			continue
+		}
+
+		switch k {
+		case "image":
+			co.Image.Name = fmt.Sprint(v)
+
+		case "entrypoint":
+			co.Entrypoint = composeCommand(v)
+			if strings.ContainsAny(fmt.Sprint(v), `"'`) {
+				report("entrypoint: quoted arguments are split on spaces, check the converted value")
+			}
+
+		case "command":
+			co.Command = composeCommand(v)
+			if strings.ContainsAny(fmt.Sprint(v), `"'`) {
+				report("command: quoted arguments are split on spaces, check the converted value")
+			}
+
+		case "environment":
+			co.Environment = composeEnvironment(v)
+
+		case "ports":
+			for _, p := range composeList(v) {
+				port, err := composePort(p)
+				if err != nil {
+					report("ports: %s", err)
+					continue
+				}
+
+				co.Ports = append(co.Ports, port)
+			}
+
+		case "volumes":
+			for _, vol := range composeList(v) {
+				volume, err := composeVolume(vol, file)
+				if err != nil {
+					report("volumes: %s", err)
+					continue
+				}
+
+				co.Volumes = append(co.Volumes, volume)
+			}
+
+		case "tmpfs":
+			for _, t := range composeList(v) {
+				co.Volumes = append(co.Volumes, Volume{Destination: fmt.Sprint(t), Type: "tmpfs"})
+			}
+
+		case "networks":
+			co.Networks = composeNetworkAttachments(v)
+
+		case "network_mode":
+			if !strings.HasPrefix(fmt.Sprint(v), "service:") {
+				report("network_mode: only service network modes are supported")
+			}
+
+		case "depends_on":
+			// depends_on is either a list of services or a map keyed by service
+			deps := composeList(v)
+			if m, ok := v.(map[string]interface{}); ok {
+				deps = []interface{}{}
+				for _, dk := range sortedKeys(m) {
+					deps = append(deps, dk)
+				}
+			}
+
+			for _, dep := range deps {
+				t, ok := types[fmt.Sprint(dep)]
+				if !ok {
+					report("depends_on: service %s not found", dep)
+					continue
+				}
+
+				co.Depends = append(co.Depends, fmt.Sprintf("%s.%s", t, dep))
+			}
+
+		case "privileged":
+			co.Privileged, _ = v.(bool)
+
+		case "cpus", "cpuset", "mem_limit":
+			if co.Resources == nil {
+				co.Resources = &Resources{}
+			}
+
+			err := composeResources(co.Resources, k, v)
+			if err != nil {
+				report("%s: %s", k, err)
+			}
+		}
+	}
+
+	if types[name] != TypeSidecar {
+		if len(co.Networks) == 0 {
+			report("networks: service is not attached to a network, attach it to a network resource")
+		}
+
+		return co, unsupported
+	}
+
+	sc := NewSidecar(name)
+	sc.Depends = co.Depends
+	sc.Target = fmt.Sprintf("%s.%s", TypeContainer, strings.TrimPrefix(fmt.Sprint(s["network_mode"]), "service:"))
+	sc.Image = co.Image
+	sc.Entrypoint = co.Entrypoint
+	sc.Command = co.Command
+	sc.Environment = co.Environment
+	sc.Volumes = co.Volumes
+	sc.Privileged = co.Privileged
+	sc.Resources = co.Resources
+
+	if len(co.Ports) > 0 || len(co.Networks) > 0 {
+		report("ports: sidecars share the network of the target, ports and networks are not converted")
+	}
+
+	return sc, unsupported
+}
+
+// composeCommand converts a command which can be either a list or a string
+func composeCommand(v interface{}) []string {
+	if s, ok := v.(string); ok {
+		return strings.Fields(s)
+	}
+
+	c := []string{}
+	for _, a := range composeList(v) {
+		c = append(c, fmt.Sprint(a))
+	}
+
+	return c
+}
+
+// composeEnvironment converts environment variables which can be either a
+// list of KEY=VALUE strings or a map
+func composeEnvironment(v interface{}) []KV {
+	env := []KV{}
+
+	if m, ok := v.(map[string]interface{}); ok {
+		for _, k := range sortedKeys(m) {
+			val := ""
+			if m[k] != nil {
+				val = fmt.Sprint(m[k])
+			}
+
+			env = append(env, KV{Key: k, Value: val})
+		}
+
+		return env
+	}
+
+	for _, e := range composeList(v) {
+		parts := strings.SplitN(fmt.Sprint(e), "=", 2)
+		kv := KV{Key: parts[0]}
+		if len(parts) == 2 {
+			kv.Value = parts[1]
+		}
+
+		env = append(env, kv)
+	}
+
+	return env
+}
+
+// composePort converts the short syntax [ip:][host:]container[/protocol] or
+// the long syntax of a compose port
+func composePort(v interface{}) (Port, error) {
+	if m, ok := v.(map[string]interface{}); ok {
+		p := Port{
+			Local:    composeNumber(m["target"]),
+			Host:     composeNumber(m["published"]),
+			Protocol: fmt.Sprint(m["protocol"]),
+		}
+
+		if m["protocol"] == nil {
+			p.Protocol = ""
+		}
+
+		p.Remote = p.Local
+		return p, nil
+	}
+
+	s := composeNumber(v)
+	p := Port{}
+
+	if i := strings.Index(s, "/"); i > 0 {
+		p.Protocol = s[i+1:]
+		s = s[:i]
+	}
+
+	if strings.Contains(s, "-") {
+		return p, xerrors.Errorf("port ranges are not supported %s", s)
+	}
+
+	parts := strings.Split(s, ":")
+	switch len(parts) {
+	case 1:
+		p.Local = parts[0]
+	case 2:
+		p.Host = parts[0]
+		p.Local = parts[1]
+	case 3:
+		if parts[0] != "" && parts[0] != "0.0.0.0" {
+			return p, xerrors.Errorf("binding to a host ip is not supported %s", s)
+		}
+
+		p.Host = parts[1]
+		p.Local = parts[2]
+	default:
+		return p, xerrors.Errorf("invalid port %s", s)
+	}
+
+	p.Remote = p.Local
+	return p, nil
+}
+
+// composeVolume converts the short syntax source:target[:mode] or the long
+// syntax of a compose volume, relative bind mounts are made absolute
+func composeVolume(v interface{}, file string) (Volume, error) {
+	if m, ok := v.(map[string]interface{}); ok {
+		vol := Volume{
+			Source:      fmt.Sprint(m["source"]),
+			Destination: fmt.Sprint(m["target"]),
+			Type:        fmt.Sprint(m["type"]),
+		}
+
+		if ro, _ := m["read_only"].(bool); ro {
+			return vol, xerrors.Errorf("read only volumes are not supported %s", vol.Destination)
+		}
+
+		if vol.Type == "bind" {
+			vol.Source = ensureAbsolute(vol.Source, file)
+		}
+
+		return vol, nil
+	}
+
+	parts := strings.Split(fmt.Sprint(v), ":")
+	if len(parts) == 1 {
+		return Volume{}, xerrors.Errorf("anonymous volumes are not supported %s", parts[0])
+	}
+
+	if len(parts) > 2 && parts[2] != "rw" {
+		return Volume{}, xerrors.Errorf("volume mode %s is not supported %s", parts[2], parts[1])
+	}
+
+	vol := Volume{Source: parts[0], Destination: parts[1]}
+
+	// sources which are not paths are named volumes
+	switch {
+	case strings.HasPrefix(vol.Source, "~"):
+		vol.Type = "bind"
+		vol.Source = filepath.Join(utils.HomeFolder(), strings.TrimPrefix(vol.Source, "~"))
+	case strings.HasPrefix(vol.Source, ".") || strings.HasPrefix(vol.Source, "/"):
+		vol.Type = "bind"
+		vol.Source = ensureAbsolute(vol.Source, file)
+	default:
+		vol.Type = "volume"
+	}
+
+	return vol, nil
+}
+
+// composeNetworkAttachments converts networks which can be either a list
+// of names or a map of names to options
+func composeNetworkAttachments(v interface{}) []NetworkAttachment {
+	na := []NetworkAttachment{}
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		for _, n := range composeList(v) {
+			na = append(na, NetworkAttachment{Name: fmt.Sprintf("%s.%s", TypeNetwork, n)})
+		}
+
+		return na
+	}
+
+	for _, k := range sortedKeys(m) {
+		a := NetworkAttachment{Name: fmt.Sprintf("%s.%s", TypeNetwork, k)}
+
+		if o, ok := m[k].(map[string]interface{}); ok {
+			a.IPAddress, _ = o["ipv4_address"].(string)
+			a.IPv6Address, _ = o["ipv6_address"].(string)
+
+			for _, al := range composeList(o["aliases"]) {
+				a.Aliases = append(a.Aliases, fmt.Sprint(al))
+			}
+		}
+
+		na = append(na, a)
+	}
+
+	return na
+}
+
+// composeResources sets the resource constraint for the given compose key
+func composeResources(r *Resources, key string, v interface{}) error {
+	s := composeNumber(v)
+
+	switch key {
+	case "cpus":
+		cpus, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return xerrors.Errorf("invalid value %s", s)
+		}
+
+		// Shipyard uses 1024 shares per CPU
+		r.CPU = int(cpus * 1024)
+
+	case "cpuset":
+		for _, p := range strings.Split(s, ",") {
+			if strings.Contains(p, "-") {
+				return xerrors.Errorf("cpu ranges are not supported %s", s)
+			}
+
+			i, err := strconv.Atoi(strings.TrimSpace(p))
+			if err != nil {
+				return xerrors.Errorf("invalid value %s", s)
+			}
+
+			r.CPUPin = append(r.CPUPin, i)
+		}
+
+	case "mem_limit":
+		mb, err := composeMemory(s)
+		if err != nil {
+			return err
+		}
+
+		r.Memory = mb
+	}
+
+	return nil
+}
+
+// composeMemory converts a compose byte value e.g. 512m, 1g or 1024 into MB
+func composeMemory(s string) (int, error) {
+	s = strings.TrimSuffix(strings.ToLower(s), "b")
+	if s == "" {
+		return 0, xerrors.Errorf("invalid value %s", s)
+	}
+
+	units := map[string]float64{"k": 1.0 / 1024, "m": 1, "g": 1024}
+	mul := 1.0 / (1024 * 1024)
+
+	if u, ok := units[s[len(s)-1:]]; ok {
+		mul = u
+		s = s[:len(s)-1]
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, xerrors.Errorf("invalid value %s", s)
+	}
+
+	return int(f * mul), nil
+}
+
+// composeNumber returns a string for values which may be numbers or strings
+// in YAML, JSON decodes all numbers as float64
+func composeNumber(v interface{}) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	if v == nil {
+		return ""
+	}
+
+	return fmt.Sprint(v)
+}
+
+func composeList(v interface{}) []interface{} {
+	l, _ := v.([]interface{})
+	return l
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+
+	switch mv := m.(type) {
+	case map[string]interface{}:
+		for k := range mv {
+			keys = append(keys, k)
+		}
+	case map[string]map[string]interface{}:
+		for k := range mv {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupComposeTest(t *testing.T, contents string) (*Config, []string, string, func()) {
+	dir := createTempDirectory(t)
+	f := createNamedFile(t, dir, "*.yaml", contents)
+
+	c := New()
+	u, err := ParseComposeFile(f, c)
+	assert.NoError(t, err)
+
+	return c, u, dir, func() {
+		removeTestFiles(t, dir)
+	}
+}
+
+func TestParseComposeCreatesNetworks(t *testing.T) {
+	c, _, _, cleanup := setupComposeTest(t, composeDefault)
+	defer cleanup()
+
+	r, err := c.FindResource("network.cloud")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.5.0.0/16", r.(*Network).Subnet)
+	assert.Equal(t, "fd00::/64", r.(*Network).SubnetIPv6)
+
+	r, err = c.FindResource("network.existing")
+	assert.NoError(t, err)
+	assert.True(t, r.(*Network).External)
+}
+
+func TestParseComposeCreatesContainers(t *testing.T) {
+	c, _, dir, cleanup := setupComposeTest(t, composeDefault)
+	defer cleanup()
+
+	r, err := c.FindResource("container.consul")
+	assert.NoError(t, err)
+
+	co := r.(*Container)
+	assert.Equal(t, "consul:1.7.2", co.Image.Name)
+	assert.Equal(t, []string{"agent", "-dev", "-client", "0.0.0.0"}, co.Command)
+	assert.Equal(t, []KV{KV{Key: "CONSUL_BIND_INTERFACE", Value: "eth0"}, KV{Key: "DEBUG", Value: "1"}}, co.Environment)
+	assert.Equal(t, []NetworkAttachment{NetworkAttachment{Name: "network.cloud", IPAddress: "10.5.0.200", Aliases: []string{"server"}}}, co.Networks)
+	assert.Equal(t, []string{"container.db"}, co.Depends)
+	assert.True(t, co.Privileged)
+
+	assert.Equal(t, []Port{
+		Port{Local: "8500", Remote: "8500", Host: "18500"},
+		Port{Local: "8600", Remote: "8600", Host: "8600", Protocol: "udp"},
+		Port{Local: "8300", Remote: "8300"},
+		Port{Local: "8301", Remote: "8301", Host: "18301"},
+	}, co.Ports)
+
+	assert.Equal(t, []Volume{
+		Volume{Destination: "/tmp", Type: "tmpfs"},
+		Volume{Source: filepath.Join(dir, "config"), Destination: "/config", Type: "bind"},
+		Volume{Source: "data", Destination: "/data", Type: "volume"},
+	}, co.Volumes)
+
+	assert.Equal(t, &Resources{CPU: 512, CPUPin: []int{0, 1}, Memory: 1024}, co.Resources)
+}
+
+func TestParseComposeCreatesSidecarsForServiceNetworkMode(t *testing.T) {
+	c, _, _, cleanup := setupComposeTest(t, composeDefault)
+	defer cleanup()
+
+	r, err := c.FindResource("sidecar.envoy")
+	assert.NoError(t, err)
+
+	sc := r.(*Sidecar)
+	assert.Equal(t, "container.consul", sc.Target)
+	assert.Equal(t, []string{"envoy", "-c", "/config/envoy.yaml"}, sc.Command)
+
+	r, err = c.FindResource("container.db")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sidecar.envoy"}, r.(*Container).Depends)
+}
+
+func TestParseComposeReportsUnsupportedFields(t *testing.T) {
+	_, u, _, cleanup := setupComposeTest(t, composeDefault)
+	defer cleanup()
+
+	assert.Contains(t, u, "networks.cloud.driver")
+	assert.Contains(t, u, "services.consul.restart")
+	assert.Contains(t, u, "services.db.build")
+	assert.Contains(t, u, "services.db.ports: port ranges are not supported 5432-5433:5432-5433")
+	assert.Contains(t, u, "services.db.volumes: volume mode ro is not supported /config")
+	assert.Contains(t, u, "services.db.networks: service is not attached to a network, attach it to a network resource")
+	assert.Contains(t, u, "volumes.data: volume options are not supported")
+}
+
+func TestParseComposeInvalidFileReturnsError(t *testing.T) {
+	dir := createTempDirectory(t)
+	defer removeTestFiles(t, dir)
+
+	f := createNamedFile(t, dir, "*.yaml", "services: [")
+
+	_, err := ParseComposeFile(f, New())
+	assert.Error(t, err)
+}
+
+var composeDefault = `
+version: "3.7"
+services:
+  consul:
+    image: consul:1.7.2
+    command: agent -dev -client 0.0.0.0
+    restart: always
+    privileged: true
+    environment:
+      CONSUL_BIND_INTERFACE: eth0
+      DEBUG: 1
+    ports:
+      - "18500:8500"
+      - "0.0.0.0:8600:8600/udp"
+      - 8300
+      - target: 8301
+        published: 18301
+    volumes:
+      - ./config:/config
+      - data:/data
+    tmpfs:
+      - /tmp
+    networks:
+      cloud:
+        ipv4_address: 10.5.0.200
+        aliases:
+          - server
+    depends_on:
+      - db
+    cpus: 0.5
+    cpuset: "0,1"
+    mem_limit: 1g
+  envoy:
+    image: envoyproxy/envoy:v1.14.1
+    command: ["envoy", "-c", "/config/envoy.yaml"]
+    network_mode: service:consul
+  db:
+    image: postgres
+    build: .
+    ports:
+      - "5432-5433:5432-5433"
+    volumes:
+      - ./config:/config:ro
+    depends_on:
+      envoy:
+        condition: service_started
+
+networks:
+  cloud:
+    driver: bridge
+    ipam:
+      config:
+        - subnet: 10.5.0.0/16
+        - subnet: fd00::/64
+  existing:
+    external: true
+
+volumes:
+  data:
+    driver: local
+`
@@ -53,6 +53,9 @@ type ResourceInfo struct {
 	Status Status `json:"status,omitempty"`
 	// DependsOn is a list of objects which must exist before this resource can be applied
 	DependsOn []string `json:"depends_on,omitempty"`
+	// Definition is the resource as it was parsed from the blueprint, providers
+	// change some attributes when the resource is created
+	Definition map[string]interface{} `json:"definition,omitempty"`
 
 	// parent container
 	Config *Config `json:"-"`
@@ -76,8 +79,13 @@ func (r *ResourceInfo) AddChild(c Resource) {
 
 // Config defines the stack config
 type Config struct {
-	Blueprint *Blueprint `json:"blueprint"`
-	Resources []Resource `json:"resources"`
+	ShipyardVersion string     `json:"shipyard_version,omitempty"` // version of Shipyard which wrote the state
+	StateVersion    int        `json:"state_version,omitempty"`    // version of the state format
+	Blueprint       *Blueprint `json:"blueprint"`
+	Resources       []Resource `json:"resources"`
+
+	Offline       bool     `json:"-"` // when set remote modules are not downloaded, only modules in the local cache are used
+	RemoteModules []string `json:"-"` // local folders of the remote modules used by the config
 }
 
 // ResourceNotFoundError is thrown when a resource could not be found
@@ -12,6 +12,8 @@ type Container struct {
 
 	Networks []NetworkAttachment `hcl:"network,block" json:"networks,omitempty"` // Attach to the correct network // only when Image is specified
 
+	DockerHost string `hcl:"docker_host,optional" json:"docker_host,omitempty" mapstructure:"docker_host"` // docker_host resource to run the container on, default local
+
 	Image       Image    `hcl:"image,block" json:"image"`                        // image to use for the container
 	Entrypoint  []string `hcl:"entrypoint,optional" json:"entrypoint,omitempty"` // entrypoint to use when starting the container
 	Command     []string `hcl:"command,optional" json:"command,omitempty"`       // command to use when starting the container
@@ -19,6 +21,8 @@ type Container struct {
 	Volumes     []Volume `hcl:"volume,block" json:"volumes,omitempty"`           // volumes to attach to the container
 	Ports       []Port   `hcl:"port,block" json:"ports,omitempty"`               // ports to expose
 
+	DisableBrowser bool `hcl:"disable_browser,optional" json:"disable_browser,omitempty" mapstructure:"disable_browser"` // do not open browser windows for this resource after it is created
+
 	Privileged bool `hcl:"privileged,optional" json:"privileged,omitempty"` // run the container in priviledged mode?
 
 	// resource constraints
@@ -26,6 +30,12 @@ type Container struct {
 
 	// health checks for the container
 	HealthCheck *HealthCheck `hcl:"health_check,block" json:"health_check,omitempty"`
+
+	// create the container again when it fails
+	Retry *Retry `hcl:"retry,block" json:"retry,omitempty"`
+
+	// maximum time to create the container including pulling the image, health checks, and retries e.g. 5m
+	Timeout string `hcl:"timeout,optional" json:"timeout,omitempty"`
 }
 
 // NewContainer returns a new Container resource with the correct default options
@@ -34,9 +44,12 @@ func NewContainer(name string) *Container {
 }
 
 type NetworkAttachment struct {
-	Name      string   `hcl:"name" json:"name"`
-	IPAddress string   `hcl:"ip_address,optional" json:"ip_address,omitempty"`
-	Aliases   []string `hcl:"aliases,optional" json:"aliases,omitempty"` // Network aliases for the resource
+	Name        string   `hcl:"name" json:"name"`
+	IPAddress   string   `hcl:"ip_address,optional" json:"ip_address,omitempty" mapstructure:"ip_address"`
+	IPv6Address string   `hcl:"ipv6_address,optional" json:"ipv6_address,omitempty" mapstructure:"ipv6_address"` // only valid when the network has an IPv6 subnet
+	Aliases     []string `hcl:"aliases,optional" json:"aliases,omitempty"`                                       // Network aliases for the resource
+
+	Impairment *NetworkImpairment `hcl:"impairment,block" json:"impairment,omitempty"` // traffic shaping for this attachment, overrides the network
 }
 
 // Resources allows the setting of resource constraints for the Container
@@ -59,6 +72,13 @@ type KV struct {
 	Value string `hcl:"value" json:"value"`
 }
 
+// ContainerInfo describes a container in the Docker engine
+type ContainerInfo struct {
+	ID         string `json:"id"`
+	Running    bool   `json:"running"`
+	ConfigHash string `json:"config_hash,omitempty"` // hash of the definition of the resource which created the container
+}
+
 // Validate the config
 func (c *Container) Validate() error {
 	return nil
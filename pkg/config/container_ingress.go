@@ -11,6 +11,8 @@ type ContainerIngress struct {
 
 	Networks []NetworkAttachment `hcl:"network,block" json:"networks,omitempty"` // Attach to the correct network // only when Image is specified
 
+	DockerHost string `hcl:"docker_host,optional" json:"docker_host,omitempty" mapstructure:"docker_host"` // docker_host resource to run the ingress on, default local
+
 	// Tartget is the name of the container to attach to "conatiner.[name]"
 	Target string `hcl:"target" json:"target"`
 
@@ -24,6 +26,8 @@ type ContainerIngress struct {
 	// Remote - This is the destination port for the target container
 	// Host   - The port to expose on localhost, this can be different from the Local container port.
 	Ports []Port `hcl:"port,block" json:"ports,omitempty"`
+
+	DisableBrowser bool `hcl:"disable_browser,optional" json:"disable_browser,omitempty" mapstructure:"disable_browser"` // do not open browser windows for this resource after it is created
 }
 
 // NewContainerIngress creates a new ingress for standard docker containers with the correct defaults
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -18,6 +19,51 @@ func TestContainerCreatesCorrectly(t *testing.T) {
 	assert.Equal(t, PendingCreation, co.Info().Status)
 }
 
+func TestContainerParsesHTTPHealthCheckOptions(t *testing.T) {
+	c, dir, cleanup := setupTestConfig(t, containerHealthCheck)
+	defer cleanup()
+
+	co, err := c.FindResource("container.testing")
+	assert.NoError(t, err)
+
+	o := co.(*Container).HealthCheck.HTTPOptions
+	assert.Equal(t, []int{200, 429}, o.SuccessCodes)
+	assert.Equal(t, "5s", o.RequestTimeout)
+	assert.Equal(t, "1s", o.Backoff)
+	assert.Equal(t, "10s", o.MaxBackoff)
+	assert.Equal(t, filepath.Join(dir, "certs/ca.pem"), o.CACert)
+	assert.True(t, o.Insecure)
+	assert.Equal(t, "leader", o.Body)
+	assert.Equal(t, map[string]string{"{.status}": "ok"}, o.JSONPath)
+	assert.Equal(t, "24h", o.CertValidFor)
+}
+
+func TestContainerParsesConsulHealthCheck(t *testing.T) {
+	c, _, cleanup := setupTestConfig(t, containerHealthCheck)
+	defer cleanup()
+
+	co, err := c.FindResource("container.testing")
+	assert.NoError(t, err)
+
+	cs := co.(*Container).HealthCheck.Consul
+	assert.Equal(t, "http://localhost:8500", cs.Address)
+	assert.Equal(t, []string{"web", "api"}, cs.Services)
+	assert.Equal(t, "abc", cs.Token)
+}
+
+func TestContainerParsesRetry(t *testing.T) {
+	c, _, cleanup := setupTestConfig(t, containerRetry)
+	defer cleanup()
+
+	co, err := c.FindResource("container.testing")
+	assert.NoError(t, err)
+
+	r := co.(*Container).Retry
+	assert.Equal(t, 5, r.Attempts)
+	assert.Equal(t, "2s", r.Backoff)
+	assert.Equal(t, "30s", r.MaxBackoff)
+}
+
 const containerDefault = `
 network "test" {
 	subnet = "10.0.0.0/24"
@@ -32,3 +78,50 @@ container "testing" {
 	}
 }
 `
+
+const containerHealthCheck = `
+container "testing" {
+	image {
+		name = "consul"
+	}
+
+	health_check {
+		timeout = "30s"
+		http    = "https://localhost:8500/v1/status/leader"
+
+		http_options {
+			success_codes   = [200, 429]
+			request_timeout = "5s"
+			backoff         = "1s"
+			max_backoff     = "10s"
+			ca_cert         = "./certs/ca.pem"
+			insecure        = true
+			body            = "leader"
+			json_path       = {
+				"{.status}" = "ok"
+			}
+			cert_valid_for  = "24h"
+		}
+
+		consul {
+			address  = "http://localhost:8500"
+			services = ["web", "api"]
+			token    = "abc"
+		}
+	}
+}
+`
+
+const containerRetry = `
+container "testing" {
+	image {
+		name = "consul"
+	}
+
+	retry {
+		attempts    = 5
+		backoff     = "2s"
+		max_backoff = "30s"
+	}
+}
+`
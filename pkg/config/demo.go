@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Demo chaos actions
+const (
+	ChaosStop       = "stop"       // stop the target container
+	ChaosStart      = "start"      // start the stopped target container
+	ChaosDisconnect = "disconnect" // disconnect the target container from the network
+	ChaosConnect    = "connect"    // reconnect the target container to the network
+)
+
+// Demo is a scripted sequence of steps which is run against the resources
+// of a running blueprint, steps are run in order and the demo stops at the
+// first step which fails
+// example config:
+//    demo "failover" {
+//      step "intro" {
+//        description = "Consul is running with a single server"
+//        pause       = true                            // wait for the presenter before continuing
+//      }
+//
+//      step "members" {
+//        exec {
+//          target  = "container.consul"
+//          command = ["consul", "members"]
+//        }
+//      }
+//
+//      step "partition" {
+//        chaos {
+//          target  = "container.consul"
+//          action  = "disconnect"                       // stop, start, disconnect, or connect
+//          network = "network.cloud"
+//        }
+//
+//        wait = "5s"                                   // time to wait after the step has run
+//      }
+//
+//      step "leader" {
+//        http {
+//          url = "http://localhost:18500/v1/status/leader"
+//        }
+//      }
+//    }
+type Demo struct {
+	Name  string     `hcl:"name,label" json:"name"`
+	Steps []DemoStep `hcl:"step,block" json:"steps,omitempty"`
+}
+
+// DemoStep is a single step in a demo, a step can contain any of exec, http,
+// and chaos actions which are run in that order
+type DemoStep struct {
+	Name        string `hcl:"name,label" json:"name"`
+	Description string `hcl:"description,optional" json:"description,omitempty"` // text shown before the step runs
+	Pause       bool   `hcl:"pause,optional" json:"pause,omitempty"`             // wait for the presenter to continue before running the step
+	Wait        string `hcl:"wait,optional" json:"wait,omitempty"`               // time to wait after the step has run e.g. 10s
+
+	Exec  *DemoExec  `hcl:"exec,block" json:"exec,omitempty"`
+	HTTP  *DemoHTTP  `hcl:"http,block" json:"http,omitempty"`
+	Chaos *DemoChaos `hcl:"chaos,block" json:"chaos,omitempty"`
+}
+
+// DemoExec runs a command in a container
+type DemoExec struct {
+	Target  string   `hcl:"target" json:"target"`
+	Command []string `hcl:"command" json:"command"`
+}
+
+// DemoHTTP makes a HTTP request, the response body is shown as the output
+type DemoHTTP struct {
+	URL     string            `hcl:"url" json:"url"`
+	Method  string            `hcl:"method,optional" json:"method,omitempty"` // default GET
+	Body    string            `hcl:"body,optional" json:"body,omitempty"`
+	Headers map[string]string `hcl:"headers,optional" json:"headers,omitempty"`
+}
+
+// DemoChaos disrupts a running container
+type DemoChaos struct {
+	Target  string `hcl:"target" json:"target"`
+	Action  string `hcl:"action" json:"action"`
+	Network string `hcl:"network,optional" json:"network,omitempty"` // network for the connect and disconnect actions
+}
+
+// validate returns errors for invalid steps in the demo
+func (d *Demo) validate() []error {
+	errors := []error{}
+
+	for _, s := range d.Steps {
+		if s.Wait != "" {
+			if _, err := time.ParseDuration(s.Wait); err != nil {
+				errors = append(errors, fmt.Errorf("demo %s step %s has an invalid wait %s: %s", d.Name, s.Name, s.Wait, err))
+			}
+		}
+
+		if s.Chaos == nil {
+			continue
+		}
+
+		switch s.Chaos.Action {
+		case ChaosStop, ChaosStart:
+		case ChaosDisconnect, ChaosConnect:
+			if s.Chaos.Network == "" {
+				errors = append(errors, fmt.Errorf("demo %s step %s must define a network for the %s action", d.Name, s.Name, s.Chaos.Action))
+			}
+		default:
+			errors = append(errors, fmt.Errorf("demo %s step %s has an invalid chaos action %s, must be one of stop, start, disconnect, or connect", d.Name, s.Name, s.Chaos.Action))
+		}
+	}
+
+	return errors
+}
@@ -0,0 +1,68 @@
+package config
+
+import "strings"
+
+// TypeDockerHost is the resource string for a DockerHost resource
+const TypeDockerHost ResourceType = "docker_host"
+
+// DockerHost defines a named Docker daemon which resources can be pinned to,
+// this allows an environment to be spread over multiple machines. Networks,
+// containers, and container ingress set docker_host to the resource name
+// e.g. docker_host.lab1, sidecars always run on the same host as their
+// target. Kubernetes and Nomad clusters are always created on the default
+// Docker host.
+//
+// Containers on different hosts can only communicate when they are attached
+// to a network with the overlay driver, the hosts must be members of the same
+// Docker Swarm and the network must be pinned to a manager node
+// example config:
+//    docker_host "lab1" {
+//      host       = "tcp://10.5.0.10:2376" // address of the daemon, tcp://, ssh://, or unix://
+//      context    = "lab1"                 // alternatively use a Docker CLI context
+//      cert_path  = "./certs"              // folder containing ca.pem, cert.pem, and key.pem
+//      tls_verify = true
+//    }
+type DockerHost struct {
+	ResourceInfo
+
+	Depends []string `hcl:"depends_on,optional" json:"depends,omitempty"`
+
+	Host      string `hcl:"host,optional" json:"host,omitempty"`
+	Context   string `hcl:"context,optional" json:"context,omitempty"`
+	CertPath  string `hcl:"cert_path,optional" json:"cert_path,omitempty" mapstructure:"cert_path"`
+	TLSVerify bool   `hcl:"tls_verify,optional" json:"tls_verify,omitempty" mapstructure:"tls_verify"`
+}
+
+// NewDockerHost creates a DockerHost resource with the correct defaults
+func NewDockerHost(name string) *DockerHost {
+	return &DockerHost{ResourceInfo: ResourceInfo{Name: name, Type: TypeDockerHost, Status: PendingCreation}}
+}
+
+// DockerHostFor returns the name of the docker_host a resource is pinned to,
+// an empty string is returned for resources which use the default Docker host
+func DockerHostFor(r Resource) string {
+	switch v := r.(type) {
+	case *DockerHost:
+		return string(TypeDockerHost) + "." + v.Name
+	case *Network:
+		return v.DockerHost
+	case *Container:
+		return v.DockerHost
+	case *ContainerIngress:
+		return v.DockerHost
+	case *Sidecar:
+		// sidecars share the network namespace of the target so must run on the same host
+		if v.Config == nil || !strings.HasPrefix(v.Target, string(TypeContainer)+".") {
+			return ""
+		}
+
+		t, err := v.FindDependentResource(v.Target)
+		if err != nil {
+			return ""
+		}
+
+		return DockerHostFor(t)
+	}
+
+	return ""
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDockerHostCreatesCorrectly(t *testing.T) {
+	c, dir, cleanup := setupTestConfig(t, dockerHostDefault)
+	defer cleanup()
+
+	r, err := c.FindResource("docker_host.lab1")
+	assert.NoError(t, err)
+
+	h := r.(*DockerHost)
+	assert.Equal(t, TypeDockerHost, h.Type)
+	assert.Equal(t, "tcp://10.5.0.10:2376", h.Host)
+	assert.Equal(t, filepath.Join(dir, "certs"), h.CertPath)
+	assert.True(t, h.TLSVerify)
+}
+
+func TestDockerHostAddsDependencies(t *testing.T) {
+	c, _, cleanup := setupTestConfig(t, dockerHostDefault)
+	defer cleanup()
+
+	n, _ := c.FindResource("network.overlay")
+	assert.Equal(t, "overlay", n.(*Network).Driver)
+	assert.Contains(t, n.Info().DependsOn, "docker_host.lab1")
+
+	co, _ := c.FindResource("container.consul")
+	assert.Contains(t, co.Info().DependsOn, "docker_host.lab1")
+}
+
+func TestDockerHostForReturnsHost(t *testing.T) {
+	c, _, cleanup := setupTestConfig(t, dockerHostDefault)
+	defer cleanup()
+
+	h, _ := c.FindResource("docker_host.lab1")
+	assert.Equal(t, "docker_host.lab1", DockerHostFor(h))
+
+	co, _ := c.FindResource("container.consul")
+	assert.Equal(t, "docker_host.lab1", DockerHostFor(co))
+
+	// sidecars run on the same host as the target
+	s, _ := c.FindResource("sidecar.envoy")
+	assert.Equal(t, "docker_host.lab1", DockerHostFor(s))
+
+	l, _ := c.FindResource("container.local")
+	assert.Equal(t, "", DockerHostFor(l))
+}
+
+var dockerHostDefault = `
+docker_host "lab1" {
+  host       = "tcp://10.5.0.10:2376"
+  cert_path  = "./certs"
+  tls_verify = true
+}
+
+network "overlay" {
+  subnet      = "10.6.0.0/16"
+  driver      = "overlay"
+  docker_host = "docker_host.lab1"
+}
+
+container "consul" {
+  docker_host = "docker_host.lab1"
+
+  image {
+    name = "consul:1.8.1"
+  }
+
+  network {
+    name = "network.overlay"
+  }
+}
+
+sidecar "envoy" {
+  target = "container.consul"
+
+  image {
+    name = "envoyproxy/envoy:v1.14.3"
+  }
+}
+
+container "local" {
+  image {
+    name = "consul:1.8.1"
+  }
+}
+`
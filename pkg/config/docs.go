@@ -20,6 +20,10 @@ type Docs struct {
 
 	IndexTitle string   `hcl:"index_title,optional" json:"index_title" mapstructure:"index_title"`
 	IndexPages []string `hcl:"index_pages,optional" json:"index_pages,omitempty" mapstructure:"index_pages"`
+
+	Sidebars   string `hcl:"sidebars,optional" json:"sidebars,omitempty"`                                  // custom sidebars.js, overrides index_title and index_pages
+	SiteConfig string `hcl:"site_config,optional" json:"site_config,omitempty" mapstructure:"site_config"` // custom docusaurus.config.js used to set the title, logo, and theme
+	Static     string `hcl:"static,optional" json:"static,omitempty"`                                      // folder containing static assets such as logos and images
 }
 
 // NewDocs creates a new Docs config resource
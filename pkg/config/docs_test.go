@@ -1,6 +1,7 @@
 package config
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -18,6 +19,19 @@ func TestDocsCreatesCorrectly(t *testing.T) {
 	assert.Equal(t, PendingCreation, cl.Info().Status)
 }
 
+func TestDocsMakesContentPathsAbsolute(t *testing.T) {
+	c, dir, cleanup := setupTestConfig(t, docsBranding)
+	defer cleanup()
+
+	cl, err := c.FindResource("docs.testing")
+	assert.NoError(t, err)
+
+	d := cl.(*Docs)
+	assert.Equal(t, filepath.Join(dir, "sidebars.js"), d.Sidebars)
+	assert.Equal(t, filepath.Join(dir, "docusaurus.config.js"), d.SiteConfig)
+	assert.Equal(t, filepath.Join(dir, "static"), d.Static)
+}
+
 const docsDefault = `
 docs "testing" {
 	path = "/"
@@ -26,3 +40,13 @@ docs "testing" {
 	index_pages = ["test"]
 }
 `
+
+const docsBranding = `
+docs "testing" {
+	path = "./docs"
+	port = "80"
+	sidebars = "./sidebars.js"
+	site_config = "./docusaurus.config.js"
+	static = "./static"
+}
+`
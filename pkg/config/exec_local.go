@@ -12,9 +12,30 @@ type ExecLocal struct {
 	// Either Script or Command must be specified
 	Script    string   `hcl:"script,optional" json:"script,omitempty"` // Path to a script to execute
 	Command   string   `hcl:"cmd,optional" json:"cmd,omitempty"`       // Command to execute
-	Arguments []string `hcl:"args,optional" json:"args,omitempty"`     // only used when combined with Command
+	Arguments []string `hcl:"args,optional" json:"args,omitempty"`     // arguments passed to the Command or Script
+
+	WorkingDirectory string `hcl:"working_directory,optional" json:"working_directory,omitempty" mapstructure:"working_directory"` // Working directory to execute the command in
+	Timeout          string `hcl:"timeout,optional" json:"timeout,omitempty"`                                                      // Maximum time the command can run for e.g. 60s, when not set the default timeout is used
+	Retries          int    `hcl:"retries,optional" json:"retries,omitempty"`                                                      // Number of times to retry the command when it fails
+	TTY              bool   `hcl:"tty,optional" json:"tty,omitempty"`                                                              // Run the command in a pseudo-terminal with standard input attached, for tools which require a TTY
 
 	Environment []KV `hcl:"env,block" json:"env"` // Envrionment variables to set
+
+	OnDestroy *ExecDestroy `hcl:"on_destroy,block" json:"on_destroy,omitempty" mapstructure:"on_destroy"` // Command to run when the resource is destroyed
+
+	Outputs []ExecOutput `hcl:"output,block" json:"outputs,omitempty"` // Values to capture from the output of the command
+
+	DisableBrowser bool `hcl:"disable_browser,optional" json:"disable_browser,omitempty" mapstructure:"disable_browser"` // do not open browser windows for this resource after it is created
+}
+
+// ExecDestroy defines a command which is run when an exec_local or exec_remote
+// resource is destroyed, the command is run before any resources the exec
+// depends on are removed
+type ExecDestroy struct {
+	// Either Script or Command must be specified
+	Script    string   `hcl:"script,optional" json:"script,omitempty"` // Path to a script to execute
+	Command   string   `hcl:"cmd,optional" json:"cmd,omitempty"`       // Command to execute
+	Arguments []string `hcl:"args,optional" json:"args,omitempty"`     // arguments passed to the Command or Script
 }
 
 // NewExecLocal creates a LocalExec resource with the default values
@@ -20,8 +20,89 @@ func TestExecLocalCreatesCorrectly(t *testing.T) {
 	// assert.Equal(t, dir+"/scripts/setup_vault.sh", ExecLocal(*ex).Script)
 }
 
+func TestExecLocalCreatesWithOptions(t *testing.T) {
+	c, dir, cleanup := setupTestConfig(t, execLocalOptions)
+	defer cleanup()
+
+	ex, err := c.FindResource("exec_local.setup_vault")
+	assert.NoError(t, err)
+
+	el := ex.(*ExecLocal)
+	assert.Equal(t, "", el.Script)
+	assert.Equal(t, dir+"/files", el.WorkingDirectory)
+	assert.Equal(t, "30s", el.Timeout)
+	assert.Equal(t, 2, el.Retries)
+	assert.Equal(t, dir+"/cleanup.sh", el.OnDestroy.Script)
+}
+
+func TestExecLocalCreatesOutputs(t *testing.T) {
+	c, _, cleanup := setupTestConfig(t, execLocalOutputs)
+	defer cleanup()
+
+	ex, err := c.FindResource("exec_local.setup_vault")
+	assert.NoError(t, err)
+
+	o := ex.(*ExecLocal).Outputs
+	assert.Len(t, o, 2)
+	assert.Equal(t, "all", o[0].Name)
+	assert.Equal(t, "token", o[1].Name)
+	assert.Equal(t, "Token: (.*)", o[1].Regex)
+}
+
+func TestExecLocalAddsDependencies(t *testing.T) {
+	c, _, cleanup := setupTestConfig(t, execLocalDepends)
+	defer cleanup()
+
+	ex, err := c.FindResource("exec_local.setup_vault")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"container.vault"}, ex.Info().DependsOn)
+}
+
 var execLocalRelative = `
 exec_local "setup_vault" {
   script = "./scripts/setup_vault.sh"
 }
 `
+
+var execLocalOutputs = `
+exec_local "setup_vault" {
+  script = "./scripts/setup_vault.sh"
+
+  output {
+    name = "all"
+  }
+
+  output {
+    name = "token"
+    regex = "Token: (.*)"
+  }
+}
+`
+
+var execLocalOptions = `
+exec_local "setup_vault" {
+  cmd = "vault"
+  args = ["status"]
+  working_directory = "./files"
+  timeout = "30s"
+  retries = 2
+
+  on_destroy {
+    script = "./cleanup.sh"
+  }
+}
+`
+
+var execLocalDepends = `
+container "vault" {
+  image {
+    name = "vault:1.6.1"
+  }
+}
+
+exec_local "setup_vault" {
+  cmd        = "vault"
+  depends_on = ["container.vault"]
+}
+`
@@ -0,0 +1,12 @@
+package config
+
+// ExecOutput captures a named value from the output of an exec_local or
+// exec_remote resource, the value is stored in the state once the
+// command has completed
+type ExecOutput struct {
+	Name  string `hcl:"name" json:"name"`
+	Regex string `hcl:"regex,optional" json:"regex,omitempty"` // regular expression used to extract the value, the first capture group is used when present, when not set the complete output is used
+	Value string `json:"value,omitempty"`                      // value extracted from the output
+
+	OpenInBrowser bool `hcl:"open_in_browser,optional" json:"open_in_browser,omitempty" mapstructure:"open_in_browser"` // open the value as a URL in a browser after the resource is created
+}
@@ -15,14 +15,32 @@ type ExecRemote struct {
 	Image  *Image `hcl:"image,block" json:"image,omitempty"`      // Create a new container and exec
 	Target string `hcl:"target,optional" json:"target,omitempty"` // Attach to a running target and exec
 
+	// When the target is a Kubernetes cluster, execute the command in a pod rather than the cluster node
+	Pod *ExecPod `hcl:"pod,block" json:"pod,omitempty"`
+
 	// Either Script or Command must be specified
-	//Script    string   `hcl:"script,optional" json:"script,omitempty"` // Path to a script to execute
+	Script           string   `hcl:"script,optional" json:"script,omitempty"`                       // Path to a local script which is copied to the container and executed
 	Command          string   `hcl:"cmd,optional" json:"cmd,omitempty"`                             // Command to execute
-	Arguments        []string `hcl:"args,optional" json:"args,omitempty"`                           // only used when combined with Command
+	Arguments        []string `hcl:"args,optional" json:"args,omitempty"`                           // arguments passed to the Command or Script
 	WorkingDirectory string   `hcl:"working_directory,optional" json:"working_directory,omitempty"` // Working directory to exectute commands
 
 	Volumes     []Volume `hcl:"volume,block" json:"volumes,omitempty"` // Volumes to mount to container
 	Environment []KV     `hcl:"env,block" json:"env,omitempty"`        // Environment varialbes to set
+
+	Outputs []ExecOutput `hcl:"output,block" json:"outputs,omitempty"` // Values to capture from the output of the command
+
+	DisableBrowser bool `hcl:"disable_browser,optional" json:"disable_browser,omitempty" mapstructure:"disable_browser"` // do not open browser windows for this resource after it is created
+
+	OnDestroy *ExecDestroy `hcl:"on_destroy,block" json:"on_destroy,omitempty" mapstructure:"on_destroy"` // Command to run when the resource is destroyed
+
+	Timeout string `hcl:"timeout,optional" json:"timeout,omitempty"` // maximum time to run the command including pulling the image e.g. 5m
+}
+
+// ExecPod defines the pod in a Kubernetes cluster to execute a command in
+type ExecPod struct {
+	Selector  string `hcl:"selector" json:"selector"`                      // label selector used to find the pod, the first running pod is used
+	Namespace string `hcl:"namespace,optional" json:"namespace,omitempty"` // namespace for the pod, defaults to default
+	Container string `hcl:"container,optional" json:"container,omitempty"` // container in the pod, only required for pods with multiple containers
 }
 
 // NewExecRemote creates a ExecRemote resorurce with the detault values
@@ -33,6 +33,8 @@ func TestExecRemoteCreatesCorrectly(t *testing.T) {
 
 	assert.Equal(t, "hashicorp/vault:latest", ex.(*ExecRemote).Image.Name)
 
+	assert.Equal(t, dir+"/scripts/setup_vault.sh", ex.(*ExecRemote).Script)
+
 	assert.Len(t, ex.(*ExecRemote).Volumes, 1)
 	assert.Equal(t, dir+"/scripts", ex.(*ExecRemote).Volumes[0].Source)
 }
@@ -50,7 +52,7 @@ exec_remote "setup_vault" {
 	  name = "network.cloud"
 	}
 
-	cmd = "/scripts/setup_vault.sh"
+	script = "./scripts/setup_vault.sh"
 
   volume {
 	  source = "./scripts"
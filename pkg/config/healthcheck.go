@@ -8,6 +8,7 @@ package config
 //    services 		= ["consul-consul"]                                              // does service exist and there are endpoints
 //    pods     		= ["component=server,app=consul", "component=client,app=consul"] // is the pod running and healthy
 //    nomad_jobs = ["redis"] 																										   // are the Nomad jobs running and healthy
+//    crds     		= ["certificates.cert-manager.io"]                               // are the CustomResourceDefinitions established
 type HealthCheck struct {
 	Timeout   string   `hcl:"timeout" json:"timeout"`
 	HTTP      string   `hcl:"http,optional" json:"http,omitempty"`
@@ -15,4 +16,50 @@ type HealthCheck struct {
 	Services  []string `hcl:"services,optional" json:"services,omitempty"`
 	Pods      []string `hcl:"pods,optional" json:"pods,omitempty"`
 	NomadJobs []string `hcl:"nomad_jobs,optional" json:"nomad_jobs,omitempty" mapstructure:"nomad_jobs"`
+	CRDs      []string `hcl:"crds,optional" json:"crds,omitempty"`
+
+	HTTPOptions *HTTPHealthCheck   `hcl:"http_options,block" json:"http_options,omitempty" mapstructure:"http_options"`
+	Consul      *ConsulHealthCheck `hcl:"consul,block" json:"consul,omitempty"`
+}
+
+// HTTPHealthCheck defines the optional settings for a HTTP health check
+// example config:
+//    http_options {
+//      success_codes   = [200, 429]       // status codes which indicate a healthy endpoint, default 200
+//      request_timeout = "5s"             // timeout for each request
+//      backoff         = "1s"             // initial interval between attempts
+//      max_backoff     = "10s"            // interval is doubled after each failure up to max_backoff
+//      ca_cert         = "./certs/ca.pem" // PEM encoded CA bundle used to verify the endpoint
+//      insecure        = false            // skip TLS verification
+//      body            = "leader"         // regular expression which must match the response body
+//      json_path       = {                // JSONPath expressions which must equal the given value
+//        "{.status}" = "ok"
+//      }
+//      cert_valid_for  = "24h"            // minimum time before the TLS certificate expires
+//    }
+type HTTPHealthCheck struct {
+	SuccessCodes   []int  `hcl:"success_codes,optional" json:"success_codes,omitempty" mapstructure:"success_codes"`
+	RequestTimeout string `hcl:"request_timeout,optional" json:"request_timeout,omitempty" mapstructure:"request_timeout"`
+	Backoff        string `hcl:"backoff,optional" json:"backoff,omitempty"`
+	MaxBackoff     string `hcl:"max_backoff,optional" json:"max_backoff,omitempty" mapstructure:"max_backoff"`
+	CACert         string `hcl:"ca_cert,optional" json:"ca_cert,omitempty" mapstructure:"ca_cert"`
+	Insecure       bool   `hcl:"insecure,optional" json:"insecure,omitempty"`
+
+	Body         string            `hcl:"body,optional" json:"body,omitempty"`
+	JSONPath     map[string]string `hcl:"json_path,optional" json:"json_path,omitempty" mapstructure:"json_path"`
+	CertValidFor string            `hcl:"cert_valid_for,optional" json:"cert_valid_for,omitempty" mapstructure:"cert_valid_for"`
+}
+
+// ConsulHealthCheck checks that services are registered in Consul and
+// have at least one instance with all health checks passing
+// example config:
+//    consul {
+//      address  = "http://localhost:8500" // address of the Consul HTTP API
+//      services = ["web", "api"]          // services which must be healthy
+//      token    = "..."                   // optional ACL token
+//    }
+type ConsulHealthCheck struct {
+	Address  string   `hcl:"address" json:"address"`
+	Services []string `hcl:"services" json:"services"`
+	Token    string   `hcl:"token,optional" json:"token,omitempty"`
 }
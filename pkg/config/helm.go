@@ -12,12 +12,16 @@ type Helm struct {
 	Cluster      string            `hcl:"cluster" json:"cluster"`
 	Chart        string            `hcl:"chart" json:"chart"`
 	Values       string            `hcl:"values,optional" json:"values"`
-	ValuesString map[string]string `hcl:"values_string,optional" json:"values_string"`
+	ValuesString map[string]string `hcl:"values_string,optional" json:"values_string" mapstructure:"values_string"`
 
 	// Namespace is the Kubernetes namespace
 	Namespace string `hcl:"namespace,optional" json:"namespace,omitempty"`
 
 	HealthCheck *HealthCheck `hcl:"health_check,block" json:"health_check,omitempty" mapstructure:"health_check"`
+
+	Retry *Retry `hcl:"retry,block" json:"retry,omitempty"` // install the chart again when it fails
+
+	Timeout string `hcl:"timeout,optional" json:"timeout,omitempty"` // maximum time to install the chart including health checks and retries e.g. 10m
 }
 
 // NewHelm creates a new Helm resource with the correct detaults
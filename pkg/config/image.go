@@ -8,4 +8,19 @@ type Image struct {
 	Username string `hcl:"username,optional" json:"username,omitempty"`
 	// Password is the Docker registry password to use for private repositories
 	Password string `hcl:"password,optional" json:"password,omitempty"`
+	// Platform overrides the platform pulled for multi-arch images e.g. linux/amd64,
+	// when the platform does not match the Docker engine the container runs using emulation
+	Platform string `hcl:"platform,optional" json:"platform,omitempty"`
+}
+
+// ImageInfo describes an image in the local Docker cache
+type ImageInfo struct {
+	Name         string            `json:"name"`
+	ID           string            `json:"id"`               // content addressable ID of the image config
+	Digest       string            `json:"digest,omitempty"` // registry digest of the manifest, empty for images built locally
+	Architecture string            `json:"architecture"`
+	OS           string            `json:"os"`
+	Size         int64             `json:"size"`
+	Created      string            `json:"created"`
+	Labels       map[string]string `json:"labels,omitempty"`
 }
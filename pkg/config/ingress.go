@@ -20,6 +20,8 @@ type Ingress struct {
 	Service   string `hcl:"service,optional" json:"service,omitempty"`
 	Namespace string `hcl:"namespace,optional" json:"namespace,omitempty"`
 	Ports     []Port `hcl:"port,block" json:"ports,omitempty"`
+
+	DisableBrowser bool `hcl:"disable_browser,optional" json:"disable_browser,omitempty" mapstructure:"disable_browser"` // do not open browser windows for this resource after it is created
 }
 
 // NewIngress creates a new ingress with the correct defaults
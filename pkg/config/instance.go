@@ -0,0 +1,236 @@
+package config
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+)
+
+// InstanceOptions define how a copy of a config is isolated from the other
+// copies of the same config
+type InstanceOptions struct {
+	// Prefix is added to the name of every resource
+	Prefix string
+	// Index is the number of the instance starting from 0, subnets are offset
+	// by the index multiplied by the size of the subnet
+	Index int
+	// PortOffset is added to every host port
+	PortOffset int
+}
+
+// Instance modifies the resources in the config so that it can run alongside
+// other instances of the same config. Resources are renamed with the prefix and
+// references to the resources, including the FQDNs of containers, are updated.
+// Host ports are offset, network subnets and static IP addresses are moved to
+// a separate range, and named volumes are prefixed. External networks are shared
+// between instances and are not modified.
+func (c *Config) Instance(o InstanceOptions) error {
+	renames := map[string]string{}
+	fqdns := map[string]string{}
+	subnets := map[string][][2]*net.IPNet{}
+
+	for _, r := range c.Resources {
+		if n, ok := r.(*Network); ok && n.External {
+			continue
+		}
+
+		name := fmt.Sprintf("%s-%s", o.Prefix, r.Info().Name)
+		renames[fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name)] = fmt.Sprintf("%s.%s", r.Info().Type, name)
+		fqdns[utils.FQDN(r.Info().Name, string(r.Info().Type))] = utils.FQDN(name, string(r.Info().Type))
+	}
+
+	for _, r := range c.Resources {
+		n, ok := r.(*Network)
+		if !ok || n.External {
+			continue
+		}
+
+		for _, s := range []*string{&n.Subnet, &n.SubnetIPv6} {
+			if *s == "" {
+				continue
+			}
+
+			old, moved, err := offsetSubnet(*s, o.Index)
+			if err != nil {
+				return fmt.Errorf("unable to offset subnet for network %s: %s", n.Name, err)
+			}
+
+			ref := fmt.Sprintf("%s.%s", TypeNetwork, n.Name)
+			subnets[ref] = append(subnets[ref], [2]*net.IPNet{old, moved})
+			*s = moved.String()
+		}
+	}
+
+	for _, r := range c.Resources {
+		if n, ok := r.(*Network); ok && n.External {
+			continue
+		}
+
+		// static addresses must move with the network subnet, this must be done
+		// before the network references are renamed
+		if co, ok := r.(*Container); ok {
+			for i, na := range co.Networks {
+				for _, s := range subnets[na.Name] {
+					co.Networks[i].IPAddress = offsetIP(co.Networks[i].IPAddress, s[0], s[1])
+					co.Networks[i].IPv6Address = offsetIP(co.Networks[i].IPv6Address, s[0], s[1])
+				}
+			}
+		}
+
+		err := instanceValue(reflect.ValueOf(r).Elem(), o, renames, fqdns)
+		if err != nil {
+			return fmt.Errorf("unable to update %s.%s: %s", r.Info().Type, r.Info().Name, err)
+		}
+
+		if k, ok := r.(*K8sCluster); ok && k.APIPort > 0 {
+			k.APIPort += o.PortOffset
+		}
+
+		r.Info().Name = fmt.Sprintf("%s-%s", o.Prefix, r.Info().Name)
+	}
+
+	return nil
+}
+
+// instanceValue walks the fields of a resource updating references, FQDNs,
+// host ports, and named volumes
+func instanceValue(v reflect.Value, o InstanceOptions, renames, fqdns map[string]string) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+
+		return instanceValue(v.Elem(), o, renames, fqdns)
+
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			err := instanceValue(v.Index(i), o, renames, fqdns)
+			if err != nil {
+				return err
+			}
+		}
+
+	case reflect.String:
+		if !v.CanSet() {
+			return nil
+		}
+
+		if n, ok := renames[v.String()]; ok {
+			v.SetString(n)
+			return nil
+		}
+
+		v.SetString(replaceFQDNs(v.String(), fqdns))
+
+	case reflect.Struct:
+		switch t := v.Addr().Interface().(type) {
+		case *Port:
+			if t.Host != "" {
+				p, err := strconv.Atoi(t.Host)
+				if err != nil {
+					return fmt.Errorf("invalid host port %s", t.Host)
+				}
+
+				t.Host = strconv.Itoa(p + o.PortOffset)
+			}
+
+			return nil
+
+		case *Volume:
+			if t.Type == "volume" {
+				t.Source = fmt.Sprintf("%s-%s", o.Prefix, t.Source)
+			}
+
+			return nil
+
+		case *ResourceInfo:
+			// the name is changed after the resource has been processed
+			return instanceValue(v.FieldByName("DependsOn"), o, renames, fqdns)
+		}
+
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				continue
+			}
+
+			err := instanceValue(v.Field(i), o, renames, fqdns)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+var fqdnPattern = regexp.MustCompile(`[a-zA-Z0-9-]+\.[a-z_]+\.shipyard\.run`)
+
+// replaceFQDNs replaces the FQDNs of renamed resources in s, matches are replaced
+// whole so that a name which is a suffix of another name is not replaced
+func replaceFQDNs(s string, fqdns map[string]string) string {
+	return fqdnPattern.ReplaceAllStringFunc(s, func(m string) string {
+		if n, ok := fqdns[m]; ok {
+			return n
+		}
+
+		return m
+	})
+}
+
+// offsetSubnet moves the subnet by index multiplied by the size of the subnet
+// e.g. 10.5.0.0/16 with index 2 returns 10.7.0.0/16
+func offsetSubnet(subnet string, index int) (*net.IPNet, *net.IPNet, error) {
+	_, cidr, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ones, bits := cidr.Mask.Size()
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+
+	base := new(big.Int).SetBytes(cidr.IP)
+	base.Add(base, size.Mul(size, big.NewInt(int64(index))))
+
+	ip := bigToIP(base, len(cidr.IP))
+	if ip == nil {
+		return nil, nil, fmt.Errorf("subnet %s can not be offset %d times", subnet, index)
+	}
+
+	return cidr, &net.IPNet{IP: ip, Mask: cidr.Mask}, nil
+}
+
+// offsetIP moves an address in the old subnet to the same position in the new subnet,
+// addresses which are not in the old subnet are returned unchanged
+func offsetIP(addr string, old, moved *net.IPNet) string {
+	ip := net.ParseIP(addr)
+	if ip == nil || !old.Contains(ip) {
+		return addr
+	}
+
+	if v4 := ip.To4(); v4 != nil && len(old.IP) == net.IPv4len {
+		ip = v4
+	}
+
+	pos := new(big.Int).Sub(new(big.Int).SetBytes(ip), new(big.Int).SetBytes(old.IP))
+	n := bigToIP(pos.Add(pos, new(big.Int).SetBytes(moved.IP)), len(moved.IP))
+
+	return n.String()
+}
+
+func bigToIP(i *big.Int, length int) net.IP {
+	b := i.Bytes()
+	if len(b) > length {
+		return nil
+	}
+
+	ip := make(net.IP, length)
+	copy(ip[length-len(b):], b)
+
+	return ip
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupInstance(t *testing.T, index int) (*Config, func()) {
+	c, _, cleanup := setupTestConfig(t, instanceConfig)
+
+	// the parser makes all volume sources absolute
+	co, _ := c.FindResource("container.consul")
+	co.(*Container).Volumes[0].Source = "data"
+
+	err := c.Instance(InstanceOptions{Prefix: "student-2", Index: index, PortOffset: 200})
+	assert.NoError(t, err)
+
+	return c, cleanup
+}
+
+func TestInstanceRenamesResourcesAndReferences(t *testing.T) {
+	c, cleanup := setupInstance(t, 2)
+	defer cleanup()
+
+	co, err := c.FindResource("container.student-2-consul")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "network.student-2-cloud", co.(*Container).Networks[0].Name)
+	assert.Contains(t, co.Info().DependsOn, "network.student-2-cloud")
+
+	i, err := c.FindResource("container_ingress.student-2-consul-http")
+	assert.NoError(t, err)
+	assert.Equal(t, "container.student-2-consul", i.(*ContainerIngress).Target)
+}
+
+func TestInstanceReplacesFQDNs(t *testing.T) {
+	c, cleanup := setupInstance(t, 2)
+	defer cleanup()
+
+	co, _ := c.FindResource("container.student-2-web")
+	env := co.(*Container).Environment
+
+	assert.Equal(t, "http://student-2-consul.container.shipyard.run:8500", env[0].Value)
+	assert.Equal(t, "http://my-consul.container.shipyard.run:8500", env[1].Value)
+}
+
+func TestInstanceOffsetsPortsSubnetsAndAddresses(t *testing.T) {
+	c, cleanup := setupInstance(t, 2)
+	defer cleanup()
+
+	n, _ := c.FindResource("network.student-2-cloud")
+	assert.Equal(t, "10.7.0.0/16", n.(*Network).Subnet)
+
+	co, _ := c.FindResource("container.student-2-consul")
+	assert.Equal(t, "10.7.0.200", co.(*Container).Networks[0].IPAddress)
+	assert.Equal(t, "18700", co.(*Container).Ports[0].Host)
+	assert.Equal(t, "8500", co.(*Container).Ports[0].Local)
+	assert.Equal(t, "student-2-data", co.(*Container).Volumes[0].Source)
+}
+
+func TestInstanceDoesNotModifyExternalNetworks(t *testing.T) {
+	c, cleanup := setupInstance(t, 2)
+	defer cleanup()
+
+	n, err := c.FindResource("network.shared")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.20.0.0/16", n.(*Network).Subnet)
+
+	co, _ := c.FindResource("container.student-2-web")
+	assert.Equal(t, "network.shared", co.(*Container).Networks[0].Name)
+}
+
+func TestOffsetSubnetReturnsErrorWhenOutOfRange(t *testing.T) {
+	_, _, err := offsetSubnet("255.0.0.0/8", 2)
+	assert.Error(t, err)
+}
+
+var instanceConfig = `
+network "cloud" {
+  subnet = "10.5.0.0/16"
+}
+
+network "shared" {
+  subnet   = "10.20.0.0/16"
+  external = true
+}
+
+container "consul" {
+  image {
+    name = "consul:1.8.1"
+  }
+
+  network {
+    name       = "network.cloud"
+    ip_address = "10.5.0.200"
+  }
+
+  port {
+    local  = "8500"
+    remote = "8500"
+    host   = "18500"
+  }
+
+  volume {
+    source      = "data"
+    destination = "/data"
+    type        = "volume"
+  }
+}
+
+container "web" {
+  image {
+    name = "nicholasjackson/fake-service:v0.9.0"
+  }
+
+  network {
+    name = "network.shared"
+  }
+
+  env {
+    key   = "UPSTREAM"
+    value = "http://consul.container.shipyard.run:8500"
+  }
+
+  env {
+    key   = "OTHER"
+    value = "http://my-consul.container.shipyard.run:8500"
+  }
+}
+
+container_ingress "consul-http" {
+  target = "container.consul"
+
+  port {
+    local  = "8500"
+    remote = "8500"
+    host   = "18501"
+  }
+}
+`
@@ -16,6 +16,68 @@ type K8sCluster struct {
 	Version string  `hcl:"version,optional" json:"version,omitempty"`
 	Nodes   int     `hcl:"nodes,optional" json:"nodes,omitempty"`
 	Images  []Image `hcl:"image,block" json:"images,omitempty"`
+
+	APIPort         int  `hcl:"api_port,optional" json:"api_port,omitempty" mapstructure:"api_port"`                         // host port for the API server, when not set a random port is used
+	MergeKubeConfig bool `hcl:"merge_kubeconfig,optional" json:"merge_kubeconfig,omitempty" mapstructure:"merge_kubeconfig"` // merge the clusters config into $KUBECONFIG or ~/.kube/config
+	PersistData     bool `hcl:"persist_data,optional" json:"persist_data,omitempty" mapstructure:"persist_data"`             // store the data for each node on a volume which is not removed on destroy
+
+	// resource constraints applied to each node in the cluster
+	Resources *Resources `hcl:"resources,block" json:"resources,omitempty"`
+
+	// resources, Kubernetes labels, and taints for individual nodes
+	NodeConfig []Node `hcl:"node,block" json:"node_config,omitempty" mapstructure:"node_config"`
+
+	ServerArgs   []string `hcl:"server_args,optional" json:"server_args,omitempty" mapstructure:"server_args"`       // additional arguments passed to the k3s server e.g. --no-deploy=servicelb
+	AgentArgs    []string `hcl:"agent_args,optional" json:"agent_args,omitempty" mapstructure:"agent_args"`          // additional arguments passed to the k3s agent e.g. --kubelet-arg=max-pods=200
+	FeatureGates []string `hcl:"feature_gates,optional" json:"feature_gates,omitempty" mapstructure:"feature_gates"` // Kubernetes feature gates to enable e.g. EphemeralContainers=true
+
+	// container registry mirrors and authentication used by the nodes when pulling images
+	Registries []Registry `hcl:"registry,block" json:"registries,omitempty"`
+
+	// pull images from the Docker Hub through a cache which is shared by all clusters,
+	// cached images are not removed when the cluster is destroyed
+	ImageCache bool `hcl:"image_cache,optional" json:"image_cache,omitempty" mapstructure:"image_cache"`
+
+	// create the cluster again when it fails to start
+	Retry *Retry `hcl:"retry,block" json:"retry,omitempty"`
+
+	// conditions which must pass before resources which depend on the cluster are created
+	HealthCheck *HealthCheck `hcl:"health_check,block" json:"health_check,omitempty" mapstructure:"health_check"`
+
+	// maximum time to create the cluster including health checks and retries e.g. 10m
+	Timeout string `hcl:"timeout,optional" json:"timeout,omitempty"`
+
+	// outputs set once the cluster has been created
+	KubeConfig  string `json:"kubeconfig,omitempty"`                               // path of the Shipyard managed Kubernetes config containing the context for the cluster
+	KubeContext string `json:"kube_context,omitempty" mapstructure:"kube_context"` // name of the context for the cluster e.g. shipyard-[blueprint]-[cluster]
+}
+
+// Registry defines the configuration the cluster nodes use to pull images from a container registry
+type Registry struct {
+	Name     string   `hcl:"name" json:"name"`                          // hostname of the registry e.g. docker.io
+	Mirrors  []string `hcl:"mirrors,optional" json:"mirrors,omitempty"` // mirror endpoints to use instead of the registry e.g. https://mirror.corp.com
+	Username string   `hcl:"username,optional" json:"username,omitempty"`
+	Password string   `hcl:"password,optional" json:"password,omitempty"`
+	Insecure bool     `hcl:"insecure,optional" json:"insecure,omitempty"` // skip TLS verification for the registry and its mirrors
+}
+
+// Node defines the configuration for a single node in a cluster, the first
+// node is named server and the additional nodes agent-1, agent-2, etc.
+type Node struct {
+	Name string `hcl:"name,label" json:"name"`
+
+	// resource constraints for the node, replaces the resources set for the cluster
+	Resources *Resources `hcl:"resources,block" json:"resources,omitempty"`
+
+	Labels []KV    `hcl:"label,block" json:"labels,omitempty"` // Kubernetes labels added to the node
+	Taints []Taint `hcl:"taint,block" json:"taints,omitempty"` // Kubernetes taints added to the node
+}
+
+// Taint defines a Kubernetes taint which is added to a node in a cluster
+type Taint struct {
+	Key    string `hcl:"key" json:"key"`
+	Value  string `hcl:"value,optional" json:"value,omitempty"`
+	Effect string `hcl:"effect,optional" json:"effect,omitempty"` // NoSchedule, PreferNoSchedule, or NoExecute, defaults to NoSchedule
 }
 
 // NewK8sCluster creates new Cluster config with the correct defaults
@@ -18,6 +18,53 @@ func TestK8sClusterCreatesCorrectly(t *testing.T) {
 	assert.Equal(t, PendingCreation, cl.Info().Status)
 }
 
+func TestK8sClusterCreatesNodeConfig(t *testing.T) {
+	c, _, cleanup := setupTestConfig(t, clusterNodeConfig)
+	defer cleanup()
+
+	cl, err := c.FindResource("k8s_cluster.testing")
+	assert.NoError(t, err)
+
+	k8s := cl.(*K8sCluster)
+	assert.Equal(t, 2048, k8s.Resources.CPU)
+	assert.Equal(t, 1024, k8s.Resources.Memory)
+	assert.Len(t, k8s.NodeConfig, 2)
+
+	assert.Equal(t, "server", k8s.NodeConfig[0].Name)
+	assert.Equal(t, "zone", k8s.NodeConfig[0].Labels[0].Key)
+	assert.Equal(t, "west", k8s.NodeConfig[0].Labels[0].Value)
+	assert.Nil(t, k8s.NodeConfig[0].Resources)
+
+	assert.Equal(t, "agent-1", k8s.NodeConfig[1].Name)
+	assert.Equal(t, 512, k8s.NodeConfig[1].Resources.Memory)
+	assert.Equal(t, "dedicated", k8s.NodeConfig[1].Taints[0].Key)
+	assert.Equal(t, "gpu", k8s.NodeConfig[1].Taints[0].Value)
+	assert.Equal(t, "NoExecute", k8s.NodeConfig[1].Taints[0].Effect)
+	assert.Equal(t, 6443, k8s.APIPort)
+	assert.True(t, k8s.MergeKubeConfig)
+	assert.True(t, k8s.PersistData)
+	assert.True(t, k8s.ImageCache)
+	assert.Equal(t, []string{"--no-deploy=servicelb"}, k8s.ServerArgs)
+	assert.Equal(t, []string{"--kubelet-arg=max-pods=200"}, k8s.AgentArgs)
+	assert.Equal(t, []string{"EphemeralContainers=true"}, k8s.FeatureGates)
+	assert.Equal(t, "docker.io", k8s.Registries[0].Name)
+	assert.Equal(t, []string{"https://mirror.corp.com"}, k8s.Registries[0].Mirrors)
+	assert.True(t, k8s.Registries[0].Insecure)
+}
+
+func TestK8sClusterParsesHealthCheck(t *testing.T) {
+	c, _, cleanup := setupTestConfig(t, clusterHealthCheck)
+	defer cleanup()
+
+	cl, err := c.FindResource("k8s_cluster.testing")
+	assert.NoError(t, err)
+
+	hc := cl.(*K8sCluster).HealthCheck
+	assert.Equal(t, "120s", hc.Timeout)
+	assert.Equal(t, "localhost:6443", hc.TCP)
+	assert.Equal(t, []string{"k8s-app=metrics-server"}, hc.Pods)
+}
+
 const clusterDefault = `
 k8s_cluster "testing" {
 	network {
@@ -26,3 +73,66 @@ k8s_cluster "testing" {
 	driver = "k3s"
 }
 `
+
+const clusterHealthCheck = `
+k8s_cluster "testing" {
+	network {
+		name = "network.test"
+	}
+	driver = "k3s"
+
+	health_check {
+		timeout = "120s"
+		tcp = "localhost:6443"
+		pods = ["k8s-app=metrics-server"]
+	}
+}
+`
+
+const clusterNodeConfig = `
+k8s_cluster "testing" {
+	network {
+		name = "network.test"
+	}
+	driver = "k3s"
+
+	api_port = 6443
+	merge_kubeconfig = true
+	persist_data = true
+	image_cache = true
+
+	server_args = ["--no-deploy=servicelb"]
+	agent_args = ["--kubelet-arg=max-pods=200"]
+	feature_gates = ["EphemeralContainers=true"]
+
+	resources {
+		cpu = 2048
+		memory = 1024
+	}
+
+	node "server" {
+		label {
+			key = "zone"
+			value = "west"
+		}
+	}
+
+	node "agent-1" {
+		resources {
+			memory = 512
+		}
+
+		taint {
+			key = "dedicated"
+			value = "gpu"
+			effect = "NoExecute"
+		}
+	}
+
+	registry {
+		name = "docker.io"
+		mirrors = ["https://mirror.corp.com"]
+		insecure = true
+	}
+}
+`
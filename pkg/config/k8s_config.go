@@ -18,6 +18,22 @@ type K8sConfig struct {
 
 	// HealthCheck defines a health check for the resource
 	HealthCheck *HealthCheck `hcl:"health_check,block" json:"health_check,omitempty" mapstructure:"health_check"`
+
+	// Timeout is the maximum time to apply the config including health checks e.g. 5m
+	Timeout string `hcl:"timeout,optional" json:"timeout,omitempty"`
+
+	// Inventory is the list of Kubernetes objects created by the last apply,
+	// this is stored in the state and is used to prune objects which have been
+	// removed from the config files when the resource is re-applied
+	Inventory []K8sObject `json:"inventory,omitempty"`
+}
+
+// K8sObject is a reference to an object in a Kubernetes cluster
+type K8sObject struct {
+	APIVersion string `json:"api_version" mapstructure:"api_version"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
 }
 
 // NewK8sConfig creates a kubernetes config resource with the correct defaults
@@ -31,6 +31,16 @@ func TestMakesPathAbsolute(t *testing.T) {
 	assert.Contains(t, kc.(*K8sConfig).Paths[1], base)
 }
 
+func TestDoesNotMakeRemotePathAbsolute(t *testing.T) {
+	c, _, cleanup := setupTestConfig(t, k8sConfigValid)
+	defer cleanup()
+
+	kc, err := c.FindResource("k8s_config.test")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "https://example.com/manifests/app.yaml", kc.(*K8sConfig).Paths[2])
+}
+
 var k8sConfigValid = `
 k8s_cluster "cloud" {
   driver  = "k3s" // default
@@ -45,7 +55,7 @@ k8s_cluster "cloud" {
 
 k8s_config "test" {
 	cluster = "cluster.cloud"
-	paths = ["/tmp/files","./myfiles"]
+	paths = ["/tmp/files","./myfiles","https://example.com/manifests/app.yaml"]
 	wait_until_ready = true
 
 	health_check {
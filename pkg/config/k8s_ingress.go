@@ -36,6 +36,8 @@ type K8sIngress struct {
 	Namespace string `hcl:"namespace,optional" json:"namespace,omitempty"`
 
 	Ports []Port `hcl:"port,block" json:"ports,omitempty"`
+
+	DisableBrowser bool `hcl:"disable_browser,optional" json:"disable_browser,omitempty" mapstructure:"disable_browser"` // do not open browser windows for this resource after it is created
 }
 
 // NewK8sIngress creates a new ingress with the correct defaults
@@ -7,7 +7,32 @@ const TypeNetwork ResourceType = "network"
 type Network struct {
 	ResourceInfo
 
-	Subnet string `hcl:"subnet" json:"subnet"`
+	Subnet     string `hcl:"subnet,optional" json:"subnet"`                                                // required unless the network is external
+	SubnetIPv6 string `hcl:"subnet_ipv6,optional" json:"subnet_ipv6,omitempty" mapstructure:"subnet_ipv6"` // optional IPv6 subnet, when set the network is dual stack
+
+	// External networks are existing Docker networks which are not managed by
+	// Shipyard, they are not created on apply or removed on destroy
+	External bool `hcl:"external,optional" json:"external,omitempty"`
+
+	// Driver is the Docker network driver, bridge or overlay, default bridge.
+	// Overlay networks span multiple Docker hosts which are members of the same swarm
+	Driver string `hcl:"driver,optional" json:"driver,omitempty"`
+
+	// DockerHost is the docker_host resource to create the network on, default local
+	DockerHost string `hcl:"docker_host,optional" json:"docker_host,omitempty" mapstructure:"docker_host"`
+
+	// Impairment applies traffic shaping to every container attached to the
+	// network, individual attachments can override this
+	Impairment *NetworkImpairment `hcl:"impairment,block" json:"impairment,omitempty"`
+}
+
+// NetworkImpairment defines traffic shaping which is applied to a containers
+// network interface, this allows the simulation of WAN conditions between services
+type NetworkImpairment struct {
+	Latency    string  `hcl:"latency,optional" json:"latency,omitempty"`                                    // delay added to outbound packets e.g. 100ms
+	Jitter     string  `hcl:"jitter,optional" json:"jitter,omitempty"`                                      // variation in the delay e.g. 10ms, requires latency
+	PacketLoss float64 `hcl:"packet_loss,optional" json:"packet_loss,omitempty" mapstructure:"packet_loss"` // percentage of packets to drop e.g. 0.5
+	Bandwidth  string  `hcl:"bandwidth,optional" json:"bandwidth,omitempty"`                                // maximum rate e.g. 1mbit
 }
 
 // NewNetwork creates a new Network resource with the correct defaults
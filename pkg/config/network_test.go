@@ -18,8 +18,52 @@ func TestNetworkCreatesCorrectly(t *testing.T) {
 	assert.Equal(t, PendingCreation, cl.Info().Status)
 }
 
+func TestNetworkExternalCreatesCorrectly(t *testing.T) {
+	c, _, cleanup := setupTestConfig(t, networkExternal)
+	defer cleanup()
+
+	cl, err := c.FindResource("network.test")
+	assert.NoError(t, err)
+
+	assert.True(t, cl.(*Network).External)
+	assert.Equal(t, "", cl.(*Network).Subnet)
+}
+
+func TestNetworkImpairmentCreatesCorrectly(t *testing.T) {
+	c, _, cleanup := setupTestConfig(t, networkImpairment)
+	defer cleanup()
+
+	cl, err := c.FindResource("network.test")
+	assert.NoError(t, err)
+
+	i := cl.(*Network).Impairment
+	assert.Equal(t, "100ms", i.Latency)
+	assert.Equal(t, "10ms", i.Jitter)
+	assert.Equal(t, 0.5, i.PacketLoss)
+	assert.Equal(t, "1mbit", i.Bandwidth)
+}
+
 const networkDefault = `
 network "test" {
 	subnet = "10.0.0.0/24"
 }
 `
+
+const networkExternal = `
+network "test" {
+	external = true
+}
+`
+
+const networkImpairment = `
+network "test" {
+	subnet = "10.0.0.0/24"
+
+	impairment {
+		latency = "100ms"
+		jitter = "10ms"
+		packet_loss = 0.5
+		bandwidth = "1mbit"
+	}
+}
+`
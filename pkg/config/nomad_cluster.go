@@ -18,6 +18,15 @@ type NomadCluster struct {
 	Environment []KV     `hcl:"env,block" json:"environment,omitempty"`
 	Images      []Image  `hcl:"image,block" json:"images,omitempty"`
 	Volumes     []Volume `hcl:"volume,block" json:"volumes,omitempty"` // volumes to attach to the cluster
+
+	// resource constraints applied to each node in the cluster
+	Resources *Resources `hcl:"resources,block" json:"resources,omitempty"`
+
+	// create the cluster again when it fails to start
+	Retry *Retry `hcl:"retry,block" json:"retry,omitempty"`
+
+	// maximum time to create the cluster including retries e.g. 10m
+	Timeout string `hcl:"timeout,optional" json:"timeout,omitempty"`
 }
 
 // NewCluster creates new Cluster config with the correct defaults
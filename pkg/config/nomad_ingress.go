@@ -18,6 +18,8 @@ type NomadIngress struct {
 	Task  string `hcl:"task" json:"task"`
 
 	Ports []Port `hcl:"port,block" json:"ports,omitempty"`
+
+	DisableBrowser bool `hcl:"disable_browser,optional" json:"disable_browser,omitempty" mapstructure:"disable_browser"` // do not open browser windows for this resource after it is created
 }
 
 // NewNomadIngress creates a new ingress with the correct defaults
@@ -16,6 +16,29 @@ type NomadJob struct {
 
 	// HealthCheck defines a health check for the resource
 	HealthCheck *HealthCheck `hcl:"health_check,block" json:"health_check,omitempty" mapstructure:"health_check"`
+
+	// Timeout is the maximum time to create the jobs including health checks e.g. 5m
+	Timeout string `hcl:"timeout,optional" json:"timeout,omitempty"`
+}
+
+// NomadAllocation is an allocation of a task group for a Nomad job as
+// returned by the Nomad API
+type NomadAllocation struct {
+	ID            string                    `json:"ID"`
+	Name          string                    `json:"Name"`
+	NodeID        string                    `json:"NodeID"`
+	JobID         string                    `json:"JobID"`
+	TaskGroup     string                    `json:"TaskGroup"`
+	ClientStatus  string                    `json:"ClientStatus"` // pending, running, complete, failed, or lost
+	DesiredStatus string                    `json:"DesiredStatus"`
+	TaskStates    map[string]NomadTaskState `json:"TaskStates"` // keyed by the name of the task
+}
+
+// NomadTaskState is the state of a task in an allocation
+type NomadTaskState struct {
+	State    string `json:"State"` // pending, running, or dead
+	Failed   bool   `json:"Failed"`
+	Restarts int    `json:"Restarts"`
 }
 
 // NewNomadJob creates a kubernetes config resource with the correct defaults
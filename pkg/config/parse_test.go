@@ -1,11 +1,14 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
 )
 
 func setup() func() {
@@ -50,6 +53,100 @@ func TestRunParsesBlueprintInHCLFormat(t *testing.T) {
 	assert.NotNil(t, c.Blueprint)
 }
 
+func TestParseModuleAddsModuleDependenciesToResources(t *testing.T) {
+	dir, cleanup := createTestFiles(t)
+	defer cleanup()
+
+	mod := filepath.Join(dir, "consul")
+	os.MkdirAll(mod, os.ModePerm)
+
+	createNamedFile(t, mod, "*.hcl", moduleContainer)
+	createNamedFile(t, dir, "*.hcl", fmt.Sprintf(moduleConfig, mod))
+
+	c := New()
+	err := ParseFolder(dir, c)
+	assert.NoError(t, err)
+
+	err = ParseReferences(c)
+	assert.NoError(t, err)
+
+	r, err := c.FindResource("container.consul")
+	assert.NoError(t, err)
+	assert.Contains(t, r.Info().DependsOn, "exec_local.setup")
+
+	// the module dependency is created before the module resources
+	g, err := c.DoYaLikeDAGs()
+	assert.NoError(t, err)
+
+	dep, _ := c.FindResource("exec_local.setup")
+	s, err := g.Ancestors(dep)
+	assert.NoError(t, err)
+	assert.Contains(t, s.List(), r)
+}
+
+func TestParseRemoteModuleWhenOfflineUsesDownloadedFolder(t *testing.T) {
+	dir, cleanup := createTestFiles(t)
+	defer cleanup()
+
+	home := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", home)
+
+	src := "github.com/shipyard-run/blueprints//modules/consul"
+	mod := utils.GetBlueprintLocalFolder(src)
+	os.MkdirAll(mod, os.ModePerm)
+
+	createNamedFile(t, mod, "*.hcl", moduleContainer)
+	createNamedFile(t, dir, "*.hcl", fmt.Sprintf(moduleConfig, src))
+
+	c := New()
+	c.Offline = true
+
+	err := ParseFolder(dir, c)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{mod}, c.RemoteModules)
+
+	_, err = c.FindResource("container.consul")
+	assert.NoError(t, err)
+}
+
+func TestParseRemoteModuleWhenOfflineAndNotDownloadedReturnsError(t *testing.T) {
+	dir, cleanup := createTestFiles(t)
+	defer cleanup()
+
+	home := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", home)
+
+	createNamedFile(t, dir, "*.hcl", fmt.Sprintf(moduleConfig, "github.com/shipyard-run/blueprints//modules/consul"))
+
+	c := New()
+	c.Offline = true
+
+	err := ParseFolder(dir, c)
+	assert.True(t, xerrors.Is(err, utils.OfflineError))
+}
+
+var moduleConfig = `
+exec_local "setup" {
+  cmd = "true"
+}
+
+module "consul" {
+  source     = "%s"
+  depends_on = ["exec_local.setup"]
+}
+`
+
+var moduleContainer = `
+container "consul" {
+  image {
+    name = "consul:1.8.1"
+  }
+}
+`
+
 /*
 func TestSingleKubernetesCluster(t *testing.T) {
 	absoluteFolderPath, err := filepath.Abs("./examples/single-cluster-k8s")
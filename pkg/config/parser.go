@@ -109,6 +109,11 @@ func parseYardHCL(file string, c *Config) error {
 		return errors.New(diag.Error())
 	}
 
+	err := CheckRequiredVersion(bp.RequiredVersion)
+	if err != nil {
+		return err
+	}
+
 	c.Blueprint = bp
 
 	return nil
@@ -149,6 +154,15 @@ func parseYardMarkdown(file string, c *Config) error {
 		bp.BrowserWindows = strings.Split(a, ",")
 	}
 
+	if a, ok := fr["required_version"].(string); ok {
+		bp.RequiredVersion = a
+
+		err := CheckRequiredVersion(a)
+		if err != nil {
+			return err
+		}
+	}
+
 	if envs, ok := fr["env"].([]interface{}); ok {
 		bp.Environment = []KV{}
 		for _, e := range envs {
@@ -200,9 +214,11 @@ func ParseHCLFile(file string, c *Config) error {
 				return err
 			}
 
-			// make all the paths absolute
+			// make all the local paths absolute, remote paths are fetched by the provider
 			for i, p := range h.Paths {
-				h.Paths[i] = ensureAbsolute(p, file)
+				if !utils.IsRemoteURI(p) {
+					h.Paths[i] = ensureAbsolute(p, file)
+				}
 			}
 
 			c.AddResource(h)
@@ -260,9 +276,11 @@ func ParseHCLFile(file string, c *Config) error {
 				return err
 			}
 
-			// make all the paths absolute
+			// make all the local paths absolute, remote paths are fetched by the provider
 			for i, p := range h.Paths {
-				h.Paths[i] = ensureAbsolute(p, file)
+				if !utils.IsRemoteURI(p) {
+					h.Paths[i] = ensureAbsolute(p, file)
+				}
 			}
 
 			c.AddResource(h)
@@ -277,6 +295,20 @@ func ParseHCLFile(file string, c *Config) error {
 
 			c.AddResource(i)
 
+		case string(TypeDockerHost):
+			h := NewDockerHost(b.Labels[0])
+
+			err := decodeBody(b, h)
+			if err != nil {
+				return err
+			}
+
+			if h.CertPath != "" {
+				h.CertPath = ensureAbsolute(h.CertPath, file)
+			}
+
+			c.AddResource(h)
+
 		case string(TypeNetwork):
 			n := NewNetwork(b.Labels[0])
 
@@ -311,6 +343,10 @@ func ParseHCLFile(file string, c *Config) error {
 				co.Volumes[i].Source = ensureAbsolute(v.Source, file)
 			}
 
+			if co.HealthCheck != nil && co.HealthCheck.HTTPOptions != nil && co.HealthCheck.HTTPOptions.CACert != "" {
+				co.HealthCheck.HTTPOptions.CACert = ensureAbsolute(co.HealthCheck.HTTPOptions.CACert, file)
+			}
+
 			c.AddResource(co)
 
 		case string(TypeContainerIngress):
@@ -347,6 +383,18 @@ func ParseHCLFile(file string, c *Config) error {
 
 			do.Path = ensureAbsolute(do.Path, file)
 
+			if do.Sidebars != "" {
+				do.Sidebars = ensureAbsolute(do.Sidebars, file)
+			}
+
+			if do.SiteConfig != "" {
+				do.SiteConfig = ensureAbsolute(do.SiteConfig, file)
+			}
+
+			if do.Static != "" {
+				do.Static = ensureAbsolute(do.Static, file)
+			}
+
 			c.AddResource(do)
 
 		case string(TypeExecLocal):
@@ -357,7 +405,17 @@ func ParseHCLFile(file string, c *Config) error {
 				return err
 			}
 
-			h.Script = ensureAbsolute(h.Script, file)
+			if h.Script != "" {
+				h.Script = ensureAbsolute(h.Script, file)
+			}
+
+			if h.WorkingDirectory != "" {
+				h.WorkingDirectory = ensureAbsolute(h.WorkingDirectory, file)
+			}
+
+			if h.OnDestroy != nil && h.OnDestroy.Script != "" {
+				h.OnDestroy.Script = ensureAbsolute(h.OnDestroy.Script, file)
+			}
 
 			c.AddResource(h)
 
@@ -369,11 +427,13 @@ func ParseHCLFile(file string, c *Config) error {
 				return err
 			}
 
-			/*
-				if h.Script != "" {
-					h.Script = ensureAbsolute(h.Script, file)
-				}
-			*/
+			if h.Script != "" {
+				h.Script = ensureAbsolute(h.Script, file)
+			}
+
+			if h.OnDestroy != nil && h.OnDestroy.Script != "" {
+				h.OnDestroy.Script = ensureAbsolute(h.OnDestroy.Script, file)
+			}
 
 			// process volumes
 			// make sure mount paths are absolute
@@ -395,11 +455,13 @@ func ParseHCLFile(file string, c *Config) error {
 			if !utils.IsLocalFolder(ensureAbsolute(m.Source, file)) {
 				// get the details
 				dst := utils.GetBlueprintLocalFolder(m.Source)
-				err := getFiles(m.Source, dst)
+				err := getFiles(m.Source, dst, c.Offline)
 				if err != nil {
 					return err
 				}
 
+				c.RemoteModules = append(c.RemoteModules, dst)
+
 				// set the source to the local folder
 				m.Source = dst
 			}
@@ -408,11 +470,19 @@ func ParseHCLFile(file string, c *Config) error {
 			m.Source = ensureAbsolute(m.Source, file)
 
 			// recursively parse references for the module
+			added := len(c.Resources)
+
 			err = ParseFolder(m.Source, c)
 			if err != nil {
 				return err
 			}
 
+			// the resources in the module are created after the module
+			// dependencies
+			for _, r := range c.Resources[added:] {
+				r.Info().DependsOn = append(r.Info().DependsOn, m.Depends...)
+			}
+
 		default:
 			return ResourceTypeNotExistError{string(b.Type), file}
 		}
@@ -432,6 +502,10 @@ func ParseReferences(c *Config) error {
 			}
 			c.DependsOn = append(c.DependsOn, c.Depends...)
 
+			if c.DockerHost != "" {
+				c.DependsOn = append(c.DependsOn, c.DockerHost)
+			}
+
 		case TypeContainerIngress:
 			c := r.(*ContainerIngress)
 			for _, n := range c.Networks {
@@ -440,6 +514,10 @@ func ParseReferences(c *Config) error {
 			c.DependsOn = append(c.DependsOn, c.Target)
 			c.DependsOn = append(c.DependsOn, c.Depends...)
 
+			if c.DockerHost != "" {
+				c.DependsOn = append(c.DependsOn, c.DockerHost)
+			}
+
 		case TypeSidecar:
 			c := r.(*Sidecar)
 			c.DependsOn = append(c.DependsOn, c.Target)
@@ -452,6 +530,10 @@ func ParseReferences(c *Config) error {
 			}
 			c.DependsOn = append(c.DependsOn, c.Depends...)
 
+		case TypeExecLocal:
+			c := r.(*ExecLocal)
+			c.DependsOn = append(c.DependsOn, c.Depends...)
+
 		case TypeExecRemote:
 			c := r.(*ExecRemote)
 			for _, n := range c.Networks {
@@ -517,6 +599,16 @@ func ParseReferences(c *Config) error {
 			c := r.(*NomadJob)
 			c.DependsOn = append(c.DependsOn, c.Cluster)
 			c.DependsOn = append(c.DependsOn, c.Depends...)
+
+		case TypeNetwork:
+			c := r.(*Network)
+			if c.DockerHost != "" {
+				c.DependsOn = append(c.DependsOn, c.DockerHost)
+			}
+
+		case TypeDockerHost:
+			c := r.(*DockerHost)
+			c.DependsOn = append(c.DependsOn, c.Depends...)
 		}
 	}
 
@@ -590,6 +682,9 @@ func decodeBody(b *hclsyntax.Block, p interface{}) error {
 // ensureAbsolute ensure that the given path is either absolute or
 // if relative is converted to abasolute based on the path of the config
 func ensureAbsolute(path, file string) string {
+	// paths written on Windows or WSL need converting to the local format
+	path = utils.HostPath(path)
+
 	if filepath.IsAbs(path) {
 		return path
 	}
@@ -600,7 +695,16 @@ func ensureAbsolute(path, file string) string {
 	return filepath.Join(baseDir, path)
 }
 
-func getFiles(source, dest string) error {
+func getFiles(source, dest string, offline bool) error {
+	// in offline mode only previously downloaded files can be used
+	if offline {
+		if _, err := os.Stat(dest); err == nil {
+			return nil
+		}
+
+		return xerrors.Errorf("module %s has not been downloaded, load a bundle which contains it: %w", source, utils.OfflineError)
+	}
+
 	pwd, err := os.Getwd()
 	if err != nil {
 		return err
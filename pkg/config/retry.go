@@ -0,0 +1,15 @@
+package config
+
+// Retry is an internal block which allows a resource to be created again
+// when it fails, e.g. a transient failure pulling an image
+// example config:
+//    retry {
+//      attempts    = 3     // maximum number of attempts to create the resource, including the first
+//      backoff     = "1s"  // interval before the first retry
+//      max_backoff = "10s" // interval is doubled after each failure up to max_backoff
+//    }
+type Retry struct {
+	Attempts   int    `hcl:"attempts,optional" json:"attempts,omitempty"`
+	Backoff    string `hcl:"backoff,optional" json:"backoff,omitempty"`
+	MaxBackoff string `hcl:"max_backoff,optional" json:"max_backoff,omitempty" mapstructure:"max_backoff"`
+}
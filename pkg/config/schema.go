@@ -0,0 +1,188 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// resourceTypes contains the Go type for each resource which can be defined
+// in a blueprint, used to build the schema
+var resourceTypes = map[ResourceType]reflect.Type{
+	TypeContainer:        reflect.TypeOf(Container{}),
+	TypeContainerIngress: reflect.TypeOf(ContainerIngress{}),
+	TypeDockerHost:       reflect.TypeOf(DockerHost{}),
+	TypeDocs:             reflect.TypeOf(Docs{}),
+	TypeExecLocal:        reflect.TypeOf(ExecLocal{}),
+	TypeExecRemote:       reflect.TypeOf(ExecRemote{}),
+	TypeHelm:             reflect.TypeOf(Helm{}),
+	TypeIngress:          reflect.TypeOf(Ingress{}),
+	TypeK8sCluster:       reflect.TypeOf(K8sCluster{}),
+	TypeK8sConfig:        reflect.TypeOf(K8sConfig{}),
+	TypeK8sIngress:       reflect.TypeOf(K8sIngress{}),
+	TypeModule:           reflect.TypeOf(Module{}),
+	TypeNetwork:          reflect.TypeOf(Network{}),
+	TypeNomadCluster:     reflect.TypeOf(NomadCluster{}),
+	TypeNomadIngress:     reflect.TypeOf(NomadIngress{}),
+	TypeNomadJob:         reflect.TypeOf(NomadJob{}),
+	TypeSidecar:          reflect.TypeOf(Sidecar{}),
+}
+
+// Schema describes the attributes and nested blocks which can be set in a
+// resource or block
+type Schema struct {
+	Labels     []string          `json:"labels,omitempty"` // names of the labels for the block
+	Attributes []AttributeSchema `json:"attributes"`
+	Blocks     []BlockSchema     `json:"blocks"`
+}
+
+// AttributeSchema describes a single attribute
+type AttributeSchema struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"` // HCL type of the attribute e.g. string, number, list(string)
+	Required bool   `json:"required"`
+}
+
+// BlockSchema describes a nested block
+type BlockSchema struct {
+	Name     string  `json:"name"`
+	Required bool    `json:"required"`
+	Multiple bool    `json:"multiple"` // can the block be defined more than once
+	Schema   *Schema `json:"schema"`
+}
+
+// Attribute returns the attribute with the given name or nil
+func (s *Schema) Attribute(name string) *AttributeSchema {
+	for i := range s.Attributes {
+		if s.Attributes[i].Name == name {
+			return &s.Attributes[i]
+		}
+	}
+
+	return nil
+}
+
+// Block returns the nested block with the given name or nil
+func (s *Schema) Block(name string) *BlockSchema {
+	for i := range s.Blocks {
+		if s.Blocks[i].Name == name {
+			return &s.Blocks[i]
+		}
+	}
+
+	return nil
+}
+
+// ResourceTypes returns the types of resource which can be defined in a
+// blueprint sorted by name
+func ResourceTypes() []ResourceType {
+	types := []ResourceType{}
+	for t := range resourceTypes {
+		types = append(types, t)
+	}
+
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	return types
+}
+
+// ResourceSchema returns the schema for the given resource type, every
+// resource has a single label containing the name of the resource
+func ResourceSchema(t ResourceType) (*Schema, error) {
+	rt, ok := resourceTypes[t]
+	if !ok {
+		return nil, fmt.Errorf("Unknown resource type %s", t)
+	}
+
+	s := schemaFor(rt, map[reflect.Type]bool{})
+	s.Labels = []string{"name"}
+
+	return s, nil
+}
+
+// BlueprintSchema returns the schema for the blueprint which can be defined
+// in a .yard file
+func BlueprintSchema() *Schema {
+	return schemaFor(reflect.TypeOf(Blueprint{}), map[reflect.Type]bool{})
+}
+
+// schemaFor builds the schema from the hcl tags of the struct, seen
+// contains the types of the parent blocks so that recursive types terminate
+func schemaFor(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	s := &Schema{Attributes: []AttributeSchema{}, Blocks: []BlockSchema{}}
+
+	seen[t] = true
+	defer delete(seen, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag, ok := f.Tag.Lookup("hcl")
+		if !ok || tag == "-" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		kind := ""
+		if len(parts) > 1 {
+			kind = parts[1]
+		}
+
+		switch kind {
+		case "label":
+			s.Labels = append(s.Labels, name)
+
+		case "block":
+			bt := f.Type
+			b := BlockSchema{Name: name, Required: bt.Kind() == reflect.Struct}
+
+			if bt.Kind() == reflect.Slice {
+				b.Multiple = true
+				bt = bt.Elem()
+			}
+
+			if bt.Kind() == reflect.Ptr {
+				bt = bt.Elem()
+			}
+
+			if seen[bt] {
+				b.Schema = &Schema{Attributes: []AttributeSchema{}, Blocks: []BlockSchema{}}
+			} else {
+				b.Schema = schemaFor(bt, seen)
+			}
+
+			s.Blocks = append(s.Blocks, b)
+
+		case "", "optional":
+			s.Attributes = append(s.Attributes, AttributeSchema{Name: name, Type: hclType(f.Type), Required: kind == ""})
+		}
+	}
+
+	return s
+}
+
+// hclType returns the HCL type name for the Go type
+func hclType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return fmt.Sprintf("list(%s)", hclType(t.Elem()))
+	case reflect.Map:
+		return fmt.Sprintf("map(%s)", hclType(t.Elem()))
+	case reflect.Ptr:
+		return hclType(t.Elem())
+	case reflect.Struct:
+		return "object"
+	}
+
+	return "any"
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceTypesReturnsSortedTypes(t *testing.T) {
+	types := ResourceTypes()
+
+	assert.Len(t, types, 17)
+	assert.Equal(t, TypeContainer, types[0])
+	assert.Contains(t, types, TypeModule)
+	assert.Contains(t, types, TypeK8sCluster)
+}
+
+func TestResourceSchemaReturnsAttributesAndBlocks(t *testing.T) {
+	s, err := ResourceSchema(TypeContainer)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"name"}, s.Labels)
+
+	a := s.Attribute("command")
+	assert.NotNil(t, a)
+	assert.Equal(t, "list(string)", a.Type)
+	assert.False(t, a.Required)
+
+	assert.Equal(t, "bool", s.Attribute("privileged").Type)
+
+	b := s.Block("image")
+	assert.NotNil(t, b)
+	assert.True(t, b.Required)
+	assert.False(t, b.Multiple)
+	assert.True(t, b.Schema.Attribute("name").Required)
+
+	b = s.Block("volume")
+	assert.NotNil(t, b)
+	assert.False(t, b.Required)
+	assert.True(t, b.Multiple)
+	assert.Equal(t, "string", b.Schema.Attribute("source").Type)
+
+	b = s.Block("resources")
+	assert.NotNil(t, b)
+	assert.False(t, b.Required)
+	assert.Equal(t, "number", b.Schema.Attribute("cpu").Type)
+	assert.Equal(t, "list(number)", b.Schema.Attribute("cpu_pin").Type)
+}
+
+func TestResourceSchemaReturnsErrorForUnknownType(t *testing.T) {
+	_, err := ResourceSchema(ResourceType("spaceship"))
+	assert.Error(t, err)
+}
+
+func TestBlueprintSchemaReturnsBlockLabels(t *testing.T) {
+	s := BlueprintSchema()
+
+	assert.NotNil(t, s.Attribute("required_version"))
+
+	b := s.Block("assert")
+	assert.NotNil(t, b)
+	assert.True(t, b.Multiple)
+	assert.Equal(t, []string{"name"}, b.Schema.Labels)
+}
@@ -25,6 +25,12 @@ type Sidecar struct {
 
 	// health checks for the container
 	HealthCheck *HealthCheck `hcl:"health_check,block" json:"health_check,omitempty"`
+
+	// create the sidecar again when it fails
+	Retry *Retry `hcl:"retry,block" json:"retry,omitempty"`
+
+	// maximum time to create the sidecar including pulling the image, health checks, and retries e.g. 5m
+	Timeout string `hcl:"timeout,optional" json:"timeout,omitempty"`
 }
 
 // NewSidecar returns a new Container resource with the correct default options
@@ -3,10 +3,12 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/mitchellh/mapstructure"
-	"github.com/shipyard-run/shipyard/pkg/utils"
 )
 
 var StateNotFoundError = fmt.Errorf("State file not found")
@@ -14,31 +16,59 @@ var StateNotFoundError = fmt.Errorf("State file not found")
 // ToJSON saves the config in JSON format to the specified path
 // returns an error if the config can not be saved.
 func (c *Config) ToJSON(path string) error {
-	sd := utils.StateDir()
-	sp := utils.StatePath()
+	// record the version so that incompatible versions of Shipyard do not
+	// attempt to read the state
+	c.ShipyardVersion = Version
+	c.StateVersion = StateVersion
+
+	d, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	return AtomicWriteFile(path, append(d, '\n'))
+}
+
+// AtomicWriteFile replaces the file at path with data, the data is written
+// to a temporary file in the same folder which is renamed over the file so
+// a partially written file is never read
+func AtomicWriteFile(path string, data []byte) error {
+	sd := filepath.Dir(path)
 
 	// if it does not exist create the state folder
-	_, err := os.Stat(sd)
+	err := os.MkdirAll(sd, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(sd, filepath.Base(path)+".*.tmp")
 	if err != nil {
-		os.MkdirAll(sd, os.ModePerm)
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
 	}
 
-	// if the statefile exists overwrite it
-	_, err = os.Stat(sp)
 	if err == nil {
-		// delete the old state
-		os.Remove(sp)
+		err = os.Rename(f.Name(), path)
 	}
 
-	// serialize the state to json and write to a file
-	f, err := os.Create(sp)
 	if err != nil {
+		os.Remove(f.Name())
 		return err
 	}
-	defer f.Close()
 
-	ne := json.NewEncoder(f)
-	return ne.Encode(c)
+	return nil
 }
 
 // FromJSON attempts to rehydrate the config from a JSON formatted statefile
@@ -54,299 +84,200 @@ func (c *Config) FromJSON(path string) error {
 	return jd.Decode(c)
 }
 
+// State is the format of the state file, the resources are kept as the raw
+// decoded JSON until the state has been migrated to the current StateVersion
+// as their layout may have changed between versions
+type State struct {
+	ShipyardVersion string                   `json:"shipyard_version,omitempty"` // version of Shipyard which wrote the state
+	StateVersion    int                      `json:"state_version,omitempty"`    // version of the state format, 0 for states written before it was recorded
+	Blueprint       json.RawMessage          `json:"blueprint,omitempty"`
+	Resources       []map[string]interface{} `json:"resources"`
+}
+
 // UnmarshalJSON is a cusom Unmarshaler to deal with
 // converting the objects back into their main type
 func (c *Config) UnmarshalJSON(b []byte) error {
-	var objMap map[string]*json.RawMessage
-	err := json.Unmarshal(b, &objMap)
+	s := &State{}
+	err := json.Unmarshal(b, s)
 	if err != nil {
 		return err
 	}
 
-	if objMap["blueprint"] != nil {
-		var rawBlueprint *json.RawMessage
-		json.Unmarshal(*objMap["blueprint"], &rawBlueprint)
-		bp := &Blueprint{}
-		err = json.Unmarshal(*rawBlueprint, &bp)
-		if err == nil {
-			c.Blueprint = bp
-		}
+	c.ShipyardVersion = s.ShipyardVersion
+	c.StateVersion = s.StateVersion
+
+	if s.StateVersion > StateVersion {
+		return StateVersionError{StateVersion: s.StateVersion, ShipyardVersion: s.ShipyardVersion}
 	}
 
-	var rawMessagesForResources []*json.RawMessage
-	err = json.Unmarshal(*objMap["resources"], &rawMessagesForResources)
+	// upgrade states written by older versions of Shipyard before decoding
+	err = migrateState(s)
 	if err != nil {
 		return err
 	}
 
-	for _, m := range rawMessagesForResources {
-		mm := map[string]interface{}{}
-		err := json.Unmarshal(*m, &mm)
+	if len(s.Blueprint) > 0 {
+		bp := &Blueprint{}
+		err = json.Unmarshal(s.Blueprint, &bp)
+		if err == nil {
+			c.Blueprint = bp
+		}
+	}
+
+	for _, mm := range s.Resources {
+		r, err := c.decodeResource(mm)
 		if err != nil {
 			return err
 		}
 
-		t := ResourceType(mm["type"].(string))
-		switch t {
-		case TypeContainer:
-			t := Container{}
-			err := mapstructure.Decode(mm, &t)
-			if err != nil {
-				return err
-			}
-			t.Name = mm["name"].(string)
-			t.Type = ResourceType(mm["type"].(string))
-			t.Status = Status(mm["status"].(string))
-
-			if d, ok := mm["depends_on"].([]interface{}); ok {
-				for _, i := range d {
-					t.DependsOn = append(t.DependsOn, i.(string))
-				}
-			}
-			c.AddResource(&t)
-
-		case TypeContainerIngress:
-			t := ContainerIngress{}
-			err := mapstructure.Decode(mm, &t)
-			if err != nil {
-				return err
-			}
-			t.Name = mm["name"].(string)
-			t.Type = ResourceType(mm["type"].(string))
-			t.Status = Status(mm["status"].(string))
-
-			if d, ok := mm["depends_on"].([]interface{}); ok {
-				for _, i := range d {
-					t.DependsOn = append(t.DependsOn, i.(string))
-				}
-			}
-			c.AddResource(&t)
-
-		case TypeSidecar:
-			t := Sidecar{}
-			err := mapstructure.Decode(mm, &t)
-			if err != nil {
-				return err
-			}
-			t.Name = mm["name"].(string)
-			t.Type = ResourceType(mm["type"].(string))
-			t.Status = Status(mm["status"].(string))
-
-			if d, ok := mm["depends_on"].([]interface{}); ok {
-				for _, i := range d {
-					t.DependsOn = append(t.DependsOn, i.(string))
-				}
-			}
-			c.AddResource(&t)
-
-		case TypeDocs:
-			t := Docs{}
-			err := mapstructure.Decode(mm, &t)
-			if err != nil {
-				return err
-			}
-			t.Name = mm["name"].(string)
-			t.Type = ResourceType(mm["type"].(string))
-			t.Status = Status(mm["status"].(string))
-
-			if d, ok := mm["depends_on"].([]interface{}); ok {
-				for _, i := range d {
-					t.DependsOn = append(t.DependsOn, i.(string))
-				}
-			}
-			c.AddResource(&t)
-
-		case TypeExecRemote:
-			t := ExecRemote{}
-			err := mapstructure.Decode(mm, &t)
-			if err != nil {
-				return err
-			}
-			t.Name = mm["name"].(string)
-			t.Type = ResourceType(mm["type"].(string))
-			t.Status = Status(mm["status"].(string))
-
-			if d, ok := mm["depends_on"].([]interface{}); ok {
-				for _, i := range d {
-					t.DependsOn = append(t.DependsOn, i.(string))
-				}
-			}
-			c.AddResource(&t)
-
-		case TypeExecLocal:
-			t := ExecLocal{}
-			err := mapstructure.Decode(mm, &t)
-			if err != nil {
-				return err
-			}
-			t.Name = mm["name"].(string)
-			t.Type = ResourceType(mm["type"].(string))
-			t.Status = Status(mm["status"].(string))
-
-			if d, ok := mm["depends_on"].([]interface{}); ok {
-				for _, i := range d {
-					t.DependsOn = append(t.DependsOn, i.(string))
-				}
-			}
-			c.AddResource(&t)
-
-		case TypeHelm:
-			t := Helm{}
-			err := mapstructure.Decode(mm, &t)
-			if err != nil {
-				return err
-			}
-			t.Name = mm["name"].(string)
-			t.Type = ResourceType(mm["type"].(string))
-			t.Status = Status(mm["status"].(string))
+		c.AddResource(r)
+	}
 
-			if d, ok := mm["depends_on"].([]interface{}); ok {
-				for _, i := range d {
-					t.DependsOn = append(t.DependsOn, i.(string))
-				}
-			}
-			c.AddResource(&t)
+	return nil
+}
 
-		case TypeIngress:
-			t := Ingress{}
-			err := mapstructure.Decode(mm, &t)
-			if err != nil {
-				return err
-			}
-			t.Name = mm["name"].(string)
-			t.Type = ResourceType(mm["type"].(string))
-			t.Status = Status(mm["status"].(string))
+// decodeResource converts the raw JSON for a resource into its type
+func (c *Config) decodeResource(mm map[string]interface{}) (Resource, error) {
+	name, _ := mm["name"].(string)
+	typ, _ := mm["type"].(string)
+	status, _ := mm["status"].(string)
+
+	var r Resource
+
+	switch ResourceType(typ) {
+	case TypeContainer:
+		r = &Container{}
+	case TypeContainerIngress:
+		r = &ContainerIngress{}
+	case TypeSidecar:
+		r = &Sidecar{}
+	case TypeDocs:
+		r = &Docs{}
+	case TypeExecRemote:
+		r = &ExecRemote{}
+	case TypeExecLocal:
+		r = &ExecLocal{}
+	case TypeHelm:
+		r = &Helm{}
+	case TypeIngress:
+		r = &Ingress{}
+	case TypeK8sCluster:
+		r = &K8sCluster{}
+	case TypeK8sConfig:
+		r = &K8sConfig{}
+	case TypeK8sIngress:
+		r = &K8sIngress{}
+	case TypeDockerHost:
+		r = &DockerHost{}
+	case TypeNetwork:
+		r = &Network{}
+	case TypeNomadCluster:
+		r = &NomadCluster{}
+	case TypeNomadJob:
+		r = &NomadJob{}
+	case TypeNomadIngress:
+		r = &NomadIngress{}
+	default:
+		return nil, fmt.Errorf(
+			"Unable to read resource %s.%s from the state, the resource type is not supported by Shipyard %s. "+
+				"The state may have been created by a newer version of Shipyard (%s)",
+			typ, name, Version, c.ShipyardVersion,
+		)
+	}
 
-			if d, ok := mm["depends_on"].([]interface{}); ok {
-				for _, i := range d {
-					t.DependsOn = append(t.DependsOn, i.(string))
-				}
-			}
-			c.AddResource(&t)
+	err := mapstructure.Decode(mm, r)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Unable to read resource %s.%s from the state (state version %d, created by Shipyard %s): %s",
+			typ, name, c.StateVersion, c.ShipyardVersion, err,
+		)
+	}
 
-		case TypeK8sCluster:
-			t := K8sCluster{}
-			err := mapstructure.Decode(mm, &t)
-			if err != nil {
-				return err
-			}
-			t.Name = mm["name"].(string)
-			t.Type = ResourceType(mm["type"].(string))
-			t.Status = Status(mm["status"].(string))
+	r.Info().Name = name
+	r.Info().Type = ResourceType(typ)
+	r.Info().Status = Status(status)
 
-			if d, ok := mm["depends_on"].([]interface{}); ok {
-				for _, i := range d {
-					t.DependsOn = append(t.DependsOn, i.(string))
-				}
+	if d, ok := mm["depends_on"].([]interface{}); ok {
+		for _, i := range d {
+			if s, ok := i.(string); ok {
+				r.Info().DependsOn = append(r.Info().DependsOn, s)
 			}
-			c.AddResource(&t)
+		}
+	}
 
-		case TypeK8sConfig:
-			t := K8sConfig{}
-			err := mapstructure.Decode(mm, &t)
-			if err != nil {
-				return err
-			}
-			t.Name = mm["name"].(string)
-			t.Type = ResourceType(mm["type"].(string))
-			t.Status = Status(mm["status"].(string))
+	if d, ok := mm["definition"].(map[string]interface{}); ok {
+		r.Info().Definition = d
+	}
 
-			if d, ok := mm["depends_on"].([]interface{}); ok {
-				for _, i := range d {
-					t.DependsOn = append(t.DependsOn, i.(string))
-				}
-			}
-			c.AddResource(&t)
+	return r, nil
+}
 
-		case TypeK8sIngress:
-			t := K8sIngress{}
-			err := mapstructure.Decode(mm, &t)
-			if err != nil {
-				return err
-			}
-			t.Name = mm["name"].(string)
-			t.Type = ResourceType(mm["type"].(string))
-			t.Status = Status(mm["status"].(string))
+// SetDefinitions records the definition of each resource in the config as it
+// was parsed from the blueprint in the folder dir. Paths inside dir are
+// recorded relative to dir so that moving the blueprint does not change the
+// definition
+func SetDefinitions(c *Config, dir string) {
+	dir, _ = filepath.Abs(dir)
 
-			if d, ok := mm["depends_on"].([]interface{}); ok {
-				for _, i := range d {
-					t.DependsOn = append(t.DependsOn, i.(string))
-				}
-			}
-			c.AddResource(&t)
+	for _, r := range c.Resources {
+		r.Info().Definition = nil
 
-		case TypeNetwork:
-			t := Network{}
-			err := mapstructure.Decode(mm, &t)
-			if err != nil {
-				return err
-			}
-			t.Name = mm["name"].(string)
-			t.Type = ResourceType(mm["type"].(string))
-			t.Status = Status(mm["status"].(string))
+		d, err := json.Marshal(r)
+		if err != nil {
+			continue
+		}
 
-			if d, ok := mm["depends_on"].([]interface{}); ok {
-				for _, i := range d {
-					t.DependsOn = append(t.DependsOn, i.(string))
-				}
-			}
-			c.AddResource(&t)
+		m := map[string]interface{}{}
+		if json.Unmarshal(d, &m) != nil {
+			continue
+		}
 
-		case TypeNomadCluster:
-			t := NomadCluster{}
-			err := mapstructure.Decode(mm, &t)
-			if err != nil {
-				return err
-			}
-			t.Name = mm["name"].(string)
-			t.Type = ResourceType(mm["type"].(string))
-			t.Status = Status(mm["status"].(string))
+		delete(m, "status")
 
-			if d, ok := mm["depends_on"].([]interface{}); ok {
-				for _, i := range d {
-					t.DependsOn = append(t.DependsOn, i.(string))
-				}
-			}
-			c.AddResource(&t)
+		r.Info().Definition = relativePaths(m, dir).(map[string]interface{})
+	}
+}
 
-		case TypeNomadJob:
-			t := NomadJob{}
-			err := mapstructure.Decode(mm, &t)
-			if err != nil {
-				return err
-			}
-			t.Name = mm["name"].(string)
-			t.Type = ResourceType(mm["type"].(string))
-			t.Status = Status(mm["status"].(string))
+// Definition returns the resource as it was defined in the blueprint, false
+// is returned when the definition was not recorded by the version of
+// Shipyard which wrote the state
+func Definition(r Resource) (Resource, bool) {
+	if r.Info().Definition == nil {
+		return nil, false
+	}
 
-			if d, ok := mm["depends_on"].([]interface{}); ok {
-				for _, i := range d {
-					t.DependsOn = append(t.DependsOn, i.(string))
-				}
-			}
-			c.AddResource(&t)
+	d, err := (&Config{}).decodeResource(r.Info().Definition)
+	if err != nil {
+		return nil, false
+	}
 
-		case TypeNomadIngress:
-			t := NomadIngress{}
-			err := mapstructure.Decode(mm, &t)
-			if err != nil {
-				return err
-			}
-			t.Name = mm["name"].(string)
-			t.Type = ResourceType(mm["type"].(string))
-			t.Status = Status(mm["status"].(string))
+	return d, true
+}
 
-			if d, ok := mm["depends_on"].([]interface{}); ok {
-				for _, i := range d {
-					t.DependsOn = append(t.DependsOn, i.(string))
-				}
-			}
-			c.AddResource(&t)
+// relativePaths replaces the absolute paths inside dir in the decoded JSON v
+// with paths relative to dir
+func relativePaths(v interface{}, dir string) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, i := range t {
+			t[k] = relativePaths(i, dir)
+		}
+	case []interface{}:
+		for k, i := range t {
+			t[k] = relativePaths(i, dir)
+		}
+	case string:
+		if !filepath.IsAbs(t) {
+			return t
+		}
 
+		rel, err := filepath.Rel(dir, t)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "./" + filepath.ToSlash(rel)
 		}
 	}
 
-	return nil
+	return v
 }
 
 // Merge config merges two config items
@@ -364,6 +295,10 @@ func (c *Config) Merge(c2 *Config) {
 					status = PendingUpdate
 				}
 
+				// values which are only known after the resource has been applied are
+				// not in the new config, carry them over from the state
+				mergeApplied(cc2, c.Resources[i])
+
 				c.Resources[i] = cc2
 				c.Resources[i].Info().Status = status
 
@@ -385,3 +320,43 @@ func (c *Config) Merge(c2 *Config) {
 		c.Blueprint = c2.Blueprint
 	}
 }
+
+// mergeApplied copies the values set when old was applied to r
+func mergeApplied(r, old Resource) {
+	switch v := r.(type) {
+	case *K8sConfig:
+		// the inventory of Kubernetes objects is used to prune removed objects
+		if o, ok := old.(*K8sConfig); ok {
+			v.Inventory = o.Inventory
+		}
+	case *K8sCluster:
+		// the Kubernetes config and context are written when the cluster is
+		// created and are needed for the outputs and to clean up on destroy
+		if o, ok := old.(*K8sCluster); ok {
+			v.KubeConfig = o.KubeConfig
+			v.KubeContext = o.KubeContext
+		}
+	case *ExecLocal:
+		// unchanged exec resources are not run again so the captured values
+		// would otherwise be lost
+		if o, ok := old.(*ExecLocal); ok {
+			mergeOutputs(v.Outputs, o.Outputs)
+		}
+	case *ExecRemote:
+		if o, ok := old.(*ExecRemote); ok {
+			mergeOutputs(v.Outputs, o.Outputs)
+		}
+	}
+}
+
+// mergeOutputs copies the captured values in old to the outputs with the
+// same name
+func mergeOutputs(outputs, old []ExecOutput) {
+	for i := range outputs {
+		for _, o := range old {
+			if outputs[i].Name == o.Name {
+				outputs[i].Value = o.Value
+			}
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/shipyard-run/shipyard/pkg/utils"
@@ -58,6 +59,28 @@ func TestConfigSerializesToJSON(t *testing.T) {
 	assert.Len(t, c2.Resources, c.ResourceCount())
 }
 
+func TestConfigToJSONReplacesStateWithoutTemporaryFiles(t *testing.T) {
+	c, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	statePath := utils.StatePath()
+	os.MkdirAll(filepath.Dir(statePath), os.ModePerm)
+	err := ioutil.WriteFile(statePath, []byte("old state"), 0644)
+	assert.NoError(t, err)
+
+	err = c.ToJSON(statePath)
+	assert.NoError(t, err)
+
+	c2 := New()
+	err = c2.FromJSON(statePath)
+	assert.NoError(t, err)
+	assert.Len(t, c2.Resources, c.ResourceCount())
+
+	files, err := ioutil.ReadDir(filepath.Dir(statePath))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
 func TestConfigDeSerializesFromJSON(t *testing.T) {
 	c, cleanup := setupConfigTests(t)
 	defer cleanup()
@@ -74,6 +97,37 @@ func TestConfigDeSerializesFromJSON(t *testing.T) {
 	assert.Equal(t, "config", c.Resources[0].Info().Name)
 }
 
+func TestConfigDeSerializesDefinitions(t *testing.T) {
+	c, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	co := NewContainer("data")
+	co.Image = Image{Name: "consul:1.8.1"}
+	co.Volumes = []Volume{{Source: "/blueprint/files", Destination: "/files"}}
+	c.AddResource(co)
+
+	SetDefinitions(c, "/blueprint")
+
+	// providers change the resource once it has been parsed
+	co.Image.Name = "shipyard-checkpoint/container-data:abc"
+
+	statePath := utils.StatePath()
+	err := c.ToJSON(statePath)
+	assert.NoError(t, err)
+
+	c = New()
+	err = c.FromJSON(statePath)
+	assert.NoError(t, err)
+
+	r, err := c.FindResource("container.data")
+	assert.NoError(t, err)
+
+	d, ok := Definition(r)
+	assert.True(t, ok)
+	assert.Equal(t, "consul:1.8.1", d.(*Container).Image.Name)
+	assert.Equal(t, "./files", d.(*Container).Volumes[0].Source)
+}
+
 func TestConfigMergesAddingItems(t *testing.T) {
 	c, cleanup := setupConfigTests(t)
 	defer cleanup()
@@ -115,3 +169,253 @@ func TestConfigMergesWithExistingItemDoesNOTSetsPendingUpdateWhenOtherStatus(t *
 	assert.Len(t, c.Resources, 9)
 	assert.Equal(t, c.Resources[0].Info().Status, PendingCreation)
 }
+
+func TestConfigMergesKeepsK8sConfigInventory(t *testing.T) {
+	c, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	kc := NewK8sConfig("config")
+	kc.Status = Applied
+	kc.Inventory = []K8sObject{K8sObject{APIVersion: "v1", Kind: "Service", Name: "web"}}
+	c.AddResource(kc)
+
+	c2 := New()
+	c2.AddResource(NewK8sConfig("config"))
+
+	c.Merge(c2)
+
+	r, err := c.FindResource("k8s_config.config")
+	assert.NoError(t, err)
+	assert.Equal(t, PendingUpdate, r.Info().Status)
+	assert.Equal(t, kc.Inventory, r.(*K8sConfig).Inventory)
+}
+
+func TestConfigMergesKeepsExecOutputValues(t *testing.T) {
+	c, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	el := NewExecLocal("setup")
+	el.Status = Applied
+	el.Outputs = []ExecOutput{ExecOutput{Name: "token", Value: "abc"}}
+	c.AddResource(el)
+
+	er := NewExecRemote("setup")
+	er.Status = Applied
+	er.Outputs = []ExecOutput{ExecOutput{Name: "token", Value: "def"}}
+	c.AddResource(er)
+
+	el2 := NewExecLocal("setup")
+	el2.Outputs = []ExecOutput{ExecOutput{Name: "token"}, ExecOutput{Name: "new"}}
+	er2 := NewExecRemote("setup")
+	er2.Outputs = []ExecOutput{ExecOutput{Name: "token"}}
+
+	c2 := New()
+	c2.AddResource(el2)
+	c2.AddResource(er2)
+
+	c.Merge(c2)
+
+	r, err := c.FindResource("exec_local.setup")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", r.(*ExecLocal).Outputs[0].Value)
+	assert.Equal(t, "", r.(*ExecLocal).Outputs[1].Value)
+
+	r, err = c.FindResource("exec_remote.setup")
+	assert.NoError(t, err)
+	assert.Equal(t, "def", r.(*ExecRemote).Outputs[0].Value)
+}
+
+func TestConfigMergesKeepsK8sClusterKubeConfig(t *testing.T) {
+	c, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	kc := NewK8sCluster("dev")
+	kc.Status = Applied
+	kc.KubeConfig = "/home/shipyard/.shipyard/config/dev/kubeconfig.yaml"
+	kc.KubeContext = "shipyard-dev"
+	c.AddResource(kc)
+
+	c2 := New()
+	c2.AddResource(NewK8sCluster("dev"))
+
+	c.Merge(c2)
+
+	r, err := c.FindResource("k8s_cluster.dev")
+	assert.NoError(t, err)
+	assert.Equal(t, kc.KubeConfig, r.(*K8sCluster).KubeConfig)
+	assert.Equal(t, kc.KubeContext, r.(*K8sCluster).KubeContext)
+}
+
+func TestConfigSerializesVersion(t *testing.T) {
+	c, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	defer setVersion("0.1.5")()
+
+	err := c.ToJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	c2 := New()
+	err = c2.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	assert.Equal(t, "0.1.5", c2.ShipyardVersion)
+	assert.Equal(t, StateVersion, c2.StateVersion)
+}
+
+func TestConfigDeSerializesStateWithoutVersion(t *testing.T) {
+	_, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	writeState(t, stateWithoutVersion)
+
+	c := New()
+	err := c.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, c.StateVersion)
+	assert.Len(t, c.Resources, 2)
+	assert.Equal(t, Applied, c.Resources[1].Info().Status)
+	assert.Equal(t, []string{"network.cloud"}, c.Resources[1].Info().DependsOn)
+}
+
+func TestConfigDeSerializeReturnsErrorForNewerStateVersion(t *testing.T) {
+	_, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	writeState(t, fmt.Sprintf(stateWithVersion, StateVersion+1, "container"))
+
+	c := New()
+	err := c.FromJSON(utils.StatePath())
+	assert.IsType(t, StateVersionError{}, err)
+	assert.Contains(t, err.Error(), "Shipyard 9.0.0")
+}
+
+func TestConfigDeSerializeMigratesOlderState(t *testing.T) {
+	_, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	m := stateMigrations[0]
+	defer func() { stateMigrations[0] = m }()
+
+	// a migration which changes the layout of a resource
+	stateMigrations[0] = func(s *State) error {
+		for _, r := range s.Resources {
+			if r["type"] == "network" {
+				r["subnet"] = "10.16.0.0/16"
+			}
+		}
+
+		return nil
+	}
+
+	writeState(t, stateWithoutVersion)
+
+	c := New()
+	err := c.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	r, err := c.FindResource("network.cloud")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.16.0.0/16", r.(*Network).Subnet)
+}
+
+func TestConfigDeSerializeReturnsErrorWhenMigrationMissing(t *testing.T) {
+	_, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	m := stateMigrations[0]
+	defer func() { stateMigrations[0] = m }()
+
+	delete(stateMigrations, 0)
+
+	writeState(t, stateWithoutVersion)
+
+	c := New()
+	err := c.FromJSON(utils.StatePath())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no migration exists")
+}
+
+func TestConfigDeSerializeReturnsErrorForUnknownResourceType(t *testing.T) {
+	_, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	writeState(t, fmt.Sprintf(stateWithVersion, StateVersion, "spaceship"))
+
+	c := New()
+	err := c.FromJSON(utils.StatePath())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "spaceship.consul")
+}
+
+func TestConfigDeSerializeReturnsErrorWithResourceWhenDecodeFails(t *testing.T) {
+	_, cleanup := setupConfigTests(t)
+	defer cleanup()
+
+	writeState(t, stateInvalidResource)
+
+	c := New()
+	err := c.FromJSON(utils.StatePath())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "container.consul")
+}
+
+func writeState(t *testing.T, state string) {
+	os.MkdirAll(utils.StateDir(), os.ModePerm)
+
+	err := ioutil.WriteFile(utils.StatePath(), []byte(state), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+var stateWithoutVersion = `
+{
+  "blueprint": null,
+  "resources": [
+    {
+      "name": "cloud",
+      "type": "network",
+      "status": "applied",
+      "subnet": "10.15.0.0/16"
+    },
+    {
+      "name": "consul",
+      "type": "container",
+      "status": "applied",
+      "depends_on": ["network.cloud"],
+      "image": { "name": "consul:1.6.1" }
+    }
+  ]
+}
+`
+
+var stateWithVersion = `
+{
+  "shipyard_version": "9.0.0",
+  "state_version": %d,
+  "blueprint": null,
+  "resources": [
+    {
+      "name": "consul",
+      "type": "%s",
+      "status": "applied"
+    }
+  ]
+}
+`
+
+var stateInvalidResource = `
+{
+  "blueprint": null,
+  "resources": [
+    {
+      "name": "consul",
+      "type": "container",
+      "status": "applied",
+      "image": "consul:1.6.1"
+    }
+  ]
+}
+`
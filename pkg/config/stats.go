@@ -0,0 +1,26 @@
+package config
+
+import "time"
+
+// ContainerStats is a point in time sample of the resources used by a container
+type ContainerStats struct {
+	ID               string    `json:"id"`
+	Name             string    `json:"name"`
+	Read             time.Time `json:"read"`
+	CPUPercentage    float64   `json:"cpu_percentage"`    // percentage of the host CPU, 100 per core
+	MemoryUsage      uint64    `json:"memory_usage"`      // memory used in bytes excluding the page cache
+	MemoryLimit      uint64    `json:"memory_limit"`      // memory limit in bytes
+	MemoryPercentage float64   `json:"memory_percentage"` // memory used as a percentage of the limit
+	NetworkRx        uint64    `json:"network_rx"`        // bytes received on all interfaces
+	NetworkTx        uint64    `json:"network_tx"`        // bytes sent on all interfaces
+}
+
+// ContainerDiskUsage is the disk space used by a container, the image it was
+// created from, and the named volumes mounted in it
+type ContainerDiskUsage struct {
+	ID           string           `json:"id"`
+	Image        string           `json:"image"`
+	ImageSize    int64            `json:"image_size"`    // size of the image in bytes including layers shared with other images
+	WritableSize int64            `json:"writable_size"` // bytes written to the container filesystem
+	Volumes      map[string]int64 `json:"volumes"`       // size in bytes of each named volume
+}
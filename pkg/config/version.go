@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-version"
+)
+
+// Version is the version of the running Shipyard binary, it is set at startup
+// by the cmd package. Development builds use the version "dev" which skips
+// the blueprint required_version check
+var Version = "dev"
+
+// StateVersion is the version of the state file format written by this
+// binary, it must be incremented and a migration added to stateMigrations
+// whenever a change to a resource would stop an older state from decoding
+const StateVersion = 1
+
+// stateMigrations upgrade a state file from the version used as the key to
+// the next version. The resources in the state are the raw decoded JSON
+// before it is converted into the resource types
+var stateMigrations = map[int]func(s *State) error{
+	// state files written before the version was recorded have the same
+	// format as version 1
+	0: func(s *State) error { return nil },
+}
+
+// StateVersionError is returned when the state file was written by a newer
+// version of Shipyard which uses a format this binary can not read
+type StateVersionError struct {
+	StateVersion    int
+	ShipyardVersion string // version of Shipyard which wrote the state
+}
+
+func (e StateVersionError) Error() string {
+	return fmt.Sprintf(
+		"The state was created by Shipyard %s using state version %d, this version of Shipyard (%s) supports up to state version %d. "+
+			"Upgrade Shipyard to manage these resources or destroy them using Shipyard %s",
+		e.ShipyardVersion, e.StateVersion, Version, StateVersion, e.ShipyardVersion,
+	)
+}
+
+// RequiredVersionError is returned when the blueprint requires a version of
+// Shipyard which does not match the running binary
+type RequiredVersionError struct {
+	Required string
+}
+
+func (e RequiredVersionError) Error() string {
+	return fmt.Sprintf(
+		"The blueprint requires Shipyard %s but this version is %s, install a version which matches the constraint, "+
+			"see https://shipyard.run/docs/install for the available options",
+		e.Required, Version,
+	)
+}
+
+// CheckRequiredVersion returns an error when the running version of Shipyard
+// does not match the version constraint, e.g. ">= 0.1.0, < 0.2.0". Development
+// builds are not checked
+func CheckRequiredVersion(constraint string) error {
+	if constraint == "" || Version == "dev" {
+		return nil
+	}
+
+	c, err := version.NewConstraint(constraint)
+	if err != nil {
+		return fmt.Errorf("Invalid required_version %s: %s", constraint, err)
+	}
+
+	v, err := version.NewVersion(Version)
+	if err != nil {
+		// unable to compare non semantic versions
+		return nil
+	}
+
+	if !c.Check(v) {
+		return RequiredVersionError{Required: constraint}
+	}
+
+	return nil
+}
+
+// migrateState upgrades the state from its version to the current
+// StateVersion, the version of the state is updated after each migration
+func migrateState(s *State) error {
+	for s.StateVersion < StateVersion {
+		m, ok := stateMigrations[s.StateVersion]
+		if !ok {
+			return fmt.Errorf("Unable to migrate state from version %d, no migration exists", s.StateVersion)
+		}
+
+		err := m(s)
+		if err != nil {
+			return fmt.Errorf("Unable to migrate state from version %d: %s", s.StateVersion, err)
+		}
+
+		s.StateVersion++
+	}
+
+	return nil
+}
@@ -2,12 +2,14 @@ package providers
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -16,6 +18,8 @@ import (
 	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/shipyard-run/shipyard/pkg/utils"
 	"golang.org/x/xerrors"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api/v1"
+	"sigs.k8s.io/yaml"
 )
 
 // https://github.com/rancher/k3d/blob/master/cli/commands.go
@@ -39,35 +43,48 @@ func NewK8sCluster(c *config.K8sCluster, cc clients.ContainerTasks, kc clients.K
 }
 
 // Create implements interface method to create a cluster of the specified type
-func (c *K8sCluster) Create() error {
+func (c *K8sCluster) Create(ctx context.Context) error {
 	switch c.config.Driver {
 	case "k3s":
-		return c.createK3s()
+		return c.createK3s(ctx)
 	default:
 		return ErrorClusterDriverNotImplemented
 	}
 }
 
 // Destroy implements interface method to destroy a cluster
-func (c *K8sCluster) Destroy() error {
+func (c *K8sCluster) Destroy(ctx context.Context) error {
 	switch c.config.Driver {
 	case "k3s":
-		return c.destroyK3s()
+		return c.destroyK3s(ctx)
 	default:
 		return ErrorClusterDriverNotImplemented
 	}
 }
 
 // Lookup the a clusters current state
-func (c *K8sCluster) Lookup() ([]string, error) {
-	return c.client.FindContainerIDs(fmt.Sprintf("server.%s", c.config.Name), c.config.Type)
+func (c *K8sCluster) Lookup(ctx context.Context) ([]string, error) {
+	ids := []string{}
+
+	// agent nodes are returned before the server so they are removed first
+	names := nodeNames(c.config)
+	for i := len(names) - 1; i >= 0; i-- {
+		nids, err := c.client.FindContainerIDs(ctx, fmt.Sprintf("%s.%s", names[i], c.config.Name), c.config.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, nids...)
+	}
+
+	return ids, nil
 }
 
-func (c *K8sCluster) createK3s() error {
+func (c *K8sCluster) createK3s(ctx context.Context) error {
 	c.log.Info("Creating Cluster", "ref", c.config.Name)
 
 	// check the cluster does not already exist
-	ids, err := c.client.FindContainerIDs(fmt.Sprintf("server.%s", c.config.Name), c.config.Type)
+	ids, err := c.client.FindContainerIDs(ctx, fmt.Sprintf("server.%s", c.config.Name), c.config.Type)
 	if err != nil {
 		return err
 	}
@@ -76,17 +93,23 @@ func (c *K8sCluster) createK3s() error {
 		return ErrorClusterExists
 	}
 
+	// node config must be for one of the nodes in the cluster
+	err = checkNodeConfig(c.config)
+	if err != nil {
+		return err
+	}
+
 	// set the image
 	image := fmt.Sprintf("%s:%s", k3sBaseImage, c.config.Version)
 
 	// pull the container image
-	err = c.client.PullImage(config.Image{Name: image}, false)
+	err = c.client.PullImage(ctx, config.Image{Name: image}, false)
 	if err != nil {
 		return err
 	}
 
 	// create the volume for the cluster
-	volID, err := c.client.CreateVolume("images")
+	volID, err := c.client.CreateVolume(ctx, "images")
 	if err != nil {
 		return err
 	}
@@ -96,9 +119,12 @@ func (c *K8sCluster) createK3s() error {
 	cc := config.NewContainer(fmt.Sprintf("server.%s", c.config.Name))
 	c.config.ResourceInfo.AddChild(cc)
 
+	server := nodeConfig(c.config, "server")
+
 	cc.Image = config.Image{Name: image}
 	cc.Networks = c.config.Networks
 	cc.Privileged = true // k3s must run Privlidged
+	cc.Resources = server.Resources
 
 	// set the volume mount for the images
 	cc.Volumes = []config.Volume{
@@ -109,6 +135,48 @@ func (c *K8sCluster) createK3s() error {
 		},
 	}
 
+	// store the k3s data dir on a named volume, the volume is not removed
+	// on destroy so re-creating the cluster restores the previous workloads
+	if c.config.PersistData {
+		dv, err := c.createDataVolume(ctx, "server")
+		if err != nil {
+			return err
+		}
+
+		cc.Volumes = append(cc.Volumes, dv)
+	}
+
+	// use the shared image cache as a mirror for the Docker Hub unless
+	// the user has configured their own mirror
+	registries := c.config.Registries
+	if UsesImageCache(c.config) {
+		addr, err := EnsureImageCache(ctx, c.client, c.config.Networks, c.log)
+		if err != nil {
+			return xerrors.Errorf("Error creating image cache: %w", err)
+		}
+
+		// copy the registries so the cache is not added to the resource config
+		registries = append([]config.Registry{}, registries...)
+		registries = append(registries, config.Registry{Name: "docker.io", Mirrors: []string{addr}})
+	}
+
+	// add the registry configuration so containerd uses any mirrors
+	registryVolumes := []config.Volume{}
+	if len(registries) > 0 {
+		rc, err := c.createRegistriesConfig(registries)
+		if err != nil {
+			return xerrors.Errorf("Error creating registry config: %w", err)
+		}
+
+		registryVolumes = append(registryVolumes, config.Volume{
+			Source:      rc,
+			Destination: "/etc/rancher/k3s/registries.yaml",
+			Type:        "bind",
+		})
+	}
+
+	cc.Volumes = append(cc.Volumes, registryVolumes...)
+
 	// set the environment variables for the K3S_KUBECONFIG_OUTPUT and K3S_CLUSTER_SECRET
 	cc.Environment = []config.KV{
 		config.KV{Key: "K3S_KUBECONFIG_OUTPUT", Value: "/output/kubeconfig.yaml"},
@@ -116,7 +184,11 @@ func (c *K8sCluster) createK3s() error {
 	}
 
 	// set the API server port to a random number 64000 - 65000
-	apiPort := rand.Intn(1000) + 64000
+	// unless the user has pinned the port
+	apiPort := c.config.APIPort
+	if apiPort == 0 {
+		apiPort = rand.Intn(1000) + 64000
+	}
 	args := []string{"server", fmt.Sprintf("--https-listen-port=%d", apiPort)}
 
 	// expose the API server port
@@ -130,21 +202,41 @@ func (c *K8sCluster) createK3s() error {
 
 	// disable the installation of traefik
 	args = append(args, "--no-deploy=traefik")
+
+	// add any labels and taints for the node
+	args = append(args, nodeArgs(server)...)
+
+	// the server node also runs the agent so agent args are passed to the server
+	args = append(args, featureGateArgs(c.config.FeatureGates)...)
+	args = append(args, c.config.ServerArgs...)
+	args = append(args, c.config.AgentArgs...)
+
 	cc.Command = args
 
-	id, err := c.client.CreateContainer(cc)
+	id, err := c.client.CreateContainer(ctx, cc)
 	if err != nil {
 		return err
 	}
 
 	// wait for the server to start
-	err = c.waitForStart(id)
+	err = Idle(ctx, func() error { return c.waitForStart(ctx, id) })
 	if err != nil {
 		return err
 	}
 
+	// the additional nodes run the k3s agent and join the server
+	ids = []string{id}
+	for i := 1; i < c.config.Nodes; i++ {
+		aid, err := c.createAgent(ctx, i, image, apiPort, volID, registryVolumes)
+		if err != nil {
+			return xerrors.Errorf("Error creating agent node: %w", err)
+		}
+
+		ids = append(ids, aid)
+	}
+
 	// get the Kubernetes config file and drop it in $HOME/.shipyard/config/[clustername]/kubeconfig.yml
-	kc, err := c.copyKubeConfig(id)
+	kc, err := c.copyKubeConfig(ctx, id)
 	if err != nil {
 		return xerrors.Errorf("Error copying Kubernetes config: %w", err)
 	}
@@ -157,6 +249,26 @@ func (c *K8sCluster) createK3s() error {
 		return xerrors.Errorf("Error creating Docker Kubernetes config: %w", err)
 	}
 
+	// add a named context for the cluster to the managed Kubernetes config
+	// so that all clusters can be accessed from a single file
+	name := kubeContextName(c.config)
+	err = c.addKubeConfigContext(kc, utils.ManagedKubeConfigPath(), name)
+	if err != nil {
+		return xerrors.Errorf("Error adding cluster to Kubernetes config: %w", err)
+	}
+
+	c.config.KubeConfig = utils.ManagedKubeConfigPath()
+	c.config.KubeContext = name
+
+	// add the cluster to the users Kubernetes config so kubectl works
+	// without needing to set KUBECONFIG
+	if c.config.MergeKubeConfig {
+		err = c.addKubeConfigContext(kc, userKubeConfigPath(), name)
+		if err != nil {
+			return xerrors.Errorf("Error merging Kubernetes config: %w", err)
+		}
+	}
+
 	// wait for all the default pods like core DNS to start running
 	// before progressing
 	// we might also need to wait for the api services to become ready
@@ -166,10 +278,10 @@ func (c *K8sCluster) createK3s() error {
 		return err
 	}
 
-	err = c.kubeClient.HealthCheckPods([]string{""}, startTimeout)
+	err = Idle(ctx, func() error { return c.kubeClient.HealthCheckPods(ctx, []string{""}, startTimeout) })
 	if err != nil {
 		// fetch the logs from the container before exit
-		lr, err := c.client.ContainerLogs(id, true, true)
+		lr, err := c.client.ContainerLogs(ctx, id, true, true)
 		if err != nil {
 			c.log.Error("Unable to get logs from container", "error", err)
 		}
@@ -183,16 +295,239 @@ func (c *K8sCluster) createK3s() error {
 	// import the images to the servers container d instance
 	// importing images means that k3s does not need to pull from a remote docker hub
 	if c.config.Images != nil && len(c.config.Images) > 0 {
-		err := c.ImportLocalDockerImages(utils.ImageVolumeName, id, c.config.Images, false)
+		for _, i := range ids {
+			err := c.ImportLocalDockerImages(ctx, utils.ImageVolumeName, i, c.config.Images, false)
+			if err != nil {
+				return xerrors.Errorf("Error importing Docker images: %w", err)
+			}
+		}
+	}
+
+	// wait for any health checks defined by the user, resources which depend
+	// on the cluster such as Helm charts are not created until these pass
+	if hc := c.config.HealthCheck; hc != nil {
+		d, err := time.ParseDuration(hc.Timeout)
+		if err != nil {
+			return xerrors.Errorf("Unable to parse health check timeout: %w", err)
+		}
+
+		err = healthCheckEndpoints(ctx, c.httpClient, hc, d)
 		if err != nil {
-			return xerrors.Errorf("Error importing Docker images: %w", err)
+			return xerrors.Errorf("Error while waiting for cluster health checks: %w", err)
+		}
+
+		err = healthCheckKubernetes(ctx, c.kubeClient, hc)
+		if err != nil {
+			return xerrors.Errorf("Error while waiting for cluster health checks: %w", err)
 		}
 	}
 
 	return nil
 }
 
-func (c *K8sCluster) waitForStart(id string) error {
+// createDataVolume creates the persistent volume which stores the k3s data
+// dir for the node
+func (c *K8sCluster) createDataVolume(ctx context.Context, node string) (config.Volume, error) {
+	id, err := c.client.CreateVolume(clients.WithPersistentVolume(ctx), dataVolumeName(c.config.Name, node))
+	if err != nil {
+		return config.Volume{}, xerrors.Errorf("Error creating data volume for node %s: %w", node, err)
+	}
+
+	return config.Volume{
+		Source:      id,
+		Destination: "/var/lib/rancher/k3s",
+		Type:        "volume",
+	}, nil
+}
+
+// dataVolumeName returns the name of the volume used to persist the
+// data for a node in the cluster
+func dataVolumeName(name, node string) string {
+	if node == "server" {
+		return fmt.Sprintf("data.%s", name)
+	}
+
+	return fmt.Sprintf("data.%s.%s", node, name)
+}
+
+// ClusterDataVolumes returns the names of the Docker volumes which store the
+// data for the nodes in the cluster, no volumes are returned when the data is
+// not persisted and is lost when the node containers are removed
+func ClusterDataVolumes(k *config.K8sCluster) []string {
+	names := []string{}
+	if !k.PersistData {
+		return names
+	}
+
+	for _, n := range nodeNames(k) {
+		names = append(names, utils.FQDNVolumeName(dataVolumeName(k.Name, n)))
+	}
+
+	return names
+}
+
+// featureGateArgs returns the k3s arguments which enable the given
+// feature gates on all of the Kubernetes components
+func featureGateArgs(gates []string) []string {
+	if len(gates) == 0 {
+		return []string{}
+	}
+
+	fg := strings.Join(gates, ",")
+
+	return []string{
+		fmt.Sprintf("--kube-apiserver-arg=feature-gates=%s", fg),
+		fmt.Sprintf("--kube-controller-manager-arg=feature-gates=%s", fg),
+		fmt.Sprintf("--kube-scheduler-arg=feature-gates=%s", fg),
+		fmt.Sprintf("--kubelet-arg=feature-gates=%s", fg),
+	}
+}
+
+// createAgent creates the container for an additional node in the cluster,
+// the k3s agent in the container joins the server on the API port
+func (c *K8sCluster) createAgent(ctx context.Context, n int, image string, apiPort int, volID string, registryVolumes []config.Volume) (string, error) {
+	name := agentName(n)
+
+	cc := config.NewContainer(fmt.Sprintf("%s.%s", name, c.config.Name))
+	c.config.ResourceInfo.AddChild(cc)
+
+	node := nodeConfig(c.config, name)
+
+	cc.Image = config.Image{Name: image}
+	cc.Networks = c.config.Networks
+	cc.Privileged = true
+	cc.Resources = node.Resources
+
+	cc.Volumes = []config.Volume{
+		config.Volume{
+			Source:      volID,
+			Destination: "/images",
+			Type:        "volume",
+		},
+	}
+	cc.Volumes = append(cc.Volumes, registryVolumes...)
+
+	if c.config.PersistData {
+		dv, err := c.createDataVolume(ctx, name)
+		if err != nil {
+			return "", err
+		}
+
+		cc.Volumes = append(cc.Volumes, dv)
+	}
+
+	cc.Environment = []config.KV{
+		config.KV{Key: "K3S_URL", Value: fmt.Sprintf("https://server.%s:%d", utils.FQDN(c.config.Name, string(c.config.Type)), apiPort)},
+		config.KV{Key: "K3S_CLUSTER_SECRET", Value: "mysupersecret"},
+	}
+
+	args := []string{"agent"}
+	args = append(args, nodeArgs(node)...)
+
+	// only the kubelet runs on an agent node
+	if len(c.config.FeatureGates) > 0 {
+		args = append(args, fmt.Sprintf("--kubelet-arg=feature-gates=%s", strings.Join(c.config.FeatureGates, ",")))
+	}
+
+	args = append(args, c.config.AgentArgs...)
+
+	cc.Command = args
+
+	id, err := c.client.CreateContainer(ctx, cc)
+	if err != nil {
+		return "", err
+	}
+
+	err = Idle(ctx, func() error { return c.waitForStart(ctx, id) })
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// agentName returns the name of the nth additional node in the cluster
+func agentName(n int) string {
+	return fmt.Sprintf("agent-%d", n)
+}
+
+// nodeNames returns the names of the nodes in the cluster, the first node
+// is the server
+func nodeNames(k *config.K8sCluster) []string {
+	names := []string{"server"}
+	for i := 1; i < k.Nodes; i++ {
+		names = append(names, agentName(i))
+	}
+
+	return names
+}
+
+// checkNodeConfig returns an error when the cluster has config for a node
+// which the cluster does not have
+func checkNodeConfig(k *config.K8sCluster) error {
+	names := nodeNames(k)
+
+	for _, n := range k.NodeConfig {
+		found := false
+		for _, name := range names {
+			if n.Name == name {
+				found = true
+			}
+		}
+
+		if !found {
+			return xerrors.Errorf("Cluster does not have node %s, nodes are named %s", n.Name, strings.Join(names, ", "))
+		}
+	}
+
+	return nil
+}
+
+// nodeConfig returns the config for the named node, nodes without their own
+// resources use the resources set for the cluster
+func nodeConfig(k *config.K8sCluster, name string) config.Node {
+	node := config.Node{Name: name}
+
+	for _, n := range k.NodeConfig {
+		if n.Name == name {
+			node = n
+		}
+	}
+
+	if node.Resources == nil {
+		node.Resources = k.Resources
+	}
+
+	return node
+}
+
+// nodeArgs returns the k3s arguments which add the labels and taints for the node
+func nodeArgs(n config.Node) []string {
+	args := []string{}
+
+	for _, l := range n.Labels {
+		args = append(args, fmt.Sprintf("--node-label=%s=%s", l.Key, l.Value))
+	}
+
+	for _, t := range n.Taints {
+		args = append(args, fmt.Sprintf("--node-taint=%s", taintString(t)))
+	}
+
+	return args
+}
+
+// taintString returns the taint in the format key=value:effect
+// used by the k3s --node-taint flag
+func taintString(t config.Taint) string {
+	effect := t.Effect
+	if effect == "" {
+		effect = "NoSchedule"
+	}
+
+	return fmt.Sprintf("%s=%s:%s", t.Key, t.Value, effect)
+}
+
+func (c *K8sCluster) waitForStart(ctx context.Context, id string) error {
 	start := time.Now()
 
 	for {
@@ -203,7 +538,7 @@ func (c *K8sCluster) waitForStart(id string) error {
 		}
 
 		// scan container logs for a line that tells us that the required services are up and running
-		out, err := c.client.ContainerLogs(id, true, true)
+		out, err := c.client.ContainerLogs(ctx, id, true, true)
 		if err != nil {
 			out.Close()
 			return fmt.Errorf(" Couldn't get docker logs for %s\n%+v", id, err)
@@ -225,12 +560,12 @@ func (c *K8sCluster) waitForStart(id string) error {
 	return nil
 }
 
-func (c *K8sCluster) copyKubeConfig(id string) (string, error) {
+func (c *K8sCluster) copyKubeConfig(ctx context.Context, id string) (string, error) {
 	// create destination kubeconfig file paths
 	_, destPath, _ := utils.CreateKubeConfigPath(c.config.Name)
 
 	// get kubeconfig file from container and read contents
-	err := c.client.CopyFromContainer(id, "/output/kubeconfig.yaml", destPath)
+	err := c.client.CopyFromContainer(ctx, id, "/output/kubeconfig.yaml", destPath)
 	if err != nil {
 		return "", err
 	}
@@ -238,6 +573,70 @@ func (c *K8sCluster) copyKubeConfig(id string) (string, error) {
 	return destPath, nil
 }
 
+// hasRegistry returns true if the registry with the given name is configured
+func hasRegistry(registries []config.Registry, name string) bool {
+	for _, r := range registries {
+		if r.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// createRegistriesConfig writes the k3s registries.yaml for the cluster
+// and returns the path of the file
+// https://rancher.com/docs/k3s/latest/en/installation/private-registry/
+func (c *K8sCluster) createRegistriesConfig(registries []config.Registry) (string, error) {
+	mirrors := bytes.NewBufferString("mirrors:\n")
+	configs := bytes.NewBufferString("configs:\n")
+
+	for _, r := range registries {
+		if len(r.Mirrors) > 0 {
+			fmt.Fprintf(mirrors, "  %q:\n    endpoint:\n", r.Name)
+			for _, m := range r.Mirrors {
+				fmt.Fprintf(mirrors, "      - %q\n", m)
+			}
+		}
+
+		if r.Username == "" && !r.Insecure {
+			continue
+		}
+
+		// auth and tls settings apply to the registry and all of its mirrors
+		hosts := []string{r.Name}
+		for _, m := range r.Mirrors {
+			hosts = append(hosts, strings.TrimPrefix(strings.TrimPrefix(m, "https://"), "http://"))
+		}
+
+		for _, h := range hosts {
+			fmt.Fprintf(configs, "  %q:\n", h)
+
+			if r.Username != "" {
+				fmt.Fprintf(configs, "    auth:\n      username: %q\n      password: %q\n", r.Username, r.Password)
+			}
+
+			if r.Insecure {
+				fmt.Fprintf(configs, "    tls:\n      insecure_skip_verify: true\n")
+			}
+		}
+	}
+
+	dir, _, _ := utils.CreateKubeConfigPath(c.config.Name)
+	path := fmt.Sprintf("%s/registries.yaml", dir)
+
+	// the file contains registry credentials, the mode of an existing file
+	// is not changed by WriteFile so it is restricted before writing
+	os.Chmod(path, 0600)
+
+	err := ioutil.WriteFile(path, append(mirrors.Bytes(), configs.Bytes()...), 0600)
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
 func (c *K8sCluster) createDockerKubeConfig(kubeconfig string) error {
 	// read the config into a string
 	f, err := os.OpenFile(kubeconfig, os.O_RDONLY, 0666)
@@ -273,11 +672,11 @@ func (c *K8sCluster) createDockerKubeConfig(kubeconfig string) error {
 }
 
 // ImportLocalDockerImages fetches Docker images stored on the local client and imports them into the cluster
-func (c *K8sCluster) ImportLocalDockerImages(name string, id string, images []config.Image, force bool) error {
+func (c *K8sCluster) ImportLocalDockerImages(ctx context.Context, name string, id string, images []config.Image, force bool) error {
 	imgs := []string{}
 
 	for _, i := range images {
-		err := c.client.PullImage(i, false)
+		err := c.client.PullImage(ctx, i, false)
 		if err != nil {
 			return err
 		}
@@ -287,7 +686,7 @@ func (c *K8sCluster) ImportLocalDockerImages(name string, id string, images []co
 
 	// import to volume
 	vn := utils.FQDNVolumeName(name)
-	imagesFile, err := c.client.CopyLocalDockerImageToVolume(imgs, vn, force)
+	imagesFile, err := c.client.CopyLocalDockerImageToVolume(ctx, imgs, vn, force)
 	if err != nil {
 		return err
 	}
@@ -295,7 +694,7 @@ func (c *K8sCluster) ImportLocalDockerImages(name string, id string, images []co
 	for _, i := range imagesFile {
 		// execute the command to import the image
 		// write any command output to the logger
-		err = c.client.ExecuteCommand(id, []string{"ctr", "image", "import", "/images/" + i}, nil, "/", c.log.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug}))
+		err = c.client.ExecuteCommand(ctx, id, []string{"ctr", "image", "import", "/images/" + i}, nil, "/", c.log.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug}))
 		if err != nil {
 			return err
 		}
@@ -304,10 +703,10 @@ func (c *K8sCluster) ImportLocalDockerImages(name string, id string, images []co
 	return nil
 }
 
-func (c *K8sCluster) destroyK3s() error {
+func (c *K8sCluster) destroyK3s(ctx context.Context) error {
 	c.log.Info("Destroy Cluster", "ref", c.config.Name)
 
-	ids, err := c.client.FindContainerIDs(fmt.Sprintf("server.%s", c.config.Name), c.config.Type)
+	ids, err := c.Lookup(ctx)
 	if err != nil {
 		return err
 	}
@@ -315,17 +714,222 @@ func (c *K8sCluster) destroyK3s() error {
 	for _, i := range ids {
 		// remove from the networks
 		for _, n := range c.config.Networks {
-			err := c.client.DetachNetwork(n.Name, i)
+			err := c.client.DetachNetwork(ctx, n.Name, i)
 			if err != nil {
 				return err
 			}
 		}
 
-		err := c.client.RemoveContainer(i)
+		err := c.client.RemoveContainer(ctx, i)
 		if err != nil {
 			return err
 		}
 	}
 
+	// the shared image cache would stop the networks from being removed
+	if UsesImageCache(c.config) {
+		err = detachImageCache(ctx, c.client, c.config.Networks, c.log)
+		if err != nil {
+			return xerrors.Errorf("Error detaching image cache: %w", err)
+		}
+	}
+
+	// clusters created before contexts were recorded use the default name
+	name := c.config.KubeContext
+	if name == "" {
+		name = kubeContextName(c.config)
+	}
+
+	err = removeKubeConfigContext(utils.ManagedKubeConfigPath(), name)
+	if err != nil {
+		return xerrors.Errorf("Error removing cluster from Kubernetes config: %w", err)
+	}
+
+	if c.config.MergeKubeConfig {
+		err = removeKubeConfigContext(userKubeConfigPath(), name)
+		if err != nil {
+			return xerrors.Errorf("Error removing cluster from Kubernetes config: %w", err)
+		}
+	}
+
+	c.config.KubeConfig = ""
+	c.config.KubeContext = ""
+
 	return nil
 }
+
+// kubeContextName returns the name of the cluster, user, and context used
+// when adding the cluster to a Kubernetes config, when the cluster is part
+// of a blueprint the slug is included e.g. shipyard-[blueprint]-[cluster]
+func kubeContextName(c *config.K8sCluster) string {
+	if c.Config != nil && c.Config.Blueprint != nil && c.Config.Blueprint.Slug != "" {
+		env, _ := utils.ReplaceNonURIChars(c.Config.Blueprint.Slug)
+		return fmt.Sprintf("shipyard-%s-%s", env, c.Name)
+	}
+
+	return fmt.Sprintf("shipyard-%s", c.Name)
+}
+
+// loadKubeConfig loads the Kubernetes config at the given path, when the
+// file does not exist an empty config is returned
+func loadKubeConfig(path string) (*clientcmdapi.Config, error) {
+	kc := &clientcmdapi.Config{APIVersion: "v1", Kind: "Config"}
+
+	d, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return kc, nil
+		}
+
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(d, kc)
+	if err != nil {
+		return nil, err
+	}
+
+	return kc, nil
+}
+
+// writeKubeConfig writes the Kubernetes config to the given path
+func writeKubeConfig(kc *clientcmdapi.Config, path string) error {
+	d, err := yaml.Marshal(kc)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, d, 0600)
+}
+
+// removeKubeConfigEntries removes the cluster, user, and context with
+// the given name from the Kubernetes config
+func removeKubeConfigEntries(kc *clientcmdapi.Config, name string) {
+	clusters := []clientcmdapi.NamedCluster{}
+	for _, c := range kc.Clusters {
+		if c.Name != name {
+			clusters = append(clusters, c)
+		}
+	}
+
+	users := []clientcmdapi.NamedAuthInfo{}
+	for _, u := range kc.AuthInfos {
+		if u.Name != name {
+			users = append(users, u)
+		}
+	}
+
+	contexts := []clientcmdapi.NamedContext{}
+	for _, c := range kc.Contexts {
+		if c.Name != name {
+			contexts = append(contexts, c)
+		}
+	}
+
+	kc.Clusters = clusters
+	kc.AuthInfos = users
+	kc.Contexts = contexts
+
+	if kc.CurrentContext == name {
+		kc.CurrentContext = ""
+	}
+}
+
+// userKubeConfigPath returns the location of the users Kubernetes config,
+// the same file kubectl writes to is used. When $KUBECONFIG is set the first
+// file in the list which exists is used, or the last file when none exist,
+// otherwise the config is $HOME/.kube/config
+func userKubeConfigPath() string {
+	paths := []string{}
+	for _, p := range filepath.SplitList(os.Getenv("KUBECONFIG")) {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+
+	for _, p := range paths {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+
+	if len(paths) > 0 {
+		return paths[len(paths)-1]
+	}
+
+	return fmt.Sprintf("%s/.kube/config", utils.HomeFolder())
+}
+
+// addKubeConfigContext adds the cluster, user, and context from the generated
+// Kubernetes config to the config at path using the given name and sets it as
+// the current context
+func (c *K8sCluster) addKubeConfigContext(kubeconfig, path, name string) error {
+	kc, err := loadKubeConfig(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	var ctx *clientcmdapi.Context
+	for _, nc := range kc.Contexts {
+		if nc.Name == kc.CurrentContext {
+			nctx := nc.Context
+			ctx = &nctx
+		}
+	}
+
+	if ctx == nil {
+		return fmt.Errorf("Kubernetes config %s does not contain the context %s", kubeconfig, kc.CurrentContext)
+	}
+
+	uc, err := loadKubeConfig(path)
+	if err != nil {
+		return err
+	}
+
+	removeKubeConfigEntries(uc, name)
+
+	for _, cl := range kc.Clusters {
+		if cl.Name == ctx.Cluster {
+			uc.Clusters = append(uc.Clusters, clientcmdapi.NamedCluster{Name: name, Cluster: cl.Cluster})
+		}
+	}
+
+	for _, u := range kc.AuthInfos {
+		if u.Name == ctx.AuthInfo {
+			uc.AuthInfos = append(uc.AuthInfos, clientcmdapi.NamedAuthInfo{Name: name, AuthInfo: u.AuthInfo})
+		}
+	}
+
+	uc.Contexts = append(uc.Contexts, clientcmdapi.NamedContext{
+		Name:    name,
+		Context: clientcmdapi.Context{Cluster: name, AuthInfo: name, Namespace: ctx.Namespace},
+	})
+	uc.CurrentContext = name
+
+	c.log.Debug("Adding context to Kubernetes config", "ref", c.config.Name, "path", path, "context", name)
+
+	return writeKubeConfig(uc, path)
+}
+
+// removeKubeConfigContext removes the cluster, user, and context added by
+// addKubeConfigContext from the config at path
+func removeKubeConfigContext(path, name string) error {
+	// nothing to remove
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	uc, err := loadKubeConfig(path)
+	if err != nil {
+		return err
+	}
+
+	removeKubeConfigEntries(uc, name)
+
+	return writeKubeConfig(uc, path)
+}
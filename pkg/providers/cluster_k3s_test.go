@@ -2,9 +2,12 @@ package providers
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -33,6 +36,7 @@ func setupClusterMocks() (*config.K8sCluster, *mocks.MockContainerTasks, *mocks.
 	md.On("RemoveContainer", mock.Anything).Return(nil)
 	md.On("RemoveVolume", mock.Anything).Return(nil)
 	md.On("DetachNetwork", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	md.On("AttachNetwork", mock.Anything, mock.Anything).Return(nil)
 
 	// set the home folder to a temp folder
 	tmpDir, _ := ioutil.TempDir("", "")
@@ -45,7 +49,7 @@ func setupClusterMocks() (*config.K8sCluster, *mocks.MockContainerTasks, *mocks.
 	if err != nil {
 		panic(err)
 	}
-	kcf.WriteString(kubeconfig)
+	kcf.WriteString(validKubeconfig)
 	kcf.Close()
 
 	// create the Kubernetes client mock
@@ -61,8 +65,13 @@ func setupClusterMocks() (*config.K8sCluster, *mocks.MockContainerTasks, *mocks.
 	c.AddResource(&cc)
 	c.AddResource(&cn)
 
+	// the users Kubernetes config is in the temp home folder
+	currentKubeConfig := os.Getenv("KUBECONFIG")
+	os.Unsetenv("KUBECONFIG")
+
 	return &cc, md, mk, func() {
 		os.Setenv("HOME", currentHome)
+		os.Setenv("KUBECONFIG", currentKubeConfig)
 		os.RemoveAll(tmpDir)
 	}
 }
@@ -74,7 +83,7 @@ func TestClusterK3ErrorsWhenUnableToLookupIDs(t *testing.T) {
 	mk := &mocks.MockKubernetes{}
 	p := NewK8sCluster(clusterConfig, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
@@ -85,7 +94,47 @@ func TestClusterK3ErrorsWhenClusterExists(t *testing.T) {
 	mk := &mocks.MockKubernetes{}
 	p := NewK8sCluster(clusterConfig, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
+	assert.Error(t, err)
+}
+
+func TestClusterK3RunsHealthChecks(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	cc.HealthCheck = &config.HealthCheck{
+		Timeout: "30s",
+		TCP:     "localhost:6443",
+		Pods:    []string{"k8s-app=metrics-server"},
+	}
+
+	hc := &mocks.MockHTTP{}
+	hc.On("HealthCheckTCP", mock.Anything, mock.Anything).Return(nil)
+
+	p := NewK8sCluster(cc, md, mk, hc, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	hc.AssertCalled(t, "HealthCheckTCP", "localhost:6443", 30*time.Second)
+	mk.AssertCalled(t, "HealthCheckPods", []string{"k8s-app=metrics-server"}, 30*time.Second)
+}
+
+func TestClusterK3HealthCheckFailureReturnsError(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	cc.HealthCheck = &config.HealthCheck{
+		Timeout: "30s",
+		TCP:     "localhost:6443",
+	}
+
+	hc := &mocks.MockHTTP{}
+	hc.On("HealthCheckTCP", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	p := NewK8sCluster(cc, md, mk, hc, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
@@ -95,7 +144,7 @@ func TestClusterK3PullsImage(t *testing.T) {
 
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 	md.AssertCalled(t, "PullImage", config.Image{Name: "rancher/k3s:v1.0.0"}, false)
 }
@@ -106,7 +155,7 @@ func TestClusterK3CreatesANewVolume(t *testing.T) {
 
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 	md.AssertCalled(t, "CreateVolume", utils.ImageVolumeName)
 }
@@ -120,7 +169,7 @@ func TestClusterK3FailsWhenUnableToCreatesANewVolume(t *testing.T) {
 
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 	md.AssertCalled(t, "CreateVolume", utils.ImageVolumeName)
 }
@@ -131,7 +180,7 @@ func TestClusterK3CreatesAServer(t *testing.T) {
 
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
@@ -158,6 +207,348 @@ func TestClusterK3CreatesAServer(t *testing.T) {
 	assert.Contains(t, params.Command[2], "traefik")
 }
 
+func TestClusterK3CreatesAServerWithResourcesLabelsAndTaints(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	cc.Resources = &config.Resources{CPU: 2048, Memory: 1024}
+	cc.NodeConfig = []config.Node{
+		config.Node{
+			Name:   "server",
+			Labels: []config.KV{config.KV{Key: "zone", Value: "west"}},
+			Taints: []config.Taint{
+				config.Taint{Key: "dedicated", Value: "gpu"},
+				config.Taint{Key: "spot", Value: "true", Effect: "NoExecute"},
+			},
+		},
+	}
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+
+	assert.Equal(t, cc.Resources, params.Resources)
+	assert.Contains(t, params.Command, "--node-label=zone=west")
+	assert.Contains(t, params.Command, "--node-taint=dedicated=gpu:NoSchedule")
+	assert.Contains(t, params.Command, "--node-taint=spot=true:NoExecute")
+}
+
+func TestClusterK3CreatesAgentsWithNodeConfig(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	// each node reads the logs to check the kubelet is running
+	removeOn(&md.Mock, "ContainerLogs")
+	for i := 0; i < 3; i++ {
+		md.On("ContainerLogs", mock.Anything, true, true).Return(
+			ioutil.NopCloser(bytes.NewBufferString("Running kubelet")),
+			nil,
+		).Once()
+	}
+
+	cc.Nodes = 3
+	cc.Resources = &config.Resources{CPU: 2048}
+	cc.FeatureGates = []string{"EphemeralContainers=true"}
+	cc.NodeConfig = []config.Node{
+		config.Node{
+			Name:      "agent-2",
+			Resources: &config.Resources{Memory: 512},
+			Labels:    []config.KV{config.KV{Key: "zone", Value: "east"}},
+			Taints:    []config.Taint{config.Taint{Key: "dedicated", Value: "gpu"}},
+		},
+	}
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	calls := getCalls(&md.Mock, "CreateContainer")
+	assert.Len(t, calls, 3)
+
+	server := calls[0].Arguments[0].(*config.Container)
+	assert.NotContains(t, server.Command, "--node-label=zone=east")
+
+	agent1 := calls[1].Arguments[0].(*config.Container)
+	assert.Equal(t, "agent-1.test", agent1.Name)
+	assert.Equal(t, "agent", agent1.Command[0])
+	assert.Equal(t, cc.Resources, agent1.Resources)
+	assert.NotContains(t, agent1.Command, "--node-label=zone=east")
+	assert.Contains(t, agent1.Command, "--kubelet-arg=feature-gates=EphemeralContainers=true")
+	assert.Contains(t, agent1.Environment[0].Value, "https://server.test.k8s_cluster.shipyard.run:")
+
+	agent2 := calls[2].Arguments[0].(*config.Container)
+	assert.Equal(t, "agent-2.test", agent2.Name)
+	assert.Equal(t, 512, agent2.Resources.Memory)
+	assert.Contains(t, agent2.Command, "--node-label=zone=east")
+	assert.Contains(t, agent2.Command, "--node-taint=dedicated=gpu:NoSchedule")
+
+	// images are imported to every node
+	md.AssertNumberOfCalls(t, "CopyLocalDockerImageToVolume", 3)
+}
+
+func TestClusterK3ReturnsErrorWhenNodeConfigIsForUnknownNode(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	cc.NodeConfig = []config.Node{config.Node{Name: "agent-1"}}
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.Error(t, err)
+
+	md.AssertNotCalled(t, "CreateContainer", mock.Anything)
+}
+
+func TestClusterK3CreatesRegistriesConfig(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	cc.Registries = []config.Registry{
+		config.Registry{Name: "docker.io", Mirrors: []string{"https://mirror.corp.com"}},
+		config.Registry{Name: "registry.corp.com:5000", Username: "nic", Password: "secret", Insecure: true},
+	}
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+
+	dir, _, _ := utils.CreateKubeConfigPath(cc.Name)
+	assert.Equal(t, dir+"/registries.yaml", params.Volumes[1].Source)
+	assert.Equal(t, "/etc/rancher/k3s/registries.yaml", params.Volumes[1].Destination)
+	assert.Equal(t, "bind", params.Volumes[1].Type)
+
+	d, err := ioutil.ReadFile(params.Volumes[1].Source)
+	assert.NoError(t, err)
+	assert.Contains(t, string(d), "\"docker.io\":\n    endpoint:\n      - \"https://mirror.corp.com\"")
+	assert.Contains(t, string(d), "\"registry.corp.com:5000\":\n    auth:\n      username: \"nic\"\n      password: \"secret\"")
+	assert.Contains(t, string(d), "insecure_skip_verify: true")
+}
+
+func TestClusterK3RestrictsRegistriesConfigPermissions(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	cc.Registries = []config.Registry{
+		config.Registry{Name: "registry.corp.com:5000", Username: "nic", Password: "secret"},
+	}
+
+	// an existing file keeps its mode when it is written
+	dir, _, _ := utils.CreateKubeConfigPath(cc.Name)
+	ioutil.WriteFile(dir+"/registries.yaml", []byte(""), 0644)
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	fi, err := os.Stat(dir + "/registries.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+}
+
+func TestClusterK3CreatesImageCache(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	cc.ImageCache = true
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	// the cache is created before the server
+	cache := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	assert.Equal(t, imageCacheName, cache.Name)
+	assert.Equal(t, "/var/lib/registry", cache.Volumes[0].Destination)
+
+	md.AssertCalled(t, "CreateVolume", imageCacheName)
+	md.AssertCalled(t, "AttachNetwork", cc.Networks[0].Name, "containerid")
+
+	params := getCalls(&md.Mock, "CreateContainer")[1].Arguments[0].(*config.Container)
+	assert.Equal(t, "/etc/rancher/k3s/registries.yaml", params.Volumes[1].Destination)
+
+	d, err := ioutil.ReadFile(params.Volumes[1].Source)
+	assert.NoError(t, err)
+	assert.Contains(t, string(d), imageCacheAddress)
+
+	// the cache should not be added to the resource config
+	assert.Len(t, cc.Registries, 0)
+}
+
+func TestClusterK3UsesImageCacheCreatedByAnotherProcess(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	// the cache does not exist when looked up but creating it conflicts
+	removeOn(&md.Mock, "FindContainerIDs")
+	removeOn(&md.Mock, "CreateContainer")
+	md.On("FindContainerIDs", imageCacheName, config.TypeContainer).Return([]string{}, nil).Once()
+	md.On("FindContainerIDs", imageCacheName, config.TypeContainer).Return([]string{"cacheid"}, nil)
+	md.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{}, nil)
+	md.On("CreateContainer", mock.MatchedBy(func(c *config.Container) bool {
+		return c.Name == imageCacheName
+	})).Return("", fmt.Errorf("Conflict. The container name is already in use"))
+	md.On("CreateContainer", mock.Anything).Return("containerid", nil)
+
+	cc.ImageCache = true
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "AttachNetwork", cc.Networks[0].Name, "cacheid")
+}
+
+func TestClusterK3PublishesImageCacheOnHostPort(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	cc.ImageCache = true
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	cache := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	assert.Equal(t, "5000", cache.Ports[0].Local)
+	assert.Equal(t, imageCacheHostPort, cache.Ports[0].Host)
+	assert.Equal(t, "127.0.0.1:"+imageCacheHostPort, ImageCacheMirror)
+}
+
+func TestClusterK3DoesNotCreateImageCacheWhenDockerHubMirrorSet(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	cc.ImageCache = true
+	cc.Registries = []config.Registry{
+		config.Registry{Name: "docker.io", Mirrors: []string{"https://mirror.corp.com"}},
+	}
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	md.AssertNotCalled(t, "CreateVolume", imageCacheName)
+	md.AssertNumberOfCalls(t, "CreateContainer", 1)
+}
+
+func TestClusterK3CreatesAServerWithPinnedAPIPort(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	cc.APIPort = 6443
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+
+	assert.Equal(t, "6443", params.Ports[0].Local)
+	assert.Equal(t, "6443", params.Ports[0].Host)
+	assert.Equal(t, "--https-listen-port=6443", params.Command[1])
+}
+
+func TestClusterK3CreatesAServerWithCustomArgs(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	cc.ServerArgs = []string{"--no-deploy=servicelb"}
+	cc.AgentArgs = []string{"--kubelet-arg=max-pods=200"}
+	cc.FeatureGates = []string{"EphemeralContainers=true", "TTLAfterFinished=true"}
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+
+	assert.Contains(t, params.Command, "--no-deploy=servicelb")
+	assert.Contains(t, params.Command, "--kubelet-arg=max-pods=200")
+	assert.Contains(t, params.Command, "--kube-apiserver-arg=feature-gates=EphemeralContainers=true,TTLAfterFinished=true")
+	assert.Contains(t, params.Command, "--kubelet-arg=feature-gates=EphemeralContainers=true,TTLAfterFinished=true")
+}
+
+func TestClusterK3CreatesAServerWithPersistentData(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	cc.PersistData = true
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "CreateVolume", "data."+cc.Name)
+
+	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+
+	assert.Equal(t, "123", params.Volumes[1].Source)
+	assert.Equal(t, "/var/lib/rancher/k3s", params.Volumes[1].Destination)
+	assert.Equal(t, "volume", params.Volumes[1].Type)
+}
+
+func TestClusterK3CreatesAgentsWithPersistentData(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	removeOn(&md.Mock, "ContainerLogs")
+	for i := 0; i < 2; i++ {
+		md.On("ContainerLogs", mock.Anything, true, true).Return(
+			ioutil.NopCloser(bytes.NewBufferString("Running kubelet")),
+			nil,
+		).Once()
+	}
+
+	cc.Nodes = 2
+	cc.PersistData = true
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "CreateVolume", "data."+cc.Name)
+	md.AssertCalled(t, "CreateVolume", "data.agent-1."+cc.Name)
+
+	agent := getCalls(&md.Mock, "CreateContainer")[1].Arguments[0].(*config.Container)
+	dv := agent.Volumes[len(agent.Volumes)-1]
+	assert.Equal(t, "/var/lib/rancher/k3s", dv.Destination)
+	assert.Equal(t, "volume", dv.Type)
+
+	assert.Equal(t,
+		[]string{"data.test.volume.shipyard.run", "data.agent-1.test.volume.shipyard.run"},
+		ClusterDataVolumes(cc),
+	)
+}
+
+func TestClusterK3CreatesAServerWithoutPersistentDataByDefault(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	md.AssertNotCalled(t, "CreateVolume", "data."+cc.Name)
+}
+
 func TestClusterK3sErrorsIfServerNOTStart(t *testing.T) {
 	cc, md, mk, cleanup := setupClusterMocks()
 	defer cleanup()
@@ -171,7 +562,7 @@ func TestClusterK3sErrorsIfServerNOTStart(t *testing.T) {
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
 	startTimeout = 10 * time.Millisecond // reset the startTimeout, do not want to wait 120s
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
@@ -181,7 +572,7 @@ func TestClusterK3sDownloadsConfig(t *testing.T) {
 
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	_, destPath, _ := utils.CreateKubeConfigPath(clusterConfig.Name)
@@ -200,7 +591,7 @@ func TestClusterK3sRaisesErrorWhenUnableToDownloadConfig(t *testing.T) {
 
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
@@ -210,7 +601,7 @@ func TestClusterK3sCreatesDockerConfig(t *testing.T) {
 
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	// check the kubeconfig file for docker uses a network ip not localhost
@@ -227,13 +618,118 @@ func TestClusterK3sCreatesDockerConfig(t *testing.T) {
 	assert.Contains(t, string(d), fmt.Sprintf("server.%s", utils.FQDN(clusterConfig.Name, string(clusterConfig.Type))))
 }
 
+func TestClusterK3sMergesKubeConfig(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	cc.MergeKubeConfig = true
+	_, destPath, _ := utils.CreateKubeConfigPath(cc.Name)
+	ioutil.WriteFile(destPath, []byte(validKubeconfig), 0644)
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	d, err := ioutil.ReadFile(utils.HomeFolder() + "/.kube/config")
+	assert.NoError(t, err)
+	assert.Contains(t, string(d), "current-context: shipyard-test")
+	assert.Contains(t, string(d), "server: https://127.0.0.1:64674")
+}
+
+func TestClusterK3sMergesKubeConfigIntoKubeConfigEnv(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	// the first file which exists is used
+	existing := filepath.Join(utils.HomeFolder(), "existing.yaml")
+	ioutil.WriteFile(existing, []byte(""), 0644)
+	os.Setenv("KUBECONFIG", strings.Join([]string{filepath.Join(utils.HomeFolder(), "missing.yaml"), existing}, string(os.PathListSeparator)))
+
+	cc.MergeKubeConfig = true
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	d, err := ioutil.ReadFile(existing)
+	assert.NoError(t, err)
+	assert.Contains(t, string(d), "current-context: shipyard-test")
+	assert.NoFileExists(t, utils.HomeFolder()+"/.kube/config")
+
+	err = p.Destroy(context.Background())
+	assert.NoError(t, err)
+
+	d, err = ioutil.ReadFile(existing)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(d), "shipyard-test")
+}
+
+func TestUserKubeConfigPathUsesLastFileWhenNoneExist(t *testing.T) {
+	_, _, _, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	os.Setenv("KUBECONFIG", strings.Join([]string{"/tmp/missing-1.yaml", "/tmp/missing-2.yaml"}, string(os.PathListSeparator)))
+	assert.Equal(t, "/tmp/missing-2.yaml", userKubeConfigPath())
+
+	os.Unsetenv("KUBECONFIG")
+	assert.Equal(t, utils.HomeFolder()+"/.kube/config", userKubeConfigPath())
+}
+
+func TestClusterK3sAddsContextToManagedKubeConfig(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	assert.Equal(t, utils.ManagedKubeConfigPath(), cc.KubeConfig)
+	assert.Equal(t, "shipyard-test", cc.KubeContext)
+
+	d, err := ioutil.ReadFile(utils.ManagedKubeConfigPath())
+	assert.NoError(t, err)
+	assert.Contains(t, string(d), "current-context: shipyard-test")
+	assert.Contains(t, string(d), "server: https://127.0.0.1:64674")
+}
+
+func TestClusterK3sContextIncludesBlueprintSlug(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	c := config.New()
+	c.Blueprint = &config.Blueprint{Slug: "consul-demo"}
+	c.AddResource(cc)
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	assert.Equal(t, "shipyard-consul-demo-test", cc.KubeContext)
+}
+
+func TestClusterK3sDoesNotMergeKubeConfigByDefault(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	assert.NoFileExists(t, utils.HomeFolder()+"/.kube/config")
+}
+
 func TestClusterK3sCreatesKubeClient(t *testing.T) {
 	cc, md, mk, cleanup := setupClusterMocks()
 	defer cleanup()
 
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 	mk.AssertCalled(t, "SetConfig", mock.Anything)
 }
@@ -247,7 +743,7 @@ func TestClusterK3sErrorsWhenFailedToCreateKubeClient(t *testing.T) {
 
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
@@ -257,7 +753,7 @@ func TestClusterK3sWaitsForPods(t *testing.T) {
 
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 	mk.AssertCalled(t, "HealthCheckPods", []string{""}, startTimeout)
 }
@@ -270,7 +766,7 @@ func TestClusterK3sErrorsWhenWaitsForPodsFail(t *testing.T) {
 
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
@@ -280,7 +776,7 @@ func TestClusterK3sImportDockerImagesPullsImages(t *testing.T) {
 
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 	md.AssertCalled(t, "PullImage", clusterConfig.Images[0], false)
 	md.AssertCalled(t, "PullImage", clusterConfig.Images[1], false)
@@ -292,7 +788,7 @@ func TestClusterK3sImportDockerCopiesImages(t *testing.T) {
 
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 	md.AssertCalled(t, "CopyLocalDockerImageToVolume", []string{"consul:1.6.1", "vault:1.6.1"}, utils.FQDNVolumeName(utils.ImageVolumeName), false)
 }
@@ -304,7 +800,7 @@ func TestClusterK3sImportDockerCopyImageFailReturnsError(t *testing.T) {
 
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
@@ -313,7 +809,7 @@ func TestClusterK3sImportDockerRunsExecCommand(t *testing.T) {
 	defer cleanup()
 
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
-	err := p.Create()
+	err := p.Create(context.Background())
 
 	assert.NoError(t, err)
 	md.AssertCalled(t, "ExecuteCommand", "containerid", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
@@ -327,7 +823,7 @@ func TestClusterK3sImportDockerExecFailReturnsError(t *testing.T) {
 
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
@@ -338,7 +834,7 @@ func TestClusterK3sDestroyGetsIDr(t *testing.T) {
 
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Destroy()
+	err := p.Destroy(context.Background())
 	assert.NoError(t, err)
 	md.AssertCalled(t, "FindContainerIDs", "server."+clusterConfig.Name, clusterConfig.Type)
 }
@@ -351,7 +847,7 @@ func TestClusterK3sDestroyWithFindIDErrorReturnsError(t *testing.T) {
 
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Destroy()
+	err := p.Destroy(context.Background())
 	assert.Error(t, err)
 }
 
@@ -363,7 +859,7 @@ func TestClusterK3sDestroyWithNoIDReturns(t *testing.T) {
 
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Destroy()
+	err := p.Destroy(context.Background())
 	assert.NoError(t, err)
 	md.AssertNotCalled(t, "RemoveContainer", mock.Anything)
 }
@@ -376,11 +872,86 @@ func TestClusterK3sDestroyRemovesContainer(t *testing.T) {
 
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
 
-	err := p.Destroy()
+	err := p.Destroy(context.Background())
 	assert.NoError(t, err)
 	md.AssertCalled(t, "RemoveContainer", mock.Anything)
 }
 
+func TestClusterK3sDestroyRemovesAgentsBeforeServer(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	removeOn(&md.Mock, "FindContainerIDs")
+	md.On("FindContainerIDs", "server.test", mock.Anything).Return([]string{"server"}, nil)
+	md.On("FindContainerIDs", "agent-1.test", mock.Anything).Return([]string{"agent"}, nil)
+	defer cleanup()
+
+	cc.Nodes = 2
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Destroy(context.Background())
+	assert.NoError(t, err)
+
+	calls := getCalls(&md.Mock, "RemoveContainer")
+	assert.Len(t, calls, 2)
+	assert.Equal(t, "agent", calls[0].Arguments[0])
+	assert.Equal(t, "server", calls[1].Arguments[0])
+}
+
+func TestClusterK3sDestroyDetachesImageCache(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	removeOn(&md.Mock, "FindContainerIDs")
+	md.On("FindContainerIDs", imageCacheName, config.TypeContainer).Return([]string{"cacheid"}, nil)
+	md.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{"found"}, nil)
+	defer cleanup()
+
+	cc.ImageCache = true
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Destroy(context.Background())
+	assert.NoError(t, err)
+	md.AssertCalled(t, "DetachNetwork", cc.Networks[0].Name, "cacheid")
+}
+
+func TestClusterK3sDestroyRemovesMergedKubeConfig(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	cc.MergeKubeConfig = true
+	_, destPath, _ := utils.CreateKubeConfigPath(cc.Name)
+	ioutil.WriteFile(destPath, []byte(validKubeconfig), 0644)
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	err = p.Destroy(context.Background())
+	assert.NoError(t, err)
+
+	d, err := ioutil.ReadFile(utils.HomeFolder() + "/.kube/config")
+	assert.NoError(t, err)
+	assert.NotContains(t, string(d), "shipyard-test")
+}
+
+func TestClusterK3sDestroyRemovesManagedKubeConfigContext(t *testing.T) {
+	cc, md, mk, cleanup := setupClusterMocks()
+	defer cleanup()
+
+	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	err = p.Destroy(context.Background())
+	assert.NoError(t, err)
+
+	d, err := ioutil.ReadFile(utils.ManagedKubeConfigPath())
+	assert.NoError(t, err)
+	assert.NotContains(t, string(d), "shipyard-test")
+	assert.Empty(t, cc.KubeContext)
+}
+
 func TestLookupReturnsIDs(t *testing.T) {
 	cc, md, mk, cleanup := setupClusterMocks()
 	p := NewK8sCluster(cc, md, mk, nil, hclog.NewNullLogger())
@@ -388,7 +959,7 @@ func TestLookupReturnsIDs(t *testing.T) {
 	md.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{"found"}, nil)
 	defer cleanup()
 
-	ids, err := p.Lookup()
+	ids, err := p.Lookup(context.Background())
 
 	assert.NoError(t, err)
 	assert.Equal(t, []string{"found"}, ids)
@@ -407,14 +978,23 @@ var clusterConfig = &config.K8sCluster{
 	Networks: []config.NetworkAttachment{config.NetworkAttachment{Name: "cloud"}},
 }
 
-var kubeconfig = `
+var validKubeconfig = `
 apiVersion: v1
 clusters:
 - cluster:
-   certificate-authority-data: LS0tLS1CRUdJTiBDRVJUSUZJQ0FURS0tLS0tCk1JSUJWakNCL3FBREFnRUNBZ0VBTUFvR0NDcUdTTTQ5QkFNQ01DTXhJVEFmQmdOVkJBTU1HR3N6Y3kxelpYSjIKWlhJdFkyRk
-FNVFUzTlRrNE1qVTNNakFlRncweE9URXlNVEF4TWpVMk1USmFGdzB5T1RFeU1EY3hNalUyTVRKYQpNQ014SVRBZkJnTlZCQU1NR0dzemN5MXpaWEoyWlhJdFkyRkFNVFUzTlRrNE1qVTNNakJaTUJNR0J5cUdTTTQ5CkFn
-RUdDQ3FHU000OUF3RUhBMElBQkhSblYydVliRU53eTlROGkxd2J6ZjQ2NytGdzV2LzRBWVQ2amM4dXorM00KTmRrZEwwd0RhNGM3Y1ByOUFXM1N0ZVRYSDNtNE9mRStJYTE3L1liaDFqR2pJekFoTUE0R0ExVWREd0VCL3
-dRRQpBd0lDcERBUEJnTlZIUk1CQWY4RUJUQURBUUgvTUFvR0NDcUdTTTQ5QkFNQ0EwY0FNRVFDSUhFYlZwbUkzbjZwCnQrYlhKaWlFK1hiRm5XUFhtYm40OFZuNmtkYkdPM3daQWlCRDNyUjF5RjQ5R0piZmVQeXBsREdC
-K3lkNVNQOEUKUmQ4OGxRWW9oRnV2enc9PQotLS0tLUVORCBDRVJUSUZJQ0FURS0tLS0tCg==
     server: https://127.0.0.1:64674
+  name: default
+contexts:
+- context:
+    cluster: default
+    user: default
+  name: default
+current-context: default
+kind: Config
+preferences: {}
+users:
+- name: default
+  user:
+    password: secret
+    username: admin
 `
@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 
@@ -28,25 +29,25 @@ func NewNomadCluster(c *config.NomadCluster, cc clients.ContainerTasks, hc clien
 }
 
 // Create implements interface method to create a cluster of the specified type
-func (c *NomadCluster) Create() error {
-	return c.createNomad()
+func (c *NomadCluster) Create(ctx context.Context) error {
+	return c.createNomad(ctx)
 }
 
 // Destroy implements interface method to destroy a cluster
-func (c *NomadCluster) Destroy() error {
-	return c.destroyNomad()
+func (c *NomadCluster) Destroy(ctx context.Context) error {
+	return c.destroyNomad(ctx)
 }
 
 // Lookup the a clusters current state
-func (c *NomadCluster) Lookup() ([]string, error) {
-	return c.client.FindContainerIDs(fmt.Sprintf("server.%s", c.config.Name), c.config.Type)
+func (c *NomadCluster) Lookup(ctx context.Context) ([]string, error) {
+	return c.client.FindContainerIDs(ctx, fmt.Sprintf("server.%s", c.config.Name), c.config.Type)
 }
 
-func (c *NomadCluster) createNomad() error {
+func (c *NomadCluster) createNomad(ctx context.Context) error {
 	c.log.Info("Creating Cluster", "ref", c.config.Name)
 
 	// check the cluster does not already exist
-	ids, err := c.client.FindContainerIDs(fmt.Sprintf("server.%s", c.config.Name), c.config.Type)
+	ids, err := c.client.FindContainerIDs(ctx, fmt.Sprintf("server.%s", c.config.Name), c.config.Type)
 	if len(ids) > 0 {
 		return ErrorClusterExists
 	}
@@ -64,13 +65,13 @@ func (c *NomadCluster) createNomad() error {
 	image := fmt.Sprintf("%s:%s", nomadBaseImage, c.config.Version)
 
 	// pull the container image
-	err = c.client.PullImage(config.Image{Name: image}, false)
+	err = c.client.PullImage(ctx, config.Image{Name: image}, false)
 	if err != nil {
 		return err
 	}
 
 	// create the volume for the cluster
-	volID, err := c.client.CreateVolume(utils.ImageVolumeName)
+	volID, err := c.client.CreateVolume(ctx, utils.ImageVolumeName)
 	if err != nil {
 		return err
 	}
@@ -83,6 +84,7 @@ func (c *NomadCluster) createNomad() error {
 	cc.Image = config.Image{Name: image}
 	cc.Networks = c.config.Networks
 	cc.Privileged = true // nomad must run Privileged as Docker needs to manipulate ip tables and stuff
+	cc.Resources = c.config.Resources
 
 	// set the volume mount for the images
 	cc.Volumes = []config.Volume{
@@ -113,7 +115,7 @@ func (c *NomadCluster) createNomad() error {
 		},
 	}
 
-	id, err := c.client.CreateContainer(cc)
+	id, err := c.client.CreateContainer(ctx, cc)
 	if err != nil {
 		return err
 	}
@@ -129,7 +131,7 @@ func (c *NomadCluster) createNomad() error {
 
 	// ensure all client nodes are up
 	c.nomadClient.SetConfig(configPath)
-	err = c.nomadClient.HealthCheckAPI(startTimeout)
+	err = Idle(ctx, func() error { return c.nomadClient.HealthCheckAPI(ctx, startTimeout) })
 	if err != nil {
 		return err
 	}
@@ -137,7 +139,7 @@ func (c *NomadCluster) createNomad() error {
 	// import the images to the servers container d instance
 	// importing images means that k3s does not need to pull from a remote docker hub
 	if c.config.Images != nil && len(c.config.Images) > 0 {
-		err := c.ImportLocalDockerImages("images", id, c.config.Images, false)
+		err := c.ImportLocalDockerImages(ctx, "images", id, c.config.Images, false)
 		if err != nil {
 			return xerrors.Errorf("Error importing Docker images: %w", err)
 		}
@@ -147,11 +149,11 @@ func (c *NomadCluster) createNomad() error {
 }
 
 // ImportLocalDockerImages fetches Docker images stored on the local client and imports them into the cluster
-func (c *NomadCluster) ImportLocalDockerImages(name string, id string, images []config.Image, force bool) error {
+func (c *NomadCluster) ImportLocalDockerImages(ctx context.Context, name string, id string, images []config.Image, force bool) error {
 	imgs := []string{}
 
 	for _, i := range images {
-		err := c.client.PullImage(i, false)
+		err := c.client.PullImage(ctx, i, false)
 		if err != nil {
 			return err
 		}
@@ -161,7 +163,7 @@ func (c *NomadCluster) ImportLocalDockerImages(name string, id string, images []
 
 	// import to volume
 	vn := utils.FQDNVolumeName(name)
-	imagesFile, err := c.client.CopyLocalDockerImageToVolume(imgs, vn, force)
+	imagesFile, err := c.client.CopyLocalDockerImageToVolume(ctx, imgs, vn, force)
 	if err != nil {
 		return err
 	}
@@ -169,7 +171,7 @@ func (c *NomadCluster) ImportLocalDockerImages(name string, id string, images []
 	// execute the command to import the image
 	// write any command output to the logger
 	for _, i := range imagesFile {
-		err = c.client.ExecuteCommand(id, []string{"docker", "load", "-i", "/images/" + i}, nil, "/", c.log.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug}))
+		err = c.client.ExecuteCommand(ctx, id, []string{"docker", "load", "-i", "/images/" + i}, nil, "/", c.log.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug}))
 		if err != nil {
 			return err
 		}
@@ -178,12 +180,12 @@ func (c *NomadCluster) ImportLocalDockerImages(name string, id string, images []
 	return nil
 }
 
-func (c *NomadCluster) destroyNomad() error {
+func (c *NomadCluster) destroyNomad(ctx context.Context) error {
 	c.log.Info("Destroy Nomad Cluster", "ref", c.config.Name)
 
 	// FindContainerIDs works on absolute addresses, we need to append the server
 
-	ids, err := c.client.FindContainerIDs(fmt.Sprintf("server.%s", c.config.Name), c.config.Type)
+	ids, err := c.client.FindContainerIDs(ctx, fmt.Sprintf("server.%s", c.config.Name), c.config.Type)
 	if err != nil {
 		return err
 	}
@@ -192,13 +194,13 @@ func (c *NomadCluster) destroyNomad() error {
 		// remove from the networks
 		for _, n := range c.config.Networks {
 			c.log.Debug("Detaching container from network", "ref", c.config.Name, "id", i, "network", n.Name)
-			err := c.client.DetachNetwork(n.Name, i)
+			err := c.client.DetachNetwork(ctx, n.Name, i)
 			if err != nil {
 				c.log.Error("Unable to detach network", "ref", c.config.Name, "network", n.Name, "error", err)
 			}
 		}
 
-		err := c.client.RemoveContainer(i)
+		err := c.client.RemoveContainer(ctx, i)
 		if err != nil {
 			return err
 		}
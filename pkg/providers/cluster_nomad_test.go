@@ -2,6 +2,7 @@ package providers
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -64,7 +65,7 @@ func TestClusterNomadErrorsWhenUnableToLookupIDs(t *testing.T) {
 
 	p := NewNomadCluster(clusterNomadConfig, md, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
@@ -74,7 +75,7 @@ func TestClusterNomadErrorsWhenClusterExists(t *testing.T) {
 
 	p := NewNomadCluster(clusterNomadConfig, md, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
@@ -84,7 +85,7 @@ func TestClusterNomadPullsImage(t *testing.T) {
 
 	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 	md.AssertCalled(t, "PullImage", config.Image{Name: "shipyardrun/nomad:v1.0.0"}, false)
 }
@@ -96,7 +97,7 @@ func TestClusterNomadPullsImageWithDefault(t *testing.T) {
 
 	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 	md.AssertCalled(t, "PullImage", config.Image{Name: "shipyardrun/nomad:" + nomadBaseVersion}, false)
 }
@@ -107,7 +108,7 @@ func TestClusterNomadCreatesANewVolume(t *testing.T) {
 
 	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 	md.AssertCalled(t, "CreateVolume", utils.ImageVolumeName)
 }
@@ -121,7 +122,7 @@ func TestClusterNomadFailsWhenUnableToCreatesANewVolume(t *testing.T) {
 
 	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 	md.AssertCalled(t, "CreateVolume", utils.ImageVolumeName)
 }
@@ -134,7 +135,7 @@ func TestClusterNomadCreatesAServer(t *testing.T) {
 
 	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
@@ -168,7 +169,7 @@ func TestClusterNomadGeneratesConfig(t *testing.T) {
 
 	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	_, configPath := utils.CreateNomadConfigPath(cc.Name)
@@ -182,7 +183,7 @@ func TestClusterNomadHealthChecksAPI(t *testing.T) {
 	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
 	startTimeout = 10 * time.Millisecond // reset the startTimeout, do not want to wait 120s
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	mh.AssertCalled(t, "HealthCheckAPI", mock.Anything)
@@ -198,7 +199,7 @@ func TestClusterNomadErrorsIfHealthFails(t *testing.T) {
 	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
 	startTimeout = 10 * time.Millisecond // reset the startTimeout, do not want to wait 120s
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
@@ -208,7 +209,7 @@ func TestClusterNomadImportDockerImagesPullsImages(t *testing.T) {
 
 	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 	md.AssertCalled(t, "PullImage", clusterConfig.Images[0], false)
 	md.AssertCalled(t, "PullImage", clusterConfig.Images[1], false)
@@ -220,7 +221,7 @@ func TestClusterNomadImportDockerCopiesImages(t *testing.T) {
 
 	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 	md.AssertCalled(t, "CopyLocalDockerImageToVolume", []string{"consul:1.6.1", "vault:1.6.1"}, "images.volume.shipyard.run", false)
 }
@@ -233,7 +234,7 @@ func TestClusterNomadImportDockerCopyImageFailReturnsError(t *testing.T) {
 
 	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
@@ -244,7 +245,7 @@ func TestClusterNomadImportDockerRunsExecCommand(t *testing.T) {
 
 	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	importCommand := []string{"docker", "load", "-i", "/images/file.tar.gz"}
@@ -260,7 +261,7 @@ func TestClusterNomadImportDockerExecFailReturnsError(t *testing.T) {
 
 	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
@@ -271,7 +272,7 @@ func TestClusterNomadDestroyGetsIDr(t *testing.T) {
 
 	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
 
-	err := p.Destroy()
+	err := p.Destroy(context.Background())
 	assert.NoError(t, err)
 	md.AssertCalled(t, "FindContainerIDs", "server."+clusterNomadConfig.Name, clusterNomadConfig.Type)
 }
@@ -284,7 +285,7 @@ func TestClusterNomadDestroyWithFindIDErrorReturnsError(t *testing.T) {
 
 	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
 
-	err := p.Destroy()
+	err := p.Destroy(context.Background())
 	assert.Error(t, err)
 }
 
@@ -296,7 +297,7 @@ func TestClusterNomadDestroyWithNoIDReturns(t *testing.T) {
 
 	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
 
-	err := p.Destroy()
+	err := p.Destroy(context.Background())
 	assert.NoError(t, err)
 	md.AssertNotCalled(t, "RemoveContainer", mock.Anything)
 }
@@ -309,7 +310,7 @@ func TestClusterNomadDestroyRemovesContainer(t *testing.T) {
 
 	p := NewNomadCluster(cc, md, mh, hclog.NewNullLogger())
 
-	err := p.Destroy()
+	err := p.Destroy(context.Background())
 	assert.NoError(t, err)
 	md.AssertCalled(t, "RemoveContainer", mock.Anything)
 }
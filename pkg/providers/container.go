@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"context"
 	"time"
 
 	hclog "github.com/hashicorp/go-hclog"
@@ -10,18 +11,19 @@ import (
 
 // Container is a provider for creating and destroying Docker containers
 type Container struct {
-	config     *config.Container
-	client     clients.ContainerTasks
-	httpClient clients.HTTP
-	log        hclog.Logger
+	config       *config.Container
+	client       clients.ContainerTasks
+	httpClient   clients.HTTP
+	consulClient clients.Consul
+	log          hclog.Logger
 }
 
 // NewContainer creates a new container with the given config and Docker client
-func NewContainer(co *config.Container, cl clients.ContainerTasks, hc clients.HTTP, l hclog.Logger) *Container {
-	return &Container{co, cl, hc, l}
+func NewContainer(co *config.Container, cl clients.ContainerTasks, hc clients.HTTP, cc clients.Consul, l hclog.Logger) *Container {
+	return &Container{co, cl, hc, cc, l}
 }
 
-func NewContainerSidecar(cs *config.Sidecar, cl clients.ContainerTasks, hc clients.HTTP, l hclog.Logger) *Container {
+func NewContainerSidecar(cs *config.Sidecar, cl clients.ContainerTasks, hc clients.HTTP, cc clients.Consul, l hclog.Logger) *Container {
 	co := config.NewContainer(cs.Name)
 	co.Depends = cs.Depends
 	co.Networks = []config.NetworkAttachment{config.NetworkAttachment{Name: cs.Target}}
@@ -36,44 +38,51 @@ func NewContainerSidecar(cs *config.Sidecar, cl clients.ContainerTasks, hc clien
 	co.Type = cs.Type
 	co.Config = cs.Config
 
-	return &Container{co, cl, hc, l}
+	return &Container{co, cl, hc, cc, l}
 }
 
 // Create implements provider method and creates a Docker container with the given config
-func (c *Container) Create() error {
+func (c *Container) Create(ctx context.Context) error {
 	c.log.Info("Creating Container", "ref", c.config.Name)
 
 	// pull any images needed for this container
-	err := c.client.PullImage(c.config.Image, false)
+	err := c.client.PullImage(ctx, c.config.Image, false)
 	if err != nil {
 		c.log.Error("Error pulling container image", "ref", c.config.Name, "image", c.config.Image.Name)
 
 		return err
 	}
 
-	_, err = c.client.CreateContainer(c.config)
+	_, err = c.client.CreateContainer(ctx, c.config)
 
-	if c.config.HealthCheck == nil {
+	if err != nil || c.config.HealthCheck == nil {
 		return err
 	}
 
 	// check the health of the container
-	if hc := c.config.HealthCheck.HTTP; hc != "" {
-		d, err := time.ParseDuration(c.config.HealthCheck.Timeout)
-		if err != nil {
-			return err
-		}
+	d, err := time.ParseDuration(c.config.HealthCheck.Timeout)
+	if err != nil {
+		return err
+	}
+
+	err = healthCheckEndpoints(ctx, c.httpClient, c.config.HealthCheck, d)
+	if err != nil {
+		return err
+	}
 
-		return c.httpClient.HealthCheckHTTP(hc, d)
+	if cs := c.config.HealthCheck.Consul; cs != nil {
+		return Idle(ctx, func() error {
+			return c.consulClient.HealthCheckServices(ctx, cs.Address, cs.Token, cs.Services, d)
+		})
 	}
 
 	return nil
 }
 
 // Destroy stops and removes the container
-func (c *Container) Destroy() error {
+func (c *Container) Destroy(ctx context.Context) error {
 	c.log.Info("Destroy Container", "ref", c.config.Name)
-	ids, err := c.client.FindContainerIDs(c.config.Name, c.config.Type)
+	ids, err := c.client.FindContainerIDs(ctx, c.config.Name, c.config.Type)
 
 	if err != nil {
 		return err
@@ -83,14 +92,14 @@ func (c *Container) Destroy() error {
 		for _, id := range ids {
 			if c.config.Type == config.TypeContainer {
 				for _, n := range c.config.Networks {
-					err := c.client.DetachNetwork(n.Name, id)
+					err := c.client.DetachNetwork(ctx, n.Name, id)
 					if err != nil {
 						c.log.Error("Unable to detach network", "ref", c.config.Name, "network", n.Name)
 					}
 				}
 			}
 
-			err := c.client.RemoveContainer(id)
+			err := c.client.RemoveContainer(ctx, id)
 			if err != nil {
 				return err
 			}
@@ -101,6 +110,6 @@ func (c *Container) Destroy() error {
 }
 
 // Lookup the ID based on the config
-func (c *Container) Lookup() ([]string, error) {
-	return c.client.FindContainerIDs(c.config.Name, c.config.Type)
+func (c *Container) Lookup(ctx context.Context) ([]string, error) {
+	return c.client.FindContainerIDs(ctx, c.config.Name, c.config.Type)
 }
@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -16,7 +17,7 @@ func TestContainerCreatesSuccessfully(t *testing.T) {
 	cc := config.NewContainer("tests")
 	md := &mocks.MockContainerTasks{}
 	hc := &mocks.MockHTTP{}
-	c := NewContainer(cc, md, hc, hclog.NewNullLogger())
+	c := NewContainer(cc, md, hc, &mocks.MockConsul{}, hclog.NewNullLogger())
 
 	// check pulls image before creating container
 	md.On("PullImage", cc.Image, false).Once().Return(nil)
@@ -24,7 +25,7 @@ func TestContainerCreatesSuccessfully(t *testing.T) {
 	// check calls CreateContainer with the config
 	md.On("CreateContainer", cc).Once().Return("", nil)
 
-	err := c.Create()
+	err := c.Create(context.Background())
 	assert.NoError(t, err)
 
 	hc.AssertNotCalled(t, "HealthCheckHTTP", mock.Anything, mock.Anything)
@@ -39,24 +40,116 @@ func TestContainerRunsHTTPChecks(t *testing.T) {
 
 	md := &mocks.MockContainerTasks{}
 	hc := &mocks.MockHTTP{}
-	c := NewContainer(cc, md, hc, hclog.NewNullLogger())
+	c := NewContainer(cc, md, hc, &mocks.MockConsul{}, hclog.NewNullLogger())
 
 	md.On("PullImage", cc.Image, false).Once().Return(nil)
 	md.On("CreateContainer", cc).Once().Return("", nil)
 
 	hc.On("HealthCheckHTTP", mock.Anything, mock.Anything).Return(nil)
 
-	err := c.Create()
+	err := c.Create(context.Background())
 	assert.NoError(t, err)
 
 	hc.AssertCalled(t, "HealthCheckHTTP", "http://localhost:8500", 30*time.Second)
 }
 
+func TestContainerRunsHTTPChecksWithOptions(t *testing.T) {
+	cc := config.NewContainer("tests")
+	cc.HealthCheck = &config.HealthCheck{
+		Timeout:     "30s",
+		HTTP:        "https://localhost:8500",
+		HTTPOptions: &config.HTTPHealthCheck{SuccessCodes: []int{200, 429}, Insecure: true},
+	}
+
+	md := &mocks.MockContainerTasks{}
+	hc := &mocks.MockHTTP{}
+	c := NewContainer(cc, md, hc, &mocks.MockConsul{}, hclog.NewNullLogger())
+
+	md.On("PullImage", cc.Image, false).Once().Return(nil)
+	md.On("CreateContainer", cc).Once().Return("", nil)
+
+	hc.On("HealthCheckHTTPWithOptions", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	err := c.Create(context.Background())
+	assert.NoError(t, err)
+
+	hc.AssertCalled(t, "HealthCheckHTTPWithOptions", "https://localhost:8500", *cc.HealthCheck.HTTPOptions, 30*time.Second)
+	hc.AssertNotCalled(t, "HealthCheckHTTP", mock.Anything, mock.Anything)
+}
+
+func TestContainerRunsTCPChecks(t *testing.T) {
+	cc := config.NewContainer("tests")
+	cc.HealthCheck = &config.HealthCheck{
+		Timeout: "30s",
+		TCP:     "localhost:5432",
+	}
+
+	md := &mocks.MockContainerTasks{}
+	hc := &mocks.MockHTTP{}
+	c := NewContainer(cc, md, hc, &mocks.MockConsul{}, hclog.NewNullLogger())
+
+	md.On("PullImage", cc.Image, false).Once().Return(nil)
+	md.On("CreateContainer", cc).Once().Return("", nil)
+
+	hc.On("HealthCheckTCP", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	err := c.Create(context.Background())
+	assert.Error(t, err)
+
+	hc.AssertCalled(t, "HealthCheckTCP", "localhost:5432", 30*time.Second)
+	hc.AssertNotCalled(t, "HealthCheckHTTP", mock.Anything, mock.Anything)
+}
+
+func TestContainerRunsConsulChecks(t *testing.T) {
+	cc := config.NewContainer("tests")
+	cc.HealthCheck = &config.HealthCheck{
+		Timeout: "30s",
+		Consul:  &config.ConsulHealthCheck{Address: "http://localhost:8500", Services: []string{"web"}, Token: "abc"},
+	}
+
+	md := &mocks.MockContainerTasks{}
+	hc := &mocks.MockHTTP{}
+	cs := &mocks.MockConsul{}
+	c := NewContainer(cc, md, hc, cs, hclog.NewNullLogger())
+
+	md.On("PullImage", cc.Image, false).Once().Return(nil)
+	md.On("CreateContainer", cc).Once().Return("", nil)
+
+	cs.On("HealthCheckServices", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	err := c.Create(context.Background())
+	assert.NoError(t, err)
+
+	cs.AssertCalled(t, "HealthCheckServices", "http://localhost:8500", "abc", []string{"web"}, 30*time.Second)
+	hc.AssertNotCalled(t, "HealthCheckHTTP", mock.Anything, mock.Anything)
+}
+
+func TestContainerConsulCheckErrorReturnsError(t *testing.T) {
+	cc := config.NewContainer("tests")
+	cc.HealthCheck = &config.HealthCheck{
+		Timeout: "30s",
+		Consul:  &config.ConsulHealthCheck{Address: "http://localhost:8500", Services: []string{"web"}},
+	}
+
+	md := &mocks.MockContainerTasks{}
+	hc := &mocks.MockHTTP{}
+	cs := &mocks.MockConsul{}
+	c := NewContainer(cc, md, hc, cs, hclog.NewNullLogger())
+
+	md.On("PullImage", cc.Image, false).Once().Return(nil)
+	md.On("CreateContainer", cc).Once().Return("", nil)
+
+	cs.On("HealthCheckServices", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	err := c.Create(context.Background())
+	assert.Error(t, err)
+}
+
 func TestContainerDoesNOTCreateWhenPullImageFail(t *testing.T) {
 	cc := config.NewContainer("tests")
 	md := &mocks.MockContainerTasks{}
 	hc := &mocks.MockHTTP{}
-	c := NewContainer(cc, md, hc, hclog.NewNullLogger())
+	c := NewContainer(cc, md, hc, &mocks.MockConsul{}, hclog.NewNullLogger())
 
 	// check pulls image before creating container and return an erro
 	imageErr := fmt.Errorf("Unable to pull image")
@@ -65,7 +158,7 @@ func TestContainerDoesNOTCreateWhenPullImageFail(t *testing.T) {
 	// check does not call CreateContainer with the config
 	md.On("CreateContainer", cc).Times(0)
 
-	err := c.Create()
+	err := c.Create(context.Background())
 	assert.Equal(t, imageErr, err)
 }
 
@@ -74,13 +167,13 @@ func TestContainerDestroysCorrectlyWhenContainerExists(t *testing.T) {
 	cc.Networks = []config.NetworkAttachment{config.NetworkAttachment{Name: "cloud"}}
 	md := &mocks.MockContainerTasks{}
 	hc := &mocks.MockHTTP{}
-	c := NewContainer(cc, md, hc, hclog.NewNullLogger())
+	c := NewContainer(cc, md, hc, &mocks.MockConsul{}, hclog.NewNullLogger())
 
 	md.On("FindContainerIDs", cc.Name, cc.Type).Return([]string{"abc"}, nil)
 	md.On("RemoveContainer", "abc").Return(nil)
 	md.On("DetachNetwork", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-	err := c.Destroy()
+	err := c.Destroy(context.Background())
 	assert.NoError(t, err)
 }
 
@@ -89,11 +182,11 @@ func TestContainerDoesNotDestroysWhenNotExists(t *testing.T) {
 	cc.Networks = []config.NetworkAttachment{config.NetworkAttachment{Name: "cloud"}}
 	md := &mocks.MockContainerTasks{}
 	hc := &mocks.MockHTTP{}
-	c := NewContainer(cc, md, hc, hclog.NewNullLogger())
+	c := NewContainer(cc, md, hc, &mocks.MockConsul{}, hclog.NewNullLogger())
 
 	md.On("FindContainerIDs", cc.Name, cc.Type).Return(nil, nil)
 
-	err := c.Destroy()
+	err := c.Destroy(context.Background())
 	assert.NoError(t, err)
 	md.AssertNotCalled(t, "RemoveContainer")
 }
@@ -103,11 +196,11 @@ func TestContainerDoesNotDestroysWhenLookupError(t *testing.T) {
 	cc.Networks = []config.NetworkAttachment{config.NetworkAttachment{Name: "cloud"}}
 	md := &mocks.MockContainerTasks{}
 	hc := &mocks.MockHTTP{}
-	c := NewContainer(cc, md, hc, hclog.NewNullLogger())
+	c := NewContainer(cc, md, hc, &mocks.MockConsul{}, hclog.NewNullLogger())
 
 	md.On("FindContainerIDs", cc.Name, cc.Type).Return(nil, fmt.Errorf("boom"))
 
-	err := c.Destroy()
+	err := c.Destroy(context.Background())
 	assert.Error(t, err)
 	md.AssertNotCalled(t, "RemoveContainer")
 }
@@ -117,11 +210,11 @@ func TestContainerLooksupIDs(t *testing.T) {
 	cc.Networks = []config.NetworkAttachment{config.NetworkAttachment{Name: "cloud"}}
 	md := &mocks.MockContainerTasks{}
 	hc := &mocks.MockHTTP{}
-	c := NewContainer(cc, md, hc, hclog.NewNullLogger())
+	c := NewContainer(cc, md, hc, &mocks.MockConsul{}, hclog.NewNullLogger())
 
 	md.On("FindContainerIDs", cc.Name, cc.Type).Return([]string{"abc"}, nil)
 
-	ids, err := c.Lookup()
+	ids, err := c.Lookup(context.Background())
 	assert.NoError(t, err)
 	assert.Equal(t, []string{"abc"}, ids)
 }
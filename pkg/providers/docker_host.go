@@ -0,0 +1,53 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// dockerHostTimeout is the maximum time to wait for a Docker host to respond
+var dockerHostTimeout = 10 * time.Second
+
+// DockerHost is a provider which checks that a Docker host can be reached,
+// the connection to the host is managed by the engine
+type DockerHost struct {
+	config *config.DockerHost
+	client clients.Docker
+	log    hclog.Logger
+}
+
+// NewDockerHost creates a DockerHost provider, the client must be connected
+// to the host defined in the config
+func NewDockerHost(c *config.DockerHost, cl clients.Docker, l hclog.Logger) *DockerHost {
+	return &DockerHost{c, cl, l}
+}
+
+// Create checks the Docker daemon for the host responds
+func (d *DockerHost) Create(ctx context.Context) error {
+	d.log.Info("Connecting to Docker host", "ref", d.config.Name, "host", d.config.Host, "context", d.config.Context)
+
+	ctx, cancel := context.WithTimeout(context.Background(), dockerHostTimeout)
+	defer cancel()
+
+	_, err := d.client.Ping(ctx)
+	if err != nil {
+		return xerrors.Errorf("Unable to connect to Docker host %s: %w", d.config.Name, err)
+	}
+
+	return nil
+}
+
+// Destroy does nothing, resources on the host are removed by their own providers
+func (d *DockerHost) Destroy(ctx context.Context) error {
+	return nil
+}
+
+// Lookup returns nothing as a Docker host has no Docker resources
+func (d *DockerHost) Lookup(ctx context.Context) ([]string, error) {
+	return []string{}, nil
+}
@@ -0,0 +1,35 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	hclog "github.com/hashicorp/go-hclog"
+	clients "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDockerHostCreatePingsHost(t *testing.T) {
+	md := &clients.MockDocker{}
+	md.On("Ping", mock.Anything).Return(types.Ping{}, nil)
+
+	p := NewDockerHost(config.NewDockerHost("lab1"), md, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+	md.AssertCalled(t, "Ping", mock.Anything)
+}
+
+func TestDockerHostCreateReturnsErrorWhenUnreachable(t *testing.T) {
+	md := &clients.MockDocker{}
+	md.On("Ping", mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	p := NewDockerHost(config.NewDockerHost("lab1"), md, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"context"
 	"fmt"
 	"html/template"
 	"io/ioutil"
@@ -31,17 +32,17 @@ func NewDocs(c *config.Docs, cc clients.ContainerTasks, l hclog.Logger) *Docs {
 }
 
 // Create a new documentation container
-func (i *Docs) Create() error {
+func (i *Docs) Create(ctx context.Context) error {
 	i.log.Info("Creating Documentation", "ref", i.config.Name)
 
 	// create the documentation container
-	err := i.createDocsContainer()
+	err := i.createDocsContainer(ctx)
 	if err != nil {
 		return err
 	}
 
 	// create the terminal server container
-	err = i.createTerminalContainer()
+	err = i.createTerminalContainer(ctx)
 	if err != nil {
 		return err
 	}
@@ -52,7 +53,7 @@ func (i *Docs) Create() error {
 	return nil
 }
 
-func (i *Docs) createDocsContainer() error {
+func (i *Docs) createDocsContainer(ctx context.Context) error {
 	// create the container config
 	cc := config.NewContainer(i.config.Name)
 	i.config.ResourceInfo.AddChild(cc)
@@ -67,7 +68,7 @@ func (i *Docs) createDocsContainer() error {
 	}
 
 	// pull the docker image
-	err := i.client.PullImage(cc.Image, false)
+	err := i.client.PullImage(ctx, cc.Image, false)
 	if err != nil {
 		return err
 	}
@@ -84,10 +85,40 @@ func (i *Docs) createDocsContainer() error {
 		)
 	}
 
-	// if the index pages have been set
-	// generate the javascript
-	if i.config.IndexTitle != "" && len(i.config.IndexPages) > 0 {
-		indexPath, err := i.generateDocusaursIndex(i.config.IndexTitle, i.config.IndexPages)
+	// the content folders are mounted rather than copied so any changes
+	// the author makes are picked up by the live reload server
+	if i.config.Static != "" {
+		cc.Volumes = append(
+			cc.Volumes,
+			config.Volume{
+				Source:      i.config.Static,
+				Destination: "/shipyard/static",
+			},
+		)
+	}
+
+	if i.config.SiteConfig != "" {
+		cc.Volumes = append(
+			cc.Volumes,
+			config.Volume{
+				Source:      i.config.SiteConfig,
+				Destination: "/shipyard/docusaurus.config.js",
+			},
+		)
+	}
+
+	// if a custom sidebar has been set use it, otherwise when the
+	// index pages have been set generate the javascript
+	if i.config.Sidebars != "" {
+		cc.Volumes = append(
+			cc.Volumes,
+			config.Volume{
+				Source:      i.config.Sidebars,
+				Destination: "/shipyard/sidebars.js",
+			},
+		)
+	} else if i.config.IndexTitle != "" && len(i.config.IndexPages) > 0 {
+		indexPath, err := i.generateDocusaursIndex(ctx, i.config.IndexTitle, i.config.IndexPages)
 		if err != nil {
 			return xerrors.Errorf("Unable to generate index for documentation: %w", err)
 		}
@@ -117,11 +148,11 @@ func (i *Docs) createDocsContainer() error {
 		},
 	}
 
-	_, err = i.client.CreateContainer(cc)
+	_, err = i.client.CreateContainer(ctx, cc)
 	return err
 }
 
-func (i *Docs) createTerminalContainer() error {
+func (i *Docs) createTerminalContainer(ctx context.Context) error {
 	// create the container config
 	cc := config.NewContainer("terminal")
 	i.config.ResourceInfo.AddChild(cc)
@@ -130,12 +161,12 @@ func (i *Docs) createTerminalContainer() error {
 	cc.Image = config.Image{Name: fmt.Sprintf("%s:%s", terminalImageName, terminalVersion)}
 
 	// pull the image
-	err := i.client.PullImage(cc.Image, false)
+	err := i.client.PullImage(ctx, cc.Image, false)
 	if err != nil {
 		return err
 	}
 
-	// TODO we are mounting the docker sock, need to look at how this works on Windows
+	// the Docker socket is in the Docker VM on Windows and is mounted as is
 	cc.Volumes = make([]config.Volume, 0)
 	cc.Volumes = append(
 		cc.Volumes,
@@ -153,31 +184,31 @@ func (i *Docs) createTerminalContainer() error {
 		},
 	}
 
-	_, err = i.client.CreateContainer(cc)
+	_, err = i.client.CreateContainer(ctx, cc)
 	return err
 }
 
 // Destroy the documentation container
-func (i *Docs) Destroy() error {
+func (i *Docs) Destroy(ctx context.Context) error {
 	i.log.Info("Destroy Documentation", "ref", i.config.Name)
 
 	// remove the docs
-	ids, err := i.client.FindContainerIDs(i.config.Name, i.config.Type)
+	ids, err := i.client.FindContainerIDs(ctx, i.config.Name, i.config.Type)
 	if err != nil {
 		return err
 	}
 
 	for _, id := range ids {
-		err := i.client.RemoveContainer(id)
+		err := i.client.RemoveContainer(ctx, id)
 		if err != nil {
 			return err
 		}
 	}
 
 	// remove the terminal server
-	ids, err = i.client.FindContainerIDs("terminal", i.config.Type)
+	ids, err = i.client.FindContainerIDs(ctx, "terminal", i.config.Type)
 	for _, id := range ids {
-		err := i.client.RemoveContainer(id)
+		err := i.client.RemoveContainer(ctx, id)
 		if err != nil {
 			return err
 		}
@@ -186,7 +217,7 @@ func (i *Docs) Destroy() error {
 }
 
 // Lookup the ID of the documentation container
-func (i *Docs) Lookup() ([]string, error) {
+func (i *Docs) Lookup(ctx context.Context) ([]string, error) {
 	/*
 		cc := &config.Container{
 			Name:       i.config.Name,
@@ -199,7 +230,7 @@ func (i *Docs) Lookup() ([]string, error) {
 	return []string{}, nil
 }
 
-func (i *Docs) generateDocusaursIndex(title string, pages []string) (string, error) {
+func (i *Docs) generateDocusaursIndex(ctx context.Context, title string, pages []string) (string, error) {
 	tmpFile, err := ioutil.TempFile(utils.ShipyardTemp(), "*.json")
 	if err != nil {
 		return "", err
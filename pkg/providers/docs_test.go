@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"testing"
@@ -34,7 +35,7 @@ func setupDocs() (*Docs, *mocks.MockContainerTasks) {
 func TestDocsPullsDocsContainer(t *testing.T) {
 	d, md := setupDocs()
 
-	err := d.Create()
+	err := d.Create(context.Background())
 	assert.NoError(t, err)
 
 	params := getCalls(&md.Mock, "PullImage")[0].Arguments[0].(config.Image)
@@ -44,7 +45,7 @@ func TestDocsPullsDocsContainer(t *testing.T) {
 func TestDocsMountsMarkdown(t *testing.T) {
 	d, md := setupDocs()
 
-	err := d.Create()
+	err := d.Create(context.Background())
 	assert.NoError(t, err)
 
 	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
@@ -58,7 +59,7 @@ func TestDocsMountsMarkdown(t *testing.T) {
 func TestDocsGeneratesDocusaurusConfig(t *testing.T) {
 	d, md := setupDocs()
 
-	err := d.Create()
+	err := d.Create(context.Background())
 	assert.NoError(t, err)
 
 	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
@@ -77,10 +78,29 @@ func TestDocsGeneratesDocusaurusConfig(t *testing.T) {
 	assert.Contains(t, string(data), `"123"`)
 }
 
+func TestDocsMountsCustomSidebarsAndBranding(t *testing.T) {
+	d, md := setupDocs()
+	d.config.Sidebars = "/files/sidebars.js"
+	d.config.SiteConfig = "/files/docusaurus.config.js"
+	d.config.Static = "/files/static"
+
+	err := d.Create(context.Background())
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+
+	assert.Len(t, params.Volumes, 4)
+	assert.Equal(t, config.Volume{Source: "/files/static", Destination: "/shipyard/static"}, params.Volumes[1])
+	assert.Equal(t, config.Volume{Source: "/files/docusaurus.config.js", Destination: "/shipyard/docusaurus.config.js"}, params.Volumes[2])
+
+	// custom sidebars replace the generated index
+	assert.Equal(t, config.Volume{Source: "/files/sidebars.js", Destination: "/shipyard/sidebars.js"}, params.Volumes[3])
+}
+
 func TestDocsSetsDocsPorts(t *testing.T) {
 	d, md := setupDocs()
 
-	err := d.Create()
+	err := d.Create(context.Background())
 	assert.NoError(t, err)
 
 	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
@@ -99,7 +119,7 @@ func TestDocsSetsDocsPorts(t *testing.T) {
 func TestDocsPullsTerminalContainer(t *testing.T) {
 	d, md := setupDocs()
 
-	err := d.Create()
+	err := d.Create(context.Background())
 	assert.NoError(t, err)
 
 	params := getCalls(&md.Mock, "PullImage")[1].Arguments[0].(config.Image)
@@ -109,7 +129,7 @@ func TestDocsPullsTerminalContainer(t *testing.T) {
 func TestDocsMountsDockerSock(t *testing.T) {
 	d, md := setupDocs()
 
-	err := d.Create()
+	err := d.Create(context.Background())
 	assert.NoError(t, err)
 
 	params := getCalls(&md.Mock, "CreateContainer")[1].Arguments[0].(*config.Container)
@@ -123,7 +143,7 @@ func TestDocsMountsDockerSock(t *testing.T) {
 func TestDocsSetsTerminalPorts(t *testing.T) {
 	d, md := setupDocs()
 
-	err := d.Create()
+	err := d.Create(context.Background())
 	assert.NoError(t, err)
 
 	params := getCalls(&md.Mock, "CreateContainer")[1].Arguments[0].(*config.Container)
@@ -136,10 +156,10 @@ func TestDocsSetsTerminalPorts(t *testing.T) {
 func TestDestroyRemovesContainers(t *testing.T) {
 	d, md := setupDocs()
 
-	err := d.Create()
+	err := d.Create(context.Background())
 	assert.NoError(t, err)
 
-	err = d.Destroy()
+	err = d.Destroy(context.Background())
 	assert.NoError(t, err)
 
 	md.AssertNumberOfCalls(t, "FindContainerIDs", 2)
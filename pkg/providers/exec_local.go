@@ -1,12 +1,16 @@
 package providers
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients"
 	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
 )
 
 // ExecLocal provider allows the execution of arbitrary commands
@@ -23,33 +27,83 @@ func NewExecLocal(c *config.ExecLocal, ex clients.Command, l hclog.Logger) *Exec
 }
 
 // Create a new exec
-func (c *ExecLocal) Create() error {
-	if c.config.Command != "" {
-		return fmt.Errorf("Only Script execution is currently implemented for Local Exec")
+func (c *ExecLocal) Create(ctx context.Context) error {
+	return c.run(ctx, c.config.Script, c.config.Command, c.config.Arguments, c.config.Outputs)
+}
+
+// run executes the script or command with the resources options
+func (c *ExecLocal) run(ctx context.Context, script, command string, args []string, outputs []config.ExecOutput) error {
+	cc := clients.CommandConfig{
+		Command:          command,
+		Arguments:        args,
+		WorkingDirectory: c.config.WorkingDirectory,
+		Retries:          c.config.Retries,
+		Name:             c.config.Name,
 	}
 
-	c.log.Debug("Localy executing script", "ref", c.config.Name, "script", c.config.Script)
+	if c.config.TTY {
+		cc.TTY = true
+		cc.Stdin = os.Stdin
+	}
 
-	// make sure the script is executable
-	err := os.Chmod(c.config.Script, 0777)
-	if err != nil {
-		c.log.Error("Unable to set script permissions", "error", err)
+	if script != "" {
+		c.log.Debug("Localy executing script", "ref", c.config.Name, "script", script)
+
+		// make sure the script is executable
+		err := os.Chmod(script, 0777)
+		if err != nil {
+			c.log.Error("Unable to set script permissions", "error", err)
+		}
+
+		cc.Command = script
+	} else {
+		c.log.Debug("Localy executing command", "ref", c.config.Name, "command", command, "args", args)
+	}
+
+	if cc.Command == "" {
+		return fmt.Errorf("Either script or cmd must be specified for Local Exec")
+	}
+
+	if c.config.Timeout != "" {
+		d, err := time.ParseDuration(c.config.Timeout)
+		if err != nil {
+			return xerrors.Errorf("Invalid timeout %s: %w", c.config.Timeout, err)
+		}
+
+		cc.Timeout = d
+	}
+
+	for _, e := range c.config.Environment {
+		cc.Env = append(cc.Env, fmt.Sprintf("%s=%s", e.Key, e.Value))
 	}
 
-	err = c.client.Execute(c.config.Script)
+	out := bytes.NewBufferString("")
+	cc.Output = out
+
+	err := c.client.Execute(ctx, cc)
 	if err != nil {
 		return err
 	}
 
+	err = extractOutputs(outputs, out.String())
+	if err != nil {
+		return xerrors.Errorf("Unable to capture output from script: %w", err)
+	}
+
 	return nil
 }
 
-// Destroy statisfies the interface method but is not implemented by LocalExec
-func (c *ExecLocal) Destroy() error {
-	return nil
+// Destroy runs the on_destroy command when set
+func (c *ExecLocal) Destroy(ctx context.Context) error {
+	if c.config.OnDestroy == nil {
+		return nil
+	}
+
+	od := c.config.OnDestroy
+	return c.run(ctx, od.Script, od.Command, od.Arguments, nil)
 }
 
 // Lookup statisfies the interface method but is not implemented by LocalExec
-func (c *ExecLocal) Lookup() ([]string, error) {
+func (c *ExecLocal) Lookup(ctx context.Context) ([]string, error) {
 	return []string{}, nil
 }
@@ -0,0 +1,200 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockCommand is defined here rather than the mocks package as the
+// Command interface depends on types from the clients package
+type mockCommand struct {
+	mock.Mock
+}
+
+func (m *mockCommand) Execute(ctx context.Context, config clients.CommandConfig) error {
+	args := m.Called(config)
+
+	return args.Error(0)
+}
+
+func setupExecLocal(t *testing.T) (*config.ExecLocal, *mockCommand, func()) {
+	tmpDir, _ := ioutil.TempDir("", "")
+	ioutil.WriteFile(tmpDir+"/script.sh", []byte("echo hello"), 0644)
+
+	c := config.NewExecLocal("tests")
+	c.Script = tmpDir + "/script.sh"
+
+	mc := &mockCommand{}
+	mc.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(clients.CommandConfig).Output.Write([]byte("token: abc123\n"))
+	}).Return(nil)
+
+	return c, mc, func() {
+		os.RemoveAll(tmpDir)
+	}
+}
+
+func TestExecLocalExecutesScript(t *testing.T) {
+	c, mc, cleanup := setupExecLocal(t)
+	defer cleanup()
+
+	p := NewExecLocal(c, mc, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	params := getCalls(&mc.Mock, "Execute")[0].Arguments[0].(clients.CommandConfig)
+	assert.Equal(t, c.Script, params.Command)
+	assert.Equal(t, "tests", params.Name)
+}
+
+func TestExecLocalExecuteErrorReturnsError(t *testing.T) {
+	c, mc, cleanup := setupExecLocal(t)
+	defer cleanup()
+
+	removeOn(&mc.Mock, "Execute")
+	mc.On("Execute", mock.Anything).Return(fmt.Errorf("boom"))
+
+	p := NewExecLocal(c, mc, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.Error(t, err)
+}
+
+func TestExecLocalCapturesOutputs(t *testing.T) {
+	c, mc, cleanup := setupExecLocal(t)
+	defer cleanup()
+
+	c.Outputs = []config.ExecOutput{
+		config.ExecOutput{Name: "all"},
+		config.ExecOutput{Name: "token", Regex: `token: (\S+)`},
+	}
+
+	p := NewExecLocal(c, mc, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "token: abc123", c.Outputs[0].Value)
+	assert.Equal(t, "abc123", c.Outputs[1].Value)
+}
+
+func TestExecLocalExecutesCommandWithOptions(t *testing.T) {
+	c, mc, cleanup := setupExecLocal(t)
+	defer cleanup()
+
+	c.Script = ""
+	c.Command = "consul"
+	c.Arguments = []string{"members"}
+	c.WorkingDirectory = "/files"
+	c.Timeout = "60s"
+	c.Retries = 3
+	c.Environment = []config.KV{config.KV{Key: "CONSUL_HTTP_ADDR", Value: "http://localhost:8500"}}
+
+	p := NewExecLocal(c, mc, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	params := getCalls(&mc.Mock, "Execute")[0].Arguments[0].(clients.CommandConfig)
+	assert.Equal(t, "consul", params.Command)
+	assert.Equal(t, []string{"members"}, params.Arguments)
+	assert.Equal(t, "/files", params.WorkingDirectory)
+	assert.Equal(t, 60*time.Second, params.Timeout)
+	assert.Equal(t, 3, params.Retries)
+	assert.Equal(t, []string{"CONSUL_HTTP_ADDR=http://localhost:8500"}, params.Env)
+	assert.False(t, params.TTY)
+}
+
+func TestExecLocalWithTTYAttachesStdin(t *testing.T) {
+	c, mc, cleanup := setupExecLocal(t)
+	defer cleanup()
+
+	c.TTY = true
+
+	p := NewExecLocal(c, mc, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	params := getCalls(&mc.Mock, "Execute")[0].Arguments[0].(clients.CommandConfig)
+	assert.True(t, params.TTY)
+	assert.Equal(t, os.Stdin, params.Stdin)
+}
+
+func TestExecLocalWithInvalidTimeoutReturnsError(t *testing.T) {
+	c, mc, cleanup := setupExecLocal(t)
+	defer cleanup()
+
+	c.Timeout = "abc"
+
+	p := NewExecLocal(c, mc, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.Error(t, err)
+	mc.AssertNotCalled(t, "Execute", mock.Anything)
+}
+
+func TestExecLocalWithNoScriptOrCommandReturnsError(t *testing.T) {
+	c, mc, cleanup := setupExecLocal(t)
+	defer cleanup()
+
+	c.Script = ""
+
+	p := NewExecLocal(c, mc, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.Error(t, err)
+}
+
+func TestExecLocalDestroyWithoutOnDestroyDoesNothing(t *testing.T) {
+	c, mc, cleanup := setupExecLocal(t)
+	defer cleanup()
+
+	p := NewExecLocal(c, mc, hclog.NewNullLogger())
+
+	err := p.Destroy(context.Background())
+	assert.NoError(t, err)
+	mc.AssertNotCalled(t, "Execute", mock.Anything)
+}
+
+func TestExecLocalDestroyExecutesOnDestroy(t *testing.T) {
+	c, mc, cleanup := setupExecLocal(t)
+	defer cleanup()
+
+	c.WorkingDirectory = "/files"
+	c.OnDestroy = &config.ExecDestroy{Command: "dns", Arguments: []string{"remove"}}
+
+	p := NewExecLocal(c, mc, hclog.NewNullLogger())
+
+	err := p.Destroy(context.Background())
+	assert.NoError(t, err)
+
+	params := getCalls(&mc.Mock, "Execute")[0].Arguments[0].(clients.CommandConfig)
+	assert.Equal(t, "dns", params.Command)
+	assert.Equal(t, []string{"remove"}, params.Arguments)
+	assert.Equal(t, "/files", params.WorkingDirectory)
+}
+
+func TestExecLocalDestroyErrorReturnsError(t *testing.T) {
+	c, mc, cleanup := setupExecLocal(t)
+	defer cleanup()
+
+	c.OnDestroy = &config.ExecDestroy{Command: "dns"}
+	removeOn(&mc.Mock, "Execute")
+	mc.On("Execute", mock.Anything).Return(fmt.Errorf("boom"))
+
+	p := NewExecLocal(c, mc, hclog.NewNullLogger())
+
+	err := p.Destroy(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,38 @@
+package providers
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// extractOutputs sets the value of each output from the output of a command
+func extractOutputs(outputs []config.ExecOutput, out string) error {
+	for i, o := range outputs {
+		if o.Regex == "" {
+			outputs[i].Value = strings.TrimSpace(out)
+			continue
+		}
+
+		re, err := regexp.Compile(o.Regex)
+		if err != nil {
+			return xerrors.Errorf("Invalid regular expression for output %s: %w", o.Name, err)
+		}
+
+		m := re.FindStringSubmatch(out)
+		if m == nil {
+			return xerrors.Errorf("Unable to find a value for output %s using the regular expression %s", o.Name, o.Regex)
+		}
+
+		// use the first capture group when present
+		if len(m) > 1 {
+			outputs[i].Value = m[1]
+		} else {
+			outputs[i].Value = m[0]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+var execOutput = `
+Unseal Key 1: abc123
+Initial Root Token: s.xyz789
+`
+
+func TestExtractOutputsWithoutRegexUsesOutput(t *testing.T) {
+	o := []config.ExecOutput{config.ExecOutput{Name: "all"}}
+
+	err := extractOutputs(o, execOutput)
+	assert.NoError(t, err)
+	assert.Equal(t, "Unseal Key 1: abc123\nInitial Root Token: s.xyz789", o[0].Value)
+}
+
+func TestExtractOutputsUsesCaptureGroup(t *testing.T) {
+	o := []config.ExecOutput{
+		config.ExecOutput{Name: "token", Regex: `Initial Root Token: (\S+)`},
+		config.ExecOutput{Name: "key", Regex: `abc\d+`},
+	}
+
+	err := extractOutputs(o, execOutput)
+	assert.NoError(t, err)
+	assert.Equal(t, "s.xyz789", o[0].Value)
+	assert.Equal(t, "abc123", o[1].Value)
+}
+
+func TestExtractOutputsWithNoMatchReturnsError(t *testing.T) {
+	o := []config.ExecOutput{config.ExecOutput{Name: "token", Regex: `Token: (\d+)`}}
+
+	err := extractOutputs(o, execOutput)
+	assert.Error(t, err)
+}
+
+func TestExtractOutputsWithInvalidRegexReturnsError(t *testing.T) {
+	o := []config.ExecOutput{config.ExecOutput{Name: "token", Regex: `(`}}
+
+	err := extractOutputs(o, execOutput)
+	assert.Error(t, err)
+}
@@ -1,44 +1,54 @@
 package providers
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
 	"time"
 
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients"
 	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
 	"golang.org/x/xerrors"
 )
 
 // ExecRemote provider allows the execution of arbitrary commands on an existing target or
 // can create a new container before running
 type ExecRemote struct {
-	config *config.ExecRemote
-	client clients.ContainerTasks
-	log    hclog.Logger
+	config     *config.ExecRemote
+	client     clients.ContainerTasks
+	kubeClient clients.Kubernetes
+	log        hclog.Logger
 }
 
 // NewRemoteExec creates a new Exec provider
-func NewRemoteExec(c *config.ExecRemote, ex clients.ContainerTasks, l hclog.Logger) *ExecRemote {
-	return &ExecRemote{c, ex, l}
+func NewRemoteExec(c *config.ExecRemote, ex clients.ContainerTasks, kc clients.Kubernetes, l hclog.Logger) *ExecRemote {
+	return &ExecRemote{c, ex, kc, l}
 }
 
 // Create a new execution instance
-func (c *ExecRemote) Create() error {
-	c.log.Info("Remote executing command", "ref", c.config.Name, "command", c.config.Command, "args", c.config.Arguments, "image", c.config.Image)
+func (c *ExecRemote) Create(ctx context.Context) error {
+	c.log.Info("Remote executing command", "ref", c.config.Name, "command", c.config.Command, "script", c.config.Script, "args", c.config.Arguments, "image", c.config.Image)
 
-	/*
-		if c.config.Script != "" {
-			return fmt.Errorf("Remote execution of Scripts are not currently implemented: %s", c.config.Script)
-		}
-	*/
+	return c.run(ctx, c.config.Script, c.config.Command, c.config.Arguments, c.config.Outputs)
+}
+
+// run executes the script or command in the target pod, the target
+// container, or a new container when no target is set
+func (c *ExecRemote) run(ctx context.Context, script, command string, args []string, outputs []config.ExecOutput) error {
+	if c.config.Pod != nil {
+		return c.execPod(script, command, args, outputs)
+	}
 
 	// execution target id
 	targetID := ""
-
 	if c.config.Target == "" {
 		// Not using existing target create new container
-		id, err := c.createRemoteExecContainer()
+		id, err := c.createRemoteExecContainer(ctx)
 		if err != nil {
 			return xerrors.Errorf("Unable to create container for remote exec: %w", err)
 		}
@@ -58,7 +68,7 @@ func (c *ExecRemote) Create() error {
 		case config.TypeNomadCluster:
 			fallthrough
 		case config.TypeContainer:
-			ids, err := c.client.FindContainerIDs(target.Info().Name, target.Info().Type)
+			ids, err := c.client.FindContainerIDs(ctx, target.Info().Name, target.Info().Type)
 
 			if err != nil {
 				return xerrors.Errorf("Unable to find remote exec target: %w", err)
@@ -72,10 +82,34 @@ func (c *ExecRemote) Create() error {
 		}
 	}
 
-	// execute the script in the container
-	command := []string{}
-	command = append(command, c.config.Command)
-	command = append(command, c.config.Arguments...)
+	err := c.execute(ctx, targetID, script, command, args, outputs)
+
+	// destroy the container if we created one, the container is removed
+	// even when the operation has been cancelled
+	if c.config.Target == "" {
+		c.client.RemoveContainer(context.Background(), targetID)
+	}
+
+	return err
+}
+
+// execute runs the command or script in the container with the given id
+func (c *ExecRemote) execute(ctx context.Context, id, script, cmd string, args []string, outputs []config.ExecOutput) error {
+	command := []string{cmd}
+
+	// copy the script to the container and execute it rather than the command
+	if script != "" {
+		dst := fmt.Sprintf("/tmp/%s", filepath.Base(script))
+
+		err := c.client.CopyToContainer(ctx, id, script, dst)
+		if err != nil {
+			return xerrors.Errorf("Unable to copy script to remote container: %w", err)
+		}
+
+		command = []string{dst}
+	}
+
+	command = append(command, args...)
 
 	// build the environment variables
 	envs := []string{}
@@ -83,20 +117,91 @@ func (c *ExecRemote) Create() error {
 		envs = append(envs, fmt.Sprintf("%s=%s", e.Key, e.Value))
 	}
 
-	err := c.client.ExecuteCommand(targetID, command, envs, c.config.WorkingDirectory, c.log.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug}))
+	out := bytes.NewBufferString("")
+	w := io.MultiWriter(c.log.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug}), out)
+
+	err := c.client.ExecuteCommand(ctx, id, command, envs, c.config.WorkingDirectory, w)
 	if err != nil {
-		err = xerrors.Errorf("Unable to execute command in remote container: %w", err)
+		return xerrors.Errorf("Unable to execute command in remote container: %w", err)
 	}
 
-	// destroy the container if we created one
-	if c.config.Target == "" {
-		c.client.RemoveContainer(targetID)
+	err = extractOutputs(outputs, out.String())
+	if err != nil {
+		return xerrors.Errorf("Unable to capture output from command: %w", err)
 	}
 
-	return err
+	return nil
+}
+
+// execPod executes the command in a pod running in the target Kubernetes cluster
+func (c *ExecRemote) execPod(script, cmd string, args []string, outputs []config.ExecOutput) error {
+	if script != "" {
+		return xerrors.Errorf("Executing scripts is not supported when running commands in a pod, use cmd instead")
+	}
+
+	target, err := c.config.FindDependentResource(c.config.Target)
+	if err != nil {
+		return xerrors.Errorf("Unable to find target: %w", err)
+	}
+
+	if target.Info().Type != config.TypeK8sCluster {
+		return xerrors.Errorf("Pod exec is only supported when the target is a %s, got %s", config.TypeK8sCluster, target.Info().Type)
+	}
+
+	_, kcPath, _ := utils.CreateKubeConfigPath(target.Info().Name)
+	err = c.kubeClient.SetConfig(kcPath)
+	if err != nil {
+		return xerrors.Errorf("Unable to create Kubernetes client: %w", err)
+	}
+
+	ns := c.config.Pod.Namespace
+	if ns == "" {
+		ns = "default"
+	}
+
+	// the Kubernetes exec API does not support setting the environment
+	// or working directory, wrap the command so the shell sets them
+	command := []string{cmd}
+	command = append(command, args...)
+
+	if len(c.config.Environment) > 0 || c.config.WorkingDirectory != "" {
+		script := "exec \"$@\""
+		if c.config.WorkingDirectory != "" {
+			script = fmt.Sprintf("cd %s && %s", shellQuote(c.config.WorkingDirectory), script)
+		}
+
+		for _, e := range c.config.Environment {
+			script = fmt.Sprintf("export %s=%s && %s", e.Key, shellQuote(e.Value), script)
+		}
+
+		command = append([]string{"sh", "-c", script, "--"}, command...)
+	}
+
+	c.log.Debug("Executing command in pod", "ref", c.config.Name, "namespace", ns, "selector", c.config.Pod.Selector, "command", strings.Join(command, " "))
+
+	out := bytes.NewBufferString("")
+	w := io.MultiWriter(c.log.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Debug}), out)
+
+	err = c.kubeClient.ExecPod(ns, c.config.Pod.Selector, c.config.Pod.Container, command, w)
+	if err != nil {
+		return xerrors.Errorf("Unable to execute command in pod: %w", err)
+	}
+
+	err = extractOutputs(outputs, out.String())
+	if err != nil {
+		return xerrors.Errorf("Unable to capture output from command: %w", err)
+	}
+
+	return nil
+}
+
+// shellQuote quotes s so that it is passed to the shell as a single word
+// without any expansion
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
 
-func (c *ExecRemote) createRemoteExecContainer() (string, error) {
+func (c *ExecRemote) createRemoteExecContainer(ctx context.Context) (string, error) {
 	// generate the ID for the new container based on the clock time and a string
 	cc := config.NewContainer(fmt.Sprintf("%d.remote_exec", time.Now().Nanosecond()))
 	c.config.ResourceInfo.AddChild(cc)
@@ -107,22 +212,29 @@ func (c *ExecRemote) createRemoteExecContainer() (string, error) {
 	cc.Volumes = c.config.Volumes
 
 	// pull any images needed for this container
-	err := c.client.PullImage(cc.Image, false)
+	err := c.client.PullImage(ctx, cc.Image, false)
 	if err != nil {
 		c.log.Error("Error pulling container image", "ref", cc.Name, "image", cc.Image.Name)
 
 		return "", err
 	}
 
-	return c.client.CreateContainer(cc)
+	return c.client.CreateContainer(ctx, cc)
 }
 
-// Destroy statisfies the interface requirements but is not used
-func (c *ExecRemote) Destroy() error {
-	return nil
+// Destroy runs the on_destroy command when set
+func (c *ExecRemote) Destroy(ctx context.Context) error {
+	if c.config.OnDestroy == nil {
+		return nil
+	}
+
+	od := c.config.OnDestroy
+	c.log.Info("Remote executing destroy command", "ref", c.config.Name, "command", od.Command, "script", od.Script, "args", od.Arguments, "image", c.config.Image)
+
+	return c.run(ctx, od.Script, od.Command, od.Arguments, nil)
 }
 
 // Lookup statisfies the interface requirements but is not used
-func (c *ExecRemote) Lookup() ([]string, error) {
+func (c *ExecRemote) Lookup(ctx context.Context) ([]string, error) {
 	return []string{}, nil
 }
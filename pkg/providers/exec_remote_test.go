@@ -1,12 +1,19 @@
 package providers
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients/mocks"
 	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -19,6 +26,7 @@ func testRemoteExecSetupMocks() (*config.ExecRemote, *config.Network, *mocks.Moc
 	md.On("ExecuteCommand", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	md.On("RemoveContainer", mock.Anything).Return(nil)
 	md.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{"1234"}, nil)
+	md.On("CopyToContainer", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
 	trex := &config.ExecRemote{
 		Image:       &config.Image{Name: "tools:v1"},
@@ -30,6 +38,8 @@ func testRemoteExecSetupMocks() (*config.ExecRemote, *config.Network, *mocks.Moc
 
 	net := config.NewNetwork("wan")
 
+	cluster := config.NewK8sCluster("k3s")
+
 	cont := config.NewContainer("test")
 	cont.Networks = []config.NetworkAttachment{config.NetworkAttachment{Name: "network.wan"}}
 
@@ -37,26 +47,44 @@ func testRemoteExecSetupMocks() (*config.ExecRemote, *config.Network, *mocks.Moc
 	c.AddResource(net)
 	c.AddResource(trex)
 	c.AddResource(cont)
+	c.AddResource(cluster)
 
 	return trex, net, md
 }
 
-/*
-func TestRemoteExecThrowsErrorIfScript(t *testing.T) {
+func TestRemoteExecWithScriptCopiesAndExecutesScript(t *testing.T) {
+	trex, _, md := testRemoteExecSetupMocks()
+	trex.Script = "/files/setup.sh"
+	trex.Command = ""
+	p := NewRemoteExec(trex, md, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "CopyToContainer", "1234", "/files/setup.sh", "/tmp/setup.sh")
+
+	params := getCalls(&md.Mock, "ExecuteCommand")[0].Arguments[1].([]string)
+	assert.Equal(t, []string{"/tmp/setup.sh", "-f", "/dev/null"}, params)
+}
+
+func TestRemoteExecWithScriptCopyFailReturnsErrorAndRemovesContainer(t *testing.T) {
 	trex, _, md := testRemoteExecSetupMocks()
-	trex.Script = "./script.sh"
-	p := NewRemoteExec(trex, md, hclog.NewNullLogger())
+	trex.Script = "/files/setup.sh"
+	removeOn(&md.Mock, "CopyToContainer")
+	md.On("CopyToContainer", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+	p := NewRemoteExec(trex, md, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
+	md.AssertNotCalled(t, "ExecuteCommand", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	md.AssertCalled(t, "RemoveContainer", "1234")
 }
-*/
 
 func TestRemoteExecPullsImageWhenNoTarget(t *testing.T) {
 	trex, _, md := testRemoteExecSetupMocks()
-	p := NewRemoteExec(trex, md, hclog.NewNullLogger())
+	p := NewRemoteExec(trex, md, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 	md.AssertCalled(t, "PullImage", mock.Anything, mock.Anything)
 }
@@ -66,17 +94,17 @@ func TestRemoteExecPullsImageReturnsErrorWhenError(t *testing.T) {
 	removeOn(&md.Mock, "PullImage")
 	md.On("PullImage", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
 
-	p := NewRemoteExec(trex, md, hclog.NewNullLogger())
+	p := NewRemoteExec(trex, md, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
 func TestRemoteExecCreatesContainerWhenNoTarget(t *testing.T) {
 	trex, _, md := testRemoteExecSetupMocks()
-	p := NewRemoteExec(trex, md, hclog.NewNullLogger())
+	p := NewRemoteExec(trex, md, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 	md.AssertCalled(t, "CreateContainer", mock.Anything)
 }
@@ -86,18 +114,18 @@ func TestRemoteExecCreatesContainerFailsReturnError(t *testing.T) {
 	removeOn(&md.Mock, "CreateContainer")
 	md.On("CreateContainer", mock.Anything).Return("", fmt.Errorf("boom"))
 
-	p := NewRemoteExec(trex, md, hclog.NewNullLogger())
+	p := NewRemoteExec(trex, md, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
 func TestRemoteExecWithTargetLooksupID(t *testing.T) {
 	trex, _, md := testRemoteExecSetupMocks()
 	trex.Target = "container.test"
-	p := NewRemoteExec(trex, md, hclog.NewNullLogger())
+	p := NewRemoteExec(trex, md, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 	md.AssertCalled(t, "FindContainerIDs", "test", config.TypeContainer)
 }
@@ -107,17 +135,17 @@ func TestRemoteExecWithTargetLooksupIDNotFoundReturnsError(t *testing.T) {
 	trex.Target = "container.test"
 	removeOn(&md.Mock, "FindContainerIDs")
 	md.On("FindContainerIDs", "test", config.TypeContainer).Return([]string{}, nil)
-	p := NewRemoteExec(trex, md, hclog.NewNullLogger())
+	p := NewRemoteExec(trex, md, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
 func TestRemoteExecExecutesCommand(t *testing.T) {
 	trex, _, md := testRemoteExecSetupMocks()
-	p := NewRemoteExec(trex, md, hclog.NewNullLogger())
+	p := NewRemoteExec(trex, md, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 	md.AssertCalled(t, "ExecuteCommand", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 
@@ -135,17 +163,32 @@ func TestRemoteExecExecutesCommandFailReturnsError(t *testing.T) {
 	removeOn(&md.Mock, "ExecuteCommand")
 	md.On("ExecuteCommand", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
 
-	p := NewRemoteExec(trex, md, hclog.NewNullLogger())
+	p := NewRemoteExec(trex, md, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
+func TestRemoteExecCapturesOutputs(t *testing.T) {
+	trex, _, md := testRemoteExecSetupMocks()
+	trex.Outputs = []config.ExecOutput{config.ExecOutput{Name: "token", Regex: `token: (\S+)`}}
+	removeOn(&md.Mock, "ExecuteCommand")
+	md.On("ExecuteCommand", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(4).(io.Writer).Write([]byte("token: abc123\n"))
+	}).Return(nil)
+
+	p := NewRemoteExec(trex, md, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", trex.Outputs[0].Value)
+}
+
 func TestRemoteExecRemovesContainer(t *testing.T) {
 	trex, _, md := testRemoteExecSetupMocks()
-	p := NewRemoteExec(trex, md, hclog.NewNullLogger())
+	p := NewRemoteExec(trex, md, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 	md.AssertCalled(t, "RemoveContainer", "1234")
 }
@@ -156,18 +199,136 @@ func TestRemoteExecRemoveContainerFailReturnsError(t *testing.T) {
 	removeOn(&md.Mock, "RemoveContainer")
 	md.On("RemoveContainer", "1234").Return(fmt.Errorf("boom"))
 
-	p := NewRemoteExec(trex, md, hclog.NewNullLogger())
+	p := NewRemoteExec(trex, md, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 */
 func TestRemoteExecDoesNOTRemovesContainerWhenTarget(t *testing.T) {
 	trex, _, md := testRemoteExecSetupMocks()
 	trex.Target = "container.test"
-	p := NewRemoteExec(trex, md, hclog.NewNullLogger())
+	p := NewRemoteExec(trex, md, nil, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+	md.AssertNotCalled(t, "RemoveContainer", mock.Anything)
+}
+
+func testRemoteExecSetupPodMocks() (*config.ExecRemote, *mocks.MockContainerTasks, *mocks.MockKubernetes) {
+	trex, _, md := testRemoteExecSetupMocks()
+	trex.Target = "k8s_cluster.k3s"
+	trex.Pod = &config.ExecPod{Selector: "app=vault"}
+
+	mk := &mocks.MockKubernetes{}
+	mk.On("SetConfig", mock.Anything).Return(nil)
+	mk.On("ExecPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	return trex, md, mk
+}
+
+func TestRemoteExecWithPodExecutesInPod(t *testing.T) {
+	trex, md, mk := testRemoteExecSetupPodMocks()
+	trex.Environment = nil
+	p := NewRemoteExec(trex, md, mk, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	_, kcPath, _ := utils.CreateKubeConfigPath("k3s")
+	mk.AssertCalled(t, "SetConfig", kcPath)
+	mk.AssertCalled(t, "ExecPod", "default", "app=vault", "", []string{"tail", "-f", "/dev/null"}, mock.Anything)
+	md.AssertNotCalled(t, "ExecuteCommand", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRemoteExecWithPodWrapsEnvironmentAndWorkingDirectory(t *testing.T) {
+	trex, md, mk := testRemoteExecSetupPodMocks()
+	trex.WorkingDirectory = "/files"
+	p := NewRemoteExec(trex, md, mk, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	params := getCalls(&mk.Mock, "ExecPod")[0].Arguments[3].([]string)
+	assert.Equal(t, []string{"sh", "-c", `export abc='123' && cd '/files' && exec "$@"`, "--", "tail", "-f", "/dev/null"}, params)
+}
+
+func TestRemoteExecWithPodQuotesWorkingDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	wd := filepath.Join(dir, "it's $HOME; exit 1")
+	os.MkdirAll(wd, os.ModePerm)
+
+	trex, md, mk := testRemoteExecSetupPodMocks()
+	trex.WorkingDirectory = wd
+	trex.Environment = []config.KV{config.KV{Key: "abc", Value: "$(echo injected)"}}
+	p := NewRemoteExec(trex, md, mk, hclog.NewNullLogger())
+
+	err = p.Create(context.Background())
+	assert.NoError(t, err)
+
+	// run the wrapped command with the local shell to check the quoting
+	params := getCalls(&mk.Mock, "ExecPod")[0].Arguments[3].([]string)
+	out, err := exec.Command(params[0], params[1], params[2], params[3], "sh", "-c", `pwd && echo "$abc"`).Output()
+	assert.NoError(t, err)
+	assert.Equal(t, wd+"\n$(echo injected)\n", string(out))
+}
+
+func TestRemoteExecWithPodAndNonKubernetesTargetReturnsError(t *testing.T) {
+	trex, md, mk := testRemoteExecSetupPodMocks()
+	trex.Target = "container.test"
+	p := NewRemoteExec(trex, md, mk, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.Error(t, err)
+	mk.AssertNotCalled(t, "ExecPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRemoteExecWithPodExecErrorReturnsError(t *testing.T) {
+	trex, md, mk := testRemoteExecSetupPodMocks()
+	removeOn(&mk.Mock, "ExecPod")
+	mk.On("ExecPod", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+	p := NewRemoteExec(trex, md, mk, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.Error(t, err)
+}
+
+func TestRemoteExecDestroyWithoutOnDestroyDoesNothing(t *testing.T) {
+	trex, _, md := testRemoteExecSetupMocks()
+	p := NewRemoteExec(trex, md, nil, hclog.NewNullLogger())
+
+	err := p.Destroy(context.Background())
+	assert.NoError(t, err)
+	md.AssertNotCalled(t, "CreateContainer", mock.Anything)
+	md.AssertNotCalled(t, "ExecuteCommand", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRemoteExecDestroyExecutesOnDestroyInTarget(t *testing.T) {
+	trex, _, md := testRemoteExecSetupMocks()
+	trex.Target = "container.test"
+	trex.OnDestroy = &config.ExecDestroy{Command: "dns", Arguments: []string{"remove"}}
+	p := NewRemoteExec(trex, md, nil, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Destroy(context.Background())
 	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "ExecuteCommand")[0].Arguments[1].([]string)
+	assert.Equal(t, []string{"dns", "remove"}, params)
 	md.AssertNotCalled(t, "RemoveContainer", mock.Anything)
 }
+
+func TestRemoteExecDestroyExecutesOnDestroyInNewContainer(t *testing.T) {
+	trex, _, md := testRemoteExecSetupMocks()
+	trex.OnDestroy = &config.ExecDestroy{Script: "/files/cleanup.sh"}
+	p := NewRemoteExec(trex, md, nil, hclog.NewNullLogger())
+
+	err := p.Destroy(context.Background())
+	assert.NoError(t, err)
+
+	md.AssertCalled(t, "CreateContainer", mock.Anything)
+	md.AssertCalled(t, "CopyToContainer", "1234", "/files/cleanup.sh", "/tmp/cleanup.sh")
+	md.AssertCalled(t, "RemoveContainer", "1234")
+}
@@ -0,0 +1,29 @@
+package providers
+
+import (
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// FetchPaths downloads any remote paths such as git repositories or HTTP urls
+// to the local cache, the returned slice contains local paths in the same order
+func FetchPaths(g clients.Getter, paths []string) ([]string, error) {
+	local := []string{}
+
+	for _, p := range paths {
+		if !utils.IsRemoteURI(p) {
+			local = append(local, p)
+			continue
+		}
+
+		dir, err := g.Fetch(p)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to fetch %s: %w", p, err)
+		}
+
+		local = append(local, dir)
+	}
+
+	return local, nil
+}
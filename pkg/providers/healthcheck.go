@@ -0,0 +1,58 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+// IdleFunc runs wait, the caller of the provider can release anything it
+// holds which is not needed while wait blocks, such as a slot limiting the
+// number of resources created at the same time
+type IdleFunc func(wait func() error) error
+
+type idleKey struct{}
+
+// WithIdle returns a copy of ctx where health checks, and other operations
+// where the provider waits for a resource to become ready, are run with f
+func WithIdle(ctx context.Context, f IdleFunc) context.Context {
+	return context.WithValue(ctx, idleKey{}, f)
+}
+
+// Idle runs wait with the IdleFunc set on ctx, wait is called directly when
+// ctx does not have an IdleFunc
+func Idle(ctx context.Context, wait func() error) error {
+	if f, ok := ctx.Value(idleKey{}).(IdleFunc); ok {
+		return f(wait)
+	}
+
+	return wait()
+}
+
+// healthCheckEndpoints waits for the HTTP and TCP endpoints defined in the
+// health check to become available, the function blocks until all endpoints
+// pass or the timeout elapses
+func healthCheckEndpoints(ctx context.Context, hc clients.HTTP, c *config.HealthCheck, timeout time.Duration) error {
+	return Idle(ctx, func() error {
+		if c.HTTP != "" {
+			var err error
+			if o := c.HTTPOptions; o != nil {
+				err = hc.HealthCheckHTTPWithOptions(ctx, c.HTTP, *o, timeout)
+			} else {
+				err = hc.HealthCheckHTTP(ctx, c.HTTP, timeout)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		if c.TCP != "" {
+			return hc.HealthCheckTCP(ctx, c.TCP, timeout)
+		}
+
+		return nil
+	})
+}
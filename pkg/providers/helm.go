@@ -1,9 +1,9 @@
 package providers
 
 import (
+	"context"
 	"path/filepath"
 	"strings"
-	"time"
 
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients"
@@ -26,7 +26,7 @@ func NewHelm(c *config.Helm, kc clients.Kubernetes, hc clients.Helm, g clients.G
 }
 
 // Create implements the provider Create method
-func (h *Helm) Create() error {
+func (h *Helm) Create(ctx context.Context) error {
 	h.log.Info("Creating Helm chart", "ref", h.config.Name)
 
 	// get the target cluster
@@ -44,11 +44,9 @@ func (h *Helm) Create() error {
 	if !utils.IsLocalFolder(h.config.Chart) {
 		h.log.Debug("Fetching remote Helm chart", "ref", h.config.Name, "chart", h.config.Chart)
 
-		helmFolder := filepath.Join(utils.GetHelmLocalFolder(""), strings.Replace(h.config.Chart, "//", "/", -1))
-
-		err := h.getterClient.Get(h.config.Chart, helmFolder)
+		helmFolder, err := FetchHelmChart(h.getterClient, h.config.Chart)
 		if err != nil {
-			return xerrors.Errorf("Unable to download remote chart: %w", err)
+			return err
 		}
 
 		// set the config to the local path
@@ -63,29 +61,22 @@ func (h *Helm) Create() error {
 		return xerrors.Errorf("unable to create Kubernetes client: %w", err)
 	}
 
-	err = h.helmClient.Create(kcPath, h.config.Name, h.config.Namespace, h.config.Chart, h.config.Values, h.config.ValuesString)
+	err = h.helmClient.Create(ctx, kcPath, h.config.Name, h.config.Namespace, h.config.Chart, h.config.Values, h.config.ValuesString)
 	if err != nil {
 		return err
 	}
 
 	// we can now health check the install
-	if h.config.HealthCheck != nil && len(h.config.HealthCheck.Pods) > 0 {
-		to, err := time.ParseDuration(h.config.HealthCheck.Timeout)
-		if err != nil {
-			return xerrors.Errorf("unable to parse healthcheck duration: %w", err)
-		}
-
-		err = h.kubeClient.HealthCheckPods(h.config.HealthCheck.Pods, to)
-		if err != nil {
-			return xerrors.Errorf("healthcheck failed after helm chart setup: %w", err)
-		}
+	err = healthCheckKubernetes(ctx, h.kubeClient, h.config.HealthCheck)
+	if err != nil {
+		return xerrors.Errorf("healthcheck failed after helm chart setup: %w", err)
 	}
 
 	return nil
 }
 
 // Destroy implements the provider Destroy method
-func (h *Helm) Destroy() error {
+func (h *Helm) Destroy(ctx context.Context) error {
 	h.log.Info("Destroy Helm chart", "ref", h.config.Name)
 	kcPath, err := h.getKubeConfigPath()
 	if err != nil {
@@ -108,7 +99,7 @@ func (h *Helm) Destroy() error {
 }
 
 // Lookup implements the provider Lookup method
-func (h *Helm) Lookup() ([]string, error) {
+func (h *Helm) Lookup(ctx context.Context) ([]string, error) {
 	return []string{}, nil
 }
 
@@ -121,3 +112,22 @@ func (h *Helm) getKubeConfigPath() (string, error) {
 	_, destPath, _ := utils.CreateKubeConfigPath(target.Info().Name)
 	return destPath, nil
 }
+
+// FetchHelmChart downloads a remote chart to the Helm cache and returns the
+// local folder containing the chart
+func FetchHelmChart(g clients.Getter, chart string) (string, error) {
+	helmFolder := HelmChartFolder(chart)
+
+	err := g.Get(chart, helmFolder)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to download remote chart: %w", err)
+	}
+
+	return helmFolder, nil
+}
+
+// HelmChartFolder returns the folder in the Helm cache a remote chart is
+// downloaded to
+func HelmChartFolder(chart string) string {
+	return filepath.Join(utils.GetHelmLocalFolder(""), strings.Replace(chart, "//", "/", -1))
+}
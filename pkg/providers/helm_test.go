@@ -1,10 +1,12 @@
 package providers
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	clients "github.com/shipyard-run/shipyard/pkg/clients/mocks"
@@ -22,6 +24,8 @@ func setupHelm() (*clients.MockHelm, *clients.MockKubernetes, *clients.Getter, *
 	kc := &clients.MockKubernetes{}
 	kc.On("SetConfig", mock.Anything).Return(nil)
 	kc.On("HealthCheckPods", mock.Anything, mock.Anything).Return(nil)
+	kc.On("WaitForCRDs", mock.Anything, mock.Anything).Return(nil)
+	kc.On("WaitForEndpoints", mock.Anything, mock.Anything).Return(nil)
 
 	mg := &clients.Getter{}
 	mg.On("Get", mock.Anything, mock.Anything).Return(nil)
@@ -43,7 +47,7 @@ func TestHelmCreateCantFindClusterReturnsError(t *testing.T) {
 	_, _, _, c, p := setupHelm()
 	c.RemoveResource(c.Resources[0])
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
@@ -53,7 +57,7 @@ func TestHelmCreateGetsRemoteRepo(t *testing.T) {
 	hc.(*config.Helm).Chart = "github.com/shipyard-run/blueprints//vault-k8s"
 	helmFolder := filepath.Join(utils.ShipyardHome(), "helm_charts", strings.Replace(hc.(*config.Helm).Chart, "//", "/", -1))
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	mg.AssertCalled(t, "Get", mock.Anything, helmFolder)
@@ -63,7 +67,7 @@ func TestHelmCreateGetsRemoteRepo(t *testing.T) {
 func TestHelmCreateSetsConfig(t *testing.T) {
 	_, kc, mg, _, p := setupHelm()
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	_, fp, _ := utils.CreateKubeConfigPath("tester")
@@ -76,14 +80,14 @@ func TestHelmCreateConfigSetFailReturnsError(t *testing.T) {
 	removeOn(&kc.Mock, "SetConfig")
 	kc.On("SetConfig", mock.Anything).Return(fmt.Errorf("boom"))
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
 func TestHelmCreateCallsCreateWithDefaultNamespace(t *testing.T) {
 	hm, _, _, _, p := setupHelm()
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	hm.AssertCalled(
@@ -102,7 +106,7 @@ func TestHelmCreateCallsCreateWithCustomNamespace(t *testing.T) {
 	hm, _, _, _, p := setupHelm()
 	p.config.Namespace = "custom"
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	hm.AssertCalled(
@@ -122,14 +126,14 @@ func TestHelmCreateCallCreateFailReturnsError(t *testing.T) {
 	removeOn(&hm.Mock, "Create")
 	hm.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
 func TestHelmDoesNotHealthChecksPodswhenNotSet(t *testing.T) {
 	_, kc, _, _, p := setupHelm()
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	kc.AssertNotCalled(t, "HealthCheckPods", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
@@ -139,7 +143,7 @@ func TestHelmHealthChecksPodswhenSet(t *testing.T) {
 	_, kc, _, _, p := setupHelm()
 	p.config.HealthCheck = &config.HealthCheck{Timeout: "1s", Pods: []string{"consul=release"}}
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	kc.AssertCalled(t, "HealthCheckPods", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
@@ -151,21 +155,37 @@ func TestHelmCreateHealthCheckPodsFailReturnsError(t *testing.T) {
 	removeOn(&kc.Mock, "HealthCheckPods")
 	kc.On("HealthCheckPods", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
+
+func TestHelmWaitsForCRDsAndWebhookWhenSet(t *testing.T) {
+	_, kc, _, _, p := setupHelm()
+	p.config.HealthCheck = &config.HealthCheck{
+		Timeout:  "1s",
+		CRDs:     []string{"certificates.cert-manager.io"},
+		Services: []string{"cert-manager/cert-manager-webhook"},
+	}
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	kc.AssertCalled(t, "WaitForCRDs", []string{"certificates.cert-manager.io"}, time.Second)
+	kc.AssertCalled(t, "WaitForEndpoints", []string{"cert-manager/cert-manager-webhook"}, time.Second)
+}
+
 func TestHelmDestroyCantFindClusterReturnsError(t *testing.T) {
 	_, _, _, c, p := setupHelm()
 	c.RemoveResource(c.Resources[0])
 
-	err := p.Destroy()
+	err := p.Destroy(context.Background())
 	assert.Error(t, err)
 }
 
 func TestHelmDestroyCallsDestroyWithDefaultNamespace(t *testing.T) {
 	hm, _, _, _, p := setupHelm()
 
-	err := p.Destroy()
+	err := p.Destroy(context.Background())
 	assert.NoError(t, err)
 	hm.AssertCalled(t, "Destroy", mock.Anything, mock.Anything, "default")
 }
@@ -176,7 +196,7 @@ func TestHelmDestroyWithErrorSwallowsError(t *testing.T) {
 	removeOn(&hm.Mock, "Destroy")
 	hm.On("Destroy", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
 
-	err := p.Destroy()
+	err := p.Destroy(context.Background())
 	assert.NoError(t, err)
 	hm.AssertCalled(t, "Destroy", mock.Anything, mock.Anything, "custom")
 }
@@ -0,0 +1,150 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// imageCacheName is the name of the pull through registry container and its volume,
+// the cache is shared by all environments and is not removed on destroy
+const imageCacheName = "image-cache"
+
+const imageCacheImage = "registry:2"
+
+// imageCacheHostPort is the port the cache is published on so that the Docker
+// engine can pull container images through it
+const imageCacheHostPort = "5050"
+
+// imageCacheAddress is the mirror endpoint cluster nodes use to reach the cache
+var imageCacheAddress = fmt.Sprintf("http://%s:5000", utils.FQDN(imageCacheName, string(config.TypeContainer)))
+
+// ImageCacheMirror is the registry the Docker engine pulls images from the
+// Docker Hub through, Docker allows plain http for registries on the loopback
+// address
+var ImageCacheMirror = fmt.Sprintf("127.0.0.1:%s", imageCacheHostPort)
+
+// imageCacheLock stops clusters which are created concurrently from each
+// creating the cache
+var imageCacheLock sync.Mutex
+
+// UsesImageCache returns true when the resource pulls images from the Docker
+// Hub through the shared image cache
+func UsesImageCache(r config.Resource) bool {
+	k, ok := r.(*config.K8sCluster)
+	return ok && k.ImageCache && !hasRegistry(k.Registries, "docker.io")
+}
+
+// EnsureImageCache creates the pull through registry if it does not exist and
+// attaches it to the given networks so that cluster nodes can reach it.
+// Images are stored on a volume so they survive the cache being re-created
+func EnsureImageCache(ctx context.Context, client clients.ContainerTasks, networks []config.NetworkAttachment, l hclog.Logger) (string, error) {
+	imageCacheLock.Lock()
+	defer imageCacheLock.Unlock()
+
+	id, err := findImageCache(ctx, client)
+	if err != nil {
+		return "", err
+	}
+
+	if id == "" {
+		id, err = createImageCache(ctx, client, l)
+		if err != nil {
+			// another Shipyard process may have created the cache, use it
+			// when it exists
+			existing, ferr := findImageCache(ctx, client)
+			if ferr != nil || existing == "" {
+				return "", err
+			}
+
+			id = existing
+		}
+	}
+
+	for _, n := range networks {
+		err := client.AttachNetwork(ctx, n.Name, id)
+		if err != nil {
+			return "", xerrors.Errorf("Unable to attach image cache to network %s: %w", n.Name, err)
+		}
+	}
+
+	return imageCacheAddress, nil
+}
+
+// detachImageCache disconnects the cache from the networks so that they can
+// be removed, nodes of other clusters on the network fall back to pulling
+// from the Docker Hub until the cache is attached again
+func detachImageCache(ctx context.Context, client clients.ContainerTasks, networks []config.NetworkAttachment, l hclog.Logger) error {
+	imageCacheLock.Lock()
+	defer imageCacheLock.Unlock()
+
+	id, err := findImageCache(ctx, client)
+	if err != nil || id == "" {
+		return err
+	}
+
+	for _, n := range networks {
+		err := client.DetachNetwork(ctx, n.Name, id)
+		if err != nil {
+			l.Debug("Unable to detach image cache from network", "network", n.Name, "error", err)
+		}
+	}
+
+	return nil
+}
+
+func findImageCache(ctx context.Context, client clients.ContainerTasks) (string, error) {
+	ids, err := client.FindContainerIDs(ctx, imageCacheName, config.TypeContainer)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to lookup image cache: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return "", nil
+	}
+
+	return ids[0], nil
+}
+
+func createImageCache(ctx context.Context, client clients.ContainerTasks, l hclog.Logger) (string, error) {
+	l.Info("Creating image cache", "image", imageCacheImage)
+
+	err := client.PullImage(ctx, config.Image{Name: imageCacheImage}, false)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to pull image for image cache: %w", err)
+	}
+
+	volID, err := client.CreateVolume(clients.WithPersistentVolume(ctx), imageCacheName)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to create volume for image cache: %w", err)
+	}
+
+	cc := config.NewContainer(imageCacheName)
+	cc.Image = config.Image{Name: imageCacheImage}
+	cc.Environment = []config.KV{
+		config.KV{Key: "REGISTRY_PROXY_REMOTEURL", Value: "https://registry-1.docker.io"},
+	}
+	cc.Volumes = []config.Volume{
+		config.Volume{
+			Source:      volID,
+			Destination: "/var/lib/registry",
+			Type:        "volume",
+		},
+	}
+	cc.Ports = []config.Port{
+		config.Port{Local: "5000", Host: imageCacheHostPort, Protocol: "tcp"},
+	}
+
+	id, err := client.CreateContainer(ctx, cc)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to create image cache: %w", err)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,52 @@
+package providers
+
+import (
+	"fmt"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+// Images returns the Docker images which are pulled when the resource is
+// created, this includes the images defined in the config and the base
+// images used by the provider such as the cluster and ingress images
+func Images(r config.Resource) []config.Image {
+	images := []config.Image{}
+
+	switch v := r.(type) {
+	case *config.Container:
+		images = append(images, v.Image)
+	case *config.Sidecar:
+		images = append(images, v.Image)
+	case *config.ExecRemote:
+		if v.Image != nil {
+			images = append(images, *v.Image)
+		}
+	case *config.Docs:
+		if v.Image != nil {
+			images = append(images, *v.Image)
+		} else {
+			images = append(images, config.Image{Name: fmt.Sprintf("%s:%s", docsImageName, docsVersion)})
+		}
+
+		images = append(images, config.Image{Name: fmt.Sprintf("%s:%s", terminalImageName, terminalVersion)})
+	case *config.K8sCluster:
+		images = append(images, config.Image{Name: fmt.Sprintf("%s:%s", k3sBaseImage, v.Version)})
+		if UsesImageCache(v) {
+			images = append(images, config.Image{Name: imageCacheImage})
+		}
+
+		images = append(images, v.Images...)
+	case *config.NomadCluster:
+		version := v.Version
+		if version == "" {
+			version = nomadBaseVersion
+		}
+
+		images = append(images, config.Image{Name: fmt.Sprintf("%s:%s", nomadBaseImage, version)})
+		images = append(images, v.Images...)
+	case *config.Ingress, *config.ContainerIngress, *config.K8sIngress, *config.NomadIngress:
+		images = append(images, config.Image{Name: ingressImage})
+	}
+
+	return images
+}
@@ -0,0 +1,46 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImagesReturnsClusterBaseImages(t *testing.T) {
+	k := config.NewK8sCluster("k3s")
+	k.Version = "v1.18.4-k3s1"
+	k.ImageCache = true
+	k.Images = []config.Image{config.Image{Name: "consul:1.8.1"}}
+
+	assert.Equal(t, []config.Image{
+		config.Image{Name: "rancher/k3s:v1.18.4-k3s1"},
+		config.Image{Name: "registry:2"},
+		config.Image{Name: "consul:1.8.1"},
+	}, Images(k))
+
+	n := config.NewNomadCluster("nomad")
+	assert.Equal(t, []config.Image{config.Image{Name: "shipyardrun/nomad:" + nomadBaseVersion}}, Images(n))
+}
+
+func TestImagesDoesNotReturnCacheWhenMirrorConfigured(t *testing.T) {
+	k := config.NewK8sCluster("k3s")
+	k.ImageCache = true
+	k.Registries = []config.Registry{config.Registry{Name: "docker.io", Mirrors: []string{"https://mirror.corp.com"}}}
+
+	assert.NotContains(t, Images(k), config.Image{Name: imageCacheImage})
+}
+
+func TestImagesReturnsProviderImages(t *testing.T) {
+	d := config.NewDocs("docs")
+	assert.Len(t, Images(d), 2)
+
+	i := config.NewK8sIngress("consul")
+	assert.Equal(t, []config.Image{config.Image{Name: ingressImage}}, Images(i))
+
+	e := config.NewExecRemote("exec")
+	assert.Empty(t, Images(e))
+
+	h := config.NewHelm("vault")
+	assert.Empty(t, Images(h))
+}
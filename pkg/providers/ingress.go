@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"context"
 	"fmt"
 
 	hclog "github.com/hashicorp/go-hclog"
@@ -77,12 +78,12 @@ func NewK8sIngress(kc *config.K8sIngress, cc clients.ContainerTasks, l hclog.Log
 }
 
 // Create the ingress
-func (i *Ingress) Create() error {
+func (i *Ingress) Create(ctx context.Context) error {
 	i.log.Info("Creating Ingress", "ref", i.config.Name)
 
 	// check the ingress does not already exist
 	// TODO, we can probably extract all of the check and pull logic into a common function
-	ids, err := i.client.FindContainerIDs(i.config.Name, i.config.Type)
+	ids, err := i.client.FindContainerIDs(ctx, i.config.Name, i.config.Type)
 	if len(ids) > 0 {
 		return xerrors.Errorf("Unable to create ingress, and ingress with the name %s already exists: %w", i.config.Name, err)
 	}
@@ -92,7 +93,7 @@ func (i *Ingress) Create() error {
 	}
 
 	// pull any images needed for this container
-	err = i.client.PullImage(config.Image{Name: ingressImage}, false)
+	err = i.client.PullImage(ctx, config.Image{Name: ingressImage}, false)
 	if err != nil {
 		i.log.Error("Error pulling container image", "ref", i.config.Name, "image", ingressImage)
 
@@ -167,7 +168,7 @@ func (i *Ingress) Create() error {
 	c.Volumes = volumes
 	c.Environment = env
 
-	_, err = i.client.CreateContainer(c)
+	_, err = i.client.CreateContainer(ctx, c)
 	if err != nil {
 		return err
 	}
@@ -179,10 +180,10 @@ func (i *Ingress) Create() error {
 }
 
 // Destroy the ingress
-func (i *Ingress) Destroy() error {
+func (i *Ingress) Destroy(ctx context.Context) error {
 	i.log.Info("Destroy Ingress", "ref", i.config.Name, "type", i.config.Type)
 
-	ids, err := i.client.FindContainerIDs(i.config.Name, i.config.Type)
+	ids, err := i.client.FindContainerIDs(ctx, i.config.Name, i.config.Type)
 	if err != nil {
 		return err
 	}
@@ -190,13 +191,13 @@ func (i *Ingress) Destroy() error {
 	for _, id := range ids {
 		for _, n := range i.config.Networks {
 			i.log.Debug("Detaching container from network", "ref", i.config.Name, "id", id, "network", n.Name)
-			err := i.client.DetachNetwork(n.Name, id)
+			err := i.client.DetachNetwork(ctx, n.Name, id)
 			if err != nil {
 				i.log.Error("Unable to detach network", "ref", i.config.Name, "network", n.Name, "error", err)
 			}
 		}
 
-		err := i.client.RemoveContainer(id)
+		err := i.client.RemoveContainer(ctx, id)
 		if err != nil {
 			return err
 		}
@@ -207,7 +208,7 @@ func (i *Ingress) Destroy() error {
 }
 
 // Lookup the id of the ingress
-func (i *Ingress) Lookup() ([]string, error) {
+func (i *Ingress) Lookup(ctx context.Context) ([]string, error) {
 	return []string{}, nil
 }
 
@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -38,7 +39,7 @@ func TestIngressK8sErrorsWhenUnableToLookupIDs(t *testing.T) {
 
 	p := NewK8sIngress(&testK8sIngressConfig, md, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
@@ -48,7 +49,7 @@ func TestIngressK8sErrorsWhenClusterExists(t *testing.T) {
 
 	p := NewK8sIngress(&testK8sIngressConfig, md, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
@@ -56,7 +57,7 @@ func TestIngressK8sTargetPullsImage(t *testing.T) {
 	md := testIngressCreateMocks()
 	p := NewK8sIngress(&testK8sIngressConfig, md, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 	md.AssertCalled(t, "PullImage", config.Image{Name: ingressImage}, false)
 }
@@ -65,7 +66,7 @@ func TestIngressK8sTargetConfiguresCommand(t *testing.T) {
 	md := testIngressCreateMocks()
 	p := NewK8sIngress(&testK8sIngressConfig, md, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
@@ -85,7 +86,7 @@ func TestIngressK8sTargetConfiguresKubeConfig(t *testing.T) {
 	md := testIngressCreateMocks()
 	p := NewK8sIngress(&testK8sIngressConfig, md, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
@@ -105,7 +106,7 @@ func TestIngressK8sTargetWithNamespaceConfiguresCommand(t *testing.T) {
 	tc.Namespace = "mine"
 	p := NewK8sIngress(&tc, md, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
@@ -120,7 +121,7 @@ func TestIngressK8sTargetWithServiceConfiguresCommand(t *testing.T) {
 	tc.Service = "myservice"
 	p := NewK8sIngress(&tc, md, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
@@ -136,7 +137,7 @@ func TestIngressK8sTargetWithPodConfiguresCommand(t *testing.T) {
 	tc.Pod = "mypod"
 	p := NewK8sIngress(&tc, md, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
@@ -152,7 +153,7 @@ func TestIngressK8sTargetWithDeploymentConfiguresCommand(t *testing.T) {
 	tc.Deployment = "mydeployment"
 	p := NewK8sIngress(&tc, md, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
@@ -165,7 +166,7 @@ func TestIngressContainerTargetConfiguresCommand(t *testing.T) {
 	md := testIngressCreateMocks()
 	p := NewContainerIngress(&testIngressContainerConfig, md, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
@@ -178,7 +179,7 @@ func TestIngressContainerAddsPorts(t *testing.T) {
 	md := testIngressCreateMocks()
 	p := NewContainerIngress(&testIngressContainerConfig, md, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
@@ -192,13 +193,28 @@ func TestIngressContainerAddsPorts(t *testing.T) {
 	assert.Equal(t, testIngressContainerConfig.Ports, params.Ports)
 }
 
+func TestIngressK8sTargetAttachesToIPv6Network(t *testing.T) {
+	md := testIngressCreateMocks()
+
+	ic := testK8sIngressConfig
+	ic.Networks = []config.NetworkAttachment{config.NetworkAttachment{Name: "cloud", IPv6Address: "2001:db8::10"}}
+
+	p := NewK8sIngress(&ic, md, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	params := getCalls(&md.Mock, "CreateContainer")[0].Arguments[0].(*config.Container)
+	assert.Equal(t, ic.Networks, params.Networks)
+}
+
 func TestIngressContainerFailReturnsError(t *testing.T) {
 	md := testIngressCreateMocks()
 	removeOn(&md.Mock, "CreateContainer")
 	md.On("CreateContainer", mock.Anything).Return("", fmt.Errorf("boom"))
 	p := NewContainerIngress(&testIngressContainerConfig, md, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
@@ -208,7 +224,7 @@ func TestIngressK8sTargetDestroysContainer(t *testing.T) {
 	md.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{"ingress"}, nil)
 	p := NewIngress(&testIngressConfig, md, hclog.NewNullLogger())
 
-	err := p.Destroy()
+	err := p.Destroy(context.Background())
 	assert.NoError(t, err)
 	md.AssertCalled(t, "RemoveContainer", "ingress")
 	md.AssertCalled(t, "DetachNetwork", mock.Anything, mock.Anything, mock.Anything)
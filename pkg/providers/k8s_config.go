@@ -1,6 +1,10 @@
 package providers
 
 import (
+	"context"
+	"strings"
+	"time"
+
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/shipyard-run/shipyard/pkg/clients"
 	"github.com/shipyard-run/shipyard/pkg/config"
@@ -11,16 +15,17 @@ import (
 type K8sConfig struct {
 	config *config.K8sConfig
 	client clients.Kubernetes
+	getter clients.Getter
 	log    hclog.Logger
 }
 
 // NewK8sConfig creates a provider which can create and destroy kubernetes configuration
-func NewK8sConfig(c *config.K8sConfig, kc clients.Kubernetes, l hclog.Logger) *K8sConfig {
-	return &K8sConfig{c, kc, l}
+func NewK8sConfig(c *config.K8sConfig, kc clients.Kubernetes, g clients.Getter, l hclog.Logger) *K8sConfig {
+	return &K8sConfig{c, kc, g, l}
 }
 
 // Create the Kubernetes resources defined by the config
-func (c *K8sConfig) Create() error {
+func (c *K8sConfig) Create(ctx context.Context) error {
 	c.log.Info("Applying Kubernetes configuration", "ref", c.config.Name, "config", c.config.Paths)
 
 	err := c.setup()
@@ -28,11 +33,40 @@ func (c *K8sConfig) Create() error {
 		return err
 	}
 
-	err = c.client.Apply(c.config.Paths, c.config.WaitUntilReady)
+	paths, err := FetchPaths(c.getter, c.config.Paths)
+	if err != nil {
+		return err
+	}
+
+	err = c.client.Apply(ctx, paths, c.config.WaitUntilReady)
 	if err != nil {
 		return err
 	}
 
+	// fetch the objects which have just been applied and remove any objects
+	// from a previous apply which no longer exist in the config
+	inv, err := c.client.Inventory(paths)
+	if err != nil {
+		return xerrors.Errorf("Unable to build inventory for Kubernetes config: %w", err)
+	}
+
+	stale := staleObjects(c.config.Inventory, inv)
+	if len(stale) > 0 {
+		c.log.Info("Pruning Kubernetes objects removed from config", "ref", c.config.Name, "objects", len(stale))
+
+		err = c.client.DeleteObjects(stale)
+		if err != nil {
+			return xerrors.Errorf("Unable to prune Kubernetes objects: %w", err)
+		}
+	}
+
+	c.config.Inventory = inv
+
+	err = healthCheckKubernetes(ctx, c.client, c.config.HealthCheck)
+	if err != nil {
+		return xerrors.Errorf("healthcheck failed after applying Kubernetes config: %w", err)
+	}
+
 	// set the status
 	c.config.Status = config.Applied
 
@@ -40,7 +74,7 @@ func (c *K8sConfig) Create() error {
 }
 
 // Destroy the Kubernetes resources defined by the config
-func (c *K8sConfig) Destroy() error {
+func (c *K8sConfig) Destroy(ctx context.Context) error {
 	c.log.Info("Destroy Kubernetes configuration", "ref", c.config.Name, "config", c.config.Paths)
 
 	err := c.setup()
@@ -48,7 +82,12 @@ func (c *K8sConfig) Destroy() error {
 		return err
 	}
 
-	err = c.client.Delete(c.config.Paths)
+	paths, err := FetchPaths(c.getter, c.config.Paths)
+	if err != nil {
+		return err
+	}
+
+	err = c.client.Delete(paths)
 	if err != nil {
 		c.log.Debug("There was a problem destroying Kuberntes config, logging message but ignoring error", "ref", c.config.Name, "error", err)
 	}
@@ -56,7 +95,7 @@ func (c *K8sConfig) Destroy() error {
 }
 
 // Lookup the Kubernetes resources defined by the config
-func (c *K8sConfig) Lookup() ([]string, error) {
+func (c *K8sConfig) Lookup(ctx context.Context) ([]string, error) {
 	return []string{}, nil
 }
 
@@ -74,3 +113,97 @@ func (c *K8sConfig) setup() error {
 
 	return nil
 }
+
+// healthCheckKubernetes waits for the CustomResourceDefinitions, pods and
+// service endpoints defined in the health check to be ready
+func healthCheckKubernetes(ctx context.Context, kc clients.Kubernetes, hc *config.HealthCheck) error {
+	if hc == nil {
+		return nil
+	}
+
+	to, err := time.ParseDuration(hc.Timeout)
+	if err != nil {
+		return xerrors.Errorf("unable to parse healthcheck duration: %w", err)
+	}
+
+	return Idle(ctx, func() error {
+		if len(hc.CRDs) > 0 {
+			err := kc.WaitForCRDs(ctx, hc.CRDs, to)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(hc.Pods) > 0 {
+			err := kc.HealthCheckPods(ctx, hc.Pods, to)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(hc.Services) > 0 {
+			err := kc.WaitForEndpoints(ctx, hc.Services, to)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// staleObjects returns the objects in previous which do not exist in current,
+// objects are matched on their group, not version, so that moving an object to
+// a new API version does not delete it
+func staleObjects(previous, current []config.K8sObject) []config.K8sObject {
+	stale := []config.K8sObject{}
+
+	for _, p := range previous {
+		found := false
+		for _, c := range current {
+			if sameObject(p, c) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			stale = append(stale, p)
+		}
+	}
+
+	return stale
+}
+
+// sameObject returns true when both objects refer to the same Kubernetes object
+func sameObject(a, b config.K8sObject) bool {
+	return apiGroup(a) == apiGroup(b) &&
+		a.Kind == b.Kind &&
+		a.Namespace == b.Namespace &&
+		a.Name == b.Name
+}
+
+// movedKinds maps kinds which were served from the deprecated extensions group
+// to the group they moved to
+var movedKinds = map[string]string{
+	"Deployment":    "apps",
+	"DaemonSet":     "apps",
+	"ReplicaSet":    "apps",
+	"Ingress":       "networking.k8s.io",
+	"NetworkPolicy": "networking.k8s.io",
+}
+
+// apiGroup returns the group part of the objects apiVersion, core objects have
+// an empty group
+func apiGroup(o config.K8sObject) string {
+	g := ""
+	if i := strings.LastIndex(o.APIVersion, "/"); i >= 0 {
+		g = o.APIVersion[:i]
+	}
+
+	if m, ok := movedKinds[o.Kind]; ok && g == "extensions" {
+		return m
+	}
+
+	return g
+}
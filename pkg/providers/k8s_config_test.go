@@ -1,8 +1,10 @@
 package providers
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	hclog "github.com/hashicorp/go-hclog"
 	clients "github.com/shipyard-run/shipyard/pkg/clients/mocks"
@@ -17,6 +19,11 @@ func setupK8sConfig() (*clients.MockKubernetes, *K8sConfig) {
 	mk.On("SetConfig", mock.Anything).Return(nil)
 	mk.On("Apply", mock.Anything, mock.Anything).Return(nil)
 	mk.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	mk.On("Inventory", mock.Anything).Return(k8sInventory, nil)
+	mk.On("DeleteObjects", mock.Anything).Return(nil)
+	mk.On("HealthCheckPods", mock.Anything, mock.Anything).Return(nil)
+	mk.On("WaitForCRDs", mock.Anything, mock.Anything).Return(nil)
+	mk.On("WaitForEndpoints", mock.Anything, mock.Anything).Return(nil)
 
 	c := config.NewK8sCluster("testcluster")
 	kc := config.NewK8sConfig("config")
@@ -27,7 +34,7 @@ func setupK8sConfig() (*clients.MockKubernetes, *K8sConfig) {
 	cc.AddResource(kc)
 	cc.AddResource(c)
 
-	p := NewK8sConfig(kc, mk, hclog.Default())
+	p := NewK8sConfig(kc, mk, &clients.Getter{}, hclog.Default())
 
 	return mk, p
 }
@@ -35,7 +42,7 @@ func setupK8sConfig() (*clients.MockKubernetes, *K8sConfig) {
 func TestCreatesCorrectly(t *testing.T) {
 	mk, p := setupK8sConfig()
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 
 	_, destPath, _ := utils.CreateKubeConfigPath("testcluster")
@@ -43,12 +50,145 @@ func TestCreatesCorrectly(t *testing.T) {
 	mk.AssertCalled(t, "Apply", p.config.Paths, p.config.WaitUntilReady)
 }
 
+func TestCreateFetchesRemotePaths(t *testing.T) {
+	mk, p := setupK8sConfig()
+	p.config.Paths = []string{"/tmp/something", "https://example.com/app.yaml"}
+
+	mg := &clients.Getter{}
+	mg.On("Fetch", "https://example.com/app.yaml").Return("/tmp/downloads/abc", nil)
+	p.getter = mg
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	mk.AssertCalled(t, "Apply", []string{"/tmp/something", "/tmp/downloads/abc"}, p.config.WaitUntilReady)
+	mk.AssertCalled(t, "Inventory", []string{"/tmp/something", "/tmp/downloads/abc"})
+}
+
+func TestCreateFetchErrorReturnsError(t *testing.T) {
+	mk, p := setupK8sConfig()
+	p.config.Paths = []string{"https://example.com/app.yaml"}
+
+	mg := &clients.Getter{}
+	mg.On("Fetch", mock.Anything).Return("", fmt.Errorf("boom"))
+	p.getter = mg
+
+	err := p.Create(context.Background())
+	assert.Error(t, err)
+
+	mk.AssertNotCalled(t, "Apply", mock.Anything, mock.Anything)
+}
+
+func TestCreateDoesNotHealthCheckWhenNotSet(t *testing.T) {
+	mk, p := setupK8sConfig()
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	mk.AssertNotCalled(t, "WaitForCRDs", mock.Anything, mock.Anything)
+	mk.AssertNotCalled(t, "HealthCheckPods", mock.Anything, mock.Anything)
+	mk.AssertNotCalled(t, "WaitForEndpoints", mock.Anything, mock.Anything)
+}
+
+func TestCreateHealthChecksWhenSet(t *testing.T) {
+	mk, p := setupK8sConfig()
+	p.config.HealthCheck = &config.HealthCheck{
+		Timeout:  "30s",
+		CRDs:     []string{"widgets.example.com"},
+		Pods:     []string{"app=widget"},
+		Services: []string{"widget-webhook"},
+	}
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	mk.AssertCalled(t, "WaitForCRDs", []string{"widgets.example.com"}, 30*time.Second)
+	mk.AssertCalled(t, "HealthCheckPods", []string{"app=widget"}, 30*time.Second)
+	mk.AssertCalled(t, "WaitForEndpoints", []string{"widget-webhook"}, 30*time.Second)
+}
+
+func TestCreateReturnsErrorWhenCRDsNotEstablished(t *testing.T) {
+	mk, p := setupK8sConfig()
+	p.config.HealthCheck = &config.HealthCheck{Timeout: "30s", CRDs: []string{"widgets.example.com"}}
+	removeOn(&mk.Mock, "WaitForCRDs")
+	mk.On("WaitForCRDs", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	err := p.Create(context.Background())
+	assert.Error(t, err)
+}
+
+func TestCreateStoresInventory(t *testing.T) {
+	_, p := setupK8sConfig()
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	assert.Equal(t, k8sInventory, p.config.Inventory)
+}
+
+func TestCreatePrunesObjectsRemovedFromConfig(t *testing.T) {
+	mk, p := setupK8sConfig()
+	removed := config.K8sObject{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "old"}
+	p.config.Inventory = append([]config.K8sObject{removed}, k8sInventory...)
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	mk.AssertCalled(t, "DeleteObjects", []config.K8sObject{removed})
+}
+
+func TestCreateDoesNotPruneWhenNothingRemoved(t *testing.T) {
+	mk, p := setupK8sConfig()
+	p.config.Inventory = k8sInventory
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	mk.AssertNotCalled(t, "DeleteObjects", mock.Anything)
+}
+
+func TestCreateDoesNotPruneObjectsMovedToNewAPIVersion(t *testing.T) {
+	mk, p := setupK8sConfig()
+	p.config.Inventory = []config.K8sObject{
+		config.K8sObject{APIVersion: "extensions/v1beta1", Kind: "Deployment", Namespace: "default", Name: "web"},
+		config.K8sObject{APIVersion: "v1", Kind: "Service", Namespace: "default", Name: "web"},
+	}
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	mk.AssertNotCalled(t, "DeleteObjects", mock.Anything)
+}
+
+func TestStaleObjectsMatchesOnGroupNotVersion(t *testing.T) {
+	previous := []config.K8sObject{
+		config.K8sObject{APIVersion: "autoscaling/v1", Kind: "HorizontalPodAutoscaler", Namespace: "default", Name: "web"},
+		config.K8sObject{APIVersion: "example.com/v1", Kind: "Widget", Namespace: "default", Name: "web"},
+	}
+	current := []config.K8sObject{
+		config.K8sObject{APIVersion: "autoscaling/v2beta2", Kind: "HorizontalPodAutoscaler", Namespace: "default", Name: "web"},
+		config.K8sObject{APIVersion: "other.com/v1", Kind: "Widget", Namespace: "default", Name: "web"},
+	}
+
+	assert.Equal(t, previous[1:], staleObjects(previous, current))
+}
+
+func TestCreatePruneErrorReturnsError(t *testing.T) {
+	mk, p := setupK8sConfig()
+	removeOn(&mk.Mock, "DeleteObjects")
+	mk.On("DeleteObjects", mock.Anything).Return(fmt.Errorf("boom"))
+	p.config.Inventory = []config.K8sObject{config.K8sObject{APIVersion: "v1", Kind: "Service", Name: "old"}}
+
+	err := p.Create(context.Background())
+	assert.Error(t, err)
+}
+
 func TestCreateSetupErrorReturnsError(t *testing.T) {
 	mk, p := setupK8sConfig()
 	removeOn(&mk.Mock, "SetConfig")
 	mk.On("SetConfig", mock.Anything).Return(fmt.Errorf("boom"))
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
@@ -56,14 +196,14 @@ func TestCreateNoClusterErrorReturnsError(t *testing.T) {
 	_, p := setupK8sConfig()
 	p.config.Config.RemoveResource(p.config.Config.Resources[1])
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
 func TestDestroysCorrectly(t *testing.T) {
 	mk, p := setupK8sConfig()
 
-	err := p.Destroy()
+	err := p.Destroy(context.Background())
 	assert.NoError(t, err)
 
 	mk.AssertCalled(t, "Delete", p.config.Paths)
@@ -74,6 +214,11 @@ func TestDestroySetupErrorReturnsError(t *testing.T) {
 	removeOn(&mk.Mock, "SetConfig")
 	mk.On("SetConfig", mock.Anything).Return(fmt.Errorf("boom"))
 
-	err := p.Destroy()
+	err := p.Destroy(context.Background())
 	assert.Error(t, err)
 }
+
+var k8sInventory = []config.K8sObject{
+	config.K8sObject{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "web"},
+	config.K8sObject{APIVersion: "v1", Kind: "Service", Namespace: "default", Name: "web"},
+}
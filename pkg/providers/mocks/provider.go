@@ -1,6 +1,8 @@
 package mocks
 
 import (
+	"context"
+
 	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/stretchr/testify/mock"
 )
@@ -14,21 +16,26 @@ func New(c config.Resource) *MockProvider {
 	return &MockProvider{c: c}
 }
 
-func (m *MockProvider) Create() error {
+func (m *MockProvider) Create(ctx context.Context) error {
 	args := m.Called()
 	return args.Error(0)
 }
 
-func (m *MockProvider) Destroy() error {
+func (m *MockProvider) Destroy(ctx context.Context) error {
 	args := m.Called()
 	return args.Error(0)
 }
 
-func (m *MockProvider) Lookup() ([]string, error) {
+func (m *MockProvider) Lookup(ctx context.Context) ([]string, error) {
 	args := m.Called()
 	return args.Get(0).([]string), args.Error(1)
 }
 
+func (m *MockProvider) ImportLocalDockerImages(ctx context.Context, name string, id string, images []config.Image, force bool) error {
+	args := m.Called(name, id, images, force)
+	return args.Error(0)
+}
+
 func (m *MockProvider) Config() config.Resource {
 	return m.c
 }
@@ -27,15 +27,30 @@ func NewNetwork(co *config.Network, cl clients.Docker, l hclog.Logger) *Network
 }
 
 // Create implements the provider interface method for creating new networks
-func (n *Network) Create() error {
+func (n *Network) Create(ctx context.Context) error {
+	if n.config.External {
+		return n.lookupExternal()
+	}
+
 	n.log.Info("Creating Network", "ref", n.config.Name)
 
-	// validate the subnet
+	// validate the subnets
 	_, cidr, err := net.ParseCIDR(n.config.Subnet)
 	if err != nil {
 		return fmt.Errorf("Unable to create network %s, invalid subnet %s", n.config.Name, n.config.Subnet)
 	}
 
+	subnets := []*net.IPNet{cidr}
+
+	if n.config.SubnetIPv6 != "" {
+		ip, cidr6, err := net.ParseCIDR(n.config.SubnetIPv6)
+		if err != nil || ip.To4() != nil {
+			return fmt.Errorf("Unable to create network %s, invalid IPv6 subnet %s", n.config.Name, n.config.SubnetIPv6)
+		}
+
+		subnets = append(subnets, cidr6)
+	}
+
 	// get all the networks
 	nets, err := n.getNetworks("")
 	if err != nil {
@@ -64,15 +79,26 @@ func (n *Network) Create() error {
 				return err
 			}
 
-			if cidr.Contains(cidr2.IP) || cidr2.Contains(cidr.IP) {
-				return fmt.Errorf("Unable to create network %s, Network %s already exists with an overlapping subnet %s. Either remove the network '%s' or change the subnet for your network", n.config.Name, ne.Name, ci.Subnet, ne.Name)
+			for _, sn := range subnets {
+				if sn.Contains(cidr2.IP) || cidr2.Contains(sn.IP) {
+					return fmt.Errorf("Unable to create network %s, Network %s already exists with an overlapping subnet %s. Either remove the network '%s' or change the subnet for your network", n.config.Name, ne.Name, ci.Subnet, ne.Name)
+				}
 			}
 		}
 	}
 
+	driver := n.config.Driver
+	if driver == "" {
+		driver = "bridge"
+	}
+
+	if driver != "bridge" && driver != "overlay" {
+		return fmt.Errorf("Unable to create network %s, invalid driver %s, valid drivers are bridge and overlay", n.config.Name, driver)
+	}
+
 	opts := types.NetworkCreate{
 		CheckDuplicate: true,
-		Driver:         "bridge",
+		Driver:         driver,
 		IPAM: &network.IPAM{
 			Config: []network.IPAMConfig{
 				network.IPAMConfig{
@@ -83,6 +109,11 @@ func (n *Network) Create() error {
 		Attachable: true,
 	}
 
+	if n.config.SubnetIPv6 != "" {
+		opts.EnableIPv6 = true
+		opts.IPAM.Config = append(opts.IPAM.Config, network.IPAMConfig{Subnet: n.config.SubnetIPv6})
+	}
+
 	_, err = n.client.NetworkCreate(context.Background(), n.config.Name, opts)
 	if err != nil {
 		return err
@@ -95,11 +126,16 @@ func (n *Network) Create() error {
 }
 
 // Destroy implements the provider interface method for destroying networks
-func (n *Network) Destroy() error {
+func (n *Network) Destroy(ctx context.Context) error {
+	if n.config.External {
+		n.log.Info("Network is external, skip removal", "ref", n.config.Name)
+		return nil
+	}
+
 	n.log.Info("Destroy Network", "ref", n.config.Name)
 
 	// check network exists if so remove
-	ids, err := n.Lookup()
+	ids, err := n.Lookup(ctx)
 	if err != nil {
 		return xerrors.Errorf("Unable to list networks: %w", err)
 	}
@@ -112,7 +148,7 @@ func (n *Network) Destroy() error {
 }
 
 // Lookup the ID for a network
-func (n *Network) Lookup() ([]string, error) {
+func (n *Network) Lookup(ctx context.Context) ([]string, error) {
 	nets, err := n.getNetworks(n.config.Name)
 
 	if err != nil {
@@ -127,6 +163,26 @@ func (n *Network) Lookup() ([]string, error) {
 	return ids, nil
 }
 
+// lookupExternal checks that an external network exists
+func (n *Network) lookupExternal() error {
+	n.log.Info("Using external Network", "ref", n.config.Name)
+
+	nets, err := n.getNetworks(n.config.Name)
+	if err != nil {
+		return xerrors.Errorf("Unable to list networks: %w", err)
+	}
+
+	// the Docker name filter matches partial names, ensure the name is exact
+	for _, ne := range nets {
+		if ne.Name == n.config.Name {
+			n.config.Status = config.Applied
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Unable to find external network %s, external networks must be created before they can be used", n.config.Name)
+}
+
 func (n *Network) getNetworks(name string) ([]types.NetworkResource, error) {
 	args := filters.NewArgs()
 	args.Add("name", name)
@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -36,7 +37,7 @@ func TestLookupReturnsID(t *testing.T) {
 			},
 		}}, nil)
 
-	ids, err := p.Lookup()
+	ids, err := p.Lookup(context.Background())
 	assert.NoError(t, err)
 	assert.Equal(t, "testnet", ids[0])
 }
@@ -48,7 +49,7 @@ func TestLookupFailReturnsError(t *testing.T) {
 	removeOn(&md.Mock, "NetworkList")
 	md.On("NetworkList", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom"))
 
-	_, err := p.Lookup()
+	_, err := p.Lookup(context.Background())
 	assert.Error(t, err)
 }
 func TestNetworkCreatesCorrectly(t *testing.T) {
@@ -57,7 +58,7 @@ func TestNetworkCreatesCorrectly(t *testing.T) {
 
 	md, p := setupNetworkTests(c)
 
-	p.Create()
+	p.Create(context.Background())
 
 	md.AssertCalled(t, "NetworkCreate", mock.Anything, mock.Anything, mock.Anything)
 
@@ -71,6 +72,82 @@ func TestNetworkCreatesCorrectly(t *testing.T) {
 	assert.Equal(t, c.Subnet, nco.IPAM.Config[0].Subnet)
 }
 
+func TestNetworkCreatesOverlay(t *testing.T) {
+	c := config.NewNetwork("testnet")
+	c.Subnet = "10.1.2.0/24"
+	c.Driver = "overlay"
+
+	md, p := setupNetworkTests(c)
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	nco := md.Calls[1].Arguments[2].(types.NetworkCreate)
+	assert.True(t, nco.Attachable)
+	assert.Equal(t, "overlay", nco.Driver)
+}
+
+func TestNetworkInvalidDriverReturnsError(t *testing.T) {
+	c := config.NewNetwork("testnet")
+	c.Subnet = "10.1.2.0/24"
+	c.Driver = "macvlan"
+
+	md, p := setupNetworkTests(c)
+
+	err := p.Create(context.Background())
+	assert.Error(t, err)
+	md.AssertNotCalled(t, "NetworkCreate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestNetworkCreatesDualStack(t *testing.T) {
+	c := config.NewNetwork("testnet")
+	c.Subnet = "10.1.2.0/24"
+	c.SubnetIPv6 = "2001:db8:1::/64"
+
+	md, p := setupNetworkTests(c)
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	nco := getCalls(&md.Mock, "NetworkCreate")[0].Arguments[2].(types.NetworkCreate)
+
+	assert.True(t, nco.EnableIPv6)
+	assert.Equal(t, c.Subnet, nco.IPAM.Config[0].Subnet)
+	assert.Equal(t, c.SubnetIPv6, nco.IPAM.Config[1].Subnet)
+}
+
+func TestNetworkWithInvalidIPv6SubnetReturnsError(t *testing.T) {
+	c := config.NewNetwork("testnet")
+	c.Subnet = "10.1.2.0/24"
+	c.SubnetIPv6 = "10.1.3.0/24"
+
+	md, p := setupNetworkTests(c)
+
+	err := p.Create(context.Background())
+	assert.Error(t, err)
+	md.AssertNotCalled(t, "NetworkCreate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestNetworkWithOverlappingIPv6SubnetReturnsError(t *testing.T) {
+	c := config.NewNetwork("testnet")
+	c.Subnet = "10.1.2.0/24"
+	c.SubnetIPv6 = "2001:db8:1::/64"
+
+	md, p := setupNetworkTests(c)
+	removeOn(&md.Mock, "NetworkList")
+	md.On("NetworkList", mock.Anything, mock.Anything).Return([]types.NetworkResource{
+		types.NetworkResource{
+			Name: "other",
+			IPAM: network.IPAM{
+				Config: []network.IPAMConfig{network.IPAMConfig{Subnet: "2001:db8:1::/48"}},
+			},
+		}}, nil)
+
+	err := p.Create(context.Background())
+	assert.Error(t, err)
+	md.AssertNotCalled(t, "NetworkCreate", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestNetworkDoesNOTCreateWhenExists(t *testing.T) {
 	c := config.NewNetwork("testnet")
 	c.Subnet = "10.1.2.0/24"
@@ -85,7 +162,7 @@ func TestNetworkDoesNOTCreateWhenExists(t *testing.T) {
 			},
 		}}, nil)
 
-	p.Create()
+	p.Create(context.Background())
 
 	md.AssertNotCalled(t, "NetworkCreate", mock.Anything, mock.Anything, mock.Anything)
 }
@@ -104,7 +181,7 @@ func TestCreateWithCorrectNameAndDifferentSubnetReturnsError(t *testing.T) {
 			},
 		}}, nil)
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
@@ -122,6 +199,47 @@ func TestCreateWithOverlappingSubnetReturnsError(t *testing.T) {
 			},
 		}}, nil)
 
-	err := p.Create()
+	err := p.Create(context.Background())
+	assert.Error(t, err)
+}
+
+func TestNetworkExternalDoesNOTCreate(t *testing.T) {
+	c := config.NewNetwork("testnet")
+	c.External = true
+
+	md, p := setupNetworkTests(c)
+	removeOn(&md.Mock, "NetworkList")
+	md.On("NetworkList", mock.Anything, mock.Anything).Return([]types.NetworkResource{
+		types.NetworkResource{ID: "abc", Name: "testnet"},
+	}, nil)
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+	md.AssertNotCalled(t, "NetworkCreate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestNetworkExternalNotFoundReturnsError(t *testing.T) {
+	c := config.NewNetwork("testnet")
+	c.External = true
+
+	md, p := setupNetworkTests(c)
+	removeOn(&md.Mock, "NetworkList")
+	md.On("NetworkList", mock.Anything, mock.Anything).Return([]types.NetworkResource{
+		types.NetworkResource{ID: "abc", Name: "testnet2"},
+	}, nil)
+
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
+
+func TestNetworkExternalDoesNOTDestroy(t *testing.T) {
+	c := config.NewNetwork("testnet")
+	c.External = true
+
+	md, p := setupNetworkTests(c)
+	md.On("NetworkRemove", mock.Anything, mock.Anything).Return(nil)
+
+	err := p.Destroy(context.Background())
+	assert.NoError(t, err)
+	md.AssertNotCalled(t, "NetworkRemove", mock.Anything, mock.Anything)
+}
@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"context"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
@@ -15,16 +16,17 @@ import (
 type NomadJob struct {
 	config *config.NomadJob
 	client clients.Nomad
+	getter clients.Getter
 	log    hclog.Logger
 }
 
 // NewNomadJob creates a provider which can create and destroy Nomad jobs
-func NewNomadJob(c *config.NomadJob, hc clients.Nomad, l hclog.Logger) *NomadJob {
-	return &NomadJob{c, hc, l}
+func NewNomadJob(c *config.NomadJob, hc clients.Nomad, g clients.Getter, l hclog.Logger) *NomadJob {
+	return &NomadJob{c, hc, g, l}
 }
 
 // Create the Nomad jobs defined by the config
-func (n *NomadJob) Create() error {
+func (n *NomadJob) Create(ctx context.Context) error {
 	n.log.Info("Create Nomad Job", "ref", n.config.Name, "files", n.config.Paths)
 
 	// find the cluster
@@ -40,7 +42,12 @@ func (n *NomadJob) Create() error {
 		return xerrors.Errorf("Unable to load nomad config %s: %w", configPath, err)
 	}
 
-	err = n.client.Create(n.config.Paths)
+	paths, err := FetchPaths(n.getter, n.config.Paths)
+	if err != nil {
+		return err
+	}
+
+	err = n.client.Create(paths)
 	if err != nil {
 		return xerrors.Errorf("Unable to create Nomad jobs: %w", err)
 	}
@@ -53,31 +60,38 @@ func (n *NomadJob) Create() error {
 			return err
 		}
 
-		for _, j := range n.config.HealthCheck.NomadJobs {
-			for {
-				if time.Now().Sub(st) >= dur {
-					return xerrors.Errorf("Timeout waiting for health checks")
-				}
-
-				n.log.Debug("Checking health for", "ref", n.config.Name, "job", j)
-
-				s, err := n.client.JobStatus(j)
-				if err == nil && s == "running" {
-					n.log.Debug("Health passed for", "ref", n.config.Name, "job", j)
-					break
+		return Idle(ctx, func() error {
+			for _, j := range n.config.HealthCheck.NomadJobs {
+				for {
+					if time.Now().Sub(st) >= dur {
+						return xerrors.Errorf("Timeout waiting for health checks")
+					}
+
+					n.log.Debug("Checking health for", "ref", n.config.Name, "job", j)
+
+					s, err := n.client.JobStatus(j)
+					if err == nil && s == "running" {
+						n.log.Debug("Health passed for", "ref", n.config.Name, "job", j)
+						break
+					}
+
+					select {
+					case <-ctx.Done():
+						return xerrors.Errorf("Health check for job %s cancelled: %w", j, ctx.Err())
+					case <-time.After(1 * time.Second):
+					}
 				}
-
-				time.Sleep(1 * time.Second)
 			}
-		}
 
+			return nil
+		})
 	}
 
 	return nil
 }
 
 // Destroy the Nomad jobs defined by the config
-func (n *NomadJob) Destroy() error {
+func (n *NomadJob) Destroy(ctx context.Context) error {
 	n.log.Info("Destroy Nomad Job", "ref", n.config.Name)
 
 	// find the cluster
@@ -94,7 +108,13 @@ func (n *NomadJob) Destroy() error {
 		return nil
 	}
 
-	err = n.client.Stop(n.config.Paths)
+	paths, err := FetchPaths(n.getter, n.config.Paths)
+	if err != nil {
+		n.log.Error("Unable to fetch Nomad job", "error", err)
+		return nil
+	}
+
+	err = n.client.Stop(paths)
 	if err != nil {
 		n.log.Error("Unable to destroy Nomad job", "config", configPath, "error", err)
 		return nil
@@ -104,7 +124,7 @@ func (n *NomadJob) Destroy() error {
 }
 
 // Lookup the Nomad jobs defined by the config
-func (n *NomadJob) Lookup() ([]string, error) {
+func (n *NomadJob) Lookup(ctx context.Context) ([]string, error) {
 	return nil, nil
 }
 
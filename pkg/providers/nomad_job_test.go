@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -39,9 +40,9 @@ func TestNomadJobWithNonExistentClusterReturnsError(t *testing.T) {
 	jc, mh := setupNomadJobMocks()
 	jc.Config.Resources = jc.Config.Resources[1:]
 
-	p := NewNomadJob(jc, mh, hclog.NewNullLogger())
+	p := NewNomadJob(jc, mh, &mocks.Getter{}, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
@@ -52,9 +53,9 @@ func TestNomadJobUnableToLoadConfigReturnsError(t *testing.T) {
 	removeOn(&mh.Mock, "SetConfig")
 	mh.On("SetConfig", mock.Anything).Return(fmt.Errorf("boom"))
 
-	p := NewNomadJob(jc, mh, hclog.NewNullLogger())
+	p := NewNomadJob(jc, mh, &mocks.Getter{}, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 func TestNomadJobCreateReturnsError(t *testing.T) {
@@ -62,17 +63,32 @@ func TestNomadJobCreateReturnsError(t *testing.T) {
 	removeOn(&mh.Mock, "Create")
 	mh.On("Create", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
 
-	p := NewNomadJob(jc, mh, hclog.NewNullLogger())
+	p := NewNomadJob(jc, mh, &mocks.Getter{}, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.Error(t, err)
 }
 
 func TestNomadJobValidatesConfig(t *testing.T) {
 	jc, mh := setupNomadJobMocks()
 
-	p := NewNomadJob(jc, mh, hclog.NewNullLogger())
+	p := NewNomadJob(jc, mh, &mocks.Getter{}, hclog.NewNullLogger())
 
-	err := p.Create()
+	err := p.Create(context.Background())
 	assert.NoError(t, err)
 }
+
+func TestNomadJobFetchesRemotePaths(t *testing.T) {
+	jc, mh := setupNomadJobMocks()
+	jc.Paths = []string{"github.com/shipyard-run/blueprints//jobs/example.nomad"}
+
+	mg := &mocks.Getter{}
+	mg.On("Fetch", mock.Anything).Return("/tmp/downloads/abc", nil)
+
+	p := NewNomadJob(jc, mh, mg, hclog.NewNullLogger())
+
+	err := p.Create(context.Background())
+	assert.NoError(t, err)
+
+	mh.AssertCalled(t, "Create", []string{"/tmp/downloads/abc"})
+}
@@ -1,10 +1,24 @@
 package providers
 
-// Provider defines an interface to be implemented by providers
+import (
+	"context"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+// Provider defines an interface to be implemented by providers, cancelling
+// the context stops any running operations
 type Provider interface {
-	Create() error
-	Destroy() error
-	Lookup() ([]string, error)
+	Create(ctx context.Context) error
+	Destroy(ctx context.Context) error
+	Lookup(ctx context.Context) ([]string, error)
+}
+
+// ImageImporter is implemented by providers which can import local
+// Docker images into the resources they create
+type ImageImporter interface {
+	Provider
+	ImportLocalDockerImages(ctx context.Context, name string, id string, images []config.Image, force bool) error
 }
 
 // ConfigWrapper alows the provider config to be deserialized to a type
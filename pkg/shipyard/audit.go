@@ -0,0 +1,303 @@
+package shipyard
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// AuditApply is recorded when resources are created with Apply
+const AuditApply = "apply"
+
+// AuditDestroy is recorded when resources are destroyed with Destroy
+const AuditDestroy = "destroy"
+
+// AuditTaint is recorded when a resource is marked to be recreated with Taint
+const AuditTaint = "taint"
+
+// AuditImport is recorded when an environment is created with Import
+const AuditImport = "import"
+
+// AuditUpgrade is recorded when an environment is changed to a new blueprint
+// revision with Upgrade
+const AuditUpgrade = "upgrade"
+
+// auditFileName is the name of the audit log which is stored in the same
+// folder as the state
+const auditFileName = "audit.log"
+
+// AuditEntry records a single operation which changed the environment
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	User      string    `json:"user"`
+	Host      string    `json:"host"`
+	Source    string    `json:"source,omitempty"` // blueprint file, folder, or archive the operation was run with
+	Commit    string    `json:"commit,omitempty"` // git commit of the source when it is in a git repository
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty"`
+	Duration  float64   `json:"duration_seconds"`
+	Resources []string  `json:"resources,omitempty"` // resources changed by the operation in the form [type].[name]
+}
+
+// AuditQuery filters the entries returned by AuditLog, empty fields match
+// every entry
+type AuditQuery struct {
+	Operation string
+	User      string
+	Resource  string    // only return operations which changed the resource [type].[name]
+	Since     time.Time // only return operations after this time
+	Limit     int       // maximum number of entries to return, the most recent entries are returned
+}
+
+// Taint marks the resource in the state to be recreated by the next Apply,
+// resource is the name of the resource in the form [type].[name]
+func (e *EngineImpl) Taint(resource string) (err error) {
+	err = e.lockState("")
+	if err != nil {
+		return err
+	}
+	defer e.unlockState(&err)
+
+	return e.runTaint(resource)
+}
+
+// runTaint taints the resource and records the operation, the caller must
+// hold the lock on the state
+func (e *EngineImpl) runTaint(resource string) error {
+	started := time.Now()
+
+	err := e.taint(resource)
+	e.audit(AuditTaint, "", started, err, resource)
+
+	return err
+}
+
+func (e *EngineImpl) taint(resource string) error {
+	sc := config.New()
+	err := sc.FromJSON(e.stateFile())
+	if err != nil {
+		return xerrors.Errorf("Unable to load state: %w", err)
+	}
+
+	r, err := sc.FindResource(resource)
+	if err != nil {
+		return xerrors.Errorf("Unable to locate resource %s in the state: %w", resource, err)
+	}
+
+	r.Info().Status = config.PendingModification
+
+	err = sc.ToJSON(e.stateFile())
+	if err != nil {
+		return xerrors.Errorf("Unable to save state: %w", err)
+	}
+
+	return nil
+}
+
+// AuditLog returns the operations recorded in the audit log which match the
+// query, entries are returned oldest first
+func (e *EngineImpl) AuditLog(q AuditQuery) ([]AuditEntry, error) {
+	entries := []AuditEntry{}
+
+	f, err := os.Open(e.auditFile())
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for s.Scan() {
+		ae := AuditEntry{}
+		err := json.Unmarshal(s.Bytes(), &ae)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to read audit log: %w", err)
+		}
+
+		if q.matches(ae) {
+			entries = append(entries, ae)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, xerrors.Errorf("Unable to read audit log: %w", err)
+	}
+
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[len(entries)-q.Limit:]
+	}
+
+	return entries, nil
+}
+
+func (q AuditQuery) matches(ae AuditEntry) bool {
+	if q.Operation != "" && q.Operation != ae.Operation {
+		return false
+	}
+
+	if q.User != "" && q.User != ae.User {
+		return false
+	}
+
+	if !q.Since.IsZero() && ae.Time.Before(q.Since) {
+		return false
+	}
+
+	if q.Resource != "" {
+		for _, r := range ae.Resources {
+			if r == q.Resource {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// auditFile returns the location of the audit log for the engine
+func (e *EngineImpl) auditFile() string {
+	return filepath.Join(filepath.Dir(e.stateFile()), auditFileName)
+}
+
+// audit appends the operation to the audit log, the resources changed by
+// the operation are taken from the result when no resources are given.
+// Failing to write the audit log does not fail the operation
+func (e *EngineImpl) audit(op, source string, started time.Time, err error, resources ...string) {
+	ae := AuditEntry{
+		Time:      started.UTC(),
+		Operation: op,
+		User:      auditUser(),
+		Source:    source,
+		Success:   err == nil,
+		Duration:  time.Since(started).Seconds(),
+		Resources: resources,
+	}
+
+	ae.Host, _ = os.Hostname()
+
+	if err != nil {
+		ae.Error = err.Error()
+	}
+
+	if source != "" {
+		if abs, err := filepath.Abs(source); err == nil {
+			ae.Source = abs
+		}
+
+		ae.Commit = gitCommit(ae.Source)
+	}
+
+	// only use the result when it was created by this operation
+	if result := e.Result(); len(resources) == 0 && result != nil && !result.Started.Before(started) {
+		for _, r := range result.Resources {
+			ae.Resources = append(ae.Resources, fmt.Sprintf("%s.%s", r.Type, r.Name))
+		}
+	}
+
+	d, _ := json.Marshal(ae)
+
+	os.MkdirAll(filepath.Dir(e.auditFile()), os.ModePerm)
+
+	f, ferr := os.OpenFile(e.auditFile(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if ferr != nil {
+		e.log.Warn("Unable to write audit log", "error", ferr)
+		return
+	}
+	defer f.Close()
+
+	_, ferr = f.Write(append(d, '\n'))
+	if ferr != nil {
+		e.log.Warn("Unable to write audit log", "error", ferr)
+	}
+}
+
+// auditUser returns the name of the user running the operation, the
+// SHIPYARD_USER environment variable can be used to set the name when
+// a shared account is used
+func auditUser() string {
+	if u := os.Getenv("SHIPYARD_USER"); u != "" {
+		return u
+	}
+
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+
+	return os.Getenv("USER")
+}
+
+// gitCommit returns the commit checked out in the git repository containing
+// path, an empty string is returned when path is not in a git repository
+func gitCommit(path string) string {
+	dir := path
+	if fi, err := os.Stat(dir); err == nil && !fi.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+
+	for {
+		gd := filepath.Join(dir, ".git")
+		if fi, err := os.Stat(gd); err == nil && fi.IsDir() {
+			return readGitHead(gd)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+
+		dir = parent
+	}
+}
+
+// readGitHead resolves HEAD in the git folder to a commit
+func readGitHead(gd string) string {
+	d, err := ioutil.ReadFile(filepath.Join(gd, "HEAD"))
+	if err != nil {
+		return ""
+	}
+
+	head := strings.TrimSpace(string(d))
+	if !strings.HasPrefix(head, "ref: ") {
+		// detached head contains the commit
+		return head
+	}
+
+	ref := strings.TrimPrefix(head, "ref: ")
+
+	d, err = ioutil.ReadFile(filepath.Join(gd, filepath.FromSlash(ref)))
+	if err == nil {
+		return strings.TrimSpace(string(d))
+	}
+
+	// refs may have been packed
+	d, err = ioutil.ReadFile(filepath.Join(gd, "packed-refs"))
+	if err != nil {
+		return ""
+	}
+
+	for _, l := range strings.Split(string(d), "\n") {
+		parts := strings.Fields(l)
+		if len(parts) == 2 && parts[1] == ref {
+			return parts[0]
+		}
+	}
+
+	return ""
+}
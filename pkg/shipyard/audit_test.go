@@ -0,0 +1,149 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupAuditTests(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	os.MkdirAll(filepath.Join(dir, ".git", "refs", "heads"), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref: refs/heads/main\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, ".git", "refs", "heads", "main"), []byte("2c1f4d2\n"), 0644)
+
+	os.MkdirAll(filepath.Join(dir, "consul"), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(dir, "consul", "container.hcl"), []byte(smokeTestContainer), 0644)
+
+	os.Setenv("SHIPYARD_USER", "nic")
+
+	return dir
+}
+
+func TestApplyWritesAuditLog(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir := setupAuditTests(t)
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv("SHIPYARD_USER")
+
+	_, err := e.Apply(context.Background(), filepath.Join(dir, "consul"))
+	assert.NoError(t, err)
+
+	ae, err := e.AuditLog(AuditQuery{})
+	assert.NoError(t, err)
+
+	assert.Len(t, ae, 1)
+	assert.Equal(t, AuditApply, ae[0].Operation)
+	assert.Equal(t, "nic", ae[0].User)
+	assert.Equal(t, filepath.Join(dir, "consul"), ae[0].Source)
+	assert.Equal(t, "2c1f4d2", ae[0].Commit)
+	assert.True(t, ae[0].Success)
+	assert.Equal(t, []string{"container.consul"}, ae[0].Resources)
+}
+
+func TestDestroyWritesFailedOperationToAuditLog(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(map[string]error{"dc1": fmt.Errorf("boom")}, mergedState)
+	defer cleanup()
+
+	err := e.Destroy(context.Background(), "", true)
+	assert.Error(t, err)
+
+	ae, err := e.AuditLog(AuditQuery{Operation: AuditDestroy})
+	assert.NoError(t, err)
+
+	assert.Len(t, ae, 1)
+	assert.False(t, ae[0].Success)
+	assert.Contains(t, ae[0].Error, "boom")
+}
+
+func TestTaintMarksResourceAndWritesAuditLog(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, demoState)
+	defer cleanup()
+
+	err := e.Taint("container.consul")
+	assert.NoError(t, err)
+
+	sc := config.New()
+	sc.FromJSON(utils.StatePath())
+	r, _ := sc.FindResource("container.consul")
+	assert.Equal(t, config.PendingModification, r.Info().Status)
+
+	ae, err := e.AuditLog(AuditQuery{Resource: "container.consul"})
+	assert.NoError(t, err)
+
+	assert.Len(t, ae, 1)
+	assert.Equal(t, AuditTaint, ae[0].Operation)
+}
+
+func TestTaintReturnsErrorWhenResourceNotFound(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, demoState)
+	defer cleanup()
+
+	err := e.Taint("container.missing")
+	assert.Error(t, err)
+
+	ae, _ := e.AuditLog(AuditQuery{})
+	assert.Len(t, ae, 1)
+	assert.False(t, ae[0].Success)
+}
+
+func TestAuditLogFiltersEntries(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	os.MkdirAll(utils.StateDir(), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(utils.StateDir(), "audit.log"), []byte(auditLog), 0644)
+
+	ae, err := e.AuditLog(AuditQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, ae, 4)
+
+	ae, err = e.AuditLog(AuditQuery{User: "nic"})
+	assert.NoError(t, err)
+	assert.Len(t, ae, 3)
+
+	ae, err = e.AuditLog(AuditQuery{Operation: AuditApply, User: "nic"})
+	assert.NoError(t, err)
+	assert.Len(t, ae, 2)
+
+	ae, err = e.AuditLog(AuditQuery{Resource: "container.vault"})
+	assert.NoError(t, err)
+	assert.Len(t, ae, 1)
+	assert.Equal(t, "erik", ae[0].User)
+
+	ae, err = e.AuditLog(AuditQuery{Since: time.Date(2020, 8, 2, 0, 0, 0, 0, time.UTC)})
+	assert.NoError(t, err)
+	assert.Len(t, ae, 2)
+
+	ae, err = e.AuditLog(AuditQuery{Limit: 1})
+	assert.NoError(t, err)
+	assert.Len(t, ae, 1)
+	assert.Equal(t, AuditDestroy, ae[0].Operation)
+}
+
+func TestAuditLogReturnsEmptyWhenNoLog(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	ae, err := e.AuditLog(AuditQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, ae, 0)
+}
+
+var auditLog = `{"time":"2020-08-01T10:00:00Z","operation":"apply","user":"nic","host":"demo","success":true,"duration_seconds":10,"resources":["container.consul"]}
+{"time":"2020-08-01T11:00:00Z","operation":"apply","user":"erik","host":"demo","success":true,"duration_seconds":10,"resources":["container.vault"]}
+{"time":"2020-08-02T10:00:00Z","operation":"apply","user":"nic","host":"demo","success":false,"error":"boom","duration_seconds":10}
+{"time":"2020-08-03T10:00:00Z","operation":"destroy","user":"nic","host":"demo","success":true,"duration_seconds":10,"resources":["container.consul"]}
+`
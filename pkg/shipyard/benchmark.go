@@ -0,0 +1,375 @@
+package shipyard
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// benchmarkFileName is the name of the file containing the timings for each
+// apply, it is stored in the same folder as the state
+const benchmarkFileName = "benchmark.log"
+
+// BenchmarkRun records the timings for a single Apply
+type BenchmarkRun struct {
+	Time      time.Time        `json:"time"`
+	Source    string           `json:"source,omitempty"`
+	Success   bool             `json:"success"`
+	Duration  float64          `json:"duration_seconds"`
+	ImagePull float64          `json:"image_pull_seconds"` // time spent pulling images before any resource was created
+	Images    []ImageTiming    `json:"images"`
+	Resources []ResourceTiming `json:"resources"` // resources created by the apply, failed resources are not recorded
+
+	started     time.Time
+	walkStarted time.Time
+	m           sync.Mutex
+}
+
+// ImageTiming is the time taken to pull a single image
+type ImageTiming struct {
+	Name     string  `json:"name"`
+	Duration float64 `json:"duration_seconds"`
+}
+
+// ResourceTiming is the time taken to create a single resource
+type ResourceTiming struct {
+	Name      string   `json:"name"` // [type].[name]
+	DependsOn []string `json:"depends_on,omitempty"`
+	Wait      float64  `json:"wait_seconds"` // time waiting for dependencies before the resource was created
+	Duration  float64  `json:"duration_seconds"`
+}
+
+// BenchmarkReport summarises the timings of the most recent successful
+// applies so blueprint authors can see which resources slow down startup
+type BenchmarkReport struct {
+	Runs                 int                 `json:"runs"`
+	Duration             float64             `json:"mean_duration_seconds"`
+	ImagePull            float64             `json:"mean_image_pull_seconds"`
+	Slowest              []ResourceBenchmark `json:"slowest"` // sorted by mean duration, slowest first
+	Images               []ImageBenchmark    `json:"images"`  // sorted by mean duration, slowest first
+	CriticalPath         []string            `json:"critical_path"`
+	CriticalPathDuration float64             `json:"critical_path_seconds"`
+}
+
+// ResourceBenchmark is the timing of a resource across multiple runs
+type ResourceBenchmark struct {
+	Name     string  `json:"name"`
+	Runs     int     `json:"runs"`
+	Mean     float64 `json:"mean_seconds"`
+	Max      float64 `json:"max_seconds"`
+	MeanWait float64 `json:"mean_wait_seconds"`
+}
+
+// ImageBenchmark is the pull time of an image across multiple runs, cached
+// images are included so the mean shows the benefit of the cache
+type ImageBenchmark struct {
+	Name string  `json:"name"`
+	Runs int     `json:"runs"`
+	Mean float64 `json:"mean_seconds"`
+	Max  float64 `json:"max_seconds"`
+}
+
+func newBenchmarkRun(source string) *BenchmarkRun {
+	return &BenchmarkRun{
+		Source:    source,
+		Images:    []ImageTiming{},
+		Resources: []ResourceTiming{},
+		started:   time.Now(),
+	}
+}
+
+// addImage records the time taken to pull an image, it is safe to call
+// from multiple goroutines
+func (b *BenchmarkRun) addImage(name string, started time.Time) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	b.Images = append(b.Images, ImageTiming{Name: name, Duration: time.Since(started).Seconds()})
+}
+
+// walkStart records the time the images have been pulled and resources
+// start being created
+func (b *BenchmarkRun) walkStart() {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	b.walkStarted = time.Now()
+	b.ImagePull = b.walkStarted.Sub(b.started).Seconds()
+}
+
+// addResource records the time taken to create a resource, started is the
+// time the provider was called
+func (b *BenchmarkRun) addResource(r config.Resource, started time.Time) {
+	rt := ResourceTiming{
+		Name:     resourceName(r),
+		Duration: time.Since(started).Seconds(),
+	}
+
+	for _, d := range r.Info().DependsOn {
+		if r.Info().Config == nil {
+			break
+		}
+
+		// dependencies can be referenced in multiple formats, use the
+		// resolved resource name so it matches the timings
+		if dr, err := r.Info().FindDependentResource(d); err == nil {
+			rt.DependsOn = append(rt.DependsOn, resourceName(dr))
+		}
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if !b.walkStarted.IsZero() {
+		rt.Wait = started.Sub(b.walkStarted).Seconds()
+	}
+
+	b.Resources = append(b.Resources, rt)
+}
+
+// BenchmarkReport returns the slowest resources, image pull times and the
+// critical path through the dependency graph using the timings from the
+// last runs successful applies. When runs is 0 all recorded applies are used
+func (e *EngineImpl) BenchmarkReport(runs int) (*BenchmarkReport, error) {
+	br, err := e.benchmarkRuns()
+	if err != nil {
+		return nil, err
+	}
+
+	if runs > 0 && len(br) > runs {
+		br = br[len(br)-runs:]
+	}
+
+	rep := &BenchmarkReport{
+		Runs:         len(br),
+		Slowest:      []ResourceBenchmark{},
+		Images:       []ImageBenchmark{},
+		CriticalPath: []string{},
+	}
+
+	if len(br) == 0 {
+		return rep, nil
+	}
+
+	resources := map[string]*ResourceBenchmark{}
+	images := map[string]*ImageBenchmark{}
+
+	for _, r := range br {
+		rep.Duration += r.Duration / float64(len(br))
+		rep.ImagePull += r.ImagePull / float64(len(br))
+
+		for _, rt := range r.Resources {
+			rb, ok := resources[rt.Name]
+			if !ok {
+				rb = &ResourceBenchmark{Name: rt.Name}
+				resources[rt.Name] = rb
+			}
+
+			rb.Runs++
+			rb.Mean += rt.Duration
+			rb.MeanWait += rt.Wait
+
+			if rt.Duration > rb.Max {
+				rb.Max = rt.Duration
+			}
+		}
+
+		for _, it := range r.Images {
+			ib, ok := images[it.Name]
+			if !ok {
+				ib = &ImageBenchmark{Name: it.Name}
+				images[it.Name] = ib
+			}
+
+			ib.Runs++
+			ib.Mean += it.Duration
+
+			if it.Duration > ib.Max {
+				ib.Max = it.Duration
+			}
+		}
+	}
+
+	for _, rb := range resources {
+		rb.Mean = rb.Mean / float64(rb.Runs)
+		rb.MeanWait = rb.MeanWait / float64(rb.Runs)
+		rep.Slowest = append(rep.Slowest, *rb)
+	}
+
+	for _, ib := range images {
+		ib.Mean = ib.Mean / float64(ib.Runs)
+		rep.Images = append(rep.Images, *ib)
+	}
+
+	sort.Slice(rep.Slowest, func(i, j int) bool {
+		if rep.Slowest[i].Mean == rep.Slowest[j].Mean {
+			return rep.Slowest[i].Name < rep.Slowest[j].Name
+		}
+
+		return rep.Slowest[i].Mean > rep.Slowest[j].Mean
+	})
+
+	sort.Slice(rep.Images, func(i, j int) bool {
+		if rep.Images[i].Mean == rep.Images[j].Mean {
+			return rep.Images[i].Name < rep.Images[j].Name
+		}
+
+		return rep.Images[i].Mean > rep.Images[j].Mean
+	})
+
+	rep.CriticalPath, rep.CriticalPathDuration = criticalPath(br[len(br)-1].Resources, resources)
+
+	return rep, nil
+}
+
+// criticalPath returns the chain of dependent resources with the longest
+// total mean duration, the dependencies are taken from the timings of the
+// most recent run. The path is returned in the order resources are created
+func criticalPath(timings []ResourceTiming, means map[string]*ResourceBenchmark) ([]string, float64) {
+	deps := map[string][]string{}
+	names := []string{}
+
+	for _, rt := range timings {
+		deps[rt.Name] = rt.DependsOn
+		names = append(names, rt.Name)
+	}
+
+	sort.Strings(names)
+
+	// longest path ending at each resource and the previous resource in it
+	longest := map[string]float64{}
+	prev := map[string]string{}
+
+	var walk func(n string) float64
+	walk = func(n string) float64 {
+		if l, ok := longest[n]; ok {
+			return l
+		}
+
+		// stops cycles, the graph has already been validated by the engine
+		longest[n] = 0
+
+		max := 0.0
+		for _, d := range deps[n] {
+			// dependencies which were not created by the run did not
+			// delay it
+			if _, ok := deps[d]; !ok {
+				continue
+			}
+
+			if l := walk(d); l > max || prev[n] == "" {
+				max = l
+				prev[n] = d
+			}
+		}
+
+		longest[n] = max + means[n].Mean
+
+		return longest[n]
+	}
+
+	end := ""
+	for _, n := range names {
+		if walk(n) > longest[end] || end == "" {
+			end = n
+		}
+	}
+
+	if end == "" {
+		return []string{}, 0
+	}
+
+	path := []string{}
+	for n := end; n != ""; n = prev[n] {
+		path = append([]string{n}, path...)
+	}
+
+	return path, longest[end]
+}
+
+// benchmarkFile returns the location of the benchmark log for the engine
+func (e *EngineImpl) benchmarkFile() string {
+	return filepath.Join(filepath.Dir(e.stateFile()), benchmarkFileName)
+}
+
+// benchmarkRuns reads the successful runs from the benchmark log oldest first
+func (e *EngineImpl) benchmarkRuns() ([]*BenchmarkRun, error) {
+	runs := []*BenchmarkRun{}
+
+	f, err := os.Open(e.benchmarkFile())
+	if os.IsNotExist(err) {
+		return runs, nil
+	}
+
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to open benchmark log: %w", err)
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for s.Scan() {
+		br := &BenchmarkRun{}
+		err := json.Unmarshal(s.Bytes(), br)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to read benchmark log: %w", err)
+		}
+
+		// failed runs stop early and would skew the timings
+		if br.Success {
+			runs = append(runs, br)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, xerrors.Errorf("Unable to read benchmark log: %w", err)
+	}
+
+	return runs, nil
+}
+
+// recordBenchmark appends the timings for the current apply to the benchmark
+// log, failing to write the log does not fail the apply
+func (e *EngineImpl) recordBenchmark(err error) {
+	b := e.benchmark
+	e.benchmark = nil
+
+	if b == nil {
+		return
+	}
+
+	b.m.Lock()
+	b.Time = b.started.UTC()
+	b.Success = err == nil
+	b.Duration = time.Since(b.started).Seconds()
+	d, _ := json.Marshal(b)
+	b.m.Unlock()
+
+	os.MkdirAll(filepath.Dir(e.benchmarkFile()), os.ModePerm)
+
+	f, ferr := os.OpenFile(e.benchmarkFile(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if ferr != nil {
+		e.log.Warn("Unable to write benchmark log", "error", ferr)
+		return
+	}
+	defer f.Close()
+
+	_, ferr = f.Write(append(d, '\n'))
+	if ferr != nil {
+		e.log.Warn("Unable to write benchmark log", "error", ferr)
+	}
+}
+
+// resourceName returns the name of the resource in the form [type].[name]
+func resourceName(r config.Resource) string {
+	return fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name)
+}
@@ -0,0 +1,83 @@
+package shipyard
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyWritesBenchmarkLog(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	_, err := e.Apply(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster")
+	assert.NoError(t, err)
+
+	br, err := e.BenchmarkReport(0)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, br.Runs)
+	assert.Len(t, br.Slowest, e.ResourceCount())
+	assert.NotEmpty(t, br.CriticalPath)
+}
+
+func TestBenchmarkReportReturnsSlowestResourcesAndCriticalPath(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	os.MkdirAll(utils.StateDir(), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(utils.StateDir(), "benchmark.log"), []byte(benchmarkLog), 0644)
+
+	br, err := e.BenchmarkReport(0)
+	assert.NoError(t, err)
+
+	// failed runs are ignored
+	assert.Equal(t, 2, br.Runs)
+	assert.Equal(t, 75.0, br.Duration)
+
+	assert.Equal(t, "container.consul", br.Slowest[0].Name)
+	assert.Equal(t, 40.0, br.Slowest[0].Mean)
+	assert.Equal(t, "k8s_cluster.k3s", br.Slowest[1].Name)
+	assert.Equal(t, 50.0, br.Slowest[1].Max)
+	assert.Equal(t, 2.0, br.Slowest[1].MeanWait)
+
+	assert.Equal(t, "consul:1.8.1", br.Images[0].Name)
+	assert.Equal(t, 6.0, br.Images[0].Mean)
+
+	assert.Equal(t, []string{"network.cloud", "k8s_cluster.k3s", "helm.vault"}, br.CriticalPath)
+	assert.Equal(t, 62.0, br.CriticalPathDuration)
+}
+
+func TestBenchmarkReportLimitsRuns(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	os.MkdirAll(utils.StateDir(), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(utils.StateDir(), "benchmark.log"), []byte(benchmarkLog), 0644)
+
+	br, err := e.BenchmarkReport(1)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, br.Runs)
+	assert.Equal(t, "k8s_cluster.k3s", br.Slowest[0].Name)
+}
+
+func TestBenchmarkReportReturnsEmptyWhenNoLog(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	br, err := e.BenchmarkReport(0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, br.Runs)
+	assert.Len(t, br.CriticalPath, 0)
+}
+
+var benchmarkLog = `{"time":"2020-08-01T10:00:00Z","success":true,"duration_seconds":70,"image_pull_seconds":10,"images":[{"name":"consul:1.8.1","duration_seconds":10}],"resources":[{"name":"network.cloud","wait_seconds":0,"duration_seconds":1},{"name":"k8s_cluster.k3s","depends_on":["network.cloud"],"wait_seconds":1,"duration_seconds":30},{"name":"container.consul","depends_on":["network.cloud"],"wait_seconds":1,"duration_seconds":40},{"name":"helm.vault","depends_on":["k8s_cluster.k3s"],"wait_seconds":31,"duration_seconds":20}]}
+{"time":"2020-08-02T10:00:00Z","success":true,"duration_seconds":80,"image_pull_seconds":2,"images":[{"name":"consul:1.8.1","duration_seconds":2}],"resources":[{"name":"network.cloud","wait_seconds":0,"duration_seconds":3},{"name":"k8s_cluster.k3s","depends_on":["network.cloud"],"wait_seconds":3,"duration_seconds":50},{"name":"container.consul","depends_on":["network.cloud"],"wait_seconds":3,"duration_seconds":40},{"name":"helm.vault","depends_on":["k8s_cluster.k3s"],"wait_seconds":53,"duration_seconds":20}]}
+{"time":"2020-08-03T10:00:00Z","success":false,"duration_seconds":500,"image_pull_seconds":0,"images":[],"resources":[{"name":"container.consul","wait_seconds":0,"duration_seconds":500}]}
+`
@@ -0,0 +1,251 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// browserCheckTimeout is the maximum time to wait for a URL to respond before
+// it is opened in the browser
+var browserCheckTimeout = 30 * time.Second
+
+// ApplyOptions control the optional behaviour of ApplyWithOptions
+type ApplyOptions struct {
+	// DisableBrowser prevents any browser windows being opened after the
+	// resources have been created
+	DisableBrowser bool
+
+	// Checkpoint creates an image from each container once the resources
+	// have been created, the next apply of the identical blueprint creates
+	// the containers from the checkpoint
+	Checkpoint bool
+
+	// DryRun returns the resources which would be created without calling
+	// any providers or changing the state, Replace is reported in the
+	// result and the other options are ignored
+	DryRun bool
+
+	// MaxParallel limits the number of resources created and images pulled
+	// at the same time, 0 creates every resource as soon as its
+	// dependencies are ready
+	MaxParallel int
+
+	// Rollback destroys the resources created by the apply when any
+	// resource fails and restores the previous state, resources which were
+	// already running are not changed
+	Rollback bool
+
+	// Replace lists resources in the form [type].[name] which are destroyed
+	// and created again even when their config has not changed, e.g. a
+	// container which has got into a bad state
+	Replace []string
+
+	// Timeout is the maximum time for the apply, when it elapses resources
+	// which are being created and image pulls are cancelled. 0 does not
+	// limit the time, resources can also set their own timeout
+	Timeout time.Duration
+
+	// Offline stops the apply accessing the network, images, Helm charts,
+	// modules, and remote files must already be in the local cache e.g.
+	// loaded from a bundle with LoadBundle
+	Offline bool
+}
+
+// ApplyWithOptions creates the resources in the same way as Apply, once all
+// the resources have been successfully created the URLs declared by the
+// blueprint and the created resources are opened in the browser.
+// Blueprint browser windows are only opened the first time a blueprint is
+// applied, resource windows are only opened when the resource is created.
+func (e *EngineImpl) ApplyWithOptions(ctx context.Context, path string, o ApplyOptions) (res []config.Resource, err error) {
+	// nothing is downloaded in offline mode, including for a dry run
+	if o.Offline {
+		e.setOffline(true)
+		defer e.setOffline(false)
+
+		ctx = clients.WithOffline(ctx)
+	}
+
+	if o.DryRun {
+		return e.dryRun(path, o.Replace)
+	}
+
+	// hold the lock for the replace, apply, and rollback
+	err = e.lockState(path)
+	if err != nil {
+		return nil, err
+	}
+	defer e.unlockState(&err)
+
+	sc := config.New()
+	sc.FromJSON(e.stateFile())
+	blueprintExists := sc.Blueprint != nil
+
+	// replaced resources are tainted so the apply recreates them
+	for _, r := range o.Replace {
+		err := e.runTaint(r)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to replace resource: %w", err)
+		}
+	}
+
+	e.setMaxParallel(o.MaxParallel)
+	defer e.setMaxParallel(0)
+
+	if o.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
+	}
+
+	res, err = e.runApply(ctx, path)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		err = xerrors.Errorf("Apply did not complete within %s: %w", o.Timeout, err)
+	}
+	// nothing needs to be rolled back when the apply failed before any
+	// resources were created
+	if result := e.Result(); err != nil && o.Rollback && result != nil && len(result.Resources) > 0 {
+		rerr := e.rollback(sc)
+		if rerr != nil {
+			return res, xerrors.Errorf("Unable to roll back failed apply, %s: %w", rerr, err)
+		}
+
+		return nil, xerrors.Errorf("Apply failed, created resources have been rolled back: %w", err)
+	}
+
+	if err != nil {
+		return res, err
+	}
+
+	// the environment is running, a failed checkpoint only slows the next apply
+	if o.Checkpoint {
+		_, cerr := e.checkpoint(ctx)
+		if cerr != nil {
+			e.log.Warn("Unable to create checkpoint", "error", cerr)
+		}
+	}
+
+	if o.DisableBrowser {
+		return res, nil
+	}
+
+	urls := []string{}
+	if !blueprintExists && e.config.Blueprint != nil {
+		urls = append(urls, e.config.Blueprint.BrowserWindows...)
+	}
+
+	for _, r := range res {
+		urls = append(urls, browserURLs(r)...)
+	}
+
+	e.openBrowserWindows(ctx, urls)
+
+	return res, nil
+}
+
+// openBrowserWindows opens each URL in the browser once it responds to
+// HTTP requests, URLs which do not respond are not opened
+func (e *EngineImpl) openBrowserWindows(ctx context.Context, urls []string) {
+	wg := sync.WaitGroup{}
+
+	for _, u := range urls {
+		wg.Add(1)
+
+		go func(uri string) {
+			defer wg.Done()
+
+			err := e.clients.HTTP.HealthCheckHTTP(ctx, uri, browserCheckTimeout)
+			if err != nil {
+				e.log.Warn("Unable to open browser, URL is not responding", "url", uri, "error", err)
+				return
+			}
+
+			err = e.clients.Browser.OpenBrowser(uri)
+			if err != nil {
+				e.log.Error("Unable to open browser", "url", uri, "error", err)
+			}
+		}(u)
+	}
+
+	wg.Wait()
+}
+
+// browserURLs returns the URLs a resource declares should be opened in the
+// browser after it is created
+func browserURLs(r config.Resource) []string {
+	ports := []config.Port{}
+	outputs := []config.ExecOutput{}
+
+	switch v := r.(type) {
+	case *config.Container:
+		if v.DisableBrowser {
+			return nil
+		}
+		ports = v.Ports
+	case *config.Ingress:
+		if v.DisableBrowser {
+			return nil
+		}
+		ports = v.Ports
+	case *config.ContainerIngress:
+		if v.DisableBrowser {
+			return nil
+		}
+		ports = v.Ports
+	case *config.K8sIngress:
+		if v.DisableBrowser {
+			return nil
+		}
+		ports = v.Ports
+	case *config.NomadIngress:
+		if v.DisableBrowser {
+			return nil
+		}
+		ports = v.Ports
+	case *config.ExecLocal:
+		if v.DisableBrowser {
+			return nil
+		}
+		outputs = v.Outputs
+	case *config.ExecRemote:
+		if v.DisableBrowser {
+			return nil
+		}
+		outputs = v.Outputs
+	case *config.Docs:
+		if v.OpenInBrowser {
+			return []string{browserURL(v.Name, strconv.Itoa(v.Port), v.Type, "")}
+		}
+	}
+
+	urls := []string{}
+	for _, p := range ports {
+		if p.Host != "" && p.OpenInBrowser != "" {
+			urls = append(urls, browserURL(r.Info().Name, p.Host, r.Info().Type, p.OpenInBrowser))
+		}
+	}
+
+	for _, o := range outputs {
+		if o.OpenInBrowser && o.Value != "" {
+			urls = append(urls, o.Value)
+		}
+	}
+
+	return urls
+}
+
+// browserURL returns the URL for a host port using the FQDN of the resource,
+// all ingress types share the ingress domain
+func browserURL(name, port string, t config.ResourceType, path string) string {
+	if t == config.TypeNomadIngress || t == config.TypeContainerIngress || t == config.TypeK8sIngress {
+		t = config.TypeIngress
+	}
+
+	return fmt.Sprintf("http://%s.%s.shipyard.run:%s%s", name, t, port, path)
+}
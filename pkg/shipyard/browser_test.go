@@ -0,0 +1,174 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupBrowserTests(t *testing.T, e Engine, httpErr error) (string, *clientmocks.MockHTTP, *clientmocks.System) {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	ioutil.WriteFile(filepath.Join(dir, "browser.hcl"), []byte(browserConfig), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "README.yard"), []byte(browserBlueprint), 0644)
+
+	hm := &clientmocks.MockHTTP{}
+	hm.On("HealthCheckHTTP", mock.Anything, mock.Anything).Return(httpErr)
+
+	bm := &clientmocks.System{}
+	bm.On("OpenBrowser", mock.Anything).Return(nil)
+
+	e.(*EngineImpl).clients.HTTP = hm
+	e.(*EngineImpl).clients.Browser = bm
+
+	return dir, hm, bm
+}
+
+func TestApplyWithOptionsOpensBrowserWindows(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, hm, bm := setupBrowserTests(t, e, nil)
+	defer os.RemoveAll(dir)
+
+	_, err := e.ApplyWithOptions(context.Background(), dir, ApplyOptions{})
+	assert.NoError(t, err)
+
+	hm.AssertNumberOfCalls(t, "HealthCheckHTTP", 2)
+	bm.AssertNumberOfCalls(t, "OpenBrowser", 2)
+	bm.AssertCalled(t, "OpenBrowser", "http://localhost:18500")
+	bm.AssertCalled(t, "OpenBrowser", "http://consul.container.shipyard.run:8500/ui")
+}
+
+func TestApplyWithOptionsDoesNotOpenBrowserWindowsWhenDisabled(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, hm, bm := setupBrowserTests(t, e, nil)
+	defer os.RemoveAll(dir)
+
+	_, err := e.ApplyWithOptions(context.Background(), dir, ApplyOptions{DisableBrowser: true})
+	assert.NoError(t, err)
+
+	hm.AssertNotCalled(t, "HealthCheckHTTP", mock.Anything, mock.Anything)
+	bm.AssertNotCalled(t, "OpenBrowser", mock.Anything)
+}
+
+func TestApplyWithOptionsDoesNotOpenBlueprintWindowsWhenAlreadyApplied(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, blueprintState)
+	defer cleanup()
+
+	dir, _, bm := setupBrowserTests(t, e, nil)
+	defer os.RemoveAll(dir)
+
+	_, err := e.ApplyWithOptions(context.Background(), dir, ApplyOptions{})
+	assert.NoError(t, err)
+
+	bm.AssertNumberOfCalls(t, "OpenBrowser", 1)
+	bm.AssertNotCalled(t, "OpenBrowser", "http://localhost:18500")
+}
+
+func TestApplyWithOptionsDoesNotOpenBrowserWindowsWhenCheckFails(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, _, bm := setupBrowserTests(t, e, fmt.Errorf("boom"))
+	defer os.RemoveAll(dir)
+
+	_, err := e.ApplyWithOptions(context.Background(), dir, ApplyOptions{})
+	assert.NoError(t, err)
+
+	bm.AssertNotCalled(t, "OpenBrowser", mock.Anything)
+}
+
+func TestApplyWithOptionsDoesNotOpenBrowserWindowsWhenApplyFails(t *testing.T) {
+	e, _, _, cleanup := setupTests(map[string]error{"consul": fmt.Errorf("boom")})
+	defer cleanup()
+
+	dir, _, bm := setupBrowserTests(t, e, nil)
+	defer os.RemoveAll(dir)
+
+	_, err := e.ApplyWithOptions(context.Background(), dir, ApplyOptions{})
+	assert.Error(t, err)
+
+	bm.AssertNotCalled(t, "OpenBrowser", mock.Anything)
+}
+
+func TestBrowserURLsReturnsDeclaredURLs(t *testing.T) {
+	d := config.NewDocs("docs")
+	d.Port = 3000
+	d.OpenInBrowser = true
+
+	ki := config.NewK8sIngress("consul")
+	ki.Ports = []config.Port{config.Port{Host: "8500", OpenInBrowser: "/"}, config.Port{Host: "8501"}}
+
+	ex := config.NewExecLocal("setup")
+	ex.Outputs = []config.ExecOutput{
+		config.ExecOutput{Name: "url", Value: "http://localhost:9090", OpenInBrowser: true},
+		config.ExecOutput{Name: "token", Value: "abc"},
+	}
+
+	c := config.NewContainer("quiet")
+	c.Ports = []config.Port{config.Port{Host: "8080", OpenInBrowser: "/"}}
+	c.DisableBrowser = true
+
+	assert.Equal(t, []string{"http://docs.docs.shipyard.run:3000"}, browserURLs(d))
+	assert.Equal(t, []string{"http://consul.ingress.shipyard.run:8500/"}, browserURLs(ki))
+	assert.Equal(t, []string{"http://localhost:9090"}, browserURLs(ex))
+	assert.Empty(t, browserURLs(c))
+}
+
+var browserConfig = `
+container "consul" {
+  image {
+    name = "consul:1.8.1"
+  }
+
+  port {
+    local           = 8500
+    remote          = 8500
+    host            = 8500
+    open_in_browser = "/ui"
+  }
+}
+
+container "quiet" {
+  image {
+    name = "consul:1.8.1"
+  }
+
+  port {
+    local           = 8500
+    remote          = 8500
+    host            = 8501
+    open_in_browser = "/ui"
+  }
+
+  disable_browser = true
+}
+`
+
+var browserBlueprint = `
+title = "Consul"
+
+browser_windows = ["http://localhost:18500"]
+`
+
+var blueprintState = `
+{
+  "blueprint": {
+    "title": "Consul",
+    "browser_windows": ["http://localhost:18500"]
+  },
+  "resources": []
+}
+`
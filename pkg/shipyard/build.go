@@ -0,0 +1,79 @@
+package shipyard
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+)
+
+// BuildImage builds an image with BuildKit, the build output is emitted as
+// EventImageBuildProgress events as the build runs and is also written to
+// bc.Output when set
+func (e *EngineImpl) BuildImage(ctx context.Context, bc clients.BuildConfig) error {
+	image := bc.Context
+	if len(bc.Tags) > 0 {
+		image = bc.Tags[0]
+	}
+
+	st := time.Now()
+
+	lw := &lineWriter{fn: func(l string) {
+		e.emit(Event{Action: "build", Phase: EventImageBuildProgress, Image: image, Duration: time.Since(st), Output: l})
+	}}
+
+	if bc.Output != nil {
+		bc.Output = io.MultiWriter(bc.Output, lw)
+	} else {
+		bc.Output = lw
+	}
+
+	err := e.clients.ImageBuilder.Build(ctx, bc)
+	lw.flush()
+
+	e.emit(Event{Action: "build", Phase: EventImageBuilt, Image: image, Duration: time.Since(st), Error: err})
+
+	return err
+}
+
+// lineWriter calls fn for every complete line written to it
+type lineWriter struct {
+	m   sync.Mutex
+	buf []byte
+	fn  func(string)
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	l.buf = append(l.buf, p...)
+
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		line := string(bytes.TrimRight(l.buf[:i], "\r"))
+		l.buf = l.buf[i+1:]
+
+		l.fn(line)
+	}
+
+	return len(p), nil
+}
+
+// flush calls fn with any output which does not end in a new line
+func (l *lineWriter) flush() {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if len(l.buf) > 0 {
+		l.fn(string(l.buf))
+		l.buf = nil
+	}
+}
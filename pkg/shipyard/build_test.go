@@ -0,0 +1,71 @@
+package shipyard
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/stretchr/testify/assert"
+)
+
+// outputImageBuilder is an ImageBuilder which writes output to the build
+// rather than running BuildKit
+type outputImageBuilder struct {
+	output string
+	err    error
+}
+
+func (b *outputImageBuilder) Build(ctx context.Context, bc clients.BuildConfig) error {
+	fmt.Fprint(bc.Output, b.output)
+	return b.err
+}
+
+func setupBuildTests(ib clients.ImageBuilder) (Engine, *[]Event, func()) {
+	e, _, _, cleanup := setupTests(nil)
+	e.GetClients().ImageBuilder = ib
+
+	events := []Event{}
+	e.AddEventHandler(func(ev Event) {
+		events = append(events, ev)
+	})
+
+	return e, &events, cleanup
+}
+
+func TestBuildImageEmitsBuildOutputAsEvents(t *testing.T) {
+	e, events, cleanup := setupBuildTests(&outputImageBuilder{output: "#1 load build definition\r\n#2 DONE\n#3 partial"})
+	defer cleanup()
+
+	out := &bytes.Buffer{}
+	err := e.BuildImage(context.Background(), clients.BuildConfig{Context: "./", Tags: []string{"web:latest"}, Output: out})
+	assert.NoError(t, err)
+
+	assert.Len(t, *events, 4)
+	assert.Equal(t, EventImageBuildProgress, (*events)[0].Phase)
+	assert.Equal(t, "build", (*events)[0].Action)
+	assert.Equal(t, "web:latest", (*events)[0].Image)
+	assert.Equal(t, "#1 load build definition", (*events)[0].Output)
+	assert.Equal(t, "#2 DONE", (*events)[1].Output)
+	assert.Equal(t, "#3 partial", (*events)[2].Output)
+
+	assert.Equal(t, EventImageBuilt, (*events)[3].Phase)
+	assert.NoError(t, (*events)[3].Error)
+
+	// output is still written to the callers writer
+	assert.Contains(t, out.String(), "#2 DONE")
+}
+
+func TestBuildImageEmitsErrorWhenBuildFails(t *testing.T) {
+	e, events, cleanup := setupBuildTests(&outputImageBuilder{err: fmt.Errorf("boom")})
+	defer cleanup()
+
+	err := e.BuildImage(context.Background(), clients.BuildConfig{Context: "./"})
+	assert.Error(t, err)
+
+	assert.Len(t, *events, 1)
+	assert.Equal(t, EventImageBuilt, (*events)[0].Phase)
+	assert.Equal(t, "./", (*events)[0].Image)
+	assert.Error(t, (*events)[0].Error)
+}
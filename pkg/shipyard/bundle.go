@@ -0,0 +1,259 @@
+package shipyard
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// bundleManifestName is the name of the manifest in an offline bundle
+const bundleManifestName = "manifest.json"
+
+// bundleImagesName is the name of the image archive in an offline bundle
+const bundleImagesName = "images.tar"
+
+// bundleCacheDir is the folder in an offline bundle containing the cached files
+const bundleCacheDir = "cache"
+
+// BundleManifest describes the contents of an offline bundle
+type BundleManifest struct {
+	Created time.Time `json:"created"`
+	Images  []string  `json:"images"`
+	Files   []string  `json:"files"` // cached files relative to the Shipyard home
+}
+
+// Bundle downloads everything needed to run the blueprint at bp using Pull
+// and writes the images and the downloaded files used by the blueprint to
+// the archive dst.
+// The bundle is loaded with LoadBundle on a machine without a network
+// connection, the blueprint can then be run in offline mode. Images for
+// resources which run on a docker_host are not included in the bundle.
+func (e *EngineImpl) Bundle(bp, dst string) (*BundleManifest, error) {
+	err := e.Pull(bp)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &BundleManifest{Created: time.Now().UTC(), Images: bundleImages(e.config), Files: []string{}}
+
+	home := utils.ShipyardHome()
+
+	seen := map[string]bool{}
+
+	for _, f := range bundleFolders(bp, e.config) {
+		err := filepath.Walk(f, func(p string, fi os.FileInfo, err error) error {
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if !fi.Mode().IsRegular() {
+				return nil
+			}
+
+			rel, _ := filepath.Rel(home, p)
+			rel = filepath.ToSlash(rel)
+
+			if !seen[rel] {
+				seen[rel] = true
+				m.Files = append(m.Files, rel)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to read the download cache: %w", err)
+		}
+	}
+
+	sort.Strings(m.Files)
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to create bundle %s: %w", dst, err)
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	md, _ := json.MarshalIndent(m, "", "  ")
+
+	err = writeExportData(tw, bundleManifestName, md)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to write bundle %s: %w", dst, err)
+	}
+
+	if len(m.Images) > 0 {
+		e.log.Info("Adding images to bundle", "count", len(m.Images))
+
+		err = writeExportEntry(tw, bundleImagesName, func(w io.Writer) error {
+			return e.clients.ContainerTasks.SaveImages(context.Background(), m.Images, w)
+		})
+
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to add images to bundle: %w", err)
+		}
+	}
+
+	e.log.Info("Adding cached files to bundle", "count", len(m.Files))
+
+	for _, name := range m.Files {
+		err := writePackFile(tw, filepath.Join(home, filepath.FromSlash(name)), path.Join(bundleCacheDir, name))
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to add %s to bundle: %w", name, err)
+		}
+	}
+
+	err = tw.Close()
+	if err == nil {
+		err = gw.Close()
+	}
+
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to write bundle %s: %w", dst, err)
+	}
+
+	return m, nil
+}
+
+// LoadBundle loads the images from a bundle created with Bundle into the
+// local Docker engine and extracts the cached files to the Shipyard home
+func (e *EngineImpl) LoadBundle(src string) (*BundleManifest, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to open bundle %s: %w", src, err)
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, xerrors.Errorf("Bundle %s is not a valid archive: %w", src, err)
+	}
+
+	tr := tar.NewReader(gr)
+
+	h, err := tr.Next()
+	if err != nil || h.Name != bundleManifestName {
+		return nil, xerrors.Errorf("Bundle %s does not contain a manifest", src)
+	}
+
+	m := &BundleManifest{}
+	err = json.NewDecoder(tr).Decode(m)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to read manifest: %w", err)
+	}
+
+	files := map[string]bool{}
+	for _, f := range m.Files {
+		files[f] = true
+	}
+
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to read bundle %s: %w", src, err)
+		}
+
+		if h.Name == bundleImagesName {
+			e.log.Info("Loading images from bundle", "count", len(m.Images))
+
+			err := e.clients.ContainerTasks.LoadImages(context.Background(), tr)
+			if err != nil {
+				return nil, xerrors.Errorf("Unable to load images: %w", err)
+			}
+
+			continue
+		}
+
+		// only files listed in the manifest are extracted, this also stops
+		// paths which would be written outside of the Shipyard home
+		name := strings.TrimPrefix(h.Name, bundleCacheDir+"/")
+		if h.Typeflag != tar.TypeReg || !files[name] || name == h.Name || path.Clean(name) != name || strings.HasPrefix(name, "../") {
+			return nil, xerrors.Errorf("Bundle contains file %s which is not in the manifest", h.Name)
+		}
+
+		_, err = extractPackFile(tr, filepath.Join(utils.ShipyardHome(), filepath.FromSlash(name)), os.FileMode(h.Mode))
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to extract %s: %w", name, err)
+		}
+	}
+
+	return m, nil
+}
+
+// bundleFolders returns the folders in the Shipyard download cache which
+// contain the remote blueprint at bp, the remote modules it uses, and the
+// remote Helm charts and files used by the resources in c
+func bundleFolders(bp string, c *config.Config) []string {
+	folders := []string{}
+
+	if isRemoteBlueprint(bp) {
+		abs, _ := filepath.Abs(bp)
+		folders = append(folders, abs)
+	}
+
+	folders = append(folders, c.RemoteModules...)
+
+	for _, r := range c.Resources {
+		if h, ok := r.(*config.Helm); ok && !utils.IsLocalFolder(h.Chart) {
+			folders = append(folders, providers.HelmChartFolder(h.Chart))
+		}
+
+		for _, p := range remotePaths(r) {
+			folders = append(folders, utils.GetDownloadLocalFolder(p))
+		}
+	}
+
+	return folders
+}
+
+// setOffline stops the engine accessing the network, remote modules, Helm
+// charts, and files are only read from the local cache
+func (e *EngineImpl) setOffline(offline bool) {
+	e.offline = offline
+	e.clients.Getter.SetOffline(offline)
+}
+
+// bundleImages returns the unique images used by the resources which run on
+// the local Docker engine
+func bundleImages(c *config.Config) []string {
+	images := []string{}
+	seen := map[string]bool{}
+
+	for _, r := range c.Resources {
+		if config.DockerHostFor(r) != "" {
+			continue
+		}
+
+		for _, i := range providers.Images(r) {
+			if i.Name != "" && !seen[i.Name] {
+				seen[i.Name] = true
+				images = append(images, i.Name)
+			}
+		}
+	}
+
+	return images
+}
@@ -0,0 +1,133 @@
+package shipyard
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupBundleTests(t *testing.T, e Engine) (string, *clientmocks.MockContainerTasks) {
+	dir, ct, _ := setupPullTests(t, e, nil, nil)
+
+	ct.On("SaveImages", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(io.Writer).Write([]byte("images"))
+		}).
+		Return(nil)
+	ct.On("LoadImages", mock.Anything).Return(nil)
+
+	// add the downloaded chart and file to the cache, and a chart which is
+	// not used by the blueprint
+	writeCacheFile(t, filepath.Join(providers.HelmChartFolder("github.com/hashicorp/vault-helm"), "Chart.yaml"))
+	writeCacheFile(t, filepath.Join(utils.GetDownloadLocalFolder("https://example.com/app.yaml"), "app.yaml"))
+	writeCacheFile(t, filepath.Join(utils.ShipyardHome(), "helm_charts", "other", "Chart.yaml"))
+
+	return dir, ct
+}
+
+func writeCacheFile(t *testing.T, path string) {
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	assert.NoError(t, err)
+
+	err = ioutil.WriteFile(path, []byte("cached"), 0644)
+	assert.NoError(t, err)
+}
+
+func TestBundleWritesImagesAndCachedFiles(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, ct := setupBundleTests(t, e)
+	defer os.RemoveAll(dir)
+
+	m, err := e.Bundle(dir, filepath.Join(dir, "bundle.tar.gz"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"consul:1.8.1", "rancher/k3s:v1.18.4-k3s1"}, m.Images)
+	assert.Equal(t, []string{
+		"downloads/" + filepath.Base(utils.GetDownloadLocalFolder("https://example.com/app.yaml")) + "/app.yaml",
+		"helm_charts/github.com/hashicorp/vault-helm/Chart.yaml",
+	}, m.Files)
+
+	ct.AssertCalled(t, "SaveImages", []string{"consul:1.8.1", "rancher/k3s:v1.18.4-k3s1"}, mock.Anything)
+	assert.FileExists(t, filepath.Join(dir, "bundle.tar.gz"))
+}
+
+func TestLoadBundleLoadsImagesAndRestoresCachedFiles(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, ct := setupBundleTests(t, e)
+	defer os.RemoveAll(dir)
+
+	_, err := e.Bundle(dir, filepath.Join(dir, "bundle.tar.gz"))
+	assert.NoError(t, err)
+
+	os.RemoveAll(filepath.Join(utils.ShipyardHome(), "helm_charts"))
+
+	m, err := e.LoadBundle(filepath.Join(dir, "bundle.tar.gz"))
+	assert.NoError(t, err)
+	assert.Len(t, m.Images, 2)
+
+	ct.AssertCalled(t, "LoadImages", mock.Anything)
+	assert.FileExists(t, filepath.Join(providers.HelmChartFolder("github.com/hashicorp/vault-helm"), "Chart.yaml"))
+	assert.NoFileExists(t, filepath.Join(utils.ShipyardHome(), "helm_charts", "other", "Chart.yaml"))
+}
+
+func TestLoadBundleReturnsErrorForInvalidArchive(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	f, err := ioutil.TempFile("", "")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	f.WriteString("not a bundle")
+	f.Close()
+
+	_, err = e.LoadBundle(f.Name())
+	assert.Error(t, err)
+}
+
+func TestBundleFoldersReturnsRemoteBlueprintAndModules(t *testing.T) {
+	_, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	c := config.New()
+	c.RemoteModules = []string{utils.GetBlueprintLocalFolder("github.com/shipyard-run/blueprints//modules/consul")}
+
+	bp := utils.GetBlueprintLocalFolder("github.com/shipyard-run/blueprints//vault-k8s")
+	f := bundleFolders(bp, c)
+
+	assert.Equal(t, []string{bp, c.RemoteModules[0]}, f)
+	assert.Len(t, bundleFolders("./local", c), 1)
+}
+
+func TestApplyWithOptionsOfflineDoesNotDownloadFiles(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, ct, gm := setupPullTests(t, e, nil, nil)
+	defer os.RemoveAll(dir)
+
+	ct.On("FindContainers", mock.Anything, mock.Anything).Return([]config.ContainerInfo{}, nil)
+	gm.On("SetOffline", mock.Anything)
+
+	_, err := e.ApplyWithOptions(context.Background(), dir, ApplyOptions{Offline: true, DisableBrowser: true})
+	assert.NoError(t, err)
+
+	gm.AssertCalled(t, "SetOffline", true)
+	// offline mode only lasts for the apply
+	gm.AssertCalled(t, "SetOffline", false)
+	assert.False(t, e.(*EngineImpl).offline)
+}
@@ -0,0 +1,252 @@
+package shipyard
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// checkpointRepository is the repository for the images created from the
+// containers when checkpointing, the tag is the blueprint fingerprint
+const checkpointRepository = "shipyard-checkpoint"
+
+// maxCheckpoints is the number of checkpoints kept, the images for older
+// checkpoints are removed when a checkpoint is created
+const maxCheckpoints = 3
+
+// Checkpoint records the images created from the containers of an
+// environment after a successful apply
+type Checkpoint struct {
+	Fingerprint string            `json:"fingerprint"` // hash of the resources in the blueprint
+	Created     time.Time         `json:"created"`
+	Images      map[string]string `json:"images"` // checkpoint image keyed by the resource [type].[name]
+}
+
+// checkpointDir returns the folder containing the checkpoint manifests
+func checkpointDir() string {
+	return filepath.Join(utils.ShipyardHome(), "checkpoints")
+}
+
+// checkpoint creates an image from the filesystem of every container created
+// by the last apply, the next apply of the identical blueprint creates the
+// containers from these images rather than running the setup again.
+// Docker commit is used so the files written by exec_remote resources and
+// the container command are restored but running processes are started
+// again, named volumes are not part of the checkpoint.
+func (e *EngineImpl) checkpoint(ctx context.Context) (*Checkpoint, error) {
+	if e.fingerprint == "" {
+		return nil, xerrors.Errorf("Unable to checkpoint, no blueprint has been applied")
+	}
+
+	cp := &Checkpoint{Fingerprint: e.fingerprint, Created: time.Now().UTC(), Images: map[string]string{}}
+
+	for _, r := range e.config.Resources {
+		if !checkpointable(r) || r.Info().Status != config.Applied {
+			continue
+		}
+
+		cl, err := e.clientsFor(r)
+		if err != nil {
+			return nil, err
+		}
+
+		// checkpoints are only restored on the local Docker engine
+		if cl != e.clients {
+			continue
+		}
+
+		ids, err := cl.ContainerTasks.FindContainerIDs(ctx, r.Info().Name, r.Info().Type)
+		if err != nil || len(ids) == 0 {
+			return nil, xerrors.Errorf("Unable to find container for %s: %w", resourceName(r), err)
+		}
+
+		ref := fmt.Sprintf("%s/%s-%s:%s", checkpointRepository, r.Info().Type, r.Info().Name, e.fingerprint)
+
+		e.log.Info("Creating checkpoint", "ref", r.Info().Name, "image", ref)
+
+		err = cl.ContainerTasks.CommitContainer(ctx, ids[0], ref)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to checkpoint %s: %w", resourceName(r), err)
+		}
+
+		cp.Images[resourceName(r)] = ref
+	}
+
+	d, _ := json.MarshalIndent(cp, "", "  ")
+
+	// checkpoints are shared by every workspace, the manifest is replaced
+	// atomically so an apply in another workspace never reads a partial file
+	err := config.AtomicWriteFile(filepath.Join(checkpointDir(), e.fingerprint+".json"), d)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to write checkpoint: %w", err)
+	}
+
+	e.pruneCheckpoints(ctx)
+
+	return cp, nil
+}
+
+// pruneCheckpoints removes the manifests and images for all but the
+// maxCheckpoints most recent checkpoints, checkpoints which can not be
+// removed are kept and removed by the next checkpoint
+func (e *EngineImpl) pruneCheckpoints(ctx context.Context) {
+	files, _ := filepath.Glob(filepath.Join(checkpointDir(), "*.json"))
+
+	cps := []*Checkpoint{}
+	for _, f := range files {
+		d, err := ioutil.ReadFile(f)
+		if err != nil {
+			continue
+		}
+
+		cp := &Checkpoint{}
+		if json.Unmarshal(d, cp) == nil {
+			cps = append(cps, cp)
+		}
+	}
+
+	if len(cps) <= maxCheckpoints {
+		return
+	}
+
+	sort.Slice(cps, func(i, j int) bool { return cps[i].Created.After(cps[j].Created) })
+
+	for _, cp := range cps[maxCheckpoints:] {
+		removed := true
+
+		for _, ref := range cp.Images {
+			// images which have already been removed are ignored
+			if exists, err := e.clients.ContainerTasks.ImageExists(ctx, ref); err == nil && !exists {
+				continue
+			}
+
+			err := e.clients.ContainerTasks.RemoveImage(ctx, ref)
+			if err != nil {
+				e.log.Warn("Unable to remove checkpoint image", "image", ref, "error", err)
+				removed = false
+			}
+		}
+
+		if removed {
+			e.log.Debug("Removed checkpoint", "fingerprint", cp.Fingerprint)
+			os.Remove(filepath.Join(checkpointDir(), cp.Fingerprint+".json"))
+		}
+	}
+}
+
+// restoreCheckpoint changes the image for containers which have not been
+// created to the checkpoint image when a checkpoint exists for the config.
+// exec_remote resources which target a restored container are not run
+// again as their changes are part of the checkpoint. The returned function
+// resets the images to the values in the blueprint so the checkpoint
+// images are not written to the state.
+func (e *EngineImpl) restoreCheckpoint(ctx context.Context) func() {
+	reset := func() {}
+
+	d, err := ioutil.ReadFile(filepath.Join(checkpointDir(), e.fingerprint+".json"))
+	if err != nil {
+		return reset
+	}
+
+	cp := &Checkpoint{}
+	err = json.Unmarshal(d, cp)
+	if err != nil {
+		e.log.Warn("Unable to read checkpoint", "fingerprint", e.fingerprint, "error", err)
+		return reset
+	}
+
+	restored := map[string]bool{}
+	images := map[*config.Image]string{}
+
+	for _, r := range e.config.Resources {
+		img := checkpointImage(r)
+		ref, ok := cp.Images[resourceName(r)]
+
+		if img == nil || !ok || r.Info().Status != config.PendingCreation {
+			continue
+		}
+
+		// the image may have been removed since the checkpoint was created
+		if exists, err := e.clients.ContainerTasks.ImageExists(ctx, ref); err != nil || !exists {
+			e.log.Debug("Checkpoint image does not exist", "ref", r.Info().Name, "image", ref)
+			continue
+		}
+
+		e.log.Info("Restoring from checkpoint", "ref", r.Info().Name, "image", ref)
+
+		images[img] = img.Name
+		img.Name = ref
+		restored[resourceName(r)] = true
+	}
+
+	for _, r := range e.config.Resources {
+		if er, ok := r.(*config.ExecRemote); ok && er.Status == config.PendingCreation && restored[er.Target] {
+			e.log.Info("Skipping exec, changes are restored from checkpoint", "ref", er.Name, "target", er.Target)
+			er.Status = config.Applied
+		}
+	}
+
+	return func() {
+		for img, name := range images {
+			img.Name = name
+		}
+	}
+}
+
+// checkpointable returns true for resources which can be restored from a
+// checkpoint, clusters are not supported as their state is held in volumes
+func checkpointable(r config.Resource) bool {
+	return checkpointImage(r) != nil
+}
+
+// checkpointImage returns the image used to create the container for the
+// resource or nil when the resource can not be checkpointed
+func checkpointImage(r config.Resource) *config.Image {
+	switch v := r.(type) {
+	case *config.Container:
+		return &v.Image
+	case *config.Sidecar:
+		return &v.Image
+	}
+
+	return nil
+}
+
+// blueprintFingerprint returns a hash of the definition of the resources as
+// parsed from the blueprint, attributes set by providers and the status of
+// the resources are not included
+func blueprintFingerprint(resources []config.Resource) string {
+	defs := []string{}
+
+	for _, r := range resources {
+		m := r.Info().Definition
+		if m == nil {
+			d, _ := json.Marshal(r)
+
+			json.Unmarshal(d, &m)
+			delete(m, "status")
+		}
+
+		d, _ := json.Marshal(m)
+		defs = append(defs, string(d))
+	}
+
+	sort.Strings(defs)
+
+	h := sha256.New()
+	for _, d := range defs {
+		h.Write([]byte(d))
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil))[:12]
+}
@@ -0,0 +1,167 @@
+package shipyard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers/mocks"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupCheckpointTests(t *testing.T, e Engine, imageExists bool) (string, *clientmocks.MockContainerTasks) {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	ioutil.WriteFile(filepath.Join(dir, "main.hcl"), []byte(checkpointBlueprint), 0644)
+
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	removeOn(&ct.Mock, "FindContainerIDs")
+	ct.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{"abc"}, nil)
+	ct.On("CommitContainer", mock.Anything, mock.Anything).Return(nil)
+	ct.On("ImageExists", mock.Anything).Return(imageExists, nil)
+
+	return dir, ct
+}
+
+func TestApplyWithCheckpointCommitsContainers(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, ct := setupCheckpointTests(t, e, true)
+	defer os.RemoveAll(dir)
+
+	_, err := e.ApplyWithOptions(context.Background(), dir, ApplyOptions{DisableBrowser: true, Checkpoint: true})
+	assert.NoError(t, err)
+
+	fp := e.(*EngineImpl).fingerprint
+	ct.AssertCalled(t, "CommitContainer", "abc", "shipyard-checkpoint/container-consul:"+fp)
+	ct.AssertNumberOfCalls(t, "CommitContainer", 1)
+
+	assert.FileExists(t, filepath.Join(utils.ShipyardHome(), "checkpoints", fp+".json"))
+}
+
+func TestApplyRestoresContainersFromCheckpoint(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, ct := setupCheckpointTests(t, e, true)
+	defer os.RemoveAll(dir)
+
+	_, err := e.ApplyWithOptions(context.Background(), dir, ApplyOptions{DisableBrowser: true, Checkpoint: true})
+	assert.NoError(t, err)
+
+	err = e.Destroy(context.Background(), "", true)
+	assert.NoError(t, err)
+
+	*mp = []*mocks.MockProvider{}
+
+	// the container was removed by destroy
+	removeOn(&ct.Mock, "FindContainerIDs")
+	ct.On("FindContainerIDs", mock.Anything, mock.Anything).Return(nil, nil)
+
+	_, err = e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	fp := e.(*EngineImpl).fingerprint
+	ct.AssertCalled(t, "PullImage", config.Image{Name: "shipyard-checkpoint/container-consul:" + fp}, false)
+
+	// the exec is restored by the checkpoint
+	assert.Equal(t, 1, providerCalls(mp, "consul", "Create"))
+	assert.Equal(t, 0, providerCalls(mp, "setup", "Create"))
+
+	// the state contains the image from the blueprint
+	sc := config.New()
+	sc.FromJSON(utils.StatePath())
+	r, _ := sc.FindResource("container.consul")
+	assert.Equal(t, "consul:1.8.1", r.(*config.Container).Image.Name)
+}
+
+func TestApplyDoesNotRestoreWhenCheckpointImageRemoved(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, _ := setupCheckpointTests(t, e, false)
+	defer os.RemoveAll(dir)
+
+	_, err := e.ApplyWithOptions(context.Background(), dir, ApplyOptions{DisableBrowser: true, Checkpoint: true})
+	assert.NoError(t, err)
+
+	err = e.Destroy(context.Background(), "", true)
+	assert.NoError(t, err)
+
+	*mp = []*mocks.MockProvider{}
+
+	_, err = e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, providerCalls(mp, "setup", "Create"))
+}
+
+func TestCheckpointRemovesOldCheckpoints(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, ct := setupCheckpointTests(t, e, true)
+	defer os.RemoveAll(dir)
+
+	ct.On("RemoveImage", mock.Anything).Return(nil)
+
+	for i := 1; i <= maxCheckpoints; i++ {
+		cp := Checkpoint{
+			Fingerprint: fmt.Sprintf("old%d", i),
+			Created:     time.Now().Add(time.Duration(-i) * time.Hour),
+			Images:      map[string]string{"container.consul": fmt.Sprintf("shipyard-checkpoint/container-consul:old%d", i)},
+		}
+
+		d, _ := json.Marshal(cp)
+		config.AtomicWriteFile(filepath.Join(checkpointDir(), cp.Fingerprint+".json"), d)
+	}
+
+	_, err := e.ApplyWithOptions(context.Background(), dir, ApplyOptions{DisableBrowser: true, Checkpoint: true})
+	assert.NoError(t, err)
+
+	// the oldest checkpoint is removed
+	ct.AssertCalled(t, "RemoveImage", fmt.Sprintf("shipyard-checkpoint/container-consul:old%d", maxCheckpoints))
+	ct.AssertNumberOfCalls(t, "RemoveImage", 1)
+
+	assert.NoFileExists(t, filepath.Join(checkpointDir(), fmt.Sprintf("old%d.json", maxCheckpoints)))
+	assert.FileExists(t, filepath.Join(checkpointDir(), e.(*EngineImpl).fingerprint+".json"))
+}
+
+func TestBlueprintFingerprintIgnoresAttributesSetByProviders(t *testing.T) {
+	c := config.New()
+	co := config.NewContainer("consul")
+	co.Image = config.Image{Name: "consul:1.8.1"}
+	c.AddResource(co)
+
+	config.SetDefinitions(c, "/blueprint")
+	fp := blueprintFingerprint(c.Resources)
+
+	co.Networks = []config.NetworkAttachment{{Name: "network.cloud", IPAddress: "10.6.0.200"}}
+
+	assert.Equal(t, fp, blueprintFingerprint(c.Resources))
+}
+
+var checkpointBlueprint = `
+container "consul" {
+  image {
+    name = "consul:1.8.1"
+  }
+}
+
+exec_remote "setup" {
+  target = "container.consul"
+  cmd    = "consul"
+  args   = ["kv", "put", "setup", "true"]
+}
+`
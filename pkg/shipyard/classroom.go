@@ -0,0 +1,205 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// defaultClassroomPortOffset is added to the host ports of each instance
+// multiplied by the instance number
+const defaultClassroomPortOffset = 100
+
+// ClassroomOptions define how many copies of a blueprint are created
+type ClassroomOptions struct {
+	// Name is used as the prefix for each instance, instances are named
+	// [name]-1, [name]-2, etc, default student
+	Name string
+	// Instances is the number of copies to create
+	Instances int
+	// PortOffset is added to host ports for each instance multiplied by the
+	// instance number, default 100. Instance 1 with the default offset exposes
+	// port 18500 as 18600, instance 2 exposes 18700
+	PortOffset int
+}
+
+// InstanceStatus is the state of a single instance in a classroom
+type InstanceStatus struct {
+	Name      string   `json:"name"`
+	Resources int      `json:"resources"`       // number of resources in the state
+	Failed    int      `json:"failed"`          // number of resources which failed to create
+	Endpoints []string `json:"endpoints"`       // addresses on the local machine for exposed ports
+	Error     string   `json:"error,omitempty"` // error returned when the instance was created
+}
+
+// ApplyClassroom creates isolated copies of the blueprint at path. Each
+// instance is tracked in a separate workspace, resources are prefixed with the
+// instance name, host ports are offset, and networks use a separate subnet.
+// Instances are created one after another, a failing instance does not stop
+// the remaining instances being created
+func (e *EngineImpl) ApplyClassroom(ctx context.Context, path string, o ClassroomOptions) ([]InstanceStatus, error) {
+	if o.Instances < 1 {
+		return nil, xerrors.Errorf("Classroom must have at least one instance")
+	}
+
+	if o.Name == "" {
+		o.Name = "student"
+	}
+
+	if o.PortOffset == 0 {
+		o.PortOffset = defaultClassroomPortOffset
+	}
+
+	status := []InstanceStatus{}
+	for i := 1; i <= o.Instances; i++ {
+		name := fmt.Sprintf("%s-%d", o.Name, i)
+		e.log.Info("Creating classroom instance", "name", name)
+
+		// instances share indexes with named workspaces so that subnets do
+		// not overlap, an instance which already exists keeps its index
+		w, err := createWorkspace(Workspace{Name: name, Classroom: o.Name, PortOffset: i * o.PortOffset})
+		if err != nil {
+			status = append(status, InstanceStatus{Name: name, Endpoints: []string{}, Error: err.Error()})
+			continue
+		}
+
+		ie := e.instanceEngine(name, &config.InstanceOptions{Prefix: name, Index: w.Index, PortOffset: w.PortOffset})
+
+		_, err = ie.Apply(ctx, path)
+
+		s := instanceStatus(name)
+		if err != nil {
+			s.Error = err.Error()
+		}
+
+		status = append(status, s)
+	}
+
+	return status, nil
+}
+
+// ClassroomStatus returns the status of each instance in the named classroom
+func (e *EngineImpl) ClassroomStatus(name string) ([]InstanceStatus, error) {
+	instances, err := classroomInstances(name)
+	if err != nil {
+		return nil, err
+	}
+
+	status := []InstanceStatus{}
+	for _, i := range instances {
+		status = append(status, instanceStatus(i))
+	}
+
+	return status, nil
+}
+
+// DestroyClassroom destroys every instance in the named classroom, all
+// instances are destroyed even when destroying an instance fails
+func (e *EngineImpl) DestroyClassroom(ctx context.Context, name string) error {
+	instances, err := classroomInstances(name)
+	if err != nil {
+		return err
+	}
+
+	failed := []string{}
+	for _, i := range instances {
+		e.log.Info("Destroying classroom instance", "name", i)
+
+		ie := e.instanceEngine(i, nil)
+
+		err := ie.Destroy(ctx, "", true)
+		if err != nil {
+			e.log.Error("Unable to destroy classroom instance", "name", i, "error", err)
+			failed = append(failed, i)
+			continue
+		}
+
+		err = removeWorkspace(i)
+		if err != nil {
+			e.log.Error("Unable to remove classroom instance", "name", i, "error", err)
+			failed = append(failed, i)
+		}
+	}
+
+	if len(failed) > 0 {
+		return xerrors.Errorf("Unable to destroy instances %v", failed)
+	}
+
+	return nil
+}
+
+// instanceEngine returns an engine which shares the clients and handlers of
+// the current engine but stores state in the workspace for the instance
+func (e *EngineImpl) instanceEngine(name string, o *config.InstanceOptions) *EngineImpl {
+	e.sync.Lock()
+	defer e.sync.Unlock()
+
+	return &EngineImpl{
+		clients:           e.clients,
+		log:               e.log.Named(name),
+		getProvider:       e.getProvider,
+		getHostClients:    e.getHostClients,
+		getClusterClients: e.getClusterClients,
+		handlers:          append([]*registeredHandler{}, e.handlers...),
+		statePath:         utils.WorkspaceStatePath(name),
+		instance:          o,
+	}
+}
+
+// classroomInstances returns the names of the workspaces for the classroom
+// sorted by instance number, named workspaces which match the instance names
+// are not returned
+func classroomInstances(name string) ([]string, error) {
+	ws, err := allWorkspaces()
+	if err != nil {
+		return nil, err
+	}
+
+	re := regexp.MustCompile(fmt.Sprintf(`^%s-(\d+)$`, regexp.QuoteMeta(name)))
+	numbers := []int{}
+
+	for _, w := range ws {
+		if m := re.FindStringSubmatch(w.Name); w.Classroom == name && m != nil {
+			n, _ := strconv.Atoi(m[1])
+			numbers = append(numbers, n)
+		}
+	}
+
+	sort.Ints(numbers)
+
+	instances := []string{}
+	for _, n := range numbers {
+		instances = append(instances, fmt.Sprintf("%s-%d", name, n))
+	}
+
+	return instances, nil
+}
+
+// instanceStatus reads the state for the instance
+func instanceStatus(name string) InstanceStatus {
+	s := InstanceStatus{Name: name, Endpoints: []string{}}
+
+	sc := config.New()
+	err := sc.FromJSON(utils.WorkspaceStatePath(name))
+	if err != nil {
+		return s
+	}
+
+	s.Resources = len(sc.Resources)
+	for _, r := range sc.Resources {
+		if r.Info().Status == config.Failed {
+			s.Failed++
+			continue
+		}
+
+		s.Endpoints = append(s.Endpoints, resourceEndpoints(r)...)
+	}
+
+	return s
+}
@@ -0,0 +1,114 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupClassroom(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	ioutil.WriteFile(filepath.Join(dir, "classroom.hcl"), []byte(classroomConfig), 0644)
+
+	return dir
+}
+
+func TestApplyClassroomCreatesIsolatedInstances(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir := setupClassroom(t)
+	defer os.RemoveAll(dir)
+
+	s, err := e.ApplyClassroom(context.Background(), dir, ClassroomOptions{Instances: 2})
+	assert.NoError(t, err)
+	testAssertMethodCalled(t, mp, "Create", 4)
+
+	assert.Len(t, s, 2)
+	assert.Equal(t, "student-1", s[0].Name)
+	assert.Equal(t, 2, s[0].Resources)
+	assert.Equal(t, []string{"localhost:18600"}, s[0].Endpoints)
+	assert.Equal(t, []string{"localhost:18700"}, s[1].Endpoints)
+
+	// each instance has a separate state
+	assert.FileExists(t, utils.WorkspaceStatePath("student-1"))
+	assert.FileExists(t, utils.WorkspaceStatePath("student-2"))
+	assert.NoFileExists(t, utils.StatePath())
+
+	sc := config.New()
+	sc.FromJSON(utils.WorkspaceStatePath("student-2"))
+	n, err := sc.FindResource("network.student-2-cloud")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.7.0.0/16", n.(*config.Network).Subnet)
+}
+
+func TestApplyClassroomReportsFailedInstances(t *testing.T) {
+	e, _, _, cleanup := setupTests(map[string]error{"class-2-consul": fmt.Errorf("boom")})
+	defer cleanup()
+
+	dir := setupClassroom(t)
+	defer os.RemoveAll(dir)
+
+	s, err := e.ApplyClassroom(context.Background(), dir, ClassroomOptions{Name: "class", Instances: 3, PortOffset: 10})
+	assert.NoError(t, err)
+
+	assert.Len(t, s, 3)
+	assert.Empty(t, s[0].Error)
+	assert.NotEmpty(t, s[1].Error)
+	assert.Equal(t, 1, s[1].Failed)
+	assert.Equal(t, []string{"localhost:18530"}, s[2].Endpoints)
+}
+
+func TestClassroomStatusAndDestroy(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir := setupClassroom(t)
+	defer os.RemoveAll(dir)
+
+	_, err := e.ApplyClassroom(context.Background(), dir, ClassroomOptions{Instances: 2})
+	assert.NoError(t, err)
+
+	s, err := e.ClassroomStatus("student")
+	assert.NoError(t, err)
+	assert.Len(t, s, 2)
+
+	err = e.DestroyClassroom(context.Background(), "student")
+	assert.NoError(t, err)
+	testAssertMethodCalled(t, mp, "Destroy", 4)
+
+	s, err = e.ClassroomStatus("student")
+	assert.NoError(t, err)
+	assert.Len(t, s, 0)
+}
+
+var classroomConfig = `
+network "cloud" {
+  subnet = "10.5.0.0/16"
+}
+
+container "consul" {
+  image {
+    name = "consul:1.8.1"
+  }
+
+  network {
+    name = "network.cloud"
+  }
+
+  port {
+    local  = "8500"
+    remote = "8500"
+    host   = "18500"
+  }
+}
+`
@@ -0,0 +1,235 @@
+package shipyard
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"sigs.k8s.io/yaml"
+)
+
+// composeVersion is the docker-compose file format used for exports, the
+// 2.x format is used as it supports resource limits and static IPs without
+// requiring swarm mode
+const composeVersion = "2.4"
+
+type composeFile struct {
+	Version  string                    `json:"version"`
+	Services map[string]composeService `json:"services,omitempty"`
+	Networks map[string]composeNetwork `json:"networks,omitempty"`
+	Volumes  map[string]composeVolume  `json:"volumes,omitempty"`
+}
+
+type composeService struct {
+	Image       string                           `json:"image"`
+	Entrypoint  []string                         `json:"entrypoint,omitempty"`
+	Command     []string                         `json:"command,omitempty"`
+	Environment map[string]string                `json:"environment,omitempty"`
+	Ports       []string                         `json:"ports,omitempty"`
+	Volumes     []string                         `json:"volumes,omitempty"`
+	Tmpfs       []string                         `json:"tmpfs,omitempty"`
+	Networks    map[string]composeServiceNetwork `json:"networks,omitempty"`
+	NetworkMode string                           `json:"network_mode,omitempty"`
+	DependsOn   []string                         `json:"depends_on,omitempty"`
+	Privileged  bool                             `json:"privileged,omitempty"`
+	CPUs        string                           `json:"cpus,omitempty"`
+	CPUSet      string                           `json:"cpuset,omitempty"`
+	MemLimit    string                           `json:"mem_limit,omitempty"`
+}
+
+type composeServiceNetwork struct {
+	IPv4Address string   `json:"ipv4_address,omitempty"`
+	IPv6Address string   `json:"ipv6_address,omitempty"`
+	Aliases     []string `json:"aliases,omitempty"`
+}
+
+type composeNetwork struct {
+	External   bool         `json:"external,omitempty"`
+	EnableIPv6 bool         `json:"enable_ipv6,omitempty"`
+	IPAM       *composeIPAM `json:"ipam,omitempty"`
+}
+
+type composeIPAM struct {
+	Config []composeIPAMConfig `json:"config"`
+}
+
+type composeIPAMConfig struct {
+	Subnet string `json:"subnet"`
+}
+
+type composeVolume struct{}
+
+// generateCompose renders the networks, containers and sidecars in the
+// config as a docker-compose file, all other resources are ignored
+func generateCompose(c *config.Config) ([]byte, error) {
+	cf := composeFile{
+		Version:  composeVersion,
+		Services: map[string]composeService{},
+		Networks: map[string]composeNetwork{},
+		Volumes:  map[string]composeVolume{},
+	}
+
+	for _, r := range c.Resources {
+		switch v := r.(type) {
+		case *config.Network:
+			cf.Networks[v.Name] = composeNetworkFromConfig(v)
+
+		case *config.Container:
+			s := composeService{
+				Image:       v.Image.Name,
+				Entrypoint:  v.Entrypoint,
+				Command:     v.Command,
+				Environment: composeEnvironment(v.Environment),
+				Ports:       composePorts(v.Ports),
+				DependsOn:   composeDependsOn(v.Depends),
+				Privileged:  v.Privileged,
+			}
+
+			for _, n := range v.Networks {
+				if s.Networks == nil {
+					s.Networks = map[string]composeServiceNetwork{}
+				}
+
+				s.Networks[strings.TrimPrefix(n.Name, "network.")] = composeServiceNetwork{
+					IPv4Address: n.IPAddress,
+					IPv6Address: n.IPv6Address,
+					Aliases:     n.Aliases,
+				}
+			}
+
+			composeVolumes(&s, v.Volumes, cf.Volumes)
+			composeResources(&s, v.Resources)
+
+			cf.Services[v.Name] = s
+
+		case *config.Sidecar:
+			s := composeService{
+				Image:       v.Image.Name,
+				Entrypoint:  v.Entrypoint,
+				Command:     v.Command,
+				Environment: composeEnvironment(v.Environment),
+				DependsOn:   composeDependsOn(append(v.Depends, v.Target)),
+				Privileged:  v.Privileged,
+				// sidecars share the network namespace of the target
+				NetworkMode: fmt.Sprintf("service:%s", strings.TrimPrefix(v.Target, "container.")),
+			}
+
+			composeVolumes(&s, v.Volumes, cf.Volumes)
+			composeResources(&s, v.Resources)
+
+			cf.Services[v.Name] = s
+		}
+	}
+
+	return yaml.Marshal(cf)
+}
+
+func composeNetworkFromConfig(n *config.Network) composeNetwork {
+	if n.External {
+		return composeNetwork{External: true}
+	}
+
+	cn := composeNetwork{}
+	ipam := &composeIPAM{}
+
+	if n.Subnet != "" {
+		ipam.Config = append(ipam.Config, composeIPAMConfig{Subnet: n.Subnet})
+	}
+
+	if n.SubnetIPv6 != "" {
+		cn.EnableIPv6 = true
+		ipam.Config = append(ipam.Config, composeIPAMConfig{Subnet: n.SubnetIPv6})
+	}
+
+	if len(ipam.Config) > 0 {
+		cn.IPAM = ipam
+	}
+
+	return cn
+}
+
+func composeEnvironment(env []config.KV) map[string]string {
+	if len(env) == 0 {
+		return nil
+	}
+
+	e := map[string]string{}
+	for _, kv := range env {
+		e[kv.Key] = kv.Value
+	}
+
+	return e
+}
+
+// composePorts returns the ports which are published to the host, ports
+// without a host port are only reachable on the network and are not exported
+func composePorts(ports []config.Port) []string {
+	p := []string{}
+	for _, port := range ports {
+		if port.Host == "" {
+			continue
+		}
+
+		s := fmt.Sprintf("%s:%s", port.Host, port.Local)
+		if port.Protocol != "" && port.Protocol != "tcp" {
+			s = fmt.Sprintf("%s/%s", s, port.Protocol)
+		}
+
+		p = append(p, s)
+	}
+
+	return p
+}
+
+// composeDependsOn converts dependencies on other containers and sidecars
+// into service names, dependencies on other resource types are dropped
+func composeDependsOn(depends []string) []string {
+	d := []string{}
+	for _, dep := range depends {
+		for _, t := range []config.ResourceType{config.TypeContainer, config.TypeSidecar} {
+			if strings.HasPrefix(dep, string(t)+".") {
+				d = append(d, strings.TrimPrefix(dep, string(t)+"."))
+			}
+		}
+	}
+
+	return d
+}
+
+func composeVolumes(s *composeService, volumes []config.Volume, named map[string]composeVolume) {
+	for _, v := range volumes {
+		switch v.Type {
+		case "tmpfs":
+			s.Tmpfs = append(s.Tmpfs, v.Destination)
+		case "volume":
+			named[v.Source] = composeVolume{}
+			s.Volumes = append(s.Volumes, fmt.Sprintf("%s:%s", v.Source, v.Destination))
+		default:
+			s.Volumes = append(s.Volumes, fmt.Sprintf("%s:%s", v.Source, v.Destination))
+		}
+	}
+}
+
+func composeResources(s *composeService, r *config.Resources) {
+	if r == nil {
+		return
+	}
+
+	// Shipyard uses 1024 shares per CPU, compose uses fractional CPUs
+	if r.CPU > 0 {
+		s.CPUs = fmt.Sprintf("%g", float64(r.CPU)/1024)
+	}
+
+	if len(r.CPUPin) > 0 {
+		pins := []string{}
+		for _, p := range r.CPUPin {
+			pins = append(pins, fmt.Sprintf("%d", p))
+		}
+
+		s.CPUSet = strings.Join(pins, ",")
+	}
+
+	if r.Memory > 0 {
+		s.MemLimit = fmt.Sprintf("%dm", r.Memory)
+	}
+}
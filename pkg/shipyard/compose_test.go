@@ -0,0 +1,98 @@
+package shipyard
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+)
+
+func TestExportComposeWritesContainersAndNetworks(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, composeState)
+	defer cleanup()
+
+	out := filepath.Join(utils.StateDir(), "docker-compose.yaml")
+	err := e.ExportCompose(out)
+	assert.NoError(t, err)
+
+	d, err := ioutil.ReadFile(out)
+	assert.NoError(t, err)
+
+	cf := composeFile{}
+	err = yaml.Unmarshal(d, &cf)
+	assert.NoError(t, err)
+
+	assert.Equal(t, composeVersion, cf.Version)
+	assert.Equal(t, "10.15.0.0/16", cf.Networks["cloud"].IPAM.Config[0].Subnet)
+	assert.Len(t, cf.Services, 2)
+
+	c := cf.Services["consul"]
+	assert.Equal(t, "consul:1.7.2", c.Image)
+	assert.Equal(t, map[string]string{"CONSUL_HTTP_ADDR": "http://localhost:8500"}, c.Environment)
+	assert.Equal(t, []string{"18500:8500", "18600:8600/udp"}, c.Ports)
+	assert.Equal(t, []string{"/tmp/config:/config", "data:/data"}, c.Volumes)
+	assert.Equal(t, "10.15.0.200", c.Networks["cloud"].IPv4Address)
+	assert.Equal(t, "0.5", c.CPUs)
+	assert.Equal(t, "512m", c.MemLimit)
+	assert.Contains(t, cf.Volumes, "data")
+
+	s := cf.Services["envoy"]
+	assert.Equal(t, "service:consul", s.NetworkMode)
+	assert.Equal(t, []string{"consul"}, s.DependsOn)
+}
+
+func TestExportComposeWithNoStateReturnsError(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	err := e.ExportCompose(filepath.Join(utils.StateDir(), "docker-compose.yaml"))
+	assert.Error(t, err)
+}
+
+var composeState = `
+{
+  "blueprint": null,
+  "resources": [
+	{
+      "name": "cloud",
+      "status": "applied",
+      "subnet": "10.15.0.0/16",
+      "type": "network"
+	},
+	{
+      "name": "consul",
+      "status": "applied",
+      "type": "container",
+      "image": {"name": "consul:1.7.2"},
+      "networks": [{"name": "network.cloud", "ip_address": "10.15.0.200"}],
+      "environment": [{"key": "CONSUL_HTTP_ADDR", "value": "http://localhost:8500"}],
+      "ports": [
+        {"local": "8500", "remote": "8500", "host": "18500"},
+        {"local": "8600", "remote": "8600", "host": "18600", "protocol": "udp"},
+        {"local": "8300", "remote": "8300"}
+      ],
+      "volumes": [
+        {"source": "/tmp/config", "destination": "/config"},
+        {"source": "data", "destination": "/data", "type": "volume"}
+      ],
+      "resources": {"cpu": 512, "memory": 512}
+	},
+	{
+      "name": "envoy",
+      "status": "applied",
+      "type": "sidecar",
+      "target": "container.consul",
+      "image": {"name": "envoyproxy/envoy:v1.14.1"}
+	},
+	{
+      "name": "k3s",
+      "status": "applied",
+      "driver": "k3s",
+      "type": "k8s_cluster"
+	}
+  ]
+}
+`
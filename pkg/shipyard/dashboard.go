@@ -0,0 +1,286 @@
+package shipyard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// DashboardHealthy is reported for resources which have been created and
+// have running containers
+const DashboardHealthy = "healthy"
+
+// DashboardUnhealthy is reported for resources which failed or have been
+// created but their containers are not running
+const DashboardUnhealthy = "unhealthy"
+
+// DashboardPending is reported for resources which have not been created
+const DashboardPending = "pending"
+
+// DashboardStatus is the state of the running environment shown by the
+// dashboard
+type DashboardStatus struct {
+	Title     string              `json:"title,omitempty"`
+	Intro     string              `json:"intro,omitempty"`
+	Resources []DashboardResource `json:"resources"` // sorted by type and name
+}
+
+// DashboardResource is a single resource shown by the dashboard
+type DashboardResource struct {
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	Status    string            `json:"status"`
+	Health    string            `json:"health"`
+	Endpoints []string          `json:"endpoints,omitempty"`
+	Outputs   map[string]string `json:"outputs,omitempty"`
+	Logs      string            `json:"logs,omitempty"` // path of the dashboard page showing the logs, empty when the resource has no containers
+}
+
+// Dashboard returns a handler which serves a web page showing the resources
+// in the running environment, their health, endpoints, outputs and links to
+// the container logs. The page is served at /, the DashboardStatus as JSON
+// at /api/status, and the logs for the first container of a resource at
+// /logs/[type].[name]
+func (e *EngineImpl) Dashboard() http.Handler {
+	m := http.NewServeMux()
+
+	// the engine reads the state for each request, requests are handled
+	// one at a time so the config is not replaced by a concurrent request
+	mu := sync.Mutex{}
+	locked := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(rw http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			h(rw, r)
+		}
+	}
+
+	m.HandleFunc("/api/status", locked(func(rw http.ResponseWriter, r *http.Request) {
+		s, err := e.DashboardStatus()
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(s)
+	}))
+
+	m.HandleFunc("/logs/", locked(e.dashboardLogs))
+
+	m.HandleFunc("/", locked(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(rw, r)
+			return
+		}
+
+		s, err := e.DashboardStatus()
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		dashboardTemplate.Execute(rw, s)
+	}))
+
+	return m
+}
+
+// DashboardStatus returns the resources in the state along with their
+// health, resources which run containers are healthy when at least one of
+// their containers is running
+func (e *EngineImpl) DashboardStatus() (*DashboardStatus, error) {
+	ds := &DashboardStatus{Resources: []DashboardResource{}}
+
+	sc, err := e.readState("")
+	if err == config.StateNotFoundError {
+		return ds, nil
+	}
+
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to read state: %w", err)
+	}
+
+	e.config = sc
+
+	if sc.Blueprint != nil {
+		ds.Title = sc.Blueprint.Title
+		ds.Intro = sc.Blueprint.Intro
+	}
+
+	for _, r := range sc.Resources {
+		dr := DashboardResource{
+			Name:      r.Info().Name,
+			Type:      string(r.Info().Type),
+			Status:    string(r.Info().Status),
+			Health:    DashboardPending,
+			Endpoints: resourceEndpoints(r),
+			Outputs:   resourceOutputs(r),
+		}
+
+		switch r.Info().Status {
+		case config.Applied:
+			dr.Health = DashboardHealthy
+		case config.Failed:
+			dr.Health = DashboardUnhealthy
+		}
+
+		if _, ok := resourceContainerName(r); ok {
+			dr.Logs = fmt.Sprintf("/logs/%s", resourceName(r))
+
+			// the health is unknown when Docker can not be reached
+			if running, err := e.containerRunning(context.Background(), r); dr.Health == DashboardHealthy && (err != nil || !running) {
+				dr.Health = DashboardUnhealthy
+			}
+		}
+
+		ds.Resources = append(ds.Resources, dr)
+	}
+
+	sort.Slice(ds.Resources, func(i, j int) bool {
+		if ds.Resources[i].Type == ds.Resources[j].Type {
+			return ds.Resources[i].Name < ds.Resources[j].Name
+		}
+
+		return ds.Resources[i].Type < ds.Resources[j].Type
+	})
+
+	return ds, nil
+}
+
+// containerRunning returns true when the resource has at least one running
+// container, stopped containers are not running
+func (e *EngineImpl) containerRunning(ctx context.Context, r config.Resource) (bool, error) {
+	name, _ := resourceContainerName(r)
+
+	cl, err := e.clientsFor(r)
+	if err != nil {
+		return false, err
+	}
+
+	cs, err := cl.ContainerTasks.FindContainers(ctx, name, r.Info().Type)
+	if err != nil {
+		return false, xerrors.Errorf("Unable to find containers for %s: %w", resourceName(r), err)
+	}
+
+	for _, c := range cs {
+		if c.Running {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// dashboardLogs writes the logs for the first container of the resource
+func (e *EngineImpl) dashboardLogs(rw http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ref := strings.TrimPrefix(r.URL.Path, "/logs/")
+
+	sc, err := e.readState("")
+	if err != nil {
+		http.Error(rw, "No resources are running", http.StatusNotFound)
+		return
+	}
+
+	e.config = sc
+
+	res, err := sc.FindResource(ref)
+	if err != nil {
+		http.Error(rw, fmt.Sprintf("Resource %s not found", ref), http.StatusNotFound)
+		return
+	}
+
+	name, ok := resourceContainerName(res)
+	if !ok {
+		http.Error(rw, fmt.Sprintf("Resource %s does not have any containers", ref), http.StatusNotFound)
+		return
+	}
+
+	cl, err := e.clientsFor(res)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ids, err := cl.ContainerTasks.FindContainerIDs(ctx, name, res.Info().Type)
+	if err != nil || len(ids) == 0 {
+		http.Error(rw, fmt.Sprintf("No containers are running for %s", ref), http.StatusNotFound)
+		return
+	}
+
+	rc, err := cl.ContainerTasks.ContainerLogs(ctx, ids[0], true, true)
+	if err != nil {
+		http.Error(rw, fmt.Sprintf("Unable to read logs for %s: %s", ref, err), http.StatusInternalServerError)
+		return
+	}
+	defer rc.Close()
+
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	// the logs contain a header for each line identifying the stream as
+	// Shipyard does not create containers with a TTY, remove these
+	_, err = stdcopy.StdCopy(rw, rw, rc)
+	if err != nil {
+		e.log.Debug("Unable to read container logs", "ref", ref, "error", err)
+	}
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	// endpoints without a scheme are HTTP servers on the local machine
+	"href": func(endpoint string) string {
+		if strings.Contains(endpoint, "://") {
+			return endpoint
+		}
+
+		return "http://" + endpoint
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta http-equiv="refresh" content="10">
+  <title>{{if .Title}}{{.Title}}{{else}}Shipyard{{end}}</title>
+  <style>
+    body { font-family: sans-serif; margin: 2em; color: #222; }
+    table { border-collapse: collapse; width: 100%; }
+    th, td { text-align: left; padding: 0.5em; border-bottom: 1px solid #ddd; vertical-align: top; }
+    .healthy { color: #2a9d3f; }
+    .unhealthy { color: #d1332e; }
+    .pending { color: #888; }
+  </style>
+</head>
+<body>
+  <h1>{{if .Title}}{{.Title}}{{else}}Shipyard{{end}}</h1>
+  {{if .Intro}}<p>{{.Intro}}</p>{{end}}
+  {{if .Resources}}
+  <table>
+    <tr><th>Resource</th><th>Health</th><th>Endpoints</th><th>Outputs</th><th>Logs</th></tr>
+    {{range .Resources}}
+    <tr>
+      <td>{{.Type}}.{{.Name}}</td>
+      <td class="{{.Health}}">{{.Health}}</td>
+      <td>{{range .Endpoints}}<a href="{{href .}}" target="_blank">{{.}}</a><br>{{end}}</td>
+      <td>{{range $k, $v := .Outputs}}{{$k}}: {{$v}}<br>{{end}}</td>
+      <td>{{if .Logs}}<a href="{{.Logs}}" target="_blank">logs</a>{{end}}</td>
+    </tr>
+    {{end}}
+  </table>
+  {{else}}
+  <p>No resources are running.</p>
+  {{end}}
+</body>
+</html>
+`))
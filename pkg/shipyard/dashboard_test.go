@@ -0,0 +1,121 @@
+package shipyard
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupDashboardTests() (Engine, *clientmocks.MockContainerTasks, func()) {
+	e, _, _, cleanup := setupTestsWithState(nil, usageState)
+
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	removeOn(&ct.Mock, "FindContainerIDs")
+	removeOn(&ct.Mock, "FindContainers")
+	ct.On("FindContainerIDs", "server.k3s", config.TypeK8sCluster).Return([]string{"k3s"}, nil)
+	ct.On("FindContainers", "server.k3s", config.TypeK8sCluster).Return([]config.ContainerInfo{{ID: "k3s", Running: true}}, nil)
+	ct.On("FindContainerIDs", "consul", config.TypeContainer).Return([]string{"consul"}, nil)
+	ct.On("FindContainers", "consul", config.TypeContainer).Return([]config.ContainerInfo{{ID: "consul", Running: true}}, nil)
+	ct.On("FindContainerIDs", "consul-2", config.TypeContainer).Return([]string{}, nil)
+	ct.On("FindContainers", "consul-2", config.TypeContainer).Return([]config.ContainerInfo{}, nil)
+
+	logs := &bytes.Buffer{}
+	stdcopy.NewStdWriter(logs, stdcopy.Stdout).Write([]byte("consul started\n"))
+	ct.On("ContainerLogs", "consul", true, true).Return(ioutil.NopCloser(logs), nil)
+
+	return e, ct, cleanup
+}
+
+func TestDashboardStatusReturnsHealthForResources(t *testing.T) {
+	e, _, cleanup := setupDashboardTests()
+	defer cleanup()
+
+	ds, err := e.DashboardStatus()
+	assert.NoError(t, err)
+
+	assert.Len(t, ds.Resources, 4)
+
+	assert.Equal(t, "consul", ds.Resources[0].Name)
+	assert.Equal(t, DashboardHealthy, ds.Resources[0].Health)
+	assert.Equal(t, "/logs/container.consul", ds.Resources[0].Logs)
+
+	// container is not running
+	assert.Equal(t, "consul-2", ds.Resources[1].Name)
+	assert.Equal(t, DashboardUnhealthy, ds.Resources[1].Health)
+
+	assert.Equal(t, "k3s", ds.Resources[2].Name)
+	assert.Equal(t, DashboardHealthy, ds.Resources[2].Health)
+
+	// networks do not have containers
+	assert.Equal(t, "cloud", ds.Resources[3].Name)
+	assert.Equal(t, DashboardHealthy, ds.Resources[3].Health)
+	assert.Empty(t, ds.Resources[3].Logs)
+}
+
+func TestDashboardStatusReturnsEmptyWhenNoState(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	ds, err := e.DashboardStatus()
+	assert.NoError(t, err)
+	assert.Len(t, ds.Resources, 0)
+}
+
+func TestDashboardServesStatusPageAndLogs(t *testing.T) {
+	e, _, cleanup := setupDashboardTests()
+	defer cleanup()
+
+	ts := httptest.NewServer(e.Dashboard())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/status")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	ds := &DashboardStatus{}
+	json.NewDecoder(resp.Body).Decode(ds)
+	assert.Len(t, ds.Resources, 4)
+
+	resp, err = http.Get(ts.URL + "/")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	d, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(d), `<a href="/logs/container.consul"`)
+
+	resp, err = http.Get(ts.URL + "/logs/container.consul")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	d, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal(t, "consul started\n", string(d))
+}
+
+func TestDashboardReturnsNotFoundForUnknownResource(t *testing.T) {
+	e, _, cleanup := setupDashboardTests()
+	defer cleanup()
+
+	ts := httptest.NewServer(e.Dashboard())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/logs/container.nginx")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Get(ts.URL + "/logs/network.cloud")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,309 @@
+package shipyard
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// demoSleep is used for waits and replay timing, replaced in tests
+var demoSleep = time.Sleep
+
+// demoStopTimeout is the time a container is given to stop by the stop chaos action
+const demoStopTimeout = 10 * time.Second
+
+// DemoOptions control how a demo is presented
+type DemoOptions struct {
+	// Output receives the description and the output of each step
+	Output io.Writer
+	// Input is read at each pause point, the demo continues when a line is read
+	Input io.Reader
+	// Unattended runs the demo without stopping at pause points
+	Unattended bool
+}
+
+// DemoRecording is the output of each step of a demo, a recording can be
+// replayed without the environment running
+type DemoRecording struct {
+	Name    string           `json:"name"`
+	Started time.Time        `json:"started"`
+	Steps   []DemoStepResult `json:"steps"`
+}
+
+// DemoStepResult is the outcome of a single step of a demo
+type DemoStepResult struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Pause       bool    `json:"pause,omitempty"`
+	Output      string  `json:"output,omitempty"`
+	Duration    float64 `json:"duration_seconds"` // time taken to run the step including any wait
+	Error       string  `json:"error,omitempty"`
+}
+
+// ToJSON returns the recording as an indented JSON document
+func (d *DemoRecording) ToJSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// DemoRecordingFromJSON loads a recording created with ToJSON
+func DemoRecordingFromJSON(data []byte) (*DemoRecording, error) {
+	d := &DemoRecording{}
+
+	err := json.Unmarshal(data, d)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to read demo recording: %w", err)
+	}
+
+	return d, nil
+}
+
+// Replay writes the recorded output of each step to the output, steps are
+// shown at the same pace as they were recorded and the demo stops at the
+// recorded pause points unless the options are unattended
+func (d *DemoRecording) Replay(o DemoOptions) error {
+	p := newDemoPresenter(o)
+
+	for _, s := range d.Steps {
+		err := p.start(s.Name, s.Description, s.Pause)
+		if err != nil {
+			return err
+		}
+
+		demoSleep(time.Duration(s.Duration * float64(time.Second)))
+
+		p.output(s.Output)
+
+		if s.Error != "" {
+			return xerrors.Errorf("Step %s failed: %s", s.Name, s.Error)
+		}
+	}
+
+	return nil
+}
+
+// RunDemo runs the steps of the named demo from the blueprint of the running
+// environment. The demo stops at the first step which fails, the steps which
+// were run are returned in the recording along with the error
+func (e *EngineImpl) RunDemo(name string, o DemoOptions) (*DemoRecording, error) {
+	sc, err := e.readState("")
+	if err != nil {
+		return nil, xerrors.Errorf("No resources are running, start a blueprint before running a demo: %w", err)
+	}
+
+	e.config = sc
+
+	var demo *config.Demo
+	if sc.Blueprint != nil {
+		for i, d := range sc.Blueprint.Demos {
+			if d.Name == name {
+				demo = &sc.Blueprint.Demos[i]
+			}
+		}
+	}
+
+	if demo == nil {
+		return nil, xerrors.Errorf("Demo %s is not defined in the blueprint", name)
+	}
+
+	rec := &DemoRecording{Name: name, Started: time.Now(), Steps: []DemoStepResult{}}
+	p := newDemoPresenter(o)
+
+	for _, s := range demo.Steps {
+		err := p.start(s.Name, s.Description, s.Pause)
+		if err != nil {
+			return rec, err
+		}
+
+		st := time.Now()
+		out, err := e.runDemoStep(context.Background(), s)
+
+		p.output(out)
+
+		if err == nil && s.Wait != "" {
+			d, _ := time.ParseDuration(s.Wait)
+			demoSleep(d)
+		}
+
+		sr := DemoStepResult{
+			Name:        s.Name,
+			Description: s.Description,
+			Pause:       s.Pause,
+			Output:      out,
+			Duration:    time.Since(st).Seconds(),
+		}
+
+		if err != nil {
+			sr.Error = err.Error()
+			rec.Steps = append(rec.Steps, sr)
+
+			return rec, xerrors.Errorf("Step %s failed: %w", s.Name, err)
+		}
+
+		rec.Steps = append(rec.Steps, sr)
+	}
+
+	return rec, nil
+}
+
+// runDemoStep runs the exec, http, and chaos actions for a step returning
+// the combined output
+func (e *EngineImpl) runDemoStep(ctx context.Context, s config.DemoStep) (string, error) {
+	out := &bytes.Buffer{}
+
+	if s.Exec != nil {
+		id, cl, err := e.demoTarget(ctx, s.Exec.Target)
+		if err != nil {
+			return out.String(), err
+		}
+
+		err = cl.ContainerTasks.ExecuteCommand(ctx, id, s.Exec.Command, nil, "/", out)
+		if err != nil {
+			return out.String(), xerrors.Errorf("Unable to run command %v in %s: %w", s.Exec.Command, s.Exec.Target, err)
+		}
+	}
+
+	if s.HTTP != nil {
+		err := e.demoHTTP(s.HTTP, out)
+		if err != nil {
+			return out.String(), err
+		}
+	}
+
+	if s.Chaos != nil {
+		id, cl, err := e.demoTarget(ctx, s.Chaos.Target)
+		if err != nil {
+			return out.String(), err
+		}
+
+		switch s.Chaos.Action {
+		case config.ChaosStop:
+			err = cl.ContainerTasks.StopContainer(ctx, id, demoStopTimeout)
+		case config.ChaosStart:
+			err = cl.ContainerTasks.StartContainer(ctx, id)
+		case config.ChaosDisconnect:
+			err = cl.ContainerTasks.DetachNetwork(ctx, s.Chaos.Network, id)
+		case config.ChaosConnect:
+			err = cl.ContainerTasks.AttachNetwork(ctx, s.Chaos.Network, id)
+		default:
+			err = fmt.Errorf("unknown action %s", s.Chaos.Action)
+		}
+
+		if err != nil {
+			return out.String(), xerrors.Errorf("Unable to %s %s: %w", s.Chaos.Action, s.Chaos.Target, err)
+		}
+	}
+
+	return out.String(), nil
+}
+
+// demoTarget returns the id of the container for the target resource and the
+// clients for the Docker host the container runs on
+func (e *EngineImpl) demoTarget(ctx context.Context, target string) (string, *Clients, error) {
+	r, err := e.config.FindResource(target)
+	if err != nil {
+		return "", nil, xerrors.Errorf("Unable to find target %s: %w", target, err)
+	}
+
+	name, ok := resourceContainerName(r)
+	if !ok {
+		return "", nil, xerrors.Errorf("Target %s does not run in a container", target)
+	}
+
+	cl, err := e.clientsFor(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	ids, err := cl.ContainerTasks.FindContainerIDs(ctx, name, r.Info().Type)
+	if err != nil || len(ids) == 0 {
+		return "", nil, xerrors.Errorf("Unable to find container for target %s: %v", target, err)
+	}
+
+	return ids[0], cl, nil
+}
+
+// demoHTTP makes the request and writes the status and the response body
+// to out, error status codes are shown in the output and are not a failure
+func (e *EngineImpl) demoHTTP(h *config.DemoHTTP, out io.Writer) error {
+	method := h.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequest(method, h.URL, strings.NewReader(h.Body))
+	if err != nil {
+		return xerrors.Errorf("Unable to create request for %s: %w", h.URL, err)
+	}
+
+	for k, v := range h.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.clients.HTTP.Do(req)
+	if err != nil {
+		return xerrors.Errorf("Unable to call %s: %w", h.URL, err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	fmt.Fprintf(out, "%s %s\n%s\n", method, resp.Status, body)
+
+	return nil
+}
+
+// demoPresenter writes the steps of a demo to the output and waits at
+// pause points
+type demoPresenter struct {
+	o  DemoOptions
+	in *bufio.Reader
+}
+
+func newDemoPresenter(o DemoOptions) *demoPresenter {
+	if o.Output == nil {
+		o.Output = ioutil.Discard
+	}
+
+	p := &demoPresenter{o: o}
+	if o.Input != nil {
+		p.in = bufio.NewReader(o.Input)
+	}
+
+	return p
+}
+
+// start shows the step and waits for the presenter when the step pauses
+func (p *demoPresenter) start(name, description string, pause bool) error {
+	fmt.Fprintf(p.o.Output, "\n==> %s\n", name)
+	if description != "" {
+		fmt.Fprintln(p.o.Output, description)
+	}
+
+	if !pause || p.o.Unattended || p.in == nil {
+		return nil
+	}
+
+	fmt.Fprint(p.o.Output, "\nPress enter to continue")
+
+	_, err := p.in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return xerrors.Errorf("Unable to read input: %w", err)
+	}
+
+	return nil
+}
+
+func (p *demoPresenter) output(out string) {
+	if out != "" {
+		fmt.Fprintln(p.o.Output, strings.TrimRight(out, "\n"))
+	}
+}
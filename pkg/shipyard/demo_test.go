@@ -0,0 +1,160 @@
+package shipyard
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupDemoTests(t *testing.T, execErr error) (Engine, *clientmocks.MockContainerTasks, *[]time.Duration, func()) {
+	e, _, _, cleanup := setupTestsWithState(nil, demoState)
+
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	removeOn(&ct.Mock, "FindContainerIDs")
+	ct.On("FindContainerIDs", "consul", config.TypeContainer).Return([]string{"abc"}, nil)
+	ct.On("ExecuteCommand", "abc", []string{"consul", "members"}, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			args.Get(4).(io.Writer).Write([]byte("consul  10.5.0.2:8301  alive\n"))
+		}).
+		Return(execErr)
+	ct.On("DetachNetwork", "network.cloud", "abc").Return(nil)
+
+	hm := &clientmocks.MockHTTP{}
+	hm.On("Do", mock.Anything).Return(
+		&http.Response{Status: "200 OK", StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(`"10.5.0.2:8300"`))},
+		nil,
+	)
+	e.(*EngineImpl).clients.HTTP = hm
+
+	sleeps := &[]time.Duration{}
+	oldSleep := demoSleep
+	demoSleep = func(d time.Duration) { *sleeps = append(*sleeps, d) }
+
+	return e, ct, sleeps, func() {
+		demoSleep = oldSleep
+		cleanup()
+	}
+}
+
+func TestRunDemoRunsStepsAndRecordsOutput(t *testing.T) {
+	e, ct, sleeps, cleanup := setupDemoTests(t, nil)
+	defer cleanup()
+
+	out := &bytes.Buffer{}
+	rec, err := e.RunDemo("failover", DemoOptions{Output: out, Input: strings.NewReader("\n")})
+	assert.NoError(t, err)
+
+	assert.Len(t, rec.Steps, 3)
+	assert.Contains(t, rec.Steps[0].Output, "alive")
+	assert.Contains(t, rec.Steps[1].Output, "10.5.0.2:8300")
+	assert.Empty(t, rec.Steps[2].Error)
+
+	assert.Contains(t, out.String(), "Consul is running with a single server")
+	assert.Contains(t, out.String(), "Press enter to continue")
+
+	ct.AssertCalled(t, "DetachNetwork", "network.cloud", "abc")
+	assert.Equal(t, []time.Duration{5 * time.Second}, *sleeps)
+}
+
+func TestRunDemoUnattendedDoesNotPause(t *testing.T) {
+	e, _, _, cleanup := setupDemoTests(t, nil)
+	defer cleanup()
+
+	out := &bytes.Buffer{}
+	_, err := e.RunDemo("failover", DemoOptions{Output: out, Input: strings.NewReader(""), Unattended: true})
+	assert.NoError(t, err)
+
+	assert.NotContains(t, out.String(), "Press enter to continue")
+}
+
+func TestRunDemoStopsAtFailingStep(t *testing.T) {
+	e, ct, _, cleanup := setupDemoTests(t, fmt.Errorf("boom"))
+	defer cleanup()
+
+	rec, err := e.RunDemo("failover", DemoOptions{Unattended: true})
+	assert.Error(t, err)
+
+	assert.Len(t, rec.Steps, 1)
+	assert.Contains(t, rec.Steps[0].Error, "boom")
+
+	ct.AssertNotCalled(t, "DetachNetwork", mock.Anything, mock.Anything)
+}
+
+func TestRunDemoReturnsErrorForUnknownDemo(t *testing.T) {
+	e, _, _, cleanup := setupDemoTests(t, nil)
+	defer cleanup()
+
+	_, err := e.RunDemo("missing", DemoOptions{})
+	assert.Error(t, err)
+}
+
+func TestDemoRecordingReplaysOutput(t *testing.T) {
+	e, _, sleeps, cleanup := setupDemoTests(t, nil)
+	defer cleanup()
+
+	rec, err := e.RunDemo("failover", DemoOptions{Unattended: true})
+	assert.NoError(t, err)
+
+	d, err := rec.ToJSON()
+	assert.NoError(t, err)
+
+	loaded, err := DemoRecordingFromJSON(d)
+	assert.NoError(t, err)
+
+	*sleeps = []time.Duration{}
+	out := &bytes.Buffer{}
+
+	err = loaded.Replay(DemoOptions{Output: out, Unattended: true})
+	assert.NoError(t, err)
+
+	assert.Contains(t, out.String(), "alive")
+	assert.Contains(t, out.String(), "10.5.0.2:8300")
+	assert.Len(t, *sleeps, 3)
+}
+
+var demoState = `
+{
+  "blueprint": {
+    "title": "Consul",
+    "demos": [
+      {
+        "name": "failover",
+        "steps": [
+          {
+            "name": "members",
+            "description": "Consul is running with a single server",
+            "pause": true,
+            "exec": {"target": "container.consul", "command": ["consul", "members"]}
+          },
+          {
+            "name": "leader",
+            "http": {"url": "http://localhost:18500/v1/status/leader"}
+          },
+          {
+            "name": "partition",
+            "chaos": {"target": "container.consul", "action": "disconnect", "network": "network.cloud"},
+            "wait": "5s"
+          }
+        ]
+      }
+    ]
+  },
+  "resources": [
+	{
+      "name": "consul",
+      "status": "applied",
+      "type": "container"
+	}
+  ]
+}
+`
@@ -0,0 +1,71 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DestroyOptions control how Destroy removes the resources
+type DestroyOptions struct {
+	// All destroys every resource in the state, when false only the
+	// resources defined in the blueprint at path are destroyed
+	All bool
+
+	// ContinueOnError destroys the remaining resources when a resource
+	// fails, by default the resources which a failed resource depends on
+	// are not destroyed
+	ContinueOnError bool
+}
+
+// DestroyError is returned by Destroy when one or more resources could not
+// be destroyed
+type DestroyError struct {
+	Errors    map[string]error // error for each resource which failed, keyed by [type].[name]
+	Remaining []string         // resources which were not removed, includes the failed resources and the resources they depend on
+}
+
+func (d *DestroyError) Error() string {
+	names := []string{}
+	for n := range d.Errors {
+		names = append(names, n)
+	}
+
+	sort.Strings(names)
+
+	v := []string{}
+	for _, n := range names {
+		v = append(v, fmt.Sprintf("  %s: %s", n, d.Errors[n]))
+	}
+
+	return fmt.Sprintf(
+		"Unable to destroy %d resources:\n%s\nResources not removed: %s",
+		len(d.Errors),
+		strings.Join(v, "\n"),
+		strings.Join(d.Remaining, ", "),
+	)
+}
+
+// DestroyWithOptions destroys the resources in the same way as Destroy, when
+// ContinueOnError is set a failed resource does not stop the other resources
+// being destroyed. A *DestroyError is returned listing every failure
+func (e *EngineImpl) DestroyWithOptions(ctx context.Context, path string, o DestroyOptions) (err error) {
+	err = e.lockState(path)
+	if err != nil {
+		return err
+	}
+	defer e.unlockState(&err)
+
+	started := time.Now()
+
+	e.operationStarted("destroy")
+
+	err = e.destroy(ctx, path, o.All, o.ContinueOnError)
+	e.audit(AuditDestroy, path, started, err)
+	e.notify(AuditDestroy, path, started, err)
+	e.operationDone("destroy", started, err)
+
+	return err
+}
@@ -0,0 +1,45 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+)
+
+func TestDestroyFailReturnsDestroyError(t *testing.T) {
+	e, _, _, cleanup := setupTests(map[string]error{"k3s": fmt.Errorf("boom")})
+	defer cleanup()
+
+	err := e.Destroy(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster", true)
+
+	de := &DestroyError{}
+	assert.True(t, xerrors.As(err, &de))
+	assert.Len(t, de.Errors, 1)
+	assert.EqualError(t, de.Errors["k8s_cluster.k3s"], "boom")
+	assert.Equal(t, []string{"k8s_cluster.k3s", "network.cloud"}, de.Remaining)
+	assert.Contains(t, err.Error(), "Resources not removed: k8s_cluster.k3s, network.cloud")
+}
+
+func TestDestroyWithContinueOnErrorDestroysRemainingResources(t *testing.T) {
+	e, _, mp, cleanup := setupTests(map[string]error{"k3s": fmt.Errorf("boom")})
+	defer cleanup()
+
+	err := e.DestroyWithOptions(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster", DestroyOptions{All: true, ContinueOnError: true})
+
+	de := &DestroyError{}
+	assert.True(t, xerrors.As(err, &de))
+	assert.Equal(t, []string{"k8s_cluster.k3s"}, de.Remaining)
+
+	testAssertMethodCalled(t, mp, "Destroy", 6)
+
+	// only the failed resource is kept in the state
+	sc := config.New()
+	err = sc.FromJSON(e.(*EngineImpl).stateFile())
+	assert.NoError(t, err)
+	assert.Len(t, sc.Resources, 1)
+	assert.Equal(t, "k3s", sc.Resources[0].Info().Name)
+}
@@ -0,0 +1,128 @@
+package shipyard
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// devContainer is the subset of the VS Code devcontainer.json schema
+// used to open a workspace inside a running environment
+type devContainer struct {
+	Name           string            `json:"name"`
+	Image          string            `json:"image"`
+	RunArgs        []string          `json:"runArgs,omitempty"`
+	ContainerEnv   map[string]string `json:"containerEnv,omitempty"`
+	Mounts         []string          `json:"mounts,omitempty"`
+	ForwardPorts   []interface{}     `json:"forwardPorts,omitempty"`
+	OverrideCmd    bool              `json:"overrideCommand"`
+	ShutdownAction string            `json:"shutdownAction"`
+}
+
+// generateDevContainer creates a devcontainer definition which uses the image,
+// environment and volumes of the workspace container. The devcontainer joins
+// the same networks as the workspace so that it can reach the other containers
+// in the environment, ports for those containers are forwarded
+func generateDevContainer(c *config.Config, workspace string) ([]byte, error) {
+	r, err := c.FindResource(workspace)
+	if err != nil {
+		return nil, xerrors.Errorf("Workspace container %s is not running: %w", workspace, err)
+	}
+
+	co, ok := r.(*config.Container)
+	if !ok {
+		return nil, xerrors.Errorf("Invalid resource type %s, only resources type container are supported", workspace)
+	}
+
+	dc := devContainer{
+		Name:  co.Name,
+		Image: co.Image.Name,
+		// the workspace container is long running, the environment is managed by Shipyard
+		OverrideCmd:    false,
+		ShutdownAction: "none",
+	}
+
+	if c.Blueprint != nil && c.Blueprint.Title != "" {
+		dc.Name = c.Blueprint.Title
+	}
+
+	networks := map[string]bool{}
+	for _, n := range co.Networks {
+		name := strings.TrimPrefix(n.Name, "network.")
+		networks[name] = true
+
+		dc.RunArgs = append(dc.RunArgs, fmt.Sprintf("--network=%s", name))
+	}
+
+	if len(co.Environment) > 0 {
+		dc.ContainerEnv = map[string]string{}
+		for _, kv := range co.Environment {
+			dc.ContainerEnv[kv.Key] = kv.Value
+		}
+	}
+
+	for _, v := range co.Volumes {
+		t := v.Type
+		if t == "" {
+			t = "bind"
+		}
+
+		if t == "tmpfs" {
+			dc.Mounts = append(dc.Mounts, fmt.Sprintf("target=%s,type=tmpfs", v.Destination))
+			continue
+		}
+
+		dc.Mounts = append(dc.Mounts, fmt.Sprintf("source=%s,target=%s,type=%s", v.Source, v.Destination, t))
+	}
+
+	// ports on the workspace are forwarded directly, ports on the other
+	// containers are forwarded using their name on the shared network
+	for _, p := range co.Ports {
+		dc.ForwardPorts = append(dc.ForwardPorts, devContainerPort(p.Local))
+	}
+
+	others := []string{}
+	for _, r := range c.Resources {
+		o, ok := r.(*config.Container)
+		if !ok || o.Name == co.Name || !devContainerSharesNetwork(o, networks) {
+			continue
+		}
+
+		for _, p := range o.Ports {
+			others = append(others, fmt.Sprintf("%s:%s", utils.FQDN(o.Name, string(o.Type)), p.Local))
+		}
+	}
+
+	sort.Strings(others)
+	for _, o := range others {
+		dc.ForwardPorts = append(dc.ForwardPorts, o)
+	}
+
+	return json.MarshalIndent(dc, "", "  ")
+}
+
+func devContainerSharesNetwork(co *config.Container, networks map[string]bool) bool {
+	for _, n := range co.Networks {
+		if networks[strings.TrimPrefix(n.Name, "network.")] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// devContainerPort returns numeric ports as numbers, the devcontainer
+// schema treats strings as host:port pairs
+func devContainerPort(p string) interface{} {
+	var i int
+	if _, err := fmt.Sscanf(p, "%d", &i); err == nil {
+		return i
+	}
+
+	return p
+}
@@ -0,0 +1,89 @@
+package shipyard
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportDevContainerUsesWorkspaceContainer(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, devContainerState)
+	defer cleanup()
+
+	out := filepath.Join(utils.StateDir(), "devcontainer.json")
+	err := e.ExportDevContainer("container.tools", out)
+	assert.NoError(t, err)
+
+	d, err := ioutil.ReadFile(out)
+	assert.NoError(t, err)
+
+	dc := devContainer{}
+	err = json.Unmarshal(d, &dc)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Consul Demo", dc.Name)
+	assert.Equal(t, "shipyardrun/tools:latest", dc.Image)
+	assert.Equal(t, []string{"--network=cloud"}, dc.RunArgs)
+	assert.Equal(t, map[string]string{"CONSUL_HTTP_ADDR": "http://consul.container.shipyard.run:8500"}, dc.ContainerEnv)
+	assert.Equal(t, []string{"source=/tmp/work,target=/work,type=bind"}, dc.Mounts)
+	assert.Equal(t, []interface{}{float64(8080), "consul.container.shipyard.run:8500"}, dc.ForwardPorts)
+}
+
+func TestExportDevContainerWithInvalidTypeReturnsError(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, devContainerState)
+	defer cleanup()
+
+	err := e.ExportDevContainer("network.cloud", filepath.Join(utils.StateDir(), "devcontainer.json"))
+	assert.Error(t, err)
+}
+
+func TestExportDevContainerWithMissingContainerReturnsError(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, devContainerState)
+	defer cleanup()
+
+	err := e.ExportDevContainer("container.missing", filepath.Join(utils.StateDir(), "devcontainer.json"))
+	assert.Error(t, err)
+}
+
+var devContainerState = `
+{
+  "blueprint": {"title": "Consul Demo"},
+  "resources": [
+	{
+      "name": "cloud",
+      "status": "applied",
+      "subnet": "10.15.0.0/16",
+      "type": "network"
+	},
+	{
+      "name": "consul",
+      "status": "applied",
+      "type": "container",
+      "image": {"name": "consul:1.7.2"},
+      "networks": [{"name": "network.cloud"}],
+      "ports": [{"local": "8500", "remote": "8500", "host": "18500"}]
+	},
+	{
+      "name": "isolated",
+      "status": "applied",
+      "type": "container",
+      "image": {"name": "nginx"},
+      "ports": [{"local": "80", "remote": "80"}]
+	},
+	{
+      "name": "tools",
+      "status": "applied",
+      "type": "container",
+      "image": {"name": "shipyardrun/tools:latest"},
+      "networks": [{"name": "network.cloud"}],
+      "environment": [{"key": "CONSUL_HTTP_ADDR", "value": "http://consul.container.shipyard.run:8500"}],
+      "volumes": [{"source": "/tmp/work", "destination": "/work"}],
+      "ports": [{"local": "8080", "remote": "8080"}]
+	}
+  ]
+}
+`
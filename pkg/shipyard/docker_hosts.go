@@ -0,0 +1,106 @@
+package shipyard
+
+import (
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// defines a function which creates the clients for a docker_host
+// enables the replacement in tests to inject mocks
+type hostClientsFunc func(h *config.DockerHost, cl *Clients) (*Clients, error)
+
+// defines a function which creates Kubernetes and Nomad clients that can be
+// configured for a single cluster without changing the shared clients
+// enables the replacement in tests to inject mocks
+type clusterClientsFunc func(cl *Clients) *Clients
+
+// clientsFor returns the clients used to create or destroy the resource,
+// resources which are pinned to a docker_host use clients connected to that
+// host, clients are created once for each host
+func (e *EngineImpl) clientsFor(r config.Resource) (*Clients, error) {
+	cl, err := e.clientsForHost(config.DockerHostFor(r))
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to get clients for resource %s.%s: %w", r.Info().Type, r.Info().Name, err)
+	}
+
+	return cl, nil
+}
+
+// clientsForHost returns the clients connected to the named docker_host, an
+// empty name returns the clients for the local Docker engine
+func (e *EngineImpl) clientsForHost(name string) (*Clients, error) {
+	return e.clientsForHostIn(e.config, name)
+}
+
+// clientsForHostIn returns the clients for the named docker_host defined in
+// the config c, e.g. the state, rather than the config for the current apply
+func (e *EngineImpl) clientsForHostIn(c *config.Config, name string) (*Clients, error) {
+	if name == "" {
+		return e.clients, nil
+	}
+
+	hr, err := c.FindResource(name)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to find Docker host %s: %w", name, err)
+	}
+
+	h, ok := hr.(*config.DockerHost)
+	if !ok {
+		return nil, xerrors.Errorf("%s must reference a docker_host", name)
+	}
+
+	e.sync.Lock()
+	defer e.sync.Unlock()
+
+	if cl, ok := e.hostClients[name]; ok {
+		return cl, nil
+	}
+
+	cl, err := e.getHostClients(h, e.clients)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to create client for Docker host %s: %w", name, err)
+	}
+
+	if e.hostClients == nil {
+		e.hostClients = map[string]*Clients{}
+	}
+
+	e.hostClients[name] = cl
+
+	return cl, nil
+}
+
+// generateHostClientsImpl returns a copy of the clients where the Docker
+// clients are connected to the given host
+func generateHostClientsImpl(h *config.DockerHost, cl *Clients) (*Clients, error) {
+	dc, err := clients.NewDocker(clients.DockerConfig{
+		Host:      h.Host,
+		Context:   h.Context,
+		CertPath:  h.CertPath,
+		TLSVerify: h.TLSVerify,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	hc := *cl
+	hc.Docker = dc
+	hc.ContainerTasks = clients.NewDockerTasks(dc, cl.ImageLog, cl.Logger)
+
+	return &hc, nil
+}
+
+// generateClusterClientsImpl returns a copy of the clients with new
+// Kubernetes and Nomad clients, SetConfig on the copy does not change the
+// config used by concurrent operations on other clusters
+func generateClusterClientsImpl(cl *Clients) *Clients {
+	ccl := *cl
+	ccl.Kubernetes = clients.NewKubernetes(60*time.Second, cl.Logger)
+	ccl.Nomad = clients.NewNomad(cl.HTTP, 1*time.Second, cl.Logger)
+
+	return &ccl
+}
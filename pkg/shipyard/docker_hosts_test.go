@@ -0,0 +1,92 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupDockerHostTests(t *testing.T, e Engine, err error) (string, *[]string) {
+	dir, _ := ioutil.TempDir("", "")
+	ioutil.WriteFile(filepath.Join(dir, "hosts.hcl"), []byte(dockerHostConfig), 0644)
+
+	created := &[]string{}
+	e.(*EngineImpl).getHostClients = func(h *config.DockerHost, cl *Clients) (*Clients, error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		*created = append(*created, h.Name)
+		hc := *cl
+		return &hc, err
+	}
+
+	return dir, created
+}
+
+func TestApplyCreatesClientsForDockerHostOnce(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, created := setupDockerHostTests(t, e, nil)
+	defer os.RemoveAll(dir)
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"lab1"}, *created)
+	testAssertMethodCalled(t, mp, "Create", 4)
+
+	// images for remote hosts are pulled by the provider not the engine
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	ct.AssertCalled(t, "PullImage", config.Image{Name: "consul:1.8.0"}, false)
+	ct.AssertNotCalled(t, "PullImage", config.Image{Name: "consul:1.8.1"}, false)
+}
+
+func TestApplyFailsResourcesWhenDockerHostClientFails(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, _ := setupDockerHostTests(t, e, fmt.Errorf("boom"))
+	defer os.RemoveAll(dir)
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.Error(t, err)
+
+	r, _ := e.(*EngineImpl).config.FindResource("docker_host.lab1")
+	assert.Equal(t, config.Failed, r.Info().Status)
+}
+
+var dockerHostConfig = `
+docker_host "lab1" {
+  host = "tcp://10.5.0.10:2376"
+}
+
+container "remote" {
+  docker_host = "docker_host.lab1"
+
+  image {
+    name = "consul:1.8.1"
+  }
+}
+
+container "local" {
+  image {
+    name = "consul:1.8.0"
+  }
+}
+
+sidecar "envoy" {
+  target = "container.remote"
+
+  image {
+    name = "consul:1.8.1"
+  }
+}
+`
@@ -0,0 +1,111 @@
+package shipyard
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/terraform/dag"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// Actions recorded in the Result for each resource by a dry run
+const (
+	DryRunCreate  = "create"
+	DryRunReplace = "replace"
+	DryRunUpdate  = "update"
+)
+
+// dryRun walks the dependency graph for the blueprint at path in the same
+// order as Apply and returns the resources which would be created, the
+// blueprint is checked against the policy and quota but no providers are
+// called and the state is not changed. Only targeted resources are returned
+// and the resources in replace are reported as replaced
+func (e *EngineImpl) dryRun(path string, replace []string) ([]config.Resource, error) {
+	res := e.startResult("dry-run")
+
+	d, err := e.readConfig(path)
+	if err != nil {
+		res.finish(err)
+		return nil, err
+	}
+
+	err = e.resolveTargets()
+	if err != nil {
+		res.finish(err)
+		return nil, err
+	}
+
+	// replaced resources are only marked in memory as the state file is
+	// not changed by a dry run
+	for _, n := range replace {
+		r, err := e.config.FindResource(n)
+		if err == nil && r.Info().Status == config.PendingCreation {
+			err = xerrors.Errorf("Resource %s is not in the state", n)
+		}
+
+		if err != nil {
+			err = xerrors.Errorf("Unable to replace resource: %w", err)
+			res.finish(err)
+			return nil, err
+		}
+
+		r.Info().Status = config.PendingModification
+	}
+
+	err = e.checkPolicy()
+	if err != nil {
+		res.finish(err)
+		return nil, err
+	}
+
+	err = e.checkQuota()
+	if err != nil {
+		res.finish(err)
+		return nil, err
+	}
+
+	pending := []config.Resource{}
+	m := sync.Mutex{}
+
+	w := dag.Walker{}
+	w.Callback = func(v dag.Vertex) (diags tfdiags.Diagnostics) {
+		r, ok := v.(config.Resource)
+		if !ok || !pendingApply(r) || !e.targeted(r) {
+			return nil
+		}
+
+		// the provider is created to check the resource is supported
+		if e.getProvider(r, e.clients) == nil {
+			return diags.Append(fmt.Errorf("Unable to create provider for resource Name: %s, Type: %s", r.Info().Name, r.Info().Type))
+		}
+
+		action := DryRunCreate
+		switch {
+		case r.Info().Status == config.PendingModification || r.Info().Status == config.Failed:
+			action = DryRunReplace
+		case reapply(r):
+			action = DryRunUpdate
+		}
+
+		e.log.Info("Dry run", "action", action, "ref", resourceName(r))
+		res.addAction(r, action)
+
+		m.Lock()
+		pending = append(pending, r)
+		m.Unlock()
+
+		return nil
+	}
+
+	w.Update(d)
+	tf := w.Wait()
+	if tf.Err() != nil {
+		err = tf.Err()
+	}
+
+	res.finish(err)
+
+	return pending, err
+}
@@ -0,0 +1,113 @@
+package shipyard
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers/mocks"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestApplyWithDryRunDoesNotCallProviders(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, hm, bm := setupBrowserTests(t, e, nil)
+	defer os.RemoveAll(dir)
+
+	res, err := e.ApplyWithOptions(context.Background(), dir, ApplyOptions{DryRun: true})
+	assert.NoError(t, err)
+	assert.Len(t, res, 2)
+
+	testAssertMethodCalled(t, mp, "Create", 0)
+	testAssertMethodCalled(t, mp, "Destroy", 0)
+	hm.AssertNotCalled(t, "HealthCheckHTTP", mock.Anything, mock.Anything)
+	bm.AssertNotCalled(t, "OpenBrowser", mock.Anything)
+
+	assert.Equal(t, "dry-run", e.Result().Action)
+	assert.Len(t, e.Result().Resources, 2)
+
+	// the state is not written
+	assert.NoFileExists(t, utils.StatePath())
+}
+
+func TestApplyWithDryRunReturnsFailedResources(t *testing.T) {
+	e, _, mp, cleanup := setupTestsWithState(nil, failedState)
+	defer cleanup()
+
+	res, err := e.ApplyWithOptions(context.Background(), "", ApplyOptions{DryRun: true})
+	assert.NoError(t, err)
+
+	assert.Len(t, res, 1)
+	assert.Equal(t, config.Failed, res[0].Info().Status)
+
+	testAssertMethodCalled(t, mp, "Create", 0)
+}
+
+func TestApplyWithDryRunRecordsActions(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, failedState)
+	defer cleanup()
+
+	_, err := e.ApplyWithOptions(context.Background(), "", ApplyOptions{DryRun: true})
+	assert.NoError(t, err)
+
+	assert.Len(t, e.Result().Resources, 1)
+	assert.Equal(t, DryRunReplace, e.Result().Resources[0].Action)
+}
+
+func TestApplyWithDryRunOnlyReturnsTargets(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	e.(*EngineImpl).targets = map[string]bool{"container.consul": true}
+
+	res, err := e.ApplyWithOptions(context.Background(), dir, ApplyOptions{DryRun: true})
+	assert.NoError(t, err)
+
+	names := []string{}
+	for _, r := range res {
+		names = append(names, resourceName(r))
+	}
+
+	assert.ElementsMatch(t, []string{"network.cloud", "container.consul"}, names)
+	assert.Equal(t, 0, providerCalls(mp, "consul", "Create"))
+}
+
+func TestApplyWithDryRunReportsReplacedResources(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	before, err := ioutil.ReadFile(utils.StatePath())
+	assert.NoError(t, err)
+
+	*mp = []*mocks.MockProvider{}
+
+	res, err := e.ApplyWithOptions(context.Background(), dir, ApplyOptions{DryRun: true, Replace: []string{"container.consul"}})
+	assert.NoError(t, err)
+
+	assert.Len(t, res, 1)
+	assert.Equal(t, "consul", res[0].Info().Name)
+	assert.Equal(t, DryRunReplace, e.Result().Resources[0].Action)
+	testAssertMethodCalled(t, mp, "Destroy", 0)
+
+	// the resource is not tainted in the state
+	after, err := ioutil.ReadFile(utils.StatePath())
+	assert.NoError(t, err)
+	assert.Equal(t, string(before), string(after))
+}
+
+func TestApplyWithDryRunReplaceReturnsErrorWhenResourceNotInState(t *testing.T) {
+	e, dir, _, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	_, err := e.ApplyWithOptions(context.Background(), dir, ApplyOptions{DryRun: true, Replace: []string{"container.consul"}})
+	assert.Error(t, err)
+}
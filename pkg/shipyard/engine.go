@@ -4,9 +4,15 @@ import (
 
 	// "fmt"
 
+	"context"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -30,20 +36,68 @@ type Clients struct {
 	Helm           clients.Helm
 	HTTP           clients.HTTP
 	Nomad          clients.Nomad
+	Consul         clients.Consul
+	Vault          clients.Vault
 	Command        clients.Command
 	Logger         hclog.Logger
 	Getter         clients.Getter
 	Browser        clients.System
 	ImageLog       clients.ImageLog
+	ImageBuilder   clients.ImageBuilder
+	Tunnel         clients.Tunnel
 }
 
 // Engine defines an interface for the Shipyard engine
 type Engine interface {
 	GetClients() *Clients
-	Apply(string) ([]config.Resource, error)
-	Destroy(string, bool) error
+	Apply(context.Context, string) ([]config.Resource, error)
+	ApplyWithOptions(context.Context, string, ApplyOptions) ([]config.Resource, error)
+	ApplyTargets(ctx context.Context, path string, names []string) ([]config.Resource, error)
+	Destroy(context.Context, string, bool) error
+	DestroyWithOptions(context.Context, string, DestroyOptions) error
+	DestroyResource(ctx context.Context, name string) error
+	Upgrade(ctx context.Context, path string) (*UpgradeChanges, error)
+	Plan(path string) (*Plan, error)
+	Validate(path string) error
+	Taint(resource string) error
+	Refresh() (*Drift, error)
+	PushImage(cluster, image string) error
+	BuildImage(ctx context.Context, bc clients.BuildConfig) error
+	Pull(path string) error
+	Bundle(path, dst string) (*BundleManifest, error)
+	LoadBundle(src string) (*BundleManifest, error)
+	GC(age time.Duration) error
+	ExportCompose(path string) error
+	ExportDevContainer(container, path string) error
+	ExportTerraform(path string) error
+	SBOM() (*SBOM, error)
+	ExportSBOM(path, format string) error
+	Graph(path, format string) ([]byte, error)
+	Export(path string) error
+	Import(path string) ([]config.Resource, error)
+	Test(ctx context.Context, path string) (*TestReport, error)
+	ApplyClassroom(ctx context.Context, path string, o ClassroomOptions) ([]InstanceStatus, error)
+	ClassroomStatus(name string) ([]InstanceStatus, error)
+	DestroyClassroom(ctx context.Context, name string) error
+	Usage() (*Usage, error)
+	Dashboard() http.Handler
+	DashboardStatus() (*DashboardStatus, error)
+	RunDemo(name string, o DemoOptions) (*DemoRecording, error)
+	AuditLog(q AuditQuery) ([]AuditEntry, error)
+	BenchmarkReport(runs int) (*BenchmarkReport, error)
+	Result() *Result
+	AddEventHandler(h EventHandler)
+	AddHooks(h Hooks)
+	SetStateBackend(c *config.StateBackend) error
+	Events() (<-chan Event, func())
 	ResourceCount() int
+	ResourceNames(t config.ResourceType) ([]string, error)
+	State() (*config.Config, error)
 	Blueprint() *config.Blueprint
+	Workspaces() ([]Workspace, error)
+	CreateWorkspace(name string) error
+	SelectWorkspace(name string) error
+	DeleteWorkspace(name string) error
 }
 
 // EngineImpl is responsible for creating and destroying resources
@@ -53,15 +107,52 @@ type EngineImpl struct {
 	log         hclog.Logger
 	getProvider getProviderFunc
 	sync        sync.Mutex
+	result      *Result         // outcome of the last operation, guarded by sync
+	benchmark   *BenchmarkRun   // timings for the current apply, nil when not benchmarking
+	targets     map[string]bool // resources created by the current apply, nil when all resources are created
+	workers     chan struct{}   // bounds the concurrent operations, nil when unlimited
+	fingerprint string          // hash of the resources for the last apply, used to find checkpoints
+	offline     bool            // set by ApplyOptions.Offline, remote modules are only read from the local cache
+	handlers    []*registeredHandler
+	handlerLock sync.Mutex // serialises calls to handlers without holding sync
+
+	operationStart time.Time // start of the running apply or destroy, used for the elapsed time of events
+
+	getHostClients    hostClientsFunc
+	hostClients       map[string]*Clients
+	getClusterClients clusterClientsFunc
+
+	statePath string                  // location of the state file, defaults to utils.StatePath
+	instance  *config.InstanceOptions // when set the config is modified to run as an isolated instance
+
+	lock *fileLock // lock on the state file held by the running operation
+
+	backend       StateBackend // set with SetStateBackend, overrides the backend defined by the blueprint
+	activeBackend StateBackend // backend the state was downloaded from for the running operation
+	remoteState   []byte       // state downloaded from the backend, nil when none was stored
 }
 
 // defines a function which is used for generating providers
 // enables the replacement in tests to inject mocks
 type getProviderFunc func(c config.Resource, cl *Clients) providers.Provider
 
-// GenerateClients creates the various clients for creating and destroying resources
-func GenerateClients(l hclog.Logger) (*Clients, error) {
-	dc, err := clients.NewDocker()
+// DockerConfig returns the connection settings for the Docker engine from
+// the standard Docker environment variables, SHIPYARD_DOCKER_CONTEXT allows
+// Shipyard to use a different Docker context to the Docker CLI
+func DockerConfig() clients.DockerConfig {
+	dcc := clients.DockerConfigFromEnv()
+
+	if ctx := os.Getenv("SHIPYARD_DOCKER_CONTEXT"); ctx != "" {
+		dcc.Context = ctx
+	}
+
+	return dcc
+}
+
+// GenerateClients creates the various clients for creating and destroying
+// resources, the Docker clients connect to the engine described by dcc
+func GenerateClients(l hclog.Logger, dcc clients.DockerConfig) (*Clients, error) {
+	dc, err := clients.NewDocker(dcc)
 	if err != nil {
 		return nil, err
 	}
@@ -70,15 +161,23 @@ func GenerateClients(l hclog.Logger) (*Clients, error) {
 
 	hec := clients.NewHelm(l)
 
-	ec := clients.NewCommand(30*time.Second, l)
+	// default timeout for local commands, exec_local can override this
+	ec := clients.NewCommand(5*time.Minute, l)
+
+	// image builds can take significantly longer than other commands
+	ib := clients.NewImageBuilder(ec, 30*time.Minute, l)
 
 	hc := clients.NewHTTP(1*time.Second, l)
 
 	nc := clients.NewNomad(hc, 1*time.Second, l)
 
-	bp := &clients.GetterImpl{}
+	csc := clients.NewConsul(hc, 1*time.Second, l)
+
+	vc := clients.NewVault(hc, 1*time.Second, l)
 
-	bc := &clients.SystemImpl{}
+	bp := clients.NewGetter(false)
+
+	bc := &clients.SystemImpl{Docker: dcc}
 
 	il := clients.NewImageFileLog(utils.ImageCacheLog())
 
@@ -92,25 +191,38 @@ func GenerateClients(l hclog.Logger) (*Clients, error) {
 		Command:        ec,
 		HTTP:           hc,
 		Nomad:          nc,
+		Consul:         csc,
+		Vault:          vc,
 		Logger:         l,
 		Getter:         bp,
 		Browser:        bc,
 		ImageLog:       il,
+		ImageBuilder:   ib,
+		Tunnel:         clients.NewTunnel(l),
 	}, nil
 }
 
-// New creates a new shipyard engine
+// New creates a new shipyard engine connected to the Docker engine set by
+// the environment
 func New(l hclog.Logger) (Engine, error) {
+	return NewWithDockerConfig(l, DockerConfig())
+}
+
+// NewWithDockerConfig creates a new shipyard engine connected to the Docker
+// engine described by dcc, e.g. a remote host over ssh or tcp
+func NewWithDockerConfig(l hclog.Logger, dcc clients.DockerConfig) (Engine, error) {
 	var err error
 	e := &EngineImpl{}
 	e.log = l
 	e.getProvider = generateProviderImpl
+	e.getHostClients = generateHostClientsImpl
+	e.getClusterClients = generateClusterClientsImpl
 
 	// Set the standard writer to our logger as the DAG uses the standard library log.
 	log.SetOutput(l.StandardWriter(&hclog.StandardLoggerOptions{ForceLevel: hclog.Trace}))
 
 	// create the clients
-	cl, err := GenerateClients(l)
+	cl, err := GenerateClients(l, dcc)
 	if err != nil {
 		return nil, err
 	}
@@ -125,62 +237,240 @@ func (e *EngineImpl) GetClients() *Clients {
 	return e.clients
 }
 
-// Apply the current config creating the resources
-func (e *EngineImpl) Apply(path string) ([]config.Resource, error) {
+// stateFile returns the location of the state file for the engine
+func (e *EngineImpl) stateFile() string {
+	if e.statePath != "" {
+		return e.statePath
+	}
+
+	return utils.StatePath()
+}
+
+// Result returns the outcome of the last Apply or Destroy, nil is returned
+// when neither has been called
+func (e *EngineImpl) Result() *Result {
+	e.sync.Lock()
+	defer e.sync.Unlock()
+
+	return e.result
+}
+
+// startResult records the outcome of a new operation, the result replaces
+// the one returned by Result
+func (e *EngineImpl) startResult(action string) *Result {
+	e.sync.Lock()
+	defer e.sync.Unlock()
+
+	e.result = newResult(action)
+
+	return e.result
+}
+
+// Apply the current config creating the resources, when ctx is cancelled
+// running image pulls, container operations and commands are stopped and
+// no further resources are created
+func (e *EngineImpl) Apply(ctx context.Context, path string) (res []config.Resource, err error) {
+	err = e.lockState(path)
+	if err != nil {
+		return nil, err
+	}
+	defer e.unlockState(&err)
+
+	return e.runApply(ctx, path)
+}
+
+// runApply applies the blueprint and records the operation, the caller must
+// hold the lock on the state
+func (e *EngineImpl) runApply(ctx context.Context, path string) ([]config.Resource, error) {
+	started := time.Now()
+	e.benchmark = newBenchmarkRun(path)
+
+	e.operationStarted("apply")
+
+	res, err := e.apply(ctx, path)
+	e.audit(AuditApply, path, started, err)
+	e.notify(AuditApply, path, started, err)
+	e.recordBenchmark(err)
+	e.operationDone("apply", started, err)
+
+	return res, err
+}
+
+func (e *EngineImpl) apply(ctx context.Context, path string) ([]config.Resource, error) {
+	res := e.startResult("apply")
+
 	d, err := e.readConfig(path)
 	if err != nil {
+		res.finish(err)
+		return nil, err
+	}
+
+	// only the targeted resources and their dependencies are created
+	err = e.resolveTargets()
+	if err != nil {
+		res.finish(err)
+		return nil, err
+	}
+
+	// organizations can forbid patterns in blueprints using a policy
+	err = e.checkPolicy()
+	if err != nil {
+		res.finish(err)
+		return nil, err
+	}
+
+	// shared machines limit the resources an environment can use
+	err = e.checkQuota()
+	if err != nil {
+		res.finish(err)
+		return nil, err
+	}
+
+	// containers are created from a checkpoint when one exists for the
+	// identical config
+	e.fingerprint = blueprintFingerprint(e.config.Resources)
+	resetImages := e.restoreCheckpoint(ctx)
+
+	// container images are pulled through the image cache when a cluster uses it
+	ctx, err = e.useImageCache(ctx)
+	if err != nil {
+		resetImages()
+		res.finish(err)
 		return nil, err
 	}
 
+	// pull all the images up front so providers do not wait on each other
+	err = e.pullImages(ctx)
+	if err != nil {
+		resetImages()
+		res.finish(err)
+		return nil, err
+	}
+
+	// images are scanned once pulled so the scanner can read them from the
+	// local Docker cache
+	err = e.scanImages(ctx)
+	if err != nil {
+		resetImages()
+		res.finish(err)
+		return nil, err
+	}
+
+	// resources are created in parallel, every resource is processed before
+	// the walk completes and the errors for all failed resources are returned
 	createdResource := []config.Resource{}
+	createdLock := sync.Mutex{}
 
 	// walk the dag and apply the config
 	w := dag.Walker{}
 	w.Callback = func(v dag.Vertex) (diags tfdiags.Diagnostics) {
+		// resources whose containers are no longer running are created again,
+		// the resource fails when Docker can not be checked
+		missing := false
+		if r, ok := v.(config.Resource); ok && e.targeted(r) && !pendingApply(r) {
+			m, err := e.missing(ctx, r)
+			if err != nil {
+				return diags.Append(err)
+			}
+
+			missing = m
+		}
+
 		// check if the resource needs to be created and if so create
-		if r, ok := v.(config.Resource); ok &&
-			(r.Info().Status == config.PendingCreation ||
-				r.Info().Status == config.PendingModification ||
-				r.Info().Status == config.Failed) {
+		if r, ok := v.(config.Resource); ok && e.targeted(r) && (pendingApply(r) || missing) {
+			// resources are not created once the apply has been cancelled
+			if ctx.Err() != nil {
+				return diags.Append(xerrors.Errorf("Unable to create %s, apply cancelled: %w", resourceName(r), ctx.Err()))
+			}
+
+			adopted, err := e.adoptExisting(ctx, r)
+			if err != nil {
+				r.Info().Status = config.Failed
+				return diags.Append(err)
+			}
+
+			if adopted {
+				e.log.Info("Resource already exists, skipping", "ref", resourceName(r))
+				r.Info().Status = config.Applied
+				return nil
+			}
+
+			wk, err := e.acquireWorker(ctx)
+			if err != nil {
+				return diags.Append(xerrors.Errorf("Unable to create %s, apply cancelled: %w", resourceName(r), err))
+			}
+			defer wk.release()
+
+			// the worker is given up while the provider waits for health checks
+			ctx := wk.withIdle(ctx)
+
+			st := e.resourceStarted("apply", r)
+
+			// resources pinned to a docker_host use a client for that host
+			cl, err := e.clientsFor(r)
+			if err != nil {
+				r.Info().Status = config.Failed
+				e.resourceDone("apply", r, st, err)
+				return diags.Append(err)
+			}
 
 			// get the provider to create the resource
-			p := e.getProvider(r, e.clients)
+			p := e.getProvider(r, cl)
 			if p == nil {
+				err := fmt.Errorf("Unable to create provider for resource Name: %s, Type: %s", r.Info().Name, r.Info().Type)
 				r.Info().Status = config.Failed
-				return diags.Append(fmt.Errorf("Unable to create provider for resource Name: %s, Type: %s", r.Info().Name, r.Info().Type))
+				e.resourceDone("apply", r, st, err)
+				return diags.Append(err)
 			}
 
-			// if we are pending modification or failed try remove the old instance and
-			// create again
-			if r.Info().Status == config.PendingModification || r.Info().Status == config.Failed {
-				err = p.Destroy()
+			// if we are pending modification, failed or the container is no longer
+			// running try remove the old instance and create again
+			if r.Info().Status == config.PendingModification || r.Info().Status == config.Failed || missing {
+				err = p.Destroy(ctx)
 				if err != nil {
 					r.Info().Status = config.Failed
+					e.resourceDone("apply", r, st, err)
 					return diags.Append(err)
 				}
 			}
 
-			// create the resource
-			err = p.Create()
+			// create the resource, containers are labelled with the hash of the
+			// definition so that they can be adopted by a later apply
+			err = e.create(clients.WithConfigHash(ctx, resourceHash(r)), r, p)
 			if err != nil {
 				r.Info().Status = config.Failed
+				e.resourceDone("apply", r, st, err)
 				return diags.Append(err)
 			}
 
 			// set the status
 			r.Info().Status = config.Applied
+
+			createdLock.Lock()
 			createdResource = append(createdResource, r)
+			createdLock.Unlock()
+
+			e.resourceDone("apply", r, st, nil)
 		}
 
 		return nil
 	}
 
+	if e.benchmark != nil {
+		e.benchmark.walkStart()
+	}
+
 	w.Update(d)
 	tf := w.Wait()
 	if tf.Err() != nil {
 		err = tf.Err()
 	}
 
+	// the resources which failed may have been stopped by the cancellation
+	if err != nil && ctx.Err() != nil {
+		err = xerrors.Errorf("Apply cancelled: %w", ctx.Err())
+	}
+
 	// update the status of anything which is pending update as this
 	// is not currently implemented
 	// eventually we should compare resources and update as required
@@ -190,23 +480,36 @@ func (e *EngineImpl) Apply(path string) ([]config.Resource, error) {
 		}
 	}
 
+	resetImages()
+
 	if len(e.config.Resources) > 0 {
 		// save the state regardless of error
-		jerr := e.config.ToJSON(utils.StatePath())
+		jerr := e.config.ToJSON(e.stateFile())
 		if jerr != nil {
+			res.finish(jerr)
 			return createdResource, jerr
 		}
 
+		res.finish(err)
 		return createdResource, err
 	}
 
+	res.finish(tf.Err())
 	return nil, tf.Err()
 }
 
-// Destroy the resources defined by the config
-func (e *EngineImpl) Destroy(path string, allResources bool) error {
+// Destroy the resources defined by the config, when ctx is cancelled no
+// further resources are destroyed
+func (e *EngineImpl) Destroy(ctx context.Context, path string, allResources bool) error {
+	return e.DestroyWithOptions(ctx, path, DestroyOptions{All: allResources})
+}
+
+func (e *EngineImpl) destroy(ctx context.Context, path string, allResources, continueOnError bool) error {
+	res := e.startResult("destroy")
+
 	d, err := e.readConfig(path)
 	if err != nil {
+		res.finish(err)
 		return err
 	}
 
@@ -217,28 +520,191 @@ func (e *EngineImpl) Destroy(path string, allResources bool) error {
 		}
 	}
 
-	// walk the dag and apply the config
+	err = e.destroyPending(ctx, d, continueOnError)
+
+	// remove any destroyed nodes from the state
+	cn := config.New()
+	for _, i := range e.config.Resources {
+		if i.Info().Status != config.Destroyed {
+			cn.AddResource(i)
+		}
+	}
+
+	// save the state regardless of error
+	if len(cn.Resources) > 0 {
+		jerr := cn.ToJSON(e.stateFile())
+		if jerr != nil {
+			res.finish(jerr)
+			return jerr
+		}
+	} else {
+		// if no resources in the state delete
+		os.RemoveAll(e.stateFile())
+	}
+
+	res.finish(err)
+	return err
+}
+
+// DestroyResource destroys a single running resource and the resources
+// which depend on it, the rest of the environment is left running. Name is
+// in the form [type].[name] e.g. container.consul
+func (e *EngineImpl) DestroyResource(ctx context.Context, name string) (err error) {
+	err = e.lockState("")
+	if err != nil {
+		return err
+	}
+	defer e.unlockState(&err)
+
+	started := time.Now()
+
+	e.operationStarted("destroy")
+
+	err = e.destroyResource(ctx, name)
+	e.audit(AuditDestroy, "", started, err)
+	e.notify(AuditDestroy, "", started, err)
+	e.operationDone("destroy", started, err)
+
+	return err
+}
+
+func (e *EngineImpl) destroyResource(ctx context.Context, name string) error {
+	res := e.startResult("destroy")
+
+	sc := config.New()
+	err := sc.FromJSON(e.stateFile())
+	if err != nil {
+		err = xerrors.Errorf("Unable to load state: %w", err)
+		res.finish(err)
+		return err
+	}
+
+	r, err := sc.FindResource(name)
+	if err != nil {
+		err = xerrors.Errorf("Unable to locate resource %s in the state: %w", name, err)
+		res.finish(err)
+		return err
+	}
+
+	// destroy the resource and everything which depends on it
+	destroy := dependents(sc, map[string]bool{resourceName(r): true})
+
+	for _, sr := range sc.Resources {
+		if destroy[resourceName(sr)] {
+			sr.Info().Status = config.PendingUpdate
+		}
+	}
+
+	e.config = sc
+
+	d, err := sc.DoYaLikeDAGs()
+	if err != nil {
+		err = xerrors.Errorf("Unable to create dependency graph: %w", err)
+		res.finish(err)
+		return err
+	}
+
+	d.TransitiveReduction()
+
+	err = e.destroyPending(ctx, d, false)
+
+	// remove the destroyed resources from the state
+	cn := config.New()
+	for _, sr := range sc.Resources {
+		if sr.Info().Status != config.Destroyed {
+			cn.AddResource(sr)
+		}
+	}
+
+	cn.Blueprint = sc.Blueprint
+
+	if len(cn.Resources) > 0 {
+		jerr := cn.ToJSON(e.stateFile())
+		if jerr != nil {
+			res.finish(jerr)
+			return jerr
+		}
+	} else {
+		os.RemoveAll(e.stateFile())
+	}
+
+	res.finish(err)
+	return err
+}
+
+// destroyPending walks the graph in reverse and destroys every resource
+// which is pending update, destroyed resources have the status Destroyed.
+// When a resource fails the resources it depends on are not destroyed unless
+// continueOnError is set, a *DestroyError is returned listing the failures
+func (e *EngineImpl) destroyPending(ctx context.Context, d *dag.AcyclicGraph, continueOnError bool) error {
+	errs := map[string]error{}
+	errsLock := sync.Mutex{}
+
+	// failed records the error for the resource, the walk only continues
+	// past the resource when continueOnError is set
+	failed := func(r config.Resource, err error) tfdiags.Diagnostics {
+		r.Info().Status = config.Failed
+
+		errsLock.Lock()
+		errs[resourceName(r)] = err
+		errsLock.Unlock()
+
+		if continueOnError {
+			return nil
+		}
+
+		return tfdiags.Diagnostics{}.Append(err)
+	}
+
+	pending := []config.Resource{}
+	for _, v := range d.Vertices() {
+		if r, ok := v.(config.Resource); ok && r.Info().Status == config.PendingUpdate {
+			pending = append(pending, r)
+		}
+	}
+
+	// walk the dag and destroy the resources
 	w := dag.Walker{}
 	w.Reverse = true
 	w.Callback = func(v dag.Vertex) (diags tfdiags.Diagnostics) {
 		// check if the resource needs to be created and if so create
 		if r, ok := v.(config.Resource); ok && r.Info().Status == config.PendingUpdate {
+			if ctx.Err() != nil {
+				return diags.Append(xerrors.Errorf("Unable to destroy %s, destroy cancelled: %w", resourceName(r), ctx.Err()))
+			}
+
+			wk, err := e.acquireWorker(ctx)
+			if err != nil {
+				return diags.Append(xerrors.Errorf("Unable to destroy %s, destroy cancelled: %w", resourceName(r), err))
+			}
+			defer wk.release()
+
+			st := e.resourceStarted("destroy", r)
+
+			cl, err := e.clientsFor(r)
+			if err != nil {
+				e.resourceDone("destroy", r, st, err)
+				return failed(r, err)
+			}
+
 			// get the provider to create the resource
-			p := e.getProvider(r, e.clients)
+			p := e.getProvider(r, cl)
 			if p == nil {
-				r.Info().Status = config.Failed
-				return diags.Append(fmt.Errorf("Unable to create provider for resource Name: %s, Type: %s", r.Info().Name, r.Info().Type))
+				err := fmt.Errorf("Unable to create provider for resource Name: %s, Type: %s", r.Info().Name, r.Info().Type)
+				e.resourceDone("destroy", r, st, err)
+				return failed(r, err)
 			}
 
 			// execute
-			err = p.Destroy()
+			err = p.Destroy(ctx)
 			if err != nil {
-				r.Info().Status = config.Failed
-				return diags.Append(err)
+				e.resourceDone("destroy", r, st, err)
+				return failed(r, err)
 			}
 
 			// set the status
 			r.Info().Status = config.Destroyed
+			e.resourceDone("destroy", r, st, nil)
 		}
 
 		return nil
@@ -246,30 +712,224 @@ func (e *EngineImpl) Destroy(path string, allResources bool) error {
 
 	w.Update(d)
 	tf := w.Wait()
-	if tf.Err() != nil {
-		err = tf.Err()
+
+	if ctx.Err() != nil && (tf.Err() != nil || len(errs) > 0) {
+		return xerrors.Errorf("Destroy cancelled: %w", ctx.Err())
 	}
 
-	// remove any destroyed nodes from the state
-	cn := config.New()
-	for _, i := range e.config.Resources {
-		if i.Info().Status != config.Destroyed {
-			cn.AddResource(i)
+	if len(errs) == 0 {
+		return tf.Err()
+	}
+
+	de := &DestroyError{Errors: errs}
+
+	// resources which were not destroyed because a resource which depends
+	// on them failed are still running
+	for _, r := range pending {
+		if r.Info().Status != config.Destroyed {
+			de.Remaining = append(de.Remaining, resourceName(r))
 		}
 	}
 
-	// save the state regardless of error
-	if len(cn.Resources) > 0 {
-		err = cn.ToJSON(utils.StatePath())
+	sort.Strings(de.Remaining)
+
+	return de
+}
+
+// PushImage imports a local Docker image into the nodes of a running cluster
+// cluster is the name of the resource in the form [type].[name] e.g. k8s_cluster.k3s
+func (e *EngineImpl) PushImage(cluster, image string) error {
+	if !strings.HasPrefix(cluster, string(config.TypeK8sCluster)+".") && !strings.HasPrefix(cluster, string(config.TypeNomadCluster)+".") {
+		return xerrors.Errorf("Invalid resource type %s, only resources type nomad_cluster and k8s_cluster are supported", cluster)
+	}
+
+	sc, err := e.readState("")
+	if err != nil {
+		return xerrors.Errorf("No resources are running, unable to push image: %w", err)
+	}
+
+	r, err := sc.FindResource(cluster)
+	if err != nil {
+		return xerrors.Errorf("Cluster %s is not running: %w", cluster, err)
+	}
+
+	p, ok := e.getProvider(r, e.clients).(providers.ImageImporter)
+	if !ok {
+		return xerrors.Errorf("Resource %s does not support importing images", cluster)
+	}
+
+	ids, err := p.Lookup(context.Background())
+	if err != nil {
+		return xerrors.Errorf("Unable to find nodes for cluster %s: %w", cluster, err)
+	}
+
+	for _, id := range ids {
+		e.log.Info("Pushing image to cluster", "ref", cluster, "id", id, "image", image)
+
+		// always force the import, the user is pushing an image which has
+		// been updated locally and the cached copy would be stale
+		err = p.ImportLocalDockerImages(context.Background(), utils.ImageVolumeName, id, []config.Image{config.Image{Name: strings.TrimSpace(image)}}, true)
 		if err != nil {
-			return err
+			return xerrors.Errorf("Unable to push image %s: %w", image, err)
 		}
-	} else {
-		// if no resources in the state delete
-		os.RemoveAll(utils.StatePath())
 	}
 
-	return tf.Err()
+	return nil
+}
+
+// GC removes stopped containers, dangling images and unused volumes created by
+// Shipyard which are older than age, an age of 0 removes everything.
+// Containers and volumes used by resources in the state of any workspace are
+// not removed, e.g. the stopped containers of a paused environment
+func (e *EngineImpl) GC(age time.Duration) error {
+	ct := e.clients.ContainerTasks
+
+	containers, volumes, err := e.stateDockerResources()
+	if err != nil {
+		return err
+	}
+
+	ids, err := ct.PruneContainers(context.Background(), age, containers)
+	e.log.Info("Removed stopped containers", "count", len(ids))
+	if err != nil {
+		return xerrors.Errorf("Unable to remove stopped containers: %w", err)
+	}
+
+	ids, err = ct.PruneImages(context.Background(), age)
+	e.log.Info("Removed dangling images", "count", len(ids))
+	if err != nil {
+		return xerrors.Errorf("Unable to remove dangling images: %w", err)
+	}
+
+	// volumes are removed last as they may have been used by the removed containers
+	names, err := ct.PruneVolumes(context.Background(), age, volumes)
+	e.log.Info("Removed unused volumes", "count", len(names))
+	if err != nil {
+		return xerrors.Errorf("Unable to remove unused volumes: %w", err)
+	}
+
+	return nil
+}
+
+// stateDockerResources returns the names of the containers and volumes used
+// by the resources in the state of the current workspace and the local state
+// of every other workspace
+func (e *EngineImpl) stateDockerResources() ([]string, []string, error) {
+	states := []*config.Config{}
+
+	sc, err := e.readState("")
+	if err != nil && err != config.StateNotFoundError {
+		return nil, nil, xerrors.Errorf("Unable to load state: %w", err)
+	}
+
+	if err == nil {
+		states = append(states, sc)
+	}
+
+	ws, err := allWorkspaces()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	paths := []string{utils.StatePath()}
+	for _, w := range ws {
+		paths = append(paths, utils.WorkspaceStatePath(w.Name))
+	}
+
+	for _, p := range paths {
+		wc := config.New()
+		if wc.FromJSON(p) == nil {
+			states = append(states, wc)
+		}
+	}
+
+	containers := []string{}
+	volumes := []string{}
+
+	for _, c := range states {
+		for _, r := range c.Resources {
+			if name, ok := resourceContainerName(r); ok {
+				containers = append(containers, utils.FQDN(name, string(r.Info().Type)))
+			}
+
+			vols := []config.Volume{}
+			switch v := r.(type) {
+			case *config.Container:
+				vols = v.Volumes
+			case *config.Sidecar:
+				vols = v.Volumes
+			case *config.K8sCluster, *config.NomadCluster:
+				volumes = append(volumes, utils.FQDNVolumeName(utils.ImageVolumeName))
+			}
+
+			for _, v := range vols {
+				if v.Type == "volume" {
+					volumes = append(volumes, v.Source, utils.FQDNVolumeName(v.Source))
+				}
+			}
+		}
+	}
+
+	return containers, volumes, nil
+}
+
+// ExportCompose writes the containers, sidecars and networks from the current
+// state to path as a docker-compose file, Kubernetes and Nomad resources are not exported
+func (e *EngineImpl) ExportCompose(path string) error {
+	sc, err := e.readState("")
+	if err != nil {
+		return xerrors.Errorf("No resources are running, unable to export compose file: %w", err)
+	}
+
+	d, err := generateCompose(sc)
+	if err != nil {
+		return xerrors.Errorf("Unable to generate compose file: %w", err)
+	}
+
+	err = ioutil.WriteFile(path, d, 0644)
+	if err != nil {
+		return xerrors.Errorf("Unable to write compose file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// ExportDevContainer writes a VS Code devcontainer definition to path which
+// uses the running container as the workspace, container is the name of the
+// resource in the form [type].[name] e.g. container.tools
+func (e *EngineImpl) ExportDevContainer(container, path string) error {
+	sc, err := e.readState("")
+	if err != nil {
+		return xerrors.Errorf("No resources are running, unable to export devcontainer: %w", err)
+	}
+
+	d, err := generateDevContainer(sc, container)
+	if err != nil {
+		return xerrors.Errorf("Unable to generate devcontainer: %w", err)
+	}
+
+	err = ioutil.WriteFile(path, d, 0644)
+	if err != nil {
+		return xerrors.Errorf("Unable to write devcontainer %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// ExportTerraform writes the resources from the current state to path as a
+// Terraform configuration using the docker, kubernetes, and helm providers
+func (e *EngineImpl) ExportTerraform(path string) error {
+	sc, err := e.readState("")
+	if err != nil {
+		return xerrors.Errorf("No resources are running, unable to export Terraform: %w", err)
+	}
+
+	err = ioutil.WriteFile(path, generateTerraform(sc), 0644)
+	if err != nil {
+		return xerrors.Errorf("Unable to write Terraform config %s: %w", path, err)
+	}
+
+	return nil
 }
 
 // ResourceCount defines the number of resources in a plan
@@ -277,6 +937,38 @@ func (e *EngineImpl) ResourceCount() int {
 	return e.config.ResourceCount()
 }
 
+// State returns the state of the running environment, when the state is
+// stored by a backend the stored state is returned
+func (e *EngineImpl) State() (*config.Config, error) {
+	return e.readState("")
+}
+
+// ResourceNames returns the names of the resources in the state in the form
+// [type].[name] sorted by name, when t is empty resources of every type are
+// returned. An empty list is returned when no resources have been created
+func (e *EngineImpl) ResourceNames(t config.ResourceType) ([]string, error) {
+	names := []string{}
+
+	sc, err := e.readState("")
+	if err == config.StateNotFoundError {
+		return names, nil
+	}
+
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to read state: %w", err)
+	}
+
+	for _, r := range sc.Resources {
+		if t == "" || r.Info().Type == t {
+			names = append(names, fmt.Sprintf("%s.%s", r.Info().Type, r.Info().Name))
+		}
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
 // Blueprint returns the blueprint for the current config
 func (e *EngineImpl) Blueprint() *config.Blueprint {
 	return e.config.Blueprint
@@ -284,30 +976,21 @@ func (e *EngineImpl) Blueprint() *config.Blueprint {
 
 func (e *EngineImpl) readConfig(path string) (*dag.AcyclicGraph, error) {
 	// load the new config
-	cc := config.New()
-	if path != "" {
-		if utils.IsHCLFile(path) {
-			err := config.ParseHCLFile(path, cc)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			err := config.ParseFolder(path, cc)
-			if err != nil {
-				return nil, err
-			}
-		}
-
-		// if we are loading from files create the deps
-		config.ParseReferences(cc)
+	cc, err := e.parseConfig(path)
+	if err != nil {
+		return nil, err
 	}
 
 	// load the existing state
 	sc := config.New()
-	err := sc.FromJSON(utils.StatePath())
-	if err != nil {
+	err = sc.FromJSON(e.stateFile())
+	if err == config.StateNotFoundError {
 		// we do not have any state to create a new one
 		e.log.Debug("Statefile does not exist")
+	} else if err != nil {
+		// do not overwrite state which can not be read, it may have been
+		// created by a different version of Shipyard
+		return nil, xerrors.Errorf("Unable to read state: %w", err)
 	}
 
 	// merge the state and items to be created or deleted
@@ -332,19 +1015,107 @@ func (e *EngineImpl) readConfig(path string) (*dag.AcyclicGraph, error) {
 	return d, nil
 }
 
+// parseConfig parses the file, folder, or blueprint package at path without
+// merging the state, an empty path returns an empty config
+func (e *EngineImpl) parseConfig(path string) (*config.Config, error) {
+	cc := config.New()
+	cc.Offline = e.offline
+	if path == "" {
+		return cc, nil
+	}
+
+	// packaged blueprints are extracted and loaded from the package cache,
+	// when keys are trusted the signature is verified before the package is
+	// extracted
+	if utils.IsBlueprintPackage(path) {
+		key, err := verifyPackage(path)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to verify package %s: %w", path, err)
+		}
+
+		if key != "" {
+			e.log.Info("Verified blueprint package signature", "package", path, "key", key)
+		} else {
+			e.log.Warn("Blueprint package has not been verified, add the public key of the author to the trusted keys to verify packages", "package", path, "trusted_keys", TrustedKeysDir())
+		}
+
+		dir, err := unpackBlueprint(path)
+		if err != nil {
+			return nil, err
+		}
+
+		path = dir
+	} else if isRemoteBlueprint(path) {
+		e.log.Warn("Remote blueprint has not been verified, only blueprint packages can be signed", "blueprint", path)
+	}
+
+	if utils.IsHCLFile(path) {
+		err := config.ParseHCLFile(path, cc)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		err := config.ParseFolder(path, cc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// if we are loading from files create the deps
+	config.ParseReferences(cc)
+
+	io, err := e.instanceOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	if io != nil {
+		err := cc.Instance(*io)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to create instance %s: %w", io.Prefix, err)
+		}
+	}
+
+	// record the definitions before providers change the resources so that
+	// Upgrade and Plan can compare them with a newer blueprint
+	dir := path
+	if utils.IsHCLFile(path) {
+		dir = filepath.Dir(path)
+	}
+
+	config.SetDefinitions(cc, dir)
+
+	return cc, nil
+}
+
+// pendingApply returns true when the resource needs to be created by Apply
+func pendingApply(r config.Resource) bool {
+	return r.Info().Status == config.PendingCreation ||
+		r.Info().Status == config.PendingModification ||
+		r.Info().Status == config.Failed ||
+		reapply(r)
+}
+
+// reapply returns true when a resource which has already been created
+// should be applied again, Kubernetes config is idempotent and re-applying
+// updates the cluster with changes to the config files
+func reapply(r config.Resource) bool {
+	return r.Info().Status == config.PendingUpdate && r.Info().Type == config.TypeK8sConfig
+}
+
 // generateProviderImpl returns providers grouped together in order of execution
 func generateProviderImpl(c config.Resource, cc *Clients) providers.Provider {
 	switch c.Info().Type {
 	case config.TypeContainer:
-		return providers.NewContainer(c.(*config.Container), cc.ContainerTasks, cc.HTTP, cc.Logger)
+		return providers.NewContainer(c.(*config.Container), cc.ContainerTasks, cc.HTTP, cc.Consul, cc.Logger)
 	case config.TypeContainerIngress:
 		return providers.NewContainerIngress(c.(*config.ContainerIngress), cc.ContainerTasks, cc.Logger)
 	case config.TypeSidecar:
-		return providers.NewContainerSidecar(c.(*config.Sidecar), cc.ContainerTasks, cc.HTTP, cc.Logger)
+		return providers.NewContainerSidecar(c.(*config.Sidecar), cc.ContainerTasks, cc.HTTP, cc.Consul, cc.Logger)
 	case config.TypeDocs:
 		return providers.NewDocs(c.(*config.Docs), cc.ContainerTasks, cc.Logger)
 	case config.TypeExecRemote:
-		return providers.NewRemoteExec(c.(*config.ExecRemote), cc.ContainerTasks, cc.Logger)
+		return providers.NewRemoteExec(c.(*config.ExecRemote), cc.ContainerTasks, cc.Kubernetes, cc.Logger)
 	case config.TypeExecLocal:
 		return providers.NewExecLocal(c.(*config.ExecLocal), cc.Command, cc.Logger)
 	case config.TypeHelm:
@@ -354,7 +1125,7 @@ func generateProviderImpl(c config.Resource, cc *Clients) providers.Provider {
 	case config.TypeK8sCluster:
 		return providers.NewK8sCluster(c.(*config.K8sCluster), cc.ContainerTasks, cc.Kubernetes, cc.HTTP, cc.Logger)
 	case config.TypeK8sConfig:
-		return providers.NewK8sConfig(c.(*config.K8sConfig), cc.Kubernetes, cc.Logger)
+		return providers.NewK8sConfig(c.(*config.K8sConfig), cc.Kubernetes, cc.Getter, cc.Logger)
 	case config.TypeK8sIngress:
 		return providers.NewK8sIngress(c.(*config.K8sIngress), cc.ContainerTasks, cc.Logger)
 	case config.TypeNomadCluster:
@@ -362,9 +1133,11 @@ func generateProviderImpl(c config.Resource, cc *Clients) providers.Provider {
 	case config.TypeNomadIngress:
 		return providers.NewNomadIngress(c.(*config.NomadIngress), cc.ContainerTasks, cc.Logger)
 	case config.TypeNomadJob:
-		return providers.NewNomadJob(c.(*config.NomadJob), cc.Nomad, cc.Logger)
+		return providers.NewNomadJob(c.(*config.NomadJob), cc.Nomad, cc.Getter, cc.Logger)
 	case config.TypeNetwork:
 		return providers.NewNetwork(c.(*config.Network), cc.Docker, cc.Logger)
+	case config.TypeDockerHost:
+		return providers.NewDockerHost(c.(*config.DockerHost), cc.Docker, cc.Logger)
 	}
 
 	return nil
@@ -1,8 +1,7 @@
-// +build !race
-
 package shipyard
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -11,15 +10,19 @@ import (
 	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/hashicorp/go-hclog"
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
 	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/shipyard-run/shipyard/pkg/providers"
 	"github.com/shipyard-run/shipyard/pkg/providers/mocks"
 	"github.com/shipyard-run/shipyard/pkg/utils"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/xerrors"
 )
 
 var lock = sync.Mutex{}
@@ -67,11 +70,22 @@ func setupTestsBase(returnVals map[string]error, state string) (Engine, *config.
 
 	p := &[]*mocks.MockProvider{}
 
-	cl := &Clients{}
+	ct := &clientmocks.MockContainerTasks{}
+	ct.On("PullImage", mock.Anything, mock.Anything).Return(nil)
+	ct.On("FindContainerIDs", mock.Anything, mock.Anything).Return(nil, nil)
+	ct.On("FindContainers", mock.Anything, mock.Anything).Return(nil, nil)
+
+	md := &clientmocks.MockDocker{}
+	md.On("NetworkList", mock.Anything, mock.Anything).Return(nil, nil)
+
+	cl := &Clients{ContainerTasks: ct, Docker: md}
 	e := &EngineImpl{
 		clients:     cl,
 		log:         hclog.NewNullLogger(),
 		getProvider: generateProviderMock(p, returnVals),
+		getClusterClients: func(cl *Clients) *Clients {
+			return cl
+		},
 	}
 
 	return e, nil, p, setupState(state)
@@ -87,12 +101,28 @@ func generateProviderMock(mp *[]*mocks.MockProvider, returnVals map[string]error
 		val := returnVals[c.Info().Name]
 		m.On("Create").Return(val)
 		m.On("Destroy").Return(val)
+		m.On("Lookup").Return([]string{"abc"}, nil)
+		m.On("ImportLocalDockerImages", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(val)
 
 		*mp = append(*mp, m)
 		return m
 	}
 }
 
+// removeOn is a utility function for removing Expectations from mock objects
+func removeOn(m *mock.Mock, method string) {
+	ec := m.ExpectedCalls
+	rc := make([]*mock.Call, 0)
+
+	for _, c := range ec {
+		if c.Method != method {
+			rc = append(rc, c)
+		}
+	}
+
+	m.ExpectedCalls = rc
+}
+
 func getTestFiles(tests string) string {
 	e, err := os.Executable()
 	if err != nil {
@@ -106,7 +136,7 @@ func TestApplyCallsProviderInCorrectOrder(t *testing.T) {
 	e, _, mp, cleanup := setupTests(nil)
 	defer cleanup()
 
-	_, err := e.Apply("../../functional_tests/test_fixtures/single_k3s_cluster")
+	_, err := e.Apply(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster")
 	assert.NoError(t, err)
 
 	// should have called in order
@@ -124,7 +154,7 @@ func TestApplyCallsProviderCreateForEachProvider(t *testing.T) {
 	e, _, mp, cleanup := setupTests(nil)
 	defer cleanup()
 
-	_, err := e.Apply("../../functional_tests/test_fixtures/single_k3s_cluster")
+	_, err := e.Apply(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster")
 	assert.NoError(t, err)
 
 	// should have call create for each provider
@@ -136,7 +166,7 @@ func TestApplyCallsProviderDestroyForResourcesPendingorFailed(t *testing.T) {
 	e, _, mp, cleanup := setupTestsWithState(nil, failedState)
 	defer cleanup()
 
-	_, err := e.Apply("")
+	_, err := e.Apply(context.Background(), "")
 	assert.NoError(t, err)
 
 	// should have call create for each provider
@@ -144,11 +174,49 @@ func TestApplyCallsProviderDestroyForResourcesPendingorFailed(t *testing.T) {
 	testAssertMethodCalled(t, mp, "Create", 1)
 }
 
+func TestApplyReturnsErrorAndKeepsStateWhenStateCreatedByNewerVersion(t *testing.T) {
+	e, _, mp, cleanup := setupTestsWithState(nil, newerVersionState)
+	defer cleanup()
+
+	_, err := e.Apply(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Shipyard 99.0.0")
+
+	testAssertMethodCalled(t, mp, "Create", 0)
+
+	// the state must not be overwritten
+	d, err := ioutil.ReadFile(utils.StatePath())
+	assert.NoError(t, err)
+	assert.Equal(t, newerVersionState, string(d))
+}
+
+func TestResourceNamesReturnsResourcesFromState(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, composeState)
+	defer cleanup()
+
+	n, err := e.ResourceNames("")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"container.consul", "k8s_cluster.k3s", "network.cloud", "sidecar.envoy"}, n)
+
+	n, err = e.ResourceNames(config.TypeNetwork)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"network.cloud"}, n)
+}
+
+func TestResourceNamesReturnsEmptyWhenNoState(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	n, err := e.ResourceNames("")
+	assert.NoError(t, err)
+	assert.Empty(t, n)
+}
+
 func TestApplyReturnsErrorWhenProviderDestroyForResourcesPendingorFailed(t *testing.T) {
 	e, _, mp, cleanup := setupTestsWithState(map[string]error{"dc1": fmt.Errorf("boom")}, failedState)
 	defer cleanup()
 
-	_, err := e.Apply("")
+	_, err := e.Apply(context.Background(), "")
 	assert.Error(t, err)
 
 	// should have call create for each provider
@@ -160,7 +228,7 @@ func TestApplyCallsProviderGenerateErrorStopsExecution(t *testing.T) {
 	e, _, mp, cleanup := setupTests(map[string]error{"cloud": fmt.Errorf("boom")})
 	defer cleanup()
 
-	_, err := e.Apply("../../functional_tests/test_fixtures/single_k3s_cluster")
+	_, err := e.Apply(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster")
 	assert.Error(t, err)
 
 	// should have call create for each provider
@@ -171,7 +239,7 @@ func TestApplyCallsProviderCreateErrorStopsExecution(t *testing.T) {
 	e, _, mp, cleanup := setupTests(map[string]error{"cloud": fmt.Errorf("boom")})
 	defer cleanup()
 
-	_, err := e.Apply("../../functional_tests/test_fixtures/single_k3s_cluster")
+	_, err := e.Apply(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster")
 	assert.Error(t, err)
 
 	// should have call create for each provider
@@ -182,7 +250,7 @@ func TestApplySetsStatusForEachResource(t *testing.T) {
 	e, _, mp, cleanup := setupTestsWithState(nil, mergedState)
 	defer cleanup()
 
-	_, err := e.Apply("")
+	_, err := e.Apply(context.Background(), "")
 	assert.NoError(t, err)
 
 	// should not call create as this is pending update
@@ -190,11 +258,23 @@ func TestApplySetsStatusForEachResource(t *testing.T) {
 	assert.Len(t, *mp, 0)
 }
 
+func TestApplyReappliesK8sConfigPendingUpdate(t *testing.T) {
+	e, _, mp, cleanup := setupTestsWithState(nil, k8sConfigState)
+	defer cleanup()
+
+	_, err := e.Apply(context.Background(), "")
+	assert.NoError(t, err)
+
+	// k8s config should be applied again without being destroyed
+	testAssertMethodCalled(t, mp, "Create", 1)
+	testAssertMethodCalled(t, mp, "Destroy", 0)
+}
+
 func TestDestroyCallsProviderDestroyForEachProvider(t *testing.T) {
 	e, _, mp, cleanup := setupTests(nil)
 	defer cleanup()
 
-	err := e.Destroy("../../functional_tests/test_fixtures/single_k3s_cluster", true)
+	err := e.Destroy(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster", true)
 	assert.NoError(t, err)
 
 	// should have call create for each provider
@@ -205,7 +285,7 @@ func TestDestroyCallsProviderGenerateErrorStopsExecution(t *testing.T) {
 	e, _, mp, cleanup := setupTests(map[string]error{"k3s": fmt.Errorf("boom")})
 	defer cleanup()
 
-	err := e.Destroy("../../functional_tests/test_fixtures/single_k3s_cluster", true)
+	err := e.Destroy(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster", true)
 	assert.Error(t, err)
 
 	// should have call create for each provider
@@ -216,7 +296,7 @@ func TestDestroyFailSetsStatus(t *testing.T) {
 	e, _, mp, cleanup := setupTests(map[string]error{"cloud": fmt.Errorf("boom")})
 	defer cleanup()
 
-	err := e.Destroy("../../functional_tests/test_fixtures/single_k3s_cluster", true)
+	err := e.Destroy(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster", true)
 	assert.Error(t, err)
 
 	// should have call create for each provider
@@ -228,7 +308,7 @@ func TestDestroyCallsProviderDestroyInCorrectOrder(t *testing.T) {
 	e, _, mp, cleanup := setupTests(nil)
 	defer cleanup()
 
-	err := e.Destroy("../../functional_tests/test_fixtures/single_k3s_cluster", true)
+	err := e.Destroy(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster", true)
 	assert.NoError(t, err)
 
 	// due to paralel nature of the DAG, these two elements can appear in any order
@@ -243,6 +323,185 @@ func TestDestroyCallsProviderDestroyInCorrectOrder(t *testing.T) {
 
 }
 
+func TestPushImageImportsImageToClusterNodes(t *testing.T) {
+	e, _, mp, cleanup := setupTestsWithState(nil, clusterState)
+	defer cleanup()
+
+	err := e.PushImage("k8s_cluster.k3s", "consul:1.7.2")
+	assert.NoError(t, err)
+
+	(*mp)[0].AssertCalled(t, "ImportLocalDockerImages", utils.ImageVolumeName, "abc", []config.Image{config.Image{Name: "consul:1.7.2"}}, true)
+}
+
+func TestPushImageWithInvalidTypeReturnsError(t *testing.T) {
+	e, _, mp, cleanup := setupTestsWithState(nil, clusterState)
+	defer cleanup()
+
+	err := e.PushImage("container.consul", "consul:1.7.2")
+	assert.Error(t, err)
+	assert.Len(t, *mp, 0)
+}
+
+func TestPushImageWithMissingClusterReturnsError(t *testing.T) {
+	e, _, mp, cleanup := setupTestsWithState(nil, clusterState)
+	defer cleanup()
+
+	err := e.PushImage("k8s_cluster.missing", "consul:1.7.2")
+	assert.Error(t, err)
+	assert.Len(t, *mp, 0)
+}
+
+func TestPushImageImportErrorReturnsError(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(map[string]error{"k3s": fmt.Errorf("boom")}, clusterState)
+	defer cleanup()
+
+	err := e.PushImage("k8s_cluster.k3s", "consul:1.7.2")
+	assert.Error(t, err)
+}
+
+func TestApplyWithCancelledContextDoesNotCreateResources(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := e.Apply(ctx, "../../functional_tests/test_fixtures/single_k3s_cluster")
+	assert.Error(t, err)
+	assert.True(t, xerrors.Is(err, context.Canceled))
+
+	testAssertMethodCalled(t, mp, "Create", 0)
+}
+
+func TestDestroyWithCancelledContextDoesNotDestroyResources(t *testing.T) {
+	e, _, mp, cleanup := setupTestsWithState(nil, composeState)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := e.Destroy(ctx, "", true)
+	assert.True(t, xerrors.Is(err, context.Canceled))
+
+	testAssertMethodCalled(t, mp, "Destroy", 0)
+}
+
+// providerCalls returns the number of times the method was called for the
+// resource with the given name
+func providerCalls(mp *[]*mocks.MockProvider, name, method string) int {
+	count := 0
+	for _, p := range *mp {
+		if p.Config().Info().Name != name {
+			continue
+		}
+
+		for _, c := range p.Calls {
+			if c.Method == method {
+				count++
+			}
+		}
+	}
+
+	return count
+}
+
+func TestApplyWithReplaceRecreatesResource(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	// all the containers are running
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	removeOn(&ct.Mock, "FindContainerIDs")
+	removeOn(&ct.Mock, "FindContainers")
+	ct.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{"abc"}, nil)
+	ct.On("FindContainers", mock.Anything, mock.Anything).Return([]config.ContainerInfo{{ID: "abc", Running: true}}, nil)
+
+	*mp = []*mocks.MockProvider{}
+
+	_, err = e.ApplyWithOptions(context.Background(), dir, ApplyOptions{DisableBrowser: true, Replace: []string{"container.consul"}})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, providerCalls(mp, "consul", "Destroy"))
+	assert.Equal(t, 1, providerCalls(mp, "consul", "Create"))
+	assert.Equal(t, 0, providerCalls(mp, "api", "Create"))
+	assert.Equal(t, 0, providerCalls(mp, "cloud", "Create"))
+}
+
+func TestApplyWithReplaceReturnsErrorWhenResourceNotInState(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	_, err := e.ApplyWithOptions(context.Background(), dir, ApplyOptions{DisableBrowser: true, Replace: []string{"container.consul"}})
+	assert.Error(t, err)
+
+	testAssertMethodCalled(t, mp, "Create", 0)
+}
+
+// runningContainer mocks FindContainers returning a running container for
+// the resource created from the definition in the blueprint at dir
+func runningContainer(t *testing.T, e Engine, dir, name string, running bool) *clientmocks.MockContainerTasks {
+	cc, err := e.(*EngineImpl).parseConfig(dir)
+	assert.NoError(t, err)
+
+	r, err := cc.FindResource(name)
+	assert.NoError(t, err)
+
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	removeOn(&ct.Mock, "FindContainers")
+	ct.On("FindContainers", r.Info().Name, r.Info().Type).Return([]config.ContainerInfo{{ID: "abc", Running: running, ConfigHash: resourceHash(r)}}, nil)
+	ct.On("FindContainers", mock.Anything, mock.Anything).Return(nil, nil)
+
+	return ct
+}
+
+func TestDestroyResourceDestroysResourceAndDependents(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	err = e.DestroyResource(context.Background(), "container.consul")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, providerCalls(mp, "cloud", "Destroy"))
+	assert.Equal(t, 1, providerCalls(mp, "consul", "Destroy"))
+	assert.Equal(t, 1, providerCalls(mp, "api", "Destroy"))
+
+	n, err := e.ResourceNames("")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"network.cloud"}, n)
+}
+
+func TestDestroyResourceNotInStateReturnsError(t *testing.T) {
+	e, _, mp, cleanup := setupTestsWithState(nil, composeState)
+	defer cleanup()
+
+	err := e.DestroyResource(context.Background(), "container.vault")
+	assert.Error(t, err)
+
+	testAssertMethodCalled(t, mp, "Destroy", 0)
+}
+
+func TestDestroyResourceWithCancelledContextDoesNotDestroyResources(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = e.DestroyResource(ctx, "container.consul")
+	assert.True(t, xerrors.Is(err, context.Canceled))
+
+	testAssertMethodCalled(t, mp, "Destroy", 0)
+}
+
 func testAssertMethodCalled(t *testing.T, p *[]*mocks.MockProvider, method string, n int, args ...interface{}) {
 	callCount := 0
 
@@ -287,3 +546,102 @@ var mergedState = `
   ]
 }
 `
+
+var newerVersionState = `
+{
+  "shipyard_version": "99.0.0",
+  "state_version": 99,
+  "blueprint": null,
+  "resources": [
+	{
+      "name": "dc1",
+      "status": "applied",
+      "subnet": "10.15.0.0/16",
+      "type": "network"
+	}
+  ]
+}
+`
+
+var k8sConfigState = `
+{
+  "blueprint": null,
+  "resources": [
+	{
+      "name": "app",
+      "status": "pending_update",
+      "cluster": "k8s_cluster.k3s",
+      "paths": ["./app.yaml"],
+      "type": "k8s_config"
+	}
+  ]
+}
+`
+
+var clusterState = `
+{
+  "blueprint": null,
+  "resources": [
+	{
+      "name": "k3s",
+      "status": "applied",
+      "driver": "k3s",
+      "type": "k8s_cluster"
+	}
+  ]
+}
+`
+
+func TestGCPrunesContainersImagesAndVolumes(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	ct.On("PruneContainers", mock.Anything, mock.Anything).Return([]string{"abc"}, nil)
+	ct.On("PruneImages", mock.Anything).Return([]string{}, nil)
+	ct.On("PruneVolumes", mock.Anything, mock.Anything).Return([]string{"cache"}, nil)
+
+	err := e.GC(24 * time.Hour)
+	assert.NoError(t, err)
+
+	ct.AssertCalled(t, "PruneContainers", 24*time.Hour, []string{})
+	ct.AssertCalled(t, "PruneImages", 24*time.Hour)
+	ct.AssertCalled(t, "PruneVolumes", 24*time.Hour, []string{})
+}
+
+func TestGCKeepsContainersAndVolumesInState(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, exportState)
+	defer cleanup()
+
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	ct.On("PruneContainers", mock.Anything, mock.Anything).Return([]string{}, nil)
+	ct.On("PruneImages", mock.Anything).Return([]string{}, nil)
+	ct.On("PruneVolumes", mock.Anything, mock.Anything).Return([]string{}, nil)
+
+	err := e.GC(0)
+	assert.NoError(t, err)
+
+	keep := map[string][]string{}
+	for _, c := range ct.Calls {
+		if c.Method == "PruneContainers" || c.Method == "PruneVolumes" {
+			keep[c.Method] = c.Arguments[1].([]string)
+		}
+	}
+
+	assert.Contains(t, keep["PruneContainers"], utils.FQDN("consul", string(config.TypeContainer)))
+	assert.Contains(t, keep["PruneContainers"], utils.FQDN("consul_2", string(config.TypeContainer)))
+	assert.Contains(t, keep["PruneVolumes"], "consul_data")
+}
+
+func TestGCReturnsErrorWhenPruneFails(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	ct.On("PruneContainers", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	err := e.GC(0)
+	assert.Error(t, err)
+
+	ct.AssertNotCalled(t, "PruneVolumes", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,244 @@
+package shipyard
+
+import (
+	"sync"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+// EventPhase is the stage of a resource operation an Event describes
+type EventPhase string
+
+// EventResourceStarted is emitted before a resource is created or destroyed
+const EventResourceStarted EventPhase = "started"
+
+// EventResourceCompleted is emitted when a resource has been successfully created or destroyed
+const EventResourceCompleted EventPhase = "completed"
+
+// EventResourceFailed is emitted when creating or destroying a resource returns an error
+const EventResourceFailed EventPhase = "failed"
+
+// EventOperationStarted is emitted before an apply or destroy starts, the
+// Resource is not set for operation events
+const EventOperationStarted EventPhase = "operation_started"
+
+// EventOperationCompleted is emitted once every resource for an apply or
+// destroy has been processed, Error is set when the operation failed
+const EventOperationCompleted EventPhase = "operation_completed"
+
+// EventImageProgress is emitted as the layers of an image are downloaded,
+// Percent is the proportion of the image which has been pulled
+const EventImageProgress EventPhase = "image_progress"
+
+// EventImagePulled is emitted when each image has been pulled before the
+// resources are created
+const EventImagePulled EventPhase = "image_pulled"
+
+// EventImageBuildProgress is emitted for each line of output from an image
+// build, Output contains the line
+const EventImageBuildProgress EventPhase = "image_build_progress"
+
+// EventImageBuilt is emitted when an image build completes, Error is set when
+// the build failed
+const EventImageBuilt EventPhase = "image_built"
+
+// eventBufferSize is the number of events buffered by the channel returned
+// from Events
+const eventBufferSize = 100
+
+// Event is emitted by the engine as resources are created and destroyed
+type Event struct {
+	Time     time.Time
+	Action   string // apply, destroy, pull, or build
+	Phase    EventPhase
+	Resource config.Resource
+	Duration time.Duration // time taken by the provider, set for completed and failed events
+	Elapsed  time.Duration // time since the operation started
+	Error    error         // set for failed events
+	Result   *Result       // outcome of the operation, set for operation completed events
+
+	Image   string  // name of the image, set for image events
+	Percent float64 // percentage of the image which has been pulled, set for image events
+	Output  string  // line of output from the build, set for image build progress events
+
+	Dropped int // events which were not delivered by the channel from Events, set for operation completed events
+}
+
+// EventHandler is called for every Event emitted by the engine, handlers
+// are called sequentially so they do not need to be safe for concurrent use
+type EventHandler func(Event)
+
+// registeredHandler wraps an EventHandler so that it can be found and
+// removed from the engine
+type registeredHandler struct {
+	fn EventHandler
+}
+
+// AddEventHandler registers a handler which is called for every Event
+func (e *EngineImpl) AddEventHandler(h EventHandler) {
+	e.addEventHandler(h)
+}
+
+func (e *EngineImpl) addEventHandler(h EventHandler) *registeredHandler {
+	e.sync.Lock()
+	defer e.sync.Unlock()
+
+	rh := &registeredHandler{fn: h}
+	e.handlers = append(e.handlers, rh)
+
+	return rh
+}
+
+func (e *EngineImpl) removeEventHandler(rh *registeredHandler) {
+	e.sync.Lock()
+	defer e.sync.Unlock()
+
+	for i, h := range e.handlers {
+		if h == rh {
+			e.handlers = append(e.handlers[:i:i], e.handlers[i+1:]...)
+			return
+		}
+	}
+}
+
+// subscription delivers events to a buffered channel for Events
+type subscription struct {
+	m       sync.Mutex
+	ch      chan Event
+	closed  bool
+	dropped int
+}
+
+func (s *subscription) send(ev Event) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	if ev.Phase != EventOperationCompleted {
+		select {
+		case s.ch <- ev:
+		default:
+			s.dropped++
+		}
+
+		return
+	}
+
+	// the last event is always delivered, when the channel is full the
+	// oldest event is discarded to make room for it
+	if len(s.ch) == cap(s.ch) {
+		select {
+		case <-s.ch:
+			s.dropped++
+		default:
+		}
+	}
+
+	ev.Dropped = s.dropped
+	s.ch <- ev
+
+	s.closed = true
+	close(s.ch)
+}
+
+func (s *subscription) close() {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+// Events returns a channel which receives the events for the next apply or
+// destroy allowing the progress of the operation to be rendered. The channel
+// is closed after the operation completed event, or when the returned cancel
+// function is called. Events are dropped when the channel is full so a slow
+// consumer does not block the engine, the number of events which were
+// dropped is set as Dropped on the operation completed event
+func (e *EngineImpl) Events() (<-chan Event, func()) {
+	s := &subscription{ch: make(chan Event, eventBufferSize)}
+
+	var rh *registeredHandler
+	rh = e.addEventHandler(func(ev Event) {
+		s.send(ev)
+
+		if ev.Phase == EventOperationCompleted {
+			e.removeEventHandler(rh)
+		}
+	})
+
+	cancel := func() {
+		e.removeEventHandler(rh)
+		s.close()
+	}
+
+	return s.ch, cancel
+}
+
+func (e *EngineImpl) emit(ev Event) {
+	e.sync.Lock()
+	ev.Time = time.Now()
+	if !e.operationStart.IsZero() {
+		ev.Elapsed = ev.Time.Sub(e.operationStart)
+	}
+
+	// copy the handlers so they are not called while holding the lock, a
+	// handler can then call back into the engine
+	handlers := make([]*registeredHandler, len(e.handlers))
+	copy(handlers, e.handlers)
+	e.sync.Unlock()
+
+	e.handlerLock.Lock()
+	defer e.handlerLock.Unlock()
+
+	for _, h := range handlers {
+		h.fn(ev)
+	}
+}
+
+// operationStarted notifies handlers that an apply or destroy is starting
+func (e *EngineImpl) operationStarted(action string) {
+	e.sync.Lock()
+	e.operationStart = time.Now()
+	e.sync.Unlock()
+
+	e.emit(Event{Action: action, Phase: EventOperationStarted})
+}
+
+// operationDone notifies handlers that an apply or destroy has finished
+func (e *EngineImpl) operationDone(action string, started time.Time, err error) {
+	e.emit(Event{Action: action, Phase: EventOperationCompleted, Duration: time.Since(started), Error: err, Result: e.Result()})
+}
+
+// resourceStarted notifies handlers that the provider for a resource is
+// about to be called, it returns the start time for resourceDone
+func (e *EngineImpl) resourceStarted(action string, r config.Resource) time.Time {
+	e.emit(Event{Action: action, Phase: EventResourceStarted, Resource: r})
+
+	return time.Now()
+}
+
+// resourceDone records the outcome of a resource in the result and
+// notifies handlers, the timings of created resources are recorded when
+// benchmarking
+func (e *EngineImpl) resourceDone(action string, r config.Resource, started time.Time, err error) {
+	e.Result().add(r, started, err)
+
+	if action == "apply" && err == nil && e.benchmark != nil {
+		e.benchmark.addResource(r, started)
+	}
+
+	ev := Event{Action: action, Phase: EventResourceCompleted, Resource: r, Duration: time.Since(started)}
+	if err != nil {
+		ev.Phase = EventResourceFailed
+		ev.Error = err
+	}
+
+	e.emit(ev)
+}
@@ -0,0 +1,147 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyEmitsEventsForEachResource(t *testing.T) {
+	e, _, _, cleanup := setupTests(map[string]error{"k3s": fmt.Errorf("boom")})
+	defer cleanup()
+
+	events := []Event{}
+	e.AddEventHandler(func(ev Event) {
+		events = append(events, ev)
+	})
+
+	_, err := e.Apply(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster")
+	assert.Error(t, err)
+
+	assert.Len(t, events, 7)
+	assert.Equal(t, EventOperationStarted, events[0].Phase)
+	assert.Equal(t, EventImagePulled, events[1].Phase)
+	assert.Equal(t, EventResourceStarted, events[2].Phase)
+	assert.Equal(t, "cloud", events[2].Resource.Info().Name)
+	assert.Equal(t, EventResourceCompleted, events[3].Phase)
+	assert.Equal(t, EventResourceStarted, events[4].Phase)
+	assert.Equal(t, EventResourceFailed, events[5].Phase)
+	assert.Equal(t, "k3s", events[5].Resource.Info().Name)
+	assert.Equal(t, "apply", events[5].Action)
+	assert.Error(t, events[5].Error)
+	assert.Equal(t, EventOperationCompleted, events[6].Phase)
+	assert.Error(t, events[6].Error)
+}
+
+func TestEventsStreamsProgress(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	ch, cancel := e.Events()
+	defer cancel()
+
+	_, err := e.Apply(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster")
+	assert.NoError(t, err)
+
+	// the channel is closed once the operation completes
+	events := []Event{}
+	for ev := range ch {
+		events = append(events, ev)
+	}
+
+	assert.Equal(t, EventOperationStarted, events[0].Phase)
+
+	assert.Equal(t, EventImagePulled, events[1].Phase)
+	assert.Equal(t, "consul:1.6.1", events[1].Image)
+	assert.Equal(t, float64(100), events[1].Percent)
+
+	last := events[len(events)-1]
+	assert.Equal(t, EventOperationCompleted, last.Phase)
+	assert.True(t, last.Elapsed > 0)
+	assert.Equal(t, 0, last.Dropped)
+
+	assert.Len(t, e.(*EngineImpl).handlers, 0)
+}
+
+func TestEventsCountsDroppedEvents(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	ch, cancel := e.Events()
+	defer cancel()
+
+	for i := 0; i < eventBufferSize+10; i++ {
+		e.(*EngineImpl).emit(Event{Phase: EventResourceStarted})
+	}
+
+	e.(*EngineImpl).emit(Event{Phase: EventOperationCompleted})
+
+	events := []Event{}
+	for ev := range ch {
+		events = append(events, ev)
+	}
+
+	assert.Len(t, events, eventBufferSize)
+
+	last := events[len(events)-1]
+	assert.Equal(t, EventOperationCompleted, last.Phase)
+	assert.Equal(t, 11, last.Dropped)
+}
+
+func TestEventsCancelClosesChannel(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	ch, cancel := e.Events()
+	cancel()
+
+	_, ok := <-ch
+	assert.False(t, ok)
+	assert.Len(t, e.(*EngineImpl).handlers, 0)
+
+	// events after cancel are not sent to the closed channel
+	e.(*EngineImpl).emit(Event{Phase: EventOperationStarted})
+}
+
+func TestEventHandlersCanCallTheEngine(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	added := 0
+	e.AddEventHandler(func(ev Event) {
+		if ev.Phase == EventOperationStarted {
+			e.AddEventHandler(func(ev Event) {})
+			added++
+		}
+	})
+
+	_, err := e.Apply(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, added)
+}
+
+func TestApplyEmitsFailedEventWhenNoProvider(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	e.(*EngineImpl).getProvider = func(c config.Resource, cc *Clients) providers.Provider {
+		return nil
+	}
+
+	failed := []string{}
+	e.AddEventHandler(func(ev Event) {
+		if ev.Phase == EventResourceFailed {
+			failed = append(failed, ev.Resource.Info().Name)
+		}
+	})
+
+	_, err := e.Apply(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster")
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"cloud"}, failed)
+	assert.False(t, e.Result().Success)
+}
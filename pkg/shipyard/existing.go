@@ -0,0 +1,72 @@
+package shipyard
+
+import (
+	"context"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// adoptExisting returns true when a resource which is not in the state
+// already has a running container, this happens when the state has been
+// removed or the environment was created by another copy of Shipyard.
+// Creating the resource would fail with a name collision so the running
+// container is used instead. Containers are only used when they were created
+// from the same definition, an error is returned when the container is
+// stopped or was created from a different definition
+func (e *EngineImpl) adoptExisting(ctx context.Context, r config.Resource) (bool, error) {
+	if r.Info().Status != config.PendingCreation {
+		return false, nil
+	}
+
+	name, ok := resourceContainerName(r)
+	if !ok {
+		return false, nil
+	}
+
+	cl, err := e.clientsFor(r)
+	if err != nil {
+		return false, err
+	}
+
+	cs, err := cl.ContainerTasks.FindContainers(ctx, name, r.Info().Type)
+	if err != nil {
+		return false, xerrors.Errorf("Unable to check for an existing container for %s: %w", resourceName(r), err)
+	}
+
+	if len(cs) == 0 {
+		return false, nil
+	}
+
+	switch {
+	case cs[0].ConfigHash != resourceHash(r):
+		return false, xerrors.Errorf("A container for %s already exists which was not created from this definition, remove the container or destroy the environment which created it", resourceName(r))
+	case !cs[0].Running:
+		return false, xerrors.Errorf("A container for %s already exists but is not running, remove the container to create the resource", resourceName(r))
+	}
+
+	return true, nil
+}
+
+// resourceHash returns the hash of the definition of the resource, the
+// containers created for the resource are labelled with the hash
+func resourceHash(r config.Resource) string {
+	return blueprintFingerprint([]config.Resource{r})
+}
+
+// missing returns true when a resource in the state has been created but
+// its container is no longer running, e.g. it has been removed or stopped
+// with the Docker CLI, these resources are created again by Apply
+func (e *EngineImpl) missing(ctx context.Context, r config.Resource) (bool, error) {
+	if r.Info().Status != config.PendingUpdate {
+		return false, nil
+	}
+
+	if _, ok := resourceContainerName(r); !ok {
+		return false, nil
+	}
+
+	running, err := e.containerRunning(ctx, r)
+
+	return !running && err == nil, err
+}
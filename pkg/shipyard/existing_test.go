@@ -0,0 +1,125 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestApplySkipsRunningResourcesNotInState(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	runningContainer(t, e, dir, "container.consul", true)
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, providerCalls(mp, "cloud", "Create"))
+	assert.Equal(t, 0, providerCalls(mp, "consul", "Create"))
+	assert.Equal(t, 1, providerCalls(mp, "api", "Create"))
+
+	r, err := e.(*EngineImpl).config.FindResource("container.consul")
+	assert.NoError(t, err)
+	assert.Equal(t, config.Applied, r.Info().Status)
+}
+
+func TestApplyFailsWhenExistingContainerHasDifferentDefinition(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	removeOn(&ct.Mock, "FindContainers")
+	ct.On("FindContainers", "consul", config.TypeContainer).Return([]config.ContainerInfo{{ID: "abc", Running: true, ConfigHash: "other"}}, nil)
+	ct.On("FindContainers", mock.Anything, mock.Anything).Return(nil, nil)
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not created from this definition")
+
+	assert.Equal(t, 0, providerCalls(mp, "consul", "Create"))
+}
+
+func TestApplyFailsWhenExistingContainerStopped(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	runningContainer(t, e, dir, "container.consul", false)
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is not running")
+
+	assert.Equal(t, 0, providerCalls(mp, "consul", "Create"))
+}
+
+func TestApplyFailsWhenDockerListFails(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	removeOn(&ct.Mock, "FindContainers")
+	ct.On("FindContainers", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+
+	assert.Equal(t, 0, providerCalls(mp, "consul", "Create"))
+}
+
+func TestApplyCreatesResourcesMissingFromDocker(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	// consul is running, api has been removed
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	removeOn(&ct.Mock, "FindContainerIDs")
+	removeOn(&ct.Mock, "FindContainers")
+	ct.On("FindContainerIDs", "consul", config.TypeContainer).Return([]string{"abc"}, nil)
+	ct.On("FindContainers", "consul", config.TypeContainer).Return([]config.ContainerInfo{{ID: "abc", Running: true}}, nil)
+	ct.On("FindContainerIDs", mock.Anything, mock.Anything).Return(nil, nil)
+	ct.On("FindContainers", mock.Anything, mock.Anything).Return([]config.ContainerInfo{}, nil)
+
+	*mp = []*mocks.MockProvider{}
+
+	_, err = e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, providerCalls(mp, "cloud", "Create"))
+	assert.Equal(t, 0, providerCalls(mp, "consul", "Create"))
+	assert.Equal(t, 1, providerCalls(mp, "api", "Create"))
+}
+
+func TestApplyRemovesStoppedContainersBeforeCreatingAgain(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	// consul has been stopped, e.g. by shipyard pause
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	removeOn(&ct.Mock, "FindContainers")
+	ct.On("FindContainers", "consul", config.TypeContainer).Return([]config.ContainerInfo{{ID: "abc", Running: false}}, nil)
+	ct.On("FindContainers", mock.Anything, mock.Anything).Return([]config.ContainerInfo{{ID: "def", Running: true}}, nil)
+
+	*mp = []*mocks.MockProvider{}
+
+	_, err = e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, providerCalls(mp, "consul", "Destroy"))
+	assert.Equal(t, 1, providerCalls(mp, "consul", "Create"))
+	assert.Equal(t, 0, providerCalls(mp, "cloud", "Create"))
+	assert.Equal(t, 0, providerCalls(mp, "api", "Create"))
+}
@@ -0,0 +1,442 @@
+package shipyard
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers"
+	"golang.org/x/xerrors"
+)
+
+// exportManifestName is the name of the manifest in an environment export
+const exportManifestName = "manifest.json"
+
+// exportStateName is the name of the state file in an environment export
+const exportStateName = "state.json"
+
+// ExportManifest describes the contents of an environment export
+type ExportManifest struct {
+	Created time.Time    `json:"created"`
+	Hosts   []ExportHost `json:"hosts"`
+}
+
+// ExportHost lists the images and volumes exported from a single Docker host
+type ExportHost struct {
+	DockerHost string   `json:"docker_host,omitempty"` // name of the docker_host resource, empty for the local Docker engine
+	Images     []string `json:"images"`
+	Volumes    []string `json:"volumes"`
+}
+
+// Export writes the state of the running environment along with the images
+// and the contents of the named volumes used by the resources, including the
+// data for clusters created with persist_data, to the archive at path. The
+// archive can be loaded with Import to recreate the environment on another
+// machine. Files referenced by the blueprint such as bind mounts and
+// Kubernetes config are not exported and must exist at the same location on
+// the machine importing the archive.
+func (e *EngineImpl) Export(path string) error {
+	sc, err := e.readState("")
+	if err != nil {
+		return xerrors.Errorf("No resources are running, unable to export environment: %w", err)
+	}
+
+	state, err := json.Marshal(sc)
+	if err != nil {
+		return xerrors.Errorf("Unable to read state: %w", err)
+	}
+
+	m := &ExportManifest{Created: time.Now().UTC(), Hosts: exportHosts(sc)}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return xerrors.Errorf("Unable to create export %s: %w", path, err)
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	md, _ := json.MarshalIndent(m, "", "  ")
+
+	err = writeExportData(tw, exportManifestName, md)
+	if err == nil {
+		err = writeExportData(tw, exportStateName, state)
+	}
+
+	if err != nil {
+		return xerrors.Errorf("Unable to write export %s: %w", path, err)
+	}
+
+	for i, h := range m.Hosts {
+		cl, err := e.clientsForHostIn(sc, h.DockerHost)
+		if err != nil {
+			return err
+		}
+
+		if len(h.Images) > 0 {
+			e.log.Info("Exporting images", "count", len(h.Images))
+
+			err = writeExportEntry(tw, exportImagesName(i), func(w io.Writer) error {
+				return cl.ContainerTasks.SaveImages(context.Background(), h.Images, w)
+			})
+
+			if err != nil {
+				return xerrors.Errorf("Unable to export images: %w", err)
+			}
+		}
+
+		for _, v := range h.Volumes {
+			e.log.Info("Exporting volume", "volume", v)
+
+			err = writeExportEntry(tw, exportVolumeName(i, v), func(w io.Writer) error {
+				return cl.ContainerTasks.ExportVolume(context.Background(), v, w)
+			})
+
+			if err != nil {
+				return xerrors.Errorf("Unable to export volume %s: %w", v, err)
+			}
+		}
+	}
+
+	err = tw.Close()
+	if err == nil {
+		err = gw.Close()
+	}
+
+	if err != nil {
+		return xerrors.Errorf("Unable to write export %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Import loads the images and volumes from an archive created by Export and
+// creates the resources from the exported state. Import returns an error when
+// resources are already running, the existing resources must be destroyed
+// before an environment can be imported.
+func (e *EngineImpl) Import(path string) (res []config.Resource, err error) {
+	err = e.lockState("")
+	if err != nil {
+		return nil, err
+	}
+	defer e.unlockState(&err)
+
+	started := time.Now()
+
+	res, err = e.importEnvironment(context.Background(), path)
+	e.audit(AuditImport, path, started, err)
+
+	return res, err
+}
+
+func (e *EngineImpl) importEnvironment(ctx context.Context, path string) ([]config.Resource, error) {
+	if _, err := os.Stat(e.stateFile()); err == nil {
+		return nil, xerrors.Errorf("Resources are already running, destroy the existing resources before importing an environment")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to open export %s: %w", path, err)
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, xerrors.Errorf("Export %s is not a valid archive: %w", path, err)
+	}
+
+	tr := tar.NewReader(gr)
+
+	h, err := tr.Next()
+	if err != nil || h.Name != exportManifestName {
+		return nil, xerrors.Errorf("Export %s does not contain a manifest", path)
+	}
+
+	m := &ExportManifest{}
+	err = json.NewDecoder(tr).Decode(m)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to read manifest: %w", err)
+	}
+
+	h, err = tr.Next()
+	if err != nil || h.Name != exportStateName {
+		return nil, xerrors.Errorf("Export %s does not contain the state", path)
+	}
+
+	sc, err := importState(tr)
+	if err != nil {
+		return nil, err
+	}
+
+	e.config = sc
+
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to read export %s: %w", path, err)
+		}
+
+		host, volume, err := parseExportName(h.Name, m)
+		if err != nil {
+			return nil, err
+		}
+
+		cl, err := e.clientsForHost(host.DockerHost)
+		if err != nil {
+			return nil, err
+		}
+
+		if volume == "" {
+			e.log.Info("Importing images", "count", len(host.Images))
+
+			err = cl.ContainerTasks.LoadImages(ctx, tr)
+			if err != nil {
+				return nil, xerrors.Errorf("Unable to import images: %w", err)
+			}
+
+			continue
+		}
+
+		e.log.Info("Importing volume", "volume", volume)
+
+		err = cl.ContainerTasks.ImportVolume(ctx, volume, tr)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to import volume %s: %w", volume, err)
+		}
+	}
+
+	// the state is only written once the images and volumes have been
+	// loaded, Apply creates the resources from the state
+	err = sc.ToJSON(e.stateFile())
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to write state: %w", err)
+	}
+
+	return e.apply(ctx, "")
+}
+
+// importState reads the exported state, resources are marked as pending
+// creation so that they are created by Apply. Exec resources and Helm
+// releases on clusters with exported data are marked as applied, their
+// changes are restored with the volumes and running them again would
+// repeat them
+func importState(r io.Reader) (*config.Config, error) {
+	tmp, err := ioutil.TempFile("", "state")
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	tmp.Close()
+
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to read state: %w", err)
+	}
+
+	sc := config.New()
+	err = sc.FromJSON(tmp.Name())
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to read state: %w", err)
+	}
+
+	for _, r := range sc.Resources {
+		r.Info().Status = config.PendingCreation
+
+		if importedApplied(sc, r) {
+			r.Info().Status = config.Applied
+		}
+	}
+
+	return sc, nil
+}
+
+// importedApplied returns true when the changes made by the resource are
+// restored by the import and the resource must not be created again
+func importedApplied(c *config.Config, r config.Resource) bool {
+	switch v := r.(type) {
+	case *config.ExecLocal, *config.ExecRemote:
+		return true
+	case *config.Helm:
+		// the release is stored in the cluster data
+		cr, err := c.FindResource(v.Cluster)
+		if err != nil {
+			return false
+		}
+
+		k, ok := cr.(*config.K8sCluster)
+		return ok && len(providers.ClusterDataVolumes(k)) > 0
+	}
+
+	return false
+}
+
+// exportHosts returns the images and named volumes used by the resources
+// grouped by the Docker host the resource runs on, the local Docker engine
+// is always first
+func exportHosts(c *config.Config) []ExportHost {
+	hosts := map[string]*ExportHost{}
+	seen := map[string]bool{}
+
+	host := func(name string) *ExportHost {
+		if _, ok := hosts[name]; !ok {
+			hosts[name] = &ExportHost{DockerHost: name, Images: []string{}, Volumes: []string{}}
+		}
+
+		return hosts[name]
+	}
+
+	for _, r := range c.Resources {
+		name := config.DockerHostFor(r)
+
+		for _, i := range providers.Images(r) {
+			if i.Name != "" && !seen[name+"/image/"+i.Name] {
+				seen[name+"/image/"+i.Name] = true
+				host(name).Images = append(host(name).Images, i.Name)
+			}
+		}
+
+		for _, v := range exportVolumes(r) {
+			if !seen[name+"/volume/"+v] {
+				seen[name+"/volume/"+v] = true
+				host(name).Volumes = append(host(name).Volumes, v)
+			}
+		}
+	}
+
+	names := []string{}
+	for k := range hosts {
+		names = append(names, k)
+	}
+
+	sort.Strings(names)
+
+	eh := []ExportHost{}
+	for _, n := range names {
+		eh = append(eh, *hosts[n])
+	}
+
+	return eh
+}
+
+// exportVolumes returns the names of the Docker volumes mounted by the
+// resource, bind mounts are not returned
+func exportVolumes(r config.Resource) []string {
+	volumes := []config.Volume{}
+
+	switch v := r.(type) {
+	case *config.Container:
+		volumes = v.Volumes
+	case *config.Sidecar:
+		volumes = v.Volumes
+	case *config.ExecRemote:
+		volumes = v.Volumes
+	case *config.NomadCluster:
+		volumes = v.Volumes
+	case *config.K8sCluster:
+		return providers.ClusterDataVolumes(v)
+	}
+
+	names := []string{}
+	for _, v := range volumes {
+		if v.Type == "volume" && v.Source != "" {
+			names = append(names, v.Source)
+		}
+	}
+
+	return names
+}
+
+func exportImagesName(host int) string {
+	return fmt.Sprintf("hosts/%d/images.tar", host)
+}
+
+func exportVolumeName(host int, volume string) string {
+	return fmt.Sprintf("hosts/%d/volumes/%s.tar", host, volume)
+}
+
+// parseExportName returns the host and the volume for an entry in the
+// export, the volume is empty for the images archive
+func parseExportName(name string, m *ExportManifest) (*ExportHost, string, error) {
+	parts := strings.SplitN(name, "/", 3)
+	if len(parts) == 3 && parts[0] == "hosts" {
+		i, err := strconv.Atoi(parts[1])
+		if err == nil && i >= 0 && i < len(m.Hosts) {
+			h := &m.Hosts[i]
+
+			if parts[2] == path.Base(exportImagesName(i)) {
+				return h, "", nil
+			}
+
+			v := strings.TrimSuffix(strings.TrimPrefix(parts[2], "volumes/"), ".tar")
+			for _, hv := range h.Volumes {
+				if hv == v && name == exportVolumeName(i, v) {
+					return h, v, nil
+				}
+			}
+		}
+	}
+
+	return nil, "", xerrors.Errorf("Export contains %s which is not in the manifest", name)
+}
+
+func writeExportData(tw *tar.Writer, name string, data []byte) error {
+	err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	_, err = tw.Write(data)
+	return err
+}
+
+// writeExportEntry adds the output of write to the archive, the size of the
+// entry must be known before it is written so the output is buffered in a
+// temporary file
+func writeExportEntry(tw *tar.Writer, name string, write func(w io.Writer) error) error {
+	tmp, err := ioutil.TempFile("", "export")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	err = write(tmp)
+	if err != nil {
+		return err
+	}
+
+	fi, err := tmp.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp.Seek(0, 0)
+
+	err = tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: fi.Size(), ModTime: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, tmp)
+	return err
+}
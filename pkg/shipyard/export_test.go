@@ -0,0 +1,261 @@
+package shipyard
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers/mocks"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupExportTests(t *testing.T) (Engine, *clientmocks.MockContainerTasks, *[]*mocks.MockProvider, string, func()) {
+	e, _, mp, cleanup := setupTestsWithState(nil, exportState)
+
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	ct.On("SaveImages", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(io.Writer).Write([]byte("images"))
+		}).
+		Return(nil)
+	ct.On("ExportVolume", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(io.Writer).Write([]byte("volume"))
+		}).
+		Return(nil)
+	ct.On("LoadImages", mock.Anything).Return(nil)
+	ct.On("ImportVolume", mock.Anything, mock.Anything).Return(nil)
+
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	return e, ct, mp, filepath.Join(dir, "export.tar.gz"), func() {
+		os.RemoveAll(dir)
+		cleanup()
+	}
+}
+
+func TestExportWritesStateImagesAndVolumes(t *testing.T) {
+	e, ct, _, path, cleanup := setupExportTests(t)
+	defer cleanup()
+
+	err := e.Export(path)
+	assert.NoError(t, err)
+
+	ct.AssertCalled(t, "SaveImages", []string{"consul:1.8.1"}, mock.Anything)
+	ct.AssertNumberOfCalls(t, "ExportVolume", 1)
+	ct.AssertCalled(t, "ExportVolume", "consul_data", mock.Anything)
+
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	assert.NoError(t, err)
+
+	tr := tar.NewReader(gr)
+	entries := map[string]string{}
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		assert.NoError(t, err)
+
+		d, _ := ioutil.ReadAll(tr)
+		entries[h.Name] = string(d)
+	}
+
+	assert.Len(t, entries, 4)
+	assert.Contains(t, entries, "manifest.json")
+	assert.Contains(t, entries["state.json"], "consul_data")
+	assert.Equal(t, "images", entries["hosts/0/images.tar"])
+	assert.Equal(t, "volume", entries["hosts/0/volumes/consul_data.tar"])
+}
+
+func TestExportReturnsErrorWhenNoState(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	err := e.Export(filepath.Join(os.TempDir(), "export.tar.gz"))
+	assert.Error(t, err)
+}
+
+func TestImportLoadsImagesAndVolumesAndCreatesResources(t *testing.T) {
+	e, ct, mp, path, cleanup := setupExportTests(t)
+	defer cleanup()
+
+	err := e.Export(path)
+	assert.NoError(t, err)
+
+	// remove the state to simulate importing on a different machine
+	os.Remove(utils.StatePath())
+
+	res, err := e.Import(path)
+	assert.NoError(t, err)
+
+	ct.AssertNumberOfCalls(t, "LoadImages", 1)
+	ct.AssertCalled(t, "ImportVolume", "consul_data", mock.Anything)
+
+	assert.Len(t, res, 2)
+	testAssertMethodCalled(t, mp, "Create", 2)
+
+	sc := config.New()
+	err = sc.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	for _, r := range sc.Resources {
+		assert.Equal(t, config.Applied, r.Info().Status)
+	}
+
+	// the resources created by the import are recorded with the import
+	ae, err := e.AuditLog(AuditQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, ae, 1)
+	assert.Equal(t, AuditImport, ae[0].Operation)
+	assert.Len(t, ae[0].Resources, 2)
+}
+
+func TestImportReturnsErrorWhenResourcesRunning(t *testing.T) {
+	e, ct, mp, path, cleanup := setupExportTests(t)
+	defer cleanup()
+
+	err := e.Export(path)
+	assert.NoError(t, err)
+
+	_, err = e.Import(path)
+	assert.Error(t, err)
+
+	ct.AssertNotCalled(t, "LoadImages", mock.Anything)
+	assert.Len(t, *mp, 0)
+}
+
+func TestImportReturnsErrorForInvalidArchive(t *testing.T) {
+	e, _, mp, path, cleanup := setupExportTests(t)
+	defer cleanup()
+
+	os.Remove(utils.StatePath())
+	ioutil.WriteFile(path, []byte("not an export"), 0644)
+
+	_, err := e.Import(path)
+	assert.Error(t, err)
+
+	assert.Len(t, *mp, 0)
+	assert.NoFileExists(t, utils.StatePath())
+}
+
+func TestExportWritesClusterData(t *testing.T) {
+	e, ct, _, path, cleanup := setupExportTests(t)
+	defer cleanup()
+
+	ioutil.WriteFile(utils.StatePath(), []byte(exportClusterState), 0644)
+
+	err := e.Export(path)
+	assert.NoError(t, err)
+
+	ct.AssertCalled(t, "ExportVolume", "data.k3s.volume.shipyard.run", mock.Anything)
+}
+
+func TestImportMarksExecAndHelmApplied(t *testing.T) {
+	e, _, mp, path, cleanup := setupExportTests(t)
+	defer cleanup()
+
+	ioutil.WriteFile(utils.StatePath(), []byte(exportClusterState), 0644)
+
+	err := e.Export(path)
+	assert.NoError(t, err)
+
+	os.Remove(utils.StatePath())
+
+	_, err = e.Import(path)
+	assert.NoError(t, err)
+
+	// only the cluster is created, the release and the changes made by
+	// the exec are restored with the cluster data
+	assert.Equal(t, 1, providerCalls(mp, "k3s", "Create"))
+	assert.Equal(t, 0, providerCalls(mp, "vault", "Create"))
+	assert.Equal(t, 0, providerCalls(mp, "setup", "Create"))
+}
+
+func TestImportCreatesHelmWhenClusterDataNotExported(t *testing.T) {
+	e, _, mp, path, cleanup := setupExportTests(t)
+	defer cleanup()
+
+	ioutil.WriteFile(utils.StatePath(), []byte(strings.Replace(exportClusterState, `"persist_data": true,`, "", 1)), 0644)
+
+	err := e.Export(path)
+	assert.NoError(t, err)
+
+	os.Remove(utils.StatePath())
+
+	_, err = e.Import(path)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, providerCalls(mp, "vault", "Create"))
+	assert.Equal(t, 0, providerCalls(mp, "setup", "Create"))
+}
+
+var exportState = `
+{
+  "resources": [
+	{
+      "name": "consul",
+      "status": "applied",
+      "type": "container",
+      "image": {"name": "consul:1.8.1"},
+      "volumes": [
+        {"source": "consul_data", "destination": "/consul/data", "type": "volume"},
+        {"source": "/tmp/config", "destination": "/config"}
+      ]
+	},
+	{
+      "name": "consul_2",
+      "status": "applied",
+      "type": "container",
+      "image": {"name": "consul:1.8.1"},
+      "volumes": [
+        {"source": "consul_data", "destination": "/consul/data", "type": "volume"}
+      ]
+	}
+  ]
+}
+`
+
+var exportClusterState = `
+{
+  "resources": [
+	{
+      "name": "k3s",
+      "status": "applied",
+      "driver": "k3s",
+      "persist_data": true,
+      "type": "k8s_cluster"
+	},
+	{
+      "name": "vault",
+      "status": "applied",
+      "type": "helm",
+      "cluster": "k8s_cluster.k3s",
+      "chart": "/tmp/charts/vault",
+      "depends": ["k8s_cluster.k3s"]
+	},
+	{
+      "name": "setup",
+      "status": "applied",
+      "type": "exec_local",
+      "cmd": "/tmp/setup.sh",
+      "depends": ["helm.vault"]
+	}
+  ]
+}
+`
@@ -0,0 +1,331 @@
+package shipyard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// GraphFormatDOT is the Graphviz DOT format
+const GraphFormatDOT = "dot"
+
+// GraphFormatMermaid is the Mermaid flowchart format
+const GraphFormatMermaid = "mermaid"
+
+// GraphFormatJSON is a JSON document containing the resources, edges, and
+// the order the resources are created in
+const GraphFormatJSON = "json"
+
+// topology is the resources in an environment, the dependencies between
+// them, and the networks they are attached to
+type topology struct {
+	Title        string         `json:"title,omitempty"`
+	Resources    []string       `json:"resources"`    // resources in the form [type].[name], sorted by name
+	Dependencies []topologyEdge `json:"dependencies"` // From depends on To
+	Networks     []topologyEdge `json:"networks"`     // resource From is attached to network To
+	Levels       [][]string     `json:"levels"`       // resources grouped by the longest chain of dependencies below them
+}
+
+// topologyEdge is a link between two resources in the topology
+type topologyEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Label string `json:"label,omitempty"` // IP address of the resource for network attachments
+}
+
+// Graph returns a diagram of the resources in the blueprint at path, the
+// dependencies between them and the networks they are attached to in the
+// given format. When path is empty the diagram is created from the running
+// environment
+func (e *EngineImpl) Graph(path, format string) ([]byte, error) {
+	if format != GraphFormatDOT && format != GraphFormatMermaid && format != GraphFormatJSON {
+		return nil, xerrors.Errorf("Graph format %s is not supported, use %s, %s, or %s", format, GraphFormatDOT, GraphFormatMermaid, GraphFormatJSON)
+	}
+
+	var sc *config.Config
+
+	if path == "" {
+		var err error
+		sc, err = e.readState("")
+		if err != nil {
+			return nil, xerrors.Errorf("No resources are running, unable to create graph: %w", err)
+		}
+
+		e.config = sc
+	} else {
+		var err error
+		sc, err = e.parseConfig(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	t, err := newTopology(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case GraphFormatMermaid:
+		return t.Mermaid(), nil
+	case GraphFormatJSON:
+		return json.MarshalIndent(t, "", "  ")
+	}
+
+	return t.DOT(), nil
+}
+
+// newTopology creates the topology from the dependency graph Apply uses,
+// attaching a resource to a network also adds the network to the
+// dependencies, these are only recorded as network edges. An error is
+// returned when a dependency can not be resolved or the graph has a cycle
+func newTopology(c *config.Config) (*topology, error) {
+	t := &topology{Resources: []string{}, Dependencies: []topologyEdge{}, Networks: []topologyEdge{}}
+
+	if c.Blueprint != nil {
+		t.Title = c.Blueprint.Title
+	}
+
+	d, err := c.DoYaLikeDAGs()
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to create dependency graph: %w", err)
+	}
+
+	err = d.Validate()
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to validate dependency graph: %w", err)
+	}
+
+	// networks the resource is attached to keyed by resource
+	attached := map[string]map[string]bool{}
+
+	for _, r := range c.Resources {
+		n := resourceName(r)
+		t.Resources = append(t.Resources, n)
+		attached[n] = map[string]bool{}
+
+		for _, na := range resourceNetworks(r) {
+			nr, err := c.FindResource(na.Name)
+			if err != nil {
+				return nil, xerrors.Errorf("Unable to find network %s for %s: %w", na.Name, n, err)
+			}
+
+			attached[n][resourceName(nr)] = true
+			t.Networks = append(t.Networks, topologyEdge{From: n, To: resourceName(nr), Label: na.IPAddress})
+		}
+	}
+
+	// every dependency including network attachments is used for the levels,
+	// edges from the root of the graph are not dependencies
+	deps := map[string][]string{}
+
+	for _, ed := range d.Edges() {
+		dep, ok := ed.Source().(config.Resource)
+		if !ok {
+			continue
+		}
+
+		r, ok := ed.Target().(config.Resource)
+		if !ok {
+			continue
+		}
+
+		n := resourceName(r)
+		dn := resourceName(dep)
+
+		deps[n] = append(deps[n], dn)
+		if !attached[n][dn] {
+			t.Dependencies = append(t.Dependencies, topologyEdge{From: n, To: dn})
+		}
+	}
+
+	sort.Strings(t.Resources)
+	sortEdges(t.Dependencies)
+	sortEdges(t.Networks)
+
+	t.Levels = graphLevels(t.Resources, deps)
+
+	return t, nil
+}
+
+// graphLevels groups the resources by the length of the longest chain of
+// dependencies below them, resources in a level only depend on resources in
+// lower levels. Apply does not wait for a level to complete, each resource
+// is created as soon as its own dependencies have been created
+func graphLevels(resources []string, deps map[string][]string) [][]string {
+	levels := map[string]int{}
+
+	var level func(n string) int
+	level = func(n string) int {
+		if l, ok := levels[n]; ok {
+			return l
+		}
+
+		// the graph has been validated so it does not contain cycles
+		l := 0
+		for _, d := range deps[n] {
+			if dl := level(d) + 1; dl > l {
+				l = dl
+			}
+		}
+
+		levels[n] = l
+		return l
+	}
+
+	grouped := [][]string{}
+	for _, r := range resources {
+		l := level(r)
+		for len(grouped) <= l {
+			grouped = append(grouped, []string{})
+		}
+
+		// resources are sorted so each level is also sorted
+		grouped[l] = append(grouped[l], r)
+	}
+
+	return grouped
+}
+
+func sortEdges(edges []topologyEdge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From == edges[j].From {
+			return edges[i].To < edges[j].To
+		}
+
+		return edges[i].From < edges[j].From
+	})
+}
+
+// DOT returns the topology as a Graphviz digraph, networks are drawn as
+// ellipses and linked to the attached resources with dashed lines
+func (t *topology) DOT() []byte {
+	b := &bytes.Buffer{}
+
+	fmt.Fprintln(b, "digraph shipyard {")
+	fmt.Fprintln(b, "  rankdir=LR;")
+
+	if t.Title != "" {
+		fmt.Fprintf(b, "  label=%q;\n", t.Title)
+	}
+
+	fmt.Fprintln(b, "  node [shape=box];")
+
+	for _, r := range t.Resources {
+		if graphIsNetwork(r) {
+			fmt.Fprintf(b, "  %q [shape=ellipse];\n", r)
+			continue
+		}
+
+		fmt.Fprintf(b, "  %q;\n", r)
+	}
+
+	for _, e := range t.Dependencies {
+		fmt.Fprintf(b, "  %q -> %q;\n", e.From, e.To)
+	}
+
+	for _, e := range t.Networks {
+		if e.Label != "" {
+			fmt.Fprintf(b, "  %q -> %q [style=dashed, dir=none, label=%q];\n", e.From, e.To, e.Label)
+			continue
+		}
+
+		fmt.Fprintf(b, "  %q -> %q [style=dashed, dir=none];\n", e.From, e.To)
+	}
+
+	fmt.Fprintln(b, "}")
+
+	return b.Bytes()
+}
+
+// Mermaid returns the topology as a Mermaid flowchart, networks are drawn as
+// circles and linked to the attached resources with dotted lines
+func (t *topology) Mermaid() []byte {
+	b := &bytes.Buffer{}
+
+	if t.Title != "" {
+		fmt.Fprintln(b, "---")
+		// quote the title so it is a single YAML string
+		fmt.Fprintf(b, "title: %q\n", t.Title)
+		fmt.Fprintln(b, "---")
+	}
+
+	fmt.Fprintln(b, "flowchart LR")
+
+	for _, r := range t.Resources {
+		if graphIsNetwork(r) {
+			fmt.Fprintf(b, "  %s((\"%s\"))\n", mermaidID(r), r)
+			continue
+		}
+
+		fmt.Fprintf(b, "  %s[\"%s\"]\n", mermaidID(r), r)
+	}
+
+	for _, e := range t.Dependencies {
+		fmt.Fprintf(b, "  %s --> %s\n", mermaidID(e.From), mermaidID(e.To))
+	}
+
+	for _, e := range t.Networks {
+		if e.Label != "" {
+			fmt.Fprintf(b, "  %s -.-|%s| %s\n", mermaidID(e.From), e.Label, mermaidID(e.To))
+			continue
+		}
+
+		fmt.Fprintf(b, "  %s -.- %s\n", mermaidID(e.From), mermaidID(e.To))
+	}
+
+	return b.Bytes()
+}
+
+// mermaidID returns a node id for the resource, Mermaid ids can not contain
+// the dots used in resource names. Characters other than letters and digits
+// are replaced with _ and their hex code so that every name has a unique id
+func mermaidID(name string) string {
+	b := &strings.Builder{}
+
+	for _, c := range []byte(name) {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			b.WriteByte(c)
+			continue
+		}
+
+		fmt.Fprintf(b, "_%02x", c)
+	}
+
+	return b.String()
+}
+
+func graphIsNetwork(name string) bool {
+	return strings.HasPrefix(name, string(config.TypeNetwork)+".")
+}
+
+// resourceNetworks returns the networks the resource is attached to
+func resourceNetworks(r config.Resource) []config.NetworkAttachment {
+	switch v := r.(type) {
+	case *config.Container:
+		return v.Networks
+	case *config.ContainerIngress:
+		return v.Networks
+	case *config.Docs:
+		return v.Networks
+	case *config.ExecRemote:
+		return v.Networks
+	case *config.Ingress:
+		return v.Networks
+	case *config.K8sCluster:
+		return v.Networks
+	case *config.K8sIngress:
+		return v.Networks
+	case *config.NomadCluster:
+		return v.Networks
+	case *config.NomadIngress:
+		return v.Networks
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package shipyard
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupGraphTests(t *testing.T) (Engine, string, func()) {
+	e, _, _, cleanup := setupTests(nil)
+
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	ioutil.WriteFile(filepath.Join(dir, "graph.hcl"), []byte(graphConfig), 0644)
+
+	return e, dir, func() {
+		cleanup()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestGraphWritesDOT(t *testing.T) {
+	e, dir, cleanup := setupGraphTests(t)
+	defer cleanup()
+
+	d, err := e.Graph(dir, GraphFormatDOT)
+	assert.NoError(t, err)
+
+	assert.Contains(t, string(d), "digraph shipyard {")
+	assert.Contains(t, string(d), `"network.cloud" [shape=ellipse];`)
+	assert.Contains(t, string(d), `"container.api" -> "container.consul";`)
+	assert.Contains(t, string(d), `"container.consul" -> "network.cloud" [style=dashed, dir=none, label="10.15.0.200"];`)
+	assert.Contains(t, string(d), `"container.api" -> "network.cloud" [style=dashed, dir=none];`)
+
+	// the network attachment is not drawn as a dependency
+	assert.NotContains(t, string(d), `"container.consul" -> "network.cloud";`)
+}
+
+func TestGraphWritesMermaid(t *testing.T) {
+	e, dir, cleanup := setupGraphTests(t)
+	defer cleanup()
+
+	d, err := e.Graph(dir, GraphFormatMermaid)
+	assert.NoError(t, err)
+
+	assert.Contains(t, string(d), "flowchart LR")
+	assert.Contains(t, string(d), `network_2ecloud(("network.cloud"))`)
+	assert.Contains(t, string(d), `container_2econsul["container.consul"]`)
+	assert.Contains(t, string(d), "container_2eapi --> container_2econsul")
+	assert.Contains(t, string(d), "container_2econsul -.-|10.15.0.200| network_2ecloud")
+}
+
+func TestMermaidIDIsUniqueForEachName(t *testing.T) {
+	assert.NotEqual(t, mermaidID("container.a-b"), mermaidID("container.a_b"))
+	assert.NotEqual(t, mermaidID("container.a_b"), mermaidID("container_a.b"))
+}
+
+func TestMermaidQuotesTitle(t *testing.T) {
+	c := config.New()
+	c.Blueprint = &config.Blueprint{Title: "Consul\ntitle: \"injected\""}
+
+	tp, err := newTopology(c)
+	assert.NoError(t, err)
+
+	assert.Contains(t, string(tp.Mermaid()), "title: \"Consul\\ntitle: \\\"injected\\\"\"\n")
+}
+
+func TestGraphReturnsErrorForUnresolvedDependency(t *testing.T) {
+	c := config.New()
+
+	co := config.NewContainer("api")
+	co.DependsOn = []string{"container.missing"}
+	c.AddResource(co)
+
+	_, err := newTopology(c)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Unable to create dependency graph")
+}
+
+func TestGraphLevelsFollowDependencies(t *testing.T) {
+	levels := graphLevels(
+		[]string{"a", "b", "c", "d"},
+		map[string][]string{"c": []string{"a"}, "d": []string{"b", "c"}},
+	)
+
+	assert.Equal(t, [][]string{{"a", "b"}, {"c"}, {"d"}}, levels)
+}
+
+func TestGraphWritesJSONWithCreationOrder(t *testing.T) {
+	e, dir, cleanup := setupGraphTests(t)
+	defer cleanup()
+
+	d, err := e.Graph(dir, GraphFormatJSON)
+	assert.NoError(t, err)
+
+	g := map[string]interface{}{}
+	err = json.Unmarshal(d, &g)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []interface{}{"container.api", "container.consul", "network.cloud"}, g["resources"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"from": "container.api", "to": "container.consul"}}, g["dependencies"])
+	assert.Len(t, g["networks"], 2)
+
+	// the api waits for consul which waits for the network
+	assert.Equal(t, []interface{}{
+		[]interface{}{"network.cloud"},
+		[]interface{}{"container.consul"},
+		[]interface{}{"container.api"},
+	}, g["levels"])
+}
+
+func TestGraphFromStateWritesRunningResources(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, composeState)
+	defer cleanup()
+
+	d, err := e.Graph("", GraphFormatDOT)
+	assert.NoError(t, err)
+
+	assert.Contains(t, string(d), `"k8s_cluster.k3s";`)
+	assert.Contains(t, string(d), `"container.consul" -> "network.cloud" [style=dashed, dir=none, label="10.15.0.200"];`)
+}
+
+func TestGraphWithNoStateReturnsError(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	_, err := e.Graph("", GraphFormatDOT)
+	assert.Error(t, err)
+}
+
+func TestGraphReturnsErrorForUnknownFormat(t *testing.T) {
+	e, dir, cleanup := setupGraphTests(t)
+	defer cleanup()
+
+	_, err := e.Graph(dir, "png")
+	assert.Error(t, err)
+}
+
+var graphConfig = `
+network "cloud" {
+  subnet = "10.15.0.0/16"
+}
+
+container "consul" {
+  image {
+    name = "consul:1.8.1"
+  }
+
+  network {
+    name       = "network.cloud"
+    ip_address = "10.15.0.200"
+  }
+}
+
+container "api" {
+  depends_on = ["container.consul"]
+
+  image {
+    name = "nicholasjackson/fake-service:v0.9.0"
+  }
+
+  network {
+    name = "network.cloud"
+  }
+}
+`
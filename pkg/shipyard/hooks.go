@@ -0,0 +1,76 @@
+package shipyard
+
+import (
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+// Hooks are callbacks for the lifecycle of an apply or destroy, they allow
+// tools which embed the engine to drive their own UI, notifications, or
+// metrics. Hooks which are not set are not called, hooks are called
+// sequentially so they do not need to be safe for concurrent use
+type Hooks struct {
+	OnApplyStarting    func()
+	OnResourceCreating func(r config.Resource)
+	OnResourceCreated  func(r config.Resource, d time.Duration)
+	OnApplyComplete    func(res *Result, err error) // err is set when the apply failed
+
+	OnDestroyStarting    func()
+	OnResourceDestroying func(r config.Resource)
+	OnResourceDestroyed  func(r config.Resource, d time.Duration)
+	OnDestroyComplete    func(res *Result, err error) // err is set when the destroy failed
+
+	OnResourceFailed func(r config.Resource, err error) // called when a resource can not be created or destroyed
+}
+
+// AddHooks registers the hooks which are called for every apply and destroy
+func (e *EngineImpl) AddHooks(h Hooks) {
+	e.AddEventHandler(h.Handle)
+}
+
+// Handle is an EventHandler which calls the hook for the event
+func (h Hooks) Handle(ev Event) {
+	switch ev.Phase {
+	case EventOperationStarted:
+		if ev.Action == "apply" && h.OnApplyStarting != nil {
+			h.OnApplyStarting()
+		}
+
+		if ev.Action == "destroy" && h.OnDestroyStarting != nil {
+			h.OnDestroyStarting()
+		}
+
+	case EventOperationCompleted:
+		if ev.Action == "apply" && h.OnApplyComplete != nil {
+			h.OnApplyComplete(ev.Result, ev.Error)
+		}
+
+		if ev.Action == "destroy" && h.OnDestroyComplete != nil {
+			h.OnDestroyComplete(ev.Result, ev.Error)
+		}
+
+	case EventResourceStarted:
+		if ev.Action == "apply" && h.OnResourceCreating != nil {
+			h.OnResourceCreating(ev.Resource)
+		}
+
+		if ev.Action == "destroy" && h.OnResourceDestroying != nil {
+			h.OnResourceDestroying(ev.Resource)
+		}
+
+	case EventResourceCompleted:
+		if ev.Action == "apply" && h.OnResourceCreated != nil {
+			h.OnResourceCreated(ev.Resource, ev.Duration)
+		}
+
+		if ev.Action == "destroy" && h.OnResourceDestroyed != nil {
+			h.OnResourceDestroyed(ev.Resource, ev.Duration)
+		}
+
+	case EventResourceFailed:
+		if h.OnResourceFailed != nil {
+			h.OnResourceFailed(ev.Resource, ev.Error)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyCallsHooks(t *testing.T) {
+	e, _, _, cleanup := setupTests(map[string]error{"k3s": fmt.Errorf("boom")})
+	defer cleanup()
+
+	starting := 0
+	creating := []string{}
+	created := []string{}
+	failed := []string{}
+	var result *Result
+	var applyErr error
+
+	e.AddHooks(Hooks{
+		OnApplyStarting:    func() { starting++ },
+		OnResourceCreating: func(r config.Resource) { creating = append(creating, r.Info().Name) },
+		OnResourceCreated:  func(r config.Resource, d time.Duration) { created = append(created, r.Info().Name) },
+		OnResourceFailed:   func(r config.Resource, err error) { failed = append(failed, r.Info().Name) },
+		OnApplyComplete: func(res *Result, err error) {
+			result = res
+			applyErr = err
+		},
+	})
+
+	_, err := e.Apply(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster")
+	assert.Error(t, err)
+
+	assert.Equal(t, 1, starting)
+	assert.Equal(t, []string{"cloud", "k3s"}, creating)
+	assert.Equal(t, []string{"cloud"}, created)
+	assert.Equal(t, []string{"k3s"}, failed)
+	assert.Equal(t, "apply", result.Action)
+	assert.Error(t, applyErr)
+}
+
+func TestDestroyCallsHooks(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	destroyed := 0
+	var result *Result
+
+	e.AddHooks(Hooks{
+		OnResourceDestroyed: func(r config.Resource, d time.Duration) { destroyed++ },
+		OnDestroyComplete:   func(res *Result, err error) { result = res },
+	})
+
+	err := e.Destroy(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster", true)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 6, destroyed)
+	assert.True(t, result.Success)
+}
@@ -0,0 +1,291 @@
+package shipyard
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// requiredImages returns the unique set of images needed to create the given resources
+func requiredImages(resources []config.Resource) []config.Image {
+	images := []config.Image{}
+	seen := map[string]bool{}
+
+	add := func(i config.Image) {
+		if i.Name == "" || seen[i.Name] {
+			return
+		}
+
+		seen[i.Name] = true
+		images = append(images, i)
+	}
+
+	for _, r := range resources {
+		// images for resources on other Docker hosts are pulled by the provider
+		if !pendingApply(r) || config.DockerHostFor(r) != "" {
+			continue
+		}
+
+		switch v := r.(type) {
+		case *config.Container:
+			add(v.Image)
+		case *config.Sidecar:
+			add(v.Image)
+		case *config.ExecRemote:
+			if v.Image != nil {
+				add(*v.Image)
+			}
+		case *config.Docs:
+			if v.Image != nil {
+				add(*v.Image)
+			}
+		case *config.K8sCluster:
+			for _, i := range v.Images {
+				add(i)
+			}
+		case *config.NomadCluster:
+			for _, i := range v.Images {
+				add(i)
+			}
+		}
+	}
+
+	return images
+}
+
+// useImageCache starts the shared image cache when a targeted cluster uses it
+// and returns a context where images from the Docker Hub are pulled through it
+func (e *EngineImpl) useImageCache(ctx context.Context) (context.Context, error) {
+	for _, r := range e.targetedResources() {
+		if !providers.UsesImageCache(r) {
+			continue
+		}
+
+		_, err := providers.EnsureImageCache(ctx, e.clients.ContainerTasks, nil, e.log)
+		if err != nil {
+			return ctx, xerrors.Errorf("Unable to create image cache: %w", err)
+		}
+
+		return clients.WithPullMirror(ctx, providers.ImageCacheMirror), nil
+	}
+
+	return ctx, nil
+}
+
+// pullImages pulls the images required by the config in parallel before any
+// resources are created, this stops multiple providers pulling the same image
+func (e *EngineImpl) pullImages(ctx context.Context) error {
+	// providers pull the image again when the resource is created, a failed
+	// pull for a resource with a retry block is retried with the resource
+	required := []config.Resource{}
+	retried := []config.Resource{}
+
+	for _, r := range e.targetedResources() {
+		// the images for resources with a timeout are pulled by the provider
+		// so that the pull is stopped when the timeout elapses
+		if to, err := resourceTimeout(r); err == nil && to > 0 {
+			continue
+		}
+
+		if resourceRetry(r) != nil {
+			retried = append(retried, r)
+			continue
+		}
+
+		required = append(required, r)
+	}
+
+	images := requiredImages(required)
+
+	pulled := map[string]bool{}
+	for _, i := range images {
+		pulled[i.Name] = true
+	}
+
+	retryImages := []config.Image{}
+	for _, i := range requiredImages(retried) {
+		if !pulled[i.Name] {
+			retryImages = append(retryImages, i)
+		}
+	}
+
+	if len(images)+len(retryImages) == 0 {
+		return nil
+	}
+
+	e.log.Info("Pulling images", "count", len(images)+len(retryImages))
+
+	if len(images) > 0 {
+		err := e.pullParallel(ctx, map[*Clients][]config.Image{e.clients: images})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(retryImages) > 0 {
+		err := e.pullParallel(ctx, map[*Clients][]config.Image{e.clients: retryImages})
+		if err != nil {
+			e.log.Warn("Unable to pull images, the pull will be retried when the resource is created", "error", err)
+		}
+	}
+
+	return nil
+}
+
+// Pull downloads every image, remote Helm chart, and remote file used by the
+// blueprint at path without creating any resources, this allows a blueprint
+// to be run when there is no network connection. Images are pulled on the
+// Docker host the resource is pinned to. The images used by a Helm chart can
+// not be determined without installing it so only the chart is downloaded.
+func (e *EngineImpl) Pull(path string) error {
+	cc, err := e.parseConfig(path)
+	if err != nil {
+		return err
+	}
+
+	e.config = cc
+
+	images := map[*Clients][]config.Image{}
+	seen := map[*Clients]map[string]bool{}
+	count := 0
+
+	for _, r := range cc.Resources {
+		if h, ok := r.(*config.Helm); ok && !utils.IsLocalFolder(h.Chart) {
+			e.log.Info("Fetching Helm chart", "ref", h.Name, "chart", h.Chart)
+
+			_, err := providers.FetchHelmChart(e.clients.Getter, h.Chart)
+			if err != nil {
+				return xerrors.Errorf("Unable to fetch chart for %s.%s: %w", h.Type, h.Name, err)
+			}
+		}
+
+		for _, p := range remotePaths(r) {
+			e.log.Info("Fetching remote files", "ref", r.Info().Name, "source", p)
+
+			_, err := e.clients.Getter.Fetch(p)
+			if err != nil {
+				return xerrors.Errorf("Unable to fetch %s for %s.%s: %w", p, r.Info().Type, r.Info().Name, err)
+			}
+		}
+
+		cl, err := e.clientsFor(r)
+		if err != nil {
+			return err
+		}
+
+		if seen[cl] == nil {
+			seen[cl] = map[string]bool{}
+		}
+
+		for _, i := range providers.Images(r) {
+			if i.Name == "" || seen[cl][i.Name] {
+				continue
+			}
+
+			seen[cl][i.Name] = true
+			images[cl] = append(images[cl], i)
+			count++
+		}
+	}
+
+	e.log.Info("Pulling images", "count", count)
+
+	return e.pullParallel(context.Background(), images)
+}
+
+// remotePaths returns the paths used by the resource which must be
+// downloaded before the resource can be created
+func remotePaths(r config.Resource) []string {
+	paths := []string{}
+
+	switch v := r.(type) {
+	case *config.K8sConfig:
+		paths = v.Paths
+	case *config.NomadJob:
+		paths = v.Paths
+	}
+
+	remote := []string{}
+	for _, p := range paths {
+		if utils.IsRemoteURI(p) {
+			remote = append(remote, p)
+		}
+	}
+
+	return remote
+}
+
+// pullParallel pulls the images using the clients for the Docker host they
+// are needed on, once all pulls have completed an error listing every image
+// which could not be pulled is returned
+func (e *EngineImpl) pullParallel(ctx context.Context, images map[*Clients][]config.Image) error {
+	count := 0
+	for _, imgs := range images {
+		count += len(imgs)
+	}
+
+	wg := sync.WaitGroup{}
+	errs := make(chan error, count)
+
+	for cl, imgs := range images {
+		for _, i := range imgs {
+			wg.Add(1)
+
+			go func(cl *Clients, i config.Image) {
+				defer wg.Done()
+
+				wk, err := e.acquireWorker(ctx)
+				if err != nil {
+					errs <- xerrors.Errorf("Unable to pull image %s: %w", i.Name, err)
+					return
+				}
+				defer wk.release()
+
+				e.log.Debug("Pulling image", "image", i.Name)
+				st := time.Now()
+
+				// report the progress of the pull each time it changes by a
+				// whole percent
+				last := -1
+				pctx := clients.WithPullProgress(ctx, func(p float64) {
+					if int(p) == last {
+						return
+					}
+
+					last = int(p)
+					e.emit(Event{Action: "pull", Phase: EventImageProgress, Image: i.Name, Duration: time.Since(st), Percent: p})
+				})
+
+				err = cl.ContainerTasks.PullImage(pctx, i, false)
+				if err != nil {
+					errs <- xerrors.Errorf("Unable to pull image %s: %w", i.Name, err)
+					return
+				}
+
+				if e.benchmark != nil {
+					e.benchmark.addImage(i.Name, st)
+				}
+
+				e.emit(Event{Action: "pull", Phase: EventImagePulled, Image: i.Name, Duration: time.Since(st), Percent: 100})
+			}(cl, i)
+		}
+	}
+
+	wg.Wait()
+	close(errs)
+
+	// report every image which could not be pulled
+	var diags tfdiags.Diagnostics
+	for err := range errs {
+		diags = diags.Append(err)
+	}
+
+	return diags.Err()
+}
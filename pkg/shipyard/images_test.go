@@ -0,0 +1,208 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestApplyPullsImagesBeforeCreate(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	_, err := e.Apply(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster")
+	assert.NoError(t, err)
+
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	ct.AssertNumberOfCalls(t, "PullImage", 1)
+	ct.AssertCalled(t, "PullImage", config.Image{Name: "consul:1.6.1"}, false)
+}
+
+func TestApplyReturnsErrorWhenImagePullFails(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	ct := &clientmocks.MockContainerTasks{}
+	ct.On("PullImage", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+	e.GetClients().ContainerTasks = ct
+
+	_, err := e.Apply(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster")
+	assert.Error(t, err)
+
+	testAssertMethodCalled(t, mp, "Create", 0)
+}
+
+func TestRequiredImagesDeduplicatesAndSkipsApplied(t *testing.T) {
+	c1 := config.NewContainer("one")
+	c1.Image = config.Image{Name: "consul:1.6.1"}
+
+	c2 := config.NewContainer("two")
+	c2.Image = config.Image{Name: "consul:1.6.1"}
+
+	c3 := config.NewContainer("three")
+	c3.Image = config.Image{Name: "vault:1.4.0"}
+	c3.Status = config.Applied
+
+	k := config.NewK8sCluster("k3s")
+	k.Images = []config.Image{config.Image{Name: "consul:1.6.1"}, config.Image{Name: "envoy:1.14"}}
+
+	images := requiredImages([]config.Resource{c1, c2, c3, k})
+
+	assert.Equal(t, []config.Image{config.Image{Name: "consul:1.6.1"}, config.Image{Name: "envoy:1.14"}}, images)
+}
+
+func setupPullTests(t *testing.T, e Engine, pullErr, getErr error) (string, *clientmocks.MockContainerTasks, *clientmocks.Getter) {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	ioutil.WriteFile(filepath.Join(dir, "pull.hcl"), []byte(pullConfig), 0644)
+
+	ct := &clientmocks.MockContainerTasks{}
+	ct.On("PullImage", mock.Anything, mock.Anything).Return(pullErr)
+	e.(*EngineImpl).clients.ContainerTasks = ct
+
+	gm := &clientmocks.Getter{}
+	gm.On("Get", mock.Anything, mock.Anything).Return(getErr)
+	gm.On("Fetch", mock.Anything).Return("", nil)
+	e.(*EngineImpl).clients.Getter = gm
+
+	return dir, ct, gm
+}
+
+func TestPullPullsImagesWithoutCreatingResources(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, ct, gm := setupPullTests(t, e, nil, nil)
+	defer os.RemoveAll(dir)
+
+	err := e.Pull(dir)
+	assert.NoError(t, err)
+
+	ct.AssertNumberOfCalls(t, "PullImage", 2)
+	ct.AssertCalled(t, "PullImage", config.Image{Name: "consul:1.8.1"}, false)
+	ct.AssertCalled(t, "PullImage", config.Image{Name: "rancher/k3s:v1.18.4-k3s1"}, false)
+
+	gm.AssertCalled(t, "Get", "github.com/hashicorp/vault-helm", mock.Anything)
+	gm.AssertCalled(t, "Fetch", "https://example.com/app.yaml")
+
+	assert.Len(t, *mp, 0)
+	assert.NoFileExists(t, utils.StatePath())
+}
+
+func TestPullReturnsErrorForEveryFailedImage(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, ct, _ := setupPullTests(t, e, fmt.Errorf("boom"), nil)
+	defer os.RemoveAll(dir)
+
+	err := e.Pull(dir)
+	assert.Error(t, err)
+
+	// all pulls complete before the error is returned
+	ct.AssertNumberOfCalls(t, "PullImage", 2)
+	assert.Contains(t, err.Error(), "consul:1.8.1")
+	assert.Contains(t, err.Error(), "rancher/k3s:v1.18.4-k3s1")
+}
+
+func TestApplyCreatesImageCacheBeforePullingImages(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "cache.hcl"), []byte(imageCacheConfig), 0644)
+
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	ct.On("CreateVolume", mock.Anything).Return("image-cache", nil)
+	ct.On("CreateContainer", mock.Anything).Return("cacheid", nil)
+
+	_, err = e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	ct.AssertCalled(t, "PullImage", config.Image{Name: "registry:2"}, false)
+	ct.AssertCalled(t, "CreateContainer", mock.MatchedBy(func(c *config.Container) bool {
+		return c.Name == "image-cache"
+	}))
+}
+
+func TestPullReturnsErrorWhenPullFails(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, _, _ := setupPullTests(t, e, fmt.Errorf("boom"), nil)
+	defer os.RemoveAll(dir)
+
+	err := e.Pull(dir)
+	assert.Error(t, err)
+}
+
+func TestPullReturnsErrorWhenChartFetchFails(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, _, _ := setupPullTests(t, e, nil, fmt.Errorf("boom"))
+	defer os.RemoveAll(dir)
+
+	err := e.Pull(dir)
+	assert.Error(t, err)
+}
+
+var imageCacheConfig = `
+container "consul" {
+  image {
+    name = "consul:1.8.1"
+  }
+}
+
+k8s_cluster "k3s" {
+  driver      = "k3s"
+  image_cache = true
+}
+`
+
+var pullConfig = `
+container "consul" {
+  image {
+    name = "consul:1.8.1"
+  }
+}
+
+container "consul_2" {
+  image {
+    name = "consul:1.8.1"
+  }
+}
+
+k8s_cluster "k3s" {
+  driver  = "k3s"
+  version = "v1.18.4-k3s1"
+
+  image {
+    name = "consul:1.8.1"
+  }
+}
+
+helm "vault" {
+  cluster = "k8s_cluster.k3s"
+  chart   = "github.com/hashicorp/vault-helm"
+}
+
+k8s_config "app" {
+  cluster          = "k8s_cluster.k3s"
+  paths            = ["https://example.com/app.yaml"]
+  wait_until_ready = false
+}
+`
@@ -0,0 +1,196 @@
+package shipyard
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// StateLockedError is returned when the state is being changed by another
+// Shipyard process, or another engine in the same process
+type StateLockedError struct {
+	PID  int    // process which holds the lock
+	Path string // location of the lock file
+}
+
+func (s *StateLockedError) Error() string {
+	return fmt.Sprintf(
+		"State locked by PID %d, wait for the other Shipyard command to complete",
+		s.PID,
+	)
+}
+
+// errLockHeld is returned by tryLockFile when another process, or another
+// operation in this process, holds the lock
+var errLockHeld = fmt.Errorf("lock is held by another process")
+
+// fileLock is an exclusive lock on a file which is held until release is
+// called or the process exits
+type fileLock struct {
+	f    *os.File
+	path string
+}
+
+// lockFile returns the location of the lock for the state file
+func (e *EngineImpl) lockFile() string {
+	return e.stateFile() + ".lock"
+}
+
+// lockState locks the state file so that it can not be changed by another
+// process, or another operation in this process, a *StateLockedError is
+// returned when the state is already locked. When the state is stored by a
+// backend the stored state is locked and the local state is replaced with
+// the stored state. The lock is
+// held by a single call, operations which call each other use the unlocked
+// functions. path is the blueprint for the operation, it is used to find
+// the backend
+func (e *EngineImpl) lockState(path string) error {
+	err := e.validateWorkspace()
+	if err != nil {
+		return err
+	}
+
+	l, err := createLock(e.lockFile())
+	if err != nil {
+		return err
+	}
+
+	b, err := e.stateBackend(path)
+	if err == nil && b != nil {
+		err = b.Lock()
+		if err == nil {
+			err = e.pullState(b)
+			if err != nil {
+				b.Unlock()
+			}
+		}
+	}
+
+	if err != nil {
+		l.release()
+		return err
+	}
+
+	e.lock = l
+	e.activeBackend = b
+
+	return nil
+}
+
+// unlockState releases the lock created by lockState, the state is uploaded
+// to the backend and the lock file is removed. err is the error returned by
+// the operation, it is set when the state can not be uploaded and the
+// operation succeeded
+func (e *EngineImpl) unlockState(err *error) {
+	l := e.lock
+	e.lock = nil
+	defer l.release()
+
+	// the operation may have applied a blueprint which defines a backend,
+	// the new backend is locked while the state is uploaded
+	b := e.activeBackend
+	e.activeBackend = nil
+
+	var perr error
+	if b == nil {
+		b, perr = e.stateBackend("")
+		if perr == nil && b != nil {
+			perr = b.Lock()
+			if perr != nil {
+				b = nil
+			}
+		}
+	}
+
+	if perr == nil && b != nil {
+		perr = e.pushState(b)
+	}
+
+	if b != nil {
+		if uerr := b.Unlock(); uerr != nil {
+			e.log.Error("Unable to unlock state in the backend", "error", uerr)
+		}
+	}
+
+	if perr == nil {
+		return
+	}
+
+	if *err == nil {
+		*err = perr
+		return
+	}
+
+	e.log.Error("Unable to upload state to the backend", "error", perr)
+}
+
+// createLock locks the file at path and writes the PID of this process to
+// it. The lock is held by the operating system so a lock left behind by a
+// process which has exited does not stop the state being locked
+func createLock(path string) (*fileLock, error) {
+	os.MkdirAll(filepath.Dir(path), os.ModePerm)
+
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to create state lock %s: %w", path, err)
+		}
+
+		err = tryLockFile(f)
+		if err == errLockHeld {
+			d, _ := ioutil.ReadAll(f)
+			f.Close()
+
+			pid, _ := strconv.Atoi(strings.TrimSpace(string(d)))
+			return nil, &StateLockedError{PID: pid, Path: path}
+		}
+
+		if err != nil {
+			f.Close()
+			return nil, xerrors.Errorf("Unable to lock state %s: %w", path, err)
+		}
+
+		// the holder of the lock removes the file before releasing it, when
+		// the file was removed while this process waited the lock is on a
+		// file which no longer exists and the new file must be locked
+		fi, ferr := f.Stat()
+		pi, perr := os.Stat(path)
+		if ferr != nil || perr != nil || !os.SameFile(fi, pi) {
+			unlockFile(f)
+			f.Close()
+			continue
+		}
+
+		l := &fileLock{f: f, path: path}
+
+		err = f.Truncate(0)
+		if err == nil {
+			_, err = fmt.Fprintf(f, "%d", os.Getpid())
+		}
+
+		if err != nil {
+			l.release()
+			return nil, xerrors.Errorf("Unable to write state lock %s: %w", path, err)
+		}
+
+		return l, nil
+	}
+}
+
+// release removes the lock file and releases the lock, the file is removed
+// while the lock is held so another process can not lock it and have it
+// removed
+func (l *fileLock) release() {
+	if l == nil {
+		return
+	}
+
+	os.Remove(l.path)
+	unlockFile(l.f)
+	l.f.Close()
+}
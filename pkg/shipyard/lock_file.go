@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package shipyard
+
+import (
+	"os"
+	"syscall"
+)
+
+// tryLockFile takes an exclusive lock on f without waiting, errLockHeld is
+// returned when the lock is held by another open file. The lock is released
+// by the operating system when the process exits
+func tryLockFile(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return errLockHeld
+	}
+
+	return err
+}
+
+// unlockFile releases the lock taken with tryLockFile
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,34 @@
+package shipyard
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// the lock is taken on a byte beyond the content of the file so that the
+// PID in the file can be read by other processes
+const lockOffsetHigh = 1
+
+// tryLockFile takes an exclusive lock on f without waiting, errLockHeld is
+// returned when the lock is held by another open file. The lock is released
+// by the operating system when the process exits
+func tryLockFile(f *os.File) error {
+	err := windows.LockFileEx(
+		windows.Handle(f.Fd()),
+		windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY,
+		0, 1, 0,
+		&windows.Overlapped{OffsetHigh: lockOffsetHigh},
+	)
+
+	if err == windows.ERROR_LOCK_VIOLATION {
+		return errLockHeld
+	}
+
+	return err
+}
+
+// unlockFile releases the lock taken with tryLockFile
+func unlockFile(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &windows.Overlapped{OffsetHigh: lockOffsetHigh})
+}
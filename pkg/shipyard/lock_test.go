@@ -0,0 +1,124 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+)
+
+func TestApplyReturnsErrorWhenStateLocked(t *testing.T) {
+	e, _, mp, cleanup := setupTestsWithState(nil, failedState)
+	defer cleanup()
+
+	lf, unlock := writeStateLock(t, e, os.Getpid())
+	defer unlock()
+
+	_, err := e.Apply(context.Background(), "")
+
+	le := &StateLockedError{}
+	assert.True(t, xerrors.As(err, &le))
+	assert.Equal(t, os.Getpid(), le.PID)
+	assert.Contains(t, err.Error(), fmt.Sprintf("State locked by PID %d", os.Getpid()))
+	assert.Len(t, *mp, 0)
+
+	// the lock belongs to the other process
+	assert.FileExists(t, lf)
+}
+
+func TestApplyReplacesStaleStateLock(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, failedState)
+	defer cleanup()
+
+	// the lock file left behind by a process which has exited
+	lf := e.(*EngineImpl).lockFile()
+	os.MkdirAll(filepath.Dir(lf), os.ModePerm)
+	ioutil.WriteFile(lf, []byte("123"), 0644)
+
+	_, err := e.Apply(context.Background(), "")
+	assert.NoError(t, err)
+}
+
+func TestApplyReturnsErrorWhenStateLockedByAnotherOperation(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, failedState)
+	defer cleanup()
+
+	err := e.(*EngineImpl).lockState("")
+	assert.NoError(t, err)
+	defer e.(*EngineImpl).unlockState(&err)
+
+	// the lock is held by the call not the engine
+	_, err = e.Apply(context.Background(), "")
+	assert.IsType(t, &StateLockedError{}, err)
+}
+
+func TestApplyRemovesStateLock(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, failedState)
+	defer cleanup()
+
+	_, err := e.Apply(context.Background(), "")
+	assert.NoError(t, err)
+
+	_, err = os.Stat(e.(*EngineImpl).lockFile())
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDestroyReturnsErrorWhenStateLocked(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	_, unlock := writeStateLock(t, e, os.Getpid())
+	defer unlock()
+
+	err := e.Destroy(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster", true)
+	assert.IsType(t, &StateLockedError{}, err)
+
+	testAssertMethodCalled(t, mp, "Destroy", 0)
+}
+
+// writeStateLock locks the state for the engine as if by the process pid,
+// the lock is held until the returned function is called
+func writeStateLock(t *testing.T, e Engine, pid int) (string, func()) {
+	lf := e.(*EngineImpl).lockFile()
+	os.MkdirAll(filepath.Dir(lf), os.ModePerm)
+
+	f, err := os.OpenFile(lf, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tryLockFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fmt.Fprintf(f, "%d", pid)
+
+	return lf, func() {
+		unlockFile(f)
+		f.Close()
+	}
+}
+
+func TestDeleteWorkspaceReturnsErrorWhenStateLocked(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	e.CreateWorkspace("staging")
+	e.SelectWorkspace("staging")
+
+	// the engine uses the state for the selected workspace
+	_, unlock := writeStateLock(t, e, os.Getpid())
+	defer unlock()
+
+	err := e.DeleteWorkspace("staging")
+	assert.IsType(t, &StateLockedError{}, err)
+
+	_, err = os.Stat(workspaceFile("staging"))
+	assert.NoError(t, err)
+}
@@ -1,6 +1,11 @@
 package mocks
 
 import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
 	"github.com/shipyard-run/shipyard/pkg/config"
 	"github.com/shipyard-run/shipyard/pkg/shipyard"
 	"github.com/stretchr/testify/mock"
@@ -22,7 +27,7 @@ func (e *Engine) GetClients() *shipyard.Clients {
 	return nil
 }
 
-func (e *Engine) Apply(path string) ([]config.Resource, error) {
+func (e *Engine) Apply(ctx context.Context, path string) ([]config.Resource, error) {
 	args := e.Called(path)
 
 	if r, ok := args.Get(0).([]config.Resource); ok {
@@ -32,14 +37,330 @@ func (e *Engine) Apply(path string) ([]config.Resource, error) {
 	return nil, args.Error(1)
 }
 
-func (e *Engine) Destroy(path string, all bool) error {
+func (e *Engine) ApplyWithOptions(ctx context.Context, path string, o shipyard.ApplyOptions) ([]config.Resource, error) {
+	args := e.Called(path, o)
+
+	if r, ok := args.Get(0).([]config.Resource); ok {
+		return r, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (e *Engine) ApplyTargets(ctx context.Context, path string, names []string) ([]config.Resource, error) {
+	args := e.Called(path, names)
+
+	if r, ok := args.Get(0).([]config.Resource); ok {
+		return r, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (e *Engine) Pull(path string) error {
+	return e.Called(path).Error(0)
+}
+
+func (e *Engine) Bundle(path, dst string) (*shipyard.BundleManifest, error) {
+	args := e.Called(path, dst)
+
+	if m, ok := args.Get(0).(*shipyard.BundleManifest); ok {
+		return m, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (e *Engine) LoadBundle(src string) (*shipyard.BundleManifest, error) {
+	args := e.Called(src)
+
+	if m, ok := args.Get(0).(*shipyard.BundleManifest); ok {
+		return m, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (e *Engine) Destroy(ctx context.Context, path string, all bool) error {
 	args := e.Called(path, all)
 
 	return args.Error(0)
 }
+
+func (e *Engine) DestroyWithOptions(ctx context.Context, path string, o shipyard.DestroyOptions) error {
+	return e.Called(path, o).Error(0)
+}
+
+func (e *Engine) DestroyResource(ctx context.Context, name string) error {
+	return e.Called(name).Error(0)
+}
+
+func (e *Engine) Upgrade(ctx context.Context, path string) (*shipyard.UpgradeChanges, error) {
+	args := e.Called(path)
+
+	if uc, ok := args.Get(0).(*shipyard.UpgradeChanges); ok {
+		return uc, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (e *Engine) Plan(path string) (*shipyard.Plan, error) {
+	args := e.Called(path)
+
+	if p, ok := args.Get(0).(*shipyard.Plan); ok {
+		return p, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (e *Engine) Validate(path string) error {
+	args := e.Called(path)
+
+	return args.Error(0)
+}
+
+func (e *Engine) PushImage(cluster, image string) error {
+	args := e.Called(cluster, image)
+
+	return args.Error(0)
+}
+
+func (e *Engine) BuildImage(ctx context.Context, bc clients.BuildConfig) error {
+	args := e.Called(bc)
+
+	return args.Error(0)
+}
+
+func (e *Engine) GC(age time.Duration) error {
+	args := e.Called(age)
+
+	return args.Error(0)
+}
+
+func (e *Engine) ExportCompose(path string) error {
+	args := e.Called(path)
+
+	return args.Error(0)
+}
+
+func (e *Engine) ExportDevContainer(container, path string) error {
+	args := e.Called(container, path)
+
+	return args.Error(0)
+}
+
+func (e *Engine) ExportTerraform(path string) error {
+	args := e.Called(path)
+
+	return args.Error(0)
+}
+
+func (e *Engine) Taint(resource string) error {
+	args := e.Called(resource)
+
+	return args.Error(0)
+}
+
+func (e *Engine) Refresh() (*shipyard.Drift, error) {
+	args := e.Called()
+
+	if d, ok := args.Get(0).(*shipyard.Drift); ok {
+		return d, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (e *Engine) AuditLog(q shipyard.AuditQuery) ([]shipyard.AuditEntry, error) {
+	args := e.Called(q)
+
+	if ae, ok := args.Get(0).([]shipyard.AuditEntry); ok {
+		return ae, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (e *Engine) BenchmarkReport(runs int) (*shipyard.BenchmarkReport, error) {
+	args := e.Called(runs)
+
+	if r, ok := args.Get(0).(*shipyard.BenchmarkReport); ok {
+		return r, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (e *Engine) Export(path string) error {
+	args := e.Called(path)
+
+	return args.Error(0)
+}
+
+func (e *Engine) Import(path string) ([]config.Resource, error) {
+	args := e.Called(path)
+
+	if r, ok := args.Get(0).([]config.Resource); ok {
+		return r, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (e *Engine) Test(ctx context.Context, path string) (*shipyard.TestReport, error) {
+	args := e.Called(path)
+
+	if r, ok := args.Get(0).(*shipyard.TestReport); ok {
+		return r, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (e *Engine) ApplyClassroom(ctx context.Context, path string, o shipyard.ClassroomOptions) ([]shipyard.InstanceStatus, error) {
+	args := e.Called(path, o)
+
+	if s, ok := args.Get(0).([]shipyard.InstanceStatus); ok {
+		return s, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (e *Engine) ClassroomStatus(name string) ([]shipyard.InstanceStatus, error) {
+	args := e.Called(name)
+
+	if s, ok := args.Get(0).([]shipyard.InstanceStatus); ok {
+		return s, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (e *Engine) DestroyClassroom(ctx context.Context, name string) error {
+	return e.Called(name).Error(0)
+}
+
+func (e *Engine) SBOM() (*shipyard.SBOM, error) {
+	args := e.Called()
+
+	if s, ok := args.Get(0).(*shipyard.SBOM); ok {
+		return s, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (e *Engine) ExportSBOM(path, format string) error {
+	args := e.Called(path, format)
+
+	return args.Error(0)
+}
+
+func (e *Engine) Graph(path, format string) ([]byte, error) {
+	args := e.Called(path, format)
+
+	if d, ok := args.Get(0).([]byte); ok {
+		return d, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (e *Engine) Usage() (*shipyard.Usage, error) {
+	args := e.Called()
+
+	if u, ok := args.Get(0).(*shipyard.Usage); ok {
+		return u, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (e *Engine) Dashboard() http.Handler {
+	args := e.Called()
+
+	if h, ok := args.Get(0).(http.Handler); ok {
+		return h
+	}
+
+	return nil
+}
+
+func (e *Engine) DashboardStatus() (*shipyard.DashboardStatus, error) {
+	args := e.Called()
+
+	if s, ok := args.Get(0).(*shipyard.DashboardStatus); ok {
+		return s, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (e *Engine) RunDemo(name string, o shipyard.DemoOptions) (*shipyard.DemoRecording, error) {
+	args := e.Called(name, o)
+
+	if r, ok := args.Get(0).(*shipyard.DemoRecording); ok {
+		return r, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (e *Engine) Result() *shipyard.Result {
+	if r, ok := e.Called().Get(0).(*shipyard.Result); ok {
+		return r
+	}
+
+	return nil
+}
+
+func (e *Engine) AddEventHandler(h shipyard.EventHandler) {
+	e.Called(h)
+}
+
+func (e *Engine) AddHooks(h shipyard.Hooks) {
+	e.Called(h)
+}
+
+func (e *Engine) SetStateBackend(c *config.StateBackend) error {
+	return e.Called(c).Error(0)
+}
+
+func (e *Engine) Events() (<-chan shipyard.Event, func()) {
+	args := e.Called()
+
+	c, _ := args.Get(0).(<-chan shipyard.Event)
+	cancel, _ := args.Get(1).(func())
+
+	return c, cancel
+}
+
 func (e *Engine) ResourceCount() int {
 	return e.Called().Int(0)
 }
+func (e *Engine) ResourceNames(t config.ResourceType) ([]string, error) {
+	args := e.Called(t)
+
+	if n, ok := args.Get(0).([]string); ok {
+		return n, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (e *Engine) State() (*config.Config, error) {
+	args := e.Called()
+
+	if c, ok := args.Get(0).(*config.Config); ok {
+		return c, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
 func (e *Engine) Blueprint() *config.Blueprint {
 	if bp, ok := e.Called().Get(0).(*config.Blueprint); ok {
 		return bp
@@ -47,3 +368,25 @@ func (e *Engine) Blueprint() *config.Blueprint {
 
 	return nil
 }
+
+func (e *Engine) Workspaces() ([]shipyard.Workspace, error) {
+	args := e.Called()
+
+	if w, ok := args.Get(0).([]shipyard.Workspace); ok {
+		return w, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (e *Engine) CreateWorkspace(name string) error {
+	return e.Called(name).Error(0)
+}
+
+func (e *Engine) SelectWorkspace(name string) error {
+	return e.Called(name).Error(0)
+}
+
+func (e *Engine) DeleteWorkspace(name string) error {
+	return e.Called(name).Error(0)
+}
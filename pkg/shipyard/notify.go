@@ -0,0 +1,174 @@
+package shipyard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NotifySlackEnvVar is the environment variable containing the URL of a
+// Slack incoming webhook which is notified when an operation completes
+const NotifySlackEnvVar = "SHIPYARD_NOTIFY_SLACK"
+
+// NotifyTeamsEnvVar is the environment variable containing the URL of a
+// Microsoft Teams incoming webhook which is notified when an operation
+// completes
+const NotifyTeamsEnvVar = "SHIPYARD_NOTIFY_TEAMS"
+
+// NotifyDesktopEnvVar is the environment variable which enables desktop
+// notifications when set to true
+const NotifyDesktopEnvVar = "SHIPYARD_NOTIFY_DESKTOP"
+
+// NotifyMinDurationEnvVar is the environment variable which sets the
+// minimum duration of an operation before notifications are sent e.g. 30s,
+// operations which complete quickly do not need a notification
+const NotifyMinDurationEnvVar = "SHIPYARD_NOTIFY_MIN_DURATION"
+
+// defaultNotifyMinDuration is used when NotifyMinDurationEnvVar is not set
+const defaultNotifyMinDuration = 1 * time.Minute
+
+// Notification is sent to the configured notifiers when an apply, destroy,
+// or upgrade finishes or fails
+type Notification struct {
+	Operation string
+	Source    string
+	Success   bool
+	Duration  time.Duration
+	Failed    []string // resources which failed in the form [type].[name]
+	Error     string
+}
+
+// Title returns a short summary of the notification
+func (n Notification) Title() string {
+	if n.Success {
+		return fmt.Sprintf("Shipyard %s completed", n.Operation)
+	}
+
+	return fmt.Sprintf("Shipyard %s failed", n.Operation)
+}
+
+// Message returns the details of the notification
+func (n Notification) Message() string {
+	m := fmt.Sprintf("%s completed in %s", strings.Title(n.Operation), n.Duration.Round(time.Second))
+	if !n.Success {
+		m = fmt.Sprintf("%s failed after %s", strings.Title(n.Operation), n.Duration.Round(time.Second))
+	}
+
+	if n.Source != "" {
+		m += fmt.Sprintf(" for %s", n.Source)
+	}
+
+	if len(n.Failed) > 0 {
+		m += fmt.Sprintf("\nFailed resources: %s", strings.Join(n.Failed, ", "))
+	}
+
+	if n.Error != "" {
+		m += fmt.Sprintf("\nError: %s", n.Error)
+	}
+
+	return m
+}
+
+// notifyMinDuration returns the minimum duration for an operation before
+// notifications are sent
+func notifyMinDuration() time.Duration {
+	v := os.Getenv(NotifyMinDurationEnvVar)
+	if v == "" {
+		return defaultNotifyMinDuration
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return defaultNotifyMinDuration
+	}
+
+	return d
+}
+
+// notify sends a notification for the operation to the notifiers configured
+// using environment variables when the operation took longer than the
+// minimum duration. Failing to send a notification does not fail the
+// operation
+func (e *EngineImpl) notify(op, source string, started time.Time, err error) {
+	slack := os.Getenv(NotifySlackEnvVar)
+	teams := os.Getenv(NotifyTeamsEnvVar)
+	desktop, _ := strconv.ParseBool(os.Getenv(NotifyDesktopEnvVar))
+
+	if slack == "" && teams == "" && !desktop {
+		return
+	}
+
+	n := Notification{
+		Operation: op,
+		Source:    source,
+		Success:   err == nil,
+		Duration:  time.Since(started),
+	}
+
+	if n.Duration < notifyMinDuration() {
+		return
+	}
+
+	if err != nil {
+		n.Error = err.Error()
+	}
+
+	// only use the result when it was created by this operation
+	if result := e.Result(); result != nil && !result.Started.Before(started) {
+		for _, r := range result.Resources {
+			if r.Error != "" {
+				n.Failed = append(n.Failed, fmt.Sprintf("%s.%s", r.Type, r.Name))
+			}
+		}
+	}
+
+	if slack != "" {
+		e.notifyWebhook("slack", slack, fmt.Sprintf("*%s*\n%s", n.Title(), n.Message()))
+	}
+
+	if teams != "" {
+		// Teams uses markdown which requires two spaces for a line break
+		e.notifyWebhook("teams", teams, fmt.Sprintf("**%s**  \n%s", n.Title(), strings.ReplaceAll(n.Message(), "\n", "  \n")))
+	}
+
+	if desktop && e.clients.Browser != nil {
+		nerr := e.clients.Browser.Notify(n.Title(), n.Message())
+		if nerr != nil {
+			e.log.Warn("Unable to send desktop notification", "error", nerr)
+		}
+	}
+}
+
+// notifyWebhook posts the text to an incoming webhook, Slack and Teams both
+// accept a JSON payload containing a text field
+func (e *EngineImpl) notifyWebhook(name, url, text string) {
+	if e.clients.HTTP == nil {
+		return
+	}
+
+	d, _ := json.Marshal(map[string]string{"text": text})
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(d))
+	if err != nil {
+		e.log.Warn("Unable to send notification", "notifier", name, "error", err)
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.clients.HTTP.Do(req)
+	if err != nil {
+		e.log.Warn("Unable to send notification", "notifier", name, "error", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		e.log.Warn("Unable to send notification", "notifier", name, "status", resp.StatusCode)
+	}
+}
@@ -0,0 +1,89 @@
+package shipyard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupNotifyTests(t *testing.T, e Engine) (*clientmocks.MockHTTP, *clientmocks.System) {
+	hm := &clientmocks.MockHTTP{}
+	hm.On("Do", mock.Anything).Return(&http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewReader(nil))}, nil)
+
+	sm := &clientmocks.System{}
+	sm.On("Notify", mock.Anything, mock.Anything).Return(nil)
+
+	e.GetClients().HTTP = hm
+	e.GetClients().Browser = sm
+
+	os.Setenv(NotifySlackEnvVar, "https://hooks.slack.com/services/abc")
+	os.Setenv(NotifyDesktopEnvVar, "true")
+	os.Setenv(NotifyMinDurationEnvVar, "0s")
+
+	t.Cleanup(func() {
+		os.Unsetenv(NotifySlackEnvVar)
+		os.Unsetenv(NotifyDesktopEnvVar)
+		os.Unsetenv(NotifyMinDurationEnvVar)
+	})
+
+	return hm, sm
+}
+
+func TestApplySendsNotificationWithFailingResource(t *testing.T) {
+	e, _, _, cleanup := setupTests(map[string]error{"consul": fmt.Errorf("boom")})
+	defer cleanup()
+
+	hm, sm := setupNotifyTests(t, e)
+
+	_, err := e.Apply(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster")
+	assert.Error(t, err)
+
+	hm.AssertNumberOfCalls(t, "Do", 1)
+
+	req := hm.Calls[0].Arguments[0].(*http.Request)
+	assert.Equal(t, "https://hooks.slack.com/services/abc", req.URL.String())
+	assert.Equal(t, http.MethodPost, req.Method)
+
+	body := map[string]string{}
+	json.NewDecoder(req.Body).Decode(&body)
+	assert.Contains(t, body["text"], "Shipyard apply failed")
+	assert.Contains(t, body["text"], "Failed resources: helm.consul")
+
+	sm.AssertCalled(t, "Notify", "Shipyard apply failed", mock.Anything)
+}
+
+func TestDestroySendsNotification(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, usageState)
+	defer cleanup()
+
+	hm, sm := setupNotifyTests(t, e)
+
+	err := e.Destroy(context.Background(), "", true)
+	assert.NoError(t, err)
+
+	hm.AssertNumberOfCalls(t, "Do", 1)
+	sm.AssertCalled(t, "Notify", "Shipyard destroy completed", mock.Anything)
+}
+
+func TestNotificationNotSentForShortOperations(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, usageState)
+	defer cleanup()
+
+	hm, sm := setupNotifyTests(t, e)
+	os.Setenv(NotifyMinDurationEnvVar, "1h")
+
+	err := e.Destroy(context.Background(), "", true)
+	assert.NoError(t, err)
+
+	hm.AssertNotCalled(t, "Do", mock.Anything)
+	sm.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,333 @@
+package shipyard
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// packManifestName is the name of the manifest in a blueprint package
+const packManifestName = "manifest.json"
+
+// packFilesDir is the folder in a blueprint package containing the blueprint files
+const packFilesDir = "blueprint"
+
+// PackManifest describes the contents of a blueprint package
+type PackManifest struct {
+	Name    string            `json:"name"`    // name of the packaged folder
+	Version string            `json:"version"` // version of the blueprint set when the package was created
+	Created time.Time         `json:"created"`
+	Files   map[string]string `json:"files"` // sha256 checksum of each file keyed by the path relative to the blueprint folder
+}
+
+// Pack bundles the blueprint folder src, including any charts, manifests, and
+// other files it contains, into the single archive dst. Hidden files and
+// folders such as .git are not included. The archive contains a manifest with
+// the version and the checksum of every file which is verified by Unpack.
+// Packages can be signed using minisign, the signature is written next to
+// the package with the extension .minisig and is verified against the keys
+// in TrustedKeysDir before the package is applied.
+func Pack(src, dst, version string) (*PackManifest, error) {
+	if version == "" {
+		return nil, xerrors.Errorf("A version must be specified for the package")
+	}
+
+	s, err := os.Stat(src)
+	if err != nil || !s.IsDir() {
+		return nil, xerrors.Errorf("Blueprint %s must be a folder", src)
+	}
+
+	src, err = filepath.Abs(src)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &PackManifest{Name: filepath.Base(src), Version: version, Created: time.Now().UTC(), Files: map[string]string{}}
+
+	err = filepath.Walk(src, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if p != src && strings.HasPrefix(fi.Name(), ".") {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(src, p)
+
+		sum, err := fileChecksum(p)
+		if err != nil {
+			return err
+		}
+
+		m.Files[filepath.ToSlash(rel)] = sum
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to read blueprint %s: %w", src, err)
+	}
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to create package %s: %w", dst, err)
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	md, _ := json.MarshalIndent(m, "", "  ")
+	err = tw.WriteHeader(&tar.Header{Name: packManifestName, Mode: 0644, Size: int64(len(md)), ModTime: m.Created})
+	if err == nil {
+		_, err = tw.Write(md)
+	}
+
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to write manifest: %w", err)
+	}
+
+	// write the files in a stable order so packing the same folder twice
+	// produces the same content
+	files := []string{}
+	for k := range m.Files {
+		files = append(files, k)
+	}
+
+	sort.Strings(files)
+
+	for _, name := range files {
+		err := writePackFile(tw, filepath.Join(src, filepath.FromSlash(name)), path.Join(packFilesDir, name))
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to add %s to package: %w", name, err)
+		}
+	}
+
+	err = tw.Close()
+	if err == nil {
+		err = gw.Close()
+	}
+
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to write package %s: %w", dst, err)
+	}
+
+	return m, nil
+}
+
+// Unpack extracts the blueprint package to the folder dst, the checksum of
+// every file is verified against the manifest. An error is returned when a
+// file has been modified, is missing, or is not listed in the manifest
+func Unpack(src, dst string) (*PackManifest, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to open package %s: %w", src, err)
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, xerrors.Errorf("Package %s is not a valid archive: %w", src, err)
+	}
+
+	tr := tar.NewReader(gr)
+
+	h, err := tr.Next()
+	if err != nil || h.Name != packManifestName {
+		return nil, xerrors.Errorf("Package %s does not contain a manifest", src)
+	}
+
+	m := &PackManifest{}
+	err = json.NewDecoder(tr).Decode(m)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to read manifest: %w", err)
+	}
+
+	extracted := map[string]bool{}
+
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to read package %s: %w", src, err)
+		}
+
+		if h.Typeflag != tar.TypeReg {
+			return nil, xerrors.Errorf("Package contains unsupported entry %s", h.Name)
+		}
+
+		// only files listed in the manifest are extracted, this also stops
+		// paths which would be written outside of the destination
+		name := strings.TrimPrefix(h.Name, packFilesDir+"/")
+		sum, ok := m.Files[name]
+		if !ok || name == h.Name || path.Clean(name) != name || strings.HasPrefix(name, "../") {
+			return nil, xerrors.Errorf("Package contains file %s which is not in the manifest", h.Name)
+		}
+
+		got, err := extractPackFile(tr, filepath.Join(dst, filepath.FromSlash(name)), os.FileMode(h.Mode))
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to extract %s: %w", name, err)
+		}
+
+		if got != sum {
+			return nil, xerrors.Errorf("Checksum for %s does not match the manifest, the package may be corrupt", name)
+		}
+
+		extracted[name] = true
+	}
+
+	for name := range m.Files {
+		if !extracted[name] {
+			return nil, xerrors.Errorf("File %s in the manifest is missing from the package", name)
+		}
+	}
+
+	return m, nil
+}
+
+// unpackBlueprint extracts a blueprint package to the package cache and
+// returns the folder containing the blueprint, packages are only extracted
+// once
+func unpackBlueprint(src string) (string, error) {
+	sum, err := fileChecksum(src)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to read package %s: %w", src, err)
+	}
+
+	dst := utils.GetPackageLocalFolder(sum)
+	if _, err := os.Stat(dst); err == nil {
+		return dst, nil
+	}
+
+	// extract to a temporary folder so that a failed extraction is not used
+	tmp := dst + ".tmp"
+	os.RemoveAll(tmp)
+
+	_, err = Unpack(src, tmp)
+	if err != nil {
+		os.RemoveAll(tmp)
+		return "", err
+	}
+
+	err = os.Rename(tmp, dst)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to unpack %s: %w", src, err)
+	}
+
+	return dst, nil
+}
+
+// FetchPackage downloads the remote blueprint package and its minisign
+// signature, uri.minisig, to the download cache and returns the local path of
+// the package. The signature is optional, packages without one can only be
+// run when no keys are trusted
+func FetchPackage(g clients.Getter, uri string) (string, error) {
+	dir, err := g.Fetch(uri)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to fetch package %s: %w", uri, err)
+	}
+
+	// go-getter options such as the checksum only apply to the package
+	base := strings.SplitN(uri, "?", 2)[0]
+	pkg := filepath.Join(dir, path.Base(base))
+
+	// remove any signature for a previous download so that a package which is
+	// no longer signed is not verified with it
+	os.Remove(pkg + SignatureExtension)
+
+	sigDir, err := g.Fetch(base + SignatureExtension)
+	if err != nil {
+		return pkg, nil
+	}
+
+	sig, err := ioutil.ReadFile(filepath.Join(sigDir, path.Base(base)+SignatureExtension))
+	if err != nil {
+		return pkg, nil
+	}
+
+	err = ioutil.WriteFile(pkg+SignatureExtension, sig, 0644)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to write signature for %s: %w", uri, err)
+	}
+
+	return pkg, nil
+}
+
+func writePackFile(tw *tar.Writer, src, name string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	err = tw.WriteHeader(&tar.Header{Name: name, Mode: int64(fi.Mode().Perm()), Size: fi.Size(), ModTime: fi.ModTime()})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// extractPackFile writes the contents of r to dst and returns the checksum
+func extractPackFile(r io.Reader, dst string, mode os.FileMode) (string, error) {
+	err := os.MkdirAll(filepath.Dir(dst), os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm())
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+
+	_, err = io.Copy(f, io.TeeReader(r, h))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+func fileChecksum(p string) (string, error) {
+	d, err := ioutil.ReadFile(p)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(d)), nil
+}
@@ -0,0 +1,149 @@
+package shipyard
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupPackTests(t *testing.T) (string, string) {
+	src, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	os.MkdirAll(filepath.Join(src, "helm"), os.ModePerm)
+	os.MkdirAll(filepath.Join(src, ".git"), os.ModePerm)
+
+	ioutil.WriteFile(filepath.Join(src, "container.hcl"), []byte(smokeTestContainer), 0644)
+	ioutil.WriteFile(filepath.Join(src, "helm", "values.yaml"), []byte("server:\n  enabled: true\n"), 0644)
+	ioutil.WriteFile(filepath.Join(src, ".git", "config"), []byte(""), 0644)
+
+	out, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	return src, out
+}
+
+// writeTestPackage creates a package containing the given files and manifest
+func writeTestPackage(t *testing.T, dst string, m PackManifest, files map[string]string) {
+	f, err := os.Create(dst)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	md, _ := json.Marshal(m)
+	tw.WriteHeader(&tar.Header{Name: "manifest.json", Mode: 0644, Size: int64(len(md))})
+	tw.Write(md)
+
+	for k, v := range files {
+		tw.WriteHeader(&tar.Header{Name: k, Mode: 0644, Size: int64(len(v))})
+		tw.Write([]byte(v))
+	}
+
+	tw.Close()
+	gw.Close()
+}
+
+func TestPackAndUnpackBlueprint(t *testing.T) {
+	src, out := setupPackTests(t)
+	defer os.RemoveAll(src)
+	defer os.RemoveAll(out)
+
+	pack := filepath.Join(out, "consul"+utils.BlueprintPackageExtension)
+
+	m, err := Pack(src, pack, "v1.0.0")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "v1.0.0", m.Version)
+	assert.Len(t, m.Files, 2)
+	assert.Contains(t, m.Files, "helm/values.yaml")
+
+	dst := filepath.Join(out, "unpacked")
+	um, err := Unpack(pack, dst)
+	assert.NoError(t, err)
+
+	assert.Equal(t, m.Files, um.Files)
+	assert.FileExists(t, filepath.Join(dst, "container.hcl"))
+	assert.FileExists(t, filepath.Join(dst, "helm", "values.yaml"))
+	assert.NoDirExists(t, filepath.Join(dst, ".git"))
+}
+
+func TestPackRequiresVersion(t *testing.T) {
+	src, out := setupPackTests(t)
+	defer os.RemoveAll(src)
+	defer os.RemoveAll(out)
+
+	_, err := Pack(src, filepath.Join(out, "consul.yardpack"), "")
+	assert.Error(t, err)
+}
+
+func TestUnpackReturnsErrorWhenChecksumDoesNotMatch(t *testing.T) {
+	out, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(out)
+
+	pack := filepath.Join(out, "consul.yardpack")
+	writeTestPackage(t, pack,
+		PackManifest{Version: "v1", Files: map[string]string{"main.hcl": "abc"}},
+		map[string]string{"blueprint/main.hcl": "modified"},
+	)
+
+	_, err := Unpack(pack, filepath.Join(out, "unpacked"))
+	assert.Error(t, err)
+}
+
+func TestUnpackReturnsErrorForFilesNotInManifest(t *testing.T) {
+	out, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(out)
+
+	pack := filepath.Join(out, "consul.yardpack")
+	writeTestPackage(t, pack,
+		PackManifest{Version: "v1", Files: map[string]string{}},
+		map[string]string{"blueprint/../../evil.sh": "rm -rf /"},
+	)
+
+	_, err := Unpack(pack, filepath.Join(out, "unpacked"))
+	assert.Error(t, err)
+	assert.NoFileExists(t, filepath.Join(out, "evil.sh"))
+}
+
+func TestUnpackReturnsErrorWhenFileMissing(t *testing.T) {
+	out, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(out)
+
+	pack := filepath.Join(out, "consul.yardpack")
+	writeTestPackage(t, pack,
+		PackManifest{Version: "v1", Files: map[string]string{"main.hcl": "abc"}},
+		map[string]string{},
+	)
+
+	_, err := Unpack(pack, filepath.Join(out, "unpacked"))
+	assert.Error(t, err)
+}
+
+func TestApplyLoadsBlueprintPackage(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	src, out := setupPackTests(t)
+	defer os.RemoveAll(src)
+	defer os.RemoveAll(out)
+
+	pack := filepath.Join(out, "consul"+utils.BlueprintPackageExtension)
+	_, err := Pack(src, pack, "v1.0.0")
+	assert.NoError(t, err)
+
+	res, err := e.Apply(context.Background(), pack)
+	assert.NoError(t, err)
+
+	assert.Len(t, res, 1)
+	testAssertMethodCalled(t, mp, "Create", 1)
+}
@@ -0,0 +1,112 @@
+package shipyard
+
+import (
+	"context"
+	"sync"
+
+	"github.com/shipyard-run/shipyard/pkg/providers"
+)
+
+// setMaxParallel limits the number of resources which are created or
+// destroyed, and images which are pulled, at the same time. When n is 0 or
+// less there is no limit
+func (e *EngineImpl) setMaxParallel(n int) {
+	if n <= 0 {
+		e.workers = nil
+		return
+	}
+
+	e.workers = make(chan struct{}, n)
+}
+
+// worker is a slot from the pool of workers, the slot is given up while the
+// holder waits for health checks or a retry so that waiting resources do not
+// stop other resources being created
+type worker struct {
+	w chan struct{}
+
+	m        sync.Mutex
+	held     bool
+	released bool // set by release, the slot is not acquired again
+}
+
+// acquireWorker blocks until a worker is available or ctx is cancelled, the
+// walker starts a goroutine for every resource whose dependencies are
+// complete so the workers bound the operations running against Docker at any
+// one time. The returned worker must be released once the operation completes
+func (e *EngineImpl) acquireWorker(ctx context.Context) (*worker, error) {
+	wk := &worker{w: e.workers}
+
+	err := wk.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return wk, nil
+}
+
+func (wk *worker) acquire(ctx context.Context) error {
+	if wk.w == nil {
+		return nil
+	}
+
+	select {
+	case wk.w <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	wk.m.Lock()
+	defer wk.m.Unlock()
+
+	// the worker was released while waiting for the slot
+	if wk.released {
+		<-wk.w
+		return nil
+	}
+
+	wk.held = true
+
+	return nil
+}
+
+// release returns the slot to the pool, release can be called more than once
+func (wk *worker) release() {
+	wk.m.Lock()
+	defer wk.m.Unlock()
+
+	wk.released = true
+	wk.give()
+}
+
+func (wk *worker) give() {
+	if wk.w != nil && wk.held {
+		<-wk.w
+		wk.held = false
+	}
+}
+
+// idle gives up the slot while wait runs and blocks until a slot is
+// available again once wait returns
+func (wk *worker) idle(ctx context.Context, wait func() error) error {
+	wk.m.Lock()
+	wk.give()
+	wk.m.Unlock()
+
+	err := wait()
+
+	aerr := wk.acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	return aerr
+}
+
+// withIdle returns a copy of ctx where providers give up the worker while
+// they wait for health checks
+func (wk *worker) withIdle(ctx context.Context) context.Context {
+	return providers.WithIdle(ctx, func(wait func() error) error {
+		return wk.idle(ctx, wait)
+	})
+}
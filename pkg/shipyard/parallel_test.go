@@ -0,0 +1,132 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+)
+
+func TestApplyReturnsErrorForEveryFailedResource(t *testing.T) {
+	e, _, mp, cleanup := setupTests(map[string]error{"consul": fmt.Errorf("consul failed"), "consul_2": fmt.Errorf("consul_2 failed")})
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "pull.hcl"), []byte(pullConfig), 0644)
+
+	_, err = e.Apply(context.Background(), dir)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "consul failed")
+	assert.Contains(t, err.Error(), "consul_2 failed")
+
+	// resources which do not depend on the failed resources are created
+	assert.Equal(t, 1, providerCalls(mp, "k3s", "Create"))
+
+	// the outcome of every resource is recorded in the state
+	sc := config.New()
+	err = sc.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	r, _ := sc.FindResource("container.consul_2")
+	assert.Equal(t, config.Failed, r.Info().Status)
+
+	r, _ = sc.FindResource("k8s_cluster.k3s")
+	assert.Equal(t, config.Applied, r.Info().Status)
+}
+
+func TestApplyWithMaxParallelCreatesResources(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	_, err := e.ApplyWithOptions(context.Background(), dir, ApplyOptions{DisableBrowser: true, MaxParallel: 1})
+	assert.NoError(t, err)
+
+	testAssertMethodCalled(t, mp, "Create", 3)
+
+	// the limit only applies to the apply which set it
+	assert.Nil(t, e.(*EngineImpl).workers)
+}
+
+func TestAcquireWorkerBlocksWhenLimitReached(t *testing.T) {
+	e := &EngineImpl{}
+	e.setMaxParallel(1)
+
+	wk, err := e.acquireWorker(context.Background())
+	assert.NoError(t, err)
+
+	acquired := make(chan struct{})
+	go func() {
+		w, _ := e.acquireWorker(context.Background())
+		w.release()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Expected worker to block until released")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	wk.release()
+
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("Expected worker to be acquired once released")
+	}
+}
+
+func TestAcquireWorkerReturnsErrorWhenContextCancelled(t *testing.T) {
+	e := &EngineImpl{}
+	e.setMaxParallel(1)
+
+	wk, err := e.acquireWorker(context.Background())
+	assert.NoError(t, err)
+	defer wk.release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	_, err = e.acquireWorker(ctx)
+	assert.True(t, xerrors.Is(err, context.Canceled))
+}
+
+func TestWorkerIsReleasedWhileIdle(t *testing.T) {
+	e := &EngineImpl{}
+	e.setMaxParallel(1)
+
+	wk, err := e.acquireWorker(context.Background())
+	assert.NoError(t, err)
+	defer wk.release()
+
+	err = providers.Idle(wk.withIdle(context.Background()), func() error {
+		// the slot is free while waiting
+		w, err := e.acquireWorker(context.Background())
+		if err != nil {
+			return err
+		}
+
+		w.release()
+		return nil
+	})
+	assert.NoError(t, err)
+
+	// the slot is held again once the wait completes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = e.acquireWorker(ctx)
+	assert.Error(t, err)
+}
@@ -0,0 +1,172 @@
+package shipyard
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// PlanReasonChanged is used when attributes of the resource have changed
+const PlanReasonChanged = "changed"
+
+// PlanReasonTainted is used when the resource has been tainted
+const PlanReasonTainted = "tainted"
+
+// PlanReasonFailed is used when the resource failed to be created
+const PlanReasonFailed = "failed"
+
+// Plan is the difference between a blueprint and the running environment
+// as Apply would resolve it, resources are in the form [type].[name] and
+// each list is sorted by name
+type Plan struct {
+	Create    []string     `json:"create"`
+	Replace   []PlanChange `json:"replace"` // tainted or failed resources which are destroyed and created again
+	Update    []string     `json:"update"`  // applied again without being destroyed
+	Unchanged []string     `json:"unchanged"`
+
+	// Apply does not modify running resources or destroy resources removed
+	// from the blueprint, Upgrade replaces changed resources and destroys
+	// removed resources
+	Changed []PlanChange `json:"changed"` // running resources with a different definition in the blueprint
+	Removed []string     `json:"removed"` // resources in the state which are no longer in the blueprint
+}
+
+// PlanChange is a resource which differs from the state
+type PlanChange struct {
+	Name       string   `json:"name"`
+	Reason     string   `json:"reason"`
+	Attributes []string `json:"attributes,omitempty"` // attributes which differ from the state when the reason is changed
+}
+
+// Plan compares the blueprint at path with the state and returns the
+// changes Apply would make, when nothing is running every resource is
+// created. Docker and Kubernetes are not called so resources which have
+// stopped, and are created again by Apply, are reported as unchanged. The
+// state is not changed
+func (e *EngineImpl) Plan(path string) (*Plan, error) {
+	cc, err := e.parseConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sc, err := e.readState(path)
+	if err == config.StateNotFoundError {
+		sc, err = config.New(), nil
+	}
+
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to read state: %w", err)
+	}
+
+	p := &Plan{
+		Create:    []string{},
+		Replace:   []PlanChange{},
+		Update:    []string{},
+		Unchanged: []string{},
+		Changed:   []PlanChange{},
+		Removed:   []string{},
+	}
+
+	// compare the running resources before the state is merged with the
+	// blueprint in the same way as Apply
+	for _, r := range sc.Resources {
+		n := resourceName(r)
+
+		nr, err := cc.FindResource(n)
+		if err != nil {
+			p.Removed = append(p.Removed, n)
+			continue
+		}
+
+		if r.Info().Status != config.Applied {
+			continue
+		}
+
+		if a := resourceChanges(r, nr); len(a) > 0 {
+			p.Changed = append(p.Changed, PlanChange{Name: n, Reason: PlanReasonChanged, Attributes: a})
+		}
+	}
+
+	sc.Merge(cc)
+
+	// check the dependencies can be resolved before anything is created
+	d, err := sc.DoYaLikeDAGs()
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to create dependency graph: %w", err)
+	}
+
+	err = d.Validate()
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to validate dependency graph: %w", err)
+	}
+
+	for _, r := range sc.Resources {
+		n := resourceName(r)
+
+		switch {
+		case r.Info().Status == config.PendingCreation:
+			p.Create = append(p.Create, n)
+		case r.Info().Status == config.PendingModification:
+			p.Replace = append(p.Replace, PlanChange{Name: n, Reason: PlanReasonTainted})
+		case r.Info().Status == config.Failed:
+			p.Replace = append(p.Replace, PlanChange{Name: n, Reason: PlanReasonFailed})
+		case reapply(r):
+			p.Update = append(p.Update, n)
+		case r.Info().Status == config.PendingUpdate:
+			p.Unchanged = append(p.Unchanged, n)
+		}
+	}
+
+	sort.Strings(p.Create)
+	sort.Strings(p.Update)
+	sort.Strings(p.Unchanged)
+	sort.Strings(p.Removed)
+	sort.Slice(p.Replace, func(i, j int) bool { return p.Replace[i].Name < p.Replace[j].Name })
+	sort.Slice(p.Changed, func(i, j int) bool { return p.Changed[i].Name < p.Changed[j].Name })
+
+	return p, nil
+}
+
+// HasChanges returns true when applying the plan would change the
+// environment
+func (p *Plan) HasChanges() bool {
+	return len(p.Create) > 0 || len(p.Replace) > 0 || len(p.Update) > 0
+}
+
+// String returns the plan as a diff, resources to be created are prefixed
+// with +, replaced with -/+, and applied again with ~. Changed and removed
+// resources which Apply leaves running are listed after the summary
+func (p *Plan) String() string {
+	sb := strings.Builder{}
+
+	for _, n := range p.Create {
+		fmt.Fprintf(&sb, "+ %s\n", n)
+	}
+
+	for _, c := range p.Replace {
+		fmt.Fprintf(&sb, "-/+ %s (%s)\n", c.Name, c.Reason)
+	}
+
+	for _, n := range p.Update {
+		fmt.Fprintf(&sb, "~ %s\n", n)
+	}
+
+	fmt.Fprintf(&sb, "\nPlan: %d to create, %d to replace, %d to update, %d unchanged\n", len(p.Create), len(p.Replace), len(p.Update), len(p.Unchanged))
+
+	if len(p.Changed) > 0 || len(p.Removed) > 0 {
+		fmt.Fprintf(&sb, "\nNot changed by apply, run upgrade to replace or destroy:\n")
+	}
+
+	for _, c := range p.Changed {
+		fmt.Fprintf(&sb, "! %s (%s)\n", c.Name, strings.Join(c.Attributes, ", "))
+	}
+
+	for _, n := range p.Removed {
+		fmt.Fprintf(&sb, "! %s (removed from the blueprint)\n", n)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,155 @@
+package shipyard
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers/mocks"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPlanReturnsChangesWithoutCallingProviders(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir := setupUpgradeTests(t, e)
+	defer os.RemoveAll(dir)
+
+	*mp = []*mocks.MockProvider{}
+
+	p, err := e.Plan(filepath.Join(dir, "v2"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"container.nginx"}, p.Create)
+	assert.Equal(t, []string{"container.consul", "container.consul_client", "container.redis", "network.cloud"}, p.Unchanged)
+	assert.Len(t, p.Replace, 0)
+
+	// apply leaves changed and removed resources running
+	assert.Equal(t, []PlanChange{
+		{Name: "container.consul", Reason: PlanReasonChanged, Attributes: []string{"image"}},
+	}, p.Changed)
+	assert.Equal(t, []string{"container.vault"}, p.Removed)
+
+	assert.True(t, p.HasChanges())
+	assert.Contains(t, p.String(), "+ container.nginx")
+	assert.Contains(t, p.String(), "! container.consul (image)")
+	assert.Contains(t, p.String(), "! container.vault (removed from the blueprint)")
+	assert.Contains(t, p.String(), "Plan: 1 to create, 0 to replace, 0 to update, 4 unchanged")
+	assert.Len(t, *mp, 0)
+
+	// the state is not changed
+	sc := config.New()
+	err = sc.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	_, err = sc.FindResource("container.vault")
+	assert.NoError(t, err)
+}
+
+func TestPlanWithNoStateCreatesAllResources(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir := setupUpgradeTests(t, e)
+	defer os.RemoveAll(dir)
+
+	os.Remove(utils.StatePath())
+
+	p, err := e.Plan(filepath.Join(dir, "v1"))
+	assert.NoError(t, err)
+
+	assert.Len(t, p.Create, 5)
+	assert.Len(t, p.Replace, 0)
+	assert.Len(t, p.Removed, 0)
+}
+
+func TestPlanReplacesTaintedResources(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir := setupUpgradeTests(t, e)
+	defer os.RemoveAll(dir)
+
+	err := e.Taint("container.redis")
+	assert.NoError(t, err)
+
+	p, err := e.Plan(filepath.Join(dir, "v1"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []PlanChange{{Name: "container.redis", Reason: PlanReasonTainted}}, p.Replace)
+	assert.Contains(t, p.String(), "-/+ container.redis (tainted)")
+}
+
+func TestPlanMatchesApply(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir := setupUpgradeTests(t, e)
+	defer os.RemoveAll(dir)
+
+	err := e.Taint("container.redis")
+	assert.NoError(t, err)
+
+	p, err := e.Plan(filepath.Join(dir, "v2"))
+	assert.NoError(t, err)
+
+	*mp = []*mocks.MockProvider{}
+
+	// the running containers are not created again
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	removeOn(&ct.Mock, "FindContainerIDs")
+	removeOn(&ct.Mock, "FindContainers")
+	ct.On("FindContainerIDs", "nginx", mock.Anything).Return([]string{}, nil)
+	ct.On("FindContainers", "nginx", mock.Anything).Return([]config.ContainerInfo{}, nil)
+	ct.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{"abc"}, nil)
+	ct.On("FindContainers", mock.Anything, mock.Anything).Return([]config.ContainerInfo{{ID: "abc", Running: true}}, nil)
+
+	_, err = e.Apply(context.Background(), filepath.Join(dir, "v2"))
+	assert.NoError(t, err)
+
+	// apply destroys the tainted resource and creates the new resource, the
+	// changed resource is left running
+	assert.Equal(t, 1, providerCalls(mp, "nginx", "Create"))
+	assert.Equal(t, 1, providerCalls(mp, "redis", "Destroy"))
+	assert.Equal(t, 1, providerCalls(mp, "redis", "Create"))
+	assert.Equal(t, 0, providerCalls(mp, "consul", "Create"))
+	testAssertMethodCalled(t, mp, "Create", 2)
+
+	assert.Equal(t, []string{"container.nginx"}, p.Create)
+	assert.Equal(t, []PlanChange{{Name: "container.redis", Reason: PlanReasonTainted}}, p.Replace)
+}
+
+func TestPlanWithSameBlueprintHasNoChanges(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir := setupUpgradeTests(t, e)
+	defer os.RemoveAll(dir)
+
+	p, err := e.Plan(filepath.Join(dir, "v1"))
+	assert.NoError(t, err)
+
+	assert.False(t, p.HasChanges())
+}
+
+func TestPlanIgnoresMovedBlueprint(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir := setupUpgradeTests(t, e)
+	defer os.RemoveAll(dir)
+
+	// paths in the blueprint folder are compared relative to the folder
+	os.Rename(filepath.Join(dir, "v1"), filepath.Join(dir, "moved"))
+
+	p, err := e.Plan(filepath.Join(dir, "moved"))
+	assert.NoError(t, err)
+
+	assert.Len(t, p.Changed, 0)
+}
@@ -0,0 +1,260 @@
+package shipyard
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl2/gohcl"
+	"github.com/hashicorp/hcl2/hclparse"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// PolicyEnvVar is the environment variable containing the location of the
+// policy file, when not set the policy is read from policy.hcl in the
+// Shipyard home folder
+const PolicyEnvVar = "SHIPYARD_POLICY"
+
+// Policy defines the rules which blueprints must follow before they can be
+// applied, organizations use a policy to forbid patterns in the blueprints
+// run by their users e.g.
+//
+//	deny_privileged       = true
+//	deny_host_ports       = true
+//	require_pinned_images = true
+//	allowed_registries    = ["docker.io/library", "ghcr.io/acme"]
+type Policy struct {
+	// DenyPrivileged forbids containers and sidecars running in privileged mode
+	DenyPrivileged bool `hcl:"deny_privileged,optional"`
+	// DenyHostPorts forbids ports published on the host, host ports are bound
+	// to all interfaces and are reachable from other machines
+	DenyHostPorts bool `hcl:"deny_host_ports,optional"`
+	// RequirePinnedImages forbids images without a tag, with the latest tag
+	// or without a digest
+	RequirePinnedImages bool `hcl:"require_pinned_images,optional"`
+	// AllowedRegistries restricts images to the given registries or
+	// repository prefixes, images from Docker Hub use the prefix docker.io
+	AllowedRegistries []string `hcl:"allowed_registries,optional"`
+}
+
+// PolicyViolation is a resource which does not follow a rule in the policy
+type PolicyViolation struct {
+	Resource string `json:"resource,omitempty"` // [type].[name], empty when the violation is not caused by a single resource
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+// PolicyError is returned by Apply when the blueprint violates the policy
+type PolicyError struct {
+	Violations []PolicyViolation
+}
+
+func (p *PolicyError) Error() string {
+	return fmt.Sprintf("Blueprint violates policy:\n%s", formatViolations(p.Violations))
+}
+
+// formatViolations returns the violations one per line
+func formatViolations(violations []PolicyViolation) string {
+	v := []string{}
+	for _, pv := range violations {
+		if pv.Resource == "" {
+			v = append(v, fmt.Sprintf("  %s (%s)", pv.Message, pv.Rule))
+			continue
+		}
+
+		v = append(v, fmt.Sprintf("  %s: %s (%s)", pv.Resource, pv.Message, pv.Rule))
+	}
+
+	return strings.Join(v, "\n")
+}
+
+// loadPolicy reads the policy file, nil is returned when no policy exists
+func loadPolicy() (*Policy, error) {
+	p := &Policy{}
+
+	ok, err := decodeEngineConfig(PolicyEnvVar, "policy.hcl", p)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// decodeEngineConfig decodes the HCL file set by the environment variable
+// into v, when the variable is not set the file name is read from the
+// Shipyard home folder. False is returned when the file does not exist
+func decodeEngineConfig(envVar, name string, v interface{}) (bool, error) {
+	f := os.Getenv(envVar)
+	if f == "" {
+		f = filepath.Join(utils.ShipyardHome(), name)
+	}
+
+	if _, err := os.Stat(f); os.IsNotExist(err) {
+		// a file set explicitly must exist so it is not silently ignored
+		if os.Getenv(envVar) != "" {
+			return false, xerrors.Errorf("File %s does not exist", f)
+		}
+
+		return false, nil
+	}
+
+	hf, diag := hclparse.NewParser().ParseHCLFile(f)
+	if diag.HasErrors() {
+		return false, xerrors.Errorf("Unable to parse %s: %s", f, diag.Error())
+	}
+
+	diag = gohcl.DecodeBody(hf.Body, nil, v)
+	if diag.HasErrors() {
+		return false, xerrors.Errorf("Unable to parse %s: %s", f, diag.Error())
+	}
+
+	return true, nil
+}
+
+// checkPolicy evaluates the policy against the resources which will be
+// created or changed by the apply, resources which have already been
+// applied are not checked
+func (e *EngineImpl) checkPolicy() error {
+	p, err := loadPolicy()
+	if err != nil || p == nil {
+		return err
+	}
+
+	violations := []PolicyViolation{}
+
+	for _, r := range e.config.Resources {
+		if pendingApply(r) {
+			violations = append(violations, p.Evaluate(r)...)
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Resource < violations[j].Resource
+	})
+
+	return &PolicyError{violations}
+}
+
+// Evaluate returns the rules in the policy which the resource violates
+func (p *Policy) Evaluate(r config.Resource) []PolicyViolation {
+	violations := []PolicyViolation{}
+
+	add := func(rule, msg string, args ...interface{}) {
+		violations = append(violations, PolicyViolation{Resource: resourceName(r), Rule: rule, Message: fmt.Sprintf(msg, args...)})
+	}
+
+	if p.DenyPrivileged {
+		switch v := r.(type) {
+		case *config.Container:
+			if v.Privileged {
+				add("deny_privileged", "privileged containers are not allowed")
+			}
+		case *config.Sidecar:
+			if v.Privileged {
+				add("deny_privileged", "privileged containers are not allowed")
+			}
+		}
+	}
+
+	// the Kubernetes API port is required to use the cluster so is allowed
+	if _, ok := r.(*config.K8sCluster); p.DenyHostPorts && !ok {
+		for _, ep := range resourceEndpoints(r) {
+			add("deny_host_ports", "host port %s is published on all interfaces", strings.TrimPrefix(ep, "localhost:"))
+		}
+	}
+
+	for _, i := range policyImages(r) {
+		if p.RequirePinnedImages && !imagePinned(i.Name) {
+			add("require_pinned_images", "image %s must use a version tag or digest", i.Name)
+		}
+
+		if len(p.AllowedRegistries) > 0 && !imageAllowed(i.Name, p.AllowedRegistries) {
+			add("allowed_registries", "image %s is not from an allowed registry", i.Name)
+		}
+	}
+
+	return violations
+}
+
+// policyImages returns the images defined in the blueprint for a resource,
+// the base images used by the providers are not included
+func policyImages(r config.Resource) []config.Image {
+	switch v := r.(type) {
+	case *config.Container:
+		return []config.Image{v.Image}
+	case *config.Sidecar:
+		return []config.Image{v.Image}
+	case *config.ExecRemote:
+		if v.Image != nil {
+			return []config.Image{*v.Image}
+		}
+	case *config.Docs:
+		if v.Image != nil {
+			return []config.Image{*v.Image}
+		}
+	case *config.K8sCluster:
+		return v.Images
+	case *config.NomadCluster:
+		return v.Images
+	}
+
+	return nil
+}
+
+// imagePinned returns true when the image has a digest or a tag which is
+// not latest
+func imagePinned(name string) bool {
+	if strings.Contains(name, "@sha256:") {
+		return true
+	}
+
+	// the tag follows the last colon after the registry and repository
+	parts := strings.Split(name, "/")
+	last := parts[len(parts)-1]
+
+	i := strings.LastIndex(last, ":")
+	if i < 0 {
+		return false
+	}
+
+	return last[i+1:] != "latest"
+}
+
+// imageAllowed returns true when the fully qualified image name starts with
+// one of the allowed registries or repository prefixes
+func imageAllowed(name string, allowed []string) bool {
+	fq := qualifiedImageName(name)
+
+	for _, a := range allowed {
+		a = strings.TrimSuffix(a, "/")
+		if fq == a || strings.HasPrefix(fq, a+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// qualifiedImageName adds the Docker Hub registry and the library
+// repository to image names which do not specify a registry
+func qualifiedImageName(name string) string {
+	parts := strings.Split(name, "/")
+
+	if len(parts) > 1 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return name
+	}
+
+	if len(parts) == 1 {
+		return "docker.io/library/" + name
+	}
+
+	return "docker.io/" + name
+}
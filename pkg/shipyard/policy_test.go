@@ -0,0 +1,102 @@
+package shipyard
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+)
+
+func setupPolicyTests(t *testing.T, policy string) string {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	ioutil.WriteFile(filepath.Join(dir, "main.hcl"), []byte(policyBlueprint), 0644)
+
+	os.MkdirAll(utils.ShipyardHome(), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(utils.ShipyardHome(), "policy.hcl"), []byte(policy), 0644)
+
+	return dir
+}
+
+func TestApplyFailsWhenBlueprintViolatesPolicy(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir := setupPolicyTests(t, `
+deny_privileged       = true
+deny_host_ports       = true
+require_pinned_images = true
+allowed_registries    = ["docker.io/library"]
+`)
+	defer os.RemoveAll(dir)
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.Error(t, err)
+
+	pe := &PolicyError{}
+	assert.True(t, xerrors.As(err, &pe))
+
+	assert.Equal(t, []PolicyViolation{
+		PolicyViolation{Resource: "container.consul", Rule: "deny_privileged", Message: "privileged containers are not allowed"},
+		PolicyViolation{Resource: "container.consul", Rule: "deny_host_ports", Message: "host port 8500 is published on all interfaces"},
+		PolicyViolation{Resource: "container.consul", Rule: "require_pinned_images", Message: "image consul must use a version tag or digest"},
+		PolicyViolation{Resource: "container.vault", Rule: "allowed_registries", Message: "image hashicorp/vault:1.5.0 is not from an allowed registry"},
+	}, pe.Violations)
+
+	// no resources are created
+	assert.Len(t, *mp, 0)
+}
+
+func TestApplySucceedsWhenBlueprintFollowsPolicy(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir := setupPolicyTests(t, `
+allowed_registries = ["docker.io"]
+`)
+	defer os.RemoveAll(dir)
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	assert.Len(t, *mp, 2)
+}
+
+func TestApplyFailsWhenPolicyFileSetAndMissing(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	os.Setenv(PolicyEnvVar, "/missing/policy.hcl")
+	defer os.Unsetenv(PolicyEnvVar)
+
+	_, err := e.Apply(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster")
+	assert.Error(t, err)
+}
+
+var policyBlueprint = `
+container "consul" {
+  image {
+    name = "consul"
+  }
+
+  privileged = true
+
+  port {
+    local  = "8500"
+    remote = "8500"
+    host   = "8500"
+  }
+}
+
+container "vault" {
+  image {
+    name = "hashicorp/vault:1.5.0"
+  }
+}
+`
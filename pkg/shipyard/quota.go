@@ -0,0 +1,235 @@
+package shipyard
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// QuotaEnvVar is the environment variable containing the location of the
+// quota file, when not set the quota is read from quota.hcl in the Shipyard
+// home folder
+const QuotaEnvVar = "SHIPYARD_QUOTA"
+
+// Quota limits the resources which can be created on a machine, quotas stop
+// one blueprint using all the resources of a machine shared by multiple
+// users e.g.
+//
+//	max_containers = 20
+//	max_memory     = 8192
+//	max_cpu        = 4096
+//	allowed_ports  = ["8000-8999", "443"]
+//
+// Memory and CPU are the totals of the limits set by the resources, when a
+// limit is set by the quota containers and clusters must set their own limit
+type Quota struct {
+	// MaxContainers is the maximum number of containers in the environment
+	MaxContainers int `hcl:"max_containers,optional"`
+	// MaxMemory is the maximum total memory in MB
+	MaxMemory int `hcl:"max_memory,optional"`
+	// MaxCPU is the maximum total CPU where 1 CPU = 1024
+	MaxCPU int `hcl:"max_cpu,optional"`
+	// AllowedPorts are the host ports or ranges of ports e.g. 8000-8999
+	// which resources can publish, when empty any port can be used
+	AllowedPorts []string `hcl:"allowed_ports,optional"`
+}
+
+// QuotaError is returned by Apply when the environment would exceed the quota
+type QuotaError struct {
+	Violations []PolicyViolation
+}
+
+func (q *QuotaError) Error() string {
+	return fmt.Sprintf("Blueprint exceeds quota:\n%s", formatViolations(q.Violations))
+}
+
+// loadQuota reads the quota file, nil is returned when no quota exists
+func loadQuota() (*Quota, error) {
+	q := &Quota{}
+
+	ok, err := decodeEngineConfig(QuotaEnvVar, "quota.hcl", q)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// checkQuota evaluates the quota against the environment which will exist
+// after the apply, this includes the resources which have already been
+// created
+func (e *EngineImpl) checkQuota() error {
+	q, err := loadQuota()
+	if err != nil || q == nil {
+		return err
+	}
+
+	violations, err := q.Evaluate(e.config.Resources)
+	if err != nil {
+		return err
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	return &QuotaError{violations}
+}
+
+// Evaluate returns the violations of the quota by the given resources,
+// only the resources which are pending apply are checked for missing limits
+// and host ports
+func (q *Quota) Evaluate(resources []config.Resource) ([]PolicyViolation, error) {
+	ports, err := parsePortRanges(q.AllowedPorts)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := []PolicyViolation{}
+	containers, memory, cpu := 0, 0, 0
+
+	for _, r := range resources {
+		if r.Info().Status == config.Destroyed {
+			continue
+		}
+
+		containers += quotaContainers(r)
+
+		lim, limited := resourceLimits(r)
+		if lim != nil {
+			memory += lim.Memory
+			cpu += lim.CPU
+		}
+
+		if !pendingApply(r) {
+			continue
+		}
+
+		if limited && q.MaxMemory > 0 && (lim == nil || lim.Memory == 0) {
+			violations = append(violations, PolicyViolation{Resource: resourceName(r), Rule: "max_memory", Message: "a memory limit must be set"})
+		}
+
+		if limited && q.MaxCPU > 0 && (lim == nil || lim.CPU == 0) {
+			violations = append(violations, PolicyViolation{Resource: resourceName(r), Rule: "max_cpu", Message: "a cpu limit must be set"})
+		}
+
+		if len(ports) == 0 {
+			continue
+		}
+
+		for _, p := range hostPorts(r) {
+			if !ports.contains(p) {
+				violations = append(violations, PolicyViolation{Resource: resourceName(r), Rule: "allowed_ports", Message: fmt.Sprintf("host port %d is not in the allowed ports", p)})
+			}
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Resource < violations[j].Resource
+	})
+
+	if q.MaxContainers > 0 && containers > q.MaxContainers {
+		violations = append(violations, PolicyViolation{Rule: "max_containers", Message: fmt.Sprintf("%d containers exceeds the maximum of %d", containers, q.MaxContainers)})
+	}
+
+	if q.MaxMemory > 0 && memory > q.MaxMemory {
+		violations = append(violations, PolicyViolation{Rule: "max_memory", Message: fmt.Sprintf("%dMB memory exceeds the maximum of %dMB", memory, q.MaxMemory)})
+	}
+
+	if q.MaxCPU > 0 && cpu > q.MaxCPU {
+		violations = append(violations, PolicyViolation{Rule: "max_cpu", Message: fmt.Sprintf("%d cpu exceeds the maximum of %d", cpu, q.MaxCPU)})
+	}
+
+	return violations, nil
+}
+
+// quotaContainers returns the number of containers created for a resource
+func quotaContainers(r config.Resource) int {
+	if _, ok := resourceContainerName(r); !ok {
+		return 0
+	}
+
+	// docs run a separate container for the terminal
+	if r.Info().Type == config.TypeDocs {
+		return 2
+	}
+
+	return 1
+}
+
+// resourceLimits returns the resource limits for the resource, the boolean
+// is false for resources where limits can not be set
+func resourceLimits(r config.Resource) (*config.Resources, bool) {
+	switch v := r.(type) {
+	case *config.Container:
+		return v.Resources, true
+	case *config.Sidecar:
+		return v.Resources, true
+	case *config.K8sCluster:
+		return v.Resources, true
+	case *config.NomadCluster:
+		return v.Resources, true
+	}
+
+	return nil, false
+}
+
+// hostPorts returns the ports the resource publishes on the host
+func hostPorts(r config.Resource) []int {
+	ports := []int{}
+
+	for _, ep := range resourceEndpoints(r) {
+		p, err := strconv.Atoi(ep[strings.LastIndex(ep, ":")+1:])
+		if err == nil {
+			ports = append(ports, p)
+		}
+	}
+
+	return ports
+}
+
+type portRange struct {
+	from, to int
+}
+
+type portRanges []portRange
+
+func (pr portRanges) contains(p int) bool {
+	for _, r := range pr {
+		if p >= r.from && p <= r.to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parsePortRanges parses ports in the form 80 or 8000-8999
+func parsePortRanges(ranges []string) (portRanges, error) {
+	pr := portRanges{}
+
+	for _, r := range ranges {
+		parts := strings.SplitN(r, "-", 2)
+
+		from, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, xerrors.Errorf("Invalid port range %s in quota: %w", r, err)
+		}
+
+		to := from
+		if len(parts) == 2 {
+			to, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+			if err != nil {
+				return nil, xerrors.Errorf("Invalid port range %s in quota: %w", r, err)
+			}
+		}
+
+		pr = append(pr, portRange{from, to})
+	}
+
+	return pr, nil
+}
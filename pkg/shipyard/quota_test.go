@@ -0,0 +1,107 @@
+package shipyard
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+)
+
+func setupQuotaTests(t *testing.T, quota, blueprint string) string {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	ioutil.WriteFile(filepath.Join(dir, "main.hcl"), []byte(blueprint), 0644)
+
+	os.MkdirAll(utils.ShipyardHome(), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(utils.ShipyardHome(), "quota.hcl"), []byte(quota), 0644)
+
+	return dir
+}
+
+func TestApplyFailsWhenBlueprintExceedsQuota(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir := setupQuotaTests(t, `
+max_containers = 1
+max_memory     = 512
+allowed_ports  = ["9000-9100"]
+`, quotaBlueprint)
+	defer os.RemoveAll(dir)
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.Error(t, err)
+
+	qe := &QuotaError{}
+	assert.True(t, xerrors.As(err, &qe))
+
+	assert.Equal(t, []PolicyViolation{
+		PolicyViolation{Resource: "container.consul", Rule: "allowed_ports", Message: "host port 8500 is not in the allowed ports"},
+		PolicyViolation{Resource: "container.vault", Rule: "max_memory", Message: "a memory limit must be set"},
+		PolicyViolation{Rule: "max_containers", Message: "2 containers exceeds the maximum of 1"},
+		PolicyViolation{Rule: "max_memory", Message: "1024MB memory exceeds the maximum of 512MB"},
+	}, qe.Violations)
+
+	assert.Len(t, *mp, 0)
+}
+
+func TestApplyQuotaIncludesRunningResources(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, usageState)
+	defer cleanup()
+
+	// the blueprint defines a single container which is already running
+	dir := setupQuotaTests(t, `
+max_containers = 2
+`, checkpointBlueprint)
+	defer os.RemoveAll(dir)
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "3 containers exceeds the maximum of 2")
+}
+
+func TestApplySucceedsWithinQuota(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir := setupQuotaTests(t, `
+max_containers = 2
+allowed_ports  = ["8500", "9000-9100"]
+`, quotaBlueprint)
+	defer os.RemoveAll(dir)
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	assert.Len(t, *mp, 2)
+}
+
+var quotaBlueprint = `
+container "consul" {
+  image {
+    name = "consul:1.8.1"
+  }
+
+  resources {
+    memory = 1024
+  }
+
+  port {
+    local  = "8500"
+    remote = "8500"
+    host   = "8500"
+  }
+}
+
+container "vault" {
+  image {
+    name = "vault:1.5.0"
+  }
+}
+`
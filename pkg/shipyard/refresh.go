@@ -0,0 +1,359 @@
+package shipyard
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// AuditRefresh is recorded when drift is written to the state by Refresh
+const AuditRefresh = "refresh"
+
+// Drift is the difference between the state and the running environment,
+// resources are in the form [type].[name] and each list is sorted by name
+type Drift struct {
+	Missing    []string `json:"missing"`    // resources which no longer exist and are created by the next Apply
+	Modified   []string `json:"modified"`   // resources which have been changed outside of Shipyard and are replaced by the next Apply
+	Dependents []string `json:"dependents"` // resources which depend on a missing or modified resource and are replaced with it
+}
+
+// HasDrift returns true when the running environment does not match the
+// state
+func (d *Drift) HasDrift() bool {
+	return len(d.Missing) > 0 || len(d.Modified) > 0
+}
+
+// Refresh queries Docker, Kubernetes, and Nomad for the resources in the
+// state and records any which have been removed or changed outside of
+// Shipyard, e.g. a container removed with the Docker CLI or a Helm release
+// uninstalled with the Helm CLI. Missing resources are marked to
+// be created and modified resources to be replaced, Plan and Apply then
+// reconcile the environment with the blueprint
+func (e *EngineImpl) Refresh() (d *Drift, err error) {
+	err = e.lockState("")
+	if err != nil {
+		return nil, err
+	}
+	defer e.unlockState(&err)
+
+	started := time.Now()
+
+	d, err = e.refresh(context.Background())
+
+	changed := []string{}
+	if d != nil {
+		changed = append(changed, d.Missing...)
+		changed = append(changed, d.Modified...)
+		changed = append(changed, d.Dependents...)
+	}
+
+	if err != nil || len(changed) > 0 {
+		e.audit(AuditRefresh, "", started, err, changed...)
+	}
+
+	return d, err
+}
+
+func (e *EngineImpl) refresh(ctx context.Context) (*Drift, error) {
+	d := &Drift{Missing: []string{}, Modified: []string{}, Dependents: []string{}}
+
+	sc := config.New()
+	err := sc.FromJSON(e.stateFile())
+	if err == config.StateNotFoundError {
+		// nothing is running
+		return d, nil
+	}
+
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to load state: %w", err)
+	}
+
+	e.config = sc
+
+	drifted := map[string]bool{}
+
+	for _, r := range sc.Resources {
+		if r.Info().Status != config.Applied {
+			continue
+		}
+
+		missing, modified, err := e.resourceDrift(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case missing:
+			e.log.Info("Resource no longer exists", "ref", resourceName(r))
+
+			d.Missing = append(d.Missing, resourceName(r))
+			drifted[resourceName(r)] = true
+		case modified:
+			e.log.Info("Resource has been modified", "ref", resourceName(r))
+
+			d.Modified = append(d.Modified, resourceName(r))
+			drifted[resourceName(r)] = true
+		}
+	}
+
+	// resources created on a missing or modified resource, e.g. Helm charts
+	// on a cluster, must be created again with it
+	for n := range dependents(sc, drifted) {
+		if drifted[n] {
+			continue
+		}
+
+		r, _ := sc.FindResource(n)
+		if r.Info().Status == config.Applied {
+			d.Dependents = append(d.Dependents, n)
+		}
+	}
+
+	for _, n := range d.Missing {
+		r, _ := sc.FindResource(n)
+		r.Info().Status = config.PendingCreation
+	}
+
+	for _, n := range append(d.Modified, d.Dependents...) {
+		r, _ := sc.FindResource(n)
+		r.Info().Status = config.PendingModification
+	}
+
+	sort.Strings(d.Missing)
+	sort.Strings(d.Modified)
+	sort.Strings(d.Dependents)
+
+	if !d.HasDrift() {
+		return d, nil
+	}
+
+	err = sc.ToJSON(e.stateFile())
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to save state: %w", err)
+	}
+
+	return d, nil
+}
+
+// resourceDrift returns whether the resource has been removed or modified
+// outside of Shipyard, resources which Refresh can not query are neither
+func (e *EngineImpl) resourceDrift(ctx context.Context, r config.Resource) (missing bool, modified bool, err error) {
+	if _, ok := resourceContainerName(r); ok {
+		return e.containerDrift(ctx, r)
+	}
+
+	switch v := r.(type) {
+	case *config.Network:
+		missing, err = e.networkMissing(ctx, v)
+	case *config.K8sConfig:
+		if len(v.Inventory) > 0 {
+			modified, err = e.k8sConfigModified(ctx, v)
+		}
+	case *config.Helm:
+		missing, err = e.helmMissing(ctx, v)
+	case *config.NomadJob:
+		missing, err = e.nomadJobMissing(ctx, v)
+	}
+
+	return missing, modified, err
+}
+
+// containerDrift returns missing when the resource has no running
+// containers and modified when the running container was not created from
+// the definition in the state, e.g. it was replaced with the Docker CLI.
+// Containers created before the definition was recorded are not modified
+func (e *EngineImpl) containerDrift(ctx context.Context, r config.Resource) (bool, bool, error) {
+	name, _ := resourceContainerName(r)
+
+	cl, err := e.clientsFor(r)
+	if err != nil {
+		return false, false, err
+	}
+
+	cs, err := cl.ContainerTasks.FindContainers(ctx, name, r.Info().Type)
+	if err != nil {
+		return false, false, xerrors.Errorf("Unable to find containers for %s: %w", resourceName(r), err)
+	}
+
+	running := []config.ContainerInfo{}
+	for _, c := range cs {
+		if c.Running {
+			running = append(running, c)
+		}
+	}
+
+	if len(running) == 0 {
+		return true, false, nil
+	}
+
+	hash := resourceHash(r)
+	for _, c := range running {
+		if c.ConfigHash != "" && c.ConfigHash != hash {
+			return false, true, nil
+		}
+	}
+
+	return false, false, nil
+}
+
+// networkMissing returns true when the Docker network has been removed,
+// external networks are not managed by Shipyard and are never missing
+func (e *EngineImpl) networkMissing(ctx context.Context, n *config.Network) (bool, error) {
+	if n.External {
+		return false, nil
+	}
+
+	cl, err := e.clientsFor(n)
+	if err != nil {
+		return false, err
+	}
+
+	args := filters.NewArgs()
+	args.Add("name", n.Name)
+
+	nets, err := cl.Docker.NetworkList(ctx, types.NetworkListOptions{Filters: args})
+	if err != nil {
+		return false, xerrors.Errorf("Unable to list networks for %s: %w", resourceName(n), err)
+	}
+
+	// the name filter matches partial names
+	for _, ne := range nets {
+		if ne.Name == n.Name {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// clusterClients returns clients configured for the cluster, running is
+// false when the cluster itself is missing in which case resources on it are
+// replaced with the cluster and do not need to be queried
+func (e *EngineImpl) clusterClients(ctx context.Context, r config.Resource, name string) (cl *Clients, cluster config.Resource, running bool, err error) {
+	cluster, err = e.config.FindResource(name)
+	if err != nil {
+		return nil, nil, false, xerrors.Errorf("Unable to find cluster for %s: %w", resourceName(r), err)
+	}
+
+	running, err = e.containerRunning(ctx, cluster)
+	if err != nil || !running {
+		return nil, nil, false, err
+	}
+
+	hcl, err := e.clientsFor(r)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	return e.getClusterClients(hcl), cluster, true, nil
+}
+
+// k8sConfigModified returns true when objects created by the Kubernetes
+// config have been deleted from the cluster
+func (e *EngineImpl) k8sConfigModified(ctx context.Context, kc *config.K8sConfig) (bool, error) {
+	cl, cluster, running, err := e.clusterClients(ctx, kc, kc.Cluster)
+	if err != nil || !running {
+		return false, err
+	}
+
+	_, kcPath, _ := utils.CreateKubeConfigPath(cluster.Info().Name)
+	err = cl.Kubernetes.SetConfig(kcPath)
+	if err != nil {
+		return false, xerrors.Errorf("Unable to create Kubernetes client for %s: %w", resourceName(cluster), err)
+	}
+
+	missing, err := cl.Kubernetes.MissingObjects(kc.Inventory)
+	if err != nil {
+		return false, xerrors.Errorf("Unable to check Kubernetes objects for %s: %w", resourceName(kc), err)
+	}
+
+	return len(missing) > 0, nil
+}
+
+// helmMissing returns true when the Helm release has been uninstalled from
+// the cluster
+func (e *EngineImpl) helmMissing(ctx context.Context, h *config.Helm) (bool, error) {
+	cl, cluster, running, err := e.clusterClients(ctx, h, h.Cluster)
+	if err != nil || !running {
+		return false, err
+	}
+
+	ns := h.Namespace
+	if ns == "" {
+		ns = "default"
+	}
+
+	_, kcPath, _ := utils.CreateKubeConfigPath(cluster.Info().Name)
+	exists, err := cl.Helm.ReleaseExists(kcPath, h.Name, ns)
+	if err != nil {
+		return false, xerrors.Errorf("Unable to check Helm release for %s: %w", resourceName(h), err)
+	}
+
+	return !exists, nil
+}
+
+// nomadJobMissing returns true when any of the jobs has been stopped or
+// removed from the cluster
+func (e *EngineImpl) nomadJobMissing(ctx context.Context, n *config.NomadJob) (bool, error) {
+	cl, cluster, running, err := e.clusterClients(ctx, n, n.Cluster)
+	if err != nil || !running {
+		return false, err
+	}
+
+	_, configPath := utils.CreateNomadConfigPath(cluster.Info().Name)
+	err = cl.Nomad.SetConfig(configPath)
+	if err != nil {
+		return false, xerrors.Errorf("Unable to load Nomad config for %s: %w", resourceName(cluster), err)
+	}
+
+	paths, err := providers.FetchPaths(cl.Getter, n.Paths)
+	if err != nil {
+		return false, err
+	}
+
+	missing, err := cl.Nomad.MissingJobs(paths)
+	if err != nil {
+		return false, xerrors.Errorf("Unable to check Nomad jobs for %s: %w", resourceName(n), err)
+	}
+
+	return len(missing) > 0, nil
+}
+
+// dependents returns the named resources and every resource in the config
+// which depends on them directly or transitively
+func dependents(c *config.Config, names map[string]bool) map[string]bool {
+	deps := map[string]bool{}
+	for n := range names {
+		deps[n] = true
+	}
+
+	for {
+		propagated := false
+
+		for _, r := range c.Resources {
+			if deps[resourceName(r)] {
+				continue
+			}
+
+			for _, dn := range r.Info().DependsOn {
+				dr, err := c.FindResource(dn)
+				if err == nil && deps[resourceName(dr)] {
+					deps[resourceName(r)] = true
+					propagated = true
+					break
+				}
+			}
+		}
+
+		if !propagated {
+			return deps
+		}
+	}
+}
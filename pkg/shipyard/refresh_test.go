@@ -0,0 +1,323 @@
+package shipyard
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers/mocks"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRefreshMarksMissingContainersAndDependents(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	// the consul container has been removed outside of Shipyard
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	removeOn(&ct.Mock, "FindContainerIDs")
+	removeOn(&ct.Mock, "FindContainers")
+	ct.On("FindContainerIDs", "consul", mock.Anything).Return(nil, nil)
+	ct.On("FindContainers", "consul", mock.Anything).Return([]config.ContainerInfo{}, nil)
+	ct.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{"abc"}, nil)
+	ct.On("FindContainers", mock.Anything, mock.Anything).Return([]config.ContainerInfo{{ID: "abc", Running: true}}, nil)
+
+	md := e.GetClients().Docker.(*clientmocks.MockDocker)
+	removeOn(&md.Mock, "NetworkList")
+	md.On("NetworkList", mock.Anything, mock.Anything).Return([]types.NetworkResource{{Name: "cloud"}}, nil)
+
+	d, err := e.Refresh()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"container.consul"}, d.Missing)
+	assert.Empty(t, d.Modified)
+	assert.Equal(t, []string{"container.api"}, d.Dependents)
+
+	sc := config.New()
+	sc.FromJSON(utils.StatePath())
+	r, _ := sc.FindResource("container.consul")
+	assert.Equal(t, config.PendingCreation, r.Info().Status)
+	r, _ = sc.FindResource("container.api")
+	assert.Equal(t, config.PendingModification, r.Info().Status)
+
+	// the next apply reconciles the drift
+	*mp = []*mocks.MockProvider{}
+
+	_, err = e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, providerCalls(mp, "consul", "Destroy"))
+	assert.Equal(t, 1, providerCalls(mp, "consul", "Create"))
+	assert.Equal(t, 1, providerCalls(mp, "api", "Destroy"))
+	assert.Equal(t, 1, providerCalls(mp, "api", "Create"))
+	assert.Equal(t, 0, providerCalls(mp, "cloud", "Create"))
+}
+
+func TestRefreshMarksRemovedNetworksMissing(t *testing.T) {
+	e, dir, _, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	// the containers are running but the network has been removed
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	removeOn(&ct.Mock, "FindContainers")
+	ct.On("FindContainers", mock.Anything, mock.Anything).Return([]config.ContainerInfo{{ID: "abc", Running: true}}, nil)
+
+	md := e.GetClients().Docker.(*clientmocks.MockDocker)
+	removeOn(&md.Mock, "NetworkList")
+	md.On("NetworkList", mock.Anything, mock.Anything).Return([]types.NetworkResource{{Name: "cloud-2"}}, nil)
+
+	d, err := e.Refresh()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"network.cloud"}, d.Missing)
+	assert.Equal(t, []string{"container.api", "container.consul"}, d.Dependents)
+}
+
+func TestRefreshMarksContainersReplacedOutsideShipyardModified(t *testing.T) {
+	e, dir, _, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	// the consul container has been replaced with one created by the Docker CLI
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	removeOn(&ct.Mock, "FindContainers")
+	ct.On("FindContainers", "consul", mock.Anything).Return([]config.ContainerInfo{{ID: "abc", Running: true, ConfigHash: "other"}}, nil)
+	ct.On("FindContainers", mock.Anything, mock.Anything).Return([]config.ContainerInfo{{ID: "abc", Running: true}}, nil)
+
+	md := e.GetClients().Docker.(*clientmocks.MockDocker)
+	removeOn(&md.Mock, "NetworkList")
+	md.On("NetworkList", mock.Anything, mock.Anything).Return([]types.NetworkResource{{Name: "cloud"}}, nil)
+
+	d, err := e.Refresh()
+	assert.NoError(t, err)
+
+	assert.Empty(t, d.Missing)
+	assert.Equal(t, []string{"container.consul"}, d.Modified)
+	assert.Equal(t, []string{"container.api"}, d.Dependents)
+}
+
+func setupRefreshTests(t *testing.T) (Engine, *clientmocks.MockContainerTasks, *clientmocks.MockKubernetes, func()) {
+	e, _, _, cleanup := setupTestsWithState(nil, refreshState)
+
+	mk := &clientmocks.MockKubernetes{}
+	mk.On("SetConfig", mock.Anything).Return(nil)
+	e.GetClients().Kubernetes = mk
+
+	mh := &clientmocks.MockHelm{}
+	mh.On("ReleaseExists", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+	e.GetClients().Helm = mh
+
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+
+	return e, ct, mk, cleanup
+}
+
+func TestRefreshMarksK8sConfigWithDeletedObjectsModified(t *testing.T) {
+	e, ct, mk, cleanup := setupRefreshTests(t)
+	defer cleanup()
+
+	removeOn(&ct.Mock, "FindContainerIDs")
+	removeOn(&ct.Mock, "FindContainers")
+	ct.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{"abc"}, nil)
+	ct.On("FindContainers", mock.Anything, mock.Anything).Return([]config.ContainerInfo{{ID: "abc", Running: true}}, nil)
+	mk.On("MissingObjects", mock.Anything).Return([]config.K8sObject{{Kind: "Deployment", Name: "app"}}, nil)
+
+	d, err := e.Refresh()
+	assert.NoError(t, err)
+
+	assert.Empty(t, d.Missing)
+	assert.Equal(t, []string{"k8s_config.app"}, d.Modified)
+	assert.Empty(t, d.Dependents)
+
+	mk.AssertCalled(t, "MissingObjects", []config.K8sObject{{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "app"}})
+
+	sc := config.New()
+	sc.FromJSON(utils.StatePath())
+	r, _ := sc.FindResource("k8s_config.app")
+	assert.Equal(t, config.PendingModification, r.Info().Status)
+
+	ae, _ := e.AuditLog(AuditQuery{Operation: AuditRefresh})
+	assert.Len(t, ae, 1)
+}
+
+func TestRefreshWithMissingClusterReplacesDependents(t *testing.T) {
+	e, _, mk, cleanup := setupRefreshTests(t)
+	defer cleanup()
+
+	d, err := e.Refresh()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"k8s_cluster.k3s"}, d.Missing)
+	assert.Empty(t, d.Modified)
+	assert.Equal(t, []string{"helm.vault", "k8s_config.app"}, d.Dependents)
+
+	mk.AssertNotCalled(t, "MissingObjects", mock.Anything)
+}
+
+func TestRefreshWithoutDriftDoesNotChangeState(t *testing.T) {
+	e, ct, mk, cleanup := setupRefreshTests(t)
+	defer cleanup()
+
+	removeOn(&ct.Mock, "FindContainerIDs")
+	removeOn(&ct.Mock, "FindContainers")
+	ct.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{"abc"}, nil)
+	ct.On("FindContainers", mock.Anything, mock.Anything).Return([]config.ContainerInfo{{ID: "abc", Running: true}}, nil)
+	mk.On("MissingObjects", mock.Anything).Return([]config.K8sObject{}, nil)
+
+	d, err := e.Refresh()
+	assert.NoError(t, err)
+	assert.False(t, d.HasDrift())
+
+	sc := config.New()
+	sc.FromJSON(utils.StatePath())
+	for _, r := range sc.Resources {
+		assert.Equal(t, config.Applied, r.Info().Status)
+	}
+
+	ae, _ := e.AuditLog(AuditQuery{})
+	assert.Len(t, ae, 0)
+}
+
+func TestRefreshMarksUninstalledHelmReleasesMissing(t *testing.T) {
+	e, ct, mk, cleanup := setupRefreshTests(t)
+	defer cleanup()
+
+	removeOn(&ct.Mock, "FindContainers")
+	ct.On("FindContainers", mock.Anything, mock.Anything).Return([]config.ContainerInfo{{ID: "abc", Running: true}}, nil)
+	mk.On("MissingObjects", mock.Anything).Return([]config.K8sObject{}, nil)
+
+	mh := e.GetClients().Helm.(*clientmocks.MockHelm)
+	removeOn(&mh.Mock, "ReleaseExists")
+	mh.On("ReleaseExists", mock.Anything, "vault", "default").Return(false, nil)
+
+	d, err := e.Refresh()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"helm.vault"}, d.Missing)
+	assert.Empty(t, d.Modified)
+
+	sc := config.New()
+	sc.FromJSON(utils.StatePath())
+	r, _ := sc.FindResource("helm.vault")
+	assert.Equal(t, config.PendingCreation, r.Info().Status)
+}
+
+func TestRefreshMarksStoppedNomadJobsMissing(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, refreshNomadState)
+	defer cleanup()
+
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	removeOn(&ct.Mock, "FindContainers")
+	ct.On("FindContainers", mock.Anything, mock.Anything).Return([]config.ContainerInfo{{ID: "abc", Running: true}}, nil)
+
+	mn := &clientmocks.MockNomad{}
+	mn.On("SetConfig", mock.Anything).Return(nil)
+	mn.On("MissingJobs", []string{"./app.nomad"}).Return([]string{"app"}, nil)
+	e.GetClients().Nomad = mn
+
+	d, err := e.Refresh()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"nomad_job.app"}, d.Missing)
+	assert.Empty(t, d.Modified)
+}
+
+func TestRefreshConfiguresClientsForEachCluster(t *testing.T) {
+	e, ct, mk, cleanup := setupRefreshTests(t)
+	defer cleanup()
+
+	removeOn(&ct.Mock, "FindContainers")
+	ct.On("FindContainers", mock.Anything, mock.Anything).Return([]config.ContainerInfo{{ID: "abc", Running: true}}, nil)
+
+	ck := &clientmocks.MockKubernetes{}
+	ck.On("SetConfig", mock.Anything).Return(nil)
+	ck.On("MissingObjects", mock.Anything).Return([]config.K8sObject{}, nil)
+
+	e.(*EngineImpl).getClusterClients = func(cl *Clients) *Clients {
+		ccl := *cl
+		ccl.Kubernetes = ck
+		return &ccl
+	}
+
+	_, err := e.Refresh()
+	assert.NoError(t, err)
+
+	// the shared client used by concurrent operations is not changed
+	mk.AssertNotCalled(t, "SetConfig", mock.Anything)
+	ck.AssertCalled(t, "SetConfig", mock.Anything)
+}
+
+func TestRefreshWithNoStateReturnsNoDrift(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	d, err := e.Refresh()
+	assert.NoError(t, err)
+	assert.False(t, d.HasDrift())
+}
+
+var refreshState = `
+{
+  "blueprint": null,
+  "resources": [
+	{
+      "name": "k3s",
+      "status": "applied",
+      "driver": "k3s",
+      "type": "k8s_cluster"
+	},
+	{
+      "name": "app",
+      "status": "applied",
+      "depends_on": ["k8s_cluster.k3s"],
+      "cluster": "k8s_cluster.k3s",
+      "paths": ["./app.yaml"],
+      "inventory": [
+        {"api_version": "apps/v1", "kind": "Deployment", "namespace": "default", "name": "app"}
+      ],
+      "type": "k8s_config"
+	},
+	{
+      "name": "vault",
+      "status": "applied",
+      "depends_on": ["k8s_cluster.k3s"],
+      "cluster": "k8s_cluster.k3s",
+      "chart": "./vault",
+      "type": "helm"
+	}
+  ]
+}
+`
+
+var refreshNomadState = `
+{
+  "blueprint": null,
+  "resources": [
+	{
+      "name": "dev",
+      "status": "applied",
+      "type": "nomad_cluster"
+	},
+	{
+      "name": "app",
+      "status": "applied",
+      "depends_on": ["nomad_cluster.dev"],
+      "cluster": "nomad_cluster.dev",
+      "paths": ["./app.nomad"],
+      "type": "nomad_job"
+	}
+  ]
+}
+`
@@ -0,0 +1,173 @@
+package shipyard
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+)
+
+// Result is a machine readable summary of an Apply or Destroy operation
+type Result struct {
+	Action    string           `json:"action"` // apply or destroy
+	Started   time.Time        `json:"started"`
+	Duration  float64          `json:"duration_seconds"`
+	Success   bool             `json:"success"`
+	Error     string           `json:"error,omitempty"`
+	Resources []ResourceResult `json:"resources"` // resources which were created or destroyed in the order they completed
+
+	m sync.Mutex
+}
+
+// ResourceResult is the outcome of creating or destroying a single resource
+type ResourceResult struct {
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	Status    string            `json:"status"`
+	Action    string            `json:"action,omitempty"` // create, replace, or update, only set by a dry run
+	Duration  float64           `json:"duration_seconds"`
+	Error     string            `json:"error,omitempty"`
+	Outputs   map[string]string `json:"outputs,omitempty"`   // values captured by the resource e.g. exec outputs
+	Endpoints []string          `json:"endpoints,omitempty"` // addresses on the local machine for exposed ports
+}
+
+func newResult(action string) *Result {
+	return &Result{Action: action, Started: time.Now(), Resources: []ResourceResult{}}
+}
+
+// ToJSON returns the result as an indented JSON document
+func (r *Result) ToJSON() ([]byte, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// add records the outcome for a resource, started is the time the
+// provider was called
+func (r *Result) add(res config.Resource, started time.Time, err error) {
+	rr := ResourceResult{
+		Name:      res.Info().Name,
+		Type:      string(res.Info().Type),
+		Status:    string(res.Info().Status),
+		Duration:  time.Since(started).Seconds(),
+		Outputs:   resourceOutputs(res),
+		Endpoints: resourceEndpoints(res),
+	}
+
+	if err != nil {
+		rr.Error = err.Error()
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.Resources = append(r.Resources, rr)
+}
+
+// addAction records the action a dry run would take for a resource
+func (r *Result) addAction(res config.Resource, action string) {
+	rr := ResourceResult{
+		Name:   res.Info().Name,
+		Type:   string(res.Info().Type),
+		Status: string(res.Info().Status),
+		Action: action,
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.Resources = append(r.Resources, rr)
+}
+
+// combine makes r part of the earlier operation prev, the resources for
+// prev are recorded before the resources for r
+func (r *Result) combine(prev *Result) {
+	prev.m.Lock()
+	defer prev.m.Unlock()
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.Action = prev.Action
+	r.Started = prev.Started
+	r.Resources = append(append([]ResourceResult{}, prev.Resources...), r.Resources...)
+}
+
+// finish sets the duration and the overall error for the operation
+func (r *Result) finish(err error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.Duration = time.Since(r.Started).Seconds()
+	r.Success = err == nil
+
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// resourceOutputs returns the values a resource captures when it is created
+func resourceOutputs(r config.Resource) map[string]string {
+	o := map[string]string{}
+
+	switch v := r.(type) {
+	case *config.ExecLocal:
+		for _, out := range v.Outputs {
+			o[out.Name] = out.Value
+		}
+	case *config.ExecRemote:
+		for _, out := range v.Outputs {
+			o[out.Name] = out.Value
+		}
+	case *config.K8sCluster:
+		if v.KubeConfig != "" {
+			o["kubeconfig"] = v.KubeConfig
+			o["kube_context"] = v.KubeContext
+		}
+	}
+
+	if len(o) == 0 {
+		return nil
+	}
+
+	return o
+}
+
+// resourceEndpoints returns the local addresses for ports which a
+// resource exposes on the host
+func resourceEndpoints(r config.Resource) []string {
+	ports := []config.Port{}
+
+	switch v := r.(type) {
+	case *config.Container:
+		ports = v.Ports
+	case *config.Ingress:
+		ports = v.Ports
+	case *config.ContainerIngress:
+		ports = v.Ports
+	case *config.K8sIngress:
+		ports = v.Ports
+	case *config.NomadIngress:
+		ports = v.Ports
+	case *config.K8sCluster:
+		if v.APIPort > 0 {
+			return []string{fmt.Sprintf("https://localhost:%d", v.APIPort)}
+		}
+	}
+
+	e := []string{}
+	for _, p := range ports {
+		if p.Host != "" {
+			e = append(e, fmt.Sprintf("localhost:%s", p.Host))
+		}
+	}
+
+	if len(e) == 0 {
+		return nil
+	}
+
+	return e
+}
@@ -0,0 +1,64 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyRecordsResultForEachResource(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	_, err := e.Apply(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster")
+	assert.NoError(t, err)
+
+	r := e.Result()
+	assert.Equal(t, "apply", r.Action)
+	assert.True(t, r.Success)
+	assert.Len(t, r.Resources, 6)
+	assert.Equal(t, "cloud", r.Resources[0].Name)
+	assert.Equal(t, string(config.Applied), r.Resources[0].Status)
+
+	for _, rr := range r.Resources {
+		if rr.Name == "consul-http" {
+			assert.Equal(t, []string{"localhost:18500"}, rr.Endpoints)
+		}
+	}
+}
+
+func TestApplyRecordsResultErrors(t *testing.T) {
+	e, _, _, cleanup := setupTests(map[string]error{"cloud": fmt.Errorf("boom")})
+	defer cleanup()
+
+	_, err := e.Apply(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster")
+	assert.Error(t, err)
+
+	r := e.Result()
+	assert.False(t, r.Success)
+	assert.NotEmpty(t, r.Error)
+	assert.Len(t, r.Resources, 1)
+	assert.Equal(t, string(config.Failed), r.Resources[0].Status)
+	assert.Equal(t, "boom", r.Resources[0].Error)
+}
+
+func TestDestroyRecordsResult(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	err := e.Destroy(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster", true)
+	assert.NoError(t, err)
+
+	r := e.Result()
+	assert.Equal(t, "destroy", r.Action)
+	assert.True(t, r.Success)
+	assert.Len(t, r.Resources, 6)
+	assert.Equal(t, string(config.Destroyed), r.Resources[0].Status)
+
+	d, err := r.ToJSON()
+	assert.NoError(t, err)
+	assert.Contains(t, string(d), `"action": "destroy"`)
+}
@@ -0,0 +1,112 @@
+package shipyard
+
+import (
+	"context"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers"
+	"golang.org/x/xerrors"
+)
+
+// defaultRetryAttempts is the number of attempts when a retry block does
+// not set attempts
+const defaultRetryAttempts = 3
+
+// defaultRetryBackoff is the interval before the first retry when a retry
+// block does not set backoff
+const defaultRetryBackoff = 1 * time.Second
+
+// createWithRetry creates the resource with the provider, when the resource
+// defines a retry block failed attempts are destroyed and created again until
+// the resource is created or the attempts have been used
+func (e *EngineImpl) createWithRetry(ctx context.Context, r config.Resource, p providers.Provider) error {
+	rt := resourceRetry(r)
+	if rt == nil {
+		return p.Create(ctx)
+	}
+
+	attempts := rt.Attempts
+	if attempts <= 0 {
+		attempts = defaultRetryAttempts
+	}
+
+	backoff := defaultRetryBackoff
+	if rt.Backoff != "" {
+		var err error
+		backoff, err = time.ParseDuration(rt.Backoff)
+		if err != nil {
+			return xerrors.Errorf("Invalid retry backoff %s: %w", rt.Backoff, err)
+		}
+	}
+
+	maxBackoff := backoff
+	if rt.MaxBackoff != "" {
+		var err error
+		maxBackoff, err = time.ParseDuration(rt.MaxBackoff)
+		if err != nil {
+			return xerrors.Errorf("Invalid retry max_backoff %s: %w", rt.MaxBackoff, err)
+		}
+	}
+
+	var err error
+	for i := 1; ; i++ {
+		err = p.Create(ctx)
+		if err == nil || i >= attempts {
+			break
+		}
+
+		e.log.Warn("Unable to create resource, retrying", "ref", resourceName(r), "attempt", i, "backoff", backoff, "error", err)
+
+		// remove anything created by the failed attempt, this is not cancelled
+		// with ctx so that a cancelled apply does not leave the resource behind
+		derr := p.Destroy(context.Background())
+		if derr != nil {
+			e.log.Debug("Unable to destroy failed resource", "ref", resourceName(r), "error", derr)
+		}
+
+		// the worker is given up during the backoff
+		werr := providers.Idle(ctx, func() error {
+			select {
+			case <-ctx.Done():
+				return xerrors.Errorf("Unable to create %s, apply cancelled: %w", resourceName(r), ctx.Err())
+			case <-time.After(backoff):
+				return nil
+			}
+		})
+		if werr != nil {
+			return werr
+		}
+
+		// backoff, doubling the interval after each attempt up to the maximum
+		backoff = backoff * 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+
+	if err != nil {
+		return xerrors.Errorf("Unable to create %s after %d attempts: %w", resourceName(r), attempts, err)
+	}
+
+	return nil
+}
+
+// resourceRetry returns the retry block for the resource, nil is returned
+// when the resource is not created again when it fails
+func resourceRetry(r config.Resource) *config.Retry {
+	switch v := r.(type) {
+	case *config.Container:
+		return v.Retry
+	case *config.Sidecar:
+		return v.Retry
+	case *config.Helm:
+		return v.Retry
+	case *config.K8sCluster:
+		return v.Retry
+	case *config.NomadCluster:
+		return v.Retry
+	}
+
+	return nil
+}
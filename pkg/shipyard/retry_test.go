@@ -0,0 +1,79 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers"
+	"github.com/shipyard-run/shipyard/pkg/providers/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRetryTests(t *testing.T, failures int) (Engine, string, *[]*mocks.MockProvider, func()) {
+	e, _, mp, cleanup := setupTests(nil)
+
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	ioutil.WriteFile(filepath.Join(dir, "main.hcl"), []byte(retryConfig), 0644)
+
+	e.(*EngineImpl).getProvider = func(c config.Resource, cc *Clients) providers.Provider {
+		lock.Lock()
+		defer lock.Unlock()
+
+		m := mocks.New(c)
+		if failures > 0 {
+			m.On("Create").Return(fmt.Errorf("boom")).Times(failures)
+		}
+		m.On("Create").Return(nil)
+		m.On("Destroy").Return(nil)
+
+		*mp = append(*mp, m)
+		return m
+	}
+
+	return e, dir, mp, func() {
+		cleanup()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestApplyRetriesFailedResources(t *testing.T) {
+	e, dir, mp, cleanup := setupRetryTests(t, 2)
+	defer cleanup()
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 3, providerCalls(mp, "consul", "Create"))
+	assert.Equal(t, 2, providerCalls(mp, "consul", "Destroy"))
+}
+
+func TestApplyReturnsErrorWhenRetriesExhausted(t *testing.T) {
+	e, dir, mp, cleanup := setupRetryTests(t, 5)
+	defer cleanup()
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "after 3 attempts")
+
+	assert.Equal(t, 3, providerCalls(mp, "consul", "Create"))
+}
+
+var retryConfig = `
+container "consul" {
+  image {
+    name = "consul:1.8.1"
+  }
+
+  retry {
+    attempts = 3
+    backoff  = "1ms"
+  }
+}
+`
@@ -0,0 +1,90 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// rollback destroys the resources created by a failed apply and restores
+// the state from before the apply. previous is the state before the apply,
+// it is empty when no environment was running. Resources which were running before the apply
+// and were not touched by it are left running, resources the apply
+// destroyed to recreate are destroyed and restored as pending creation
+func (e *EngineImpl) rollback(previous *config.Config) error {
+	e.log.Info("Rolling back failed apply")
+
+	// the result records every resource the apply created, recreated, or
+	// failed to create
+	touched := map[string]bool{}
+	for _, rr := range e.Result().Resources {
+		touched[fmt.Sprintf("%s.%s", rr.Type, rr.Name)] = true
+	}
+
+	for _, r := range e.config.Resources {
+		// resources the apply did not change are still running
+		if !touched[resourceName(r)] {
+			if r.Info().Status == config.PendingUpdate {
+				r.Info().Status = config.Applied
+			}
+
+			continue
+		}
+
+		// failed resources may have been partially created
+		if r.Info().Status == config.Applied || r.Info().Status == config.Failed {
+			r.Info().Status = config.PendingUpdate
+		}
+	}
+
+	d, err := e.config.DoYaLikeDAGs()
+	if err != nil {
+		return xerrors.Errorf("Unable to create dependency graph: %w", err)
+	}
+
+	d.TransitiveReduction()
+
+	// the apply may have been cancelled, the rollback must still complete
+	err = e.destroyPending(context.Background(), d, false)
+	if err != nil {
+		// keep the state for the resources which could not be destroyed so
+		// they can be removed with destroy
+		cn := config.New()
+		for _, r := range e.config.Resources {
+			if r.Info().Status != config.Destroyed {
+				cn.AddResource(r)
+			}
+		}
+
+		cn.Blueprint = e.config.Blueprint
+
+		if jerr := cn.ToJSON(e.stateFile()); jerr != nil {
+			e.log.Error("Unable to save state", "error", jerr)
+		}
+
+		return err
+	}
+
+	if len(previous.Resources) == 0 {
+		os.RemoveAll(e.stateFile())
+		return nil
+	}
+
+	// resources which were running before the apply but were destroyed by
+	// it are no longer running, they are created by the next apply
+	for _, r := range previous.Resources {
+		if touched[resourceName(r)] && r.Info().Status == config.Applied {
+			r.Info().Status = config.PendingCreation
+		}
+	}
+
+	err = previous.ToJSON(e.stateFile())
+	if err != nil {
+		return xerrors.Errorf("Unable to restore state: %w", err)
+	}
+
+	return nil
+}
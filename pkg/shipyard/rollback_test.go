@@ -0,0 +1,119 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers"
+	"github.com/shipyard-run/shipyard/pkg/providers/mocks"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// failCreateProvider returns a provider which fails to create the named
+// resource but can destroy it
+func failCreateProvider(mp *[]*mocks.MockProvider, name string) getProviderFunc {
+	gp := generateProviderMock(mp, nil)
+
+	return func(c config.Resource, cc *Clients) providers.Provider {
+		if c.Info().Name != name {
+			return gp(c, cc)
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		m := mocks.New(c)
+		m.On("Create").Return(fmt.Errorf("boom"))
+		m.On("Destroy").Return(nil)
+
+		*mp = append(*mp, m)
+		return m
+	}
+}
+
+func TestApplyWithRollbackDestroysCreatedResources(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	e.(*EngineImpl).getProvider = failCreateProvider(mp, "api")
+
+	_, err := e.ApplyWithOptions(context.Background(), dir, ApplyOptions{DisableBrowser: true, Rollback: true})
+	assert.Error(t, err)
+
+	assert.Equal(t, 1, providerCalls(mp, "cloud", "Destroy"))
+	assert.Equal(t, 1, providerCalls(mp, "consul", "Destroy"))
+	assert.Equal(t, 1, providerCalls(mp, "api", "Destroy"))
+
+	n, err := e.ResourceNames("")
+	assert.NoError(t, err)
+	assert.Empty(t, n)
+}
+
+func TestApplyWithRollbackKeepsRunningResources(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	_, err := e.ApplyTargets(context.Background(), dir, []string{"container.consul"})
+	assert.NoError(t, err)
+
+	// consul is running so it is not recreated by the apply
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	removeOn(&ct.Mock, "FindContainerIDs")
+	removeOn(&ct.Mock, "FindContainers")
+	ct.On("FindContainerIDs", "consul", config.TypeContainer).Return([]string{"abc"}, nil)
+	ct.On("FindContainers", "consul", config.TypeContainer).Return([]config.ContainerInfo{{ID: "abc", Running: true}}, nil)
+	ct.On("FindContainerIDs", mock.Anything, mock.Anything).Return(nil, nil)
+	ct.On("FindContainers", mock.Anything, mock.Anything).Return([]config.ContainerInfo{}, nil)
+
+	e.(*EngineImpl).getProvider = failCreateProvider(mp, "api")
+
+	_, err = e.ApplyWithOptions(context.Background(), dir, ApplyOptions{DisableBrowser: true, Rollback: true})
+	assert.Error(t, err)
+
+	assert.Equal(t, 0, providerCalls(mp, "cloud", "Destroy"))
+	assert.Equal(t, 0, providerCalls(mp, "consul", "Destroy"))
+	assert.Equal(t, 1, providerCalls(mp, "api", "Destroy"))
+
+	sc := config.New()
+	err = sc.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	r, err := sc.FindResource("container.api")
+	assert.NoError(t, err)
+	assert.Equal(t, config.PendingCreation, r.Info().Status)
+}
+
+func TestApplyWithRollbackRestoresReplacedResourcesAsPendingCreation(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	_, err := e.ApplyTargets(context.Background(), dir, []string{"container.consul"})
+	assert.NoError(t, err)
+
+	e.(*EngineImpl).getProvider = failCreateProvider(mp, "api")
+
+	_, err = e.ApplyWithOptions(context.Background(), dir, ApplyOptions{DisableBrowser: true, Rollback: true, Replace: []string{"container.consul"}})
+	assert.Error(t, err)
+
+	// consul was destroyed and recreated by the apply, the new instance is
+	// destroyed by the rollback
+	assert.Equal(t, 0, providerCalls(mp, "cloud", "Destroy"))
+	assert.Equal(t, 2, providerCalls(mp, "consul", "Destroy"))
+
+	sc := config.New()
+	err = sc.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	r, err := sc.FindResource("container.consul")
+	assert.NoError(t, err)
+	assert.Equal(t, config.PendingCreation, r.Info().Status)
+
+	r, err = sc.FindResource("network.cloud")
+	assert.NoError(t, err)
+	assert.Equal(t, config.Applied, r.Info().Status)
+}
@@ -0,0 +1,570 @@
+package shipyard
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// SBOMFormatSPDX is the SPDX 2.2 JSON format
+const SBOMFormatSPDX = "spdx"
+
+// SBOMFormatCycloneDX is the CycloneDX 1.4 JSON format
+const SBOMFormatCycloneDX = "cyclonedx"
+
+// SBOM is a bill of materials listing the images used by the running
+// environment, the contents of the images are not analysed
+type SBOM struct {
+	Created time.Time   `json:"created"`
+	Images  []SBOMImage `json:"images"` // sorted by name
+}
+
+// SBOMImage is an image used by one or more resources
+type SBOMImage struct {
+	config.ImageInfo
+	Resources []string `json:"resources"` // resources using the image in the form [type].[name]
+}
+
+// SBOM returns the images used by the created resources in the running
+// environment along with their digests, images which are no longer in the
+// Docker cache are listed without their details. Images for the workloads
+// created by helm, k8s_config, and nomad_job resources are read from the
+// manifests and jobs deployed to the clusters
+func (e *EngineImpl) SBOM() (*SBOM, error) {
+	sc, err := e.readState("")
+	if err != nil {
+		return nil, xerrors.Errorf("No resources are running: %w", err)
+	}
+
+	images := map[string]*SBOMImage{}
+
+	for _, r := range sc.Resources {
+		if r.Info().Status != config.Applied {
+			continue
+		}
+
+		cl, err := e.clientsForHostIn(sc, config.DockerHostFor(r))
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to get clients for resource %s: %w", resourceName(r), err)
+		}
+
+		names, err := e.resourceImages(sc, r, cl)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, n := range names {
+			si, ok := images[n]
+			if !ok {
+				si = &SBOMImage{ImageInfo: config.ImageInfo{Name: n}}
+
+				info, err := cl.ContainerTasks.InspectImage(context.Background(), n)
+				if err != nil {
+					e.log.Debug("Unable to inspect image", "image", n, "error", err)
+				} else {
+					si.ImageInfo = *info
+				}
+
+				images[n] = si
+			}
+
+			si.Resources = append(si.Resources, resourceName(r))
+		}
+	}
+
+	s := &SBOM{Created: time.Now().UTC(), Images: []SBOMImage{}}
+	for _, i := range images {
+		sort.Strings(i.Resources)
+		s.Images = append(s.Images, *i)
+	}
+
+	sort.Slice(s.Images, func(i, j int) bool {
+		return s.Images[i].Name < s.Images[j].Name
+	})
+
+	return s, nil
+}
+
+// resourceImages returns the names of the images used by the resource in
+// the state sc, workload images are only returned for resources deployed to
+// a cluster
+func (e *EngineImpl) resourceImages(sc *config.Config, r config.Resource, cl *Clients) ([]string, error) {
+	names := []string{}
+
+	switch v := r.(type) {
+	case *config.K8sConfig:
+		paths, err := providers.FetchPaths(cl.Getter, v.Paths)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to fetch Kubernetes config for %s: %w", resourceName(r), err)
+		}
+
+		for _, p := range paths {
+			d, err := readManifests(p)
+			if err != nil {
+				return nil, xerrors.Errorf("Unable to read Kubernetes config for %s: %w", resourceName(r), err)
+			}
+
+			i, err := manifestImages(d)
+			if err != nil {
+				return nil, xerrors.Errorf("Unable to parse Kubernetes config for %s: %w", resourceName(r), err)
+			}
+
+			names = append(names, i...)
+		}
+	case *config.Helm:
+		cluster, err := sc.FindResource(v.Cluster)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to find cluster for %s: %w", resourceName(r), err)
+		}
+
+		ns := v.Namespace
+		if ns == "" {
+			ns = "default"
+		}
+
+		_, kcPath, _ := utils.CreateKubeConfigPath(cluster.Info().Name)
+		m, err := e.getClusterClients(cl).Helm.Manifest(kcPath, v.Name, ns)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to get Helm release for %s: %w", resourceName(r), err)
+		}
+
+		i, err := manifestImages([]byte(m))
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to parse Helm release for %s: %w", resourceName(r), err)
+		}
+
+		names = append(names, i...)
+	case *config.NomadJob:
+		cluster, err := sc.FindResource(v.Cluster)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to find cluster for %s: %w", resourceName(r), err)
+		}
+
+		ccl := e.getClusterClients(cl)
+
+		_, configPath := utils.CreateNomadConfigPath(cluster.Info().Name)
+		err = ccl.Nomad.SetConfig(configPath)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to load Nomad config for %s: %w", resourceName(cluster), err)
+		}
+
+		paths, err := providers.FetchPaths(ccl.Getter, v.Paths)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to fetch Nomad jobs for %s: %w", resourceName(r), err)
+		}
+
+		for _, p := range paths {
+			d, err := ccl.Nomad.ParseJob(p)
+			if err != nil {
+				return nil, xerrors.Errorf("Unable to parse Nomad job for %s: %w", resourceName(r), err)
+			}
+
+			i, err := jobImages(d)
+			if err != nil {
+				return nil, xerrors.Errorf("Unable to parse Nomad job for %s: %w", resourceName(r), err)
+			}
+
+			names = append(names, i...)
+		}
+	default:
+		for _, i := range providers.Images(r) {
+			names = append(names, i.Name)
+		}
+	}
+
+	// a resource can use the same image more than once
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, n := range names {
+		if n != "" && !seen[n] {
+			seen[n] = true
+			unique = append(unique, n)
+		}
+	}
+
+	return unique, nil
+}
+
+// readManifests returns the contents of a Kubernetes config file, or the
+// yaml files in a folder, as a single multi document manifest
+func readManifests(path string) ([]byte, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if fi.IsDir() {
+		files = []string{}
+
+		for _, ext := range []string{"*.yaml", "*.yml"} {
+			f, err := filepath.Glob(filepath.Join(path, ext))
+			if err != nil {
+				return nil, err
+			}
+
+			files = append(files, f...)
+		}
+	}
+
+	m := []byte{}
+	for _, f := range files {
+		d, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		m = append(m, []byte("\n---\n")...)
+		m = append(m, d...)
+	}
+
+	return m, nil
+}
+
+// manifestImages returns the images for the containers defined by the
+// objects in a multi document Kubernetes manifest
+func manifestImages(m []byte) ([]string, error) {
+	images := []string{}
+
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			for k, c := range t {
+				if k == "containers" || k == "initContainers" {
+					cs, _ := c.([]interface{})
+					for _, ci := range cs {
+						if cm, ok := ci.(map[string]interface{}); ok {
+							if i, ok := cm["image"].(string); ok {
+								images = append(images, i)
+							}
+						}
+					}
+
+					continue
+				}
+
+				walk(c)
+			}
+		case []interface{}:
+			for _, c := range t {
+				walk(c)
+			}
+		}
+	}
+
+	d := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(m), 4096)
+	for {
+		var o interface{}
+		err := d.Decode(&o)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		walk(o)
+	}
+
+	return images, nil
+}
+
+// jobImages returns the images used by the docker tasks in a Nomad job
+// returned by the Nomad job parse API
+func jobImages(d []byte) ([]string, error) {
+	job := struct {
+		TaskGroups []struct {
+			Tasks []struct {
+				Config map[string]interface{}
+			}
+		}
+	}{}
+
+	err := json.Unmarshal(d, &job)
+	if err != nil {
+		return nil, err
+	}
+
+	images := []string{}
+	for _, tg := range job.TaskGroups {
+		for _, t := range tg.Tasks {
+			if i, ok := t.Config["image"].(string); ok {
+				images = append(images, i)
+			}
+		}
+	}
+
+	return images, nil
+}
+
+// ExportSBOM writes the bill of materials for the running environment to
+// path in the given format, either spdx or cyclonedx
+func (e *EngineImpl) ExportSBOM(path, format string) error {
+	s, err := e.SBOM()
+	if err != nil {
+		return err
+	}
+
+	var d []byte
+
+	switch format {
+	case SBOMFormatSPDX:
+		d, err = s.SPDX()
+	case SBOMFormatCycloneDX:
+		d, err = s.CycloneDX()
+	default:
+		return xerrors.Errorf("SBOM format %s is not supported, use %s or %s", format, SBOMFormatSPDX, SBOMFormatCycloneDX)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(path, d, 0644)
+	if err != nil {
+		return xerrors.Errorf("Unable to write SBOM %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// SPDX returns the bill of materials as an SPDX 2.2 JSON document, each
+// image is a package described by the document
+func (s *SBOM) SPDX() ([]byte, error) {
+	type checksum struct {
+		Algorithm string `json:"algorithm"`
+		Value     string `json:"checksumValue"`
+	}
+
+	type externalRef struct {
+		Category string `json:"referenceCategory"`
+		Type     string `json:"referenceType"`
+		Locator  string `json:"referenceLocator"`
+	}
+
+	type pkg struct {
+		ID               string        `json:"SPDXID"`
+		Name             string        `json:"name"`
+		Version          string        `json:"versionInfo,omitempty"`
+		DownloadLocation string        `json:"downloadLocation"`
+		FilesAnalyzed    bool          `json:"filesAnalyzed"`
+		LicenseConcluded string        `json:"licenseConcluded"`
+		LicenseDeclared  string        `json:"licenseDeclared"`
+		CopyrightText    string        `json:"copyrightText"`
+		Checksums        []checksum    `json:"checksums,omitempty"`
+		ExternalRefs     []externalRef `json:"externalRefs,omitempty"`
+		Comment          string        `json:"comment,omitempty"`
+	}
+
+	type relationship struct {
+		Element string `json:"spdxElementId"`
+		Type    string `json:"relationshipType"`
+		Related string `json:"relatedSpdxElement"`
+	}
+
+	doc := struct {
+		Version      string `json:"spdxVersion"`
+		DataLicense  string `json:"dataLicense"`
+		ID           string `json:"SPDXID"`
+		Name         string `json:"name"`
+		Namespace    string `json:"documentNamespace"`
+		CreationInfo struct {
+			Created  string   `json:"created"`
+			Creators []string `json:"creators"`
+		} `json:"creationInfo"`
+		Packages      []pkg          `json:"packages"`
+		Relationships []relationship `json:"relationships"`
+	}{
+		Version:       "SPDX-2.2",
+		DataLicense:   "CC0-1.0",
+		ID:            "SPDXRef-DOCUMENT",
+		Name:          "shipyard-environment",
+		Namespace:     fmt.Sprintf("https://shipyard.run/spdx/shipyard-environment-%s", newUUID()),
+		Packages:      []pkg{},
+		Relationships: []relationship{},
+	}
+
+	doc.CreationInfo.Created = s.Created.Format(time.RFC3339)
+	doc.CreationInfo.Creators = []string{"Tool: shipyard"}
+
+	for n, i := range s.Images {
+		repo, version := splitImageName(i.Name)
+
+		p := pkg{
+			ID:               fmt.Sprintf("SPDXRef-Image-%d", n+1),
+			Name:             repo,
+			Version:          version,
+			DownloadLocation: "NOASSERTION",
+			LicenseConcluded: "NOASSERTION",
+			LicenseDeclared:  "NOASSERTION",
+			CopyrightText:    "NOASSERTION",
+			ExternalRefs:     []externalRef{{"PACKAGE-MANAGER", "purl", imagePURL(i.ImageInfo)}},
+			Comment:          fmt.Sprintf("Used by %s", strings.Join(i.Resources, ", ")),
+		}
+
+		if h := strings.TrimPrefix(i.Digest, "sha256:"); h != i.Digest {
+			p.Checksums = []checksum{{"SHA256", h}}
+		}
+
+		doc.Packages = append(doc.Packages, p)
+		doc.Relationships = append(doc.Relationships, relationship{doc.ID, "DESCRIBES", p.ID})
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// CycloneDX returns the bill of materials as a CycloneDX 1.4 JSON document,
+// each image is a container component
+func (s *SBOM) CycloneDX() ([]byte, error) {
+	type hash struct {
+		Alg     string `json:"alg"`
+		Content string `json:"content"`
+	}
+
+	type property struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	type tool struct {
+		Vendor string `json:"vendor"`
+		Name   string `json:"name"`
+	}
+
+	type component struct {
+		Type       string     `json:"type"`
+		Ref        string     `json:"bom-ref"`
+		Name       string     `json:"name"`
+		Version    string     `json:"version,omitempty"`
+		Hashes     []hash     `json:"hashes,omitempty"`
+		PURL       string     `json:"purl"`
+		Properties []property `json:"properties,omitempty"`
+	}
+
+	doc := struct {
+		Format   string `json:"bomFormat"`
+		Spec     string `json:"specVersion"`
+		Serial   string `json:"serialNumber"`
+		Version  int    `json:"version"`
+		Metadata struct {
+			Timestamp string `json:"timestamp"`
+			Tools     []tool `json:"tools"`
+		} `json:"metadata"`
+		Components []component `json:"components"`
+	}{
+		Format:     "CycloneDX",
+		Spec:       "1.4",
+		Serial:     "urn:uuid:" + newUUID(),
+		Version:    1,
+		Components: []component{},
+	}
+
+	doc.Metadata.Timestamp = s.Created.Format(time.RFC3339)
+	doc.Metadata.Tools = []tool{{"Shipyard", "shipyard"}}
+
+	for _, i := range s.Images {
+		repo, version := splitImageName(i.Name)
+
+		c := component{
+			Type:    "container",
+			Ref:     i.Name,
+			Name:    repo,
+			Version: version,
+			PURL:    imagePURL(i.ImageInfo),
+		}
+
+		if h := strings.TrimPrefix(i.Digest, "sha256:"); h != i.Digest {
+			c.Hashes = []hash{{"SHA-256", h}}
+		}
+
+		for _, r := range i.Resources {
+			c.Properties = append(c.Properties, property{"shipyard:resource", r})
+		}
+
+		if i.Architecture != "" {
+			c.Properties = append(c.Properties, property{"shipyard:platform", fmt.Sprintf("%s/%s", i.OS, i.Architecture)})
+		}
+
+		doc.Components = append(doc.Components, c)
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// splitImageName returns the repository and tag for an image, images
+// referenced by digest return the digest as the tag
+func splitImageName(name string) (string, string) {
+	repo, version := name, ""
+
+	if i := strings.Index(repo, "@"); i > 0 {
+		repo, version = repo[:i], repo[i+1:]
+	}
+
+	// the tag is dropped when the image is pinned to a digest
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		if version == "" {
+			version = repo[i+1:]
+		}
+
+		repo = repo[:i]
+	}
+
+	return repo, version
+}
+
+// imagePURL returns the package URL for an image, the version is the
+// digest when the image has been pulled from a registry
+func imagePURL(i config.ImageInfo) string {
+	repo, version := splitImageName(i.Name)
+	if i.Digest != "" {
+		version = i.Digest
+	}
+
+	fq := strings.SplitN(qualifiedImageName(repo), "/", 2)
+
+	p := fmt.Sprintf("pkg:docker/%s", fq[1])
+	if version != "" {
+		p += "@" + url.PathEscape(version)
+	}
+
+	if fq[0] != "docker.io" {
+		p += "?repository_url=" + url.QueryEscape(fq[0])
+	}
+
+	return p
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		// fall back to a UUID derived from the time when random data is not available
+		h := sha256.Sum256([]byte(time.Now().String()))
+		copy(b, h[:])
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
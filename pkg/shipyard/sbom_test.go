@@ -0,0 +1,271 @@
+package shipyard
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupSBOMTests() (Engine, func()) {
+	e, _, _, cleanup := setupTestsWithState(nil, composeState)
+
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	ct.On("InspectImage", "consul:1.7.2").Return(&config.ImageInfo{
+		Name:         "consul:1.7.2",
+		ID:           "sha256:123",
+		Digest:       "sha256:abc",
+		Architecture: "amd64",
+		OS:           "linux",
+	}, nil)
+	ct.On("InspectImage", mock.Anything).Return(nil, fmt.Errorf("not found"))
+
+	return e, cleanup
+}
+
+func TestSBOMReturnsImagesForResources(t *testing.T) {
+	e, cleanup := setupSBOMTests()
+	defer cleanup()
+
+	s, err := e.SBOM()
+	assert.NoError(t, err)
+
+	assert.Len(t, s.Images, 3)
+
+	assert.Equal(t, "consul:1.7.2", s.Images[0].Name)
+	assert.Equal(t, "sha256:abc", s.Images[0].Digest)
+	assert.Equal(t, []string{"container.consul"}, s.Images[0].Resources)
+
+	// images which are not in the cache are listed without details
+	assert.Equal(t, "envoyproxy/envoy:v1.14.1", s.Images[1].Name)
+	assert.Equal(t, []string{"sidecar.envoy"}, s.Images[1].Resources)
+	assert.Empty(t, s.Images[1].Digest)
+}
+
+func TestSBOMReturnsErrorWhenNoState(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	_, err := e.SBOM()
+	assert.Error(t, err)
+}
+
+func TestSBOMDoesNotChangeConfig(t *testing.T) {
+	e, cleanup := setupSBOMTests()
+	defer cleanup()
+
+	_, err := e.SBOM()
+	assert.NoError(t, err)
+
+	assert.Nil(t, e.(*EngineImpl).config)
+}
+
+func TestSBOMReturnsImagesForClusterWorkloads(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "app.yaml"), []byte(sbomManifest), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "job.nomad"), []byte(""), 0644)
+
+	e, _, _, cleanup := setupTestsWithState(nil, fmt.Sprintf(sbomWorkloadState, dir, filepath.Join(dir, "job.nomad")))
+	defer cleanup()
+
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	ct.On("InspectImage", mock.Anything).Return(nil, fmt.Errorf("not found"))
+
+	hm := &clientmocks.MockHelm{}
+	hm.On("Manifest", mock.Anything, "vault", "default").Return(sbomHelmManifest, nil)
+
+	nm := &clientmocks.MockNomad{}
+	nm.On("SetConfig", mock.Anything).Return(nil)
+	nm.On("ParseJob", mock.Anything).Return([]byte(sbomNomadJob), nil)
+
+	e.GetClients().Helm = hm
+	e.GetClients().Nomad = nm
+
+	s, err := e.SBOM()
+	assert.NoError(t, err)
+
+	images := map[string][]string{}
+	for _, i := range s.Images {
+		images[i.Name] = i.Resources
+	}
+
+	assert.Equal(t, []string{"k8s_config.app"}, images["nginx:1.19"])
+	assert.Equal(t, []string{"k8s_config.app"}, images["busybox@sha256:abc"])
+	assert.Equal(t, []string{"helm.vault"}, images["vault:1.5.0"])
+	assert.Equal(t, []string{"nomad_job.redis"}, images["redis:6"])
+
+	// config values which are not container images are ignored
+	assert.NotContains(t, images, "not-an-image")
+}
+
+func TestSplitImageName(t *testing.T) {
+	tests := map[string][]string{
+		"consul":                              {"consul", ""},
+		"consul:1.8.1":                        {"consul", "1.8.1"},
+		"localhost:5000/consul":               {"localhost:5000/consul", ""},
+		"localhost:5000/consul:1.8.1":         {"localhost:5000/consul", "1.8.1"},
+		"consul@sha256:abc":                   {"consul", "sha256:abc"},
+		"consul:1.8.1@sha256:abc":             {"consul", "sha256:abc"},
+		"localhost:5000/consul:1.8@sha256:ab": {"localhost:5000/consul", "sha256:ab"},
+	}
+
+	for n, exp := range tests {
+		repo, version := splitImageName(n)
+		assert.Equal(t, exp[0], repo, n)
+		assert.Equal(t, exp[1], version, n)
+	}
+}
+
+func TestExportSBOMWritesSPDX(t *testing.T) {
+	e, cleanup := setupSBOMTests()
+	defer cleanup()
+
+	out := filepath.Join(utils.ShipyardHome(), "sbom.spdx.json")
+	os.MkdirAll(utils.ShipyardHome(), os.ModePerm)
+
+	err := e.ExportSBOM(out, SBOMFormatSPDX)
+	assert.NoError(t, err)
+
+	doc := map[string]interface{}{}
+	d, _ := ioutil.ReadFile(out)
+	json.Unmarshal(d, &doc)
+
+	assert.Equal(t, "SPDX-2.2", doc["spdxVersion"])
+
+	pkgs := doc["packages"].([]interface{})
+	assert.Len(t, pkgs, 3)
+
+	p := pkgs[0].(map[string]interface{})
+	assert.Equal(t, "consul", p["name"])
+	assert.Equal(t, "1.7.2", p["versionInfo"])
+	assert.Equal(t, "pkg:docker/library/consul@sha256:abc", p["externalRefs"].([]interface{})[0].(map[string]interface{})["referenceLocator"])
+	assert.Equal(t, "abc", p["checksums"].([]interface{})[0].(map[string]interface{})["checksumValue"])
+}
+
+func TestExportSBOMWritesCycloneDX(t *testing.T) {
+	e, cleanup := setupSBOMTests()
+	defer cleanup()
+
+	out := filepath.Join(utils.ShipyardHome(), "sbom.cdx.json")
+	os.MkdirAll(utils.ShipyardHome(), os.ModePerm)
+
+	err := e.ExportSBOM(out, SBOMFormatCycloneDX)
+	assert.NoError(t, err)
+
+	doc := map[string]interface{}{}
+	d, _ := ioutil.ReadFile(out)
+	json.Unmarshal(d, &doc)
+
+	assert.Equal(t, "CycloneDX", doc["bomFormat"])
+
+	c := doc["components"].([]interface{})[1].(map[string]interface{})
+	assert.Equal(t, "container", c["type"])
+	assert.Equal(t, "envoyproxy/envoy", c["name"])
+	assert.Equal(t, "pkg:docker/envoyproxy/envoy@v1.14.1", c["purl"])
+}
+
+func TestExportSBOMReturnsErrorForUnknownFormat(t *testing.T) {
+	e, cleanup := setupSBOMTests()
+	defer cleanup()
+
+	err := e.ExportSBOM("sbom.json", "xml")
+	assert.Error(t, err)
+}
+
+var sbomWorkloadState = `
+{
+  "blueprint": null,
+  "resources": [
+	{
+      "name": "k3s",
+      "status": "applied",
+      "type": "k8s_cluster"
+	},
+	{
+      "name": "app",
+      "status": "applied",
+      "cluster": "k8s_cluster.k3s",
+      "paths": [%q],
+      "type": "k8s_config"
+	},
+	{
+      "name": "vault",
+      "status": "applied",
+      "cluster": "k8s_cluster.k3s",
+      "type": "helm"
+	},
+	{
+      "name": "dev",
+      "status": "applied",
+      "type": "nomad_cluster"
+	},
+	{
+      "name": "redis",
+      "status": "applied",
+      "cluster": "nomad_cluster.dev",
+      "paths": [%q],
+      "type": "nomad_job"
+	}
+  ]
+}
+`
+
+var sbomManifest = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+data:
+  image: not-an-image
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: busybox@sha256:abc
+      containers:
+      - name: app
+        image: nginx:1.19
+`
+
+var sbomHelmManifest = `
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: vault
+spec:
+  containers:
+  - name: vault
+    image: vault:1.5.0
+`
+
+var sbomNomadJob = `
+{
+  "ID": "redis",
+  "TaskGroups": [
+    {
+      "Tasks": [
+        {"Driver": "docker", "Config": {"image": "redis:6"}},
+        {"Driver": "exec", "Config": {"command": "/bin/true"}}
+      ]
+    }
+  ]
+}
+`
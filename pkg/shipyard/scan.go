@@ -0,0 +1,239 @@
+package shipyard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers"
+	"golang.org/x/xerrors"
+)
+
+// Vulnerability is a vulnerability reported by the image scanner
+type Vulnerability struct {
+	ID       string `json:"id"`
+	Package  string `json:"package"`
+	Version  string `json:"version"`
+	Severity string `json:"severity"`
+}
+
+// ImageScanResult contains the vulnerabilities found in an image at or
+// above the severity set in the blueprint
+type ImageScanResult struct {
+	Image           string          `json:"image"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+}
+
+// ScanError is returned by Apply when the scan action is block and images
+// contain vulnerabilities at or above the severity set in the blueprint
+type ScanError struct {
+	Severity string
+	Results  []ImageScanResult
+}
+
+func (s *ScanError) Error() string {
+	r := []string{}
+	for _, ir := range s.Results {
+		ids := []string{}
+		for _, v := range ir.Vulnerabilities {
+			ids = append(ids, fmt.Sprintf("%s (%s)", v.ID, v.Severity))
+		}
+
+		r = append(r, fmt.Sprintf("  %s: %s", ir.Image, strings.Join(ids, ", ")))
+	}
+
+	return fmt.Sprintf("Images contain vulnerabilities with severity %s or higher:\n%s", s.Severity, strings.Join(r, "\n"))
+}
+
+// scanImages scans the images for the resources which will be created when
+// the blueprint defines a scan block. Depending on the action in the
+// blueprint vulnerabilities are logged as warnings or the apply is blocked.
+// Images for resources on a docker_host are not scanned.
+func (e *EngineImpl) scanImages(ctx context.Context) error {
+	if e.config.Blueprint == nil || e.config.Blueprint.Scan == nil {
+		return nil
+	}
+
+	s := e.config.Blueprint.Scan
+	block := s.Action == "block"
+
+	severity := strings.ToUpper(s.Severity)
+	if severity == "" {
+		severity = "HIGH"
+	}
+
+	if config.SeverityLevel(severity) < 0 {
+		return xerrors.Errorf("Unknown scan severity %s", s.Severity)
+	}
+
+	images := []string{}
+	seen := map[string]bool{}
+
+	for _, r := range e.targetedResources() {
+		if !pendingApply(r) || config.DockerHostFor(r) != "" {
+			continue
+		}
+
+		for _, i := range providers.Images(r) {
+			if i.Name != "" && !seen[i.Name] {
+				seen[i.Name] = true
+				images = append(images, i.Name)
+			}
+		}
+	}
+
+	sort.Strings(images)
+
+	results := []ImageScanResult{}
+
+	for _, i := range images {
+		e.log.Info("Scanning image for vulnerabilities", "image", i)
+
+		vulns, err := e.scanImage(ctx, s, i, severity)
+		if err != nil {
+			// when blocking an image which can not be scanned must not be used
+			if block {
+				return xerrors.Errorf("Unable to scan image %s: %w", i, err)
+			}
+
+			e.log.Warn("Unable to scan image", "image", i, "error", err)
+			continue
+		}
+
+		if len(vulns) == 0 {
+			continue
+		}
+
+		e.log.Warn("Image contains vulnerabilities", "image", i, "severity", severity, "count", len(vulns))
+		results = append(results, ImageScanResult{Image: i, Vulnerabilities: vulns})
+	}
+
+	if block && len(results) > 0 {
+		return &ScanError{Severity: severity, Results: results}
+	}
+
+	return nil
+}
+
+// scanImage runs the scanner for the image and returns the vulnerabilities
+// at or above the severity which are not ignored
+func (e *EngineImpl) scanImage(ctx context.Context, s *config.ImageScan, image, severity string) ([]Vulnerability, error) {
+	scanner := s.Scanner
+	if scanner == "" {
+		scanner = "trivy"
+	}
+
+	cmd := s.Command
+	if cmd == "" {
+		cmd = scanner
+	}
+
+	var args []string
+	var parse func([]byte) ([]Vulnerability, error)
+
+	switch scanner {
+	case "trivy":
+		args = []string{"--quiet", "image", "--format", "json", image}
+		parse = parseTrivy
+	case "grype":
+		args = []string{image, "--quiet", "--output", "json"}
+		parse = parseGrype
+	default:
+		return nil, xerrors.Errorf("Unknown scanner %s", scanner)
+	}
+
+	out := &bytes.Buffer{}
+
+	err := e.clients.Command.Execute(ctx, clients.CommandConfig{
+		Command:   cmd,
+		Arguments: args,
+		Output:    out,
+	})
+
+	if err != nil {
+		return nil, xerrors.Errorf("Scanner %s failed: %w", scanner, err)
+	}
+
+	all, err := parse(out.Bytes())
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to read output from %s: %w", scanner, err)
+	}
+
+	ignore := map[string]bool{}
+	for _, id := range s.Ignore {
+		ignore[id] = true
+	}
+
+	vulns := []Vulnerability{}
+	for _, v := range all {
+		if !ignore[v.ID] && config.SeverityLevel(v.Severity) >= config.SeverityLevel(severity) {
+			vulns = append(vulns, v)
+		}
+	}
+
+	return vulns, nil
+}
+
+// parseTrivy reads the JSON report from trivy, older versions return a list
+// of results and newer versions return an object containing the results
+func parseTrivy(d []byte) ([]Vulnerability, error) {
+	type result struct {
+		Vulnerabilities []struct {
+			VulnerabilityID  string
+			PkgName          string
+			InstalledVersion string
+			Severity         string
+		}
+	}
+
+	results := []result{}
+
+	report := struct{ Results []result }{}
+	if err := json.Unmarshal(d, &report); err == nil {
+		results = report.Results
+	} else if err := json.Unmarshal(d, &results); err != nil {
+		return nil, err
+	}
+
+	vulns := []Vulnerability{}
+	for _, r := range results {
+		for _, v := range r.Vulnerabilities {
+			vulns = append(vulns, Vulnerability{ID: v.VulnerabilityID, Package: v.PkgName, Version: v.InstalledVersion, Severity: strings.ToUpper(v.Severity)})
+		}
+	}
+
+	return vulns, nil
+}
+
+// parseGrype reads the JSON report from grype
+func parseGrype(d []byte) ([]Vulnerability, error) {
+	report := struct {
+		Matches []struct {
+			Vulnerability struct {
+				ID       string `json:"id"`
+				Severity string `json:"severity"`
+			} `json:"vulnerability"`
+			Artifact struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			} `json:"artifact"`
+		} `json:"matches"`
+	}{}
+
+	err := json.Unmarshal(d, &report)
+	if err != nil {
+		return nil, err
+	}
+
+	vulns := []Vulnerability{}
+	for _, m := range report.Matches {
+		vulns = append(vulns, Vulnerability{ID: m.Vulnerability.ID, Package: m.Artifact.Name, Version: m.Artifact.Version, Severity: strings.ToUpper(m.Vulnerability.Severity)})
+	}
+
+	return vulns, nil
+}
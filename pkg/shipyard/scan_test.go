@@ -0,0 +1,132 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/xerrors"
+)
+
+// mockCommand is defined here as the Command interface depends on types
+// from the clients package
+type mockCommand struct {
+	mock.Mock
+}
+
+func (m *mockCommand) Execute(ctx context.Context, config clients.CommandConfig) error {
+	return m.Called(config).Error(0)
+}
+
+func setupScanTests(t *testing.T, e Engine, action, report string, scanErr error) (string, *mockCommand) {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	ioutil.WriteFile(filepath.Join(dir, "main.hcl"), []byte(scanContainer), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "README.yard"), []byte(fmt.Sprintf(scanBlueprint, action)), 0644)
+
+	mc := &mockCommand{}
+	mc.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(clients.CommandConfig).Output.Write([]byte(report))
+	}).Return(scanErr)
+
+	e.GetClients().Command = mc
+
+	return dir, mc
+}
+
+func TestApplyBlocksWhenImagesContainVulnerabilities(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, mc := setupScanTests(t, e, "block", trivyReport, nil)
+	defer os.RemoveAll(dir)
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.Error(t, err)
+
+	se := &ScanError{}
+	assert.True(t, xerrors.As(err, &se))
+	assert.Len(t, se.Results, 1)
+	assert.Equal(t, "consul:1.8.1", se.Results[0].Image)
+
+	// medium and ignored vulnerabilities are not reported
+	assert.Equal(t, []Vulnerability{Vulnerability{ID: "CVE-2020-0002", Package: "openssl", Version: "1.1.1", Severity: "CRITICAL"}}, se.Results[0].Vulnerabilities)
+
+	params := mc.Calls[0].Arguments[0].(clients.CommandConfig)
+	assert.Equal(t, "trivy", params.Command)
+	assert.Contains(t, params.Arguments, "consul:1.8.1")
+
+	assert.Len(t, *mp, 0)
+}
+
+func TestApplyWarnsWhenImagesContainVulnerabilities(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, _ := setupScanTests(t, e, "warn", trivyReport, nil)
+	defer os.RemoveAll(dir)
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	assert.Len(t, *mp, 1)
+}
+
+func TestApplyBlocksWhenScannerFails(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, _ := setupScanTests(t, e, "block", "", fmt.Errorf("trivy not found"))
+	defer os.RemoveAll(dir)
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Unable to scan image consul:1.8.1")
+}
+
+func TestParseGrypeReturnsVulnerabilities(t *testing.T) {
+	v, err := parseGrype([]byte(`{"matches":[{"vulnerability":{"id":"CVE-2020-0003","severity":"High"},"artifact":{"name":"curl","version":"7.0"}}]}`))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []Vulnerability{Vulnerability{ID: "CVE-2020-0003", Package: "curl", Version: "7.0", Severity: "HIGH"}}, v)
+}
+
+var scanBlueprint = `
+title = "Scanned"
+
+scan {
+  severity = "high"
+  action   = "%s"
+  ignore   = ["CVE-2020-0003"]
+}
+`
+
+var scanContainer = `
+container "consul" {
+  image {
+    name = "consul:1.8.1"
+  }
+}
+`
+
+var trivyReport = `
+{
+  "Results": [
+    {
+      "Target": "consul:1.8.1 (alpine 3.12.0)",
+      "Vulnerabilities": [
+        {"VulnerabilityID": "CVE-2020-0001", "PkgName": "musl", "InstalledVersion": "1.1.24", "Severity": "MEDIUM"},
+        {"VulnerabilityID": "CVE-2020-0002", "PkgName": "openssl", "InstalledVersion": "1.1.1", "Severity": "CRITICAL"},
+        {"VulnerabilityID": "CVE-2020-0003", "PkgName": "curl", "InstalledVersion": "7.0", "Severity": "HIGH"}
+      ]
+    }
+  ]
+}
+`
@@ -0,0 +1,293 @@
+package shipyard
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/xerrors"
+)
+
+// SignatureExtension is appended to the name of a blueprint package to give
+// the location of its signature, this is the default used by minisign
+const SignatureExtension = ".minisig"
+
+// trustedComment prefixes the comment covered by the global signature
+const trustedComment = "trusted comment: "
+
+// minisign algorithms, legacy signatures sign the file and current
+// signatures sign the BLAKE2b-512 hash of the file
+var (
+	signatureAlgLegacy = []byte("Ed")
+	signatureAlgHashed = []byte("ED")
+)
+
+// TrustedKeysDir returns the folder containing the minisign public keys
+// which are trusted to sign blueprint packages
+func TrustedKeysDir() string {
+	return filepath.Join(utils.ShipyardHome(), "trusted_keys")
+}
+
+type trustedKey struct {
+	file string
+	id   []byte
+	key  ed25519.PublicKey
+}
+
+// loadTrustedKeys reads the public keys in the trusted keys folder, keys are
+// files with the extension .pub created by minisign -G
+func loadTrustedKeys() ([]trustedKey, error) {
+	files, err := filepath.Glob(filepath.Join(TrustedKeysDir(), "*.pub"))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []trustedKey{}
+
+	for _, f := range files {
+		d, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to read trusted key %s: %w", f, err)
+		}
+
+		k, err := decodeMinisignLine(d)
+		if err != nil || len(k) != 42 || !bytes.Equal(k[:2], signatureAlgLegacy) {
+			return nil, xerrors.Errorf("Trusted key %s is not a valid minisign public key", f)
+		}
+
+		keys = append(keys, trustedKey{file: filepath.Base(f), id: k[2:10], key: ed25519.PublicKey(k[10:])})
+	}
+
+	return keys, nil
+}
+
+// verifyPackage checks the signature for the blueprint package against the
+// trusted keys. When no keys are trusted packages are not verified, once a
+// key has been trusted every package must be signed by a trusted key.
+// The name of the key which signed the package is returned, an empty name
+// means the package has not been verified.
+func verifyPackage(pkg string) (string, error) {
+	keys, err := loadTrustedKeys()
+	if err != nil {
+		return "", err
+	}
+
+	if len(keys) == 0 {
+		return "", nil
+	}
+
+	sig, err := ioutil.ReadFile(pkg + SignatureExtension)
+	if os.IsNotExist(err) {
+		return "", xerrors.Errorf("Package %s is not signed, packages must be signed by a trusted key", pkg)
+	}
+
+	if err != nil {
+		return "", xerrors.Errorf("Unable to read signature for %s: %w", pkg, err)
+	}
+
+	d, err := ioutil.ReadFile(pkg)
+	if err != nil {
+		return "", xerrors.Errorf("Unable to read package %s: %w", pkg, err)
+	}
+
+	return verifySignature(d, sig, keys)
+}
+
+// verifySignature verifies a minisign signature for the data, the
+// signature contains an untrusted comment, the signature, a trusted comment
+// and a global signature of the signature and trusted comment
+func verifySignature(data, sig []byte, keys []trustedKey) (string, error) {
+	lines := []string{}
+
+	s := bufio.NewScanner(bytes.NewReader(sig))
+	for s.Scan() {
+		if l := strings.TrimSpace(s.Text()); l != "" {
+			lines = append(lines, l)
+		}
+	}
+
+	if len(lines) != 4 || !strings.HasPrefix(lines[2], trustedComment) {
+		return "", xerrors.Errorf("Signature is not a valid minisign signature")
+	}
+
+	sd, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(sd) != 74 {
+		return "", xerrors.Errorf("Signature is not a valid minisign signature")
+	}
+
+	gs, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(gs) != ed25519.SignatureSize {
+		return "", xerrors.Errorf("Signature is not a valid minisign signature")
+	}
+
+	alg, id, signature := sd[:2], sd[2:10], sd[10:]
+
+	switch {
+	case bytes.Equal(alg, signatureAlgHashed):
+		h := blake2b.Sum512(data)
+		data = h[:]
+	case !bytes.Equal(alg, signatureAlgLegacy):
+		return "", xerrors.Errorf("Signature algorithm %s is not supported", alg)
+	}
+
+	for _, k := range keys {
+		if !bytes.Equal(k.id, id) {
+			continue
+		}
+
+		if !ed25519.Verify(k.key, data, signature) {
+			return "", xerrors.Errorf("Signature does not match the package, the package may have been modified")
+		}
+
+		comment := strings.TrimPrefix(lines[2], trustedComment)
+		if !ed25519.Verify(k.key, append(append([]byte{}, signature...), comment...), gs) {
+			return "", xerrors.Errorf("Trusted comment in the signature has been modified")
+		}
+
+		return k.file, nil
+	}
+
+	return "", xerrors.Errorf("Package is not signed by a trusted key, trusted keys are read from %s", TrustedKeysDir())
+}
+
+// decodeMinisignLine decodes the first line of a minisign file which is not
+// a comment
+func decodeMinisignLine(d []byte) ([]byte, error) {
+	s := bufio.NewScanner(bytes.NewReader(d))
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		if l == "" || strings.HasPrefix(l, "untrusted comment:") {
+			continue
+		}
+
+		return base64.StdEncoding.DecodeString(l)
+	}
+
+	return nil, xerrors.Errorf("No key found")
+}
+
+// isRemoteBlueprint returns true for blueprint folders fetched from a remote
+// source, folders are not signed so their content can not be verified
+func isRemoteBlueprint(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	remote := filepath.Join(utils.ShipyardHome(), "blueprints") + string(os.PathSeparator)
+	return strings.HasPrefix(abs, remote)
+}
+
+// minisign secret keys which are not encrypted have a zero KDF algorithm
+var (
+	kdfAlgNone     = []byte{0, 0}
+	checksumAlgB2  = []byte("B2")
+	secretKeyBytes = 158
+)
+
+// GenerateSigningKey writes a new minisign secret key to path and the
+// public key to path.pub, the public key is copied to the trusted keys
+// folder of anyone who runs the packages signed with the secret key.
+// The secret key is not encrypted, it is only readable by the current user
+func GenerateSigningKey(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return xerrors.Errorf("Key %s already exists", path)
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return xerrors.Errorf("Unable to generate key: %w", err)
+	}
+
+	id := make([]byte, 8)
+	_, err = rand.Read(id)
+	if err != nil {
+		return xerrors.Errorf("Unable to generate key: %w", err)
+	}
+
+	sum := blake2b.Sum256(append(append(append([]byte{}, signatureAlgLegacy...), id...), priv...))
+
+	sk := []byte{}
+	sk = append(sk, signatureAlgLegacy...)
+	sk = append(sk, kdfAlgNone...)
+	sk = append(sk, checksumAlgB2...)
+	sk = append(sk, make([]byte, 48)...) // salt, opslimit and memlimit are unused without a KDF
+	sk = append(sk, id...)
+	sk = append(sk, priv...)
+	sk = append(sk, sum[:]...)
+
+	err = ioutil.WriteFile(path, []byte("untrusted comment: minisign secret key\n"+base64.StdEncoding.EncodeToString(sk)+"\n"), 0600)
+	if err != nil {
+		return xerrors.Errorf("Unable to write secret key: %w", err)
+	}
+
+	pk := append(append(append([]byte{}, signatureAlgLegacy...), id...), pub...)
+	err = ioutil.WriteFile(path+".pub", []byte(fmt.Sprintf("untrusted comment: minisign public key %X\n%s\n", id, base64.StdEncoding.EncodeToString(pk))), 0644)
+	if err != nil {
+		return xerrors.Errorf("Unable to write public key: %w", err)
+	}
+
+	return nil
+}
+
+// SignPackage signs the blueprint package with the minisign secret key and
+// writes the signature next to the package, keys encrypted with a password
+// are not supported, sign the package with minisign -Sm instead
+func SignPackage(pkg, keyPath string) error {
+	d, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return xerrors.Errorf("Unable to read secret key %s: %w", keyPath, err)
+	}
+
+	sk, err := decodeMinisignLine(d)
+	if err != nil || len(sk) != secretKeyBytes || !bytes.Equal(sk[:2], signatureAlgLegacy) {
+		return xerrors.Errorf("Key %s is not a valid minisign secret key", keyPath)
+	}
+
+	if !bytes.Equal(sk[2:4], kdfAlgNone) {
+		return xerrors.Errorf("Key %s is encrypted with a password, sign the package with minisign -Sm %s", keyPath, pkg)
+	}
+
+	id, priv, sum := sk[54:62], ed25519.PrivateKey(sk[62:126]), sk[126:]
+
+	expected := blake2b.Sum256(append(append(append([]byte{}, signatureAlgLegacy...), id...), priv...))
+	if !bytes.Equal(sum, expected[:]) {
+		return xerrors.Errorf("Key %s is corrupt, the checksum does not match", keyPath)
+	}
+
+	data, err := ioutil.ReadFile(pkg)
+	if err != nil {
+		return xerrors.Errorf("Unable to read package %s: %w", pkg, err)
+	}
+
+	h := blake2b.Sum512(data)
+	sig := ed25519.Sign(priv, h[:])
+
+	comment := fmt.Sprintf("timestamp:%d\tfile:%s\thashed", time.Now().Unix(), filepath.Base(pkg))
+	gs := ed25519.Sign(priv, append(append([]byte{}, sig...), comment...))
+
+	ms := fmt.Sprintf(
+		"untrusted comment: signature from shipyard secret key\n%s\n%s%s\n%s\n",
+		base64.StdEncoding.EncodeToString(append(append(append([]byte{}, signatureAlgHashed...), id...), sig...)),
+		trustedComment,
+		comment,
+		base64.StdEncoding.EncodeToString(gs),
+	)
+
+	err = ioutil.WriteFile(pkg+SignatureExtension, []byte(ms), 0644)
+	if err != nil {
+		return xerrors.Errorf("Unable to write signature: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,268 @@
+package shipyard
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/blake2b"
+)
+
+// signTestPackage writes a minisign public key to the trusted keys when
+// trust is true and signs the package with the matching private key
+func signTestPackage(t *testing.T, pkg string, trust bool) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	id := []byte("12345678")
+
+	if trust {
+		os.MkdirAll(TrustedKeysDir(), os.ModePerm)
+		k := base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), id...), pub...))
+		ioutil.WriteFile(filepath.Join(TrustedKeysDir(), "workshop.pub"), []byte("untrusted comment: minisign public key\n"+k+"\n"), 0644)
+	}
+
+	d, _ := ioutil.ReadFile(pkg)
+	h := blake2b.Sum512(d)
+	sig := ed25519.Sign(priv, h[:])
+
+	comment := "timestamp:1600000000\tfile:consul.yardpack"
+	gs := ed25519.Sign(priv, append(append([]byte{}, sig...), comment...))
+
+	ms := fmt.Sprintf(
+		"untrusted comment: signature from minisign secret key\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(append(append([]byte("ED"), id...), sig...)),
+		comment,
+		base64.StdEncoding.EncodeToString(gs),
+	)
+
+	ioutil.WriteFile(pkg+SignatureExtension, []byte(ms), 0644)
+}
+
+func setupSignatureTests(t *testing.T) (string, func()) {
+	src, out := setupPackTests(t)
+
+	pack := filepath.Join(out, "consul"+utils.BlueprintPackageExtension)
+	_, err := Pack(src, pack, "v1.0.0")
+	assert.NoError(t, err)
+
+	return pack, func() {
+		os.RemoveAll(src)
+		os.RemoveAll(out)
+	}
+}
+
+func TestApplyVerifiesSignedPackage(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	pack, cleanupPack := setupSignatureTests(t)
+	defer cleanupPack()
+
+	signTestPackage(t, pack, true)
+
+	_, err := e.Apply(context.Background(), pack)
+	assert.NoError(t, err)
+
+	testAssertMethodCalled(t, mp, "Create", 1)
+}
+
+func TestApplyFailsWhenSignedPackageModified(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	pack, cleanupPack := setupSignatureTests(t)
+	defer cleanupPack()
+
+	signTestPackage(t, pack, true)
+
+	f, _ := os.OpenFile(pack, os.O_APPEND|os.O_WRONLY, 0644)
+	f.Write([]byte("modified"))
+	f.Close()
+
+	_, err := e.Apply(context.Background(), pack)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "package may have been modified")
+
+	assert.Len(t, *mp, 0)
+}
+
+func TestApplyFailsWhenPackageSignedByUntrustedKey(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	pack, cleanupPack := setupSignatureTests(t)
+	defer cleanupPack()
+
+	// trust a key then sign with a different key
+	signTestPackage(t, pack, true)
+	signTestPackage(t, pack, false)
+
+	_, err := e.Apply(context.Background(), pack)
+	assert.Error(t, err)
+}
+
+func TestApplyFailsWhenPackageUnsignedAndKeysTrusted(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	pack, cleanupPack := setupSignatureTests(t)
+	defer cleanupPack()
+
+	signTestPackage(t, pack, true)
+	os.Remove(pack + SignatureExtension)
+
+	_, err := e.Apply(context.Background(), pack)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is not signed")
+}
+
+func TestApplyRunsUnverifiedRemoteBlueprintWhenKeysTrusted(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	pack, cleanupPack := setupSignatureTests(t)
+	defer cleanupPack()
+
+	signTestPackage(t, pack, true)
+
+	// remote folders can not be signed, they are run with a warning
+	dir := utils.GetBlueprintLocalFolder("github.com/shipyard-run/blueprints//consul")
+	os.MkdirAll(dir, os.ModePerm)
+	ioutil.WriteFile(filepath.Join(dir, "main.hcl"), []byte(checkpointBlueprint), 0644)
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	assert.NotEmpty(t, *mp)
+}
+
+func TestSignPackageWithGeneratedKeyVerifies(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	pack, cleanupPack := setupSignatureTests(t)
+	defer cleanupPack()
+
+	key := filepath.Join(filepath.Dir(pack), "shipyard.key")
+	err := GenerateSigningKey(key)
+	assert.NoError(t, err)
+
+	fi, err := os.Stat(key)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	pub, _ := ioutil.ReadFile(key + ".pub")
+	os.MkdirAll(TrustedKeysDir(), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(TrustedKeysDir(), "author.pub"), pub, 0644)
+
+	err = SignPackage(pack, key)
+	assert.NoError(t, err)
+
+	_, err = e.Apply(context.Background(), pack)
+	assert.NoError(t, err)
+
+	testAssertMethodCalled(t, mp, "Create", 1)
+}
+
+func TestGenerateSigningKeyDoesNotOverwriteKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	key := filepath.Join(dir, "shipyard.key")
+	ioutil.WriteFile(key, []byte("existing"), 0600)
+
+	err = GenerateSigningKey(key)
+	assert.Error(t, err)
+}
+
+func TestSignPackageReturnsErrorForEncryptedKey(t *testing.T) {
+	pack, cleanupPack := setupSignatureTests(t)
+	defer cleanupPack()
+
+	key := filepath.Join(filepath.Dir(pack), "shipyard.key")
+	err := GenerateSigningKey(key)
+	assert.NoError(t, err)
+
+	// set the KDF algorithm to scrypt as used by minisign for encrypted keys
+	d, _ := ioutil.ReadFile(key)
+	sk, _ := decodeMinisignLine(d)
+	copy(sk[2:4], "Sc")
+	ioutil.WriteFile(key, []byte("untrusted comment: minisign encrypted secret key\n"+base64.StdEncoding.EncodeToString(sk)+"\n"), 0600)
+
+	err = SignPackage(pack, key)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "encrypted")
+}
+
+func setupFetchPackageTests(t *testing.T, signed bool) (*clientmocks.Getter, string, func()) {
+	pack, cleanupPack := setupSignatureTests(t)
+
+	signTestPackage(t, pack, true)
+
+	// the signature is downloaded to a different folder to the package
+	sigDir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	if signed {
+		os.Rename(pack+SignatureExtension, filepath.Join(sigDir, filepath.Base(pack)+SignatureExtension))
+	} else {
+		os.Remove(pack + SignatureExtension)
+	}
+
+	uri := "https://example.com/" + filepath.Base(pack)
+
+	mg := &clientmocks.Getter{}
+	mg.On("Fetch", uri+"?checksum=sha256:abc").Return(filepath.Dir(pack), nil)
+
+	if signed {
+		mg.On("Fetch", uri+SignatureExtension).Return(sigDir, nil)
+	} else {
+		mg.On("Fetch", uri+SignatureExtension).Return("", fmt.Errorf("not found"))
+	}
+
+	return mg, pack, func() {
+		cleanupPack()
+		os.RemoveAll(sigDir)
+	}
+}
+
+func TestFetchPackageDownloadsPackageAndSignature(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	mg, pack, cleanupFetch := setupFetchPackageTests(t, true)
+	defer cleanupFetch()
+
+	p, err := FetchPackage(mg, "https://example.com/"+filepath.Base(pack)+"?checksum=sha256:abc")
+	assert.NoError(t, err)
+	assert.Equal(t, pack, p)
+	assert.FileExists(t, pack+SignatureExtension)
+
+	_, err = e.Apply(context.Background(), p)
+	assert.NoError(t, err)
+}
+
+func TestFetchPackageWithoutSignatureFailsVerificationWhenKeysTrusted(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	mg, pack, cleanupFetch := setupFetchPackageTests(t, false)
+	defer cleanupFetch()
+
+	p, err := FetchPackage(mg, "https://example.com/"+filepath.Base(pack)+"?checksum=sha256:abc")
+	assert.NoError(t, err)
+
+	_, err = e.Apply(context.Background(), p)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is not signed")
+}
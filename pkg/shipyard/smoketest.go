@@ -0,0 +1,238 @@
+package shipyard
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// defaultAssertionTimeout is used when an assertion does not define a timeout
+const defaultAssertionTimeout = 30 * time.Second
+
+// TestReport is the outcome of running the assertions for a blueprint
+type TestReport struct {
+	Name     string
+	Started  time.Time
+	Duration time.Duration
+	Cases    []TestCase
+}
+
+// TestCase is the outcome of a single step of a test run, the apply and
+// destroy steps are reported alongside the assertions
+type TestCase struct {
+	Name     string
+	Duration time.Duration
+	Error    error
+}
+
+// Failed returns true when any of the test cases failed
+func (t *TestReport) Failed() bool {
+	return t.Failures() > 0
+}
+
+// Failures returns the number of failed test cases
+func (t *TestReport) Failures() int {
+	f := 0
+	for _, c := range t.Cases {
+		if c.Error != nil {
+			f++
+		}
+	}
+
+	return f
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Content string `xml:",chardata"`
+}
+
+// ToJUnit returns the report as a JUnit XML document which can be
+// consumed by CI systems
+func (t *TestReport) ToJUnit() ([]byte, error) {
+	s := junitTestSuite{
+		Name:      t.Name,
+		Tests:     len(t.Cases),
+		Failures:  t.Failures(),
+		Time:      junitSeconds(t.Duration),
+		Timestamp: t.Started.UTC().Format("2006-01-02T15:04:05"),
+		Cases:     []junitTestCase{},
+	}
+
+	for _, c := range t.Cases {
+		jc := junitTestCase{Name: c.Name, Classname: t.Name, Time: junitSeconds(c.Duration)}
+		if c.Error != nil {
+			jc.Failure = &junitFailure{Message: c.Error.Error(), Content: c.Error.Error()}
+		}
+
+		s.Cases = append(s.Cases, jc)
+	}
+
+	d, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{s}}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), d...), nil
+}
+
+func junitSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+// Test applies the blueprint at path, runs the assertions defined in the
+// blueprint, and then destroys all the resources. The blueprint must be
+// applied to an empty environment so that existing resources are not
+// removed when the test completes. An error is only returned when the test
+// can not be run, failing steps are recorded in the report
+func (e *EngineImpl) Test(ctx context.Context, path string) (*TestReport, error) {
+	sc := config.New()
+	err := sc.FromJSON(e.stateFile())
+	if err == nil && len(sc.Resources) > 0 {
+		return nil, xerrors.Errorf("Unable to test blueprint, %d resources are already running, run yard destroy before testing", len(sc.Resources))
+	}
+
+	report := &TestReport{Name: path, Started: time.Now(), Cases: []TestCase{}}
+
+	st := time.Now()
+	_, err = e.Apply(ctx, path)
+	report.Cases = append(report.Cases, TestCase{Name: "apply", Duration: time.Since(st), Error: err})
+
+	// only run the assertions when the resources were created
+	if err == nil && e.config.Blueprint != nil {
+		if e.config.Blueprint.Title != "" {
+			report.Name = e.config.Blueprint.Title
+		}
+
+		for _, a := range e.config.Blueprint.Assertions {
+			st := time.Now()
+			err := e.runAssertion(ctx, a)
+			report.Cases = append(report.Cases, TestCase{Name: a.Name, Duration: time.Since(st), Error: err})
+		}
+	}
+
+	// always clean up, even when apply fails or is cancelled there may be
+	// resources
+	st = time.Now()
+	err = e.Destroy(context.Background(), path, true)
+	report.Cases = append(report.Cases, TestCase{Name: "destroy", Duration: time.Since(st), Error: err})
+
+	report.Duration = time.Since(report.Started)
+
+	return report, nil
+}
+
+// runAssertion runs the checks defined by the assertion, all checks
+// must pass for the assertion to pass
+func (e *EngineImpl) runAssertion(ctx context.Context, a config.Assertion) error {
+	timeout := defaultAssertionTimeout
+	if a.Timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(a.Timeout)
+		if err != nil {
+			return xerrors.Errorf("Invalid timeout %s: %w", a.Timeout, err)
+		}
+	}
+
+	if a.HTTP != "" {
+		opts := config.HTTPHealthCheck{}
+		if a.HTTPOptions != nil {
+			opts = *a.HTTPOptions
+		}
+
+		err := e.clients.HTTP.HealthCheckHTTPWithOptions(ctx, a.HTTP, opts, timeout)
+		if err != nil {
+			return xerrors.Errorf("HTTP check for %s failed: %w", a.HTTP, err)
+		}
+	}
+
+	if a.Exec != nil {
+		err := e.assertExec(ctx, a.Exec)
+		if err != nil {
+			return err
+		}
+	}
+
+	if a.Pods != nil {
+		r, err := e.config.FindResource(a.Pods.Cluster)
+		if err != nil {
+			return xerrors.Errorf("Unable to find cluster %s: %w", a.Pods.Cluster, err)
+		}
+
+		_, kubeconfig, _ := utils.CreateKubeConfigPath(r.Info().Name)
+
+		err = e.clients.Kubernetes.SetConfig(kubeconfig)
+		if err != nil {
+			return xerrors.Errorf("Unable to create Kubernetes client for %s: %w", a.Pods.Cluster, err)
+		}
+
+		err = e.clients.Kubernetes.HealthCheckPods(ctx, a.Pods.Selectors, timeout)
+		if err != nil {
+			return xerrors.Errorf("Pods %v are not running: %w", a.Pods.Selectors, err)
+		}
+	}
+
+	return nil
+}
+
+// assertExec runs the command in the target container and compares the
+// exit code with the expected value
+func (e *EngineImpl) assertExec(ctx context.Context, ex *config.ExecAssertion) error {
+	r, err := e.config.FindResource(ex.Target)
+	if err != nil {
+		return xerrors.Errorf("Unable to find exec target %s: %w", ex.Target, err)
+	}
+
+	ids, err := e.clients.ContainerTasks.FindContainerIDs(ctx, r.Info().Name, r.Info().Type)
+	if err != nil || len(ids) == 0 {
+		return xerrors.Errorf("Unable to find container for exec target %s: %v", ex.Target, err)
+	}
+
+	out := &bytes.Buffer{}
+	err = e.clients.ContainerTasks.ExecuteCommand(ctx, ids[0], ex.Command, nil, "/", out)
+
+	code := 0
+	if err != nil {
+		var exErr *clients.ExecError
+		if !xerrors.As(err, &exErr) {
+			return xerrors.Errorf("Unable to run command %v in %s: %w", ex.Command, ex.Target, err)
+		}
+
+		code = exErr.ExitCode
+	}
+
+	if code != ex.ExitCode {
+		return fmt.Errorf("Command %v in %s returned exit code %d, expected %d, output: %s", ex.Command, ex.Target, code, ex.ExitCode, out.String())
+	}
+
+	return nil
+}
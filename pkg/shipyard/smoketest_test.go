@@ -0,0 +1,148 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupSmokeTest(t *testing.T, e Engine, httpErr error) (string, *clientmocks.MockContainerTasks) {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	ioutil.WriteFile(filepath.Join(dir, "container.hcl"), []byte(smokeTestContainer), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "README.yard"), []byte(smokeTestBlueprint), 0644)
+
+	hm := &clientmocks.MockHTTP{}
+	hm.On("HealthCheckHTTPWithOptions", "http://localhost:18500/v1/leader", mock.Anything, 10*time.Second).Return(httpErr)
+
+	ct := e.(*EngineImpl).clients.ContainerTasks.(*clientmocks.MockContainerTasks)
+	removeOn(&ct.Mock, "FindContainerIDs")
+	ct.On("FindContainerIDs", "consul", config.TypeContainer).Return([]string{"abc"}, nil)
+	ct.On("ExecuteCommand", "abc", []string{"consul", "members"}, mock.Anything, mock.Anything, mock.Anything).
+		Return(&clients.ExecError{ExitCode: 1})
+
+	e.(*EngineImpl).clients.HTTP = hm
+
+	return dir, ct
+}
+
+func TestTestRunsAssertionsAndDestroys(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, _ := setupSmokeTest(t, e, nil)
+	defer os.RemoveAll(dir)
+
+	r, err := e.Test(context.Background(), dir)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Consul", r.Name)
+	assert.Len(t, r.Cases, 4)
+	assert.Equal(t, "apply", r.Cases[0].Name)
+	assert.Equal(t, "consul_leader", r.Cases[1].Name)
+	assert.Equal(t, "consul_members", r.Cases[2].Name)
+	assert.Equal(t, "destroy", r.Cases[3].Name)
+	assert.False(t, r.Failed())
+
+	testAssertMethodCalled(t, mp, "Destroy", 1)
+	assert.NoFileExists(t, utils.StatePath())
+}
+
+func TestTestReportsFailedAssertions(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, _ := setupSmokeTest(t, e, fmt.Errorf("boom"))
+	defer os.RemoveAll(dir)
+
+	r, err := e.Test(context.Background(), dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, r.Failures())
+	assert.Error(t, r.Cases[1].Error)
+
+	d, err := r.ToJUnit()
+	assert.NoError(t, err)
+	assert.Contains(t, string(d), `<testsuite name="Consul" tests="4" failures="1"`)
+	assert.Contains(t, string(d), `<testcase name="consul_leader" classname="Consul"`)
+	assert.Contains(t, string(d), `<failure message="HTTP check for http://localhost:18500/v1/leader failed: boom">`)
+}
+
+func TestTestReportsExecAssertionWhenCommandCanNotRun(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir, ct := setupSmokeTest(t, e, nil)
+	defer os.RemoveAll(dir)
+
+	// errors which are not exit codes fail the assertion even when the
+	// message contains an exit code
+	removeOn(&ct.Mock, "ExecuteCommand")
+	ct.On("ExecuteCommand", "abc", []string{"consul", "members"}, mock.Anything, mock.Anything, mock.Anything).
+		Return(fmt.Errorf("unable to reach daemon, last exit code 1"))
+
+	r, err := e.Test(context.Background(), dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, r.Failures())
+	assert.Contains(t, r.Cases[2].Error.Error(), "Unable to run command")
+}
+
+func TestTestSkipsAssertionsWhenApplyFails(t *testing.T) {
+	e, _, _, cleanup := setupTests(map[string]error{"consul": fmt.Errorf("boom")})
+	defer cleanup()
+
+	dir, ct := setupSmokeTest(t, e, nil)
+	defer os.RemoveAll(dir)
+
+	r, err := e.Test(context.Background(), dir)
+	assert.NoError(t, err)
+
+	assert.Len(t, r.Cases, 2)
+	assert.Error(t, r.Cases[0].Error)
+	ct.AssertNotCalled(t, "ExecuteCommand", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTestReturnsErrorWhenResourcesRunning(t *testing.T) {
+	e, _, mp, cleanup := setupTestsWithState(nil, composeState)
+	defer cleanup()
+
+	_, err := e.Test(context.Background(), "")
+	assert.Error(t, err)
+	assert.Len(t, *mp, 0)
+}
+
+var smokeTestContainer = `
+container "consul" {
+  image {
+    name = "consul:1.8.1"
+  }
+}
+`
+
+var smokeTestBlueprint = `
+title = "Consul"
+
+assert "consul_leader" {
+  timeout = "10s"
+  http    = "http://localhost:18500/v1/leader"
+}
+
+assert "consul_members" {
+  exec {
+    target    = "container.consul"
+    command   = ["consul", "members"]
+    exit_code = 1
+  }
+}
+`
@@ -0,0 +1,521 @@
+package shipyard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// defaultConsulAddress is used by the consul backend when the address is not
+// set in the backend or the environment
+const defaultConsulAddress = "http://localhost:8500"
+
+// StateTokenEnvVar sets the token for the http state backend when the token
+// is not set in the backend, the token is not written to the state so it must
+// be set in the environment for commands which read the backend from the state
+const StateTokenEnvVar = "SHIPYARD_STATE_TOKEN"
+
+// stateRequestTimeout is the maximum time for a request to the http state
+// backend
+const stateRequestTimeout = 30 * time.Second
+
+// BackendLockedError is returned when the state stored by a backend is locked
+// by another Shipyard process
+type BackendLockedError struct {
+	Holder string // host and PID of the process which holds the lock
+	Lock   string // location of the lock in the backend
+}
+
+func (b *BackendLockedError) Error() string {
+	return fmt.Sprintf(
+		"State locked in the backend by %s, wait for the other Shipyard command to complete. "+
+			"If the process is no longer running remove the lock %s",
+		b.Holder, b.Lock,
+	)
+}
+
+// StateBackend stores the state outside the local state folder so that it
+// can be shared by CI runners and teammates
+type StateBackend interface {
+	// Get returns the stored state, nil is returned when no state is stored
+	Get() ([]byte, error)
+	// Put stores the state
+	Put(state []byte) error
+	// Delete removes the stored state
+	Delete() error
+	// Lock stops other Shipyard processes changing the stored state, a
+	// *BackendLockedError is returned when the state is already locked
+	Lock() error
+	// Unlock releases the lock created by Lock
+	Unlock() error
+}
+
+// SetStateBackend stores the state in the backend for every operation,
+// the backend overrides any backend defined by the blueprint
+func (e *EngineImpl) SetStateBackend(c *config.StateBackend) error {
+	if errs := c.Validate(); len(errs) > 0 {
+		return xerrors.Errorf("Invalid state backend: %w", errs[0])
+	}
+
+	b, err := e.newStateBackend(c)
+	if err != nil {
+		return err
+	}
+
+	e.backend = b
+
+	return nil
+}
+
+// stateBackend returns the backend which stores the state, the backend set
+// with SetStateBackend is used before the backend defined by the blueprint at
+// path, then the blueprint in the local state. nil is returned when the state
+// is only stored locally
+func (e *EngineImpl) stateBackend(path string) (StateBackend, error) {
+	if e.backend != nil {
+		return e.backend, nil
+	}
+
+	var bc *config.StateBackend
+
+	// errors in the blueprint are returned by the operation
+	if path != "" {
+		if c, err := e.parseConfig(path); err == nil && c.Blueprint != nil {
+			bc = c.Blueprint.StateBackend
+		}
+	}
+
+	if bc == nil {
+		sc := config.New()
+		if err := sc.FromJSON(e.stateFile()); err == nil && sc.Blueprint != nil {
+			bc = sc.Blueprint.StateBackend
+		}
+	}
+
+	if bc == nil {
+		return nil, nil
+	}
+
+	return e.newStateBackend(bc)
+}
+
+// newStateBackend creates the backend for the config
+func (e *EngineImpl) newStateBackend(c *config.StateBackend) (StateBackend, error) {
+	switch c.Type {
+	case "s3":
+		cfg := aws.NewConfig()
+		if c.Region != "" {
+			cfg = cfg.WithRegion(c.Region)
+		}
+
+		// custom endpoints e.g. Minio do not support virtual hosted buckets
+		if c.Address != "" {
+			cfg = cfg.WithEndpoint(c.Address).WithS3ForcePathStyle(true)
+		}
+
+		s, err := session.NewSession(cfg)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to create S3 session: %w", err)
+		}
+
+		return &s3StateBackend{client: s3.New(s), bucket: c.Bucket, key: c.Key}, nil
+
+	case "consul":
+		if e.clients == nil || e.clients.Consul == nil {
+			return nil, xerrors.Errorf("Unable to create consul state backend, no Consul client")
+		}
+
+		address := c.Address
+		if address == "" {
+			address = os.Getenv("CONSUL_HTTP_ADDR")
+		}
+
+		if address == "" {
+			address = defaultConsulAddress
+		}
+
+		token := c.Token
+		if token == "" {
+			token = os.Getenv("CONSUL_HTTP_TOKEN")
+		}
+
+		return &consulStateBackend{client: e.clients.Consul, address: address, token: token, key: c.Key}, nil
+
+	case "http":
+		if e.clients == nil || e.clients.HTTP == nil {
+			return nil, xerrors.Errorf("Unable to create http state backend, no HTTP client")
+		}
+
+		token := c.Token
+		if token == "" {
+			token = os.Getenv(StateTokenEnvVar)
+		}
+
+		return &httpStateBackend{client: e.clients.HTTP, address: c.Address, token: token}, nil
+	}
+
+	return nil, xerrors.Errorf("Unknown state backend %s, must be one of s3, consul, or http", c.Type)
+}
+
+// readState returns the state for commands which do not change it, when the
+// state is stored by a backend the stored state is read so that commands
+// show the environment created by other machines. path is the blueprint for
+// the command, it is used to find the backend
+func (e *EngineImpl) readState(path string) (*config.Config, error) {
+	err := e.validateWorkspace()
+	if err != nil {
+		return nil, err
+	}
+
+	sc := config.New()
+
+	b, err := e.stateBackend(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if b != nil {
+		d, err := b.Get()
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to download state from the backend: %w", err)
+		}
+
+		if d != nil {
+			err = json.Unmarshal(d, sc)
+			if err != nil {
+				return nil, xerrors.Errorf("Unable to read state downloaded from the backend: %w", err)
+			}
+
+			return sc, nil
+		}
+	}
+
+	err = sc.FromJSON(e.stateFile())
+	if err != nil {
+		return nil, err
+	}
+
+	return sc, nil
+}
+
+// lockInfo is written to the lock in the backend so that the process which
+// holds the lock can be found
+func lockInfo() []byte {
+	host, _ := os.Hostname()
+	return []byte(fmt.Sprintf("%s:%d", host, os.Getpid()))
+}
+
+// pullState replaces the local state with the state stored by the backend,
+// the local state is kept when the backend does not store a state so that
+// it is uploaded when the operation completes
+func (e *EngineImpl) pullState(b StateBackend) error {
+	d, err := b.Get()
+	if err != nil {
+		return xerrors.Errorf("Unable to download state from the backend: %w", err)
+	}
+
+	e.remoteState = d
+	if d == nil {
+		return nil
+	}
+
+	err = config.AtomicWriteFile(e.stateFile(), d)
+	if err != nil {
+		return xerrors.Errorf("Unable to write state downloaded from the backend: %w", err)
+	}
+
+	return nil
+}
+
+// pushState uploads the local state to the backend when it has changed, the
+// stored state is removed when the local state has been removed
+func (e *EngineImpl) pushState(b StateBackend) error {
+	d, err := ioutil.ReadFile(e.stateFile())
+	if os.IsNotExist(err) {
+		if e.remoteState == nil {
+			return nil
+		}
+
+		err = b.Delete()
+		if err != nil {
+			return xerrors.Errorf("Unable to remove state from the backend: %w", err)
+		}
+
+		e.remoteState = nil
+		return nil
+	}
+
+	if err != nil {
+		return xerrors.Errorf("Unable to read state: %w", err)
+	}
+
+	if bytes.Equal(d, e.remoteState) {
+		return nil
+	}
+
+	err = b.Put(d)
+	if err != nil {
+		return xerrors.Errorf("Unable to upload state to the backend: %w", err)
+	}
+
+	e.remoteState = d
+
+	return nil
+}
+
+// s3StateBackend stores the state as an object in an S3 bucket
+type s3StateBackend struct {
+	client s3iface.S3API
+	bucket string
+	key    string
+}
+
+func (s *s3StateBackend) Get() ([]byte, error) {
+	out, err := s.client.GetObject(&s3.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(s.key)})
+	if err != nil {
+		if ae, ok := err.(awserr.Error); ok && ae.Code() == s3.ErrCodeNoSuchKey {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+	defer out.Body.Close()
+
+	return ioutil.ReadAll(out.Body)
+}
+
+func (s *s3StateBackend) Put(state []byte) error {
+	_, err := s.client.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(s.key),
+		Body:        bytes.NewReader(state),
+		ContentType: aws.String("application/json"),
+	})
+
+	return err
+}
+
+func (s *s3StateBackend) Delete() error {
+	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(s.key)})
+
+	return err
+}
+
+// Lock creates the lock object with a conditional put which fails when the
+// object already exists
+func (s *s3StateBackend) Lock() error {
+	req, _ := s.client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.lockKey()),
+		Body:   bytes.NewReader(lockInfo()),
+	})
+	req.HTTPRequest.Header.Set("If-None-Match", "*")
+
+	err := req.Send()
+	if rf, ok := err.(awserr.RequestFailure); ok && (rf.StatusCode() == http.StatusPreconditionFailed || rf.StatusCode() == http.StatusConflict) {
+		holder := "another process"
+		out, gerr := s.client.GetObject(&s3.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(s.lockKey())})
+		if gerr == nil {
+			d, _ := ioutil.ReadAll(out.Body)
+			out.Body.Close()
+			holder = string(d)
+		}
+
+		return &BackendLockedError{Holder: holder, Lock: fmt.Sprintf("s3://%s/%s", s.bucket, s.lockKey())}
+	}
+
+	if err != nil {
+		return xerrors.Errorf("Unable to lock state in the backend: %w", err)
+	}
+
+	return nil
+}
+
+func (s *s3StateBackend) Unlock() error {
+	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(s.lockKey())})
+
+	return err
+}
+
+func (s *s3StateBackend) lockKey() string {
+	return s.key + ".lock"
+}
+
+// consulStateBackend stores the state as a key in the Consul KV store
+type consulStateBackend struct {
+	client  clients.Consul
+	address string
+	token   string
+	key     string
+}
+
+func (c *consulStateBackend) Get() ([]byte, error) {
+	return c.client.GetKV(c.address, c.token, c.key)
+}
+
+func (c *consulStateBackend) Put(state []byte) error {
+	return c.client.PutKV(c.address, c.token, c.key, state)
+}
+
+func (c *consulStateBackend) Delete() error {
+	return c.client.DeleteKV(c.address, c.token, c.key)
+}
+
+// Lock creates the lock key with a check-and-set which fails when the key
+// already exists
+func (c *consulStateBackend) Lock() error {
+	ok, err := c.client.CreateKV(c.address, c.token, c.lockKey(), lockInfo())
+	if err != nil {
+		return xerrors.Errorf("Unable to lock state in the backend: %w", err)
+	}
+
+	if !ok {
+		holder := "another process"
+		if d, err := c.client.GetKV(c.address, c.token, c.lockKey()); err == nil && d != nil {
+			holder = string(d)
+		}
+
+		return &BackendLockedError{Holder: holder, Lock: c.lockKey()}
+	}
+
+	return nil
+}
+
+func (c *consulStateBackend) Unlock() error {
+	return c.client.DeleteKV(c.address, c.token, c.lockKey())
+}
+
+func (c *consulStateBackend) lockKey() string {
+	return c.key + ".lock"
+}
+
+// httpStateBackend stores the state at a URL, the state is read with GET,
+// written with POST, and removed with DELETE. The state is locked with the
+// LOCK and UNLOCK methods used by the Terraform http backend, servers which
+// do not support locking can not be used
+type httpStateBackend struct {
+	client  clients.HTTP
+	address string
+	token   string
+}
+
+func (h *httpStateBackend) Get() ([]byte, error) {
+	status, d, err := h.do(http.MethodGet, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if status == http.StatusNotFound || status == http.StatusNoContent {
+		return nil, nil
+	}
+
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("Error getting state from %s, got status code %d", h.address, status)
+	}
+
+	return d, nil
+}
+
+func (h *httpStateBackend) Put(state []byte) error {
+	status, _, err := h.do(http.MethodPost, state)
+	if err != nil {
+		return err
+	}
+
+	if status < 200 || status > 299 {
+		return fmt.Errorf("Error storing state at %s, got status code %d", h.address, status)
+	}
+
+	return nil
+}
+
+func (h *httpStateBackend) Delete() error {
+	status, _, err := h.do(http.MethodDelete, nil)
+	if err != nil {
+		return err
+	}
+
+	if status != http.StatusNotFound && (status < 200 || status > 299) {
+		return fmt.Errorf("Error deleting state at %s, got status code %d", h.address, status)
+	}
+
+	return nil
+}
+
+func (h *httpStateBackend) Lock() error {
+	status, d, err := h.do("LOCK", lockInfo())
+	if err != nil {
+		return xerrors.Errorf("Unable to lock state in the backend: %w", err)
+	}
+
+	switch {
+	case status == http.StatusLocked || status == http.StatusConflict:
+		return &BackendLockedError{Holder: string(d), Lock: h.address}
+	case status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented:
+		return fmt.Errorf("State backend %s does not support locking, the state can not be shared safely", h.address)
+	case status < 200 || status > 299:
+		return fmt.Errorf("Error locking state at %s, got status code %d", h.address, status)
+	}
+
+	return nil
+}
+
+func (h *httpStateBackend) Unlock() error {
+	status, _, err := h.do("UNLOCK", lockInfo())
+	if err != nil {
+		return err
+	}
+
+	if status < 200 || status > 299 {
+		return fmt.Errorf("Error unlocking state at %s, got status code %d", h.address, status)
+	}
+
+	return nil
+}
+
+// do makes the request and returns the status code and body of the response,
+// the request is cancelled when it does not complete within the timeout
+func (h *httpStateBackend) do(method string, body []byte) (int, []byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), stateRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, h.address, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, xerrors.Errorf("Unable to create http request: %w", err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if h.token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	d, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, xerrors.Errorf("Unable to read response from %s: %w", h.address, err)
+	}
+
+	return resp.StatusCode, d, nil
+}
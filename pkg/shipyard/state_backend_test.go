@@ -0,0 +1,379 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shipyard-run/shipyard/pkg/clients"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryStateBackend is a StateBackend which stores the state in memory
+type memoryStateBackend struct {
+	state   []byte
+	puts    int
+	deletes int
+	putErr  error
+	locked  bool
+	locks   int
+}
+
+func (m *memoryStateBackend) Lock() error {
+	if m.locked {
+		return &BackendLockedError{Holder: "other:1", Lock: "memory"}
+	}
+
+	m.locked = true
+	m.locks++
+	return nil
+}
+
+func (m *memoryStateBackend) Unlock() error {
+	m.locked = false
+	return nil
+}
+
+func (m *memoryStateBackend) Get() ([]byte, error) {
+	return m.state, nil
+}
+
+func (m *memoryStateBackend) Put(state []byte) error {
+	m.puts++
+	if m.putErr != nil {
+		return m.putErr
+	}
+
+	m.state = state
+	return nil
+}
+
+func (m *memoryStateBackend) Delete() error {
+	m.deletes++
+	m.state = nil
+	return nil
+}
+
+func TestApplyDownloadsAndUploadsStateWithBackend(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	b := &memoryStateBackend{state: []byte(failedState)}
+	e.(*EngineImpl).backend = b
+
+	_, err := e.Apply(context.Background(), "")
+	assert.NoError(t, err)
+
+	// the failed resource from the stored state is recreated
+	testAssertMethodCalled(t, mp, "Destroy", 1)
+	testAssertMethodCalled(t, mp, "Create", 1)
+
+	assert.Equal(t, 1, b.puts)
+	assert.Contains(t, string(b.state), `"status":"applied"`)
+
+	_, err = os.Stat(e.(*EngineImpl).lockFile())
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestApplyDoesNotUploadUnchangedState(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	b := &memoryStateBackend{}
+	e.(*EngineImpl).backend = b
+
+	_, err := e.Apply(context.Background(), "")
+	assert.NoError(t, err)
+
+	// nothing was created so there is no state to store
+	assert.Equal(t, 0, b.puts)
+	assert.Equal(t, 0, b.deletes)
+}
+
+func TestApplyReturnsErrorWhenStateUploadFails(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	e.(*EngineImpl).backend = &memoryStateBackend{state: []byte(failedState), putErr: fmt.Errorf("boom")}
+
+	_, err := e.Apply(context.Background(), "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Unable to upload state to the backend")
+}
+
+func TestApplyLocksStateInBackend(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	b := &memoryStateBackend{}
+	e.(*EngineImpl).backend = b
+
+	_, err := e.Apply(context.Background(), "")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, b.locks)
+	assert.False(t, b.locked)
+}
+
+func TestApplyReturnsErrorWhenStateLockedInBackend(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	b := &memoryStateBackend{state: []byte(failedState), locked: true}
+	e.(*EngineImpl).backend = b
+
+	_, err := e.Apply(context.Background(), "")
+	assert.IsType(t, &BackendLockedError{}, err)
+	assert.Contains(t, err.Error(), "other:1")
+
+	testAssertMethodCalled(t, mp, "Create", 0)
+	assert.True(t, b.locked)
+}
+
+func TestStateReadsStateFromBackend(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	b := &memoryStateBackend{state: []byte(failedState)}
+	e.(*EngineImpl).backend = b
+
+	sc, err := e.State()
+	assert.NoError(t, err)
+	assert.Len(t, sc.Resources, 1)
+
+	// reading the state does not lock it
+	assert.Equal(t, 0, b.locks)
+}
+
+func TestResourceNamesReadsStateFromBackend(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	b := &memoryStateBackend{state: []byte(failedState)}
+	e.(*EngineImpl).backend = b
+
+	n, err := e.ResourceNames(config.TypeNetwork)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"network.dc1"}, n)
+}
+
+func TestDestroyRemovesStateFromBackend(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	b := &memoryStateBackend{state: []byte(failedState)}
+	e.(*EngineImpl).backend = b
+
+	err := e.Destroy(context.Background(), "", true)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, b.deletes)
+	assert.Nil(t, b.state)
+}
+
+func TestApplyUsesStateBackendFromBlueprint(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	stored := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			rw.WriteHeader(http.StatusNotFound)
+		case http.MethodPost:
+			d, _ := ioutil.ReadAll(r.Body)
+			stored = string(d)
+		}
+	}))
+	defer ts.Close()
+
+	e.GetClients().HTTP = clients.NewHTTP(1*time.Millisecond, hclog.NewNullLogger())
+
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	bp := fmt.Sprintf(`
+state_backend {
+  type    = "http"
+  address = "%s"
+}
+`, ts.URL)
+
+	ioutil.WriteFile(filepath.Join(dir, "blueprint.yard"), []byte(bp), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "network.hcl"), []byte(`network "cloud" { subnet = "10.15.0.0/16" }`), 0644)
+
+	_, err = e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	assert.Contains(t, stored, `"name":"cloud"`)
+	assert.Contains(t, stored, `"state_backend"`)
+}
+
+func TestHTTPStateBackendStoresState(t *testing.T) {
+	stored := []byte(nil)
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer abc", r.Header.Get("Authorization"))
+
+		switch r.Method {
+		case http.MethodGet:
+			if stored == nil {
+				rw.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			rw.Write(stored)
+		case http.MethodPost:
+			stored, _ = ioutil.ReadAll(r.Body)
+		case http.MethodDelete:
+			stored = nil
+		}
+	}))
+	defer ts.Close()
+
+	b := &httpStateBackend{client: clients.NewHTTP(1*time.Millisecond, hclog.NewNullLogger()), address: ts.URL, token: "abc"}
+
+	d, err := b.Get()
+	assert.NoError(t, err)
+	assert.Nil(t, d)
+
+	err = b.Put([]byte("state"))
+	assert.NoError(t, err)
+
+	d, err = b.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "state", string(d))
+
+	err = b.Delete()
+	assert.NoError(t, err)
+	assert.Nil(t, stored)
+}
+
+func TestHTTPStateBackendLocksState(t *testing.T) {
+	locked := false
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "LOCK":
+			if locked {
+				rw.WriteHeader(http.StatusLocked)
+				fmt.Fprint(rw, "other:1")
+				return
+			}
+
+			locked = true
+		case "UNLOCK":
+			locked = false
+		}
+	}))
+	defer ts.Close()
+
+	b := &httpStateBackend{client: clients.NewHTTP(1*time.Millisecond, hclog.NewNullLogger()), address: ts.URL}
+
+	err := b.Lock()
+	assert.NoError(t, err)
+
+	err = b.Lock()
+	assert.IsType(t, &BackendLockedError{}, err)
+	assert.Contains(t, err.Error(), "other:1")
+
+	err = b.Unlock()
+	assert.NoError(t, err)
+	assert.False(t, locked)
+}
+
+func TestHTTPStateBackendReturnsErrorWhenLockingNotSupported(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	defer ts.Close()
+
+	b := &httpStateBackend{client: clients.NewHTTP(1*time.Millisecond, hclog.NewNullLogger()), address: ts.URL}
+
+	err := b.Lock()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not support locking")
+}
+
+func TestHTTPStateBackendReadsTokenFromEnvironment(t *testing.T) {
+	os.Setenv(StateTokenEnvVar, "abc")
+	defer os.Unsetenv(StateTokenEnvVar)
+
+	e := &EngineImpl{log: hclog.NewNullLogger(), clients: &Clients{HTTP: clients.NewHTTP(1*time.Millisecond, hclog.NewNullLogger())}}
+	b, err := e.newStateBackend(&config.StateBackend{Type: "http", Address: "http://localhost"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "abc", b.(*httpStateBackend).token)
+}
+
+func TestS3StateBackendStoresState(t *testing.T) {
+	objects := map[string][]byte{}
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			d, ok := objects[r.URL.Path]
+			if !ok {
+				rw.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(rw, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code></Error>`)
+				return
+			}
+
+			rw.Write(d)
+		case http.MethodPut:
+			if _, ok := objects[r.URL.Path]; ok && r.Header.Get("If-None-Match") == "*" {
+				rw.WriteHeader(http.StatusPreconditionFailed)
+				fmt.Fprint(rw, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>PreconditionFailed</Code></Error>`)
+				return
+			}
+
+			objects[r.URL.Path], _ = ioutil.ReadAll(r.Body)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			rw.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	os.Setenv("AWS_ACCESS_KEY_ID", "test")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+	defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+
+	e := &EngineImpl{log: hclog.NewNullLogger()}
+	b, err := e.newStateBackend(&config.StateBackend{Type: "s3", Address: ts.URL, Bucket: "shipyard", Key: "dev/state.json", Region: "eu-west-1"})
+	assert.NoError(t, err)
+
+	d, err := b.Get()
+	assert.NoError(t, err)
+	assert.Nil(t, d)
+
+	err = b.Put([]byte("state"))
+	assert.NoError(t, err)
+	assert.Equal(t, "state", string(objects["/shipyard/dev/state.json"]))
+
+	d, err = b.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, "state", string(d))
+
+	err = b.Delete()
+	assert.NoError(t, err)
+	assert.Len(t, objects, 0)
+
+	err = b.Lock()
+	assert.NoError(t, err)
+	assert.Contains(t, objects, "/shipyard/dev/state.json.lock")
+
+	err = b.Lock()
+	assert.IsType(t, &BackendLockedError{}, err)
+
+	err = b.Unlock()
+	assert.NoError(t, err)
+	assert.Len(t, objects, 0)
+}
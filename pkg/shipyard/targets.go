@@ -0,0 +1,85 @@
+package shipyard
+
+import (
+	"context"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// ApplyTargets creates the named resources from the blueprint at path along
+// with the resources they depend on, other resources in the blueprint are
+// not created or changed. Names are in the form [type].[name] e.g.
+// container.consul
+func (e *EngineImpl) ApplyTargets(ctx context.Context, path string, names []string) ([]config.Resource, error) {
+	if len(names) == 0 {
+		return nil, xerrors.Errorf("At least one target resource must be specified")
+	}
+
+	e.targets = map[string]bool{}
+	for _, n := range names {
+		e.targets[n] = true
+	}
+
+	defer func() { e.targets = nil }()
+
+	return e.Apply(ctx, path)
+}
+
+// resolveTargets adds the transitive dependencies of the target resources to
+// the targets, it returns an error when a target does not exist in the config
+func (e *EngineImpl) resolveTargets() error {
+	if e.targets == nil {
+		return nil
+	}
+
+	resolved := map[string]bool{}
+
+	var add func(r config.Resource)
+	add = func(r config.Resource) {
+		if resolved[resourceName(r)] {
+			return
+		}
+
+		resolved[resourceName(r)] = true
+
+		for _, d := range r.Info().DependsOn {
+			if dr, err := e.config.FindResource(d); err == nil {
+				add(dr)
+			}
+		}
+	}
+
+	for n := range e.targets {
+		r, err := e.config.FindResource(n)
+		if err != nil {
+			return xerrors.Errorf("Unable to find target %s: %w", n, err)
+		}
+
+		add(r)
+	}
+
+	e.targets = resolved
+
+	return nil
+}
+
+// targeted returns true when the resource should be created by the current
+// apply, all resources are targeted unless ApplyTargets was called
+func (e *EngineImpl) targeted(r config.Resource) bool {
+	return e.targets == nil || e.targets[resourceName(r)]
+}
+
+// targetedResources returns the resources in the config which are targeted
+// by the current apply
+func (e *EngineImpl) targetedResources() []config.Resource {
+	res := []config.Resource{}
+
+	for _, r := range e.config.Resources {
+		if e.targeted(r) {
+			res = append(res, r)
+		}
+	}
+
+	return res
+}
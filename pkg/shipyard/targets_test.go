@@ -0,0 +1,66 @@
+package shipyard
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTargetTests(t *testing.T) (Engine, string, *[]*mocks.MockProvider, func()) {
+	e, _, mp, cleanup := setupTests(nil)
+
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	ioutil.WriteFile(filepath.Join(dir, "graph.hcl"), []byte(graphConfig), 0644)
+
+	return e, dir, mp, func() {
+		cleanup()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestApplyTargetsCreatesTargetAndDependencies(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	_, err := e.ApplyTargets(context.Background(), dir, []string{"container.consul"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, providerCalls(mp, "cloud", "Create"))
+	assert.Equal(t, 1, providerCalls(mp, "consul", "Create"))
+	assert.Equal(t, 0, providerCalls(mp, "api", "Create"))
+
+	// resources which were not targeted are created by the next apply
+	r, err := e.(*EngineImpl).config.FindResource("container.api")
+	assert.NoError(t, err)
+	assert.Equal(t, config.PendingCreation, r.Info().Status)
+}
+
+func TestApplyTargetsCreatesTransitiveDependencies(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	_, err := e.ApplyTargets(context.Background(), dir, []string{"container.api"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, providerCalls(mp, "cloud", "Create"))
+	assert.Equal(t, 1, providerCalls(mp, "consul", "Create"))
+	assert.Equal(t, 1, providerCalls(mp, "api", "Create"))
+}
+
+func TestApplyTargetsWithUnknownTargetReturnsError(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	_, err := e.ApplyTargets(context.Background(), dir, []string{"container.vault"})
+	assert.Error(t, err)
+
+	testAssertMethodCalled(t, mp, "Create", 0)
+}
@@ -0,0 +1,366 @@
+package shipyard
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl2/hclwrite"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// terraformWriter builds a Terraform configuration which uses the docker,
+// kubernetes and helm providers
+type terraformWriter struct {
+	b bytes.Buffer
+}
+
+var terraformInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// generateTerraform converts the resources in the config into Terraform
+// resources. Networks, containers, sidecars, Kubernetes config, and Helm
+// charts are converted, the Kubernetes and Helm providers are configured to use
+// the Kubernetes config for each cluster. Resources which have no equivalent
+// are listed in a comment so they can be recreated manually
+func generateTerraform(c *config.Config) []byte {
+	w := &terraformWriter{}
+	unsupported := []string{}
+
+	w.block("terraform", nil, func() {
+		w.block("required_providers", nil, func() {
+			w.raw("docker", `{ source = "kreuzwerker/docker" }`)
+			w.raw("kubernetes", `{ source = "hashicorp/kubernetes" }`)
+			w.raw("helm", `{ source = "hashicorp/helm" }`)
+		})
+	})
+
+	for _, r := range c.Resources {
+		switch v := r.(type) {
+		case *config.Network:
+			w.network(v)
+		case *config.Container:
+			w.container(v)
+		case *config.Sidecar:
+			w.sidecar(v)
+		case *config.K8sCluster:
+			w.k8sCluster(v)
+		case *config.K8sConfig:
+			w.k8sConfig(v)
+		case *config.Helm:
+			w.helm(v)
+		default:
+			unsupported = append(unsupported, fmt.Sprintf("%s.%s", v.Info().Type, v.Info().Name))
+		}
+	}
+
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		w.b.WriteString("\n# The following resources have no Terraform equivalent and have not been exported\n")
+		for _, u := range unsupported {
+			w.b.WriteString(fmt.Sprintf("# - %s\n", u))
+		}
+	}
+
+	return hclwrite.Format(w.b.Bytes())
+}
+
+func (w *terraformWriter) network(n *config.Network) {
+	if n.External {
+		w.block("data", []string{"docker_network", terraformName(n.Name)}, func() {
+			w.str("name", n.Name)
+		})
+
+		return
+	}
+
+	w.block("resource", []string{"docker_network", terraformName(n.Name)}, func() {
+		w.str("name", n.Name)
+
+		if n.SubnetIPv6 != "" {
+			w.raw("ipv6", "true")
+		}
+
+		for _, s := range []string{n.Subnet, n.SubnetIPv6} {
+			if s != "" {
+				w.block("ipam_config", nil, func() {
+					w.str("subnet", s)
+				})
+			}
+		}
+	})
+}
+
+func (w *terraformWriter) container(co *config.Container) {
+	name := terraformContainerName(resourceName(co))
+	w.image(name, co.Image)
+
+	w.block("resource", []string{"docker_container", name}, func() {
+		w.str("name", utils.FQDN(co.Name, string(co.Type)))
+		w.raw("image", fmt.Sprintf("docker_image.%s.image_id", name))
+		w.containerArgs(co.Entrypoint, co.Command, co.Environment, co.Privileged, co.Resources)
+
+		for _, p := range co.Ports {
+			if p.Host == "" {
+				continue
+			}
+
+			w.block("ports", nil, func() {
+				w.raw("internal", p.Local)
+				w.raw("external", p.Host)
+
+				if p.Protocol != "" {
+					w.str("protocol", p.Protocol)
+				}
+			})
+		}
+
+		w.volumes(co.Volumes)
+
+		for _, n := range co.Networks {
+			w.block("networks_advanced", nil, func() {
+				w.raw("name", terraformNetworkRef(co.Config, n.Name))
+
+				if n.IPAddress != "" {
+					w.str("ipv4_address", n.IPAddress)
+				}
+
+				if n.IPv6Address != "" {
+					w.str("ipv6_address", n.IPv6Address)
+				}
+
+				if len(n.Aliases) > 0 {
+					w.list("aliases", n.Aliases)
+				}
+			})
+		}
+	})
+}
+
+func (w *terraformWriter) sidecar(s *config.Sidecar) {
+	name := terraformContainerName(resourceName(s))
+	w.image(name, s.Image)
+
+	w.block("resource", []string{"docker_container", name}, func() {
+		w.str("name", utils.FQDN(s.Name, string(s.Type)))
+		w.raw("image", fmt.Sprintf("docker_image.%s.image_id", name))
+
+		// sidecars share the network namespace of the target container
+		target := terraformContainerName(s.Target)
+		w.raw("network_mode", fmt.Sprintf(`"container:${docker_container.%s.id}"`, target))
+
+		w.containerArgs(s.Entrypoint, s.Command, s.Environment, s.Privileged, s.Resources)
+		w.volumes(s.Volumes)
+	})
+}
+
+func (w *terraformWriter) image(name string, i config.Image) {
+	w.block("resource", []string{"docker_image", name}, func() {
+		w.str("name", i.Name)
+		w.raw("keep_locally", "true")
+	})
+}
+
+func (w *terraformWriter) containerArgs(entrypoint, command []string, env []config.KV, privileged bool, r *config.Resources) {
+	if len(entrypoint) > 0 {
+		w.list("entrypoint", entrypoint)
+	}
+
+	if len(command) > 0 {
+		w.list("command", command)
+	}
+
+	if len(env) > 0 {
+		e := []string{}
+		for _, kv := range env {
+			e = append(e, fmt.Sprintf("%s=%s", kv.Key, kv.Value))
+		}
+
+		w.list("env", e)
+	}
+
+	if privileged {
+		w.raw("privileged", "true")
+	}
+
+	if r != nil {
+		if r.CPU > 0 {
+			w.raw("cpu_shares", fmt.Sprintf("%d", r.CPU))
+		}
+
+		if len(r.CPUPin) > 0 {
+			pins := []string{}
+			for _, p := range r.CPUPin {
+				pins = append(pins, fmt.Sprintf("%d", p))
+			}
+
+			w.str("cpu_set", strings.Join(pins, ","))
+		}
+
+		if r.Memory > 0 {
+			w.raw("memory", fmt.Sprintf("%d", r.Memory))
+		}
+	}
+}
+
+func (w *terraformWriter) volumes(volumes []config.Volume) {
+	for _, v := range volumes {
+		if v.Type == "tmpfs" {
+			continue
+		}
+
+		w.block("volumes", nil, func() {
+			if v.Type == "volume" {
+				w.str("volume_name", v.Source)
+			} else {
+				w.str("host_path", v.Source)
+			}
+
+			w.str("container_path", v.Destination)
+		})
+	}
+}
+
+// k8sCluster configures the kubernetes and helm providers for the cluster,
+// the cluster itself is managed outside of Terraform
+func (w *terraformWriter) k8sCluster(k *config.K8sCluster) {
+	name := terraformName(k.Name)
+
+	// prefer the managed config which is shared by all clusters
+	kubeconfig := k.KubeConfig
+	if kubeconfig == "" {
+		_, kubeconfig, _ = utils.CreateKubeConfigPath(k.Name)
+	}
+
+	providerConfig := func() {
+		w.str("config_path", kubeconfig)
+
+		if k.KubeContext != "" {
+			w.str("config_context", k.KubeContext)
+		}
+	}
+
+	w.block("provider", []string{"kubernetes"}, func() {
+		w.str("alias", name)
+		providerConfig()
+	})
+
+	w.block("provider", []string{"helm"}, func() {
+		w.str("alias", name)
+		w.block("kubernetes", nil, providerConfig)
+	})
+}
+
+// k8sConfig creates a manifest for each file, files which contain more
+// than one document must be split before applying
+func (w *terraformWriter) k8sConfig(k *config.K8sConfig) {
+	for i, p := range k.Paths {
+		name := terraformName(k.Name)
+		if len(k.Paths) > 1 {
+			name = fmt.Sprintf("%s_%d", name, i)
+		}
+
+		w.block("resource", []string{"kubernetes_manifest", name}, func() {
+			w.raw("provider", fmt.Sprintf("kubernetes.%s", terraformName(strings.TrimPrefix(k.Cluster, "k8s_cluster."))))
+			w.raw("manifest", fmt.Sprintf("yamldecode(file(%s))", terraformString(p)))
+		})
+	}
+}
+
+func (w *terraformWriter) helm(h *config.Helm) {
+	w.block("resource", []string{"helm_release", terraformName(h.Name)}, func() {
+		w.raw("provider", fmt.Sprintf("helm.%s", terraformName(strings.TrimPrefix(h.Cluster, "k8s_cluster."))))
+		w.str("name", h.Name)
+		w.str("chart", h.Chart)
+
+		if h.Namespace != "" {
+			w.str("namespace", h.Namespace)
+		}
+
+		if h.Values != "" {
+			w.raw("values", fmt.Sprintf("[file(%s)]", terraformString(h.Values)))
+		}
+
+		keys := []string{}
+		for k := range h.ValuesString {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			w.block("set", nil, func() {
+				w.str("name", k)
+				w.str("value", h.ValuesString[k])
+			})
+		}
+	})
+}
+
+func (w *terraformWriter) block(t string, labels []string, body func()) {
+	w.b.WriteString("\n" + t)
+	for _, l := range labels {
+		w.b.WriteString(" " + terraformString(l))
+	}
+
+	w.b.WriteString(" {\n")
+	body()
+	w.b.WriteString("}\n")
+}
+
+func (w *terraformWriter) raw(name, expr string) {
+	w.b.WriteString(fmt.Sprintf("%s = %s\n", name, expr))
+}
+
+func (w *terraformWriter) str(name, value string) {
+	w.raw(name, terraformString(value))
+}
+
+func (w *terraformWriter) list(name string, values []string) {
+	q := []string{}
+	for _, v := range values {
+		q = append(q, terraformString(v))
+	}
+
+	w.raw(name, fmt.Sprintf("[%s]", strings.Join(q, ", ")))
+}
+
+// terraformString returns the value as a quoted and escaped HCL string
+func terraformString(s string) string {
+	return string(hclwrite.TokensForValue(cty.StringVal(s)).Bytes())
+}
+
+// terraformName converts a Shipyard resource name into a valid Terraform identifier
+func terraformName(name string) string {
+	n := terraformInvalidChars.ReplaceAllString(name, "_")
+	if n == "" || (n[0] >= '0' && n[0] <= '9') {
+		n = "_" + n
+	}
+
+	return n
+}
+
+// terraformContainerName returns the Terraform identifier for the container
+// and image of a resource in the form [type].[name], containers and sidecars
+// both create docker_container resources so the type is part of the name
+func terraformContainerName(ref string) string {
+	return terraformName(strings.Replace(ref, ".", "_", 1))
+}
+
+// terraformNetworkRef returns a reference to the name of the Terraform network,
+// external networks are referenced using a data source
+func terraformNetworkRef(c *config.Config, network string) string {
+	name := terraformName(strings.TrimPrefix(network, "network."))
+
+	if c != nil {
+		if r, err := c.FindResource(network); err == nil {
+			if n, ok := r.(*config.Network); ok && n.External {
+				return fmt.Sprintf("data.docker_network.%s.name", name)
+			}
+		}
+	}
+
+	return fmt.Sprintf("docker_network.%s.name", name)
+}
@@ -0,0 +1,139 @@
+package shipyard
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl2/hclparse"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportTerraformWritesResources(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, terraformState)
+	defer cleanup()
+
+	out := filepath.Join(utils.StateDir(), "main.tf")
+	err := e.ExportTerraform(out)
+	assert.NoError(t, err)
+
+	d, err := ioutil.ReadFile(out)
+	assert.NoError(t, err)
+
+	_, diags := hclparse.NewParser().ParseHCL(d, out)
+	assert.False(t, diags.HasErrors(), diags.Error())
+
+	tf := string(d)
+	assert.Contains(t, tf, `resource "docker_network" "cloud"`)
+	assert.Contains(t, tf, `resource "docker_container" "container_consul"`)
+	assert.Contains(t, tf, `resource "docker_image" "container_consul"`)
+	assert.Contains(t, tf, `resource "docker_container" "sidecar_envoy"`)
+	assert.Contains(t, tf, `docker_image.sidecar_envoy.image_id`)
+	assert.Contains(t, tf, `docker_network.cloud.name`)
+	assert.Contains(t, tf, `network_mode = "container:${docker_container.container_consul.id}"`)
+	assert.Contains(t, tf, `config_context = "shipyard-k3s"`)
+	assert.Contains(t, tf, `resource "helm_release" "vault"`)
+	assert.Contains(t, tf, `provider = helm.k3s`)
+	assert.Contains(t, tf, `"server.dev.enabled"`)
+	assert.Contains(t, tf, `# - k8s_ingress.consul-http`)
+}
+
+func TestExportTerraformNamesContainersAndSidecarsByType(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, terraformSameNameState)
+	defer cleanup()
+
+	out := filepath.Join(utils.StateDir(), "main.tf")
+	err := e.ExportTerraform(out)
+	assert.NoError(t, err)
+
+	d, err := ioutil.ReadFile(out)
+	assert.NoError(t, err)
+
+	_, diags := hclparse.NewParser().ParseHCL(d, out)
+	assert.False(t, diags.HasErrors(), diags.Error())
+
+	// duplicate resources are an error in Terraform
+	tf := string(d)
+	assert.Equal(t, 1, strings.Count(tf, `resource "docker_container" "container_app"`))
+	assert.Equal(t, 1, strings.Count(tf, `resource "docker_container" "sidecar_app"`))
+	assert.Equal(t, 1, strings.Count(tf, `resource "docker_image" "container_app"`))
+	assert.Equal(t, 1, strings.Count(tf, `resource "docker_image" "sidecar_app"`))
+	assert.Contains(t, tf, `network_mode = "container:${docker_container.container_app.id}"`)
+}
+
+var terraformState = `
+{
+  "blueprint": null,
+  "resources": [
+	{
+      "name": "cloud",
+      "status": "applied",
+      "subnet": "10.15.0.0/16",
+      "type": "network"
+	},
+	{
+      "name": "consul",
+      "status": "applied",
+      "type": "container",
+      "image": {"name": "consul:1.7.2"},
+      "networks": [{"name": "network.cloud", "ip_address": "10.15.0.200"}],
+      "environment": [{"key": "CONSUL_HTTP_ADDR", "value": "http://localhost:8500"}],
+      "ports": [{"local": "8500", "remote": "8500", "host": "18500"}],
+      "volumes": [{"source": "/tmp/config", "destination": "/config"}]
+	},
+	{
+      "name": "envoy",
+      "status": "applied",
+      "type": "sidecar",
+      "target": "container.consul",
+      "image": {"name": "envoyproxy/envoy:v1.14.1"}
+	},
+	{
+      "name": "k3s",
+      "status": "applied",
+      "driver": "k3s",
+      "type": "k8s_cluster",
+      "kubeconfig": "/tmp/kubeconfig.yaml",
+      "kube_context": "shipyard-k3s"
+	},
+	{
+      "name": "vault",
+      "status": "applied",
+      "type": "helm",
+      "cluster": "k8s_cluster.k3s",
+      "chart": "/tmp/charts/vault",
+      "values_string": {"server.dev.enabled": "true"}
+	},
+	{
+      "name": "consul-http",
+      "status": "applied",
+      "type": "k8s_ingress",
+      "cluster": "k8s_cluster.k3s",
+      "service": "consul"
+	}
+  ]
+}
+`
+
+var terraformSameNameState = `
+{
+  "blueprint": null,
+  "resources": [
+	{
+      "name": "app",
+      "status": "applied",
+      "type": "container",
+      "image": {"name": "nginx:1.19"}
+	},
+	{
+      "name": "app",
+      "status": "applied",
+      "type": "sidecar",
+      "target": "container.app",
+      "image": {"name": "envoyproxy/envoy:v1.14.1"}
+	}
+  ]
+}
+`
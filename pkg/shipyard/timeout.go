@@ -0,0 +1,81 @@
+package shipyard
+
+import (
+	"context"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers"
+	"golang.org/x/xerrors"
+)
+
+// create creates the resource with the provider, when the resource defines a
+// timeout an error is returned if the resource has not been created before
+// it elapses. The provider is stopped when the timeout elapses, this includes
+// pulling the images for the resource and any retries. A provider which
+// completes after the timeout is still failed so the resource is replaced by
+// the next apply
+func (e *EngineImpl) create(ctx context.Context, r config.Resource, p providers.Provider) error {
+	to, err := resourceTimeout(r)
+	if err != nil {
+		return xerrors.Errorf("Unable to create %s: %w", resourceName(r), err)
+	}
+
+	if to == 0 {
+		return e.createWithRetry(ctx, r, p)
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, to)
+	defer cancel()
+
+	err = e.createWithRetry(tctx, r, p)
+
+	// the apply was cancelled before the resource timeout
+	if ctx.Err() != nil {
+		return xerrors.Errorf("Unable to create %s, apply cancelled: %w", resourceName(r), ctx.Err())
+	}
+
+	if tctx.Err() != nil {
+		e.log.Error("Timeout creating resource", "ref", resourceName(r), "timeout", to)
+
+		return xerrors.Errorf("Timeout creating %s after %s: %w", resourceName(r), to, tctx.Err())
+	}
+
+	return err
+}
+
+// resourceTimeout returns the maximum time to create the resource, 0 is
+// returned when the resource does not define a timeout
+func resourceTimeout(r config.Resource) (time.Duration, error) {
+	var to string
+
+	switch v := r.(type) {
+	case *config.Container:
+		to = v.Timeout
+	case *config.Sidecar:
+		to = v.Timeout
+	case *config.Helm:
+		to = v.Timeout
+	case *config.K8sCluster:
+		to = v.Timeout
+	case *config.K8sConfig:
+		to = v.Timeout
+	case *config.NomadCluster:
+		to = v.Timeout
+	case *config.NomadJob:
+		to = v.Timeout
+	case *config.ExecRemote:
+		to = v.Timeout
+	}
+
+	if to == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(to)
+	if err != nil {
+		return 0, xerrors.Errorf("Invalid timeout %s: %w", to, err)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,143 @@
+package shipyard
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers"
+	"github.com/shipyard-run/shipyard/pkg/providers/mocks"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateReturnsErrorForInvalidTimeout(t *testing.T) {
+	dir := setupValidateTests(t, map[string]string{"timeout.hcl": strings.Replace(timeoutConfig, `"10ms"`, `"soon"`, 1)})
+	defer os.RemoveAll(dir)
+
+	err := Validate(dir, hclog.NewNullLogger())
+	assert.Error(t, err)
+
+	ie, ok := err.(*InvalidBlueprintError)
+	assert.True(t, ok)
+	assert.Len(t, ie.Errors, 1)
+	assert.Equal(t, "container.consul", ie.Errors[0].Resource)
+	assert.Contains(t, ie.Errors[0].Message, "Invalid timeout soon")
+}
+
+// slowCreateProvider returns a provider which takes d to create the named
+// resource
+func slowCreateProvider(mp *[]*mocks.MockProvider, name string, d time.Duration) getProviderFunc {
+	gp := generateProviderMock(mp, nil)
+
+	return func(c config.Resource, cc *Clients) providers.Provider {
+		if c.Info().Name != name {
+			return gp(c, cc)
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		m := mocks.New(c)
+		m.On("Create").After(d).Return(nil)
+		m.On("Destroy").Return(nil)
+
+		*mp = append(*mp, m)
+		return m
+	}
+}
+
+func setupTimeoutTests(t *testing.T, blueprint string) (Engine, string, *[]*mocks.MockProvider, func()) {
+	e, _, mp, cleanup := setupTests(nil)
+
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	ioutil.WriteFile(filepath.Join(dir, "main.hcl"), []byte(blueprint), 0644)
+
+	return e, dir, mp, func() {
+		cleanup()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestApplyFailsResourceWhenTimeoutElapses(t *testing.T) {
+	e, dir, mp, cleanup := setupTimeoutTests(t, timeoutConfig)
+	defer cleanup()
+
+	e.(*EngineImpl).getProvider = slowCreateProvider(mp, "consul", 500*time.Millisecond)
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Timeout creating container.consul after 10ms")
+
+	// dependents are not created
+	assert.Equal(t, 0, providerCalls(mp, "api", "Create"))
+
+	sc := config.New()
+	sc.FromJSON(utils.StatePath())
+	r, _ := sc.FindResource("container.consul")
+	assert.Equal(t, config.Failed, r.Info().Status)
+}
+
+func TestApplyWithInvalidResourceTimeoutReturnsError(t *testing.T) {
+	e, dir, mp, cleanup := setupTimeoutTests(t, strings.Replace(timeoutConfig, `"10ms"`, `"soon"`, 1))
+	defer cleanup()
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid timeout soon")
+
+	assert.Equal(t, 0, providerCalls(mp, "consul", "Create"))
+}
+
+func TestApplyDoesNotPullImagesForResourcesWithTimeout(t *testing.T) {
+	e, dir, _, cleanup := setupTimeoutTests(t, timeoutConfig)
+	defer cleanup()
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	// the image for the resource with a timeout is pulled by the provider
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	ct.AssertNumberOfCalls(t, "PullImage", 1)
+	ct.AssertCalled(t, "PullImage", config.Image{Name: "nicholasjackson/fake-service:v0.9.0"}, false)
+}
+
+func TestApplyWithTimeoutCancelsApply(t *testing.T) {
+	e, dir, mp, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	e.(*EngineImpl).getProvider = slowCreateProvider(mp, "consul", 200*time.Millisecond)
+
+	_, err := e.ApplyWithOptions(context.Background(), dir, ApplyOptions{DisableBrowser: true, Timeout: 10 * time.Millisecond})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Apply did not complete within 10ms")
+
+	assert.Equal(t, 0, providerCalls(mp, "api", "Create"))
+}
+
+var timeoutConfig = `
+container "consul" {
+  image {
+    name = "consul:1.8.1"
+  }
+
+  timeout = "10ms"
+}
+
+container "api" {
+  depends_on = ["container.consul"]
+
+  image {
+    name = "nicholasjackson/fake-service:v0.9.0"
+  }
+}
+`
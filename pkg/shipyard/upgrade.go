@@ -0,0 +1,365 @@
+package shipyard
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// UpgradeChanges are the resources changed by Upgrade in the form
+// [type].[name]
+type UpgradeChanges struct {
+	Added     []string `json:"added"`
+	Updated   []string `json:"updated"` // destroyed and created again with the new definition
+	Removed   []string `json:"removed"`
+	Unchanged []string `json:"unchanged"`
+}
+
+// Upgrade changes the running environment to match a newer revision of the
+// blueprint at path. Only the resources which differ from the state are
+// changed, resources which are no longer defined are destroyed, new
+// resources are created, and resources with a changed definition are
+// destroyed and created again along with the resources which depend on
+// them. Named volumes are not removed so the data for recreated containers
+// is preserved.
+func (e *EngineImpl) Upgrade(ctx context.Context, path string) (uc *UpgradeChanges, err error) {
+	err = e.lockState(path)
+	if err != nil {
+		return nil, err
+	}
+	defer e.unlockState(&err)
+
+	started := time.Now()
+
+	uc, err = e.upgrade(ctx, path)
+	e.audit(AuditUpgrade, path, started, err)
+	e.notify(AuditUpgrade, path, started, err)
+
+	return uc, err
+}
+
+func (e *EngineImpl) upgrade(ctx context.Context, path string) (*UpgradeChanges, error) {
+	res := e.startResult("upgrade")
+
+	sc := config.New()
+	err := sc.FromJSON(e.stateFile())
+	if err != nil {
+		err = xerrors.Errorf("Unable to upgrade, no environment is running: %w", err)
+		res.finish(err)
+		return nil, err
+	}
+
+	cc, err := e.parseConfig(path)
+	if err != nil {
+		res.finish(err)
+		return nil, err
+	}
+
+	uc := upgradeChanges(sc, cc)
+
+	e.log.Info("Upgrading environment", "added", len(uc.Added), "updated", len(uc.Updated), "removed", len(uc.Removed))
+
+	// destroy the removed and updated resources in reverse dependency order
+	recreate := map[string]bool{}
+	for _, n := range append(uc.Removed, uc.Updated...) {
+		recreate[n] = true
+	}
+
+	for _, r := range sc.Resources {
+		if recreate[resourceName(r)] {
+			r.Info().Status = config.PendingUpdate
+		}
+	}
+
+	e.config = sc
+
+	d, err := sc.DoYaLikeDAGs()
+	if err != nil {
+		err = xerrors.Errorf("Unable to create dependency graph: %w", err)
+		res.finish(err)
+		return nil, err
+	}
+
+	d.TransitiveReduction()
+
+	err = e.destroyPending(ctx, d, false)
+
+	// the new state contains the resources which have not changed and the
+	// new definitions for the updated and added resources
+	cn := config.New()
+	for _, r := range sc.Resources {
+		if r.Info().Status != config.Destroyed {
+			cn.AddResource(r)
+		}
+	}
+
+	if err == nil {
+		for _, r := range cc.Resources {
+			if _, ferr := cn.FindResource(resourceName(r)); ferr != nil {
+				r.Info().Status = config.PendingCreation
+				cn.AddResource(r)
+			}
+		}
+
+		if cc.Blueprint != nil {
+			cn.Blueprint = cc.Blueprint
+		}
+	}
+
+	if len(cn.Resources) > 0 {
+		jerr := cn.ToJSON(e.stateFile())
+		if jerr != nil {
+			res.finish(jerr)
+			return nil, jerr
+		}
+	}
+
+	if err != nil {
+		err = xerrors.Errorf("Unable to destroy resources removed from the blueprint: %w", err)
+		res.finish(err)
+		return uc, err
+	}
+
+	// create the new resources from the state, apply records its own result
+	// combine it with the destroyed resources
+	_, err = e.apply(ctx, "")
+
+	applied := e.Result()
+	applied.combine(res)
+	applied.finish(err)
+
+	return uc, err
+}
+
+// upgradeChanges compares the resources in the state with the new config,
+// resources which depend on a changed resource are also updated as they
+// need to be created again when the dependency is recreated
+func upgradeChanges(sc, cc *config.Config) *UpgradeChanges {
+	uc := &UpgradeChanges{Added: []string{}, Updated: []string{}, Removed: []string{}, Unchanged: []string{}}
+
+	changed := map[string]bool{}
+
+	for _, r := range sc.Resources {
+		nr, err := cc.FindResource(resourceName(r))
+		if err != nil {
+			uc.Removed = append(uc.Removed, resourceName(r))
+			changed[resourceName(r)] = true
+			continue
+		}
+
+		// resources which failed or were tainted must be created again
+		if r.Info().Status != config.Applied || resourceChanged(r, nr) {
+			changed[resourceName(r)] = true
+		}
+	}
+
+	// propagate the changes to the dependent resources
+	for {
+		propagated := false
+
+		for _, r := range cc.Resources {
+			if changed[resourceName(r)] {
+				continue
+			}
+
+			for _, dn := range r.Info().DependsOn {
+				dr, err := cc.FindResource(dn)
+				if err == nil && changed[resourceName(dr)] {
+					changed[resourceName(r)] = true
+					propagated = true
+					break
+				}
+			}
+		}
+
+		if !propagated {
+			break
+		}
+	}
+
+	for _, r := range cc.Resources {
+		n := resourceName(r)
+
+		switch {
+		case !stateContains(sc, n):
+			uc.Added = append(uc.Added, n)
+		case changed[n]:
+			uc.Updated = append(uc.Updated, n)
+		default:
+			uc.Unchanged = append(uc.Unchanged, n)
+		}
+	}
+
+	sort.Strings(uc.Added)
+	sort.Strings(uc.Updated)
+	sort.Strings(uc.Removed)
+	sort.Strings(uc.Unchanged)
+
+	return uc
+}
+
+func stateContains(sc *config.Config, name string) bool {
+	_, err := sc.FindResource(name)
+	return err == nil
+}
+
+// resourceChanged returns true when the attributes set in the blueprint for
+// the new resource differ from the resource in the state
+func resourceChanged(old, new config.Resource) bool {
+	return len(resourceChanges(old, new)) > 0
+}
+
+// resourceChanges returns the names of the attributes set in the blueprint
+// which differ between the resource in the state and the new resource. The
+// definitions parsed from the blueprints are compared, states written
+// before definitions were recorded are compared with the values the
+// providers set
+func resourceChanges(old, new config.Resource) []string {
+	od, ook := config.Definition(old)
+	nd, nok := config.Definition(new)
+	if ook && nok {
+		return hclChanges(od, nd, map[string]bool{})
+	}
+
+	changes := []string{}
+	ignore := map[string]bool{}
+
+	// providers set some attributes when the resource is created, compare
+	// these using the values the provider would set
+	switch o := old.(type) {
+	case *config.Helm:
+		n := new.(*config.Helm)
+
+		chart := n.Chart
+		if !utils.IsLocalFolder(chart) && o.Chart != chart {
+			chart = providers.HelmChartFolder(chart)
+		}
+
+		ns := n.Namespace
+		if ns == "" {
+			ns = "default"
+		}
+
+		if o.Chart != chart {
+			changes = append(changes, "chart")
+		}
+
+		if o.Namespace != ns {
+			changes = append(changes, "namespace")
+		}
+
+		ignore["chart"] = true
+		ignore["namespace"] = true
+
+	case *config.NomadCluster:
+		if new.(*config.NomadCluster).Version == "" {
+			ignore["version"] = true
+		}
+	}
+
+	return append(changes, hclChanges(old, new, ignore)...)
+}
+
+// hclChanges returns the names of the attributes which can be set in a
+// blueprint which differ between the two resources
+func hclChanges(old, new config.Resource, ignore map[string]bool) []string {
+	changes := []string{}
+
+	a := reflect.Indirect(reflect.ValueOf(old))
+	b := reflect.Indirect(reflect.ValueOf(new))
+
+	for i := 0; i < a.NumField(); i++ {
+		tag, ok := a.Type().Field(i).Tag.Lookup("hcl")
+		if !ok || tag == "-" || strings.HasSuffix(tag, ",label") || strings.HasSuffix(tag, ",remain") {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if ignore[name] {
+			continue
+		}
+
+		if !hclEqual(a.Field(i), b.Field(i), nil) {
+			changes = append(changes, name)
+		}
+	}
+
+	return changes
+}
+
+// hclEqual compares the fields of two values which can be set in a
+// blueprint, values without a hcl tag are set when the resource is created
+// and are ignored
+func hclEqual(a, b reflect.Value, ignore map[string]bool) bool {
+	if a.Kind() != b.Kind() {
+		return false
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+
+		return hclEqual(a.Elem(), b.Elem(), ignore)
+
+	case reflect.Struct:
+		if a.Type() != b.Type() {
+			return false
+		}
+
+		for i := 0; i < a.NumField(); i++ {
+			tag, ok := a.Type().Field(i).Tag.Lookup("hcl")
+			if !ok || tag == "-" || strings.HasSuffix(tag, ",label") || strings.HasSuffix(tag, ",remain") {
+				continue
+			}
+
+			if ignore[strings.Split(tag, ",")[0]] {
+				continue
+			}
+
+			if !hclEqual(a.Field(i), b.Field(i), nil) {
+				return false
+			}
+		}
+
+		return true
+
+	case reflect.Slice:
+		// empty and unset lists are the same in a blueprint
+		if a.Len() != b.Len() {
+			return false
+		}
+
+		for i := 0; i < a.Len(); i++ {
+			if !hclEqual(a.Index(i), b.Index(i), nil) {
+				return false
+			}
+		}
+
+		return true
+
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+
+		for _, k := range a.MapKeys() {
+			bv := b.MapIndex(k)
+			if !bv.IsValid() || !hclEqual(a.MapIndex(k), bv, nil) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
@@ -0,0 +1,237 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/providers/mocks"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupUpgradeTests(t *testing.T, e Engine) string {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	os.MkdirAll(filepath.Join(dir, "v1"), os.ModePerm)
+	os.MkdirAll(filepath.Join(dir, "v2"), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(dir, "v1", "main.hcl"), []byte(upgradeV1), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "v2", "main.hcl"), []byte(upgradeV2), 0644)
+
+	_, err = e.Apply(context.Background(), filepath.Join(dir, "v1"))
+	assert.NoError(t, err)
+
+	return dir
+}
+
+func TestUpgradeAppliesOnlyChangedResources(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir := setupUpgradeTests(t, e)
+	defer os.RemoveAll(dir)
+
+	*mp = []*mocks.MockProvider{}
+
+	uc, err := e.Upgrade(context.Background(), filepath.Join(dir, "v2"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"container.nginx"}, uc.Added)
+	assert.Equal(t, []string{"container.consul", "container.consul_client"}, uc.Updated)
+	assert.Equal(t, []string{"container.vault"}, uc.Removed)
+	assert.Equal(t, []string{"container.redis", "network.cloud"}, uc.Unchanged)
+
+	assert.Equal(t, 1, providerCalls(mp, "vault", "Destroy"))
+	assert.Equal(t, 0, providerCalls(mp, "vault", "Create"))
+	assert.Equal(t, 1, providerCalls(mp, "consul", "Destroy"))
+	assert.Equal(t, 1, providerCalls(mp, "consul", "Create"))
+	assert.Equal(t, 1, providerCalls(mp, "consul_client", "Create"))
+	assert.Equal(t, 1, providerCalls(mp, "nginx", "Create"))
+	assert.Equal(t, 0, providerCalls(mp, "redis", "Create")+providerCalls(mp, "redis", "Destroy"))
+	assert.Equal(t, 0, providerCalls(mp, "cloud", "Create")+providerCalls(mp, "cloud", "Destroy"))
+
+	sc := config.New()
+	err = sc.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+	assert.Len(t, sc.Resources, 5)
+
+	r, err := sc.FindResource("container.consul")
+	assert.NoError(t, err)
+	assert.Equal(t, "consul:1.9.0", r.(*config.Container).Image.Name)
+	assert.Equal(t, config.Applied, r.Info().Status)
+
+	_, err = sc.FindResource("container.vault")
+	assert.Error(t, err)
+}
+
+func TestUpgradeWithSameBlueprintChangesNothing(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir := setupUpgradeTests(t, e)
+	defer os.RemoveAll(dir)
+
+	*mp = []*mocks.MockProvider{}
+
+	uc, err := e.Upgrade(context.Background(), filepath.Join(dir, "v1"))
+	assert.NoError(t, err)
+
+	assert.Len(t, uc.Added, 0)
+	assert.Len(t, uc.Updated, 0)
+	assert.Len(t, uc.Removed, 0)
+	assert.Len(t, *mp, 0)
+}
+
+func TestUpgradeIgnoresAttributesSetByProviders(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir := setupUpgradeTests(t, e)
+	defer os.RemoveAll(dir)
+
+	// providers set defaults for attributes which are not in the blueprint
+	sc := config.New()
+	err := sc.FromJSON(utils.StatePath())
+	assert.NoError(t, err)
+
+	r, _ := sc.FindResource("container.redis")
+	r.(*config.Container).Networks[0].IPAddress = "10.15.0.200"
+	sc.ToJSON(utils.StatePath())
+
+	*mp = []*mocks.MockProvider{}
+
+	uc, err := e.Upgrade(context.Background(), filepath.Join(dir, "v1"))
+	assert.NoError(t, err)
+
+	assert.Len(t, uc.Updated, 0)
+	assert.Equal(t, 0, providerCalls(mp, "redis", "Destroy"))
+}
+
+func TestUpgradeReturnsErrorWhenNoState(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	_, err := e.Upgrade(context.Background(), "../../functional_tests/test_fixtures/single_k3s_cluster")
+	assert.Error(t, err)
+}
+
+func TestUpgradeKeepsStateWhenDestroyFails(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir := setupUpgradeTests(t, e)
+	defer os.RemoveAll(dir)
+
+	e.(*EngineImpl).getProvider = generateProviderMock(mp, map[string]error{"vault": fmt.Errorf("boom")})
+
+	_, err := e.Upgrade(context.Background(), filepath.Join(dir, "v2"))
+	assert.Error(t, err)
+
+	sc := config.New()
+	sc.FromJSON(utils.StatePath())
+
+	r, err := sc.FindResource("container.vault")
+	assert.NoError(t, err)
+	assert.Equal(t, config.Failed, r.Info().Status)
+
+	// new resources are not created when the old ones can not be removed
+	_, err = sc.FindResource("container.nginx")
+	assert.Error(t, err)
+}
+
+var upgradeV1 = `
+network "cloud" {
+  subnet = "10.15.0.0/16"
+}
+
+container "consul" {
+  image {
+    name = "consul:1.8.1"
+  }
+
+  network {
+    name = "network.cloud"
+  }
+
+  volume {
+    source      = "consul_data"
+    destination = "/data"
+    type        = "volume"
+  }
+}
+
+container "consul_client" {
+  depends_on = ["container.consul"]
+
+  image {
+    name = "consul:1.8.1"
+  }
+}
+
+container "vault" {
+  image {
+    name = "vault:1.5.0"
+  }
+}
+
+container "redis" {
+  image {
+    name = "redis:6.0"
+  }
+
+  network {
+    name = "network.cloud"
+  }
+}
+`
+
+var upgradeV2 = `
+network "cloud" {
+  subnet = "10.15.0.0/16"
+}
+
+container "consul" {
+  image {
+    name = "consul:1.9.0"
+  }
+
+  network {
+    name = "network.cloud"
+  }
+
+  volume {
+    source      = "consul_data"
+    destination = "/data"
+    type        = "volume"
+  }
+}
+
+container "consul_client" {
+  depends_on = ["container.consul"]
+
+  image {
+    name = "consul:1.8.1"
+  }
+}
+
+container "redis" {
+  image {
+    name = "redis:6.0"
+  }
+
+  network {
+    name = "network.cloud"
+  }
+}
+
+container "nginx" {
+  image {
+    name = "nginx:1.19"
+  }
+}
+`
@@ -0,0 +1,155 @@
+package shipyard
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"golang.org/x/xerrors"
+)
+
+// Usage is a point in time sample of the host resources used by the
+// environment
+type Usage struct {
+	Total     ResourceUsage   `json:"total"`
+	Resources []ResourceUsage `json:"resources"` // sorted by CPU and then memory usage, highest first
+}
+
+// ResourceUsage is the host resources used by the containers for a resource
+type ResourceUsage struct {
+	Name          string  `json:"name"`
+	Type          string  `json:"type"`
+	Containers    int     `json:"containers"`
+	CPUPercentage float64 `json:"cpu_percentage"` // percentage of the host CPU, 100 per core
+	MemoryUsage   uint64  `json:"memory_usage"`   // memory used in bytes excluding the page cache
+	NetworkRx     uint64  `json:"network_rx"`     // bytes received on all interfaces
+	NetworkTx     uint64  `json:"network_tx"`     // bytes sent on all interfaces
+	ImageSize     int64   `json:"image_size"`     // size of the images in bytes
+	WritableSize  int64   `json:"writable_size"`  // bytes written to the container filesystems
+	VolumeSize    int64   `json:"volume_size"`    // size of the named volumes in bytes
+}
+
+// Usage returns the CPU, memory, network, and disk space used by each running
+// resource and the total for the environment. Images and volumes which are
+// shared between resources are counted once in the total
+func (e *EngineImpl) Usage() (*Usage, error) {
+	sc, err := e.readState("")
+	if err != nil {
+		return nil, xerrors.Errorf("No resources are running: %w", err)
+	}
+
+	e.config = sc
+
+	u := &Usage{Resources: []ResourceUsage{}}
+	images := map[string]bool{}
+	volumes := map[string]bool{}
+
+	// disk usage is expensive to calculate, fetch it once for each Docker host
+	disk := map[*Clients]map[string]config.ContainerDiskUsage{}
+
+	for _, r := range sc.Resources {
+		name, ok := resourceContainerName(r)
+		if !ok {
+			continue
+		}
+
+		cl, err := e.clientsFor(r)
+		if err != nil {
+			return nil, err
+		}
+
+		ids, err := cl.ContainerTasks.FindContainerIDs(context.Background(), name, r.Info().Type)
+		if err != nil {
+			return nil, xerrors.Errorf("Unable to find containers for %s.%s: %w", r.Info().Type, r.Info().Name, err)
+		}
+
+		if len(ids) == 0 {
+			continue
+		}
+
+		if _, ok := disk[cl]; !ok {
+			du, err := cl.ContainerTasks.DiskUsage(context.Background())
+			if err != nil {
+				return nil, err
+			}
+
+			disk[cl] = du
+		}
+
+		ru := ResourceUsage{Name: r.Info().Name, Type: string(r.Info().Type), Containers: len(ids)}
+
+		for _, id := range ids {
+			// stopped containers do not report stats but still use disk space
+			s, err := cl.ContainerTasks.ContainerStats(context.Background(), id)
+			if err != nil {
+				e.log.Debug("Unable to get stats for container", "resource", r.Info().Name, "id", id, "error", err)
+			} else {
+				ru.CPUPercentage += s.CPUPercentage
+				ru.MemoryUsage += s.MemoryUsage
+				ru.NetworkRx += s.NetworkRx
+				ru.NetworkTx += s.NetworkTx
+			}
+
+			du, ok := disk[cl][id]
+			if !ok {
+				continue
+			}
+
+			ru.ImageSize += du.ImageSize
+			ru.WritableSize += du.WritableSize
+
+			if !images[du.Image] {
+				images[du.Image] = true
+				u.Total.ImageSize += du.ImageSize
+			}
+
+			for v, size := range du.Volumes {
+				ru.VolumeSize += size
+
+				if !volumes[v] {
+					volumes[v] = true
+					u.Total.VolumeSize += size
+				}
+			}
+		}
+
+		u.Total.Containers += ru.Containers
+		u.Total.CPUPercentage += ru.CPUPercentage
+		u.Total.MemoryUsage += ru.MemoryUsage
+		u.Total.NetworkRx += ru.NetworkRx
+		u.Total.NetworkTx += ru.NetworkTx
+		u.Total.WritableSize += ru.WritableSize
+
+		u.Resources = append(u.Resources, ru)
+	}
+
+	sort.SliceStable(u.Resources, func(i, j int) bool {
+		if u.Resources[i].CPUPercentage != u.Resources[j].CPUPercentage {
+			return u.Resources[i].CPUPercentage > u.Resources[j].CPUPercentage
+		}
+
+		return u.Resources[i].MemoryUsage > u.Resources[j].MemoryUsage
+	})
+
+	return u, nil
+}
+
+// resourceContainerName returns the name of the containers for resources which
+// run in Docker, clusters use the server container
+func resourceContainerName(r config.Resource) (string, bool) {
+	switch r.Info().Type {
+	case config.TypeK8sCluster, config.TypeNomadCluster:
+		return fmt.Sprintf("server.%s", r.Info().Name), true
+	case config.TypeContainer,
+		config.TypeSidecar,
+		config.TypeIngress,
+		config.TypeContainerIngress,
+		config.TypeK8sIngress,
+		config.TypeNomadIngress,
+		config.TypeDocs:
+		return r.Info().Name, true
+	}
+
+	return "", false
+}
@@ -0,0 +1,124 @@
+package shipyard
+
+import (
+	"fmt"
+	"testing"
+
+	clientmocks "github.com/shipyard-run/shipyard/pkg/clients/mocks"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var usageState = `
+{
+  "blueprint": null,
+  "resources": [
+	{
+      "name": "k3s",
+      "status": "applied",
+      "type": "k8s_cluster"
+	},
+	{
+      "name": "consul",
+      "status": "applied",
+      "type": "container"
+	},
+	{
+      "name": "consul-2",
+      "status": "applied",
+      "type": "container"
+	},
+	{
+      "name": "cloud",
+      "status": "applied",
+      "type": "network"
+	}
+  ]
+}
+`
+
+func setupUsageTests() (Engine, *clientmocks.MockContainerTasks, func()) {
+	e, _, _, cleanup := setupTestsWithState(nil, usageState)
+
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	removeOn(&ct.Mock, "FindContainerIDs")
+	ct.On("FindContainerIDs", "server.k3s", config.TypeK8sCluster).Return([]string{"k3s"}, nil)
+	ct.On("FindContainerIDs", "consul", config.TypeContainer).Return([]string{"consul"}, nil)
+	ct.On("FindContainerIDs", "consul-2", config.TypeContainer).Return([]string{"consul-2"}, nil)
+
+	ct.On("ContainerStats", "k3s").Return(&config.ContainerStats{CPUPercentage: 50, MemoryUsage: 1000, NetworkRx: 10, NetworkTx: 20}, nil)
+	ct.On("ContainerStats", "consul").Return(&config.ContainerStats{CPUPercentage: 5, MemoryUsage: 100, NetworkRx: 1, NetworkTx: 2}, nil)
+	ct.On("ContainerStats", "consul-2").Return(nil, fmt.Errorf("container is not running"))
+
+	ct.On("DiskUsage").Return(map[string]config.ContainerDiskUsage{
+		"k3s":      config.ContainerDiskUsage{Image: "k3s", ImageSize: 500, WritableSize: 50, Volumes: map[string]int64{"images": 300}},
+		"consul":   config.ContainerDiskUsage{Image: "consul", ImageSize: 100, WritableSize: 5, Volumes: map[string]int64{"images": 300}},
+		"consul-2": config.ContainerDiskUsage{Image: "consul", ImageSize: 100, WritableSize: 1, Volumes: map[string]int64{}},
+	}, nil)
+
+	return e, ct, cleanup
+}
+
+func TestUsageReturnsUsagePerResourceSortedByCPU(t *testing.T) {
+	e, _, cleanup := setupUsageTests()
+	defer cleanup()
+
+	u, err := e.Usage()
+	assert.NoError(t, err)
+
+	assert.Len(t, u.Resources, 3)
+	assert.Equal(t, ResourceUsage{
+		Name:          "k3s",
+		Type:          "k8s_cluster",
+		Containers:    1,
+		CPUPercentage: 50,
+		MemoryUsage:   1000,
+		NetworkRx:     10,
+		NetworkTx:     20,
+		ImageSize:     500,
+		WritableSize:  50,
+		VolumeSize:    300,
+	}, u.Resources[0])
+	assert.Equal(t, "consul", u.Resources[1].Name)
+	assert.Equal(t, "consul-2", u.Resources[2].Name)
+	assert.Equal(t, int64(100), u.Resources[2].ImageSize)
+}
+
+func TestUsageCountsSharedImagesAndVolumesOnceInTotal(t *testing.T) {
+	e, ct, cleanup := setupUsageTests()
+	defer cleanup()
+
+	u, err := e.Usage()
+	assert.NoError(t, err)
+
+	assert.Equal(t, 3, u.Total.Containers)
+	assert.Equal(t, float64(55), u.Total.CPUPercentage)
+	assert.Equal(t, uint64(1100), u.Total.MemoryUsage)
+	assert.Equal(t, int64(600), u.Total.ImageSize)
+	assert.Equal(t, int64(56), u.Total.WritableSize)
+	assert.Equal(t, int64(300), u.Total.VolumeSize)
+
+	ct.AssertNumberOfCalls(t, "DiskUsage", 1)
+}
+
+func TestUsageReturnsErrorWhenDiskUsageFails(t *testing.T) {
+	e, _, _, cleanup := setupTestsWithState(nil, usageState)
+	defer cleanup()
+
+	ct := e.GetClients().ContainerTasks.(*clientmocks.MockContainerTasks)
+	removeOn(&ct.Mock, "FindContainerIDs")
+	ct.On("FindContainerIDs", mock.Anything, mock.Anything).Return([]string{"abc"}, nil)
+	ct.On("DiskUsage").Return(nil, fmt.Errorf("boom"))
+
+	_, err := e.Usage()
+	assert.Error(t, err)
+}
+
+func TestUsageReturnsErrorWhenNoState(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	_, err := e.Usage()
+	assert.Error(t, err)
+}
@@ -0,0 +1,232 @@
+package shipyard
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/hcl2/hcl/hclsyntax"
+	"github.com/hashicorp/hcl2/hclparse"
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// ValidationError is a problem with a blueprint found by Validate
+type ValidationError struct {
+	Resource string `json:"resource,omitempty"` // [type].[name], empty when the error is not caused by a single resource
+	File     string `json:"file,omitempty"`     // file the error was found in, when known
+	Message  string `json:"message"`
+}
+
+// InvalidBlueprintError is returned by Validate when the blueprint has errors
+type InvalidBlueprintError struct {
+	Errors []ValidationError
+}
+
+func (i *InvalidBlueprintError) Error() string {
+	v := []string{}
+	for _, ve := range i.Errors {
+		msg := ve.Message
+		if ve.Resource != "" {
+			msg = fmt.Sprintf("%s: %s", ve.Resource, msg)
+		}
+
+		if ve.File != "" {
+			msg = fmt.Sprintf("%s (%s)", msg, ve.File)
+		}
+
+		v = append(v, "  "+msg)
+	}
+
+	return fmt.Sprintf("Blueprint is not valid:\n%s", strings.Join(v, "\n"))
+}
+
+// Validate checks the blueprint at path in the same way as
+// EngineImpl.Validate without creating the clients, Docker does not need to
+// be installed so blueprints can be checked in CI
+func Validate(path string, l hclog.Logger) error {
+	e := &EngineImpl{log: l}
+
+	return e.Validate(path)
+}
+
+// Validate parses the blueprint at path and checks references resolve, names
+// are unique, ports are valid, and clusters use a supported driver. An
+// *InvalidBlueprintError listing every problem is returned when the blueprint
+// is not valid, the state is not read or changed
+func (e *EngineImpl) Validate(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return xerrors.Errorf("Unable to read blueprint %s: %w", path, err)
+	}
+
+	// resources defined more than once are silently merged by the parser
+	errs := duplicateResources(path)
+
+	cc, err := e.parseConfig(path)
+	if err != nil {
+		errs = append(errs, ValidationError{Message: err.Error()})
+		return &InvalidBlueprintError{errs}
+	}
+
+	if cc.Blueprint != nil {
+		for _, err := range cc.Blueprint.Validate() {
+			errs = append(errs, ValidationError{Message: err.Error()})
+		}
+	}
+
+	references := true
+	hostPortsUsed := map[string]string{}
+
+	for _, r := range cc.Resources {
+		add := func(format string, args ...interface{}) {
+			errs = append(errs, ValidationError{Resource: resourceName(r), Message: fmt.Sprintf(format, args...)})
+		}
+
+		if _, err := utils.ValidateName(r.Info().Name); err != nil {
+			add("invalid name: %s", err)
+		}
+
+		for _, d := range r.Info().DependsOn {
+			if !strings.Contains(d, ".") {
+				add("invalid reference %s, references must be in the form [type].[name]", d)
+				references = false
+				continue
+			}
+
+			if _, err := cc.FindResource(d); err != nil {
+				add("references %s which does not exist", d)
+				references = false
+			}
+		}
+
+		for _, p := range resourcePorts(r) {
+			for _, v := range []string{p.Local, p.Remote, p.Host} {
+				if v != "" && !validPort(v) {
+					add("invalid port %s, ports must be between 1 and 65535", v)
+				}
+			}
+
+			if p.Protocol != "" && p.Protocol != "tcp" && p.Protocol != "udp" {
+				add("invalid port protocol %s, must be one of tcp or udp", p.Protocol)
+			}
+		}
+
+		// host ports can only be used once on each Docker host
+		for _, p := range hostPorts(r) {
+			k := fmt.Sprintf("%s:%d", config.DockerHostFor(r), p)
+			if o, ok := hostPortsUsed[k]; ok {
+				add("host port %d is also used by %s", p, o)
+				continue
+			}
+
+			hostPortsUsed[k] = resourceName(r)
+		}
+
+		if _, err := resourceTimeout(r); err != nil {
+			add("%s", err)
+		}
+
+		if kc, ok := r.(*config.K8sCluster); ok && kc.Driver != "k3s" {
+			add("unknown cluster driver %s, must be k3s", kc.Driver)
+		}
+
+		switch v := r.(type) {
+		case interface{ Validate() []error }:
+			for _, err := range v.Validate() {
+				add("%s", err)
+			}
+		case interface{ Validate() error }:
+			if err := v.Validate(); err != nil {
+				add("%s", err)
+			}
+		}
+	}
+
+	// cycles can only be found once every reference resolves
+	if references {
+		d, err := cc.DoYaLikeDAGs()
+		if err == nil {
+			err = d.Validate()
+		}
+
+		if err != nil {
+			errs = append(errs, ValidationError{Message: fmt.Sprintf("invalid dependency graph: %s", err)})
+		}
+	}
+
+	if len(errs) > 0 {
+		return &InvalidBlueprintError{errs}
+	}
+
+	return nil
+}
+
+// duplicateResources returns an error for each resource which is defined
+// more than once in the HCL files at path
+func duplicateResources(path string) []ValidationError {
+	files := []string{path}
+	if !utils.IsHCLFile(path) {
+		files, _ = filepath.Glob(filepath.Join(path, "*.hcl"))
+	}
+
+	errs := []ValidationError{}
+	defined := map[string]string{}
+
+	for _, f := range files {
+		hf, diag := hclparse.NewParser().ParseHCLFile(f)
+		if diag.HasErrors() {
+			// syntax errors are returned by the parser
+			continue
+		}
+
+		body, ok := hf.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		for _, b := range body.Blocks {
+			if len(b.Labels) != 1 {
+				continue
+			}
+
+			n := fmt.Sprintf("%s.%s", b.Type, b.Labels[0])
+			if o, ok := defined[n]; ok {
+				errs = append(errs, ValidationError{Resource: n, File: f, Message: fmt.Sprintf("defined more than once, also defined in %s", o)})
+				continue
+			}
+
+			defined[n] = f
+		}
+	}
+
+	return errs
+}
+
+// resourcePorts returns the port mappings for the resource
+func resourcePorts(r config.Resource) []config.Port {
+	switch v := r.(type) {
+	case *config.Container:
+		return v.Ports
+	case *config.Ingress:
+		return v.Ports
+	case *config.ContainerIngress:
+		return v.Ports
+	case *config.K8sIngress:
+		return v.Ports
+	case *config.NomadIngress:
+		return v.Ports
+	}
+
+	return nil
+}
+
+// validPort returns true when the port is a number between 1 and 65535
+func validPort(p string) bool {
+	i, err := strconv.Atoi(p)
+
+	return err == nil && i > 0 && i <= 65535
+}
@@ -0,0 +1,140 @@
+package shipyard
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupValidateTests(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	for n, c := range files {
+		ioutil.WriteFile(filepath.Join(dir, n), []byte(c), 0644)
+	}
+
+	return dir
+}
+
+func TestValidateWithValidBlueprintReturnsNoError(t *testing.T) {
+	dir := setupValidateTests(t, map[string]string{"graph.hcl": graphConfig})
+	defer os.RemoveAll(dir)
+
+	err := Validate(dir, hclog.NewNullLogger())
+	assert.NoError(t, err)
+}
+
+func TestValidateReturnsAllErrors(t *testing.T) {
+	dir := setupValidateTests(t, map[string]string{"graph.hcl": graphConfig, "invalid.hcl": invalidConfig})
+	defer os.RemoveAll(dir)
+
+	err := Validate(dir, hclog.NewNullLogger())
+	assert.Error(t, err)
+
+	ie, ok := err.(*InvalidBlueprintError)
+	assert.True(t, ok)
+
+	errs := map[string][]string{}
+	for _, ve := range ie.Errors {
+		errs[ve.Resource] = append(errs[ve.Resource], ve.Message)
+	}
+
+	assert.Contains(t, errs["container.consul"][0], "defined more than once")
+	assert.Equal(t, []string{"references network.missing which does not exist"}, errs["container.web"])
+	assert.Equal(t, []string{"invalid port 70000, ports must be between 1 and 65535", "invalid port protocol http, must be one of tcp or udp"}, errs["ingress.web"])
+	assert.Equal(t, []string{"host port 8080 is also used by ingress.web"}, errs["container.app"])
+	assert.Equal(t, []string{"unknown cluster driver kind, must be k3s"}, errs["k8s_cluster.dev"])
+}
+
+func TestValidateReturnsErrorForDependencyCycle(t *testing.T) {
+	dir := setupValidateTests(t, map[string]string{"cycle.hcl": cycleConfig})
+	defer os.RemoveAll(dir)
+
+	err := Validate(dir, hclog.NewNullLogger())
+	assert.Error(t, err)
+
+	ie, ok := err.(*InvalidBlueprintError)
+	assert.True(t, ok)
+	assert.Len(t, ie.Errors, 1)
+	assert.Contains(t, ie.Errors[0].Message, "invalid dependency graph")
+}
+
+func TestValidateReturnsErrorWhenPathMissing(t *testing.T) {
+	err := Validate("/missing/blueprint", hclog.NewNullLogger())
+	assert.Error(t, err)
+
+	_, ok := err.(*InvalidBlueprintError)
+	assert.False(t, ok)
+}
+
+var invalidConfig = `
+container "consul" {
+  image {
+    name = "consul:1.8.1"
+  }
+}
+
+container "web" {
+  image {
+    name = "nginx"
+  }
+
+  network {
+    name = "network.missing"
+  }
+}
+
+ingress "web" {
+  target = "container.consul"
+
+  port {
+    local    = 8080
+    remote   = 70000
+    host     = 8080
+    protocol = "http"
+  }
+}
+
+container "app" {
+  image {
+    name = "nginx"
+  }
+
+  port {
+    local  = 80
+    remote = 80
+    host   = 8080
+  }
+}
+
+k8s_cluster "dev" {
+  driver = "kind"
+
+  network {
+    name = "network.cloud"
+  }
+}
+`
+
+var cycleConfig = `
+container "a" {
+  depends_on = ["container.b"]
+
+  image {
+    name = "nginx"
+  }
+}
+
+container "b" {
+  depends_on = ["container.a"]
+
+  image {
+    name = "nginx"
+  }
+}
+`
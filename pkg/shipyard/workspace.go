@@ -0,0 +1,339 @@
+package shipyard
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"golang.org/x/xerrors"
+)
+
+// defaultWorkspacePortOffset is added to the host ports of each workspace
+// multiplied by the workspace index
+const defaultWorkspacePortOffset = 1000
+
+// Workspace is an environment with its own state. Resources in workspaces
+// other than the default are prefixed with the workspace name, networks use
+// a separate subnet, and host ports are offset so that several environments
+// can run side by side
+type Workspace struct {
+	Name       string `json:"name"`
+	Index      int    `json:"index"`               // subnets are offset by the index, 0 for the default workspace
+	PortOffset int    `json:"port_offset"`         // added to every host port
+	Classroom  string `json:"classroom,omitempty"` // name of the classroom for classroom instances
+	Current    bool   `json:"current"`
+	Resources  int    `json:"resources"` // number of resources in the state
+}
+
+// Workspaces returns the default workspace followed by the named workspaces
+// sorted by name
+func (e *EngineImpl) Workspaces() ([]Workspace, error) {
+	ws, err := namedWorkspaces()
+	if err != nil {
+		return nil, err
+	}
+
+	ws = append([]Workspace{{Name: utils.DefaultWorkspace}}, ws...)
+
+	current := utils.CurrentWorkspace()
+	for i := range ws {
+		ws[i].Current = ws[i].Name == current
+
+		sc := config.New()
+		if err := sc.FromJSON(workspaceStatePath(ws[i].Name)); err == nil {
+			ws[i].Resources = len(sc.Resources)
+		}
+	}
+
+	return ws, nil
+}
+
+// CreateWorkspace creates the named workspace using the lowest index which
+// is not used by another workspace or classroom instance
+func (e *EngineImpl) CreateWorkspace(name string) error {
+	if name == utils.DefaultWorkspace {
+		return xerrors.Errorf("Workspace %s already exists", name)
+	}
+
+	if _, err := os.Stat(workspaceFile(name)); err == nil {
+		return xerrors.Errorf("Workspace %s already exists", name)
+	}
+
+	_, err := createWorkspace(Workspace{Name: name})
+
+	return err
+}
+
+// SelectWorkspace makes the named workspace the current workspace for this
+// and future commands, the workspace must have been created with
+// CreateWorkspace. The workspace set by the environment variable
+// utils.WorkspaceEnvVar is used in preference to the selected workspace
+func (e *EngineImpl) SelectWorkspace(name string) error {
+	if name == utils.DefaultWorkspace {
+		err := os.Remove(utils.CurrentWorkspacePath())
+		if err != nil && !os.IsNotExist(err) {
+			return xerrors.Errorf("Unable to select workspace %s: %w", name, err)
+		}
+
+		return nil
+	}
+
+	w, err := workspace(name)
+	if err != nil {
+		return err
+	}
+
+	if w.Classroom != "" {
+		return xerrors.Errorf("Workspace %s is an instance of the classroom %s and can not be selected", name, w.Classroom)
+	}
+
+	os.MkdirAll(utils.ShipyardHome(), os.ModePerm)
+
+	err = ioutil.WriteFile(utils.CurrentWorkspacePath(), []byte(name), 0644)
+	if err != nil {
+		return xerrors.Errorf("Unable to select workspace %s: %w", name, err)
+	}
+
+	if w := os.Getenv(utils.WorkspaceEnvVar); w != "" && w != name {
+		e.log.Warn("Workspace is set by the environment and overrides the selected workspace", "env", utils.WorkspaceEnvVar, "workspace", w)
+	}
+
+	return nil
+}
+
+// DeleteWorkspace removes the named workspace, the resources in the
+// workspace must be destroyed first. When the workspace is the current
+// workspace the default workspace is selected
+func (e *EngineImpl) DeleteWorkspace(name string) error {
+	if name == utils.DefaultWorkspace {
+		return xerrors.Errorf("The default workspace can not be deleted")
+	}
+
+	w, err := workspace(name)
+	if err != nil {
+		return err
+	}
+
+	if w.Classroom != "" {
+		return xerrors.Errorf("Workspace %s is an instance of the classroom %s, use classroom destroy to remove it", name, w.Classroom)
+	}
+
+	err = removeWorkspace(name)
+	if err != nil {
+		return err
+	}
+
+	if d, _ := ioutil.ReadFile(utils.CurrentWorkspacePath()); strings.TrimSpace(string(d)) == name {
+		os.Remove(utils.CurrentWorkspacePath())
+	}
+
+	return nil
+}
+
+// removeWorkspace deletes the folder for the workspace, the state is locked
+// so that resources can not be created in the workspace while it is removed
+func removeWorkspace(name string) error {
+	l, err := createLock(utils.WorkspaceStatePath(name) + ".lock")
+	if err != nil {
+		return err
+	}
+	defer l.release()
+
+	sc := config.New()
+	if err := sc.FromJSON(utils.WorkspaceStatePath(name)); err == nil && len(sc.Resources) > 0 {
+		return xerrors.Errorf("Workspace %s contains %d resources, destroy the resources before deleting the workspace", name, len(sc.Resources))
+	}
+
+	err = os.RemoveAll(filepath.Dir(utils.WorkspaceStatePath(name)))
+	if err != nil {
+		return xerrors.Errorf("Unable to delete workspace %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// validateWorkspace returns an error when the current workspace has an
+// invalid name or has not been created, classroom instances always use
+// their own workspace
+func (e *EngineImpl) validateWorkspace() error {
+	if e.statePath != "" {
+		return nil
+	}
+
+	err := utils.ValidateWorkspace()
+	if err != nil {
+		return err
+	}
+
+	if name := utils.CurrentWorkspace(); name != utils.DefaultWorkspace {
+		_, err = workspace(name)
+	}
+
+	return err
+}
+
+// instanceOptions returns the options which isolate the resources in the
+// config, classroom instances use their own options and resources in the
+// default workspace are not modified
+func (e *EngineImpl) instanceOptions() (*config.InstanceOptions, error) {
+	if e.instance != nil || e.statePath != "" {
+		return e.instance, nil
+	}
+
+	name := utils.CurrentWorkspace()
+	if name == utils.DefaultWorkspace {
+		return nil, nil
+	}
+
+	w, err := workspace(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config.InstanceOptions{Prefix: w.Name, Index: w.Index, PortOffset: w.PortOffset}, nil
+}
+
+// workspace returns the named workspace, an error is returned when the
+// workspace has not been created
+func workspace(name string) (*Workspace, error) {
+	if _, err := utils.ValidateName(name); err != nil {
+		return nil, xerrors.Errorf("Invalid workspace name %s: %w", name, err)
+	}
+
+	d, err := ioutil.ReadFile(workspaceFile(name))
+	if os.IsNotExist(err) {
+		return nil, xerrors.Errorf("Workspace %s does not exist, create it with 'shipyard workspace new %s'", name, name)
+	}
+
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to read workspace %s: %w", name, err)
+	}
+
+	w := &Workspace{}
+	err = json.Unmarshal(d, w)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to read workspace %s: %w", name, err)
+	}
+
+	return w, nil
+}
+
+// createWorkspace writes the file for the workspace w. Named workspaces and
+// classroom instances share the same indexes so that their subnets do not
+// overlap, the lowest index not used by another workspace is allocated.
+// An existing workspace for the same classroom is returned unchanged. When
+// w.PortOffset is not set it defaults to the index multiplied by
+// defaultWorkspacePortOffset
+func createWorkspace(w Workspace) (*Workspace, error) {
+	if _, err := utils.ValidateName(w.Name); err != nil {
+		return nil, xerrors.Errorf("Invalid workspace name %s: %w", w.Name, err)
+	}
+
+	// serialise creation so that two commands can not allocate the same index
+	l, err := createLock(filepath.Join(utils.WorkspacesDir(), "workspaces.lock"))
+	if err != nil {
+		return nil, err
+	}
+	defer l.release()
+
+	if existing, err := workspace(w.Name); err == nil {
+		if existing.Classroom != w.Classroom || w.Classroom == "" {
+			return nil, xerrors.Errorf("Workspace %s already exists", w.Name)
+		}
+
+		return existing, nil
+	}
+
+	ws, err := allWorkspaces()
+	if err != nil {
+		return nil, err
+	}
+
+	used := map[int]bool{}
+	for _, w := range ws {
+		used[w.Index] = true
+	}
+
+	w.Index = 1
+	for used[w.Index] {
+		w.Index++
+	}
+
+	if w.PortOffset == 0 {
+		w.PortOffset = w.Index * defaultWorkspacePortOffset
+	}
+
+	d, _ := json.Marshal(w)
+
+	os.MkdirAll(filepath.Dir(workspaceFile(w.Name)), os.ModePerm)
+
+	err = ioutil.WriteFile(workspaceFile(w.Name), d, 0644)
+	if err != nil {
+		return nil, xerrors.Errorf("Unable to create workspace %s: %w", w.Name, err)
+	}
+
+	return &w, nil
+}
+
+// namedWorkspaces returns the workspaces created with CreateWorkspace sorted
+// by name, classroom instances are not returned
+func namedWorkspaces() ([]Workspace, error) {
+	all, err := allWorkspaces()
+	if err != nil {
+		return nil, err
+	}
+
+	ws := []Workspace{}
+	for _, w := range all {
+		if w.Classroom == "" {
+			ws = append(ws, w)
+		}
+	}
+
+	return ws, nil
+}
+
+// allWorkspaces returns the named workspaces and classroom instances sorted
+// by name
+func allWorkspaces() ([]Workspace, error) {
+	files, err := ioutil.ReadDir(utils.WorkspacesDir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, xerrors.Errorf("Unable to read workspaces: %w", err)
+	}
+
+	ws := []Workspace{}
+	for _, f := range files {
+		d, err := ioutil.ReadFile(workspaceFile(f.Name()))
+		if !f.IsDir() || err != nil {
+			continue
+		}
+
+		w := Workspace{}
+		if json.Unmarshal(d, &w) == nil {
+			ws = append(ws, w)
+		}
+	}
+
+	sort.Slice(ws, func(i, j int) bool { return ws[i].Name < ws[j].Name })
+
+	return ws, nil
+}
+
+// workspaceFile returns the location of the file describing the workspace
+func workspaceFile(name string) string {
+	return filepath.Join(utils.WorkspacesDir(), name, "workspace.json")
+}
+
+// workspaceStatePath returns the location of the state for the workspace
+func workspaceStatePath(name string) string {
+	if name == utils.DefaultWorkspace {
+		return filepath.Join(utils.StateDir(), "state.json")
+	}
+
+	return utils.WorkspaceStatePath(name)
+}
@@ -0,0 +1,232 @@
+package shipyard
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyClassroomSharesIndexesWithWorkspaces(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir := setupClassroom(t)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, e.CreateWorkspace("staging"))
+
+	_, err := e.ApplyClassroom(context.Background(), dir, ClassroomOptions{Instances: 1})
+	assert.NoError(t, err)
+
+	sc := config.New()
+	sc.FromJSON(utils.WorkspaceStatePath("student-1"))
+	n, err := sc.FindResource("network.student-1-cloud")
+	assert.NoError(t, err)
+
+	// staging uses index 1 so the instance uses the next free index
+	assert.Equal(t, "10.7.0.0/16", n.(*config.Network).Subnet)
+
+	// classroom instances are not named workspaces
+	ws, err := e.Workspaces()
+	assert.NoError(t, err)
+	assert.Len(t, ws, 2)
+
+	err = e.SelectWorkspace("student-1")
+	assert.Error(t, err)
+}
+
+func TestApplyClassroomDoesNotUseNamedWorkspace(t *testing.T) {
+	e, _, mp, cleanup := setupTests(nil)
+	defer cleanup()
+
+	dir := setupClassroom(t)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, e.CreateWorkspace("student-1"))
+
+	s, err := e.ApplyClassroom(context.Background(), dir, ClassroomOptions{Instances: 2})
+	assert.NoError(t, err)
+	testAssertMethodCalled(t, mp, "Create", 2)
+
+	assert.Contains(t, s[0].Error, "already exists")
+	assert.Empty(t, s[1].Error)
+
+	s, err = e.ClassroomStatus("student")
+	assert.NoError(t, err)
+	assert.Len(t, s, 1)
+	assert.Equal(t, "student-2", s[0].Name)
+}
+
+func TestSelectWorkspaceSelectsCreatedWorkspace(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	err := e.CreateWorkspace("staging")
+	assert.NoError(t, err)
+
+	err = e.SelectWorkspace("staging")
+	assert.NoError(t, err)
+
+	ws, err := e.Workspaces()
+	assert.NoError(t, err)
+	assert.Len(t, ws, 2)
+
+	assert.Equal(t, "default", ws[0].Name)
+	assert.False(t, ws[0].Current)
+
+	assert.Equal(t, "staging", ws[1].Name)
+	assert.Equal(t, 1, ws[1].Index)
+	assert.Equal(t, 1000, ws[1].PortOffset)
+	assert.True(t, ws[1].Current)
+
+	assert.Equal(t, utils.WorkspaceStatePath("staging"), e.(*EngineImpl).stateFile())
+}
+
+func TestSelectWorkspaceReturnsErrorWhenWorkspaceDoesNotExist(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	err := e.SelectWorkspace("staging")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not exist")
+
+	_, err = os.Stat(workspaceFile("staging"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCreateWorkspaceReturnsErrorWhenWorkspaceExists(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	assert.NoError(t, e.CreateWorkspace("staging"))
+
+	err := e.CreateWorkspace("staging")
+	assert.Error(t, err)
+}
+
+func TestCreateWorkspaceUsesLowestFreeIndex(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	assert.NoError(t, e.CreateWorkspace("one"))
+	assert.NoError(t, e.CreateWorkspace("two"))
+	assert.NoError(t, e.DeleteWorkspace("one"))
+	assert.NoError(t, e.CreateWorkspace("three"))
+
+	ws, err := e.Workspaces()
+	assert.NoError(t, err)
+	assert.Len(t, ws, 3)
+
+	assert.Equal(t, "three", ws[1].Name)
+	assert.Equal(t, 1, ws[1].Index)
+	assert.Equal(t, "two", ws[2].Name)
+	assert.Equal(t, 2, ws[2].Index)
+}
+
+func TestCreateWorkspaceReturnsErrorWhenNameInvalid(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	err := e.CreateWorkspace("my workspace")
+	assert.Error(t, err)
+}
+
+func TestApplyReturnsErrorWhenWorkspaceFromEnvInvalid(t *testing.T) {
+	e, dir, _, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	os.Setenv(utils.WorkspaceEnvVar, "../state")
+	defer os.Unsetenv(utils.WorkspaceEnvVar)
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid workspace name")
+}
+
+func TestApplyReturnsErrorWhenWorkspaceFromEnvDoesNotExist(t *testing.T) {
+	e, dir, _, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	os.Setenv(utils.WorkspaceEnvVar, "staging")
+	defer os.Unsetenv(utils.WorkspaceEnvVar)
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not exist")
+
+	_, err = os.Stat(workspaceFile("staging"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestApplyInWorkspaceIsolatesResources(t *testing.T) {
+	e, dir, _, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	assert.NoError(t, e.CreateWorkspace("staging"))
+
+	err := e.SelectWorkspace("staging")
+	assert.NoError(t, err)
+
+	_, err = e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	sc := config.New()
+	err = sc.FromJSON(utils.WorkspaceStatePath("staging"))
+	assert.NoError(t, err)
+
+	n, err := sc.FindResource("network.staging-cloud")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.16.0.0/16", n.(*config.Network).Subnet)
+
+	_, err = sc.FindResource("container.staging-consul")
+	assert.NoError(t, err)
+
+	// the default workspace is not changed
+	_, err = os.Stat(filepath.Join(utils.StateDir(), "state.json"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDeleteWorkspaceReturnsErrorWhenResourcesExist(t *testing.T) {
+	e, dir, _, cleanup := setupTargetTests(t)
+	defer cleanup()
+
+	e.CreateWorkspace("staging")
+	e.SelectWorkspace("staging")
+
+	_, err := e.Apply(context.Background(), dir)
+	assert.NoError(t, err)
+
+	err = e.DeleteWorkspace("staging")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "destroy the resources")
+}
+
+func TestDeleteWorkspaceSelectsDefaultWorkspace(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	e.CreateWorkspace("staging")
+	e.SelectWorkspace("staging")
+
+	err := e.DeleteWorkspace("staging")
+	assert.NoError(t, err)
+
+	assert.Equal(t, utils.DefaultWorkspace, utils.CurrentWorkspace())
+
+	ws, err := e.Workspaces()
+	assert.NoError(t, err)
+	assert.Len(t, ws, 1)
+}
+
+func TestDeleteWorkspaceReturnsErrorForDefault(t *testing.T) {
+	e, _, _, cleanup := setupTests(nil)
+	defer cleanup()
+
+	err := e.DeleteWorkspace(utils.DefaultWorkspace)
+	assert.Error(t, err)
+}
@@ -0,0 +1,305 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+)
+
+// maxLogLines is the number of lines of log output shown below the resources
+const maxLogLines = 8
+
+// defaultWidth is used when the width of the terminal is not known
+const defaultWidth = 80
+
+var ansiCodes = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
+
+// Renderer draws a live view of the resources being created or destroyed by
+// the engine. Resources are shown in a tree grouped by type with their status
+// and the time taken, the most recent lines of log output are shown below the
+// tree. The view is redrawn in place using ANSI escape codes so the writer
+// must be a terminal.
+type Renderer struct {
+	w     io.Writer
+	width int
+	now   func() time.Time
+
+	mu        sync.Mutex
+	action    string
+	order     []string
+	resources map[string]*resourceStatus
+	logs      []string
+	partial   string
+	drawn     int // lines written by the last frame
+	stopped   bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+type resourceStatus struct {
+	name     string
+	typ      config.ResourceType
+	phase    shipyard.EventPhase
+	started  time.Time
+	duration time.Duration
+	err      error
+}
+
+// New creates a Renderer which draws to w, lines are truncated to width
+// characters so that the view can be redrawn in place, when width is 0
+// a width of 80 characters is used
+func New(w io.Writer, width int) *Renderer {
+	if width <= 0 {
+		width = defaultWidth
+	}
+
+	return &Renderer{
+		w:         w,
+		width:     width,
+		now:       time.Now,
+		resources: map[string]*resourceStatus{},
+		logs:      []string{},
+	}
+}
+
+// Handle is an engine EventHandler
+func (r *Renderer) Handle(e shipyard.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stopped || e.Resource == nil {
+		return
+	}
+
+	r.action = e.Action
+	key := fmt.Sprintf("%s.%s", e.Resource.Info().Type, e.Resource.Info().Name)
+
+	rs, ok := r.resources[key]
+	if !ok {
+		rs = &resourceStatus{name: e.Resource.Info().Name, typ: e.Resource.Info().Type}
+		r.resources[key] = rs
+		r.order = append(r.order, key)
+	}
+
+	rs.phase = e.Phase
+	rs.err = e.Error
+
+	if e.Phase == shipyard.EventResourceStarted {
+		rs.started = r.now()
+		rs.duration = 0
+	} else {
+		rs.duration = e.Duration
+	}
+
+	r.draw(true)
+}
+
+// Write adds log output to the view, only complete lines are shown. Write
+// allows the Renderer to be used as the output for a logger
+func (r *Renderer) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lines := strings.Split(r.partial+string(p), "\n")
+	r.partial = lines[len(lines)-1]
+
+	for _, l := range lines[:len(lines)-1] {
+		r.logs = append(r.logs, strings.TrimRight(ansiCodes.ReplaceAllString(l, ""), "\r"))
+	}
+
+	if len(r.logs) > maxLogLines {
+		r.logs = r.logs[len(r.logs)-maxLogLines:]
+	}
+
+	if !r.stopped {
+		r.draw(true)
+	}
+
+	return len(p), nil
+}
+
+// Start redraws the view at the given interval so that the timers for
+// running resources are updated
+func (r *Renderer) Start(interval time.Duration) {
+	r.done = make(chan struct{})
+	r.wg.Add(1)
+
+	go func() {
+		defer r.wg.Done()
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+				r.mu.Lock()
+				r.draw(true)
+				r.mu.Unlock()
+			case <-r.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop draws the final view without the log output, once stopped events and
+// log output are no longer drawn
+func (r *Renderer) Stop() {
+	if r.done != nil {
+		close(r.done)
+		r.wg.Wait()
+		r.done = nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stopped {
+		return
+	}
+
+	r.draw(false)
+	r.stopped = true
+}
+
+// draw clears the previous frame and writes the current view, must be
+// called with the lock held
+func (r *Renderer) draw(logs bool) {
+	b := &strings.Builder{}
+
+	if r.drawn > 0 {
+		// move to the start of the previous frame and clear to the end of the screen
+		fmt.Fprintf(b, "\r\x1b[%dA\x1b[J", r.drawn)
+	}
+
+	lines := r.frame(logs)
+	for _, l := range lines {
+		b.WriteString(l)
+		b.WriteString("\n")
+	}
+
+	r.drawn = len(lines)
+	io.WriteString(r.w, b.String())
+}
+
+// frame returns the lines for the current view
+func (r *Renderer) frame(logs bool) []string {
+	lines := []string{}
+
+	// group the resources by type keeping the order the types were first seen
+	types := []config.ResourceType{}
+	groups := map[config.ResourceType][]*resourceStatus{}
+
+	for _, k := range r.order {
+		rs := r.resources[k]
+		if _, ok := groups[rs.typ]; !ok {
+			types = append(types, rs.typ)
+		}
+
+		groups[rs.typ] = append(groups[rs.typ], rs)
+	}
+
+	if len(types) > 0 {
+		lines = append(lines, r.truncate(fmt.Sprintf("%s %s", r.action, r.summary())))
+	}
+
+	for i, t := range types {
+		branch, indent := "├─", "│  "
+		if i == len(types)-1 {
+			branch, indent = "└─", "   "
+		}
+
+		lines = append(lines, r.truncate(fmt.Sprintf("%s %s", branch, t)))
+
+		rs := groups[t]
+		sort.SliceStable(rs, func(a, b int) bool { return rs[a].name < rs[b].name })
+
+		for j, s := range rs {
+			leaf := "├─"
+			if j == len(rs)-1 {
+				leaf = "└─"
+			}
+
+			lines = append(lines, r.truncate(fmt.Sprintf("%s%s %s %-30s %s", indent, leaf, statusSymbol(s.phase), s.name, r.status(s))))
+		}
+	}
+
+	if logs && len(r.logs) > 0 {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+
+		for _, l := range r.logs {
+			lines = append(lines, r.truncate("  "+l))
+		}
+	}
+
+	return lines
+}
+
+// summary returns the number of resources in each phase
+func (r *Renderer) summary() string {
+	running, completed, failed := 0, 0, 0
+	for _, rs := range r.resources {
+		switch rs.phase {
+		case shipyard.EventResourceStarted:
+			running++
+		case shipyard.EventResourceCompleted:
+			completed++
+		case shipyard.EventResourceFailed:
+			failed++
+		}
+	}
+
+	return fmt.Sprintf("(%d running, %d completed, %d failed)", running, completed, failed)
+}
+
+// status returns the elapsed time for running resources and the time taken
+// and any error for resources which have finished
+func (r *Renderer) status(s *resourceStatus) string {
+	switch s.phase {
+	case shipyard.EventResourceStarted:
+		return formatDuration(r.now().Sub(s.started))
+	case shipyard.EventResourceFailed:
+		return fmt.Sprintf("%s  %s", formatDuration(s.duration), s.err)
+	}
+
+	return formatDuration(s.duration)
+}
+
+// truncate shortens s to the width of the terminal, line breaks are removed so
+// that each line of the frame is a single line on screen
+func (r *Renderer) truncate(s string) string {
+	s = strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+
+	rs := []rune(s)
+	if len(rs) <= r.width {
+		return s
+	}
+
+	return string(rs[:r.width-1]) + "…"
+}
+
+func statusSymbol(p shipyard.EventPhase) string {
+	switch p {
+	case shipyard.EventResourceCompleted:
+		return "✔"
+	case shipyard.EventResourceFailed:
+		return "✘"
+	}
+
+	return "●"
+}
+
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
@@ -0,0 +1,132 @@
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shipyard-run/shipyard/pkg/config"
+	"github.com/shipyard-run/shipyard/pkg/shipyard"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRenderer(width int) (*Renderer, *bytes.Buffer, *time.Time) {
+	b := &bytes.Buffer{}
+	r := New(b, width)
+
+	now := time.Unix(1600000000, 0)
+	r.now = func() time.Time { return now }
+
+	return r, b, &now
+}
+
+func testEvent(r config.Resource, p shipyard.EventPhase, d time.Duration, err error) shipyard.Event {
+	return shipyard.Event{Action: "apply", Phase: p, Resource: r, Duration: d, Error: err}
+}
+
+// lastFrame returns the lines drawn after the last clear
+func lastFrame(b *bytes.Buffer) []string {
+	out := b.String()
+	if i := strings.LastIndex(out, "\x1b[J"); i >= 0 {
+		out = out[i+3:]
+	}
+
+	return strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+}
+
+func TestHandleDrawsResourcesGroupedByType(t *testing.T) {
+	r, b, _ := setupRenderer(0)
+
+	r.Handle(testEvent(config.NewNetwork("cloud"), shipyard.EventResourceStarted, 0, nil))
+	r.Handle(testEvent(config.NewNetwork("cloud"), shipyard.EventResourceCompleted, 2*time.Second, nil))
+	r.Handle(testEvent(config.NewContainer("vault"), shipyard.EventResourceStarted, 0, nil))
+	r.Handle(testEvent(config.NewContainer("consul"), shipyard.EventResourceStarted, 0, nil))
+
+	f := lastFrame(b)
+	assert.Len(t, f, 6)
+	assert.Equal(t, "apply (2 running, 1 completed, 0 failed)", f[0])
+	assert.Equal(t, "├─ network", f[1])
+	assert.Contains(t, f[2], "└─ ✔ cloud")
+	assert.Contains(t, f[2], "2.0s")
+	assert.Equal(t, "└─ container", f[3])
+	assert.Contains(t, f[4], "├─ ● consul")
+	assert.Contains(t, f[5], "└─ ● vault")
+}
+
+func TestHandleRedrawsInPlace(t *testing.T) {
+	r, b, _ := setupRenderer(0)
+
+	r.Handle(testEvent(config.NewContainer("consul"), shipyard.EventResourceStarted, 0, nil))
+	assert.NotContains(t, b.String(), "\x1b[")
+
+	r.Handle(testEvent(config.NewContainer("consul"), shipyard.EventResourceCompleted, time.Second, nil))
+	assert.Contains(t, b.String(), "\r\x1b[3A\x1b[J")
+}
+
+func TestHandleShowsElapsedTimeForRunningResources(t *testing.T) {
+	r, b, now := setupRenderer(0)
+
+	r.Handle(testEvent(config.NewContainer("consul"), shipyard.EventResourceStarted, 0, nil))
+	*now = now.Add(3 * time.Second)
+	r.Write([]byte("log\n"))
+
+	assert.Contains(t, lastFrame(b)[2], "3.0s")
+}
+
+func TestHandleShowsErrorForFailedResources(t *testing.T) {
+	r, b, _ := setupRenderer(0)
+
+	r.Handle(testEvent(config.NewContainer("consul"), shipyard.EventResourceStarted, 0, nil))
+	r.Handle(testEvent(config.NewContainer("consul"), shipyard.EventResourceFailed, time.Second, fmt.Errorf("boom\nbang")))
+
+	f := lastFrame(b)
+	assert.Equal(t, "apply (0 running, 0 completed, 1 failed)", f[0])
+	assert.Contains(t, f[2], "✘ consul")
+	assert.Contains(t, f[2], "boom bang")
+}
+
+func TestWriteShowsTailOfCompleteLogLines(t *testing.T) {
+	r, b, _ := setupRenderer(0)
+
+	r.Handle(testEvent(config.NewContainer("consul"), shipyard.EventResourceStarted, 0, nil))
+
+	for i := 0; i < 10; i++ {
+		r.Write([]byte(fmt.Sprintf("\x1b[97mline %d\x1b[0m\n", i)))
+	}
+	r.Write([]byte("partial"))
+
+	f := lastFrame(b)
+	assert.Len(t, f, 3+1+maxLogLines)
+	assert.Equal(t, "  line 2", f[4])
+	assert.Equal(t, "  line 9", f[len(f)-1])
+}
+
+func TestDrawTruncatesLinesToWidth(t *testing.T) {
+	r, b, _ := setupRenderer(20)
+
+	r.Write([]byte(strings.Repeat("a", 50) + "\n"))
+
+	f := lastFrame(b)
+	assert.Equal(t, 20, len([]rune(f[0])))
+	assert.True(t, strings.HasSuffix(f[0], "…"))
+}
+
+func TestStopDrawsFinalFrameWithoutLogs(t *testing.T) {
+	r, b, _ := setupRenderer(0)
+	r.Start(time.Millisecond)
+
+	r.Handle(testEvent(config.NewContainer("consul"), shipyard.EventResourceStarted, 0, nil))
+	r.Write([]byte("log line\n"))
+	r.Stop()
+
+	f := lastFrame(b)
+	assert.Len(t, f, 3)
+
+	// events after stop are not drawn
+	l := b.Len()
+	r.Handle(testEvent(config.NewContainer("consul"), shipyard.EventResourceCompleted, 0, nil))
+	r.Write([]byte("log line\n"))
+	assert.Equal(t, l, b.Len())
+}
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// platforms which need paths to be translated
+const (
+	platformLinux   = "linux"
+	platformWindows = "windows"
+	platformWSL     = "wsl"
+)
+
+// windowsPath matches absolute Windows paths such as C:\Users or C:/Users
+var windowsPath = regexp.MustCompile(`^([a-zA-Z]):[\\/]`)
+
+// dockerDrivePath matches Windows paths in the format used by Docker on
+// Windows such as //c/Users
+var dockerDrivePath = regexp.MustCompile(`^//([a-zA-Z])(/|$)`)
+
+// IsWSL returns true when Shipyard is running inside the Windows Subsystem
+// for Linux
+func IsWSL() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+
+	d, err := ioutil.ReadFile("/proc/version")
+	return err == nil && strings.Contains(strings.ToLower(string(d)), "microsoft")
+}
+
+// HostPath converts a path written for another platform into a path on the
+// local machine. When running on Windows Docker style paths such as //c/Users
+// are converted to C:\Users, when running in WSL Windows paths such as
+// C:\Users are converted to /mnt/c/Users. Other paths are not modified.
+func HostPath(p string) string {
+	return hostPath(p, currentPlatform())
+}
+
+// DockerPath converts a path on the local machine to the path the Docker
+// engine uses for the source of a bind mount. When running on Windows,
+// paths such as C:\Users are converted to //c/Users. Other paths are not
+// modified.
+func DockerPath(p string) string {
+	return dockerPath(p, currentPlatform())
+}
+
+// IsHostPath returns false when the path refers to a location inside the
+// Docker VM rather than the local machine, e.g. /var/run/docker.sock on
+// Windows
+func IsHostPath(p string) bool {
+	return isHostPath(p, currentPlatform())
+}
+
+func currentPlatform() string {
+	if runtime.GOOS == "windows" {
+		return platformWindows
+	}
+
+	if IsWSL() {
+		return platformWSL
+	}
+
+	return platformLinux
+}
+
+func hostPath(p, platform string) string {
+	switch platform {
+	case platformWindows:
+		if m := dockerDrivePath.FindStringSubmatch(p); m != nil {
+			return strings.ToUpper(m[1]) + ":\\" + strings.ReplaceAll(strings.TrimLeft(p[len(m[0]):], "/"), "/", "\\")
+		}
+
+	case platformWSL:
+		if m := windowsPath.FindStringSubmatch(p); m != nil {
+			return "/mnt/" + strings.ToLower(m[1]) + "/" + strings.ReplaceAll(p[len(m[0]):], "\\", "/")
+		}
+
+		if m := dockerDrivePath.FindStringSubmatch(p); m != nil {
+			return "/mnt/" + strings.ToLower(m[1]) + "/" + strings.TrimLeft(p[len(m[0]):], "/")
+		}
+	}
+
+	return p
+}
+
+func dockerPath(p, platform string) string {
+	if platform != platformWindows {
+		return p
+	}
+
+	if m := windowsPath.FindStringSubmatch(p); m != nil {
+		return "//" + strings.ToLower(m[1]) + "/" + strings.ReplaceAll(p[len(m[0]):], "\\", "/")
+	}
+
+	return p
+}
+
+func isHostPath(p, platform string) bool {
+	if platform != platformWindows {
+		return true
+	}
+
+	// unix style paths which are not on a drive are in the Docker VM
+	return !strings.HasPrefix(p, "/") || dockerDrivePath.MatchString(p)
+}
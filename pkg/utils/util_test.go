@@ -34,6 +34,29 @@ func TestArgIsNotFolder(t *testing.T) {
 	assert.False(t, is)
 }
 
+func TestIsRemoteURIDetectsRemoteLocations(t *testing.T) {
+	assert.True(t, IsRemoteURI("github.com/shipyard-run/blueprints//vault-k8s"))
+	assert.True(t, IsRemoteURI("https://example.com/manifests/app.yaml"))
+	assert.True(t, IsRemoteURI("git::https://example.com/repo.git"))
+	assert.True(t, IsRemoteURI("s3::https://s3.amazonaws.com/bucket/app.yaml"))
+}
+
+func TestIsRemoteURIIgnoresLocalPaths(t *testing.T) {
+	assert.False(t, IsRemoteURI("/tmp/app.yaml"))
+	assert.False(t, IsRemoteURI("./app.yaml"))
+	assert.False(t, IsRemoteURI("../manifests"))
+	assert.False(t, IsRemoteURI(""))
+}
+
+func TestGetDownloadLocalFolderIsUniquePerURI(t *testing.T) {
+	a := GetDownloadLocalFolder("https://example.com/a.yaml")
+	b := GetDownloadLocalFolder("https://example.com/b.yaml")
+
+	assert.NotEqual(t, a, b)
+	assert.Equal(t, a, GetDownloadLocalFolder("https://example.com/a.yaml"))
+	assert.Contains(t, a, filepath.Join(ShipyardHome(), "downloads"))
+}
+
 func TestArgIsBlueprintFolder(t *testing.T) {
 	dir, err := GetBlueprintFolder("github.com/org/repo//folder")
 
@@ -98,6 +121,55 @@ func TestStatePathReturnsCorrectValue(t *testing.T) {
 	assert.Equal(t, filepath.Join(os.Getenv("HOME"), ".shipyard/state/state.json"), h)
 }
 
+func TestStatePathReturnsWorkspaceStateWhenSelected(t *testing.T) {
+	os.Setenv(WorkspaceEnvVar, "staging")
+	defer os.Unsetenv(WorkspaceEnvVar)
+
+	h := StatePath()
+	assert.Equal(t, filepath.Join(os.Getenv("HOME"), ".shipyard/workspaces/staging/state.json"), h)
+}
+
+func TestStatePathDoesNotLeaveWorkspacesDir(t *testing.T) {
+	os.Setenv(WorkspaceEnvVar, "../../etc")
+	defer os.Unsetenv(WorkspaceEnvVar)
+
+	h := StatePath()
+	assert.Equal(t, filepath.Join(os.Getenv("HOME"), ".shipyard/workspaces/etc/state.json"), h)
+}
+
+func TestValidateWorkspaceReturnsErrorWhenNameInvalid(t *testing.T) {
+	os.Setenv(WorkspaceEnvVar, "../staging")
+	defer os.Unsetenv(WorkspaceEnvVar)
+
+	err := ValidateWorkspace()
+	assert.Error(t, err)
+}
+
+func TestCurrentWorkspaceReadsSelectedWorkspace(t *testing.T) {
+	home := os.Getenv("HOME")
+	tmp, _ := ioutil.TempDir("", "")
+	os.Setenv("HOME", tmp)
+	defer os.Setenv("HOME", home)
+	defer os.RemoveAll(tmp)
+
+	assert.Equal(t, DefaultWorkspace, CurrentWorkspace())
+
+	os.MkdirAll(ShipyardHome(), os.ModePerm)
+	ioutil.WriteFile(CurrentWorkspacePath(), []byte("staging\n"), 0644)
+
+	assert.Equal(t, "staging", CurrentWorkspace())
+}
+
+func TestManagedKubeConfigPathReturnsCorrectValue(t *testing.T) {
+	h := ManagedKubeConfigPath()
+	assert.Equal(t, filepath.Join(os.Getenv("HOME"), ".shipyard/config/kubeconfig.yaml"), h)
+}
+
+func TestWorkspaceStatePathReturnsCorrectValue(t *testing.T) {
+	h := WorkspaceStatePath("student-1")
+	assert.Equal(t, filepath.Join(os.Getenv("HOME"), ".shipyard/workspaces/student-1/state.json"), h)
+}
+
 func TestCreateKubeConfigPathReturnsCorrectValues(t *testing.T) {
 	home := os.Getenv("HOME")
 	tmp, _ := ioutil.TempDir("", "")
@@ -167,6 +239,35 @@ func TestIsHCLFile(t *testing.T) {
 	}
 }
 
+func TestIsBlueprintPackage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	pack := filepath.Join(dir, "consul"+BlueprintPackageExtension)
+	ioutil.WriteFile(pack, []byte(""), 0644)
+
+	other := filepath.Join(dir, "consul.tar.gz")
+	ioutil.WriteFile(other, []byte(""), 0644)
+
+	assert.True(t, IsBlueprintPackage(pack))
+	assert.False(t, IsBlueprintPackage(other))
+	assert.False(t, IsBlueprintPackage(dir))
+	assert.False(t, IsBlueprintPackage(filepath.Join(dir, "missing"+BlueprintPackageExtension)))
+}
+
+func TestIsRemoteBlueprintPackage(t *testing.T) {
+	assert.True(t, IsRemoteBlueprintPackage("https://example.com/consul"+BlueprintPackageExtension))
+	assert.True(t, IsRemoteBlueprintPackage("https://example.com/consul"+BlueprintPackageExtension+"?checksum=sha256:abc"))
+	assert.False(t, IsRemoteBlueprintPackage("github.com/shipyard-run/blueprints//consul"))
+}
+
+func TestPackageLocalFolder(t *testing.T) {
+	dst := GetPackageLocalFolder("abc")
+
+	assert.Equal(t, ShipyardHome()+"/packages/abc", dst)
+}
+
 func TestBlueprintLocalFolder(t *testing.T) {
 	dst := GetBlueprintLocalFolder("github.com/shipyard-run/blueprints//vault-k8s")
 
@@ -177,3 +278,35 @@ func TestDockerSockReturnsCorrectValue(t *testing.T) {
 	ds := GetDockerSock()
 	assert.Equal(t, "/var/run/docker.sock", ds)
 }
+
+func TestHostPathConvertsDockerPathsOnWindows(t *testing.T) {
+	assert.Equal(t, `C:\Users\nic\blueprint`, hostPath("//c/Users/nic/blueprint", platformWindows))
+	assert.Equal(t, `C:\Users\nic\blueprint`, hostPath(`C:\Users\nic\blueprint`, platformWindows))
+	assert.Equal(t, "/var/run/docker.sock", hostPath("/var/run/docker.sock", platformWindows))
+}
+
+func TestHostPathConvertsWindowsPathsInWSL(t *testing.T) {
+	assert.Equal(t, "/mnt/c/Users/nic/blueprint", hostPath(`C:\Users\nic\blueprint`, platformWSL))
+	assert.Equal(t, "/mnt/d/files", hostPath("D:/files", platformWSL))
+	assert.Equal(t, "/mnt/c/Users/nic", hostPath("//c/Users/nic", platformWSL))
+	assert.Equal(t, "/home/nic/blueprint", hostPath("/home/nic/blueprint", platformWSL))
+}
+
+func TestHostPathDoesNotModifyPathsOnLinux(t *testing.T) {
+	assert.Equal(t, `C:\Users\nic`, hostPath(`C:\Users\nic`, platformLinux))
+	assert.Equal(t, "//c/Users/nic", hostPath("//c/Users/nic", platformLinux))
+}
+
+func TestDockerPathConvertsDrivesOnWindows(t *testing.T) {
+	assert.Equal(t, "//c/Users/nic/blueprint", dockerPath(`C:\Users\nic\blueprint`, platformWindows))
+	assert.Equal(t, "//d/files", dockerPath("D:/files", platformWindows))
+	assert.Equal(t, "/var/run/docker.sock", dockerPath("/var/run/docker.sock", platformWindows))
+	assert.Equal(t, `C:\Users\nic`, dockerPath(`C:\Users\nic`, platformWSL))
+}
+
+func TestIsHostPathReturnsFalseForDockerVMPathsOnWindows(t *testing.T) {
+	assert.False(t, isHostPath("/var/run/docker.sock", platformWindows))
+	assert.True(t, isHostPath(`C:\Users\nic`, platformWindows))
+	assert.True(t, isHostPath("//c/Users/nic", platformWindows))
+	assert.True(t, isHostPath("/var/run/docker.sock", platformLinux))
+}
@@ -1,17 +1,30 @@
 package utils
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
+
+	"github.com/hashicorp/go-getter"
 )
 
 var InvalidBlueprintURIError = fmt.Errorf("Inavlid blueprint URI")
 var NameExceedsMaxLengthError = fmt.Errorf("Name exceeds the max length of 128 characters")
 var NameContainsInvalidCharactersError = fmt.Errorf("Name contains invalid characters characters must be either a-z, A-Z, 0-9, -, _")
+var OfflineError = fmt.Errorf("Shipyard is running in offline mode, remote resources can not be fetched")
+
+// WorkspaceEnvVar is the environment variable which selects the workspace,
+// it overrides the workspace selected with shipyard workspace select
+const WorkspaceEnvVar = "SHIPYARD_WORKSPACE"
+
+// DefaultWorkspace is the workspace used when no workspace has been selected,
+// its state is stored in StateDir
+const DefaultWorkspace = "default"
 
 // ImageVolumeName is the name of the volume which stores the images for clusters
 const ImageVolumeName string = "images"
@@ -71,9 +84,9 @@ func FQDNVolumeName(name string) string {
 // CreateKubeConfigPath creates the file path for the KubeConfig file when
 // using Kubernetes cluster
 func CreateKubeConfigPath(name string) (dir, filePath string, dockerPath string) {
-	dir = fmt.Sprintf("%s/.shipyard/config/%s", HomeFolder(), name)
-	filePath = fmt.Sprintf("%s/kubeconfig.yaml", dir)
-	dockerPath = fmt.Sprintf("%s/kubeconfig-docker.yaml", dir)
+	dir = filepath.Join(ShipyardHome(), "config", name)
+	filePath = filepath.Join(dir, "kubeconfig.yaml")
+	dockerPath = filepath.Join(dir, "kubeconfig-docker.yaml")
 
 	// create the folders
 	err := os.MkdirAll(dir, 0755)
@@ -87,8 +100,8 @@ func CreateKubeConfigPath(name string) (dir, filePath string, dockerPath string)
 // CreateNomadConfigPath creates the file path for the Nomad config file when
 // using Kubernetes cluster
 func CreateNomadConfigPath(name string) (dir, filePath string) {
-	dir = fmt.Sprintf("%s/.shipyard/config/%s", HomeFolder(), name)
-	filePath = fmt.Sprintf("%s/nomad.json", dir)
+	dir = filepath.Join(ShipyardHome(), "config", name)
+	filePath = filepath.Join(dir, "nomad.json")
 
 	// create the folders
 	err := os.MkdirAll(dir, 0755)
@@ -112,12 +125,12 @@ func HomeFolder() string {
 // ShipyardHome returns the location of the shipyard
 // folder, usually $HOME/.shipyard
 func ShipyardHome() string {
-	return fmt.Sprintf("%s/.shipyard", HomeFolder())
+	return filepath.Join(HomeFolder(), ".shipyard")
 }
 
 // ShipyardTemp returns a temporary folder
 func ShipyardTemp() string {
-	dir := filepath.Join(ShipyardHome(), "/tmp")
+	dir := filepath.Join(ShipyardHome(), "tmp")
 	err := os.MkdirAll(dir, 0755)
 	if err != nil {
 		panic(err)
@@ -129,17 +142,72 @@ func ShipyardTemp() string {
 // StateDir returns the location of the shipyard
 // state, usually $HOME/.shipyard/state
 func StateDir() string {
-	return fmt.Sprintf("%s/state", ShipyardHome())
+	return filepath.Join(ShipyardHome(), "state")
 }
 
-// StatePath returns the full path for the state file
+// StatePath returns the full path for the state file of the current
+// workspace
 func StatePath() string {
-	return fmt.Sprintf("%s/state.json", StateDir())
+	if w := CurrentWorkspace(); w != DefaultWorkspace {
+		return WorkspaceStatePath(w)
+	}
+
+	return filepath.Join(StateDir(), "state.json")
+}
+
+// CurrentWorkspacePath returns the location of the file containing the name
+// of the selected workspace
+func CurrentWorkspacePath() string {
+	return filepath.Join(ShipyardHome(), "workspace")
+}
+
+// CurrentWorkspace returns the name of the workspace set by WorkspaceEnvVar
+// or the selected workspace, DefaultWorkspace is returned when neither is set
+func CurrentWorkspace() string {
+	if w := os.Getenv(WorkspaceEnvVar); w != "" {
+		return w
+	}
+
+	d, err := ioutil.ReadFile(CurrentWorkspacePath())
+	if err == nil && strings.TrimSpace(string(d)) != "" {
+		return strings.TrimSpace(string(d))
+	}
+
+	return DefaultWorkspace
+}
+
+// ValidateWorkspace returns an error when the name of the current workspace
+// is not a valid name
+func ValidateWorkspace() error {
+	w := CurrentWorkspace()
+	if _, err := ValidateName(w); err != nil {
+		return fmt.Errorf("Invalid workspace name %s set by %s or workspace select: %w", w, WorkspaceEnvVar, err)
+	}
+
+	return nil
+}
+
+// WorkspacesDir returns the folder containing the state for each workspace
+func WorkspacesDir() string {
+	return filepath.Join(ShipyardHome(), "workspaces")
+}
+
+// WorkspaceStatePath returns the full path for the state file of the named
+// workspace, names containing path separators or .. can not refer to a
+// location outside of WorkspacesDir
+func WorkspaceStatePath(name string) string {
+	return filepath.Join(WorkspacesDir(), filepath.Base(filepath.Clean("/"+name)), "state.json")
+}
+
+// ManagedKubeConfigPath returns the location of the Kubernetes config which
+// contains a context for every cluster created by Shipyard
+func ManagedKubeConfigPath() string {
+	return filepath.Join(ShipyardHome(), "config", "kubeconfig.yaml")
 }
 
 // ImageCacheLog returns the location of the image cache log
 func ImageCacheLog() string {
-	return fmt.Sprintf("%s/images.log", ShipyardHome())
+	return filepath.Join(ShipyardHome(), "images.log")
 }
 
 // IsLocalFolder tests if the given path is a localfolder and can
@@ -160,6 +228,21 @@ func IsLocalFolder(path string) bool {
 	return false
 }
 
+// IsRemoteURI tests if the given path refers to a remote location such as
+// a git repository, HTTP url or S3 bucket which must be downloaded before use
+func IsRemoteURI(path string) bool {
+	if path == "" || filepath.IsAbs(path) {
+		return false
+	}
+
+	src, err := getter.Detect(path, "/", getter.Detectors)
+	if err != nil {
+		return false
+	}
+
+	return !strings.HasPrefix(src, "file://")
+}
+
 // IsHCLFile tests if the given path resolves to a HCL config file
 func IsHCLFile(path string) bool {
 	s, err := os.Stat(path)
@@ -178,6 +261,32 @@ func IsHCLFile(path string) bool {
 	return true
 }
 
+// BlueprintPackageExtension is the file extension for packaged blueprints
+const BlueprintPackageExtension = ".yardpack"
+
+// IsBlueprintPackage tests if the given path resolves to a packaged blueprint
+func IsBlueprintPackage(path string) bool {
+	s, err := os.Stat(path)
+	if err != nil || s.IsDir() {
+		return false
+	}
+
+	return strings.HasSuffix(s.Name(), BlueprintPackageExtension)
+}
+
+// IsRemoteBlueprintPackage tests if the given uri refers to a packaged
+// blueprint which must be downloaded, e.g. https://example.com/app.yardpack
+func IsRemoteBlueprintPackage(uri string) bool {
+	if IsLocalFolder(uri) || IsBlueprintPackage(uri) {
+		return false
+	}
+
+	// ignore go-getter options such as ?checksum=
+	uri = strings.SplitN(uri, "?", 2)[0]
+
+	return strings.HasSuffix(uri, BlueprintPackageExtension)
+}
+
 // GetBlueprintFolder parses a blueprint uri and returns the top level
 // blueprint folder
 // if the URI is not a blueprint will return an error
@@ -199,12 +308,24 @@ func GetBlueprintLocalFolder(blueprint string) string {
 	return filepath.Join(ShipyardHome(), "blueprints", blueprint)
 }
 
+// GetPackageLocalFolder returns the folder a packaged blueprint with the
+// given checksum is unpacked to
+func GetPackageLocalFolder(checksum string) string {
+	return filepath.Join(ShipyardHome(), "packages", checksum)
+}
+
 // GetHelmLocalFolder returns the full storage path
 // for the given blueprint URI
 func GetHelmLocalFolder(blueprint string) string {
 	return filepath.Join(ShipyardHome(), "helm_charts", blueprint)
 }
 
+// GetDownloadLocalFolder returns the full storage path for
+// files downloaded from the given URI
+func GetDownloadLocalFolder(uri string) string {
+	return filepath.Join(ShipyardHome(), "downloads", fmt.Sprintf("%x", sha256.Sum256([]byte(uri))))
+}
+
 // GetDockerSock returns the location of the Docker sock depending on the platform
 func GetDockerSock() string {
 	//TODO: need to think about what happens if Docker is running at a TCP address rather than a socket